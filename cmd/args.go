@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// isFileArg reports whether arg names an existing regular file, which makes
+// the invocation a list of files to process rather than a directory to scan.
+func isFileArg(arg string) bool {
+	info, err := os.Stat(arg)
+
+	return err == nil && info.Mode().IsRegular()
+}
+
+// globMeta holds the characters that make an argument a glob, braces
+// included since --globs expands them.
+const globMeta = "*?[{"
+
+// isGlobArg reports whether arg is a quoted glob such as 'src/**/*.go'
+// rather than a path: it does not exist and holds glob metacharacters.
+func isGlobArg(arg string) bool {
+	if !strings.ContainsAny(arg, globMeta) {
+		return false
+	}
+	_, err := os.Lstat(arg)
+
+	return errors.Is(err, os.ErrNotExist)
+}
+
+// globArgs splits glob arguments into the directory to scan, the longest
+// literal directory prefix they share, and the rest of each as an include
+// glob. A leading "**/" is dropped, since globs already match at any depth,
+// and a pattern of "**" alone selects everything.
+func globArgs(args []string) (string, []string, error) {
+	dir := ""
+	var globs []string
+	for _, arg := range args {
+		if !isGlobArg(arg) {
+			return "", nil, fmt.Errorf("'%s' is not a glob; when the first argument is a glob, every argument must be one", arg)
+		}
+
+		segments := strings.Split(filepath.ToSlash(arg), "/")
+		literal := 0
+		for literal < len(segments)-1 && !strings.ContainsAny(segments[literal], globMeta) {
+			literal++
+		}
+		argDir := filepath.FromSlash(strings.Join(segments[:literal], "/"))
+		switch {
+		case argDir == "" && literal > 0:
+			argDir = string(filepath.Separator)
+		case argDir == "":
+			argDir = "."
+		}
+		if dir != "" && argDir != dir {
+			return "", nil, fmt.Errorf("glob arguments must share a directory, but '%s' is in %s and an earlier one in %s", arg, argDir, dir)
+		}
+		dir = argDir
+
+		glob := strings.Join(segments[literal:], "/")
+		for strings.HasPrefix(glob, "**/") {
+			glob = strings.TrimPrefix(glob, "**/")
+		}
+		if glob != "**" {
+			globs = append(globs, glob)
+		}
+	}
+
+	return dir, globs, nil
+}
+
+// fileArgs turns an invocation made only of files into the directory that
+// holds them all, the closest common parent, and their paths relative to it.
+// The directory stays relative when every argument is.
+func fileArgs(args []string) (string, []string, error) {
+	absPaths := make([]string, len(args))
+	allRelative := true
+	for i, arg := range args {
+		info, err := os.Stat(arg)
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil, fmt.Errorf("file '%s' does not exist", arg)
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("cannot access file '%s': %w", arg, err)
+		}
+		if !info.Mode().IsRegular() {
+			return "", nil, fmt.Errorf("'%s' is not a file; when the first argument is a file, every argument must be one (to scan a directory, pass it first)", arg)
+		}
+
+		if absPaths[i], err = filepath.Abs(arg); err != nil {
+			return "", nil, err
+		}
+		allRelative = allRelative && !filepath.IsAbs(arg)
+	}
+
+	base := filepath.Dir(absPaths[0])
+	for _, path := range absPaths[1:] {
+		base = commonDir(base, filepath.Dir(path))
+	}
+
+	paths := make([]string, len(absPaths))
+	for i, path := range absPaths {
+		rel, err := filepath.Rel(base, path)
+		if err != nil {
+			return "", nil, err
+		}
+		paths[i] = rel
+	}
+
+	if allRelative {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return "", nil, err
+		}
+		if rel, err := filepath.Rel(cwd, base); err == nil {
+			base = rel
+		}
+	}
+
+	return base, paths, nil
+}
+
+// commonDir returns the closest directory containing both absolute
+// directories a and b.
+func commonDir(a, b string) string {
+	for {
+		rel, err := filepath.Rel(a, b)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return a
+		}
+
+		parent := filepath.Dir(a)
+		if parent == a {
+			return a
+		}
+		a = parent
+	}
+}
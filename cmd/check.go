@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/connerohnesorge/catls/internal/catls"
+	"github.com/spf13/cobra"
+)
+
+// checkCmd verifies the integrity trailer of a document written with
+// --integrity.
+var checkCmd = &cobra.Command{
+	Use:   "check [FILE]",
+	Short: "Verify that a document written with --integrity arrived whole",
+	Long: `check reads a document written with --integrity from FILE, or from stdin when
+FILE is omitted or "-", and verifies the SHA-256 its trailer records against
+everything before the trailer. It prints the files and lines the trailer
+records when they match, and exits with status 4 when the document was
+changed, cut short, or has no trailer.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runCheck,
+}
+
+func runCheck(cmd *cobra.Command, args []string) error {
+	var document []byte
+	var err error
+	name := "stdin"
+	if len(args) == 0 || args[0] == "-" {
+		document, err = io.ReadAll(cmd.InOrStdin())
+	} else {
+		name = args[0]
+		document, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot read document: %w", err)
+	}
+
+	trailer, err := catls.CheckIntegrity(document)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "%s: OK, %d files and %d lines, sha256 %s\n", name, trailer.Files, trailer.Lines, trailer.SHA256)
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/connerohnesorge/catls/internal/catls"
+)
+
+func TestCheckCommand(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var document bytes.Buffer
+	app, err := catls.New(&catls.Config{Directory: dir, OutputFormat: catls.OutputFormatMarkdown, Output: &document, Integrity: true})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "out.md")
+	if err := os.WriteFile(path, document.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	rootCmd.SetOut(&buf)
+	t.Cleanup(func() {
+		rootCmd.SetArgs(nil)
+		rootCmd.SetOut(nil)
+		rootCmd.SetIn(nil)
+	})
+
+	rootCmd.SetArgs([]string{"check", path})
+	if err := Execute(); err != nil {
+		t.Fatalf("check of a whole document: %v", err)
+	}
+	if !strings.Contains(buf.String(), "OK, 1 files and 1 lines") {
+		t.Errorf("check output = %q, want the recorded counts", buf.String())
+	}
+
+	// A document cut short on stdin
+	rootCmd.SetIn(bytes.NewReader(document.Bytes()[:document.Len()/2]))
+	rootCmd.SetArgs([]string{"check"})
+	err = Execute()
+	if !errors.Is(err, catls.ErrNoIntegrityTrailer) || ExitCode(err) != exitCheckFailed {
+		t.Errorf("check of a truncated document = %v, want ErrNoIntegrityTrailer", err)
+	}
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/term"
+	"github.com/connerohnesorge/catls/internal/catls"
+)
+
+// Values accepted by --color.
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+// resolveColor reports whether output is colored for a --color value. auto
+// colors only when stdout is a terminal and NO_COLOR is unset.
+func resolveColor(mode string) (bool, error) {
+	switch mode {
+	case colorAlways:
+		return true, nil
+	case colorNever:
+		return false, nil
+	case colorAuto:
+		return os.Getenv("NO_COLOR") == "" && term.IsTerminal(os.Stdout.Fd()), nil
+	default:
+		return false, fmt.Errorf("--color expects %s, %s, or %s, got %q", colorAuto, colorAlways, colorNever, mode)
+	}
+}
+
+// applyDefaultTheme picks the light theme for colored pretty output when no
+// --theme was given and the terminal background is light. The background is
+// only queried when it matters, since the query talks to the terminal.
+func applyDefaultTheme(cfg *catls.Config) {
+	pretty := cfg.OutputFormat == catls.OutputFormatPretty || slices.Contains(cfg.OutputFormats, catls.OutputFormatPretty)
+	if !cfg.Color || cfg.Theme != "" || !pretty {
+		return
+	}
+
+	if !lipgloss.HasDarkBackground() {
+		cfg.Theme = catls.LightTheme
+	}
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/x/term"
+
+	"github.com/connerohnesorge/catls/internal/catls"
+)
+
+// defaultTerminalWarnSize is the projected output size above which a run
+// writing to a terminal asks before printing.
+const defaultTerminalWarnSize = "1MB"
+
+// terminalConfirm returns the prompt used before printing large output, or
+// nil when the run should not ask: --yes was given, stdout is not a terminal
+// (it is piped to a pager or a file), or stdin is not a terminal that could
+// answer.
+func terminalConfirm(assumeYes bool) func(size int64) bool {
+	if assumeYes || !term.IsTerminal(os.Stdout.Fd()) || !term.IsTerminal(os.Stdin.Fd()) {
+		return nil
+	}
+
+	return func(size int64) bool {
+		return askToContinue(os.Stdin, os.Stderr, size)
+	}
+}
+
+// terminalConfirmScan returns the prompt used before a broad recursive scan,
+// or nil when stdin or stderr is not a terminal, in which case such a scan
+// needs --force.
+func terminalConfirmScan() func(dir, reason string) bool {
+	if !term.IsTerminal(os.Stdin.Fd()) || !term.IsTerminal(os.Stderr.Fd()) {
+		return nil
+	}
+
+	return func(dir, reason string) bool {
+		return askToScan(os.Stdin, os.Stderr, dir, reason)
+	}
+}
+
+// askToContinue writes the large output prompt to w and reports whether the
+// answer read from r is yes.
+func askToContinue(r io.Reader, w io.Writer, size int64) bool {
+	fmt.Fprintf(w, "about to print ~%s to your terminal, continue? [y/N] ", catls.FormatByteSize(size))
+
+	return readYes(r)
+}
+
+// askToScan writes the broad scan prompt to w and reports whether the answer
+// read from r is yes.
+func askToScan(r io.Reader, w io.Writer, dir, reason string) bool {
+	fmt.Fprintf(w, "%s is %s; scan it recursively? [y/N] ", dir, reason)
+
+	return readYes(r)
+}
+
+// readYes reads one line from r and reports whether it says yes. Anything
+// else, including no answer, is no.
+func readYes(r io.Reader) bool {
+	answer, _ := bufio.NewReader(r).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
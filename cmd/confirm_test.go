@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/connerohnesorge/catls/internal/catls"
+)
+
+func TestAskToContinue(t *testing.T) {
+	tests := []struct {
+		name   string
+		answer string
+		want   bool
+	}{
+		{name: "yes", answer: "y\n", want: true},
+		{name: "full word with spaces", answer: "  Yes \n", want: true},
+		{name: "no", answer: "n\n"},
+		{name: "empty line defaults to no", answer: "\n"},
+		{name: "end of input", answer: ""},
+		{name: "anything else", answer: "sure\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var prompt bytes.Buffer
+			if got := askToContinue(strings.NewReader(tt.answer), &prompt, 14<<20); got != tt.want {
+				t.Errorf("askToContinue() = %v, want %v", got, tt.want)
+			}
+
+			want := "about to print ~14MB to your terminal, continue? [y/N] "
+			if prompt.String() != want {
+				t.Errorf("prompt = %q, want %q", prompt.String(), want)
+			}
+		})
+	}
+}
+
+func TestAskToScan(t *testing.T) {
+	var prompt bytes.Buffer
+	if !askToScan(strings.NewReader("yes\n"), &prompt, "/home/me", "your home directory") {
+		t.Error("askToScan() = false, want true for yes")
+	}
+	if want := "/home/me is your home directory; scan it recursively? [y/N] "; prompt.String() != want {
+		t.Errorf("prompt = %q, want %q", prompt.String(), want)
+	}
+	if askToScan(strings.NewReader("\n"), &prompt, "/", "a filesystem root") {
+		t.Error("askToScan() = true, want false for an empty answer")
+	}
+}
+
+func TestFormatByteSize(t *testing.T) {
+	tests := []struct {
+		size int64
+		want string
+	}{
+		{size: 0, want: "0B"},
+		{size: 1023, want: "1023B"},
+		{size: 1 << 10, want: "1KB"},
+		{size: 1536 << 10, want: "1.5MB"},
+		{size: 14<<20 + 300<<10, want: "14MB"},
+		{size: 3 << 30, want: "3GB"},
+		{size: 2048 << 30, want: "2048GB"},
+	}
+
+	for _, tt := range tests {
+		if got := catls.FormatByteSize(tt.size); got != tt.want {
+			t.Errorf("FormatByteSize(%d) = %q, want %q", tt.size, got, tt.want)
+		}
+	}
+}
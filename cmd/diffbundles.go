@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/connerohnesorge/catls/internal/catls"
+	"github.com/spf13/cobra"
+)
+
+// diffBundlesCmd compares two saved catls outputs without touching the files
+// they came from.
+var diffBundlesCmd = &cobra.Command{
+	Use:   "diff-bundles OLD NEW",
+	Short: "Report files added, removed, or changed between two catls outputs",
+	Long: `diff-bundles compares two outputs of --format json, or two manifests written by
+--manifest, and reports the files that were added, removed, or changed. Files
+are matched by relative path. Manifests are compared by SHA-256 and JSON
+outputs by the lines they recorded and their line numbers; a file truncated in either output is
+reported as incomparable unless the lines both recorded already differ.
+--diff adds a unified diff of each changed file's recorded lines. It exits
+with status 7 when there are differences.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runDiffBundles,
+}
+
+func init() {
+	flags := diffBundlesCmd.Flags()
+	flags.Bool(
+		"diff",
+		false,
+		"Include a unified diff of each changed file's recorded lines (JSON outputs only)",
+	)
+	flags.StringP(
+		"format",
+		"f",
+		"",
+		"Report format: xml, json, markdown (default: a short summary)",
+	)
+}
+
+func runDiffBundles(cmd *cobra.Command, args []string) error {
+	flags := cmd.Flags()
+	withDiff, _ := flags.GetBool("diff")
+	format, _ := flags.GetString("format")
+
+	older, err := catls.ReadBundle(args[0])
+	if err != nil {
+		return err
+	}
+	newer, err := catls.ReadBundle(args[1])
+	if err != nil {
+		return err
+	}
+
+	diff, err := catls.DiffBundles(older, newer, withDiff)
+	if err != nil {
+		return err
+	}
+	if err := catls.WriteBundleDiff(cmd.OutOrStdout(), catls.OutputFormat(format), diff); err != nil {
+		return err
+	}
+
+	if diff.Differences() > 0 {
+		return fmt.Errorf("%d files differ between %s and %s: %w", diff.Differences(), args[0], args[1], catls.ErrBundlesDiffer)
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/connerohnesorge/catls/internal/catls"
+)
+
+func TestDiffBundlesCommand(t *testing.T) {
+	dir := t.TempDir()
+	out := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	snapshot := func(name string) string {
+		t.Helper()
+		var buf bytes.Buffer
+		app, err := catls.New(&catls.Config{Directory: dir, OutputFormat: catls.OutputFormatJSON, Output: &buf})
+		if err != nil {
+			t.Fatalf("New() unexpected error: %v", err)
+		}
+		if err := app.Run(context.Background()); err != nil {
+			t.Fatalf("Run() unexpected error: %v", err)
+		}
+		path := filepath.Join(out, name)
+		if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+
+		return path
+	}
+
+	var buf bytes.Buffer
+	rootCmd.SetOut(&buf)
+	t.Cleanup(func() {
+		rootCmd.SetArgs(nil)
+		rootCmd.SetOut(nil)
+		_ = diffBundlesCmd.Flags().Set("diff", "false")
+	})
+	run := func(args ...string) error {
+		buf.Reset()
+		rootCmd.SetArgs(args)
+
+		return Execute()
+	}
+
+	write("a.go", "package a\n")
+	write("b.go", "package b\n\nfunc B() {}\n")
+	before := snapshot("before.json")
+
+	if err := run("diff-bundles", before, before); err != nil {
+		t.Fatalf("diff-bundles of one output with itself: %v\n%s", err, buf.String())
+	}
+	if !strings.Contains(buf.String(), "OK: 2 files match") {
+		t.Errorf("diff-bundles output = %q, want a match summary", buf.String())
+	}
+
+	write("b.go", "package b\n\nfunc B() { panic(1) }\n")
+	write("c.go", "package c\n")
+	after := snapshot("after.json")
+
+	err := run("diff-bundles", "--diff", before, after)
+	if !errors.Is(err, catls.ErrBundlesDiffer) || ExitCode(err) != exitBundlesDiffer {
+		t.Fatalf("diff-bundles error = %v, want ErrBundlesDiffer", err)
+	}
+	for _, want := range []string{"added         c.go", "changed       b.go", "-func B() {}\n+func B() { panic(1) }\n", "2 differences between"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("diff-bundles output = %q, want %q", buf.String(), want)
+		}
+	}
+}
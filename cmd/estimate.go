@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"github.com/connerohnesorge/catls/internal/catls"
+	"github.com/spf13/cobra"
+)
+
+// estimateCmd sizes a run without reading file contents. It accepts the same
+// arguments and flags as the root command; the flags are attached in init in
+// root.go once they are defined.
+var estimateCmd = &cobra.Command{
+	Use:   "estimate [directory] [files...]",
+	Short: "Report the size of a run without reading file contents",
+	Long: `estimate runs the scan and filters with the given flags and reports the
+number of files, total bytes, an estimated token count (bytes/4), the largest
+files, and bytes per extension. Use --format json to drive scripts.`,
+	Args: cobra.ArbitraryArgs,
+	RunE: runEstimate,
+}
+
+func runEstimate(cmd *cobra.Command, args []string) error {
+	cfg, err := buildConfig(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	app, err := catls.New(cfg)
+	if err != nil {
+		return err
+	}
+
+	report, err := app.Estimate(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	return catls.WriteEstimate(cmd.OutOrStdout(), cfg.OutputFormat, report)
+}
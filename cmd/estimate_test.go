@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/connerohnesorge/catls/internal/catls"
+	"github.com/spf13/pflag"
+)
+
+func TestEstimateCommandSharesFlags(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"a.go": "package a", "b.txt": "text"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	rootCmd.SetArgs([]string{"estimate", "-f", "json", "--ignore-globs", "*.txt", dir})
+	rootCmd.SetOut(&buf)
+	t.Cleanup(func() {
+		rootCmd.SetArgs(nil)
+		rootCmd.SetOut(nil)
+		_ = estimateCmd.Flags().Set("format", "xml")
+		_ = estimateCmd.Flags().Lookup("ignore-globs").Value.(pflag.SliceValue).Replace(nil)
+	})
+
+	if err := Execute(); err != nil {
+		t.Fatalf("Execute() unexpected error: %v", err)
+	}
+
+	var report catls.EstimateReport
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("estimate output is not JSON: %v\n%s", err, buf.String())
+	}
+	if report.Files != 1 || report.Bytes != int64(len("package a")) {
+		t.Errorf("estimate = %+v, want only a.go", report)
+	}
+}
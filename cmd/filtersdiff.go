@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/connerohnesorge/catls/internal/catls"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// filtersDiffCmd compares the files two sets of filter flags select from
+// one scan. It accepts the same arguments and flags as the root command;
+// the flags are attached in init in root.go once they are defined.
+var filtersDiffCmd = &cobra.Command{
+	Use:   "filters-diff [directory] --compare-with FLAGS",
+	Short: "Show which files a change of filter flags would add or remove",
+	Long: `filters-diff scans once with the given flags and checks every file found
+against two filter configurations: the given flags, and the same flags with
+those in --compare-with set over them. A flag named in --compare-with
+replaces its current value rather than adding to it. It prints the files only
+the current flags select, marked "-", those only the new flags select, marked
+"+", each with the rule the other configuration drops it by, and the counts.
+Flags that decide what the scan visits, such as -r or --ignore-dir, cannot
+be compared, since the scan is shared.`,
+	Example: `  catls filters-diff -r --ignore-globs '*.md' --compare-with "--ignore-globs '*.md,docs/**'" .
+  catls filters-diff -r --globs '*.go' --compare-with '--type go --imports internal/scanner' .`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runFiltersDiff,
+}
+
+func init() {
+	filtersDiffCmd.Flags().String(
+		"compare-with",
+		"",
+		"Flags to set over the given ones for the new configuration, quoted as for a shell",
+	)
+	_ = filtersDiffCmd.MarkFlagRequired("compare-with")
+}
+
+func runFiltersDiff(cmd *cobra.Command, args []string) error {
+	cfg, err := buildConfig(cmd, args)
+	if err != nil {
+		return err
+	}
+	cfg.ConfirmBroadScan = terminalConfirmScan()
+
+	compareWith, _ := cmd.Flags().GetString("compare-with")
+	compareCfg, err := compareConfig(cmd, args, compareWith)
+	if err != nil {
+		return err
+	}
+
+	app, err := catls.New(cfg)
+	if err != nil {
+		return err
+	}
+	compareApp, err := catls.New(compareCfg)
+	if err != nil {
+		return fmt.Errorf("--compare-with: %w", err)
+	}
+
+	diff, err := app.DiffFilters(cmd.Context(), compareApp)
+	if err != nil {
+		return err
+	}
+
+	return catls.WriteFilterDiff(cmd.OutOrStdout(), diff)
+}
+
+// compareConfig builds the configuration of the flags in compareWith set
+// over those given to cmd, on a flag set of its own so neither parse sees
+// the other's state.
+func compareConfig(cmd *cobra.Command, args []string, compareWith string) (*catls.Config, error) {
+	fields, err := splitFlagString(compareWith)
+	if err != nil {
+		return nil, fmt.Errorf("--compare-with: %w", err)
+	}
+
+	compare := &cobra.Command{}
+	flags := compare.Flags()
+	setupFlags(flags)
+	if err := flags.Parse(fields); err != nil {
+		return nil, fmt.Errorf("--compare-with: %w", err)
+	}
+	if flags.NArg() > 0 {
+		return nil, fmt.Errorf("--compare-with takes only flags, not %q", flags.Arg(0))
+	}
+	for _, name := range scanOnlyFlags {
+		if flags.Changed(name) {
+			return nil, fmt.Errorf("--compare-with cannot set --%s: both configurations share one scan", name)
+		}
+	}
+
+	// Flags the comparison leaves alone keep their current values
+	var errs []error
+	cmd.Flags().Visit(func(current *pflag.Flag) {
+		flag := flags.Lookup(current.Name)
+		if flag == nil || flag.Changed {
+			return
+		}
+		if values, ok := current.Value.(pflag.SliceValue); ok {
+			errs = append(errs, flag.Value.(pflag.SliceValue).Replace(slices.Clone(values.GetSlice())))
+		} else {
+			errs = append(errs, flag.Value.Set(current.Value.String()))
+		}
+		flag.Changed = true
+	})
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	return buildConfig(compare, args)
+}
+
+// splitFlagString splits s into arguments as a shell would, honoring single
+// and double quotes and backslash escapes, without expanding anything.
+func splitFlagString(s string) ([]string, error) {
+	var fields []string
+	var field strings.Builder
+	inField := false
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			field.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				field.WriteRune(r)
+			}
+		case r == '\\':
+			escaped, inField = true, true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				field.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inField = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+				inField = false
+			}
+		default:
+			field.WriteRune(r)
+			inField = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote or escape in %q", s)
+	}
+	if inField {
+		fields = append(fields, field.String())
+	}
+
+	return fields, nil
+}
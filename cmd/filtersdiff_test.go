@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestSplitFlagString(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{input: "", want: nil},
+		{input: "  --omit-bins  ", want: []string{"--omit-bins"}},
+		{input: "--globs '*.go' --type go", want: []string{"--globs", "*.go", "--type", "go"}},
+		{input: `--ignore-globs "docs/**,*.md"`, want: []string{"--ignore-globs", "docs/**,*.md"}},
+		{input: `--pattern 'it''s'`, want: []string{"--pattern", "its"}},
+		{input: `--pattern "say \"hi\""`, want: []string{"--pattern", `say "hi"`}},
+		{input: `--pattern a\ b ''`, want: []string{"--pattern", "a b", ""}},
+		{input: `--globs '*.go`, wantErr: true},
+		{input: `--globs \`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := splitFlagString(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("splitFlagString() = %q, want an error", got)
+				}
+
+				return
+			}
+			if err != nil || !slices.Equal(got, tt.want) {
+				t.Errorf("splitFlagString() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareConfig(t *testing.T) {
+	newCmd := func(args ...string) *cobra.Command {
+		t.Helper()
+		cmd := &cobra.Command{}
+		setupFlags(cmd.Flags())
+		cmd.Flags().String("compare-with", "", "")
+		if err := cmd.Flags().Parse(args); err != nil {
+			t.Fatal(err)
+		}
+
+		return cmd
+	}
+	dir := t.TempDir()
+	current := newCmd("-r", "--globs", "*.go,*.md", "--ignore-globs", "*_test.go", "--omit-bins", "--no-ignore-file")
+
+	cfg, err := compareConfig(current, []string{dir}, "--ignore-globs 'docs/**' --type go")
+	if err != nil {
+		t.Fatalf("compareConfig() unexpected error: %v", err)
+	}
+	if !slices.Equal(cfg.IgnoreGlobs, []string{"docs/**"}) {
+		t.Errorf("IgnoreGlobs = %q, want the compared flag to replace the current one", cfg.IgnoreGlobs)
+	}
+	if !slices.Equal(cfg.Globs, []string{"*.go", "*.md"}) || !cfg.OmitBins || !cfg.Recursive {
+		t.Errorf("Globs = %q, OmitBins = %v, Recursive = %v, want the current flags kept", cfg.Globs, cfg.OmitBins, cfg.Recursive)
+	}
+	if !slices.Equal(cfg.Types, []string{"go"}) || cfg.Directory != dir {
+		t.Errorf("Types = %q, Directory = %q, want [go] and %q", cfg.Types, cfg.Directory, dir)
+	}
+
+	// Parsing the comparison leaves the current flags alone
+	if globs, _ := current.Flags().GetStringSlice("ignore-globs"); !slices.Equal(globs, []string{"*_test.go"}) {
+		t.Errorf("current --ignore-globs = %q after the comparison", globs)
+	}
+
+	for compareWith, wantErr := range map[string]string{
+		"--ignore-dir docs": "cannot set --ignore-dir",
+		"-a":                "cannot set --all",
+		"--omit-bins src":   `takes only flags, not "src"`,
+		"--no-such-flag":    "unknown flag",
+		"--type golang":     `unknown --type "golang"`,
+	} {
+		cfg, err := compareConfig(current, []string{dir}, compareWith)
+		if err == nil {
+			// Validation errors surface when the App is created
+			err = cfg.Validate()
+		}
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("compareConfig(%q) error = %v, want %q", compareWith, err, wantErr)
+		}
+	}
+}
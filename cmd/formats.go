@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/connerohnesorge/catls/internal/catls"
+	"github.com/spf13/cobra"
+)
+
+// formatsCmd lists the registered output formats, optionally with sample output.
+var formatsCmd = &cobra.Command{
+	Use:   "formats",
+	Short: "Describe the available output formats",
+	Long: `formats lists every registered output format with a short description,
+the flags that affect it, and the keys it accepts through --format-opt. With --sample, a small example file is rendered
+through each format.`,
+	Args: cobra.NoArgs,
+	RunE: runFormats,
+}
+
+func init() {
+	formatsCmd.Flags().Bool(
+		"sample",
+		false,
+		"Render a sample file through each format",
+	)
+	rootCmd.AddCommand(formatsCmd)
+}
+
+func runFormats(cmd *cobra.Command, _ []string) error {
+	sample, _ := cmd.Flags().GetBool("sample")
+
+	return writeFormats(cmd, cmd.OutOrStdout(), sample)
+}
+
+// writeFormats describes every registered format, in registration order.
+func writeFormats(cmd *cobra.Command, w io.Writer, sample bool) error {
+	formats := catls.Formats()
+
+	width := 0
+	for _, info := range formats {
+		width = max(width, len(info.Name))
+	}
+
+	for i, info := range formats {
+		if sample && i > 0 {
+			fmt.Fprintln(w)
+		}
+
+		fmt.Fprintf(w, "%-*s  %s\n", width, info.Name, info.Description)
+
+		options := "none"
+		if len(info.Options) > 0 {
+			options = strings.Join(info.Options, ", ")
+		}
+		fmt.Fprintf(w, "%-*s  Options: %s\n", width, "", options)
+		for _, opt := range info.FormatOptions {
+			fmt.Fprintf(w, "%-*s  --format-opt %s:%s=%s  %s\n", width, "", info.Name, opt.Key, opt.Value, opt.Description)
+		}
+
+		if !sample {
+			continue
+		}
+
+		fmt.Fprintln(w)
+		if err := catls.RenderSample(cmd.Context(), info.Name, w); err != nil {
+			return fmt.Errorf("failed to render %s sample: %w", info.Name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/connerohnesorge/catls/internal/catls"
+)
+
+func TestFormatsCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "lists formats",
+			args: []string{"formats"},
+			want: []string{"Options: --line-numbers", "Options: --todos", "--format-opt xml:indent=N", "--format-opt json:pretty=BOOL"},
+		},
+		{
+			name: "renders samples",
+			args: []string{"formats", "--sample"},
+			want: []string{`<file path="example/hello.go">`, `"path": "example/hello.go"`, "## example/hello.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			rootCmd.SetArgs(tt.args)
+			rootCmd.SetOut(&buf)
+			t.Cleanup(func() {
+				rootCmd.SetArgs(nil)
+				rootCmd.SetOut(nil)
+				_ = formatsCmd.Flags().Set("sample", "false")
+			})
+
+			if err := Execute(); err != nil {
+				t.Fatalf("Execute() unexpected error: %v", err)
+			}
+
+			output := buf.String()
+			for _, name := range catls.GetSupportedFormats() {
+				if !strings.Contains(output, name+" ") {
+					t.Errorf("output does not describe format %s:\n%s", name, output)
+				}
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(output, want) {
+					t.Errorf("output missing %q:\n%s", want, output)
+				}
+			}
+		})
+	}
+}
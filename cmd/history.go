@@ -0,0 +1,326 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/connerohnesorge/catls/internal/catls"
+	"github.com/connerohnesorge/catls/internal/history"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// historyCmd lists the runs recorded with --history in the current
+// directory.
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List the runs recorded with --history in the current directory",
+	Long: `history lists the runs started in the current directory with --history,
+newest first, numbered for 'catls rerun N', with when each ran, how many files
+it wrote, and how much output it wrote to stdout. Runs are recorded in
+catls/history.jsonl under $XDG_STATE_HOME, or else ~/.local/state on Linux and
+the user configuration directory on macOS and Windows. The file is rotated at
+1MB, keeping one previous file, and paths in it are stored relative to the
+run's directory or the home directory where possible.`,
+	Args: cobra.NoArgs,
+	RunE: runHistory,
+}
+
+// rerunCmd replays a run recorded with --history.
+var rerunCmd = &cobra.Command{
+	Use:   "rerun N",
+	Short: "Run the Nth run 'catls history' lists again",
+	Long: `rerun replays the run numbered N by 'catls history' in the current
+directory, with the arguments and flags it was given. The flags are parsed
+and checked as if they were typed again, so a run whose flags are no longer
+valid fails as it would on the command line.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRerun,
+}
+
+func init() {
+	historyCmd.Flags().Int(
+		"limit",
+		20,
+		"Most recent runs to list (0 lists every run)",
+	)
+}
+
+// historyRecorder adds a run to the history once it succeeds. A nil
+// *historyRecorder records nothing.
+type historyRecorder struct {
+	path  string
+	entry history.Entry
+	out   *countingWriter // Counts what the run writes to stdout; nil with --output-dir
+}
+
+// newHistoryRecorder returns the recorder of a run of cmd with args and cfg,
+// or nil without --history. It counts the bytes written through cfg.Output.
+func newHistoryRecorder(cmd *cobra.Command, args []string, cfg *catls.Config) (*historyRecorder, error) {
+	if enabled, _ := cmd.Flags().GetBool("history"); !enabled {
+		return nil, nil
+	}
+
+	path, err := history.DefaultPath()
+	if err != nil {
+		return nil, fmt.Errorf("--history: %w", err)
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("--history: %w", err)
+	}
+	home, _ := os.UserHomeDir()
+
+	recorder := &historyRecorder{
+		path: path,
+		entry: history.Entry{
+			Time:        time.Now(),
+			Dir:         history.Relativize(dir, "", home),
+			Flags:       historyFlags(cmd.Flags(), dir, home),
+			OutputBytes: -1,
+		},
+	}
+	for _, arg := range args {
+		recorder.entry.Args = append(recorder.entry.Args, history.Relativize(arg, dir, home))
+	}
+	if cfg.OutputDir == "" {
+		out := cfg.Output
+		if out == nil {
+			out = os.Stdout
+		}
+		recorder.out = &countingWriter{w: out}
+		cfg.Output = recorder.out
+	}
+
+	return recorder, nil
+}
+
+// record appends the run to the history. The run already succeeded, so a
+// history that cannot be written only warns.
+func (r *historyRecorder) record(stats catls.RunStats) {
+	if r == nil {
+		return
+	}
+
+	r.entry.Files = stats.Files
+	if r.out != nil {
+		r.entry.OutputBytes = r.out.n
+	}
+	if err := history.Append(r.path, r.entry); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: run not recorded in the history: %v\n", err)
+	}
+}
+
+// pathFlags are the flags whose value is a path, which the history stores
+// relativized and rerun resolves. Other values are stored as given, since a
+// pattern or glob that looks like a path is not one.
+var pathFlags = map[string]bool{
+	"allowlist":    true,
+	"detect-cache": true,
+	"manifest":     true,
+	"output-dir":   true,
+	"profile-json": true,
+	"relative-to":  true,
+}
+
+// historyFlags returns the flags given to a run as --name=value arguments,
+// one per value of a slice flag, with absolute paths relativized.
+func historyFlags(flags *pflag.FlagSet, dir, home string) []string {
+	var args []string
+	flags.Visit(func(flag *pflag.Flag) {
+		values := []string{flag.Value.String()}
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			values = slice.GetSlice()
+		}
+		for _, value := range values {
+			if pathFlags[flag.Name] {
+				value = history.Relativize(value, dir, home)
+			}
+			if flag.Value.Type() == "stringSlice" {
+				value = csvField(value)
+			}
+			args = append(args, "--"+flag.Name+"="+value)
+		}
+	})
+
+	return args
+}
+
+// csvField quotes value as a field of the comma-separated values a string
+// slice flag parses, so a value holding a comma stays one value.
+func csvField(value string) string {
+	if !strings.ContainsAny(value, `,"`) {
+		return value
+	}
+
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	_ = w.Write([]string{value})
+	w.Flush()
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+
+	return n, err
+}
+
+// historyLocation returns the history file and the current directory as
+// the history stores it.
+func historyLocation() (path, dir, home string, err error) {
+	if path, err = history.DefaultPath(); err != nil {
+		return "", "", "", err
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", "", "", err
+	}
+	home, _ = os.UserHomeDir()
+
+	return path, history.Relativize(cwd, "", home), home, nil
+}
+
+func runHistory(cmd *cobra.Command, _ []string) error {
+	path, dir, _, err := historyLocation()
+	if err != nil {
+		return err
+	}
+	entries, err := history.List(path, dir)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	if len(entries) == 0 {
+		fmt.Fprintf(out, "No runs recorded in %s; run catls with --history to record them.\n", dir)
+
+		return nil
+	}
+
+	limit, _ := cmd.Flags().GetInt("limit")
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+	for i, entry := range entries {
+		files := strconv.Itoa(entry.Files) + " files"
+		if entry.Files == 1 {
+			files = "1 file"
+		}
+		size := "-"
+		if entry.OutputBytes >= 0 {
+			size = catls.FormatByteSize(entry.OutputBytes)
+		}
+		fmt.Fprintf(out, "%3d  %s  %11s  %5s  %s\n",
+			i+1, entry.Time.Local().Format("2006-01-02 15:04"), files, size, commandLine(entry))
+	}
+
+	return nil
+}
+
+func runRerun(cmd *cobra.Command, args []string) error {
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 1 {
+		return fmt.Errorf("invalid run number %q: want the number 'catls history' lists, such as 1 for the latest run", args[0])
+	}
+
+	path, dir, home, err := historyLocation()
+	if err != nil {
+		return err
+	}
+	entries, err := history.List(path, dir)
+	if err != nil {
+		return err
+	}
+	if n > len(entries) {
+		return fmt.Errorf("no run %d in %s: %d recorded; see 'catls history'", n, dir, len(entries))
+	}
+	entry := entries[n-1]
+
+	fmt.Fprintf(cmd.ErrOrStderr(), "catls: rerunning %s\n", commandLine(entry))
+	replay, replayArgs, err := replayCommand(entry, home)
+	if err != nil {
+		return fmt.Errorf("run %d: %w", n, err)
+	}
+	replay.SetOut(cmd.OutOrStdout())
+	replay.SetErr(cmd.ErrOrStderr())
+
+	cfg, err := buildConfig(replay, replayArgs)
+	if err != nil {
+		return err
+	}
+	recorder, err := newHistoryRecorder(replay, replayArgs, cfg)
+	if err != nil {
+		return err
+	}
+
+	return runConfig(replay, cfg, recorder)
+}
+
+// replayCommand parses the flags of entry on a flag set of its own, as
+// compareConfig does for --compare-with, and returns it with the entry's
+// arguments, paths under "~" in them and in path flags expanded to home.
+func replayCommand(entry history.Entry, home string) (*cobra.Command, []string, error) {
+	replay := &cobra.Command{}
+	flags := replay.Flags()
+	setupFlags(flags)
+
+	fields := make([]string, len(entry.Flags))
+	for i, flag := range entry.Flags {
+		name, value, _ := strings.Cut(flag, "=")
+		if pathFlags[strings.TrimPrefix(name, "--")] {
+			value = history.Resolve(value, home)
+		}
+		fields[i] = name + "=" + value
+	}
+	if err := flags.Parse(fields); err != nil {
+		return nil, nil, err
+	}
+	if flags.NArg() > 0 {
+		return nil, nil, fmt.Errorf("the history holds an argument among its flags: %q", flags.Arg(0))
+	}
+
+	args := make([]string, len(entry.Args))
+	for i, arg := range entry.Args {
+		args[i] = history.Resolve(arg, home)
+	}
+
+	return replay, args, nil
+}
+
+// commandLine renders entry as the command that would run it.
+func commandLine(entry history.Entry) string {
+	words := []string{"catls"}
+	for _, arg := range slices.Concat(entry.Flags, entry.Args) {
+		words = append(words, shellQuote(arg))
+	}
+
+	return strings.Join(words, " ")
+}
+
+// shellSafe matches the arguments a shell takes as they are.
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./~-]+$`)
+
+// shellQuote quotes arg for a POSIX shell when it needs quoting.
+func shellQuote(arg string) string {
+	if shellSafe.MatchString(arg) {
+		return arg
+	}
+
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
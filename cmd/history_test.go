@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/connerohnesorge/catls/internal/history"
+	"github.com/spf13/cobra"
+)
+
+// runCommand returns a command with the flags of a run, parsed from args.
+func runCommand(t *testing.T, args ...string) *cobra.Command {
+	t.Helper()
+
+	cmd := &cobra.Command{}
+	setupFlags(cmd.Flags())
+	if err := cmd.Flags().Parse(args); err != nil {
+		t.Fatalf("Parse(%q) unexpected error: %v", args, err)
+	}
+
+	return cmd
+}
+
+// historyCommand returns a command running run with output to a buffer.
+func historyCommand(run func(*cobra.Command, []string) error, flags func(*cobra.Command)) (*cobra.Command, *bytes.Buffer) {
+	var out bytes.Buffer
+	cmd := &cobra.Command{RunE: run}
+	if flags != nil {
+		flags(cmd)
+	}
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+
+	return cmd, &out
+}
+
+func TestHistoryRerun(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	dir := t.TempDir()
+	t.Chdir(dir)
+	for name, content := range map[string]string{"a.go": "package a\n", "b.txt": "text\n"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	// Not recorded without --history
+	outDir := filepath.Join(dir, "out")
+	if err := runCatls(runCommand(t, "--output-dir", outDir), []string{"."}); err != nil {
+		t.Fatalf("runCatls() unexpected error: %v", err)
+	}
+	path, stored, _, err := historyLocation()
+	if err != nil {
+		t.Fatalf("historyLocation() unexpected error: %v", err)
+	}
+	if entries, _ := history.List(path, stored); len(entries) != 0 {
+		t.Fatalf("a run without --history was recorded: %+v", entries)
+	}
+
+	cmd := runCommand(t, "--history", "--globs", "*.go", "--format", "markdown", "--output-dir", outDir)
+	if err := runCatls(cmd, []string{dir}); err != nil {
+		t.Fatalf("runCatls() unexpected error: %v", err)
+	}
+	entries, err := history.List(path, stored)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("List() = %+v, %v, want the run", entries, err)
+	}
+	entry := entries[0]
+	wantFlags := []string{"--format=markdown", "--globs=*.go", "--history=true", "--output-dir=out"}
+	if !slices.Equal(entry.Flags, wantFlags) || !slices.Equal(entry.Args, []string{"."}) {
+		t.Errorf("recorded %q %q, want %q with the arguments relative to the directory", entry.Flags, entry.Args, wantFlags)
+	}
+	if entry.Files != 1 || entry.OutputBytes != -1 {
+		t.Errorf("recorded %d files and %d bytes, want 1 file to --output-dir", entry.Files, entry.OutputBytes)
+	}
+
+	list, out := historyCommand(runHistory, func(cmd *cobra.Command) { cmd.Flags().Int("limit", 20, "") })
+	list.SetArgs([]string{})
+	if err := list.Execute(); err != nil {
+		t.Fatalf("history unexpected error: %v", err)
+	}
+	if want := "     1 file      -  catls --format=markdown '--globs=*.go' --history=true --output-dir=out ."; !strings.Contains(out.String(), want) {
+		t.Errorf("history output does not contain %q:\n%s", want, out.String())
+	}
+
+	if err := os.RemoveAll(outDir); err != nil {
+		t.Fatalf("failed to remove %s: %v", outDir, err)
+	}
+	rerun, out := historyCommand(runRerun, nil)
+	rerun.SetArgs([]string{"1"})
+	if err := rerun.Execute(); err != nil {
+		t.Fatalf("rerun unexpected error: %v\n%s", err, out.String())
+	}
+	document, err := os.ReadFile(filepath.Join(outDir, "out-markdown.md"))
+	if err != nil {
+		t.Fatalf("rerun did not write the output again: %v", err)
+	}
+	if !strings.Contains(string(document), "a.go") || strings.Contains(string(document), "b.txt") {
+		t.Errorf("rerun did not apply the recorded flags:\n%s", document)
+	}
+
+	// The rerun had --history, so it was recorded too
+	if entries, _ := history.List(path, stored); len(entries) != 2 {
+		t.Errorf("history holds %d runs after the rerun, want 2", len(entries))
+	}
+}
+
+func TestRerunErrors(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+
+	path, dir, _, err := historyLocation()
+	if err != nil {
+		t.Fatalf("historyLocation() unexpected error: %v", err)
+	}
+	if err := history.Append(path, history.Entry{Dir: dir, Flags: []string{"--format=html"}}); err != nil {
+		t.Fatalf("Append() unexpected error: %v", err)
+	}
+	if err := history.Append(path, history.Entry{Dir: dir, Flags: []string{"--no-such-flag=1"}}); err != nil {
+		t.Fatalf("Append() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		run     string
+		wantErr string
+	}{
+		{run: "0", wantErr: `invalid run number "0"`},
+		{run: "last", wantErr: `invalid run number "last"`},
+		{run: "3", wantErr: "no run 3 in"},
+		{run: "1", wantErr: "run 1: unknown flag: --no-such-flag"},
+		{run: "2", wantErr: "unsupported output format"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.run, func(t *testing.T) {
+			err := runRerun(&cobra.Command{}, []string{tt.run})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("runRerun(%s) error = %v, want %q", tt.run, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHistoryFlagsRelativizesOnlyPaths(t *testing.T) {
+	home := t.TempDir()
+	dir := filepath.Join(home, "src")
+	cmd := runCommand(t,
+		"--pattern", dir,
+		"--globs", "~/x/*.go",
+		"--output-dir", filepath.Join(dir, "out"),
+		"--manifest", filepath.Join(home, "m.json"),
+	)
+
+	got := historyFlags(cmd.Flags(), dir, home)
+	want := []string{
+		"--globs=~/x/*.go",
+		"--manifest=" + filepath.FromSlash("~/m.json"),
+		"--output-dir=out",
+		"--pattern=" + dir,
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("historyFlags() = %q, want %q", got, want)
+	}
+
+	replay, _, err := replayCommand(history.Entry{Flags: got}, home)
+	if err != nil {
+		t.Fatalf("replayCommand() unexpected error: %v", err)
+	}
+	for name, want := range map[string]string{
+		"pattern":  "[" + dir + "]",
+		"manifest": filepath.Join(home, "m.json"),
+		"globs":    "[~/x/*.go]",
+	} {
+		if got := replay.Flags().Lookup(name).Value.String(); got != want {
+			t.Errorf("replayed --%s = %q, want %q", name, got, want)
+		}
+	}
+}
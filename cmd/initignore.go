@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/connerohnesorge/catls/internal/catls"
+	"github.com/spf13/cobra"
+)
+
+// initIgnoreCmd writes a suggested .catlsignore for a directory. It scans
+// with the default ignore rules rather than the root flags, so the
+// suggestions do not depend on an existing ignore file.
+var initIgnoreCmd = &cobra.Command{
+	Use:   "init-ignore [directory]",
+	Short: "Write a .catlsignore suggesting what to skip in a tree",
+	Long: `init-ignore scans the directory recursively, without reading file contents,
+and groups the files that dependency and build directories, lockfiles, and
+generated code match, largest first. It writes them as a .catlsignore in the
+directory, each glob after a comment giving the bytes it saves; top-level
+directories holding a quarter or more of the bytes are listed commented out.
+catls reads the file on every run of that directory unless --no-ignore-file
+is given. An existing file is never overwritten without --force, which also
+scans a home or root directory, or a very wide one, without asking.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runInitIgnore,
+}
+
+func init() {
+	flags := initIgnoreCmd.Flags()
+	flags.Bool(
+		"dry-run",
+		false,
+		"Print the suggested ignore file instead of writing it",
+	)
+	flags.Bool(
+		"force",
+		false,
+		"Overwrite an existing ignore file, and scan a broad directory without asking",
+	)
+}
+
+func runInitIgnore(cmd *cobra.Command, args []string) error {
+	flags := cmd.Flags()
+	dryRun, _ := flags.GetBool("dry-run")
+	force, _ := flags.GetBool("force")
+
+	dir := "."
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	path := filepath.Join(dir, catls.IgnoreFileName)
+	if _, err := os.Stat(path); err == nil && !dryRun && !force {
+		return fmt.Errorf("%s already exists; pass --force to overwrite it", path)
+	}
+
+	app, err := catls.New(&catls.Config{
+		Directory:        dir,
+		Recursive:        true,
+		IgnoreDir:        defaultIgnoreDirs(),
+		OutputFormat:     catls.OutputFormatXML,
+		Force:            force,
+		ConfirmBroadScan: terminalConfirmScan(),
+	})
+	if err != nil {
+		return err
+	}
+	report, err := app.SuggestIgnores(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		return catls.WriteIgnoreFile(cmd.OutOrStdout(), report)
+	}
+	if len(report.Suggestions) == 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), "Nothing to ignore in %s; %s not written\n", dir, path)
+
+		return nil
+	}
+
+	return writeIgnoreFile(cmd, path, report, force)
+}
+
+// writeIgnoreFile writes report to path, failing if the file exists unless
+// force is set, and reports what the required globs save.
+func writeIgnoreFile(cmd *cobra.Command, path string, report catls.IgnoreReport, force bool) error {
+	mode := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if force {
+		mode = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, mode, 0o644)
+	if errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("%s already exists; pass --force to overwrite it", path)
+	}
+	if err != nil {
+		return err
+	}
+	if err := catls.WriteIgnoreFile(f, report); err != nil {
+		f.Close()
+
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	patterns, saved := 0, int64(0)
+	for _, s := range report.Suggestions {
+		if !s.Optional {
+			patterns++
+			saved += s.Bytes
+		}
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Wrote %s: %d globs skipping %d of %d bytes\n", path, patterns, saved, report.Bytes)
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/connerohnesorge/catls/internal/catls"
+)
+
+func TestInitIgnoreCommand(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"main.go": "package main", "go.sum": "sums"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	rootCmd.SetOut(&buf)
+	resetFlags := func() {
+		_ = initIgnoreCmd.Flags().Set("dry-run", "false")
+		_ = initIgnoreCmd.Flags().Set("force", "false")
+	}
+	t.Cleanup(func() {
+		rootCmd.SetArgs(nil)
+		rootCmd.SetOut(nil)
+		resetFlags()
+	})
+	run := func(args ...string) error {
+		buf.Reset()
+		resetFlags()
+		rootCmd.SetArgs(args)
+
+		return Execute()
+	}
+	path := filepath.Join(dir, catls.IgnoreFileName)
+
+	if err := run("init-ignore", "--dry-run", dir); err != nil {
+		t.Fatalf("init-ignore --dry-run: %v", err)
+	}
+	if !strings.Contains(buf.String(), "# lockfile: saves 4 bytes in 1 file\ngo.sum\n") {
+		t.Errorf("init-ignore --dry-run output = %q, want the go.sum glob", buf.String())
+	}
+	if _, err := os.Stat(path); err == nil {
+		t.Fatal("init-ignore --dry-run wrote the ignore file")
+	}
+
+	if err := run("init-ignore", dir); err != nil {
+		t.Fatalf("init-ignore: %v", err)
+	}
+	if !strings.Contains(buf.String(), "1 globs skipping 4 of 16 bytes") {
+		t.Errorf("init-ignore output = %q, want a summary", buf.String())
+	}
+	if err := run("init-ignore", dir); err == nil || !strings.Contains(err.Error(), "pass --force") {
+		t.Errorf("init-ignore over an existing file error = %v, want a --force hint", err)
+	}
+	if err := run("init-ignore", "--force", dir); err != nil {
+		t.Errorf("init-ignore --force: %v", err)
+	}
+
+	cfg, err := buildConfig(rootCmd, []string{dir})
+	if err != nil {
+		t.Fatalf("buildConfig() unexpected error: %v", err)
+	}
+	if !strings.Contains(strings.Join(cfg.IgnoreGlobs, " "), "go.sum") {
+		t.Errorf("IgnoreGlobs = %q, want the globs of %s", cfg.IgnoreGlobs, catls.IgnoreFileName)
+	}
+}
+
+func TestInitIgnoreForceBroadScan(t *testing.T) {
+	dir := t.TempDir()
+	for i := range catls.BroadScanEntries + 1 {
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("f%d.txt", i)), nil, 0o644); err != nil {
+			t.Fatalf("failed to write file %d: %v", i, err)
+		}
+	}
+
+	rootCmd.SetOut(&bytes.Buffer{})
+	t.Cleanup(func() {
+		rootCmd.SetArgs(nil)
+		rootCmd.SetOut(nil)
+		_ = initIgnoreCmd.Flags().Set("dry-run", "false")
+		_ = initIgnoreCmd.Flags().Set("force", "false")
+	})
+
+	rootCmd.SetArgs([]string{"init-ignore", "--dry-run", dir})
+	if err := Execute(); !errors.Is(err, catls.ErrBroadScan) {
+		t.Errorf("init-ignore of a wide directory error = %v, want ErrBroadScan", err)
+	}
+	rootCmd.SetArgs([]string{"init-ignore", "--dry-run", "--force", dir})
+	if err := Execute(); err != nil {
+		t.Errorf("init-ignore --force of a wide directory: %v", err)
+	}
+}
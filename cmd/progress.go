@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/connerohnesorge/catls/internal/catls"
+)
+
+// progressInterval is how often the progress line is redrawn at most.
+const progressInterval = 100 * time.Millisecond
+
+// terminalProgress returns the events that keep a progress line on stderr,
+// or nil when --progress was not given or stderr is not a terminal. Progress
+// is also off when stdout is a terminal, where the line would be drawn into
+// the output.
+func terminalProgress(enabled bool) *catls.Events {
+	if !enabled || !term.IsTerminal(os.Stderr.Fd()) || term.IsTerminal(os.Stdout.Fd()) {
+		return nil
+	}
+
+	return progressEvents(os.Stderr, progressInterval)
+}
+
+// progressEvents returns events that redraw a one-line count of the files
+// found and then processed on w, at most once per interval, and erase it when
+// the run completes.
+func progressEvents(w io.Writer, interval time.Duration) *catls.Events {
+	var (
+		found int
+		drawn time.Time
+	)
+	draw := func(format string, args ...any) {
+		if now := time.Now(); now.Sub(drawn) >= interval {
+			drawn = now
+			fmt.Fprintf(w, "\r\x1b[K"+format, args...)
+		}
+	}
+
+	return &catls.Events{
+		OnFileDiscovered: func(catls.FileDiscoveredEvent) {
+			found++
+			draw("catls: found %d files", found)
+		},
+		OnFileProcessed: func(event catls.FileProcessedEvent) {
+			draw("catls: %d/%d files", event.Index+1, event.Total)
+		},
+		OnRunComplete: func(catls.RunCompleteEvent) {
+			fmt.Fprint(w, "\r\x1b[K")
+		},
+	}
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/connerohnesorge/catls/internal/catls"
+)
+
+func TestProgressEvents(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, name := range []string{"a.go", "b.go"} {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte("package a\n"), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	var progress, out bytes.Buffer
+	app, err := catls.New(&catls.Config{
+		Directory:    tmpDir,
+		OutputFormat: catls.OutputFormatXML,
+		Output:       &out,
+		Events:       progressEvents(&progress, 0),
+	})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	got := progress.String()
+	for _, want := range []string{"catls: found 2 files", "catls: 1/2 files", "catls: 2/2 files"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("progress %q missing %q", got, want)
+		}
+	}
+	if !strings.HasSuffix(got, "\r\x1b[K") {
+		t.Errorf("progress %q should end by erasing the line", got)
+	}
+	if strings.Contains(out.String(), "catls:") {
+		t.Error("progress was written into the output")
+	}
+}
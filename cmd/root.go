@@ -5,11 +5,20 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
 	"strings"
 
+	"github.com/charmbracelet/x/term"
 	"github.com/connerohnesorge/catls/internal/catls"
+	"github.com/connerohnesorge/catls/internal/fdlimit"
+	"github.com/connerohnesorge/catls/internal/interactive"
+	"github.com/connerohnesorge/catls/internal/scanner"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 // rootCmd is the main cobra command for catls.
@@ -19,23 +28,77 @@ var rootCmd = &cobra.Command{
 	Use:   "catls [directory] [files...]",
 	Short: "List files and their contents",
 	Long: `catls recursively lists files and displays their contents in XML format.
-It supports filtering by glob patterns, ignoring directories, and various output options.`,
+It supports filtering by glob patterns, ignoring directories, and various output options.
+When the first argument is a file, every argument must be a file, and exactly
+those files are processed.`,
+	// Positional arguments are a directory and files, not subcommands
+	Args: cobra.ArbitraryArgs,
 	RunE: runCatls,
 }
 
 // Execute runs the root command and returns any error that occurs.
-// The caller is responsible for handling errors and exit codes.
+// The caller is responsible for handling errors and exit codes; see ExitCode.
 func Execute() error {
 	return rootCmd.Execute()
 }
 
-func init() {
-	setupFlags()
+// Exit codes returned by ExitCode.
+const (
+	exitError         = 1 // Any failure without a more specific code
+	exitCaseCollision = 3 // --fail-on-case-collision found colliding paths
+	exitVerifyChanged = 4 // verify found files that differ from the manifest
+	exitBundlesDiffer = 7 // diff-bundles found files that differ between the outputs
+	exitOverBudget    = 4 // The written files exceeded a --budget rule
+	exitCheckFailed   = 4 // check found a document changed, cut short, or without a trailer
+	exitUnlisted      = 5 // --allowlist found included files it does not list
+	exitUnreadable    = 6 // --fail-fast stopped at a file that could not be read
+)
+
+// ExitCode maps an error returned by Execute to the process exit status.
+func ExitCode(err error) int {
+	if errors.Is(err, catls.ErrCaseCollision) {
+		return exitCaseCollision
+	}
+	if errors.Is(err, catls.ErrManifestMismatch) {
+		return exitVerifyChanged
+	}
+	if errors.Is(err, catls.ErrBundlesDiffer) {
+		return exitBundlesDiffer
+	}
+	if errors.Is(err, catls.ErrUnreadable) {
+		return exitUnreadable
+	}
+	if errors.Is(err, catls.ErrBudgetExceeded) {
+		return exitOverBudget
+	}
+	if errors.Is(err, catls.ErrIntegrityMismatch) || errors.Is(err, catls.ErrNoIntegrityTrailer) {
+		return exitCheckFailed
+	}
+	if errors.Is(err, catls.ErrNotAllowlisted) {
+		return exitUnlisted
+	}
+
+	return exitError
 }
 
-func setupFlags() {
-	flags := rootCmd.Flags()
+func init() {
+	setupFlags(rootCmd.Flags())
+	rootCmd.MarkFlagsMutuallyExclusive("detect-cache", "no-detect-cache")
+	rootCmd.MarkFlagsMutuallyExclusive("only-executable", "no-executable")
 
+	// estimate, serve, scan, render, and filters-diff select files exactly
+	// like a normal run, so they share every flag
+	estimateCmd.Flags().AddFlagSet(rootCmd.Flags())
+	serveCmd.Flags().AddFlagSet(rootCmd.Flags())
+	scanCmd.Flags().AddFlagSet(rootCmd.Flags())
+	renderCmd.Flags().AddFlagSet(rootCmd.Flags())
+	filtersDiffCmd.Flags().AddFlagSet(rootCmd.Flags())
+	rootCmd.AddCommand(estimateCmd, serveCmd, verifyCmd, diffBundlesCmd, initIgnoreCmd, scanCmd, renderCmd, filtersDiffCmd, checkCmd, historyCmd, rerunCmd)
+}
+
+// setupFlags defines the flags of a run on flags: the root command's, and
+// those --compare-with of filters-diff is parsed with.
+func setupFlags(flags *pflag.FlagSet) {
 	flags.BoolP(
 		"all",
 		"a",
@@ -53,20 +116,354 @@ func setupFlags() {
 		defaultIgnoreDirs(),
 		"Ignore directory DIR (can be used multiple times)",
 	)
+	flags.Bool(
+		"one-file-system",
+		false,
+		"Do not cross filesystem boundaries while recursing",
+	)
+	flags.Bool(
+		"follow-symlinks",
+		false,
+		"Descend into symlinked directories and junctions, skipping links to directories scanned otherwise",
+	)
+	flags.Bool(
+		"skip-git-submodules",
+		false,
+		"Do not recurse into git submodules",
+	)
+	flags.Int(
+		"expand-tabs",
+		0,
+		"Replace tabs with spaces using tab stops N columns apart (0 keeps tabs)",
+	)
+	flags.Int(
+		"fold",
+		0,
+		"Wrap content lines wider than N columns for display (0 means no folding; pretty output on a terminal folds at its width)",
+	)
+	flags.Bool(
+		"strip-ansi",
+		false,
+		"Remove ANSI escape sequences from file content",
+	)
+	flags.Bool(
+		"trim-trailing",
+		false,
+		"Remove trailing whitespace from every line",
+	)
+	flags.Bool(
+		"normalize-crlf",
+		false,
+		"Resolve carriage returns left in lines as a terminal shows progress redraws, keeping the text after the last one",
+	)
+	flags.Bool(
+		"deterministic",
+		false,
+		"Make output byte-identical across machines and working directories",
+	)
+	flags.Bool(
+		"todos",
+		false,
+		"Only show files with TODO-style annotations, keeping the annotated lines, and add an index",
+	)
+	flags.StringSlice(
+		"todo-keywords",
+		catls.DefaultTodoKeywords,
+		"Annotation keywords matched by --todos (whole words, case-sensitive)",
+	)
+	flags.Int(
+		"todo-context",
+		0,
+		"Lines of context to keep around each annotated line with --todos",
+	)
+	flags.String(
+		"blame-since",
+		"",
+		"Only show lines git blame attributes to changes after REF or DATE (YYYY-MM-DD), skipping unchanged files",
+	)
+	flags.Int(
+		"blame-context",
+		0,
+		"Lines of context to keep around each changed line with --blame-since",
+	)
+	flags.Bool(
+		"front-matter-only",
+		false,
+		"Show only the YAML (---) or TOML (+++) front matter of each file, skipping files without one",
+	)
+	flags.Bool(
+		"strip-front-matter",
+		false,
+		"Remove the YAML (---) or TOML (+++) front matter from each file, showing only the body",
+	)
+	flags.Bool(
+		"cross-link",
+		false,
+		"Link mentions of other included files' paths to their sections (Markdown and catls serve)",
+	)
+	flags.Bool(
+		"project-header",
+		false,
+		"Open the output with the project's name, from go.mod, package.json, Cargo.toml, or pyproject.toml, and its language mix",
+	)
+	flags.Bool(
+		"no-config-echo",
+		false,
+		"Do not record the version and effective settings at the top of xml, json, and markdown output",
+	)
+	flags.Bool(
+		"only-executable",
+		false,
+		"Only include executable files (on Windows: .bat, .cmd, .ps1, .exe)",
+	)
+	flags.Bool(
+		"no-executable",
+		false,
+		"Skip executable files (on Windows: .bat, .cmd, .ps1, .exe)",
+	)
+	flags.StringArray(
+		"format-opt",
+		nil,
+		"Format-specific option as [format:]key=value (can be used multiple times; see 'catls formats')",
+	)
+	flags.String(
+		"embed-images",
+		"",
+		"Embed PNG, JPEG, GIF, WebP, BMP, and ICO files up to MAXSIZE (default "+defaultEmbedImagesSize+") inline in markdown output",
+	)
+	flags.Lookup("embed-images").NoOptDefVal = defaultEmbedImagesSize
+	flags.String(
+		"content-encoding",
+		"",
+		"JSON and XML only: text (default), or base64 to add each file's exact bytes, binary files included",
+	)
+	flags.String(
+		"raw-max-size",
+		"",
+		"Largest file --content-encoding base64 encodes (default 1MB); larger files keep only their lines",
+	)
+	flags.Bool(
+		"pretty-json",
+		false,
+		"Re-indent JSON files before output; line numbers refer to the reformatted text",
+	)
+	flags.Bool(
+		"pretty-yaml",
+		false,
+		"Re-indent YAML files before output; line numbers refer to the reformatted text",
+	)
+	flags.String(
+		"pretty-max-size",
+		"",
+		"Largest file --pretty-json and --pretty-yaml reformat (default 512KB); larger files are shown as they are",
+	)
+	flags.Bool(
+		"signatures",
+		false,
+		"Reduce Go, Python, JavaScript, and TypeScript files to imports, type and function signatures, and doc comments",
+	)
+	flags.String(
+		"signatures-fallback",
+		"",
+		"What --signatures writes for other languages: full (default) or head for their first 20 lines",
+	)
+	flags.Bool(
+		"summarize-lockfiles",
+		false,
+		"Write a summary of known lockfiles (dependency count, direct dependencies, size, hash) instead of their content",
+	)
+	flags.Bool(
+		"xattrs",
+		false,
+		"Record the names of each file's extended attributes in json and xml output and the manifest (Linux and macOS)",
+	)
+	flags.Int(
+		"chunk-lines",
+		0,
+		"Chunks format only: lines per chunk (default 120)",
+	)
+	flags.Int(
+		"chunk-overlap",
+		0,
+		"Chunks format only: lines each chunk repeats from the end of the one before",
+	)
+	flags.Bool(
+		"legacy-truncation",
+		false,
+		"Write the \"(N more lines)\" notice inside file content as older releases did",
+	)
+	flags.Bool(
+		"fail-on-case-collision",
+		false,
+		"Exit with status 3 if selected paths differ only by case",
+	)
+	flags.Bool(
+		"strict-snapshot",
+		false,
+		"Mark files that changed between the scan and the read as modified during the run",
+	)
+	flags.StringSlice(
+		"fail-fast",
+		nil,
+		"Stop with status 6 at the first file that cannot be read for one of these reasons: "+
+			strings.Join(catls.ErrorCategories(), ", ")+" (default all)",
+	)
+	flags.Lookup("fail-fast").NoOptDefVal = failFastAll
+	flags.Int(
+		"max-files",
+		defaultMaxFiles,
+		"Abort when more than N files are found (0 means no limit)",
+	)
+	flags.Int(
+		"max-depth",
+		defaultMaxDepth,
+		"Do not descend more than N directories below the scanned one, with a warning (0 means no limit)",
+	)
+	flags.Int(
+		"max-files-per-dir",
+		0,
+		"Write at most N files from each directory, noting how many were left out (0 means no limit)",
+	)
+	flags.Int(
+		"max-open-files",
+		fdlimit.DefaultMaxOpen,
+		"Keep at most N files open for reading at once",
+	)
+	flags.Int(
+		"max-tokens",
+		0,
+		"Leave out files once their estimated tokens (bytes/4) would exceed N (0 means no limit)",
+	)
+	flags.StringArray(
+		"budget",
+		nil,
+		"Cap the total size of included files matching TARGET, as TARGET=SIZE: a glob ('*.md=200K') or a directory ('docs/=1M'); exits with status 4 when exceeded (repeatable)",
+	)
+	flags.Bool(
+		"budget-warn-only",
+		false,
+		"Report exceeded --budget rules without failing",
+	)
+	flags.String(
+		"allowlist",
+		"",
+		"Fail with status 5, listing them, when included files match none of the globs in FILE (one per line)",
+	)
+	flags.Bool(
+		"allowlist-warn",
+		false,
+		"Leave out files --allowlist does not match, with a warning, instead of failing",
+	)
+	flags.String(
+		"terminal-warn-size",
+		defaultTerminalWarnSize,
+		"Ask before printing more than SIZE of text files to a terminal (0 never asks)",
+	)
+	flags.BoolP(
+		"yes",
+		"y",
+		false,
+		"Print large output to a terminal without asking",
+	)
+	flags.Bool(
+		"force",
+		false,
+		"Scan the home directory, a filesystem root, or a very wide directory recursively without asking",
+	)
+	flags.Bool(
+		"progress",
+		false,
+		"Show a count of files found and processed on stderr when output is not a terminal",
+	)
+	flags.Bool(
+		"include-dirs",
+		false,
+		"Output a record for each traversed directory, including empty ones",
+	)
 	flags.StringSlice(
 		"globs",
 		nil,
-		"Only include files matching glob pattern (can be used multiple times)",
+		"Only include files matching glob pattern; braces expand, as in '*.{go,md}' (can be used multiple times)",
 	)
 	flags.StringSlice(
 		"ignore-globs",
 		nil,
 		"Ignore files matching glob pattern (can be used multiple times)",
 	)
+	flags.StringArray(
+		"path-regex",
+		nil,
+		"Only include files whose relative path matches this regular expression (can be used multiple times)",
+	)
+	flags.StringArray(
+		"exclude-path-regex",
+		nil,
+		"Exclude files whose relative path matches this regular expression (can be used multiple times)",
+	)
 	flags.String(
-		"pattern",
+		"ignore-cmd",
 		"",
-		"Only show lines matching glob PATTERN",
+		"Run CMD with the selected paths, NUL-separated, on stdin and leave out the paths it prints",
+	)
+	flags.Bool(
+		"no-ignore-file",
+		false,
+		"Do not read ignore globs from .catlsignore in the directory",
+	)
+	flags.Bool(
+		"gitignore",
+		false,
+		"Skip what git ignores: .gitignore files, .git/info/exclude, and the global excludes file (core.excludesFile)",
+	)
+	flags.Bool(
+		"gitignore-verify",
+		false,
+		"With --gitignore, print each skipped path to stderr after the ignore file, line, and pattern that matched, like git check-ignore -v",
+	)
+	flags.StringSlice(
+		"type",
+		nil,
+		"Only include files of detected type TYPE, or 'unknown' (can be used multiple times)",
+	)
+	flags.StringSlice(
+		"exclude-type",
+		nil,
+		"Skip files of detected type TYPE, or 'unknown' (can be used multiple times)",
+	)
+	flags.StringSlice(
+		"imports",
+		nil,
+		"Only include Go files importing PATH, matched as a full import path or its trailing part (can be used multiple times)",
+	)
+	flags.Bool(
+		"imports-include-others",
+		false,
+		"With --imports, keep files that are not Go files",
+	)
+	flags.StringArray(
+		"lang-map",
+		nil,
+		"Treat files with extension EXT as type LANG, as EXT=LANG (can be used multiple times)",
+	)
+	flags.Bool(
+		"no-directives",
+		false,
+		"Ignore catls:lang=NAME directives in the first three lines of files",
+	)
+	flags.StringArray(
+		"pattern",
+		nil,
+		"Only show lines matching glob PATTERN, or regex with a re: prefix (can be used multiple times; any may match)",
+	)
+	flags.Bool(
+		"pattern-all",
+		false,
+		"Only include files in which every --pattern matches at least one line",
+	)
+	flags.Int(
+		"max-matches",
+		0,
+		"Keep at most N lines matching --pattern per file, then stop reading it with a note (0 = no limit)",
 	)
 	flags.BoolP(
 		"line-numbers",
@@ -74,11 +471,36 @@ func setupFlags() {
 		false,
 		"Show line numbers",
 	)
+	flags.String(
+		"line-number-format",
+		"pipe",
+		"Line number gutter style: pipe, colon, tab, padded",
+	)
 	flags.Bool(
 		"debug",
 		false,
 		"Enable debug output",
 	)
+	flags.String(
+		"explain",
+		"",
+		"Explain step by step whether the file at PATH would be output, instead of printing anything",
+	)
+	flags.Bool(
+		"profile",
+		false,
+		"Print per-stage timings and the slowest files of each stage to stderr",
+	)
+	flags.String(
+		"profile-json",
+		"",
+		"Write raw per-file stage timings as JSON to FILE",
+	)
+	flags.Bool(
+		"history",
+		false,
+		"Record this run in the local history for 'catls history' and 'catls rerun'",
+	)
 	flags.BoolP(
 		"interactive",
 		"I",
@@ -91,24 +513,134 @@ func setupFlags() {
 		false,
 		"Launch a TUI to manually reorder the file list before output",
 	)
+	flags.String(
+		"sort",
+		string(catls.SortName),
+		"File order: name (by path bytes) or natural (numbers by value, case-insensitive)",
+	)
 	flags.Bool(
 		"omit-bins",
 		false,
 		"Skip binary files in output",
 	)
+	flags.Bool(
+		"skip-empty",
+		false,
+		"Skip empty and whitespace-only files",
+	)
+	flags.Bool(
+		"dedupe-content",
+		false,
+		"Write the content of byte-identical files once and refer to the first copy from the others",
+	)
+	flags.String(
+		"detect-cache",
+		"",
+		"Path of the binary/type detection cache, which records the absolute paths of files read (default: user cache dir)",
+	)
+	flags.Bool(
+		"no-detect-cache",
+		false,
+		"Do not read or write the detection cache",
+	)
+	flags.Bool(
+		"readme-first",
+		false,
+		"Place each directory's README before its other files, rendered as documentation",
+	)
+	flags.Int(
+		"readme-lines",
+		0,
+		"Limit READMEs placed by --readme-first to N lines (0 means no limit)",
+	)
 	flags.StringP(
 		"format",
 		"f",
 		"xml",
-		"Output format: xml, json, markdown",
+		"Output format: xml, json, markdown, prompt, pretty, chunks (run 'catls formats' for details); several, comma-separated, with --output-dir",
+	)
+	flags.String(
+		"schema",
+		"",
+		"Print the JSON Schema of the --format output and exit; NAME picks one of "+
+			strings.Join(catls.GetSchemaNames(), ", ")+" instead",
+	)
+	flags.Lookup("schema").NoOptDefVal = schemaOfFormat
+	flags.String(
+		"output-dir",
+		"",
+		"Write each format to DIR/out-<format>.<ext> instead of stdout",
+	)
+	flags.String(
+		"output-template",
+		"",
+		"With --output-dir, write each file to its own document named by this Go template, such as '{{.RelPath}}.md', and list them in "+catls.OutputIndexName,
+	)
+	flags.String(
+		"throttle",
+		"",
+		"Write stdout output at no more than RATE, such as 200K/s, in small chunks",
+	)
+	flags.Bool(
+		"integrity",
+		false,
+		"End the output with the files and lines written and a SHA-256 of everything before it, for 'catls check' (xml, json, markdown, prompt)",
+	)
+	flags.String(
+		"color",
+		colorAuto,
+		"Color pretty output: auto (when stdout is a terminal and NO_COLOR is unset), always, never",
+	)
+	flags.String(
+		"theme",
+		"",
+		"Highlighting theme for pretty output (default "+catls.DefaultTheme+", or "+catls.LightTheme+" on light terminals)",
+	)
+	flags.String(
+		"tui-theme",
+		string(interactive.ThemeAuto),
+		"Colors of --interactive and --order: auto, dark, light, mono (bold and underline only)",
+	)
+	flags.String(
+		"fence-style",
+		"",
+		"Markdown content delimiter: backtick (default), tilde, indent, none",
+	)
+	flags.String(
+		"sentinel",
+		"",
+		"Markdown line around unfenced content; supports {path}, {type}, {lines}, {edge}",
+	)
+	flags.String(
+		"manifest",
+		"",
+		"After the run, write the written files' hashes and the selection flags to this JSON file for 'catls verify'",
+	)
+	flags.Bool(
+		"list",
+		false,
+		"Print only the paths of the selected files, one per line, instead of their contents",
+	)
+	flags.Bool(
+		"print0",
+		false,
+		"End each path printed by --list with a NUL byte instead of a newline, for xargs -0",
 	)
 	flags.String(
 		"relative-to",
 		"",
-		"Display paths relative to this directory (default: scan directory)",
+		"Display paths relative to this directory, or 'git' for the root of the enclosing git work tree (default: scan directory)",
 	)
 }
 
+// defaultMaxFiles is large enough for any reasonable source tree while still
+// stopping runaway scans of caches and package stores.
+const defaultMaxFiles = 100_000
+
+// defaultMaxDepth is deeper than any tree written by hand, so only generated
+// trees nested without bound stop at it.
+const defaultMaxDepth = 256
+
 func defaultIgnoreDirs() []string {
 	return []string{
 		"node_modules",
@@ -127,15 +659,54 @@ func defaultIgnoreDirs() []string {
 }
 
 func runCatls(cmd *cobra.Command, args []string) error {
+	if cmd.Flags().Changed("schema") {
+		return writeSchema(cmd, args)
+	}
+
 	cfg, err := buildConfig(cmd, args)
 	if err != nil {
 		return err
 	}
+	recorder, err := newHistoryRecorder(cmd, args, cfg)
+	if err != nil {
+		return err
+	}
+
+	return runConfig(cmd, cfg, recorder)
+}
+
+// runConfig runs catls with cfg, asking on the terminal before large output
+// and broad scans. A run that succeeds is added to the history by recorder.
+func runConfig(cmd *cobra.Command, cfg *catls.Config, recorder *historyRecorder) error {
+	assumeYes, _ := cmd.Flags().GetBool("yes")
+	cfg.ConfirmLargeOutput = terminalConfirm(assumeYes)
+	cfg.ConfirmBroadScan = terminalConfirmScan()
+	showProgress, _ := cmd.Flags().GetBool("progress")
+	cfg.Events = terminalProgress(showProgress)
+	applyDefaultTheme(cfg)
+	applyDefaultFold(cmd, cfg)
 
 	ctx := context.Background()
-	app := catls.New(cfg)
+	app, err := catls.New(cfg)
+	if err != nil {
+		return err
+	}
+
+	if path, _ := cmd.Flags().GetString("explain"); path != "" {
+		explanation, err := app.Explain(path)
+		if err != nil {
+			return err
+		}
 
-	return app.Run(ctx)
+		return catls.WriteExplanation(cmd.OutOrStdout(), explanation)
+	}
+
+	if err := app.Run(ctx); err != nil {
+		return err
+	}
+	recorder.record(app.Stats())
+
+	return nil
 }
 
 func buildConfig(cmd *cobra.Command, args []string) (*catls.Config, error) {
@@ -145,31 +716,416 @@ func buildConfig(cmd *cobra.Command, args []string) (*catls.Config, error) {
 		Directory: ".",
 	}
 
-	if len(args) > 0 {
+	var argGlobs []string // Include globs given as a quoted glob argument
+	switch {
+	case len(args) > 0 && isFileArg(args[0]):
+		dir, paths, err := fileArgs(args)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Directory, cfg.Paths = dir, paths
+	case len(args) > 0 && isGlobArg(args[0]):
+		dir, globs, err := globArgs(args)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Directory, argGlobs = dir, globs
+	case len(args) > 0:
 		cfg.Directory = args[0]
 		cfg.Files = args[1:]
 	}
 
 	cfg.ShowAll, _ = flags.GetBool("all")
 	cfg.Recursive, _ = flags.GetBool("recursive")
+	cfg.Force, _ = flags.GetBool("force")
+	cfg.OneFileSystem, _ = flags.GetBool("one-file-system")
+	cfg.SkipGitSubmodules, _ = flags.GetBool("skip-git-submodules")
+	cfg.FollowSymlinks, _ = flags.GetBool("follow-symlinks")
+	cfg.IncludeDirs, _ = flags.GetBool("include-dirs")
+	cfg.MaxFiles, _ = flags.GetInt("max-files")
+	cfg.MaxDepth, _ = flags.GetInt("max-depth")
+	cfg.MaxFilesPerDir, _ = flags.GetInt("max-files-per-dir")
+	if err := applyMaxOpenFiles(flags); err != nil {
+		return nil, err
+	}
+	cfg.MaxTokens, _ = flags.GetInt("max-tokens")
+	budgets, _ := flags.GetStringArray("budget")
+	for _, rule := range budgets {
+		budget, err := parseSizeBudget(rule)
+		if err != nil {
+			return nil, err
+		}
+		cfg.SizeBudgets = append(cfg.SizeBudgets, budget)
+	}
+	cfg.BudgetWarnOnly, _ = flags.GetBool("budget-warn-only")
+	if path, _ := flags.GetString("allowlist"); path != "" {
+		allowlist, err := catls.ReadAllowlist(path)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Allowlist = allowlist
+	}
+	cfg.AllowlistWarn, _ = flags.GetBool("allowlist-warn")
+	cfg.FailOnCaseCollision, _ = flags.GetBool("fail-on-case-collision")
+	cfg.StrictSnapshot, _ = flags.GetBool("strict-snapshot")
+	failFast, _ := flags.GetStringSlice("fail-fast")
+	cfg.FailFast = parseFailFast(failFast)
+	cfg.LegacyTruncation, _ = flags.GetBool("legacy-truncation")
+	cfg.ChunkLines, _ = flags.GetInt("chunk-lines")
+	cfg.ChunkOverlap, _ = flags.GetInt("chunk-overlap")
+	cfg.OnlyExecutable, _ = flags.GetBool("only-executable")
+	cfg.NoExecutable, _ = flags.GetBool("no-executable")
+	formatOpts, _ := flags.GetStringArray("format-opt")
+	parsedOpts, err := parseFormatOpts(formatOpts)
+	if err != nil {
+		return nil, err
+	}
+	cfg.FormatOptions = parsedOpts
+	if size, _ := flags.GetString("embed-images"); size != "" {
+		maxSize, err := parseByteSize(size)
+		if err != nil {
+			return nil, fmt.Errorf("--embed-images: %w", err)
+		}
+		cfg.EmbedImages = maxSize
+	}
+	encoding, _ := flags.GetString("content-encoding")
+	cfg.ContentEncoding = catls.ContentEncoding(encoding)
+	if size, _ := flags.GetString("raw-max-size"); size != "" {
+		if cfg.RawContentMax, err = parseByteSize(size); err != nil {
+			return nil, fmt.Errorf("--raw-max-size: %w", err)
+		}
+	}
+	cfg.PrettyJSON, _ = flags.GetBool("pretty-json")
+	cfg.PrettyYAML, _ = flags.GetBool("pretty-yaml")
+	cfg.Signatures, _ = flags.GetBool("signatures")
+	fallback, _ := flags.GetString("signatures-fallback")
+	cfg.SignaturesFallback = catls.SignaturesFallback(fallback)
+	cfg.SummarizeLockfiles, _ = flags.GetBool("summarize-lockfiles")
+	cfg.Xattrs, _ = flags.GetBool("xattrs")
+	if size, _ := flags.GetString("pretty-max-size"); size != "" {
+		if cfg.PrettyMaxSize, err = parseByteSize(size); err != nil {
+			return nil, fmt.Errorf("--pretty-max-size: %w", err)
+		}
+	}
+	warnSize, _ := flags.GetString("terminal-warn-size")
+	if cfg.LargeOutputSize, err = parseByteSize(warnSize); err != nil {
+		return nil, fmt.Errorf("--terminal-warn-size: %w", err)
+	}
+	cfg.ExpandTabs, _ = flags.GetInt("expand-tabs")
+	cfg.Fold, _ = flags.GetInt("fold")
+	cfg.StripANSI, _ = flags.GetBool("strip-ansi")
+	cfg.TrimTrailing, _ = flags.GetBool("trim-trailing")
+	cfg.NormalizeCRLF, _ = flags.GetBool("normalize-crlf")
+	cfg.Deterministic, _ = flags.GetBool("deterministic")
+	cfg.Todos, _ = flags.GetBool("todos")
+	cfg.TodoKeywords, _ = flags.GetStringSlice("todo-keywords")
+	cfg.TodoContext, _ = flags.GetInt("todo-context")
+	cfg.BlameSince, _ = flags.GetString("blame-since")
+	cfg.BlameContext, _ = flags.GetInt("blame-context")
+	cfg.FrontMatterOnly, _ = flags.GetBool("front-matter-only")
+	cfg.StripFrontMatter, _ = flags.GetBool("strip-front-matter")
+	cfg.CrossLink, _ = flags.GetBool("cross-link")
+	cfg.ProjectHeader, _ = flags.GetBool("project-header")
+	noConfigEcho, _ := flags.GetBool("no-config-echo")
+	cfg.ConfigEcho = !noConfigEcho
 	cfg.Debug, _ = flags.GetBool("debug")
+	cfg.Profile, _ = flags.GetBool("profile")
+	cfg.ProfileJSON, _ = flags.GetString("profile-json")
 	cfg.Interactive, _ = flags.GetBool("interactive")
 	cfg.Order, _ = flags.GetBool("order")
+	sortOrder, _ := flags.GetString("sort")
+	cfg.Sort = catls.SortOrder(sortOrder)
 	cfg.ShowLineNumbers, _ = flags.GetBool("line-numbers")
 	cfg.OmitBins, _ = flags.GetBool("omit-bins")
-	cfg.ContentPattern, _ = flags.GetString("pattern")
+	cfg.SkipEmpty, _ = flags.GetBool("skip-empty")
+	cfg.DedupeContent, _ = flags.GetBool("dedupe-content")
+	cfg.ReadmeFirst, _ = flags.GetBool("readme-first")
+	cfg.ReadmeLines, _ = flags.GetInt("readme-lines")
+	cfg.ContentPatterns, _ = flags.GetStringArray("pattern")
+	cfg.PatternAll, _ = flags.GetBool("pattern-all")
+	cfg.MaxMatches, _ = flags.GetInt("max-matches")
 	cfg.RelativeTo, _ = flags.GetString("relative-to")
 	cfg.IgnoreDir, _ = flags.GetStringSlice("ignore-dir")
-	cfg.Globs, _ = flags.GetStringSlice("globs")
-	cfg.IgnoreGlobs, _ = flags.GetStringSlice("ignore-globs")
+	globs, _ := flags.GetStringSlice("globs")
+	cfg.Globs = append(joinBraceSplits(globs), argGlobs...)
+	ignoreGlobs, _ := flags.GetStringSlice("ignore-globs")
+	cfg.IgnoreGlobs = joinBraceSplits(ignoreGlobs)
+	cfg.PathRegex, _ = flags.GetStringArray("path-regex")
+	cfg.ExcludePathRegex, _ = flags.GetStringArray("exclude-path-regex")
+	cfg.GitIgnore, _ = flags.GetBool("gitignore")
+	cfg.GitIgnoreVerify, _ = flags.GetBool("gitignore-verify")
+	cfg.IgnoreCmd, _ = flags.GetString("ignore-cmd")
+	cfg.AllowIgnoreCmd = cfg.IgnoreCmd != ""
+	if err := applyIgnoreFile(cfg, flags); err != nil {
+		return nil, err
+	}
+	cfg.Types, _ = flags.GetStringSlice("type")
+	cfg.ExcludeTypes, _ = flags.GetStringSlice("exclude-type")
+	cfg.Imports, _ = flags.GetStringSlice("imports")
+	cfg.ImportsIncludeOthers, _ = flags.GetBool("imports-include-others")
+	cfg.NoDirectives, _ = flags.GetBool("no-directives")
+	langMap, _ := flags.GetStringArray("lang-map")
+	if cfg.LangMap, err = parseLangMap(langMap); err != nil {
+		return nil, err
+	}
+
+	applyDetectCacheFlags(cfg, flags)
+
+	lineFormatStr, _ := flags.GetString("line-number-format")
+	cfg.LineNumberFormat = catls.LineNumberFormat(lineFormatStr)
 
-	// Handle output format
 	formatStr, _ := flags.GetString("format")
-	cfg.OutputFormat = catls.OutputFormat(formatStr)
-	if !cfg.OutputFormat.IsValid() {
-		return nil, fmt.Errorf("unsupported output format: %s (supported: %s)",
-			formatStr, strings.Join(catls.GetSupportedFormats(), ", "))
+	cfg.OutputDir, _ = flags.GetString("output-dir")
+	cfg.OutputTemplate, _ = flags.GetString("output-template")
+	if rate, _ := flags.GetString("throttle"); rate != "" {
+		if cfg.Throttle, err = parseByteRate(rate); err != nil {
+			return nil, fmt.Errorf("--throttle: %w", err)
+		}
+	}
+	cfg.Integrity, _ = flags.GetBool("integrity")
+	cfg.ManifestPath, _ = flags.GetString("manifest")
+	cfg.OutputFormat, cfg.OutputFormats = parseFormats(formatStr)
+
+	fenceStr, _ := flags.GetString("fence-style")
+	cfg.FenceStyle = catls.FenceStyle(fenceStr)
+	cfg.Sentinel, _ = flags.GetString("sentinel")
+
+	colorMode, _ := flags.GetString("color")
+	if cfg.Color, err = resolveColor(colorMode); err != nil {
+		return nil, err
+	}
+	if cfg.OutputDir != "" && colorMode == colorAuto {
+		// Files in the output directory are not a terminal
+		cfg.Color = false
+	}
+	cfg.Theme, _ = flags.GetString("theme")
+	tuiTheme, _ := flags.GetString("tui-theme")
+	cfg.TUITheme = interactive.Theme(tuiTheme)
+
+	cfg.List, _ = flags.GetBool("list")
+	cfg.Print0, _ = flags.GetBool("print0")
+	if err := checkListFlags(cfg, flags); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
 	}
 
 	return cfg, nil
 }
+
+// checkListFlags rejects --format, --color and --line-number-format with
+// --list. All have defaults, so only the command line tells whether they were
+// given.
+func checkListFlags(cfg *catls.Config, flags *pflag.FlagSet) error {
+	if !cfg.List {
+		return nil
+	}
+
+	var conflicts []string
+	for _, name := range []string{"format", "color", "line-number-format"} {
+		if flags.Changed(name) {
+			conflicts = append(conflicts, "--"+name)
+		}
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("--list writes only paths and cannot be combined with %s", strings.Join(conflicts, ", "))
+}
+
+// parseFormatOpts splits --format-opt values into a map keyed by everything
+// before the first "=". Later values for the same key win; a prefixed key
+// and a bare one are different keys, which catls.ParseFormatOptions orders.
+func parseFormatOpts(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	opts := make(map[string]string, len(values))
+	for _, value := range values {
+		key, optValue, found := strings.Cut(value, "=")
+		if !found || key == "" {
+			return nil, fmt.Errorf("--format-opt expects [format:]key=value, got %q", value)
+		}
+		opts[key] = optValue
+	}
+
+	return opts, nil
+}
+
+// parseFormats splits a comma-separated --format value. The first format is
+// the primary one; all of them are returned only when there are several.
+func parseFormats(value string) (catls.OutputFormat, []catls.OutputFormat) {
+	names := strings.Split(value, ",")
+	formats := make([]catls.OutputFormat, len(names))
+	for i, name := range names {
+		formats[i] = catls.OutputFormat(strings.TrimSpace(name))
+	}
+
+	if len(formats) == 1 {
+		return formats[0], nil
+	}
+
+	return formats[0], formats
+}
+
+// joinBraceSplits rejoins glob patterns that the comma-separated slice flags
+// split inside braces, so --globs '*.{go,md}' arrives as one pattern rather
+// than "*.{go" and "md}".
+func joinBraceSplits(values []string) []string {
+	var joined []string
+	depth := 0
+	for _, value := range values {
+		if depth > 0 {
+			joined[len(joined)-1] += "," + value
+		} else {
+			joined = append(joined, value)
+		}
+		depth = max(depth+strings.Count(value, "{")-strings.Count(value, "}"), 0)
+	}
+
+	return joined
+}
+
+// parseLangMap splits --lang-map values into a map from lowercase extension,
+// with any leading dot removed, to lowercase type. Later values for an
+// extension win.
+func parseLangMap(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	langMap := make(map[string]string, len(values))
+	for _, value := range values {
+		ext, lang, found := strings.Cut(value, "=")
+		if !found {
+			return nil, fmt.Errorf("--lang-map expects ext=lang, got %q", value)
+		}
+		langMap[strings.ToLower(strings.TrimPrefix(ext, "."))] = strings.ToLower(lang)
+	}
+
+	return langMap, nil
+}
+
+// prettyGutterWidth is the width of the usual line number gutter: four
+// digits and the "| " separator.
+const prettyGutterWidth = 6
+
+// applyDefaultFold folds pretty output written to a terminal at the
+// terminal's width, less the line number gutter, when --fold is not given.
+func applyDefaultFold(cmd *cobra.Command, cfg *catls.Config) {
+	if cmd.Flags().Changed("fold") || cfg.OutputFormat != catls.OutputFormatPretty || cfg.OutputDir != "" {
+		return
+	}
+	width, _, err := term.GetSize(os.Stdout.Fd())
+	if err != nil || width <= 0 {
+		return
+	}
+	if cfg.ShowLineNumbers {
+		width -= prettyGutterWidth
+	}
+	cfg.Fold = max(width, 1)
+}
+
+// schemaOfFormat is the --schema value when given without one, selecting
+// the schema of the --format output.
+const schemaOfFormat = "format"
+
+// writeSchema prints the JSON Schema --schema names, or that of the single
+// format --format selects. The name must be attached, as --schema=NAME, so
+// a word after a bare --schema is an argument, which it rejects.
+func writeSchema(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("--schema takes no arguments; to name a schema, write --schema=%s", args[0])
+	}
+	name, _ := cmd.Flags().GetString("schema")
+	if name == schemaOfFormat {
+		formatStr, _ := cmd.Flags().GetString("format")
+		format, formats := parseFormats(formatStr)
+		if len(formats) > 1 {
+			return errors.New("--schema describes one format; give it a NAME or a single --format")
+		}
+		name = string(format)
+	}
+
+	schema, err := catls.Schema(name)
+	if err != nil {
+		return err
+	}
+	_, err = cmd.OutOrStdout().Write(schema)
+
+	return err
+}
+
+// failFastAll is the --fail-fast value selecting every error category, and
+// its value when given without one.
+const failFastAll = "all"
+
+// parseFailFast converts --fail-fast values to error categories, expanding
+// "all". Unknown categories are kept for Validate to reject.
+func parseFailFast(values []string) []catls.ErrorCategory {
+	var categories []catls.ErrorCategory
+	for _, value := range values {
+		names := []string{value}
+		if value == failFastAll {
+			names = catls.ErrorCategories()
+		}
+		for _, name := range names {
+			categories = append(categories, catls.ErrorCategory(name))
+		}
+	}
+
+	return categories
+}
+
+// applyIgnoreFile adds the globs of the directory's .catlsignore, if it has
+// one, to the ignore globs unless --no-ignore-file is set.
+func applyIgnoreFile(cfg *catls.Config, flags *pflag.FlagSet) error {
+	if disabled, _ := flags.GetBool("no-ignore-file"); disabled {
+		return nil
+	}
+
+	patterns, err := catls.ReadIgnoreFile(filepath.Join(cfg.Directory, catls.IgnoreFileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	cfg.IgnoreGlobs = append(cfg.IgnoreGlobs, patterns...)
+
+	return nil
+}
+
+// applyMaxOpenFiles bounds the files open at once across the process with
+// --max-open-files. The bound is process-wide, so it is set here, once per
+// command, rather than by each App.
+func applyMaxOpenFiles(flags *pflag.FlagSet) error {
+	limit, _ := flags.GetInt("max-open-files")
+	if limit < 0 {
+		return fmt.Errorf("--max-open-files must not be negative, got %d", limit)
+	}
+	fdlimit.SetMaxOpen(limit)
+
+	return nil
+}
+
+// applyDetectCacheFlags resolves where detection results are persisted. An
+// unavailable user cache directory silently falls back to per-run memoization.
+func applyDetectCacheFlags(cfg *catls.Config, flags *pflag.FlagSet) {
+	if disabled, _ := flags.GetBool("no-detect-cache"); disabled {
+		return
+	}
+
+	cfg.DetectCachePath, _ = flags.GetString("detect-cache")
+	if cfg.DetectCachePath != "" {
+		return
+	}
+
+	if path, err := scanner.DefaultDetectionCachePath(); err == nil {
+		cfg.DetectCachePath = path
+	}
+}
@@ -1,8 +1,19 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 
+	"github.com/connerohnesorge/catls/internal/catls"
+	"github.com/connerohnesorge/catls/internal/fdlimit"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 )
@@ -14,20 +25,127 @@ func createTestFlags() *pflag.FlagSet {
 	flags.BoolP("all", "a", false, "Include hidden files")
 	flags.BoolP("recursive", "r", false, "Recursively list files in subdirectories")
 	flags.StringSlice("ignore-dir", defaultIgnoreDirs(), "Ignore directory DIR")
+	flags.Bool("one-file-system", false, "Do not cross filesystem boundaries")
+	flags.Bool("skip-git-submodules", false, "Do not recurse into git submodules")
+	flags.Bool("follow-symlinks", false, "Descend into symlinked directories")
+	flags.Int("expand-tabs", 0, "Replace tabs with spaces")
+	flags.Int("fold", 0, "Wrap content lines wider than N columns")
+	flags.Bool("strip-ansi", false, "Remove ANSI escape sequences")
+	flags.Bool("trim-trailing", false, "Remove trailing whitespace")
+	flags.Bool("normalize-crlf", false, "Remove carriage returns")
+	flags.Bool("deterministic", false, "Make output byte-identical across machines")
+	flags.Bool("todos", false, "Only show files with annotations")
+	flags.StringSlice("todo-keywords", catls.DefaultTodoKeywords, "Annotation keywords matched by --todos")
+	flags.Int("todo-context", 0, "Lines of context around annotated lines")
+	flags.String("blame-since", "", "Only show lines changed after REF or DATE")
+	flags.Int("blame-context", 0, "Lines of context around changed lines")
+	flags.Bool("front-matter-only", false, "Show only the front matter of each file")
+	flags.Bool("strip-front-matter", false, "Remove the front matter from each file")
+	flags.Bool("cross-link", false, "Link mentions of other included files")
+	flags.Bool("no-config-echo", false, "Do not record the settings at the top of the output")
+	flags.Bool("project-header", false, "Open the output with the project's name and language mix")
+	flags.Bool("legacy-truncation", false, "Write the truncation notice inside file content")
+	flags.Int("chunk-lines", 0, "Chunks format only: lines per chunk")
+	flags.Int("chunk-overlap", 0, "Chunks format only: lines of overlap")
+	flags.Bool("only-executable", false, "Only include executable files")
+	flags.Bool("no-executable", false, "Skip executable files")
+	flags.StringArray("format-opt", nil, "Format-specific option as [format:]key=value")
+	flags.String("embed-images", "", "Embed small images inline in markdown output")
+	flags.Lookup("embed-images").NoOptDefVal = defaultEmbedImagesSize
+	flags.String("content-encoding", "", "JSON and XML only: text or base64")
+	flags.String("raw-max-size", "", "Largest file --content-encoding base64 encodes")
+	flags.Bool("pretty-json", false, "Re-indent JSON files")
+	flags.Bool("pretty-yaml", false, "Re-indent YAML files")
+	flags.String("pretty-max-size", "", "Largest file --pretty-json and --pretty-yaml reformat")
+	flags.Bool("signatures", false, "Reduce source files to their signatures")
+	flags.String("signatures-fallback", "", "What --signatures writes for other languages")
+	flags.Bool("summarize-lockfiles", false, "Summarize known lockfiles instead of writing their content")
+	flags.Bool("xattrs", false, "Record extended attribute names")
+	flags.Bool("fail-on-case-collision", false, "Exit with status 3 if paths differ only by case")
+	flags.Bool("strict-snapshot", false, "Mark files changed during the run")
+	flags.StringSlice("fail-fast", nil, "Stop at the first unreadable file of these categories")
+	flags.Lookup("fail-fast").NoOptDefVal = failFastAll
+	flags.Int("max-files", defaultMaxFiles, "Abort when more than N files are found")
+	flags.Int("max-depth", defaultMaxDepth, "Do not descend more than N directories deep")
+	flags.Int("max-files-per-dir", 0, "Write at most N files from each directory")
+	flags.Int("max-open-files", fdlimit.DefaultMaxOpen, "Keep at most N files open at once")
+	flags.Int("max-tokens", 0, "Leave out files over the token budget")
+	flags.StringArray("budget", nil, "Cap the total size of included files matching TARGET")
+	flags.Bool("budget-warn-only", false, "Report exceeded --budget rules without failing")
+	flags.String("allowlist", "", "Fail when included files match none of the globs in FILE")
+	flags.Bool("allowlist-warn", false, "Leave out files --allowlist does not match")
+	flags.String("terminal-warn-size", defaultTerminalWarnSize, "Ask before printing large output to a terminal")
+	flags.BoolP("yes", "y", false, "Print large output without asking")
+	flags.Bool("force", false, "Scan broad directories without asking")
+	flags.Bool("progress", false, "Show a count of files found and processed on stderr")
+	flags.Bool("include-dirs", false, "Output a record for each traversed directory")
 	flags.StringSlice("globs", nil, "Only include files matching glob pattern")
 	flags.StringSlice("ignore-globs", nil, "Ignore files matching glob pattern")
-	flags.String("pattern", "", "Only show lines matching glob PATTERN")
+	flags.StringArray("path-regex", nil, "Only include files whose path matches")
+	flags.StringArray("exclude-path-regex", nil, "Exclude files whose path matches")
+	flags.String("ignore-cmd", "", "Leave out the paths CMD prints")
+	flags.Bool("no-ignore-file", false, "Do not read .catlsignore")
+	flags.Bool("gitignore", false, "Skip what git ignores")
+	flags.Bool("gitignore-verify", false, "Print each path --gitignore skips with the pattern that matched")
+	flags.StringSlice("type", nil, "Only include files of detected type")
+	flags.StringSlice("exclude-type", nil, "Skip files of detected type")
+	flags.StringSlice("imports", nil, "Only include Go files importing PATH")
+	flags.Bool("imports-include-others", false, "With --imports, keep files that are not Go files")
+	flags.StringArray("lang-map", nil, "Treat files with extension EXT as type LANG")
+	flags.Bool("no-directives", false, "Ignore catls:lang=NAME directives")
+	flags.StringArray("pattern", nil, "Only show lines matching glob PATTERN")
+	flags.Bool("pattern-all", false, "Only include files in which every pattern matches")
+	flags.Int("max-matches", 0, "Matching lines kept per file")
 	flags.BoolP("line-numbers", "n", false, "Show line numbers")
+	flags.String("line-number-format", "pipe", "Line number gutter style")
 	flags.Bool("debug", false, "Enable debug output")
+	flags.String("explain", "", "Explain whether the file at PATH would be output")
+	flags.Bool("profile", false, "Print per-stage timings to stderr")
+	flags.String("profile-json", "", "Write raw per-file stage timings as JSON")
+	flags.Bool("history", false, "Record this run in the local history")
 	flags.BoolP("interactive", "I", false, "Interactive file selection mode")
 	flags.BoolP("order", "O", false, "Launch a TUI to manually reorder the file list before output")
+	flags.String("sort", "name", "File order: name or natural")
 	flags.Bool("omit-bins", false, "Skip binary files in output")
+	flags.Bool("skip-empty", false, "Skip empty and whitespace-only files")
+	flags.Bool("dedupe-content", false, "Write the content of byte-identical files once")
+	flags.String("detect-cache", "", "Path of the detection cache")
+	flags.Bool("no-detect-cache", false, "Do not read or write the detection cache")
+	flags.Bool("readme-first", false, "Place each directory's README before its other files")
+	flags.Int("readme-lines", 0, "Limit READMEs placed by --readme-first to N lines")
 	flags.StringP("format", "f", "xml", "Output format: xml, json, markdown")
+	flags.String("fence-style", "", "Markdown content delimiter")
+	flags.String("sentinel", "", "Markdown line around unfenced content")
+	flags.String("schema", "", "Print the JSON Schema of the --format output")
+	flags.Lookup("schema").NoOptDefVal = schemaOfFormat
+	flags.String("output-dir", "", "Write each format to a file in DIR")
+	flags.String("output-template", "", "Write each file to its own document named by this template")
+	flags.String("throttle", "", "Write stdout output at no more than RATE")
+	flags.Bool("integrity", false, "End the output with an integrity trailer")
+	flags.String("color", colorAuto, "Color pretty output")
+	flags.String("theme", "", "Highlighting theme for pretty output")
+	flags.String("tui-theme", "auto", "Colors of the TUIs")
+	flags.String("manifest", "", "Write a manifest of the written files")
+	flags.Bool("list", false, "Print only the paths of the selected files")
+	flags.Bool("print0", false, "End each path printed by --list with a NUL byte")
 	flags.String("relative-to", "", "Display paths relative to this directory")
 
 	return flags
 }
 
+// chdirWithDirs switches the test into a fresh temp directory containing dirs.
+func chdirWithDirs(t *testing.T, dirs ...string) {
+	t.Helper()
+
+	tmpDir := t.TempDir()
+	for _, dir := range dirs {
+		if err := os.MkdirAll(filepath.Join(tmpDir, dir), 0o755); err != nil {
+			t.Fatalf("failed to create %s: %v", dir, err)
+		}
+	}
+	t.Chdir(tmpDir)
+}
+
 func TestBuildConfig_RelativeToFlag(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -59,10 +177,10 @@ func TestBuildConfig_RelativeToFlag(t *testing.T) {
 		},
 		{
 			name:           "relative-to with directory arg",
-			args:           []string{"/some/path"},
+			args:           []string{"some/path"},
 			flags:          map[string]string{"relative-to": "/home/user"},
 			wantRelativeTo: "/home/user",
-			wantDirectory:  "/some/path",
+			wantDirectory:  "some/path",
 		},
 		{
 			name:           "relative-to with relative path",
@@ -73,6 +191,9 @@ func TestBuildConfig_RelativeToFlag(t *testing.T) {
 		},
 	}
 
+	// Directory arguments are validated, so they must exist.
+	chdirWithDirs(t, "some/path", "src")
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Create a new command with fresh flags for each test
@@ -158,6 +279,8 @@ func TestBuildConfig_OrderShortFlag(t *testing.T) {
 }
 
 func TestBuildConfig_RelativeToWithOtherFlags(t *testing.T) {
+	chdirWithDirs(t, "src")
+
 	cmd := &cobra.Command{
 		Use: "test",
 	}
@@ -197,3 +320,610 @@ func TestBuildConfig_RelativeToWithOtherFlags(t *testing.T) {
 		t.Errorf("OutputFormat = %v, want json", cfg.OutputFormat)
 	}
 }
+
+func TestBuildConfig_LineNumberFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    catls.LineNumberFormat
+		wantErr bool
+	}{
+		{name: "default is pipe", value: "", want: catls.LineNumberFormatPipe},
+		{name: "colon preset", value: "colon", want: catls.LineNumberFormatColon},
+		{name: "tab preset", value: "tab", want: catls.LineNumberFormatTab},
+		{name: "unknown preset", value: "roman", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{Use: "test"}
+			cmd.Flags().AddFlagSet(createTestFlags())
+
+			if tt.value != "" {
+				if err := cmd.Flags().Set("line-number-format", tt.value); err != nil {
+					t.Fatalf("failed to set flag line-number-format: %v", err)
+				}
+			}
+
+			cfg, err := buildConfig(cmd, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("buildConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if cfg.LineNumberFormat != tt.want {
+				t.Errorf("cfg.LineNumberFormat = %v, want %v", cfg.LineNumberFormat, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildConfig_EmbedImages(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    int64
+		wantErr string
+	}{
+		{name: "off by default", args: []string{"--format", "markdown"}},
+		{name: "bare flag uses default size", args: []string{"--embed-images", "--format", "markdown"}, want: 64 << 10},
+		{name: "explicit bytes", args: []string{"--embed-images=2048", "--format", "markdown"}, want: 2048},
+		{name: "size suffix", args: []string{"--embed-images=1MB", "--format", "markdown"}, want: 1 << 20},
+		{name: "lowercase suffix", args: []string{"--embed-images=8k", "--format", "markdown"}, want: 8 << 10},
+		{name: "invalid size", args: []string{"--embed-images=lots", "--format", "markdown"}, wantErr: `--embed-images: invalid size "lots"`},
+		{name: "requires markdown", args: []string{"--embed-images"}, wantErr: "--embed-images only applies to markdown output"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{Use: "test"}
+			cmd.Flags().AddFlagSet(createTestFlags())
+			if err := cmd.Flags().Parse(tt.args); err != nil {
+				t.Fatalf("failed to parse flags: %v", err)
+			}
+
+			cfg, err := buildConfig(cmd, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("buildConfig() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatalf("buildConfig() unexpected error: %v", err)
+			}
+
+			if cfg.EmbedImages != tt.want {
+				t.Errorf("cfg.EmbedImages = %d, want %d", cfg.EmbedImages, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildConfig_DetectCache(t *testing.T) {
+	t.Run("explicit path", func(t *testing.T) {
+		cmd := &cobra.Command{Use: "test"}
+		cmd.Flags().AddFlagSet(createTestFlags())
+		if err := cmd.Flags().Set("detect-cache", "/tmp/detect.json"); err != nil {
+			t.Fatalf("failed to set flag: %v", err)
+		}
+
+		cfg, err := buildConfig(cmd, nil)
+		if err != nil {
+			t.Fatalf("buildConfig() unexpected error: %v", err)
+		}
+		if cfg.DetectCachePath != "/tmp/detect.json" {
+			t.Errorf("DetectCachePath = %q, want /tmp/detect.json", cfg.DetectCachePath)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		cmd := &cobra.Command{Use: "test"}
+		cmd.Flags().AddFlagSet(createTestFlags())
+		if err := cmd.Flags().Set("no-detect-cache", "true"); err != nil {
+			t.Fatalf("failed to set flag: %v", err)
+		}
+
+		cfg, err := buildConfig(cmd, nil)
+		if err != nil {
+			t.Fatalf("buildConfig() unexpected error: %v", err)
+		}
+		if cfg.DetectCachePath != "" {
+			t.Errorf("DetectCachePath = %q, want empty when disabled", cfg.DetectCachePath)
+		}
+	})
+}
+
+func TestBuildConfig_ValidationErrors(t *testing.T) {
+	chdirWithDirs(t, "src")
+	if err := os.WriteFile("file.txt", []byte("x"), 0o644); err != nil {
+		t.Fatalf("failed to write file.txt: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		flags   map[string]string
+		wantErr string
+	}{
+		{name: "unknown format", flags: map[string]string{"format": "yaml"}, wantErr: "unsupported output format: yaml"},
+		{name: "unknown line number format", flags: map[string]string{"line-number-format": "roman"}, wantErr: "unsupported line number format"},
+		{name: "missing directory", args: []string{"missing"}, wantErr: "does not exist"},
+		{name: "file instead of directory", args: []string{"file.txt"}},
+		{name: "file then directory", args: []string{"file.txt", "src"}, wantErr: "'src' is not a file"},
+		{name: "file then missing file", args: []string{"file.txt", "missing.go"}, wantErr: "file 'missing.go' does not exist"},
+		{name: "fence style with xml", flags: map[string]string{"fence-style": "tilde"}, wantErr: "only apply to markdown"},
+		{name: "allowlist warn alone", flags: map[string]string{"allowlist-warn": "true"}, wantErr: "--allowlist-warn requires --allowlist"},
+		{name: "missing allowlist", flags: map[string]string{"allowlist": "/nonexistent/allowlist"}, wantErr: "--allowlist:"},
+		{name: "budget without size", flags: map[string]string{"budget": "*.md"}, wantErr: "want TARGET=SIZE"},
+		{name: "budget with bad size", flags: map[string]string{"budget": "docs/=lots"}, wantErr: "--budget \"docs/=lots\""},
+		{name: "budget warn only alone", flags: map[string]string{"budget-warn-only": "true"}, wantErr: "requires --budget"},
+		{name: "gitignore verify alone", flags: map[string]string{"gitignore-verify": "true"}, wantErr: "requires --gitignore"},
+		{
+			name:    "sentinel with backtick fences",
+			flags:   map[string]string{"format": "markdown", "fence-style": "backtick", "sentinel": "-- {path}"},
+			wantErr: "--sentinel requires --fence-style none",
+		},
+		{name: "pattern-all without pattern", flags: map[string]string{"pattern-all": "true"}, wantErr: "--pattern-all requires --pattern"},
+		{name: "max-matches without pattern", flags: map[string]string{"max-matches": "5"}, wantErr: "--max-matches requires --pattern"},
+		{name: "negative max-matches", flags: map[string]string{"pattern": "*x*", "max-matches": "-1"}, wantErr: "--max-matches must not be negative"},
+		{name: "invalid regex pattern", flags: map[string]string{"pattern": "re:(unclosed"}, wantErr: "invalid --pattern"},
+		{name: "todos with pattern", flags: map[string]string{"todos": "true", "pattern": "*x*"}, wantErr: "--todos cannot be combined with --pattern"},
+		{
+			name:    "front matter only and strip",
+			flags:   map[string]string{"front-matter-only": "true", "strip-front-matter": "true"},
+			wantErr: "--front-matter-only cannot be combined with --strip-front-matter",
+		},
+		{name: "todo context without todos", flags: map[string]string{"todo-context": "2"}, wantErr: "--todo-context requires --todos"},
+		{name: "blame context without blame since", flags: map[string]string{"blame-context": "2"}, wantErr: "--blame-context requires --blame-since"},
+		{name: "fail fast categories", flags: map[string]string{"fail-fast": "permission,all"}},
+		{name: "unknown fail fast category", flags: map[string]string{"fail-fast": "vanished"}, wantErr: `invalid --fail-fast category "vanished"`},
+		{name: "negative tab width", flags: map[string]string{"expand-tabs": "-4"}, wantErr: "--expand-tabs must not be negative"},
+		{name: "negative fold width", flags: map[string]string{"fold": "-1"}, wantErr: "--fold must not be negative"},
+		{name: "format option without value", flags: map[string]string{"format-opt": "xml:indent"}, wantErr: "--format-opt expects [format:]key=value"},
+		{name: "unknown format option", flags: map[string]string{"format-opt": "xml:color=red"}, wantErr: "unknown --format-opt xml:color"},
+		{name: "format option for another format", flags: map[string]string{"format-opt": "json:pretty=false"}, wantErr: "does not apply to xml output"},
+		{name: "format option for the selected format", flags: map[string]string{"format-opt": "indent=2"}},
+		{name: "only and no executable", flags: map[string]string{"only-executable": "true", "no-executable": "true"}, wantErr: "--only-executable cannot be combined with --no-executable"},
+		{name: "deterministic with readme-first", flags: map[string]string{"deterministic": "true", "readme-first": "true"}, wantErr: "--deterministic cannot be combined with --readme-first"},
+		{name: "unknown type", flags: map[string]string{"type": "golang"}, wantErr: `unknown --type "golang"`},
+		{name: "unknown exclude type", flags: map[string]string{"exclude-type": "shell"}, wantErr: `unknown --exclude-type "shell"`},
+		{name: "unknown-type selector", flags: map[string]string{"type": "unknown,bash"}},
+		{name: "imports with slashes", flags: map[string]string{"imports": "/internal/scanner/"}, wantErr: `invalid --imports "/internal/scanner/"`},
+		{name: "imports include others alone", flags: map[string]string{"imports-include-others": "true"}, wantErr: "requires --imports"},
+		{name: "lang map without type", flags: map[string]string{"lang-map": "tpl"}, wantErr: "--lang-map expects ext=lang"},
+		{name: "lang map with empty type", flags: map[string]string{"lang-map": "tpl="}, wantErr: "invalid --lang-map tpl="},
+		{name: "type introduced by lang map", flags: map[string]string{"lang-map": ".TPL=gotmpl", "type": "gotmpl"}},
+		{name: "negative max tokens", flags: map[string]string{"max-tokens": "-1"}, wantErr: "--max-tokens must not be negative"},
+		{name: "negative max files", flags: map[string]string{"max-files": "-1"}, wantErr: "--max-files must not be negative"},
+		{name: "negative max depth", flags: map[string]string{"max-depth": "-1"}, wantErr: "--max-depth must not be negative"},
+		{name: "negative max files per dir", flags: map[string]string{"max-files-per-dir": "-1"}, wantErr: "--max-files-per-dir must not be negative"},
+		{name: "negative max open files", flags: map[string]string{"max-open-files": "-1"}, wantErr: "--max-open-files must not be negative"},
+		{name: "unknown sort order", flags: map[string]string{"sort": "size"}, wantErr: "unsupported sort order: size"},
+		{name: "natural sort", flags: map[string]string{"sort": "natural"}},
+		{name: "unknown content encoding", flags: map[string]string{"content-encoding": "hex"}, wantErr: "unsupported content encoding: hex"},
+		{name: "base64 with markdown", flags: map[string]string{"format": "markdown", "content-encoding": "base64"}, wantErr: "--content-encoding base64 only applies to json and xml output"},
+		{name: "raw max size without base64", flags: map[string]string{"raw-max-size": "2MB"}, wantErr: "--raw-max-size requires --content-encoding base64"},
+		{name: "base64 with json", flags: map[string]string{"format": "json", "content-encoding": "base64", "raw-max-size": "64K"}},
+		{name: "pretty max size alone", flags: map[string]string{"pretty-max-size": "1MB"}, wantErr: "--pretty-max-size requires --pretty-json or --pretty-yaml"},
+		{name: "pretty max size with pretty json", flags: map[string]string{"pretty-json": "true", "pretty-max-size": "1MB"}},
+		{name: "invalid terminal warn size", flags: map[string]string{"terminal-warn-size": "huge"}, wantErr: `--terminal-warn-size: invalid size "huge"`},
+		{name: "sentinel with markdown", flags: map[string]string{"format": "markdown", "sentinel": "-- {path}"}},
+		{name: "invalid color", flags: map[string]string{"color": "sometimes"}, wantErr: `--color expects auto, always, or never, got "sometimes"`},
+		{name: "theme without pretty", flags: map[string]string{"theme": "dracula"}, wantErr: "--theme only applies to pretty output, not xml"},
+		{name: "unknown theme", flags: map[string]string{"format": "pretty", "theme": "neon"}, wantErr: "unknown theme: neon"},
+		{name: "theme with pretty", flags: map[string]string{"format": "pretty", "theme": "dracula", "color": "always"}},
+		{name: "unknown TUI theme", flags: map[string]string{"tui-theme": "sepia"}, wantErr: "unknown TUI theme: sepia (supported: auto, dark, light, mono)"},
+		{name: "mono TUI theme", flags: map[string]string{"tui-theme": "mono"}},
+		{name: "several formats without output dir", flags: map[string]string{"format": "xml,markdown"}, wantErr: "writing several formats (xml, markdown) requires --output-dir"},
+		{name: "format listed twice", flags: map[string]string{"format": "json, json", "output-dir": "out"}, wantErr: "output format json is listed more than once"},
+		{name: "unknown format in list", flags: map[string]string{"format": "json,yaml", "output-dir": "out"}, wantErr: "unsupported output format: yaml"},
+		{name: "output dir is a file", flags: map[string]string{"output-dir": "file.txt"}, wantErr: "--output-dir 'file.txt' is a file, not a directory"},
+		{name: "output template", flags: map[string]string{"output-dir": "out", "output-template": "{{.RelPath}}.md", "format": "markdown"}},
+		{name: "output template without dir", flags: map[string]string{"output-template": "{{.RelPath}}.md"}, wantErr: "--output-template requires --output-dir"},
+		{name: "output template does not parse", flags: map[string]string{"output-dir": "out", "output-template": "{{.RelPath"}, wantErr: "invalid --output-template"},
+		{name: "output template with integrity", flags: map[string]string{"output-dir": "out", "output-template": "{{.RelPath}}.xml", "integrity": "true"}, wantErr: "--integrity does not apply to --output-template"},
+		{name: "throttle rate", flags: map[string]string{"throttle": "200k/s"}},
+		{name: "throttle without unit", flags: map[string]string{"throttle": "4096"}},
+		{name: "zero throttle", flags: map[string]string{"throttle": "0/s"}, wantErr: `--throttle: invalid rate "0/s"`},
+		{name: "throttle per minute", flags: map[string]string{"throttle": "1M/min"}, wantErr: "invalid rate"},
+		{name: "integrity", flags: map[string]string{"integrity": "true", "format": "json"}},
+		{name: "integrity with pretty", flags: map[string]string{"integrity": "true", "format": "pretty"}, wantErr: "--integrity is not supported by pretty output"},
+		{name: "integrity with list", flags: map[string]string{"integrity": "true", "list": "true"}, wantErr: "--integrity does not apply to --list"},
+		{
+			name:  "options for several formats",
+			flags: map[string]string{"format": "xml,json,markdown", "output-dir": "out", "format-opt": "json:pretty=false", "fence-style": "tilde"},
+		},
+		{
+			name:    "bare option with several formats",
+			flags:   map[string]string{"format": "xml,json", "output-dir": "out", "format-opt": "indent=2"},
+			wantErr: "unknown --format-opt json:indent",
+		},
+		{name: "brace alternatives", flags: map[string]string{"globs": "*.{go,md}", "ignore-globs": "{a,b}_test.go"}},
+		{name: "unclosed brace", flags: map[string]string{"globs": "*.{go"}, wantErr: `invalid --globs pattern "*.{go": unclosed '{'`},
+		{name: "stray brace", flags: map[string]string{"ignore-globs": "*.go}"}, wantErr: `invalid --ignore-globs pattern "*.go}": unexpected '}'`},
+		{name: "path regex with a comma", flags: map[string]string{"path-regex": `^a{1,3}/`, "exclude-path-regex": `(?i)\.md$`}},
+		{name: "invalid path regex", flags: map[string]string{"path-regex": "(src"}, wantErr: `invalid --path-regex "(src": error parsing regexp`},
+		{name: "invalid exclude path regex", flags: map[string]string{"exclude-path-regex": "*.go"}, wantErr: `invalid --exclude-path-regex "*.go"`},
+		{name: "print0 without list", flags: map[string]string{"print0": "true"}, wantErr: "--print0 requires --list"},
+		{name: "list with format", flags: map[string]string{"list": "true", "format": "json"}, wantErr: "--list writes only paths and cannot be combined with --format"},
+		{
+			name:    "list with content options",
+			flags:   map[string]string{"list": "true", "line-numbers": "true", "strip-ansi": "true"},
+			wantErr: "--list writes only paths and cannot be combined with --line-numbers, --strip-ansi",
+		},
+		{
+			name:    "list with line number format",
+			flags:   map[string]string{"list": "true", "line-number-format": "colon"},
+			wantErr: "--list writes only paths and cannot be combined with --line-number-format",
+		},
+		{
+			name:    "list with default line number format",
+			flags:   map[string]string{"list": "true", "line-number-format": "pipe"},
+			wantErr: "--list writes only paths and cannot be combined with --line-number-format",
+		},
+		{name: "list with print0 and filters", flags: map[string]string{"list": "true", "print0": "true", "skip-empty": "true", "globs": "*.go"}},
+		{name: "valid directory", args: []string{"src"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{Use: "test"}
+			cmd.Flags().AddFlagSet(createTestFlags())
+
+			for name, value := range tt.flags {
+				if err := cmd.Flags().Set(name, value); err != nil {
+					t.Fatalf("failed to set flag %s: %v", name, err)
+				}
+			}
+
+			_, err := buildConfig(cmd, tt.args)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("buildConfig() unexpected error: %v", err)
+				}
+
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("buildConfig() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDetectCacheFlagsMutuallyExclusive(t *testing.T) {
+	rootCmd.SetArgs([]string{"--detect-cache", "x.json", "--no-detect-cache", t.TempDir()})
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+	t.Cleanup(func() {
+		rootCmd.SetArgs(nil)
+		rootCmd.SilenceErrors = false
+		rootCmd.SilenceUsage = false
+		// Subcommands share these flags, and with them the conflict
+		for name, value := range map[string]string{"detect-cache": "", "no-detect-cache": "false"} {
+			flag := rootCmd.Flags().Lookup(name)
+			_ = flag.Value.Set(value)
+			flag.Changed = false
+		}
+	})
+
+	err := Execute()
+	if err == nil || !strings.Contains(err.Error(), "none of the others can be") {
+		t.Errorf("Execute() error = %v, want mutually exclusive flag error", err)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "generic error", err: errors.New("boom"), want: 1},
+		{name: "case collision", err: fmt.Errorf("run: %w", catls.ErrCaseCollision), want: 3},
+		{name: "manifest mismatch", err: fmt.Errorf("verify: %w", catls.ErrManifestMismatch), want: 4},
+		{name: "unreadable file", err: fmt.Errorf("run: %w", catls.ErrUnreadable), want: 6},
+		{name: "over budget", err: fmt.Errorf("run: %w", catls.ErrBudgetExceeded), want: 4},
+		{name: "not allowlisted", err: fmt.Errorf("run: %w", catls.ErrNotAllowlisted), want: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExitCode(tt.err); got != tt.want {
+				t.Errorf("ExitCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeterministicOutput(t *testing.T) {
+	parent := t.TempDir()
+	fixture := filepath.Join(parent, "fixture")
+	for name, content := range map[string]string{
+		"src/main.go":      "package main\r\n\r\nfunc main() {}\r\n",
+		"src/progress.txt": "10%\r50%\r100%\n",
+		"notes/prompt.txt": "close with </file>\n",
+		"run.sh":           "#!/bin/sh\necho hi\n",
+		"src/lib/util.go":  "package lib\n",
+		"src/lib0.txt":     "sorts after src/lib/ only with forward slashes\n",
+	} {
+		path := filepath.Join(fixture, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o755); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	run := func(t *testing.T, cwd, dir, format string) string {
+		t.Helper()
+		t.Chdir(cwd)
+
+		cmd := &cobra.Command{Use: "test"}
+		cmd.Flags().AddFlagSet(createTestFlags())
+		for name, value := range map[string]string{
+			"deterministic":   "true",
+			"recursive":       "true",
+			"no-detect-cache": "true",
+			"format":          format,
+		} {
+			if err := cmd.Flags().Set(name, value); err != nil {
+				t.Fatalf("failed to set flag %s: %v", name, err)
+			}
+		}
+
+		cfg, err := buildConfig(cmd, []string{dir})
+		if err != nil {
+			t.Fatalf("buildConfig() unexpected error: %v", err)
+		}
+		var buf bytes.Buffer
+		cfg.Output = &buf
+		app, err := catls.New(cfg)
+		if err != nil {
+			t.Fatalf("New() unexpected error: %v", err)
+		}
+		if err := app.Run(context.Background()); err != nil {
+			t.Fatalf("Run() unexpected error: %v", err)
+		}
+
+		return buf.String()
+	}
+
+	for _, format := range catls.GetSupportedFormats() {
+		t.Run(format, func(t *testing.T) {
+			fromFixture := run(t, fixture, ".", format)
+			fromParent := run(t, parent, "fixture", format)
+
+			if fromFixture != fromParent {
+				t.Errorf("output differs between working directories:\n--- from fixture\n%s\n--- from parent\n%s", fromFixture, fromParent)
+			}
+			if strings.Contains(fromFixture, "\r") {
+				t.Errorf("output contains carriage returns:\n%q", fromFixture)
+			}
+			if strings.Contains(fromFixture, "xecutable") {
+				t.Errorf("output contains the executable bit:\n%s", fromFixture)
+			}
+			if lib, extra := strings.Index(fromFixture, "src/lib/util.go"), strings.Index(fromFixture, "src/lib0.txt"); lib < 0 || extra < 0 || extra < lib {
+				t.Errorf("files are not in forward-slash path order:\n%s", fromFixture)
+			}
+		})
+	}
+
+	// Carriage returns are resolved line by line, not joined
+	var document struct {
+		Files []catls.JSONFile `json:"files"`
+	}
+	if err := json.Unmarshal([]byte(run(t, fixture, ".", "json")), &document); err != nil {
+		t.Fatalf("invalid JSON output: %v", err)
+	}
+	want := map[string][]string{
+		"src/main.go":      {"package main", "", "func main() {}"},
+		"src/progress.txt": {"100%"},
+	}
+	for _, file := range document.Files {
+		wantLines, ok := want[file.Path]
+		if !ok {
+			continue
+		}
+		var lines []string
+		for _, line := range file.Lines {
+			lines = append(lines, line.Content)
+		}
+		if !slices.Equal(lines, wantLines) {
+			t.Errorf("%s lines = %q, want %q", file.Path, lines, wantLines)
+		}
+		delete(want, file.Path)
+	}
+	if len(want) > 0 {
+		t.Errorf("JSON output is missing %v", want)
+	}
+}
+
+func TestJoinBraceSplits(t *testing.T) {
+	tests := []struct {
+		values []string
+		want   []string
+	}{
+		{values: nil},
+		{values: []string{"*.go", "*.md"}, want: []string{"*.go", "*.md"}},
+		{values: []string{"*.{go", "md}", "*.txt"}, want: []string{"*.{go,md}", "*.txt"}},
+		{values: []string{"{a", "{b", "c}", "d}"}, want: []string{"{a,{b,c},d}"}},
+		{values: []string{"*.go}", "{a", "b}"}, want: []string{"*.go}", "{a,b}"}},
+	}
+
+	for _, tt := range tests {
+		if got := joinBraceSplits(tt.values); !slices.Equal(got, tt.want) {
+			t.Errorf("joinBraceSplits(%q) = %q, want %q", tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestFileArgs(t *testing.T) {
+	chdirWithDirs(t, "internal/catls", "cmd")
+	for _, name := range []string{"main.go", "internal/catls/catls.go", "cmd/root.go"} {
+		if err := os.WriteFile(name, []byte("package x"), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		args      []string
+		wantDir   string
+		wantPaths []string
+	}{
+		{name: "single file", args: []string{"main.go"}, wantDir: ".", wantPaths: []string{"main.go"}},
+		{
+			name:      "files below the first one's directory",
+			args:      []string{"main.go", "internal/catls/catls.go"},
+			wantDir:   ".",
+			wantPaths: []string{"main.go", filepath.Join("internal", "catls", "catls.go")},
+		},
+		{
+			name:      "sibling directories",
+			args:      []string{"internal/catls/catls.go", "cmd/root.go"},
+			wantDir:   ".",
+			wantPaths: []string{filepath.Join("internal", "catls", "catls.go"), filepath.Join("cmd", "root.go")},
+		},
+		{name: "nested file", args: []string{"internal/catls/catls.go"}, wantDir: filepath.Join("internal", "catls"), wantPaths: []string{"catls.go"}},
+		{
+			name:      "absolute path",
+			args:      []string{filepath.Join(cwd, "cmd", "root.go")},
+			wantDir:   filepath.Join(cwd, "cmd"),
+			wantPaths: []string{"root.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, paths, err := fileArgs(tt.args)
+			if err != nil {
+				t.Fatalf("fileArgs() error = %v", err)
+			}
+			if dir != tt.wantDir || !slices.Equal(paths, tt.wantPaths) {
+				t.Errorf("fileArgs() = %q, %q, want %q, %q", dir, paths, tt.wantDir, tt.wantPaths)
+			}
+		})
+	}
+}
+
+func TestGlobArgs(t *testing.T) {
+	chdirWithDirs(t, "src/lib", "docs")
+
+	tests := []struct {
+		name      string
+		args      []string
+		wantDir   string
+		wantGlobs []string
+		wantErr   string
+	}{
+		{name: "recursive glob", args: []string{"src/**/*.ts"}, wantDir: "src", wantGlobs: []string{"*.ts"}},
+		{name: "nested literal prefix", args: []string{"src/lib/*.go"}, wantDir: filepath.Join("src", "lib"), wantGlobs: []string{"*.go"}},
+		{name: "glob in a directory segment", args: []string{"src/*/util.go"}, wantDir: "src", wantGlobs: []string{"*/util.go"}},
+		{name: "no literal prefix", args: []string{"*.{go,md}"}, wantDir: ".", wantGlobs: []string{"*.{go,md}"}},
+		{name: "everything below", args: []string{"docs/**"}, wantDir: "docs"},
+		{name: "several globs", args: []string{"src/*.go", "src/**/*.md"}, wantDir: "src", wantGlobs: []string{"*.go", "*.md"}},
+		{name: "different directories", args: []string{"src/*.go", "docs/*.md"}, wantErr: "must share a directory"},
+		{name: "glob then directory", args: []string{"src/*.go", "docs"}, wantErr: "'docs' is not a glob"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, globs, err := globArgs(tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("globArgs() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatalf("globArgs() error = %v", err)
+			}
+			if dir != tt.wantDir || !slices.Equal(globs, tt.wantGlobs) {
+				t.Errorf("globArgs() = %q, %q, want %q, %q", dir, globs, tt.wantDir, tt.wantGlobs)
+			}
+		})
+	}
+}
+
+// TestBuildConfig_GlobArg checks that a quoted glob scans its directory with
+// the rest as an include glob, alongside any --globs, while an existing
+// directory holding a glob metacharacter is still scanned as it is.
+func TestBuildConfig_GlobArg(t *testing.T) {
+	chdirWithDirs(t, "src", "odd[dir]")
+
+	cmd := &cobra.Command{Use: "test"}
+	cmd.Flags().AddFlagSet(createTestFlags())
+	if err := cmd.Flags().Set("globs", "*.md"); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := buildConfig(cmd, []string{"src/**/*.go"})
+	if err != nil {
+		t.Fatalf("buildConfig() unexpected error: %v", err)
+	}
+	if cfg.Directory != "src" || !slices.Equal(cfg.Globs, []string{"*.md", "*.go"}) {
+		t.Errorf("buildConfig() = directory %q, globs %q", cfg.Directory, cfg.Globs)
+	}
+
+	cfg, err = buildConfig(cmd, []string{"odd[dir]"})
+	if err != nil {
+		t.Fatalf("buildConfig() unexpected error: %v", err)
+	}
+	if cfg.Directory != "odd[dir]" || !slices.Equal(cfg.Globs, []string{"*.md"}) {
+		t.Errorf("buildConfig() = directory %q, globs %q", cfg.Directory, cfg.Globs)
+	}
+}
+
+func TestWriteSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr string
+	}{
+		{name: "selected format", args: []string{"--schema", "-f", "json"}, want: `"title": "catls JSON output"`},
+		{name: "chunks", args: []string{"--schema", "--format", "chunks"}, want: `"title": "catls chunks record"`},
+		{name: "named", args: []string{"--schema=manifest"}, want: `"title": "catls manifest"`},
+		{name: "default format has none", args: []string{"--schema"}, wantErr: "no schema for xml output"},
+		{name: "several formats", args: []string{"--schema", "-f", "json,chunks"}, wantErr: "--schema describes one format"},
+		{name: "detached name", args: []string{"--schema", "json"}, wantErr: "write --schema=json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			cmd := &cobra.Command{Use: "test"}
+			cmd.Flags().AddFlagSet(createTestFlags())
+			cmd.SetOut(&buf)
+			if err := cmd.Flags().Parse(tt.args); err != nil {
+				t.Fatalf("failed to parse %v: %v", tt.args, err)
+			}
+
+			err := writeSchema(cmd, cmd.Flags().Args())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("writeSchema() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatalf("writeSchema() unexpected error: %v", err)
+			}
+			if !strings.Contains(buf.String(), tt.want) {
+				t.Errorf("output does not contain %s:\n%s", tt.want, buf.String())
+			}
+		})
+	}
+}
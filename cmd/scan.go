@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/connerohnesorge/catls/internal/catls"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// scanCmd writes the file list of a scan for render. It accepts the same
+// arguments and flags as the root command; the flags are attached in init in
+// root.go once they are defined.
+var scanCmd = &cobra.Command{
+	Use:   "scan [directory]",
+	Short: "Write the files a run would select as a scan artifact for render",
+	Long: `scan runs the scan and filters with the given flags and writes the files
+found, with their size, modification time, and detected type, as JSON to
+stdout, along with the absolute path of the scanned directory. 'catls render
+--from FILE' then processes and formats them without scanning again, as often
+as needed. Flags that only shape output are ignored.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runScan,
+}
+
+// renderCmd processes and formats the files of a scan artifact. It shares
+// the root command's flags, attached in init in root.go, except those that
+// decide what a scan visits.
+var renderCmd = &cobra.Command{
+	Use:   "render --from FILE",
+	Short: "Process and format the files of a scan artifact without rescanning",
+	Long: `render reads a scan artifact written by 'catls scan' and filters, processes,
+and formats its files like a normal run, without walking the directory again.
+Filters such as --globs, --type, or --ignore-globs narrow the scanned files
+further. Each file is checked first: files that no longer exist are reported as
+unreadable, and files that changed are read as they are now.`,
+	Args: cobra.NoArgs,
+	RunE: runRender,
+}
+
+// scanOnlyFlags decide what a scan visits, so render rejects them.
+var scanOnlyFlags = []string{
+	"all", "recursive", "force", "ignore-dir", "one-file-system", "skip-git-submodules", "follow-symlinks",
+	"include-dirs", "max-files", "max-depth", "no-ignore-file", "gitignore", "gitignore-verify", "relative-to",
+}
+
+func init() {
+	renderCmd.Flags().String(
+		"from",
+		"",
+		"Scan artifact written by 'catls scan'",
+	)
+	_ = renderCmd.MarkFlagRequired("from")
+}
+
+func runScan(cmd *cobra.Command, args []string) error {
+	cfg, err := buildConfig(cmd, args)
+	if err != nil {
+		return err
+	}
+	cfg.ConfirmBroadScan = terminalConfirmScan()
+
+	app, err := catls.New(cfg)
+	if err != nil {
+		return err
+	}
+
+	artifact, err := app.Scan(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	return catls.WriteScanArtifact(cmd.OutOrStdout(), artifact)
+}
+
+func runRender(cmd *cobra.Command, _ []string) error {
+	if err := checkRenderFlags(cmd.Flags()); err != nil {
+		return err
+	}
+	path, _ := cmd.Flags().GetString("from")
+	artifact, err := catls.ReadScanArtifact(path)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := buildConfig(cmd, nil)
+	if err != nil {
+		return err
+	}
+	cfg.Directory, cfg.ScanArtifact = artifact.Root, artifact
+	// The scan settings are the artifact's, so the configuration echo
+	// reports how the files were found
+	scanned := artifact.Config
+	cfg.ShowAll, cfg.Recursive, cfg.IgnoreDir = scanned.ShowAll, scanned.Recursive, scanned.IgnoreDir
+	cfg.OneFileSystem, cfg.SkipGitSubmodules, cfg.MaxFiles = scanned.OneFileSystem, scanned.SkipGitSubmodules, scanned.MaxFiles
+
+	return runConfig(cmd, cfg, nil)
+}
+
+// checkRenderFlags rejects the flags in scanOnlyFlags, which only the
+// command line tells apart from their defaults.
+func checkRenderFlags(flags *pflag.FlagSet) error {
+	var given []string
+	for _, name := range scanOnlyFlags {
+		if flags.Changed(name) {
+			given = append(given, "--"+name)
+		}
+	}
+	if len(given) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("render takes the scan settings from the artifact; pass %s to 'catls scan' instead", strings.Join(given, ", "))
+}
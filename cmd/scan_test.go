@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/connerohnesorge/catls/internal/catls"
+)
+
+func TestScanCommand(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"a.go": "package a", "sub/b.txt": "text"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	rootCmd.SetOut(&buf)
+	t.Cleanup(func() {
+		rootCmd.SetArgs(nil)
+		rootCmd.SetOut(nil)
+		recursive := scanCmd.Flags().Lookup("recursive")
+		_ = recursive.Value.Set("false")
+		recursive.Changed = false
+		_ = renderCmd.Flags().Set("from", "")
+	})
+
+	rootCmd.SetArgs([]string{"scan", "-r", dir})
+	if err := Execute(); err != nil {
+		t.Fatalf("Execute() unexpected error: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "scan.json")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	artifact, err := catls.ReadScanArtifact(path)
+	if err != nil {
+		t.Fatalf("ReadScanArtifact() unexpected error: %v", err)
+	}
+	if root, _ := filepath.Abs(dir); artifact.Root != root {
+		t.Errorf("Root = %q, want %q", artifact.Root, root)
+	}
+	var paths []string
+	for _, file := range artifact.Files {
+		paths = append(paths, file.Path)
+	}
+	if got := strings.Join(paths, " "); got != "a.go sub/b.txt" {
+		t.Errorf("scanned files = %s, want a.go sub/b.txt", got)
+	}
+
+	// Flags deciding what a scan visits belong to scan
+	rootCmd.SetArgs([]string{"render", "--from", path, "-r"})
+	if err := Execute(); err == nil || !strings.Contains(err.Error(), "pass --recursive to 'catls scan'") {
+		t.Errorf("render -r error = %v, want it rejected", err)
+	}
+}
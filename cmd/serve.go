@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/connerohnesorge/catls/internal/serve"
+	"github.com/spf13/cobra"
+)
+
+// serveCmd previews a run in the browser. It accepts the same arguments and
+// flags as the root command; the shared flags are attached in init in root.go
+// once they are defined.
+var serveCmd = &cobra.Command{
+	Use:   "serve [directory] [files...]",
+	Short: "Browse the selected files in a local web page",
+	Long: `serve starts a local HTTP server that renders the files a normal run would
+select as a browsable page: a sidebar of files, a syntax-highlighted view of the
+chosen file, and links to download the run in each output format. Every page
+load rescans, so edits show up on refresh. Press Ctrl-C to stop.`,
+	Args: cobra.ArbitraryArgs,
+	RunE: runServe,
+}
+
+func init() {
+	serveCmd.Flags().String(
+		"addr",
+		"127.0.0.1:0",
+		"Address to listen on; port 0 picks a free port",
+	)
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	cfg, err := buildConfig(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	server, err := serve.New(cfg)
+	if err != nil {
+		return err
+	}
+
+	addr, _ := cmd.Flags().GetString("addr")
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Serving %s at http://%s/ (press Ctrl-C to stop)\n", cfg.Directory, ln.Addr())
+
+	return server.Serve(ctx, ln)
+}
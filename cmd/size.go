@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/connerohnesorge/catls/internal/catls"
+)
+
+// defaultEmbedImagesSize is the --embed-images cap when no size is given.
+const defaultEmbedImagesSize = "64K"
+
+// byteSizeUnits maps size suffixes to their multipliers. Units are binary, so
+// 1K is 1024 bytes.
+var byteSizeUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30},
+	{"B", 1},
+}
+
+// parseByteSize parses a size such as "65536", "64K", or "1MB".
+func parseByteSize(s string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(s))
+	multiplier := int64(1)
+	for _, unit := range byteSizeUnits {
+		if trimmed, ok := strings.CutSuffix(value, unit.suffix); ok {
+			value, multiplier = trimmed, unit.multiplier
+
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	return n * multiplier, nil
+}
+
+// parseByteRate parses a rate in bytes per second, a size as parseByteSize
+// takes it with an optional "/s", such as "200K/s". Zero is rejected.
+func parseByteRate(s string) (int64, error) {
+	size, _ := strings.CutSuffix(strings.TrimSpace(strings.ToLower(s)), "/s")
+	rate, err := parseByteSize(size)
+	if err != nil || rate == 0 {
+		return 0, fmt.Errorf("invalid rate %q, want a size per second such as 200K/s", s)
+	}
+
+	return rate, nil
+}
+
+// parseSizeBudget parses a --budget rule, TARGET=SIZE, where TARGET is a
+// glob such as "*.md" or, ending with a slash, a directory such as "docs/".
+func parseSizeBudget(rule string) (catls.SizeBudget, error) {
+	target, size, ok := strings.Cut(rule, "=")
+	if !ok {
+		return catls.SizeBudget{}, fmt.Errorf("--budget %q: want TARGET=SIZE, such as '*.md=200K' or 'docs/=1M'", rule)
+	}
+	limit, err := parseByteSize(size)
+	if err != nil {
+		return catls.SizeBudget{}, fmt.Errorf("--budget %q: %w", rule, err)
+	}
+	budget, err := catls.NewSizeBudget(strings.TrimSpace(target), limit)
+	if err != nil {
+		return catls.SizeBudget{}, fmt.Errorf("--budget %q: %w", rule, err)
+	}
+
+	return budget, nil
+}
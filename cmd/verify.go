@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/connerohnesorge/catls/internal/catls"
+	"github.com/spf13/cobra"
+)
+
+// verifyCmd compares a directory with a manifest written by --manifest. It
+// takes its selection settings from the manifest, not from flags.
+var verifyCmd = &cobra.Command{
+	Use:   "verify --manifest FILE [directory]",
+	Short: "Report files added, removed, or changed since a manifest was written",
+	Long: `verify rescans the directory recorded in a manifest written by --manifest, or
+the given directory instead, with the recorded selection flags, and reports the
+files that were added, removed, or whose SHA-256 changed. It exits with status 4
+when there are differences. --update rewrites the manifest to match once the
+changes are reviewed.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runVerify,
+}
+
+func init() {
+	flags := verifyCmd.Flags()
+	flags.String(
+		"manifest",
+		"",
+		"Manifest written by --manifest",
+	)
+	flags.Bool(
+		"update",
+		false,
+		"Rewrite the manifest to match the directory instead of failing on differences",
+	)
+	flags.StringP(
+		"format",
+		"f",
+		"",
+		"Report format: xml, json, markdown (default: a short summary)",
+	)
+	_ = verifyCmd.MarkFlagRequired("manifest")
+}
+
+func runVerify(cmd *cobra.Command, args []string) error {
+	flags := cmd.Flags()
+	path, _ := flags.GetString("manifest")
+	update, _ := flags.GetBool("update")
+	format, _ := flags.GetString("format")
+
+	recorded, err := catls.ReadManifest(path)
+	if err != nil {
+		return err
+	}
+
+	cfg := recorded.Config.Config()
+	if len(args) > 0 {
+		cfg.Directory = args[0]
+	}
+	cfg.OutputFormat = catls.OutputFormatXML
+	cfg.Output = io.Discard
+
+	app, err := catls.New(cfg)
+	if err != nil {
+		return err
+	}
+	current, err := app.BuildManifest(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	report := catls.DiffManifests(recorded, current)
+	report.Manifest = path
+	if err := catls.WriteVerifyReport(cmd.OutOrStdout(), catls.OutputFormat(format), report); err != nil {
+		return err
+	}
+
+	if update {
+		return catls.WriteManifest(path, current)
+	}
+	if report.Differences() > 0 {
+		return fmt.Errorf("%d files differ from %s: %w", report.Differences(), path, catls.ErrManifestMismatch)
+	}
+
+	return nil
+}
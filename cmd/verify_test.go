@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/connerohnesorge/catls/internal/catls"
+	"github.com/spf13/cobra"
+)
+
+func TestVerifyCommand(t *testing.T) {
+	dir := t.TempDir()
+	manifest := filepath.Join(t.TempDir(), "manifest.json")
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	write("a.go", "package a")
+	write("b.go", "package b")
+	write("notes.txt", "not selected")
+
+	var buf bytes.Buffer
+	rootCmd.SetOut(&buf)
+	t.Cleanup(func() {
+		rootCmd.SetArgs(nil)
+		rootCmd.SetOut(nil)
+		_ = verifyCmd.Flags().Set("update", "false")
+	})
+	run := func(args ...string) error {
+		buf.Reset()
+		rootCmd.SetArgs(args)
+
+		return Execute()
+	}
+
+	record := &cobra.Command{Use: "test"}
+	record.Flags().AddFlagSet(createTestFlags())
+	for name, value := range map[string]string{"globs": "*.go", "manifest": manifest} {
+		if err := record.Flags().Set(name, value); err != nil {
+			t.Fatalf("failed to set flag %s: %v", name, err)
+		}
+	}
+	// Recorded as typed from inside the directory, and verified from elsewhere
+	t.Chdir(dir)
+	cfg, err := buildConfig(record, []string{"."})
+	if err != nil {
+		t.Fatalf("buildConfig() unexpected error: %v", err)
+	}
+	cfg.Output = io.Discard
+	app, err := catls.New(cfg)
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	t.Chdir(t.TempDir())
+
+	if err := run("verify", "--manifest", manifest); err != nil {
+		t.Fatalf("verify of an unchanged directory: %v\n%s", err, buf.String())
+	}
+	if !strings.Contains(buf.String(), "OK: 2 files match") {
+		t.Errorf("verify output = %q, want a match summary", buf.String())
+	}
+
+	write("b.go", "package b // edited")
+	write("c.go", "package c")
+	write("notes.txt", "edited, but not selected")
+	if err := os.Remove(filepath.Join(dir, "a.go")); err != nil {
+		t.Fatal(err)
+	}
+
+	err = run("verify", "--manifest", manifest)
+	if !errors.Is(err, catls.ErrManifestMismatch) || ExitCode(err) != exitVerifyChanged {
+		t.Fatalf("verify error = %v, want ErrManifestMismatch", err)
+	}
+	for _, want := range []string{"added    c.go", "removed  a.go", "changed  b.go", "3 differences from"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("verify output = %q, want %q", buf.String(), want)
+		}
+	}
+
+	if err := run("verify", "--manifest", manifest, "--update"); err != nil {
+		t.Fatalf("verify --update: %v", err)
+	}
+	_ = verifyCmd.Flags().Set("update", "false")
+	if err := run("verify", "--manifest", manifest); err != nil {
+		t.Errorf("verify after --update: %v\n%s", err, buf.String())
+	}
+}
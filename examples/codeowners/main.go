@@ -0,0 +1,142 @@
+// Command codeowners shows how a tool embedding catls applies its own rules
+// through catls.Config.IncludeFunc. It renders, as markdown, only the files
+// of a directory that its CODEOWNERS file assigns to one owner:
+//
+//	go run ./examples/codeowners -owner @org/docs .
+//
+// CODEOWNERS is read from .github/, the directory itself, or docs/, as on
+// GitHub, and the last matching line decides a file's owners. Patterns
+// support *, ?, leading slashes, and trailing slashes; ** is not handled.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/connerohnesorge/catls/internal/catls"
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+// rule is one line of a CODEOWNERS file.
+type rule struct {
+	pattern string
+	owners  []string
+}
+
+func main() {
+	owner := flag.String("owner", "", "only show files owned by this user, team, or email")
+	flag.Parse()
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	if err := run(dir, *owner); err != nil {
+		fmt.Fprintln(os.Stderr, "codeowners:", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir, owner string) error {
+	if owner == "" {
+		return errors.New("-owner is required")
+	}
+
+	rules, err := readCodeowners(dir)
+	if err != nil {
+		return err
+	}
+
+	app, err := catls.New(&catls.Config{
+		Directory:    dir,
+		Recursive:    true,
+		OutputFormat: catls.OutputFormatMarkdown,
+		Output:       os.Stdout,
+		// Runs only for files the built-in filters keep
+		IncludeFunc: func(file scanner.FileInfo) bool {
+			return slices.Contains(ownersOf(rules, filepath.ToSlash(file.RelPath)), owner)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return app.Run(context.Background())
+}
+
+// readCodeowners reads the rules of the first CODEOWNERS file found in dir.
+func readCodeowners(dir string) ([]rule, error) {
+	for _, name := range []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"} {
+		file, err := os.Open(filepath.Join(dir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			_ = file.Close()
+		}()
+
+		var rules []rule
+		sc := bufio.NewScanner(file)
+		for sc.Scan() {
+			line, _, _ := strings.Cut(sc.Text(), "#")
+			if fields := strings.Fields(line); len(fields) > 0 {
+				rules = append(rules, rule{pattern: fields[0], owners: fields[1:]})
+			}
+		}
+
+		return rules, sc.Err()
+	}
+
+	return nil, fmt.Errorf("no CODEOWNERS file in %s", dir)
+}
+
+// ownersOf returns the owners of the last rule matching rel, a slash
+// separated path relative to the repository root.
+func ownersOf(rules []rule, rel string) []string {
+	for i := len(rules) - 1; i >= 0; i-- {
+		if matches(rules[i].pattern, rel) {
+			return rules[i].owners
+		}
+	}
+
+	return nil
+}
+
+// matches reports whether a CODEOWNERS pattern covers rel. A pattern with a
+// slash before its end is anchored at the root; one without matches at any
+// depth. A pattern matching a directory covers everything below it, and one
+// ending in a slash matches only directories.
+func matches(pattern, rel string) bool {
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	segments := strings.Split(rel, "/")
+	n := strings.Count(pattern, "/") + 1
+	for start := 0; start+n <= len(segments); start++ {
+		if anchored && start > 0 {
+			break
+		}
+		if ok, _ := path.Match(pattern, strings.Join(segments[start:start+n], "/")); !ok {
+			continue
+		}
+		if start+n < len(segments) || !dirOnly {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,111 @@
+// Command events shows how a tool embedding catls follows a run through
+// catls.Events. It renders a directory as XML to a file, or discards it, and
+// writes each event to stdout as a JSON line, ending with the run's stats:
+//
+//	go run ./examples/events -o context.xml ./internal
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/connerohnesorge/catls/internal/catls"
+)
+
+// line is one JSON line of output.
+type line struct {
+	Event    string          `json:"event"`
+	Path     string          `json:"path,omitempty"`
+	Outcome  string          `json:"outcome,omitempty"`
+	Reason   string          `json:"reason,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	Progress string          `json:"progress,omitempty"`
+	Duration time.Duration   `json:"durationNs,omitempty"`
+	Stats    *catls.RunStats `json:"stats,omitempty"`
+}
+
+func main() {
+	output := flag.String("o", "", "write the XML output to this file instead of discarding it")
+	flag.Parse()
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	if err := run(dir, *output); err != nil {
+		fmt.Fprintln(os.Stderr, "events:", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir, path string) error {
+	out := io.Discard
+	if path != "" {
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = file.Close()
+		}()
+		out = file
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	emit := func(l line) {
+		_ = encoder.Encode(l)
+	}
+
+	// Stats are kept from the events alone, as a consumer that never sees
+	// the App would
+	var stats catls.RunStats
+	events := &catls.Events{
+		OnScanStart: func(event catls.ScanStartEvent) {
+			emit(line{Event: "scan-start", Path: event.Directory})
+		},
+		OnFileDiscovered: func(event catls.FileDiscoveredEvent) {
+			emit(line{Event: "file-discovered", Path: event.File.RelPath})
+		},
+		OnFileProcessed: func(event catls.FileProcessedEvent) {
+			stats.Record(event)
+			l := line{
+				Event:    "file-processed",
+				Path:     event.File.RelPath,
+				Outcome:  string(event.Outcome),
+				Reason:   event.Reason,
+				Progress: fmt.Sprintf("%d/%d", event.Index+1, event.Total),
+				Duration: event.Duration,
+			}
+			if event.Err != nil {
+				l.Error = event.Err.Error()
+			}
+			emit(l)
+		},
+		OnRunComplete: func(event catls.RunCompleteEvent) {
+			l := line{Event: "run-complete", Duration: event.Duration, Stats: &stats}
+			if event.Err != nil {
+				l.Error = event.Err.Error()
+			}
+			emit(l)
+		},
+	}
+
+	app, err := catls.New(&catls.Config{
+		Directory:    dir,
+		Recursive:    true,
+		OutputFormat: catls.OutputFormatXML,
+		Output:       out,
+		Events:       events,
+	})
+	if err != nil {
+		return err
+	}
+
+	return app.Run(context.Background())
+}
@@ -110,13 +110,18 @@
           ++ builtins.attrValues scriptPackages;
       };
 
-      packages.default = pkgs.buildGoModule {
+      packages.default = pkgs.buildGoModule rec {
         pname = "catls";
         version = "2.0.0";
 
         src = ./.;
 
-        vendorHash = "sha256-2UtazHF/Un9+aOqce0E8aED70qqoZUQYomMHGUHKs/I=";
+        vendorHash = "sha256-jVqAmHtCGTouZp29tES6ZpF5wm9B3J9r726V/FK1TlE=";
+
+        # Only the catls command; examples/ are not installed
+        subPackages = ["."];
+
+        ldflags = ["-X github.com/connerohnesorge/catls/internal/catls.Version=${version}"];
 
         meta = with pkgs.lib; {
           description = "Enhanced file listing utility with XML, Markdown, and JSON output";
@@ -0,0 +1,137 @@
+package catls
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+// ErrNotAllowlisted is returned when the scan finds files that no Allowlist
+// pattern matches and AllowlistWarn is not set.
+var ErrNotAllowlisted = errors.New("files not in the allowlist")
+
+// allowlistRule is the name of the Allowlist rule in explanations.
+const allowlistRule = "allowlist"
+
+// checkAllowlist returns the files an Allowlist pattern matches. The others
+// passed every filter, so they are drift: each is written to stderr with the
+// pattern closest to it, and the run fails with ErrNotAllowlisted unless
+// AllowlistWarn, which leaves them out instead. Directory records are kept.
+func (a *App) checkAllowlist(files []scanner.FileInfo) ([]scanner.FileInfo, error) {
+	if len(a.cfg.Allowlist) == 0 {
+		return files, nil
+	}
+
+	allowed := make([]scanner.FileInfo, 0, len(files))
+	var unlisted []string
+	for _, file := range files {
+		if _, ok := scanner.MatchingGlob(file.RelPath, a.cfg.Allowlist); ok || file.IsDir {
+			allowed = append(allowed, file)
+		} else {
+			unlisted = append(unlisted, file.RelPath)
+		}
+	}
+	if len(unlisted) == 0 {
+		return allowed, nil
+	}
+
+	if a.cfg.AllowlistWarn {
+		writeUnlisted(a.warn, fmt.Sprintf("Warning: leaving out %s not in the allowlist:", pluralFiles(len(unlisted))), unlisted, a.cfg.Allowlist)
+
+		return allowed, nil
+	}
+	writeUnlisted(a.warn, "Files not in the allowlist:", unlisted, a.cfg.Allowlist)
+
+	return nil, fmt.Errorf("%w: %s", ErrNotAllowlisted, pluralFiles(len(unlisted)))
+}
+
+// writeUnlisted writes header and a line per path no allowlist pattern
+// matches, with the closest pattern as a hint.
+func writeUnlisted(w io.Writer, header string, paths, allowlist []string) {
+	fmt.Fprintln(w, header)
+	for _, path := range paths {
+		fmt.Fprintf(w, "  %s (closest pattern: %s)\n", path, closestPattern(path, allowlist))
+	}
+}
+
+// closestPattern returns the pattern with the smallest edit distance to
+// path, the first of them on a tie.
+func closestPattern(path string, patterns []string) string {
+	closest, best := "", -1
+	for _, pattern := range patterns {
+		if d := editDistance(path, pattern); best < 0 || d < best {
+			closest, best = pattern, d
+		}
+	}
+
+	return closest
+}
+
+// editDistance returns the Levenshtein distance between a and b: the fewest
+// rune insertions, deletions, and substitutions that turn one into the other.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	row := make([]int, len(rb)+1)
+	for j := range row {
+		row[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		diagonal := row[0]
+		row[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			diagonal, row[j] = row[j], min(row[j]+1, row[j-1]+1, diagonal+cost)
+		}
+	}
+
+	return row[len(rb)]
+}
+
+// allowlistVerdict checks file against Allowlist for an explanation.
+func (a *App) allowlistVerdict(file scanner.FileInfo) scanner.Verdict {
+	verdict := scanner.Verdict{Rule: allowlistRule}
+	if pattern, ok := scanner.MatchingGlob(file.RelPath, a.cfg.Allowlist); ok {
+		verdict.Detail = fmt.Sprintf("matches %q", pattern)
+
+		return verdict
+	}
+
+	verdict.Excluded = true
+	verdict.Detail = fmt.Sprintf("matches none; closest %q", closestPattern(file.RelPath, a.cfg.Allowlist))
+	if !a.cfg.AllowlistWarn {
+		verdict.Detail += ", failing the run"
+	}
+
+	return verdict
+}
+
+// ReadAllowlist returns the patterns of an allowlist file, which has the
+// format of an ignore file: one glob per line, with blank lines and "#"
+// comments skipped. An allowlist without patterns is an error, since it
+// would reject every file.
+func ReadAllowlist(path string) ([]string, error) {
+	patterns, err := ReadIgnoreFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("--allowlist: %w", err)
+	}
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("--allowlist: %s has no patterns", path)
+	}
+
+	return patterns, nil
+}
+
+// validateAllowlist requires Allowlist for AllowlistWarn.
+func (c *Config) validateAllowlist() error {
+	if c.AllowlistWarn && len(c.Allowlist) == 0 {
+		return errors.New("--allowlist-warn requires --allowlist")
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package catls
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "", b: "", want: 0},
+		{a: "abc", b: "", want: 3},
+		{a: "", b: "abc", want: 3},
+		{a: "kitten", b: "sitting", want: 3},
+		{a: "src/*.go", b: "src/main.go", want: 4},
+		{a: "日本.txt", b: "日記.txt", want: 1},
+	}
+
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := editDistance(tt.b, tt.a); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.b, tt.a, got, tt.want)
+		}
+	}
+
+	allowlist := []string{"docs/*.md", "src/*.go", "src/?.go", "src/*.ts"}
+	if got := closestPattern("src/main.rs", allowlist); got != "src/*.ts" {
+		t.Errorf("closestPattern() = %q, want src/*.ts", got)
+	}
+	if got := closestPattern("src/x.go", allowlist); got != "src/*.go" {
+		t.Errorf("closestPattern() = %q, want the first of the nearest, src/*.go", got)
+	}
+	if got := closestPattern("doc/intro.md", allowlist); got != "docs/*.md" {
+		t.Errorf("closestPattern() = %q, want docs/*.md", got)
+	}
+}
+
+func TestAllowlist(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"README.md":    "# a",
+		"src/main.go":  "package main",
+		"src/extra.rs": "fn main() {}",
+		"notes.txt":    "drift",
+	})
+	allowlist := []string{"README.md", "src/*.go"}
+
+	for _, tt := range []struct {
+		name string
+		warn bool
+		want string
+	}{
+		{name: "fail"},
+		{name: "warn", warn: true, want: "README.md,src/main.go"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			app, err := New(&Config{
+				Directory:     tmpDir,
+				Recursive:     true,
+				OutputFormat:  OutputFormatXML,
+				Allowlist:     allowlist,
+				AllowlistWarn: tt.warn,
+			})
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+
+			var paths []string
+			for file, err := range app.Files(context.Background()) {
+				if err != nil {
+					if tt.warn || !errors.Is(err, ErrNotAllowlisted) || !strings.HasSuffix(err.Error(), ": 2 files") {
+						t.Fatalf("Files() error = %v", err)
+					}
+
+					return
+				}
+				paths = append(paths, file.Info.RelPath)
+			}
+			if tt.want == "" {
+				t.Fatal("Files() succeeded, want ErrNotAllowlisted")
+			}
+			if got := strings.Join(paths, ","); got != tt.want {
+				t.Errorf("Files() yielded %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	var report bytes.Buffer
+	writeUnlisted(&report, "Files not in the allowlist:", []string{"notes.txt", "src/extra.rs"}, allowlist)
+	want := "Files not in the allowlist:\n  notes.txt (closest pattern: src/*.go)\n  src/extra.rs (closest pattern: src/*.go)\n"
+	if report.String() != want {
+		t.Errorf("report =\n%s\nwant\n%s", report.String(), want)
+	}
+
+	app, err := New(&Config{Directory: tmpDir, Recursive: true, OutputFormat: OutputFormatXML, Allowlist: allowlist})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	explanation, err := app.Explain(filepath.Join(tmpDir, "src", "extra.rs"))
+	if err != nil {
+		t.Fatalf("Explain() unexpected error: %v", err)
+	}
+	verdict, excluded := explanation.Decision()
+	if !excluded || verdict.Rule != allowlistRule || !strings.Contains(verdict.Detail, `closest "src/*.go"`) {
+		t.Errorf("Decision() = %+v, %v, want the allowlist to exclude it", verdict, excluded)
+	}
+}
+
+func TestReadAllowlist(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "allowlist")
+	if err := os.WriteFile(path, []byte("# reviewed paths\nsrc/*.go\n\nREADME.md\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	patterns, err := ReadAllowlist(path)
+	if err != nil || strings.Join(patterns, ",") != "src/*.go,README.md" {
+		t.Errorf("ReadAllowlist() = %v, %v, want [src/*.go README.md]", patterns, err)
+	}
+
+	if err := os.WriteFile(path, []byte("# nothing yet\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadAllowlist(path); err == nil || !strings.Contains(err.Error(), "has no patterns") {
+		t.Errorf("ReadAllowlist() of an empty allowlist = %v, want an error", err)
+	}
+}
@@ -0,0 +1,285 @@
+package catls
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+// blameDateLayouts are the dates BlameSince accepts when it names no commit.
+// Dates without a zone are local time.
+var blameDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// blameCutoff is BlameSince resolved against the repository: the argument
+// that makes git blame attribute every line older than the cutoff to a
+// boundary commit.
+type blameCutoff struct {
+	arg string
+}
+
+// resolveBlameSince checks that dir is inside a git work tree and resolves
+// since, a commit or a date, to a blameCutoff. A commit wins over a date
+// spelled the same way.
+func resolveBlameSince(ctx context.Context, dir, since string) (*blameCutoff, error) {
+	if _, err := runGit(ctx, dir, "rev-parse", "--show-toplevel"); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("--blame-since needs a git repository, but %s is not inside one", dir)
+		}
+
+		return nil, fmt.Errorf("--blame-since: %w", err)
+	}
+
+	if _, err := runGit(ctx, dir, "rev-parse", "--verify", "--quiet", "--end-of-options", since+"^{commit}"); err == nil {
+		return &blameCutoff{arg: "^" + since}, nil
+	}
+
+	for _, layout := range blameDateLayouts {
+		if date, err := time.ParseInLocation(layout, since, time.Local); err == nil {
+			return &blameCutoff{arg: "--since=" + date.Format(time.RFC3339)}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("--blame-since %q is neither a commit in %s nor a date such as 2006-01-02 or %s", since, dir, time.RFC3339)
+}
+
+// changedLines runs git blame once on file and returns the numbers of the
+// lines changed after the cutoff, including changes not yet committed. all
+// reports a file git does not track, every line of which is new.
+func (c *blameCutoff) changedLines(ctx context.Context, file scanner.FileInfo) (changed map[int]bool, all bool, err error) {
+	out, err := runGit(ctx, filepath.Dir(file.Path), "blame", "--porcelain", "--root", c.arg, "--", filepath.Base(file.Path))
+	if err != nil {
+		if strings.Contains(err.Error(), "no such path") {
+			return nil, true, nil
+		}
+
+		return nil, false, err
+	}
+
+	changed, err = parseBlame(out)
+
+	return changed, false, err
+}
+
+// parseBlame reads git blame --porcelain output and returns the final line
+// numbers not attributed to a boundary commit. Porcelain output names the
+// boundary only in the first header of each commit, so lines are grouped by
+// commit until the end.
+func parseBlame(out []byte) (map[int]bool, error) {
+	linesByCommit := make(map[string][]int)
+	boundary := make(map[string]bool)
+	var commit string
+
+	scan := bufio.NewScanner(bytes.NewReader(out))
+	scan.Buffer(nil, 1<<30)
+	for scan.Scan() {
+		line := scan.Text()
+		switch {
+		case strings.HasPrefix(line, "\t"):
+			continue
+		case line == "boundary":
+			boundary[commit] = true
+		case isBlameHeader(line):
+			fields := strings.Fields(line)
+			final, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return nil, fmt.Errorf("malformed git blame header %q", line)
+			}
+			commit = fields[0]
+			linesByCommit[commit] = append(linesByCommit[commit], final)
+		}
+	}
+	if err := scan.Err(); err != nil {
+		return nil, err
+	}
+
+	changed := make(map[int]bool)
+	for commit, lines := range linesByCommit {
+		if boundary[commit] {
+			continue
+		}
+		for _, line := range lines {
+			changed[line] = true
+		}
+	}
+
+	return changed, nil
+}
+
+// isBlameHeader reports whether line opens a line entry: a commit hash
+// followed by the original and final line numbers.
+func isBlameHeader(line string) bool {
+	hash, rest, ok := strings.Cut(line, " ")
+	if !ok || (len(hash) != 40 && len(hash) != 64) || len(strings.Fields(rest)) < 2 {
+		return false
+	}
+	for _, r := range hash {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// runGit runs git in dir and returns its stdout. A failing command's error
+// carries its stderr.
+func runGit(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %w: %s", args[0], err, msg)
+		}
+
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// sliceChanged keeps the lines of file within contextLines of a changed
+// line and puts a gap marker wherever lines are left out. Line numbers
+// count from the start of the file, so gaps also cover lines an earlier
+// filter left out.
+func sliceChanged(file *ProcessedFile, changed map[int]bool, contextLines int) {
+	keep := make(map[int]bool, len(changed)*(2*contextLines+1))
+	for line := range changed {
+		for n := line - contextLines; n <= line+contextLines; n++ {
+			keep[n] = true
+		}
+	}
+
+	var (
+		lines []FilteredLine
+		last  int
+	)
+	for _, line := range file.Lines {
+		if !keep[line.LineNumber] {
+			continue
+		}
+		if gap := line.LineNumber - last - 1; gap > 0 {
+			lines = append(lines, gapLine(last+1, gap))
+		}
+		lines = append(lines, line)
+		last = line.LineNumber
+	}
+	if gap := file.TotalLines - last; gap > 0 && !file.IsTruncated {
+		lines = append(lines, gapLine(last+1, gap))
+	}
+
+	file.Lines = lines
+}
+
+// gapLine returns the marker for gap lines left out from line number first.
+func gapLine(first, gap int) FilteredLine {
+	unit := "lines"
+	if gap == 1 {
+		unit = "line"
+	}
+
+	return FilteredLine{LineNumber: first, Content: fmt.Sprintf("⋯ %d %s not shown", gap, unit), Gap: gap}
+}
+
+// shouldSkipBlame applies BlameSince to file: it reports whether the file is
+// dropped because no line changed since the cutoff, and otherwise slices
+// its content to the changed lines. A file git cannot blame is kept whole,
+// with a warning.
+func (a *App) shouldSkipBlame(ctx context.Context, file *ProcessedFile) bool {
+	verdict, changed := a.blameVerdict(ctx, file)
+	if !verdict.Excluded {
+		if changed != nil {
+			sliceChanged(file, changed, a.cfg.BlameContext)
+		}
+
+		return false
+	}
+
+	if a.cfg.Debug {
+		fmt.Fprintf(a.warn, "Debug: Skipping file: %s (%s)\n", file.Info.RelPath, verdict)
+	}
+	a.stats.SkippedUnchanged++
+	a.omit(file.Info, OmitReasonUnchanged)
+
+	return true
+}
+
+// blameVerdict applies BlameSince and returns the changed lines of a file
+// that is kept, or nil if every line is kept. Unreadable files are kept so
+// the error is reported.
+func (a *App) blameVerdict(ctx context.Context, file *ProcessedFile) (scanner.Verdict, map[int]bool) {
+	verdict := scanner.Verdict{Rule: "blame since"}
+	if a.blame == nil || file.Error != nil {
+		return verdict, nil
+	}
+
+	changed, all, err := a.blame.changedLines(ctx, file.Info)
+	switch {
+	case err != nil:
+		fmt.Fprintf(a.warn, "Warning: Cannot blame %s, showing it whole: %v\n", file.Info.RelPath, err)
+		verdict.Detail = "not blamed"
+	case all:
+		verdict.Detail = "not tracked by git"
+	case len(changed) == 0:
+		verdict.Excluded = true
+		verdict.Detail = "no line changed since " + a.cfg.BlameSince
+	default:
+		verdict.Detail = fmt.Sprintf("%d lines changed since %s", len(changed), a.cfg.BlameSince)
+
+		return verdict, changed
+	}
+
+	return verdict, nil
+}
+
+// resolveBlame resolves BlameSince once per App, before any file is read.
+func (a *App) resolveBlame(ctx context.Context) error {
+	if a.cfg.BlameSince == "" || a.blame != nil {
+		return nil
+	}
+
+	cutoff, err := resolveBlameSince(ctx, a.cfg.Directory, a.cfg.BlameSince)
+	if err != nil {
+		return err
+	}
+	a.blame = cutoff
+
+	return nil
+}
+
+// validateBlame checks the BlameSince settings. Re-indented content no longer
+// lines up with the lines git blames.
+func (c *Config) validateBlame() error {
+	if c.BlameContext < 0 {
+		return fmt.Errorf("--blame-context must not be negative, got %d", c.BlameContext)
+	}
+
+	if c.BlameSince == "" {
+		if c.BlameContext > 0 {
+			return errors.New("--blame-context requires --blame-since")
+		}
+
+		return nil
+	}
+
+	if c.PrettyJSON || c.PrettyYAML {
+		return errors.New("--blame-since cannot be combined with --pretty-json or --pretty-yaml")
+	}
+
+	return nil
+}
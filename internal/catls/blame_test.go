@@ -0,0 +1,155 @@
+package catls
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+func TestBlameSince(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	tmpDir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", tmpDir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+
+	git("init", "-q")
+	writeTree(t, tmpDir, map[string]string{
+		"main.go": "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+		"old.go":  "package old\n",
+	})
+	git("add", ".")
+	git("commit", "-q", "-m", "first")
+	git("tag", "base")
+	writeTree(t, tmpDir, map[string]string{"main.go": "1\n2\n3\n4\nfive\n6\n7\n8\n9\n10\n"})
+	git("commit", "-q", "-am", "second")
+	writeTree(t, tmpDir, map[string]string{
+		"main.go": "1\n2\n3\n4\nfive\n6\n7\n8\n9\nten\n",
+		"new.go":  "package new\n",
+	})
+
+	app, err := New(&Config{Directory: tmpDir, OutputFormat: OutputFormatXML, Output: io.Discard, BlameSince: "base", BlameContext: 1})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	lines := make(map[string][]string)
+	for file, err := range app.Files(context.Background()) {
+		if err != nil {
+			t.Fatalf("Files() unexpected error: %v", err)
+		}
+		for _, line := range file.Lines {
+			lines[file.Info.RelPath] = append(lines[file.Info.RelPath], line.Content)
+		}
+	}
+
+	// The committed change and the uncommitted one are kept with one line of
+	// context, and the lines between them become a gap marker
+	want := "⋯ 3 lines not shown|4|five|6|⋯ 2 lines not shown|9|ten"
+	if got := strings.Join(lines["main.go"], "|"); got != want {
+		t.Errorf("main.go lines = %q, want %q", got, want)
+	}
+	if _, ok := lines["old.go"]; ok {
+		t.Error("old.go has no changes since base but was not skipped")
+	}
+	if got := strings.Join(lines["new.go"], "|"); got != "package new" {
+		t.Errorf("untracked new.go lines = %q, want the whole file", got)
+	}
+	if app.stats.SkippedUnchanged != 1 {
+		t.Errorf("SkippedUnchanged = %d, want 1", app.stats.SkippedUnchanged)
+	}
+
+	// A date before every commit keeps every line
+	app, err = New(&Config{Directory: tmpDir, OutputFormat: OutputFormatXML, Output: io.Discard, BlameSince: "2000-01-01"})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	count := 0
+	for file, err := range app.Files(context.Background()) {
+		if err != nil {
+			t.Fatalf("Files() unexpected error: %v", err)
+		}
+		count++
+		if file.Info.RelPath == "old.go" && len(file.Lines) != 1 {
+			t.Errorf("old.go lines = %v, want the whole file", file.Lines)
+		}
+	}
+	if count != 3 {
+		t.Errorf("Files() yielded %d files, want 3", count)
+	}
+
+	app, err = New(&Config{Directory: tmpDir, OutputFormat: OutputFormatXML, Output: io.Discard, BlameSince: "not-a-ref"})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if err := app.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "is neither a commit") {
+		t.Errorf("Run() with an unknown ref error = %v, want it rejected", err)
+	}
+
+	app, err = New(&Config{Directory: t.TempDir(), OutputFormat: OutputFormatXML, Output: io.Discard, BlameSince: "HEAD"})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if err := app.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "not inside one") {
+		t.Errorf("Run() outside a repository error = %v, want a clear error", err)
+	}
+}
+
+func TestBlameGapMarkers(t *testing.T) {
+	file := ProcessedFile{
+		Info:     scanner.FileInfo{Path: "/tmp/main.go", RelPath: "main.go"},
+		FileType: "go",
+		Lines: []FilteredLine{
+			{LineNumber: 1, Content: "⋯ 3 lines not shown", Gap: 3},
+			{LineNumber: 4, Content: "four"},
+			{LineNumber: 5, Content: "⋯ 2 lines not shown", Gap: 2},
+		},
+		TotalLines: 6,
+	}
+
+	tests := []struct {
+		name      string
+		formatter func(io.Writer) OutputFormatter
+		want      string
+	}{
+		{
+			name:      "xml",
+			formatter: func(w io.Writer) OutputFormatter { return NewXMLOutput(w) },
+			want:      "<content>\n      <gap lines=\"3\">⋯ 3 lines not shown</gap>\n   4| four\n      <gap lines=\"2\">⋯ 2 lines not shown</gap>\n</content>",
+		},
+		{
+			name: "xml with cdata",
+			formatter: func(w io.Writer) OutputFormatter {
+				x := NewXMLOutput(w)
+				x.cdata = true
+				return x
+			},
+			want: "<![CDATA[\n      ]]><gap lines=\"3\">⋯ 3 lines not shown</gap><![CDATA[\n   4| four\n",
+		},
+		{
+			name:      "markdown",
+			formatter: func(w io.Writer) OutputFormatter { return NewMarkdownOutput(w) },
+			want:      "*⋯ 3 lines not shown*\n\n```go name=\"main.go\"\n   4| four\n```\n\n*⋯ 2 lines not shown*\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			runFormatter(t, tt.formatter(&buf), []ProcessedFile{file}, &Config{ShowLineNumbers: true})
+			if !strings.Contains(buf.String(), tt.want) {
+				t.Errorf("output = %q, want it to contain %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
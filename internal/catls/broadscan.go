@@ -0,0 +1,82 @@
+package catls
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// BroadScanEntries is the number of entries directly under the scanned
+// directory above which a recursive scan counts as broad.
+const BroadScanEntries = 1000
+
+// ErrBroadScan is returned when a recursive scan of the home directory, a
+// filesystem root, or a very wide directory is neither forced nor confirmed.
+var ErrBroadScan = errors.New("refusing to scan a broad directory")
+
+// checkBroadScan guards recursive scans that would walk far more than a
+// project: the user's home directory, a filesystem root, or a directory with
+// more than BroadScanEntries entries at its top level. Such a scan goes ahead
+// with Force or when ConfirmBroadScan agrees; otherwise it fails naming the
+// reason. Scans of explicit Paths never walk, so they are never guarded.
+func (a *App) checkBroadScan() error {
+	if a.cfg.Force || !a.cfg.Recursive || len(a.cfg.Paths) > 0 {
+		return nil
+	}
+
+	dir := resolveScanDir(a.cfg.Directory)
+	reason := broadScanReason(dir)
+	if reason == "" {
+		return nil
+	}
+
+	if a.cfg.ConfirmBroadScan != nil {
+		if a.cfg.ConfirmBroadScan(dir, reason) {
+			return nil
+		}
+
+		return fmt.Errorf("%w: %s is %s; scan cancelled", ErrBroadScan, dir, reason)
+	}
+
+	return fmt.Errorf("%w: %s is %s; pass --force to scan it anyway", ErrBroadScan, dir, reason)
+}
+
+// broadScanReason describes why a recursive scan of dir is broad, or returns
+// an empty string for an ordinary directory.
+func broadScanReason(dir string) string {
+	if filepath.Dir(dir) == dir {
+		return "a filesystem root"
+	}
+	if home, err := os.UserHomeDir(); err == nil && resolveScanDir(home) == dir {
+		return "your home directory"
+	}
+
+	f, err := os.Open(dir)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	// Reading one past the limit is enough to tell, however wide dir is
+	names, _ := f.Readdirnames(BroadScanEntries + 1)
+	if len(names) > BroadScanEntries {
+		return fmt.Sprintf("a directory with more than %d entries", BroadScanEntries)
+	}
+
+	return ""
+}
+
+// resolveScanDir returns dir as an absolute path with symlinks resolved, so
+// a link to the home directory is recognized. It falls back to the cleaned
+// absolute path when dir cannot be resolved.
+func resolveScanDir(dir string) string {
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		return abs
+	}
+
+	return filepath.Clean(dir)
+}
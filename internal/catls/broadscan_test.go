@@ -0,0 +1,99 @@
+package catls
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBroadScanGuard(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	if err := os.WriteFile(filepath.Join(home, "notes.txt"), []byte("hi\n"), 0o644); err != nil {
+		t.Fatalf("failed to write notes.txt: %v", err)
+	}
+
+	wide := t.TempDir()
+	for i := range BroadScanEntries + 1 {
+		if err := os.WriteFile(filepath.Join(wide, fmt.Sprintf("f%04d.txt", i)), nil, 0o644); err != nil {
+			t.Fatalf("failed to write file %d: %v", i, err)
+		}
+	}
+
+	project := t.TempDir()
+	if err := os.WriteFile(filepath.Join(project, "main.go"), []byte("package main\n"), 0o644); err != nil {
+		t.Fatalf("failed to write main.go: %v", err)
+	}
+
+	link := filepath.Join(t.TempDir(), "home")
+	if err := os.Symlink(home, link); err != nil {
+		t.Fatalf("failed to link home: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		ask     bool // Set ConfirmBroadScan, answering with answer
+		answer  bool
+		wantErr string
+	}{
+		{name: "project", cfg: Config{Directory: project, Recursive: true}},
+		{name: "home", cfg: Config{Directory: home, Recursive: true}, wantErr: "is your home directory; pass --force"},
+		{name: "link to home", cfg: Config{Directory: link, Recursive: true}, wantErr: "is your home directory"},
+		{name: "filesystem root", cfg: Config{Directory: string(filepath.Separator), Recursive: true}, wantErr: "is a filesystem root"},
+		{name: "wide", cfg: Config{Directory: wide, Recursive: true}, wantErr: fmt.Sprintf("more than %d entries", BroadScanEntries)},
+		{name: "home without -r", cfg: Config{Directory: home}},
+		{name: "home with paths", cfg: Config{Directory: home, Recursive: true, Paths: []string{"notes.txt"}}},
+		{name: "forced", cfg: Config{Directory: home, Recursive: true, Force: true}},
+		{name: "confirmed", cfg: Config{Directory: home, Recursive: true}, ask: true, answer: true},
+		{name: "declined", cfg: Config{Directory: home, Recursive: true}, ask: true, wantErr: "scan cancelled"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			cfg := tt.cfg
+			cfg.Output = &buf
+			cfg.OutputFormat = OutputFormatXML
+			asked := false
+			if tt.ask {
+				cfg.ConfirmBroadScan = func(dir, reason string) bool {
+					asked = true
+					if dir != resolveScanDir(home) || reason != "your home directory" {
+						t.Errorf("ConfirmBroadScan(%q, %q), want the home directory", dir, reason)
+					}
+
+					return tt.answer
+				}
+			}
+
+			app, err := New(&cfg)
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+			err = app.Run(context.Background())
+			if asked != tt.ask {
+				t.Errorf("ConfirmBroadScan asked = %v, want %v", asked, tt.ask)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Run() unexpected error: %v", err)
+				}
+
+				return
+			}
+			if !errors.Is(err, ErrBroadScan) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Run() error = %v, want ErrBroadScan containing %q", err, tt.wantErr)
+			}
+			if buf.Len() != 0 {
+				t.Errorf("Run() wrote output before refusing the scan: %q", buf.String())
+			}
+		})
+	}
+}
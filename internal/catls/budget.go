@@ -0,0 +1,103 @@
+package catls
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+// Reasons recorded on OmittedFile.
+const (
+	OmitReasonEmpty         = "empty"
+	OmitReasonNoTodos       = "no todos"
+	OmitReasonPattern       = "does not match every pattern"
+	OmitReasonNoFrontMatter = "no front matter"
+	OmitReasonBudget        = "over token budget"
+	OmitReasonDirLimit      = "over per-directory limit"
+	OmitReasonUnchanged     = "unchanged since cutoff"
+)
+
+// OmittedFile is a file the scan selected but the run left out of its output.
+type OmittedFile struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// RunSummary describes what a run wrote and what it left out.
+type RunSummary struct {
+	Root      string        // Base name of the scanned directory
+	Tokens    int           // Estimated tokens of the written content
+	MaxTokens int           // Token budget in effect (0 means no limit)
+	Omitted   []OmittedFile // Files left out, in scan order
+}
+
+// RunSummaryWriter is implemented by formatters that report what a run left
+// out. App calls WriteRunSummary after the last WriteFile and before
+// WriteFooter. Formatters that do not implement it simply omit the summary.
+type RunSummaryWriter interface {
+	WriteRunSummary(ctx context.Context, summary RunSummary) error
+}
+
+// estimateTokens estimates the tokens of the content a file contributes, with
+// the same bytes-per-token ratio Estimate uses.
+func estimateTokens(file *ProcessedFile) int {
+	size := 0
+	for _, line := range file.Lines {
+		size += len(line.Content) + 1
+	}
+
+	return (size + bytesPerToken - 1) / bytesPerToken
+}
+
+// omit records a file left out of the output.
+func (a *App) omit(file scanner.FileInfo, reason string) {
+	a.omitted = append(a.omitted, OmittedFile{Path: file.RelPath, Reason: reason})
+	a.fileProcessed(file, nil, reason)
+}
+
+// shouldSkipBudget reports whether MaxTokens drops this file because its
+// content no longer fits. Later, smaller files may still fit.
+func (a *App) shouldSkipBudget(file *ProcessedFile) bool {
+	tokens := estimateTokens(file)
+	if a.cfg.MaxTokens == 0 || a.tokens+tokens <= a.cfg.MaxTokens {
+		a.tokens += tokens
+
+		return false
+	}
+
+	if a.cfg.Debug {
+		fmt.Fprintf(a.warn, "Debug: Skipping file over the token budget (%d tokens): %s\n", tokens, file.Info.RelPath)
+	}
+	a.stats.SkippedBudget++
+	a.omit(file.Info, OmitReasonBudget)
+
+	return true
+}
+
+// runSummary summarizes the most recent run for RunSummaryWriter.
+func (a *App) runSummary() RunSummary {
+	return RunSummary{
+		Root:      a.rootName(),
+		Tokens:    a.tokens,
+		MaxTokens: a.cfg.MaxTokens,
+		Omitted:   a.omitted,
+	}
+}
+
+// rootName returns the base name of the scanned directory, as reported in
+// run summaries and the configuration echo. It is empty when Deterministic is
+// set, since it names the checkout directory.
+func (a *App) rootName() string {
+	if a.cfg.Deterministic {
+		return ""
+	}
+
+	root := a.cfg.Directory
+	if abs, err := filepath.Abs(root); err == nil {
+		root = filepath.Base(abs)
+	}
+
+	return root
+}
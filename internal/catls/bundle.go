@@ -0,0 +1,423 @@
+package catls
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/connerohnesorge/catls/internal/unidiff"
+)
+
+// bundleDiffContext is how many unchanged lines surround each change in the
+// diffs of a BundleDiff.
+const bundleDiffContext = 3
+
+// ErrBundlesDiffer is returned when two bundles list different files or
+// different content.
+var ErrBundlesDiffer = errors.New("bundles differ")
+
+// BundleKind is the kind of file a Bundle was read from.
+type BundleKind string
+
+// Kinds of Bundle.
+const (
+	BundleKindJSON     BundleKind = "json"     // Output of --format json
+	BundleKindManifest BundleKind = "manifest" // Written by --manifest
+)
+
+// Bundle is a catls output read back for comparison: the files a run wrote
+// and what it recorded about each.
+type Bundle struct {
+	Path  string
+	Kind  BundleKind
+	Files []BundleFile
+}
+
+// BundleFile is one file of a Bundle. A manifest records a hash; JSON output
+// records the lines it wrote.
+type BundleFile struct {
+	Path       string   // Relative path, normalized by normalizeBundlePath
+	SHA256     string   // Manifests only
+	Lines      []string // JSON only; the lines written, after any truncation
+	Numbers    []int    // JSON only; the line number of each of Lines
+	TotalLines int      // JSON only
+	Truncated  bool     // Lines leaves out part of the file
+	Binary     bool     // The content was not written
+	Error      string   // The file could not be read
+}
+
+// ReadBundle loads a JSON output or a manifest from path. Directory records
+// are left out; files are keyed by their normalized path.
+func ReadBundle(path string) (Bundle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Bundle{}, fmt.Errorf("cannot read bundle: %w", err)
+	}
+
+	var probe struct {
+		Config json.RawMessage `json:"config"`
+		Files  json.RawMessage `json:"files"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil || probe.Files == nil {
+		return Bundle{}, fmt.Errorf("%s is neither catls JSON output nor a manifest", path)
+	}
+
+	bundle := Bundle{Path: path, Kind: BundleKindJSON}
+	if probe.Config != nil {
+		manifest, err := ReadManifest(path)
+		if err != nil {
+			return Bundle{}, err
+		}
+		bundle.Kind = BundleKindManifest
+		for _, file := range manifest.Files {
+			bundle.Files = append(bundle.Files, BundleFile{Path: file.Path, SHA256: file.SHA256})
+		}
+	} else {
+		var files []JSONFile
+		if err := json.Unmarshal(probe.Files, &files); err != nil {
+			return Bundle{}, fmt.Errorf("invalid JSON output %s: %w", path, err)
+		}
+		for _, file := range files {
+			if file.Kind == jsonKindDirectory {
+				continue
+			}
+			bundle.Files = append(bundle.Files, newBundleFile(file))
+		}
+	}
+
+	seen := make(map[string]bool, len(bundle.Files))
+	for i := range bundle.Files {
+		bundle.Files[i].Path = normalizeBundlePath(bundle.Files[i].Path)
+		if seen[bundle.Files[i].Path] {
+			return Bundle{}, fmt.Errorf("%s lists %s more than once", path, bundle.Files[i].Path)
+		}
+		seen[bundle.Files[i].Path] = true
+	}
+
+	return bundle, nil
+}
+
+// newBundleFile keeps what a JSON file records about its content. Lines
+// folded by Fold are joined again.
+func newBundleFile(file JSONFile) BundleFile {
+	bundleFile := BundleFile{
+		Path:       file.Path,
+		TotalLines: file.TotalLines,
+		Truncated:  file.Truncated,
+		Binary:     file.Binary,
+		Lines:      make([]string, 0, len(file.Lines)),
+		Numbers:    make([]int, 0, len(file.Lines)),
+	}
+	if file.Error != nil {
+		bundleFile.Error = *file.Error
+	}
+	for _, line := range file.Lines {
+		if line.Gap > 0 {
+			continue
+		}
+		if n := len(bundleFile.Lines); line.Continued && n > 0 {
+			bundleFile.Lines[n-1] = strings.TrimSuffix(bundleFile.Lines[n-1], foldMarker) + line.Content
+
+			continue
+		}
+		bundleFile.Lines = append(bundleFile.Lines, line.Content)
+		bundleFile.Numbers = append(bundleFile.Numbers, line.Number)
+	}
+
+	return bundleFile
+}
+
+// normalizeBundlePath writes path with forward slashes and without "./" or
+// redundant separators, so outputs written on different systems or with
+// different spellings of the directory compare equal.
+func normalizeBundlePath(p string) string {
+	return path.Clean(strings.ReplaceAll(p, `\`, "/"))
+}
+
+// Reasons a file is reported as incomparable.
+const (
+	incomparableTruncated  = "truncated"  // One side leaves out lines the other may differ in
+	incomparableBinary     = "binary"     // Neither side wrote the content
+	incomparableUnreadable = "unreadable" // One side could not read the file
+)
+
+// BundleDiff lists how the files of a newer bundle differ from an older one.
+type BundleDiff struct {
+	Old          string               `json:"old"`
+	New          string               `json:"new"`
+	Added        []string             `json:"added"`   // Only in the newer bundle
+	Removed      []string             `json:"removed"` // Only in the older bundle
+	Changed      []BundleChange       `json:"changed"`
+	Incomparable []BundleIncomparable `json:"incomparable"` // In both, but what was recorded cannot tell
+	Unchanged    int                  `json:"unchanged"`
+}
+
+// BundleChange is a file whose content differs between two bundles.
+type BundleChange struct {
+	Path string `json:"path"`
+	Diff string `json:"diff,omitempty"` // Unified diff of the recorded lines, when requested and recorded
+}
+
+// BundleIncomparable is a file in both bundles whose content cannot be
+// compared, with the reason.
+type BundleIncomparable struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// Differences returns the number of added, removed, and changed files.
+// Incomparable files are not counted.
+func (d BundleDiff) Differences() int {
+	return len(d.Added) + len(d.Removed) + len(d.Changed)
+}
+
+// DiffBundles compares the newer bundle with the older one. Files are matched
+// by normalized path. Manifests are compared by hash and JSON outputs by the
+// lines they recorded; a JSON output cannot be compared with a manifest. With
+// withDiff, changed files of JSON outputs carry a unified diff. Paths keep the
+// order of the bundle they come from.
+func DiffBundles(older, newer Bundle, withDiff bool) (BundleDiff, error) {
+	if older.Kind != newer.Kind {
+		return BundleDiff{}, fmt.Errorf("cannot compare %s (%s) with %s (%s)", older.Path, older.Kind, newer.Path, newer.Kind)
+	}
+
+	diff := BundleDiff{
+		Old:          older.Path,
+		New:          newer.Path,
+		Added:        []string{},
+		Removed:      []string{},
+		Changed:      []BundleChange{},
+		Incomparable: []BundleIncomparable{},
+	}
+
+	files := make(map[string]BundleFile, len(newer.Files))
+	for _, file := range newer.Files {
+		files[file.Path] = file
+	}
+
+	seen := make(map[string]bool, len(older.Files))
+	for _, old := range older.Files {
+		seen[old.Path] = true
+		current, ok := files[old.Path]
+		if !ok {
+			diff.Removed = append(diff.Removed, old.Path)
+
+			continue
+		}
+
+		changed, reason := compareBundleFiles(old, current)
+		switch {
+		case changed:
+			change := BundleChange{Path: old.Path}
+			if withDiff && newer.Kind == BundleKindJSON {
+				change.Diff = bundleFileDiff(old, current)
+			}
+			diff.Changed = append(diff.Changed, change)
+		case reason != "":
+			diff.Incomparable = append(diff.Incomparable, BundleIncomparable{Path: old.Path, Reason: reason})
+		default:
+			diff.Unchanged++
+		}
+	}
+	for _, file := range newer.Files {
+		if !seen[file.Path] {
+			diff.Added = append(diff.Added, file.Path)
+		}
+	}
+
+	return diff, nil
+}
+
+// compareBundleFiles reports whether a file changed between two bundles or,
+// when what they recorded cannot tell, why not. Truncated content still
+// counts as changed when the line counts or the lines both sides wrote differ.
+// Lines are compared with their numbers, so the same line moved within a
+// file, as kept by --todos or a line range, is a change.
+func compareBundleFiles(old, current BundleFile) (bool, string) {
+	if old.SHA256 != "" || current.SHA256 != "" {
+		return old.SHA256 != current.SHA256, ""
+	}
+
+	switch {
+	case old.Error != "" || current.Error != "":
+		return false, incomparableUnreadable
+	case old.Binary != current.Binary:
+		return true, ""
+	case old.Binary:
+		return false, incomparableBinary
+	case old.TotalLines != current.TotalLines:
+		return true, ""
+	}
+
+	shown := min(len(old.Lines), len(current.Lines))
+	if !slices.Equal(old.Lines[:shown], current.Lines[:shown]) ||
+		!slices.Equal(shownNumbers(old, shown), shownNumbers(current, shown)) {
+		return true, ""
+	}
+	if old.Truncated || current.Truncated {
+		return false, incomparableTruncated
+	}
+
+	return len(old.Lines) != len(current.Lines), ""
+}
+
+// shownNumbers returns the numbers of the first n lines of file, as many as
+// it recorded.
+func shownNumbers(file BundleFile, n int) []int {
+	return file.Numbers[:min(n, len(file.Numbers))]
+}
+
+// bundleFileDiff returns a unified diff of the lines both bundles recorded
+// for a file. When either side is truncated, only the lines both wrote are
+// compared, so lines one side left out do not show as removed. When only
+// line numbers changed, the lines are diffed with their numbers.
+func bundleFileDiff(old, current BundleFile) string {
+	oldLines, newLines := old.Lines, current.Lines
+	if old.Truncated || current.Truncated {
+		shown := min(len(oldLines), len(newLines))
+		oldLines, newLines = oldLines[:shown], newLines[:shown]
+	}
+	if slices.Equal(oldLines, newLines) {
+		oldLines, newLines = numberedBundleLines(old, len(oldLines)), numberedBundleLines(current, len(newLines))
+	}
+
+	return unidiff.Lines("a/"+old.Path, "b/"+current.Path, oldLines, newLines, bundleDiffContext)
+}
+
+// numberedBundleLines returns the first n lines of file, each prefixed with its
+// line number.
+func numberedBundleLines(file BundleFile, n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		number := 0
+		if i < len(file.Numbers) {
+			number = file.Numbers[i]
+		}
+		lines[i] = fmt.Sprintf("%d: %s", number, file.Lines[i])
+	}
+
+	return lines
+}
+
+// WriteBundleDiff renders diff in the given format. An empty format writes a
+// short summary for people: one line per difference, the diffs, and a total.
+func WriteBundleDiff(w io.Writer, format OutputFormat, diff BundleDiff) error {
+	var b strings.Builder
+
+	switch format {
+	case "":
+		writeBundleDiffSummary(&b, diff)
+	case OutputFormatXML:
+		writeBundleDiffXML(&b, diff)
+	case OutputFormatJSON, OutputFormatChunks:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(diff)
+	case OutputFormatMarkdown, OutputFormatPrompt, OutputFormatPretty:
+		writeBundleDiffMarkdown(&b, diff)
+	default:
+		return fmt.Errorf("diff-bundles does not support output format: %s", format)
+	}
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+func writeBundleDiffSummary(b *strings.Builder, diff BundleDiff) {
+	for _, path := range diff.Added {
+		fmt.Fprintf(b, "%-12s  %s\n", "added", path)
+	}
+	for _, path := range diff.Removed {
+		fmt.Fprintf(b, "%-12s  %s\n", "removed", path)
+	}
+	for _, change := range diff.Changed {
+		fmt.Fprintf(b, "%-12s  %s\n", "changed", change.Path)
+	}
+	for _, file := range diff.Incomparable {
+		fmt.Fprintf(b, "%-12s  %s (content incomparable: %s)\n", "incomparable", file.Path, file.Reason)
+	}
+	for _, change := range diff.Changed {
+		if change.Diff != "" {
+			b.WriteString("\n" + change.Diff)
+		}
+	}
+
+	if diff.Differences() == 0 {
+		fmt.Fprintf(b, "OK: %d files match between %s and %s", diff.Unchanged, diff.Old, diff.New)
+		if len(diff.Incomparable) > 0 {
+			fmt.Fprintf(b, ", %d incomparable", len(diff.Incomparable))
+		}
+		b.WriteString("\n")
+
+		return
+	}
+	fmt.Fprintf(b, "%d differences between %s and %s: %d added, %d removed, %d changed, %d incomparable, %d unchanged\n",
+		diff.Differences(), diff.Old, diff.New, len(diff.Added), len(diff.Removed), len(diff.Changed),
+		len(diff.Incomparable), diff.Unchanged)
+}
+
+func writeBundleDiffXML(b *strings.Builder, diff BundleDiff) {
+	fmt.Fprintf(b, "<diff-bundles old=\"%s\" new=\"%s\" unchanged=\"%d\">\n",
+		escapeXMLAttr(diff.Old), escapeXMLAttr(diff.New), diff.Unchanged)
+	for _, path := range diff.Added {
+		fmt.Fprintf(b, "<added path=\"%s\"/>\n", escapeXMLAttr(path))
+	}
+	for _, path := range diff.Removed {
+		fmt.Fprintf(b, "<removed path=\"%s\"/>\n", escapeXMLAttr(path))
+	}
+	for _, change := range diff.Changed {
+		if change.Diff == "" {
+			fmt.Fprintf(b, "<changed path=\"%s\"/>\n", escapeXMLAttr(change.Path))
+
+			continue
+		}
+		fmt.Fprintf(b, "<changed path=\"%s\">\n<diff>\n%s</diff>\n</changed>\n",
+			escapeXMLAttr(change.Path), escapeXMLText(change.Diff))
+	}
+	for _, file := range diff.Incomparable {
+		fmt.Fprintf(b, "<incomparable path=\"%s\" reason=\"%s\"/>\n", escapeXMLAttr(file.Path), file.Reason)
+	}
+	b.WriteString("</diff-bundles>\n")
+}
+
+func writeBundleDiffMarkdown(b *strings.Builder, diff BundleDiff) {
+	b.WriteString("# Bundle diff\n\n")
+	fmt.Fprintf(b, "- Old: %s\n", diff.Old)
+	fmt.Fprintf(b, "- New: %s\n", diff.New)
+	fmt.Fprintf(b, "- Added: %d\n", len(diff.Added))
+	fmt.Fprintf(b, "- Removed: %d\n", len(diff.Removed))
+	fmt.Fprintf(b, "- Changed: %d\n", len(diff.Changed))
+	fmt.Fprintf(b, "- Incomparable: %d\n", len(diff.Incomparable))
+	fmt.Fprintf(b, "- Unchanged: %d\n", diff.Unchanged)
+
+	if diff.Differences()+len(diff.Incomparable) > 0 {
+		b.WriteString("\n## Differences\n\n| Change | Path |\n| --- | --- |\n")
+		for _, path := range diff.Added {
+			fmt.Fprintf(b, "| added | %s |\n", path)
+		}
+		for _, path := range diff.Removed {
+			fmt.Fprintf(b, "| removed | %s |\n", path)
+		}
+		for _, change := range diff.Changed {
+			fmt.Fprintf(b, "| changed | %s |\n", change.Path)
+		}
+		for _, file := range diff.Incomparable {
+			fmt.Fprintf(b, "| incomparable (%s) | %s |\n", file.Reason, file.Path)
+		}
+	}
+
+	for _, change := range diff.Changed {
+		if change.Diff == "" {
+			continue
+		}
+		fence := longestRunFence([]FilteredLine{{Content: change.Diff}}, '`')
+		fmt.Fprintf(b, "\n## %s\n\n%sdiff\n%s%s\n", change.Path, fence, change.Diff, fence)
+	}
+}
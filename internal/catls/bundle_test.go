@@ -0,0 +1,172 @@
+package catls
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadBundle(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+
+		return path
+	}
+
+	jsonOutput := write("out.json", `{"files": [
+		{"path": "src", "kind": "directory", "binary": false, "totalLines": 0, "truncated": false},
+		{"path": "./src/a.go", "binary": false, "lines": [{"number": 1, "content": "package a"}], "totalLines": 1, "truncated": false},
+		{"path": "src\\b.go", "binary": false, "lines": [{"number": 1, "content": "package b"}], "totalLines": 9, "truncated": true}
+	]}`)
+	bundle, err := ReadBundle(jsonOutput)
+	if err != nil {
+		t.Fatalf("ReadBundle() unexpected error: %v", err)
+	}
+	want := []BundleFile{
+		{Path: "src/a.go", Lines: []string{"package a"}, Numbers: []int{1}, TotalLines: 1},
+		{Path: "src/b.go", Lines: []string{"package b"}, Numbers: []int{1}, TotalLines: 9, Truncated: true},
+	}
+	if bundle.Kind != BundleKindJSON || !reflect.DeepEqual(bundle.Files, want) {
+		t.Errorf("ReadBundle() = %s %+v, want json %+v", bundle.Kind, bundle.Files, want)
+	}
+
+	manifest := write("manifest.json", `{"version": 1, "config": {"directory": "."}, "files": [{"path": "a.go", "sha256": "aa"}]}`)
+	if bundle, err := ReadBundle(manifest); err != nil || bundle.Kind != BundleKindManifest || bundle.Files[0].SHA256 != "aa" {
+		t.Errorf("ReadBundle() of a manifest = %+v, %v", bundle, err)
+	}
+
+	for name, content := range map[string]string{
+		"twice.json": `{"files": [{"path": "a.go"}, {"path": "./a.go"}]}`,
+		"xml.json":   `<files></files>`,
+		"other.json": `{"name": "not catls"}`,
+	} {
+		if _, err := ReadBundle(write(name, content)); err == nil {
+			t.Errorf("ReadBundle(%s) should fail", name)
+		}
+	}
+}
+
+func TestDiffBundles(t *testing.T) {
+	lines := func(s string) []string { return strings.Fields(s) }
+	older := Bundle{Path: "old.json", Kind: BundleKindJSON, Files: []BundleFile{
+		{Path: "gone.go", Lines: lines("x"), TotalLines: 1},
+		{Path: "same.go", Lines: lines("a b"), TotalLines: 2},
+		{Path: "edit.go", Lines: lines("a b c"), TotalLines: 3},
+		{Path: "long.go", Lines: lines("a b"), TotalLines: 50, Truncated: true},
+		{Path: "longer.go", Lines: lines("a b"), TotalLines: 50, Truncated: true},
+		{Path: "cut.go", Lines: lines("a b"), TotalLines: 50, Truncated: true},
+		{Path: "moved.go", Lines: lines("TODO"), Numbers: []int{3}, TotalLines: 9},
+		{Path: "logo.png", Binary: true},
+		{Path: "locked.go", Error: "permission denied"},
+	}}
+	newer := Bundle{Path: "new.json", Kind: BundleKindJSON, Files: []BundleFile{
+		{Path: "same.go", Lines: lines("a b"), TotalLines: 2},
+		{Path: "edit.go", Lines: lines("a B c"), TotalLines: 3},
+		{Path: "long.go", Lines: lines("a b c d"), TotalLines: 50, Truncated: true},
+		{Path: "longer.go", Lines: lines("a b"), TotalLines: 51, Truncated: true},
+		{Path: "cut.go", Lines: lines("a z"), TotalLines: 50, Truncated: true},
+		{Path: "moved.go", Lines: lines("TODO"), Numbers: []int{5}, TotalLines: 9},
+		{Path: "logo.png", Binary: true},
+		{Path: "locked.go", Lines: lines("package locked"), TotalLines: 1},
+		{Path: "new.go", Lines: lines("y"), TotalLines: 1},
+	}}
+
+	diff, err := DiffBundles(older, newer, true)
+	if err != nil {
+		t.Fatalf("DiffBundles() unexpected error: %v", err)
+	}
+	want := BundleDiff{
+		Old:     "old.json",
+		New:     "new.json",
+		Added:   []string{"new.go"},
+		Removed: []string{"gone.go"},
+		Changed: []BundleChange{
+			{Path: "edit.go", Diff: "--- a/edit.go\n+++ b/edit.go\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n"},
+			{Path: "longer.go"},
+			{Path: "cut.go", Diff: "--- a/cut.go\n+++ b/cut.go\n@@ -1,2 +1,2 @@\n a\n-b\n+z\n"},
+			{Path: "moved.go", Diff: "--- a/moved.go\n+++ b/moved.go\n@@ -1 +1 @@\n-3: TODO\n+5: TODO\n"},
+		},
+		Incomparable: []BundleIncomparable{
+			{Path: "long.go", Reason: "truncated"},
+			{Path: "logo.png", Reason: "binary"},
+			{Path: "locked.go", Reason: "unreadable"},
+		},
+		Unchanged: 1,
+	}
+	if !reflect.DeepEqual(diff, want) {
+		t.Errorf("DiffBundles() =\n%+v\nwant\n%+v", diff, want)
+	}
+	if diff.Differences() != 6 {
+		t.Errorf("Differences() = %d, want 6", diff.Differences())
+	}
+
+	manifest := Bundle{Path: "m.json", Kind: BundleKindManifest}
+	if _, err := DiffBundles(older, manifest, false); err == nil {
+		t.Error("DiffBundles() of a JSON output and a manifest should fail")
+	}
+
+	hashed := Bundle{Kind: BundleKindManifest, Files: []BundleFile{{Path: "a.go", SHA256: "aa"}, {Path: "b.go", SHA256: "bb"}}}
+	rehashed := Bundle{Kind: BundleKindManifest, Files: []BundleFile{{Path: "a.go", SHA256: "aa"}, {Path: "b.go", SHA256: "b2"}}}
+	if diff, _ := DiffBundles(hashed, rehashed, true); len(diff.Changed) != 1 || diff.Changed[0] != (BundleChange{Path: "b.go"}) || diff.Unchanged != 1 {
+		t.Errorf("DiffBundles() of manifests = %+v, want b.go changed without a diff", diff)
+	}
+}
+
+func TestWriteBundleDiff(t *testing.T) {
+	diff := BundleDiff{
+		Old:          "old.json",
+		New:          "new.json",
+		Added:        []string{"new.go"},
+		Removed:      []string{},
+		Changed:      []BundleChange{{Path: "a&b.go", Diff: "--- a/a&b.go\n+++ b/a&b.go\n@@ -1 +1 @@\n-x\n+y\n"}},
+		Incomparable: []BundleIncomparable{{Path: "long.go", Reason: "truncated"}},
+		Unchanged:    2,
+	}
+
+	tests := []struct {
+		format OutputFormat
+		want   []string
+	}{
+		{format: "", want: []string{
+			"added         new.go\n",
+			"incomparable  long.go (content incomparable: truncated)\n",
+			"\n--- a/a&b.go\n",
+			"2 differences between old.json and new.json: 1 added, 0 removed, 1 changed, 1 incomparable, 2 unchanged\n",
+		}},
+		{format: OutputFormatXML, want: []string{
+			`<diff-bundles old="old.json" new="new.json" unchanged="2">`,
+			"<changed path=\"a&amp;b.go\">\n<diff>\n--- a/a&amp;b.go\n",
+			`<incomparable path="long.go" reason="truncated"/>`,
+		}},
+		{format: OutputFormatMarkdown, want: []string{"- Incomparable: 1\n", "| incomparable (truncated) | long.go |\n", "## a&b.go\n\n```diff\n--- a/a&b.go\n"}},
+		{format: OutputFormatJSON, want: []string{`"removed": []`, `"reason": "truncated"`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteBundleDiff(&buf, tt.format, diff); err != nil {
+				t.Fatalf("WriteBundleDiff() error = %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("output missing %q:\n%s", want, buf.String())
+				}
+			}
+		})
+	}
+
+	var buf bytes.Buffer
+	clean := BundleDiff{Old: "a", New: "b", Unchanged: 3}
+	if err := WriteBundleDiff(&buf, "", clean); err != nil || buf.String() != "OK: 3 files match between a and b\n" {
+		t.Errorf("WriteBundleDiff() of no differences = %q, %v", buf.String(), err)
+	}
+}
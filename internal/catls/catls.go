@@ -3,16 +3,32 @@ package catls
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"iter"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
+	"github.com/connerohnesorge/catls/internal/contentcache"
+	"github.com/connerohnesorge/catls/internal/gitignore"
 	"github.com/connerohnesorge/catls/internal/interactive"
+	"github.com/connerohnesorge/catls/internal/profile"
 	"github.com/connerohnesorge/catls/internal/reorder"
 	"github.com/connerohnesorge/catls/internal/scanner"
+	"github.com/connerohnesorge/catls/internal/xattr"
 )
 
+// ErrCaseCollision is returned when FailOnCaseCollision is set and two selected
+// paths differ only by case.
+var ErrCaseCollision = errors.New("paths differ only by case")
+
+// RelativeToGit is the RelativeTo value that makes paths relative to the
+// root of the git work tree containing Directory, as git ls-files shows them.
+const RelativeToGit = "git"
+
 // Config holds all configuration options for catls.
 type Config struct {
 	Directory       string
@@ -25,11 +41,333 @@ type Config struct {
 	IgnoreDir       []string
 	Globs           []string
 	IgnoreGlobs     []string
-	ContentPattern  string
+	ContentPatterns []string
 	ShowLineNumbers bool
 	OmitBins        bool
 	OutputFormat    OutputFormat
-	RelativeTo      string
+	// RelativeTo is the directory output paths are relative to, instead of
+	// Directory; "." shows paths as they were found, and RelativeToGit the
+	// root of the git work tree containing Directory.
+	RelativeTo string
+	// Sort orders scanned files by relative path, and with them the
+	// interactive list and the groups ReadmeFirst and MaxFilesPerDir form.
+	// Empty means SortName. Paths keep the order they are given in.
+	Sort SortOrder
+
+	// Paths lists files relative to Directory to process, in this order,
+	// instead of scanning it. Named files bypass the hidden-file rule and the
+	// default ignore globs, which exist to prune scans; other filters apply.
+	Paths []string
+	// ScanArtifact, when set, supplies the files of an earlier scan in place
+	// of scanning Directory, which must be its root. The file filter applies
+	// to them again; the settings that decide what a scan visits do not.
+	ScanArtifact *ScanArtifact
+	// LineNumberFormat selects the gutter preset used when ShowLineNumbers is set.
+	LineNumberFormat LineNumberFormat
+	// SkipEmpty excludes zero-byte and whitespace-only files from output.
+	SkipEmpty bool
+	// OneFileSystem keeps recursive scans on the filesystem holding Directory.
+	OneFileSystem bool
+	// SkipGitSubmodules stops recursion at directories that are git submodules.
+	SkipGitSubmodules bool
+	// FollowSymlinks descends into symlinked directories and junctions, which
+	// are otherwise skipped; a link back to a scanned directory is not followed.
+	FollowSymlinks bool
+	// FenceStyle selects how Markdown output delimits file content.
+	FenceStyle FenceStyle
+	// Sentinel is a template for the lines surrounding content when FenceStyle is none.
+	Sentinel string
+	// ReadmeFirst places each directory's README ahead of its other files and
+	// marks it as documentation.
+	ReadmeFirst bool
+	// ReadmeLines limits READMEs placed by ReadmeFirst to this many lines (0 means no limit).
+	ReadmeLines int
+	// Output receives the formatted output and, unless List is set, status
+	// messages. Nil means os.Stdout.
+	Output io.Writer
+	// Warnings receives warnings, debug messages and the run summary, kept
+	// apart from Output. Nil means os.Stderr.
+	Warnings io.Writer
+	// Throttle limits writes to Output to this many bytes per second, in
+	// small chunks, for consumers that fail when fed everything at once
+	// (0 means no limit). Files in OutputDir are written at full speed.
+	Throttle int64
+	// Integrity closes the document with an IntegrityTrailer recording the
+	// files and lines written and the SHA-256 of everything before it, so a
+	// receiver can run CheckIntegrity to tell whether it arrived whole. Only
+	// formats implementing IntegrityWriter support it.
+	Integrity bool
+	// OutputDir writes the formatted output to a file per format in this
+	// directory, named by OutputFileName, instead of Output. The directory is
+	// created if needed; status messages still go to Output.
+	OutputDir string
+	// OutputFormats lists several formats to write to OutputDir from a single
+	// scan, OutputFormat among them. Empty means OutputFormat alone.
+	OutputFormats []OutputFormat
+	// OutputTemplate splits the output into one document per file and
+	// format under OutputDir, named by executing this text/template with
+	// OutputTemplateData, and lists them in OutputIndexName.
+	OutputTemplate string
+	// IncludeDirs adds a structural record for every traversed directory,
+	// including empty ones. Directories are never matched against file globs.
+	IncludeDirs bool
+	// ExpandTabs replaces tabs with spaces up to tab stops this many columns
+	// apart (0 leaves tabs alone).
+	ExpandTabs int
+	// StripANSI removes ANSI escape sequences from file content.
+	StripANSI bool
+	// TrimTrailing removes trailing whitespace from every line.
+	TrimTrailing bool
+	// NormalizeCRLF resolves carriage returns left in lines after splitting,
+	// keeping the text after the last one, as a progress redraw leaves it.
+	NormalizeCRLF bool
+	// Types keeps only files whose detected type is listed; TypeUnknown selects
+	// files without one.
+	Types []string
+	// ExcludeTypes drops files whose detected type is listed.
+	ExcludeTypes []string
+	// LangMap overrides type detection for files with these extensions, keyed
+	// by lowercase extension without the dot.
+	LangMap map[string]string
+	// NoDirectives ignores catls:lang=NAME directives. Otherwise a directive
+	// in any comment within the first three lines of a file sets its type,
+	// over LangMap and every other kind of detection.
+	NoDirectives bool
+	// OnlyExecutable keeps only executable files.
+	OnlyExecutable bool
+	// NoExecutable drops executable files.
+	NoExecutable bool
+	// Imports keeps only Go files importing one of the listed packages, by
+	// full import path or a trailing part of it after a slash.
+	Imports []string
+	// ImportsIncludeOthers keeps files that are not Go files alongside those
+	// Imports selects, instead of dropping them.
+	ImportsIncludeOthers bool
+	// FormatOptions holds --format-opt settings as given, keyed "format:key" or,
+	// for the selected format, a bare "key". See ParseFormatOptions.
+	FormatOptions map[string]string
+	// EmbedImages embeds binary images up to this many bytes inline in
+	// Markdown output (0 leaves them as binary placeholders).
+	EmbedImages int64
+	// ContentEncoding with ContentEncodingBase64 adds the exact bytes of each
+	// file to JSON and XML output. Empty means ContentEncodingText.
+	ContentEncoding ContentEncoding
+	// RawContentMax caps the files ContentEncodingBase64 encodes, in bytes (0
+	// means DefaultRawContentMax). Larger files keep only their lines, or the
+	// binary placeholder.
+	RawContentMax int64
+	// PrettyJSON re-indents the content of JSON files before it is filtered
+	// and numbered, so line numbers refer to the reformatted text. Files that
+	// do not parse are shown as they are.
+	PrettyJSON bool
+	// PrettyYAML re-indents the content of YAML files like PrettyJSON.
+	PrettyYAML bool
+	// PrettyMaxSize caps the files PrettyJSON and PrettyYAML reformat, in
+	// bytes (0 means DefaultPrettyMaxSize).
+	PrettyMaxSize int64
+	// Signatures reduces Go, Python, JavaScript and TypeScript files to a map
+	// of their declarations: package and import lines, type and function
+	// signatures, and doc comments, with function bodies elided. Line numbers
+	// still refer to the file.
+	Signatures bool
+	// SignaturesFallback selects what Signatures writes for files of other
+	// languages (empty means SignaturesFallbackFull).
+	SignaturesFallback SignaturesFallback
+	// SummarizeLockfiles writes a summary of known lockfiles, such as
+	// package-lock.json and go.sum, in place of their content: the number of
+	// locked packages, the direct dependencies where the lockfile names them,
+	// and the size and hash of the file.
+	SummarizeLockfiles bool
+	// Xattrs records the names, not the values, of each file's extended
+	// attributes, such as com.apple.quarantine, in json and xml output and
+	// the manifest. Only Linux and macOS support it.
+	Xattrs bool
+	// ChunkLines is the number of lines in each chunk of OutputFormatChunks
+	// (0 means DefaultChunkLines).
+	ChunkLines int
+	// ChunkOverlap is the number of lines a chunk repeats from the end of the
+	// one before it; it must be less than ChunkLines.
+	ChunkOverlap int
+	// LegacyTruncation writes the "... (N more lines)" notice inside file
+	// content, as older releases did, instead of signaling truncation out of band.
+	LegacyTruncation bool
+	// Todos restricts output to files with annotation keywords, keeping only the
+	// annotated lines and TodoContext lines around them, and adds a keyword index.
+	Todos bool
+	// TodoKeywords are the markers Todos looks for (empty means DefaultTodoKeywords).
+	TodoKeywords []string
+	// TodoContext is the number of lines kept before and after each annotated line.
+	TodoContext int
+	// FailOnCaseCollision turns the warning about paths that differ only by
+	// case into an ErrCaseCollision error.
+	FailOnCaseCollision bool
+	// PatternAll keeps only files in which every content pattern matches at
+	// least one line. Lines are still kept when any pattern matches them.
+	PatternAll bool
+	// MaxMatches caps the lines content patterns keep per file; reading a
+	// file stops at the next match, which is noted (0 means no limit).
+	// PatternAll sees only the lines kept.
+	MaxMatches int
+	// MaxTokens stops adding files whose estimated tokens would push the run
+	// over this budget; they are reported as omitted (0 means no limit).
+	MaxTokens int
+	// SizeBudgets cap the total size of the written files each rule
+	// matches. The run ends with a report of every rule's usage and, unless
+	// BudgetWarnOnly, fails with ErrBudgetExceeded if any cap is exceeded.
+	SizeBudgets []SizeBudget
+	// BudgetWarnOnly reports exceeded SizeBudgets without failing the run.
+	BudgetWarnOnly bool
+	// Allowlist declares the files a run may include, as globs matched like
+	// Globs. Files that pass every filter but match none of them are listed
+	// on stderr and fail the run with ErrNotAllowlisted.
+	Allowlist []string
+	// AllowlistWarn leaves out the files Allowlist does not match, with a
+	// warning, instead of failing the run.
+	AllowlistWarn bool
+	// MaxFiles aborts the scan once more than this many files are found, so an
+	// unexpectedly huge tree fails fast instead of exhausting memory (0 means no limit).
+	MaxFiles int
+	// MaxDepth stops a recursive scan from descending into directories more
+	// than this many levels below Directory, with a warning when it does
+	// (0 means no limit).
+	MaxDepth int
+	// Deterministic makes output depend only on the selected files' paths and
+	// contents: forward-slash paths in strict path order, normalized line
+	// endings, no executable bit, no working-directory-dependent paths in error
+	// messages, and no random delimiters.
+	Deterministic bool
+	// Profile prints per-stage totals and the slowest files of each stage to
+	// stderr after the run.
+	Profile bool
+	// ProfileJSON writes the raw per-file stage timings to this path as JSON.
+	ProfileJSON string
+	// Color styles pretty output with ANSI escape sequences and highlights its
+	// content. Other formats never contain escape sequences.
+	Color bool
+	// Theme names the highlighting theme of pretty output (empty means
+	// DefaultTheme). See GetSupportedThemes.
+	Theme string
+	// TUITheme colors the Interactive and Order TUIs (empty means
+	// interactive.ThemeAuto).
+	TUITheme interactive.Theme
+	// LargeOutputSize is the projected size in bytes of the selected text
+	// files above which ConfirmLargeOutput is asked (0 never asks).
+	LargeOutputSize int64
+	// ConfirmLargeOutput is called with the projected size before anything is
+	// written when it exceeds LargeOutputSize. Returning false cancels the run.
+	// Nil never asks.
+	ConfirmLargeOutput func(size int64) bool
+	// Force runs a recursive scan of the home directory, a filesystem root,
+	// or a very wide directory without asking. See ErrBroadScan.
+	Force bool
+	// ConfirmBroadScan is called with the resolved directory and the reason
+	// it is broad before such a scan starts. Returning false cancels the run.
+	// Nil refuses the scan unless Force is set.
+	ConfirmBroadScan func(dir, reason string) bool
+	// DedupeContent writes the content of byte-identical files only once:
+	// later copies, and hard links to a written file, become references to the
+	// first with ProcessedFile.DuplicateOf set.
+	DedupeContent bool
+	// List writes only the paths of the selected files to Output instead of
+	// formatting them, so the output can be piped to other tools. No
+	// formatter is involved and status messages go to Warnings.
+	List bool
+	// Print0 ends each path written by List with a NUL byte instead of a
+	// newline, for xargs -0 and paths containing newlines.
+	Print0 bool
+	// ManifestPath writes a Manifest of the written files, with their hashes
+	// and the selection settings, to this path after the run.
+	ManifestPath string
+	// FrontMatterOnly keeps only the YAML or TOML front matter block that
+	// opens a file, highlighted as its language, and drops files without one.
+	FrontMatterOnly bool
+	// StripFrontMatter removes the front matter block that opens a file,
+	// keeping only the body. Files without one are unchanged.
+	StripFrontMatter bool
+	// ConfigEcho records the effective configuration, as a ConfigEcho, at the
+	// top of formats that support it. The command line turns it on by default.
+	ConfigEcho bool
+	// MaxFilesPerDir writes at most this many files from each directory,
+	// counting only a file's immediate parent, and notes how many more each
+	// full directory had (0 means no limit).
+	MaxFilesPerDir int
+	// CrossLink links mentions of other included files' relative paths in
+	// content to those files' sections, in formats that support it.
+	CrossLink bool
+	// ProjectHeader opens the output with the project's name, from a manifest
+	// in Directory or else the directory's name, and its language mix.
+	ProjectHeader bool
+	// Events receives progress as Run or Files works through the files.
+	// Nil sends nothing.
+	Events *Events
+	// IncludeFunc decides, after every built-in filter has kept a file,
+	// whether the file is selected. It is called once per file the scan
+	// finds and the built-in filters keep; Explain consults it as well.
+	// Nil keeps every such file.
+	IncludeFunc func(file scanner.FileInfo) bool
+	// TransformFunc may rewrite a file after built-in processing and before
+	// the token budget and output see it, for example to change its lines.
+	// It is called once per processed file, including binary files and files
+	// that could not be read, but not for directory records or duplicates
+	// written by DedupeContent. Nil leaves files as processed.
+	TransformFunc func(file *ProcessedFile)
+	// DetectCachePath persists binary and type detection results across runs.
+	// Empty means results are only memoized for the current run.
+	DetectCachePath string
+	// DetectCache, if set, is used in place of the cache DetectCachePath
+	// names, so Apps created one after another, such as those of catls
+	// serve, share detection results. It holds at most
+	// scanner.MaxDetectionEntries entries.
+	DetectCache *scanner.DetectionCache
+	// ContentCache, if set, holds the lines of files read for output so Apps
+	// created one after another, such as those of catls serve, read a file
+	// again only when its size or modification time changed. Nil reads every
+	// file each run.
+	ContentCache *contentcache.Cache
+	// PathRegex limits output to files whose relative path, with forward
+	// slashes, matches one of these regular expressions. Matching is case
+	// sensitive unless a pattern starts with (?i).
+	PathRegex []string
+	// ExcludePathRegex leaves out files whose relative path, with forward
+	// slashes, matches any of these regular expressions.
+	ExcludePathRegex []string
+	// GitIgnore skips the files and directories git ignores: those matching
+	// the .gitignore files of the work tree holding Directory, including
+	// those above Directory, $GIT_DIR/info/exclude, and the global excludes
+	// file, with git's precedence. Named Paths are not checked.
+	GitIgnore bool
+	// GitIgnoreVerify writes each path GitIgnore skips to stderr with the
+	// ignore file, line, and pattern that matched, like git check-ignore -v.
+	GitIgnoreVerify bool
+	// IgnoreCmd is a command, split on whitespace without quoting and run in
+	// Directory, that receives the relative paths of the selected files on
+	// stdin and prints those to leave out. A relative command path is
+	// resolved against the working directory. It runs once per scan, after every built-in
+	// filter. Since it executes a program, it is rejected unless
+	// AllowIgnoreCmd is set.
+	IgnoreCmd string
+	// AllowIgnoreCmd permits IgnoreCmd to run. The command line sets it with
+	// --ignore-cmd.
+	AllowIgnoreCmd bool
+	// BlameSince, a commit or a date, restricts output to the lines git blame
+	// attributes to later changes, including uncommitted ones, and BlameContext
+	// lines around them. Files without such lines are skipped, and Run fails
+	// upfront if Directory is not in a git repository.
+	BlameSince string
+	// BlameContext is the number of lines kept before and after each changed line.
+	BlameContext int
+	// Fold wraps content lines wider than this many columns for display,
+	// after content patterns and todos have seen them whole (0 means no
+	// folding). Continuation segments show no line number.
+	Fold int
+	// StrictSnapshot marks files that changed between the scan and the read
+	// as modified during the run, in the output and RunStats. Either way,
+	// their size and modification time are taken from the read.
+	StrictSnapshot bool
+	// FailFast stops the run with ErrUnreadable at the first file that could
+	// not be read for one of these reasons. Files that fail for other reasons
+	// are written with their error, as they are when FailFast is empty.
+	FailFast []ErrorCategory
 }
 
 // defaultIgnoreGlobs returns standard ignore patterns.
@@ -43,11 +381,51 @@ func (*Config) defaultIgnoreGlobs() []string {
 	}
 }
 
+// warnings returns the writer receiving warnings and debug messages.
+func (c *Config) warnings() io.Writer {
+	if c.Warnings == nil {
+		return os.Stderr
+	}
+
+	return c.Warnings
+}
+
 // AllIgnoreGlobs combines default and user-specified ignore patterns.
 func (c *Config) AllIgnoreGlobs() []string {
 	return append(c.defaultIgnoreGlobs(), c.IgnoreGlobs...)
 }
 
+// RunStats summarizes the files a run wrote or skipped.
+type RunStats struct {
+	Files              int   // Files processed and handed to the output formatter or Files consumer
+	Binary             int   // Written files that were binary
+	Empty              int   // Written files that were empty or whitespace-only
+	Errors             int   // Written files that could not be read
+	SkippedEmpty       int   // Files dropped by SkipEmpty
+	SkippedTodos       int   // Files dropped by Todos because they have no annotations
+	SkippedMatch       int   // Files dropped by PatternAll because a pattern never matched
+	SkippedFrontMatter int   // Files dropped by FrontMatterOnly because they have no front matter
+	SkippedDirLimit    int   // Files dropped by MaxFilesPerDir because their directory was full
+	SkippedBudget      int   // Files dropped because they would exceed MaxTokens
+	SkippedUnchanged   int   // Files dropped by BlameSince because no line changed since the cutoff
+	Duplicates         int   // Written files that referred to identical content instead of repeating it
+	DuplicateBytes     int64 // Bytes of content not repeated because of Duplicates
+	Dirs               int   // Directory records written because of IncludeDirs
+	Reformatted        int   // Written files re-indented by PrettyJSON or PrettyYAML
+	ReformatFailed     int   // Written files PrettyJSON or PrettyYAML could not parse and left as they are
+	Signatures         int   // Written files Signatures reduced to their signatures
+	HeadExcerpts       int   // Written files of other languages SignaturesFallbackHead cut to their first lines
+	// Budgets is the usage of each of SizeBudgets, in order.
+	Budgets []BudgetUsage
+	// ErrorsByCategory breaks Errors down by why the files could not be read.
+	ErrorsByCategory ErrorCounts
+	// ModifiedDuringRun counts written files StrictSnapshot found changed
+	// between the scan and the read.
+	ModifiedDuringRun int
+	// Lockfiles counts written files SummarizeLockfiles summarized.
+	Lockfiles int
+}
+
 // App represents the main catls application.
 type App struct {
 	cfg       *Config
@@ -55,77 +433,361 @@ type App struct {
 	filter    *FileFilter
 	processor *FileProcessor
 	output    OutputFormatter
+	out       io.Writer
+	status    io.Writer            // Receives status messages: out, or warn with List
+	warn      io.Writer            // Receives warnings and debug messages: Config.Warnings, or stderr
+	throttled *throttledOutput     // out when Throttle is set, else nil
+	tee       *integrityTee        // Hashes what the formatter writes to out when Integrity is set, else nil
+	terminal  interactive.Terminal // Where Interactive and Order run; never out
+	cache     *scanner.DetectionCache
+	events    *Events            // Config.Events, with the profile subscribed when profiling
+	timer     *profile.Collector // Times pipeline stages for OnStageTimed (nil when unobserved)
+	profile   *profile.Collector // Stage timings kept for Profile and ProfileJSON (nil unless profiling)
+	runState
 }
 
-// New creates a new catls application instance.
-func New(cfg *Config) *App {
-	output, err := NewOutputFormatter(cfg.OutputFormat)
-	if err != nil {
-		// This should not happen if config validation is working correctly
-		panic(fmt.Sprintf("failed to create output formatter: %v", err))
+// New creates a new catls application instance. It returns an error if the
+// configuration does not pass Validate.
+func New(cfg *Config) (*App, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	out := cfg.Output
+	if out == nil {
+		out = os.Stdout
+	}
+	throttled := newThrottledOutput(cfg, out)
+	if throttled != nil {
+		out = throttled
+	}
+
+	// Output directories are opened by Run, so an App that never runs leaves
+	// no files behind
+	var output OutputFormatter
+	var tee *integrityTee
+	if cfg.OutputDir == "" && !cfg.List {
+		opts, err := cfg.formatOptions(cfg.OutputFormat)
+		if err != nil {
+			return nil, err
+		}
+		formatterOut := out
+		if tee = newIntegrityTee(cfg, out); tee != nil {
+			formatterOut = tee
+		}
+		if output, err = NewOutputFormatter(cfg.OutputFormat, formatterOut, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	cache := cfg.DetectCache
+	if cache == nil && cfg.DetectCachePath != "" {
+		cache = scanner.LoadDetectionCache(cfg.DetectCachePath)
+	} else if cache == nil {
+		cache = scanner.NewMemoryDetectionCache()
+	}
+
+	events, collector := profileEvents(cfg)
+	timer := stageTimer(events)
+
+	warn := cfg.warnings()
+	status := out
+	if cfg.List {
+		status = warn
+	}
+
+	processor := NewFileProcessor(cache, lineTransformers(cfg)...)
+	processor.langMap = cfg.LangMap
+	processor.directives = !cfg.NoDirectives
+	processor.frontMatter = frontMatterModeOf(cfg)
+	processor.reformat = newReformatter(cfg)
+	processor.signatures = newSignatureReducer(cfg)
+	processor.untruncated = cfg.writesFormat(OutputFormatChunks)
+	processor.hashContent = cfg.ManifestPath != ""
+	processor.profile = timer
+	processor.contentCache = cfg.ContentCache
+	processor.warn = warn
+	if cfg.Xattrs {
+		processor.listXattrs = xattr.List
 	}
 
 	return &App{
 		cfg:       cfg,
 		scanner:   scanner.New(),
 		filter:    NewFileFilter(cfg),
-		processor: NewFileProcessor(),
+		processor: processor,
 		output:    output,
-	}
+		out:       out,
+		status:    status,
+		warn:      warn,
+		throttled: throttled,
+		tee:       tee,
+		terminal:  interactive.Terminal{Theme: cfg.TUITheme},
+		cache:     cache,
+		events:    events,
+		timer:     timer,
+		profile:   collector,
+	}, nil
+}
+
+// Stats returns counters collected by the most recent Run or Files iteration.
+func (a *App) Stats() RunStats {
+	return a.stats
 }
 
 // Run executes the catls operation.
-func (a *App) Run(ctx context.Context) error {
-	if err := a.validateConfig(); err != nil {
+func (a *App) Run(ctx context.Context) (err error) {
+	a.beginRun()
+	a.throttled.bind(ctx)
+	complete := a.startEvents()
+	defer func() {
+		complete(err)
+		a.endRun()
+	}()
+
+	files, cont, err := a.selectFiles(ctx)
+	if err != nil || !cont {
 		return err
 	}
 
-	if a.cfg.Debug {
-		fmt.Fprintf(os.Stderr, "Debug: Ignoring directories: %v\n", a.cfg.IgnoreDir)
+	if a.cfg.List {
+		return a.writeList(files)
 	}
 
-	a.addFilesToGlobs()
+	if !a.confirmLargeOutput(files) {
+		fmt.Fprintln(a.status, "Output cancelled.")
 
-	scanCfg := &scanner.Config{
-		Directory:   a.cfg.Directory,
-		ShowAll:     a.cfg.ShowAll,
-		Recursive:   a.cfg.Recursive,
-		IgnoreDir:   a.cfg.IgnoreDir,
-		IgnoreGlobs: a.cfg.AllIgnoreGlobs(),
-		Debug:       a.cfg.Debug,
-		RelativeTo:  a.cfg.RelativeTo,
+		return nil
+	}
+
+	return a.processAndOutput(ctx, files)
+}
+
+// confirmLargeOutput asks ConfirmLargeOutput whether to continue when the
+// selected files project more than LargeOutputSize bytes of output, unless
+// the output goes to OutputDir. The projection uses the sizes recorded while
+// scanning, so no file is read, and leaves out binary files, whose content is
+// never printed.
+func (a *App) confirmLargeOutput(files []scanner.FileInfo) bool {
+	if a.cfg.ConfirmLargeOutput == nil || a.cfg.LargeOutputSize <= 0 || a.cfg.OutputDir != "" {
+		return true
 	}
 
-	files, err := a.scanner.Scan(ctx, scanCfg)
+	var size int64
+	for _, file := range files {
+		if !file.IsDir && !file.IsBinary {
+			size += file.Size
+		}
+	}
+	if size <= a.cfg.LargeOutputSize {
+		return true
+	}
+
+	return a.cfg.ConfirmLargeOutput(size)
+}
+
+// Files returns an iterator over every file that survives scanning, interactive
+// selection, reordering, and filtering, already processed and ready to render.
+// No OutputFormatter is involved, so library consumers can do their own rendering.
+// With IncludeDirs, directory records are yielded with Info.IsDir set and no content.
+// Iteration stops at the first error, which is yielded with a zero ProcessedFile;
+// context cancellation is reported the same way. Breaking out of the loop early
+// is safe: the pipeline runs on the caller's goroutine and holds no open files
+// between yields.
+func (a *App) Files(ctx context.Context) iter.Seq2[ProcessedFile, error] {
+	return func(yield func(ProcessedFile, error) bool) {
+		var err error
+		a.beginRun()
+		complete := a.startEvents()
+		defer func() {
+			complete(err)
+			a.endRun()
+		}()
+
+		files, cont, err := a.selectFiles(ctx)
+		if err != nil {
+			yield(ProcessedFile{}, err)
+
+			return
+		}
+		if !cont {
+			return
+		}
+
+		for processed, fileErr := range a.processFiles(ctx, files) {
+			err = fileErr
+			if !yield(processed, err) {
+				return
+			}
+		}
+	}
+}
+
+// selectFiles validates the configuration, scans the directory, and applies the
+// interactive selector and reorder TUI when enabled. The returned bool is false
+// when there is nothing to output because no files were found or the user cancelled.
+func (a *App) selectFiles(ctx context.Context) ([]scanner.FileInfo, bool, error) {
+	if err := a.resolveBlame(ctx); err != nil {
+		return nil, false, err
+	}
+
+	files, found, err := a.scanFiles(ctx, false)
 	if err != nil {
-		return fmt.Errorf("failed to scan files: %w", err)
+		return nil, false, err
 	}
 
-	if len(files) == 0 {
-		fmt.Printf("No files found in directory: %s\n", a.cfg.Directory)
+	if found == 0 {
+		fmt.Fprintf(a.status, "No files found in directory: %s\n", a.cfg.Directory)
 
-		return nil
+		return nil, false, nil
+	}
+
+	if err := a.checkCaseCollisions(files); err != nil {
+		return nil, false, err
+	}
+	if files, err = a.checkAllowlist(files); err != nil {
+		return nil, false, err
 	}
 
 	selected, cont, err := a.applyInteractive(files)
+	if err != nil || !cont {
+		return nil, false, err
+	}
+
+	return a.applyReorder(selected)
+}
+
+// scanFiles validates the configuration and scans the directory with the file
+// filter as include predicate, or takes the files of ScanArtifact. found
+// counts the files seen before filtering.
+// With skipBinaryCheck, file contents are not read, so IsBinary is unset.
+func (a *App) scanFiles(ctx context.Context, skipBinaryCheck bool) ([]scanner.FileInfo, int, error) {
+	if err := a.validateConfig(); err != nil {
+		return nil, 0, err
+	}
+	if a.cfg.ScanArtifact != nil {
+		return a.artifactFiles(ctx)
+	}
+	if err := a.checkBroadScan(); err != nil {
+		return nil, 0, err
+	}
+
+	if a.cfg.Debug {
+		fmt.Fprintf(a.warn, "Debug: Ignoring directories: %v\n", a.cfg.IgnoreDir)
+	}
+
+	a.addFilesToGlobs()
+	scanCfg := a.scanConfig(skipBinaryCheck)
+	gitIgnore, err := a.newGitIgnoreFilter()
 	if err != nil {
-		return err
+		return nil, 0, err
 	}
-	if !cont {
-		return nil
+
+	// The file filter runs as the scanner's predicates: the rules that need
+	// only the path and stat before the file is read for detection, and every
+	// rule after. Files they reject still count as found, so an over-narrow
+	// filter yields empty output rather than a "no files" message.
+	found := 0
+	matches := newGlobMatches(a.cfg)
+	// Outputs written inside the tree are skipped, so a rerun does not
+	// include the output of the run before
+	outputs := a.selfOutputPaths()
+	defaultDescend := a.scanner.DefaultShouldDescend(scanCfg)
+	descend := func(dirPath string) bool {
+		return !isSelfOutput(dirPath, outputs) && defaultDescend(dirPath) && !gitIgnore.ignored(dirPath, true)
+	}
+	defaultInclude := scanner.DefaultShouldInclude(scanCfg)
+	prefilter := func(file scanner.FileInfo) bool {
+		if len(a.cfg.Paths) == 0 && (isSelfOutput(file.Path, outputs) || !defaultInclude(file) || gitIgnore.ignored(file.Path, false)) {
+			return false
+		}
+		found++
+		matches.record(file.RelPath)
+
+		if verdict, excluded := a.filter.exclusion(file, a.cfg, a.filter.readRules()); excluded {
+			a.recordExcluded(file, verdict, false)
+
+			return false
+		}
+
+		return true
+	}
+	include := func(file scanner.FileInfo) bool {
+		if verdict, excluded := a.filter.exclusion(file, a.cfg, a.filter.fileRules(a.cfg)); excluded {
+			a.recordExcluded(file, verdict, true)
+
+			return false
+		}
+		a.fileDiscovered(file)
+
+		return true
+	}
+
+	// Types are detected while scanning so type filters can run in the include
+	// predicate. Estimates only stat files, so they detect types only to filter.
+	opts := []scanner.Option{scanner.WithDescend(descend), scanner.WithPrefilter(prefilter), scanner.WithInclude(include)}
+	if !skipBinaryCheck || a.cfg.filtersTypes() {
+		opts = append(opts, scanner.WithTypeDetector(a.processor.detectType))
 	}
-	files = selected
 
-	ordered, cont, err := a.applyReorder(files)
+	files, err := a.scanner.Scan(ctx, scanCfg, opts...)
+	if errors.Is(err, scanner.ErrTooManyFiles) {
+		return nil, 0, fmt.Errorf("%w; narrow the scan with --ignore-dir, --globs, or --ignore-globs, or raise --max-files", err)
+	}
 	if err != nil {
-		return err
+		return nil, 0, fmt.Errorf("failed to scan files: %w", err)
 	}
-	if !cont {
-		return nil
+	if files, err = a.applyIgnoreCmd(ctx, files); err != nil {
+		return nil, 0, err
+	}
+	if a.cfg.Deterministic {
+		files = deterministicFiles(files, a.cfg.comparePaths)
+	}
+	if found > 0 && !slices.ContainsFunc(files, func(file scanner.FileInfo) bool { return !file.IsDir }) {
+		matches.writeHint(a.warn, found)
 	}
-	files = ordered
 
-	return a.processAndOutput(ctx, files)
+	return files, found, nil
+}
+
+// scanConfig builds the scanner configuration of a run.
+func (a *App) scanConfig(skipBinaryCheck bool) *scanner.Config {
+	return &scanner.Config{
+		Directory:   a.cfg.Directory,
+		ShowAll:     a.cfg.ShowAll,
+		Recursive:   a.cfg.Recursive,
+		IgnoreDir:   a.cfg.IgnoreDir,
+		IgnoreGlobs: a.cfg.AllIgnoreGlobs(),
+		Debug:       a.cfg.Debug,
+		RelativeTo:  a.cfg.RelativeTo,
+		Paths:       a.cfg.Paths,
+
+		OneFileSystem:     a.cfg.OneFileSystem,
+		SkipGitSubmodules: a.cfg.SkipGitSubmodules,
+		FollowSymlinks:    a.cfg.FollowSymlinks,
+		IncludeDirs:       a.cfg.IncludeDirs,
+		MaxFiles:          a.cfg.MaxFiles,
+		MaxDepth:          a.cfg.MaxDepth,
+		SkipBinaryCheck:   skipBinaryCheck,
+		NaturalSort:       a.cfg.Sort == SortNatural,
+		DetectCache:       a.cache,
+		Profile:           a.timer,
+	}
+}
+
+// checkCaseCollisions warns about paths that would collide on a
+// case-insensitive filesystem and fails if FailOnCaseCollision is set.
+func (a *App) checkCaseCollisions(files []scanner.FileInfo) error {
+	groups := scanner.CaseCollisions(files)
+	for _, paths := range groups {
+		fmt.Fprintf(a.warn, "Warning: paths differ only by case: %s\n", strings.Join(paths, ", "))
+	}
+
+	if len(groups) > 0 && a.cfg.FailOnCaseCollision {
+		return fmt.Errorf("%w: %d group(s) of colliding paths", ErrCaseCollision, len(groups))
+	}
+
+	return nil
 }
 
 func (a *App) applyInteractive(files []scanner.FileInfo) ([]scanner.FileInfo, bool, error) {
@@ -139,7 +801,7 @@ func (a *App) applyInteractive(files []scanner.FileInfo) ([]scanner.FileInfo, bo
 	}
 
 	if selected == nil {
-		fmt.Println("No files selected.")
+		fmt.Fprintln(a.status, "No files selected.")
 
 		return nil, false, nil
 	}
@@ -152,13 +814,13 @@ func (a *App) applyReorder(files []scanner.FileInfo) ([]scanner.FileInfo, bool,
 		return files, true, nil
 	}
 
-	ordered, err := reorder.Reorder(files)
+	ordered, err := reorder.Reorder(files, a.terminal)
 	if err != nil {
 		return nil, false, fmt.Errorf("reorder TUI failed: %w", err)
 	}
 
 	if ordered == nil {
-		fmt.Println("Reorder cancelled.")
+		fmt.Fprintln(a.status, "Reorder cancelled.")
 
 		return nil, false, nil
 	}
@@ -166,17 +828,40 @@ func (a *App) applyReorder(files []scanner.FileInfo) ([]scanner.FileInfo, bool,
 	return ordered, true, nil
 }
 
-func (*App) runInteractiveSelector(files []scanner.FileInfo) ([]scanner.FileInfo, error) {
-	items := make([]interactive.FileItem, len(files))
-	for i, f := range files {
-		items[i] = interactive.FileItem{
-			Path:     f.Path,
-			RelPath:  f.RelPath,
-			IsBinary: f.IsBinary,
+// runInteractiveSelector lets the user pick files. Directory records are not
+// offered for selection and are kept in place. Files past MaxFilesPerDir
+// start deselected, and the limit is not applied again to the selection. Files
+// the file filter excluded are offered, hidden, with the reason; those the
+// user force-includes are kept without applying the filter again. Files the
+// user previews are cached so processing does not read them again. Files the
+// user edits take the size, modification time, and binary flag the selector
+// saw after the edit, and their type is detected again; the scan's filters
+// are not applied again.
+func (a *App) runInteractiveSelector(files []scanner.FileInfo) ([]scanner.FileInfo, error) {
+	over := overDirLimit(files, a.cfg.MaxFilesPerDir)
+	files, reasons := a.withExcluded(files)
+	items := make([]interactive.FileItem, 0, len(files))
+	for _, f := range files {
+		if f.IsDir {
+			continue
 		}
+		items = append(items, interactive.FileItem{
+			Path:         f.Path,
+			RelPath:      f.RelPath,
+			IsBinary:     f.IsBinary,
+			Size:         f.Size,
+			ModTime:      f.ModTime,
+			OverDirLimit: over[f.Path],
+			Excluded:     reasons[f.Path],
+			Unselectable: a.unselectableReason(f),
+		})
+	}
+
+	if a.processor.contentCache == nil {
+		a.processor.contentCache = contentcache.New(contentcache.DefaultMaxBytes)
 	}
 
-	selected, err := interactive.SelectFiles(items)
+	selected, err := interactive.SelectFiles(items, a.processor.contentCache, a.terminal)
 	if err != nil {
 		return nil, fmt.Errorf("interactive selection failed: %w", err)
 	}
@@ -185,22 +870,65 @@ func (*App) runInteractiveSelector(files []scanner.FileInfo) ([]scanner.FileInfo
 		return nil, nil
 	}
 
-	result := make([]scanner.FileInfo, len(selected))
-	for i, s := range selected {
-		result[i] = scanner.FileInfo{
-			Path:     s.Path,
-			RelPath:  s.RelPath,
-			IsBinary: s.IsBinary,
+	chosen := make(map[string]interactive.FileItem, len(selected))
+	for _, s := range selected {
+		chosen[s.Path] = s
+	}
+
+	result := make([]scanner.FileInfo, 0, len(files))
+	for _, f := range files {
+		if f.IsDir {
+			result = append(result, f)
+
+			continue
+		}
+		if item, ok := chosen[f.Path]; ok {
+			if item.ForceIncluded {
+				a.forceInclude(&f)
+			}
+			if !item.ModTime.Equal(f.ModTime) || item.Size != f.Size {
+				f.IsBinary, f.Size, f.ModTime = item.IsBinary, item.Size, item.ModTime
+				f.FileType, f.HasType = "", false
+			}
+			result = append(result, f)
 		}
 	}
 
 	return result, nil
 }
 
-// validateConfig ensures the configuration is valid.
+// unselectableReason returns why the interactive selector should not offer
+// file, or "" when it should. A binary file is written only as a
+// placeholder unless EmbedImages carries it as an image or
+// ContentEncodingBase64 carries its bytes, each up to its size limit.
+func (a *App) unselectableReason(file scanner.FileInfo) string {
+	switch {
+	case !file.IsBinary:
+		return ""
+	case a.cfg.ContentEncoding == ContentEncodingBase64 && file.Size <= a.cfg.rawContentMax():
+		return ""
+	case a.cfg.EmbedImages > 0 && file.Size <= a.cfg.EmbedImages && scanner.SniffImageFile(file.Path) != "":
+		return ""
+	case a.cfg.ContentEncoding == ContentEncodingBase64:
+		return "binary, over --raw-max-size, written as a placeholder"
+	}
+
+	return "binary, written as a placeholder"
+}
+
+// validateConfig ensures the configuration is still valid at run time and
+// normalizes it for scanning.
 func (a *App) validateConfig() error {
-	if _, err := os.Stat(a.cfg.Directory); os.IsNotExist(err) {
-		return fmt.Errorf("directory '%s' does not exist", a.cfg.Directory)
+	if err := a.cfg.validateDirectory(); err != nil {
+		return err
+	}
+
+	if a.cfg.RelativeTo == RelativeToGit {
+		root, err := gitignore.WorkTreeRoot(a.cfg.Directory)
+		if err != nil {
+			return fmt.Errorf("--relative-to git: %w", err)
+		}
+		a.cfg.RelativeTo = root
 	}
 
 	// Normalize ignore directories
@@ -208,6 +936,17 @@ func (a *App) validateConfig() error {
 		a.cfg.IgnoreDir[i] = strings.TrimSuffix(dir, "/")
 	}
 
+	// Expand brace alternatives, which the glob matcher does not understand
+	globs, err := expandGlobs("--globs", a.cfg.Globs)
+	if err != nil {
+		return err
+	}
+	ignoreGlobs, err := expandGlobs("--ignore-globs", a.cfg.IgnoreGlobs)
+	if err != nil {
+		return err
+	}
+	a.cfg.Globs, a.cfg.IgnoreGlobs = globs, ignoreGlobs
+
 	return nil
 }
 
@@ -225,40 +964,445 @@ func (a *App) addFilesToGlobs() {
 }
 
 // processAndOutput handles file processing and output generation.
-func (a *App) processAndOutput(ctx context.Context, files []scanner.FileInfo) error {
+func (a *App) processAndOutput(ctx context.Context, files []scanner.FileInfo) (err error) {
+	if a.cfg.OutputDir != "" {
+		open := a.openOutputDir
+		if a.cfg.OutputTemplate != "" {
+			open = a.openSplitOutput
+		}
+		closeFiles, err := open()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			err = errors.Join(err, closeFiles())
+		}()
+	}
+
+	if writer, ok := a.output.(ConfigEchoWriter); ok && a.cfg.ConfigEcho {
+		writer.SetConfigEcho(a.configEcho())
+	}
+	if writer, ok := a.output.(ProjectHeaderWriter); ok && a.cfg.ProjectHeader {
+		writer.SetProjectHeader(a.projectHeader(files))
+	}
+	if err := a.setIntegrity(a.cfg.OutputFormat, a.output, a.tee); err != nil {
+		return err
+	}
+
 	// Write header
 	if err := a.output.WriteHeader(ctx); err != nil {
 		return fmt.Errorf("failed to write output header: %w", err)
 	}
 
-	for _, file := range files {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
+	processedFiles := a.processFiles(ctx, files)
+	if a.cfg.Todos || a.cfg.CrossLink {
+		// The index precedes the files and links may point ahead, so everything
+		// is processed before writing
+		buffered, err := a.prepareBuffered(ctx, processedFiles)
+		if err != nil {
+			return err
 		}
+		processedFiles = buffered
+	}
 
-		// Apply file filtering
-		if !a.filter.ShouldIncludeFile(file, a.cfg) {
-			continue
+	for processed, err := range processedFiles {
+		if err != nil {
+			return err
 		}
 
-		// Create filter for this specific file processing
-		filter := NewFileFilter(a.cfg)
-
-		// Process the file
-		processed := a.processor.ProcessFile(file, filter)
+		foldFile(&processed, a.cfg.Fold)
 
 		// Write processed file using the output formatter
-		if err := a.output.WriteFile(ctx, &processed, a.cfg); err != nil {
-			return fmt.Errorf("failed to write file %s: %w", file.RelPath, err)
+		done := a.timer.Start(processed.Info.RelPath, profile.StageFormat)
+		err = a.output.WriteFile(ctx, &processed, a.cfg)
+		done()
+		if err != nil {
+			return fmt.Errorf("failed to write file %s: %w", processed.Info.RelPath, err)
+		}
+		if err := a.recordManifest(&processed); err != nil {
+			return err
+		}
+		a.written.count(&processed)
+	}
+
+	if writer, ok := a.output.(RunSummaryWriter); ok {
+		if err := writer.WriteRunSummary(ctx, a.runSummary()); err != nil {
+			return fmt.Errorf("failed to write run summary: %w", err)
 		}
 	}
+	if a.stats.SkippedBudget > 0 {
+		fmt.Fprintf(a.warn, "Warning: left out %d files to stay within --max-tokens %d\n",
+			a.stats.SkippedBudget, a.cfg.MaxTokens)
+	}
+	if a.stats.Duplicates > 0 {
+		fmt.Fprintf(a.warn, "Deduplicated %d files, saving %d bytes\n", a.stats.Duplicates, a.stats.DuplicateBytes)
+	}
+	if a.stats.Errors > 0 {
+		fmt.Fprintf(a.warn, "Could not read %d files: %s\n", a.stats.Errors, a.stats.ErrorsByCategory)
+	}
+	if a.stats.ModifiedDuringRun > 0 {
+		fmt.Fprintf(a.warn, "Warning: %d files changed while catls was running\n", a.stats.ModifiedDuringRun)
+	}
+	if a.stats.Reformatted > 0 || a.stats.ReformatFailed > 0 {
+		fmt.Fprintf(a.warn, "Reformatted %d files, %d could not be parsed\n", a.stats.Reformatted, a.stats.ReformatFailed)
+	}
+	if a.stats.Signatures > 0 || a.stats.HeadExcerpts > 0 {
+		fmt.Fprintf(a.warn, "Reduced %d files to signatures and %d others to their first %d lines\n", a.stats.Signatures, a.stats.HeadExcerpts, SignaturesHeadLines)
+	}
+	if a.stats.Lockfiles > 0 {
+		fmt.Fprintf(a.warn, "Summarized %d lockfiles instead of writing their content\n", a.stats.Lockfiles)
+	}
+	budgetErr := a.writeBudgetReport(a.warn)
 
 	// Write footer
 	if err := a.output.WriteFooter(ctx); err != nil {
 		return fmt.Errorf("failed to write output footer: %w", err)
 	}
 
-	return nil
+	if err := a.writeRunManifest(); err != nil {
+		return err
+	}
+
+	if err := a.writeProfile(); err != nil {
+		return err
+	}
+
+	if a.cfg.Debug {
+		fmt.Fprintf(a.warn, "Debug: Wrote %d files (%d binary, %d empty, %d errors, %d duplicates) and %d directories, skipped %d empty, %d without todos, %d not matching every pattern, %d without front matter, %d over the per-directory limit, %d over the token budget and %d unchanged since --blame-since\n",
+			a.stats.Files, a.stats.Binary, a.stats.Empty, a.stats.Errors, a.stats.Duplicates, a.stats.Dirs,
+			a.stats.SkippedEmpty, a.stats.SkippedTodos, a.stats.SkippedMatch, a.stats.SkippedFrontMatter, a.stats.SkippedDirLimit, a.stats.SkippedBudget, a.stats.SkippedUnchanged)
+	}
+
+	return budgetErr
+}
+
+// prepareBuffered drains processed, writes the keyword index in Todos mode
+// and hands cross-links to the formatter with CrossLink, as far as the
+// formatter supports them, and returns the drained files for writing.
+func (a *App) prepareBuffered(ctx context.Context, processed iter.Seq2[ProcessedFile, error]) (iter.Seq2[ProcessedFile, error], error) {
+	var files []ProcessedFile
+	for file, err := range processed {
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	if writer, ok := a.output.(TodoIndexWriter); ok && a.cfg.Todos {
+		index := BuildTodoIndex(files, a.cfg.todoKeywords())
+		if err := writer.WriteTodoIndex(ctx, index); err != nil {
+			return nil, fmt.Errorf("failed to write todo index: %w", err)
+		}
+	}
+	if linker, ok := a.output.(CrossLinker); ok && a.cfg.CrossLink {
+		linker.SetCrossLinks(NewCrossLinks(files))
+	}
+
+	return func(yield func(ProcessedFile, error) bool) {
+		for _, file := range files {
+			if !yield(file, nil) {
+				return
+			}
+		}
+	}, nil
+}
+
+// processFiles processes already-filtered files in order, yielding each result.
+// It is the single processing pipeline shared by Run and Files.
+func (a *App) processFiles(ctx context.Context, files []scanner.FileInfo) iter.Seq2[ProcessedFile, error] {
+	return func(yield func(ProcessedFile, error) bool) {
+		if a.cfg.DedupeContent {
+			a.contents = newContentIndex()
+		}
+		defer a.saveDetectCache()
+
+		// Files were already filtered by the scanner's include predicate
+		if a.cfg.ReadmeFirst {
+			files = readmeFirst(files)
+		}
+		// Interactive selection already showed the limit and is final
+		var limit *dirLimit
+		if !a.cfg.Interactive {
+			limit = newDirLimit(files, a.cfg.MaxFilesPerDir)
+		}
+
+		for i, file := range files {
+			select {
+			case <-ctx.Done():
+				yield(ProcessedFile{}, ctx.Err())
+
+				return
+			default:
+			}
+			a.startFile(i, len(files))
+
+			if file.IsDir {
+				a.stats.Dirs++
+				dir := ProcessedFile{Info: file}
+				a.fileProcessed(file, &dir, "")
+				if !yield(dir, nil) {
+					return
+				}
+
+				continue
+			}
+
+			if a.shouldSkipDirLimit(limit, file) || a.shouldSkipEmpty(file) {
+				continue
+			}
+
+			var key contentKey
+			if a.contents != nil {
+				var first string
+				if first, key = a.contents.lookup(file); first != "" {
+					duplicate := a.duplicateFile(file, first)
+					a.recordStats(&duplicate)
+					duplicate.DirOmitted = limit.add(file)
+					a.fileProcessed(file, &duplicate, "")
+					if !yield(duplicate, nil) {
+						return
+					}
+
+					continue
+				}
+			}
+
+			// Process the file
+			processed := a.processFile(file)
+			if a.cfg.Deterministic {
+				scrubError(&processed)
+			}
+			if err := a.failFast(&processed); err != nil {
+				yield(ProcessedFile{}, err)
+
+				return
+			}
+			if a.shouldSkipFrontMatter(&processed) || a.shouldSkipTodos(&processed) || a.shouldSkipPatternAll(&processed) || a.shouldSkipBlame(ctx, &processed) {
+				continue
+			}
+			if a.cfg.EmbedImages > 0 {
+				a.embedImage(&processed)
+			}
+			if a.cfg.ReadmeFirst && isReadme(file.RelPath) {
+				processed.IsReadme = true
+				limitReadme(&processed, a.cfg.ReadmeLines)
+			}
+			if a.cfg.TransformFunc != nil {
+				a.cfg.TransformFunc(&processed)
+			}
+			if a.shouldSkipBudget(&processed) {
+				continue
+			}
+			if a.cfg.ContentEncoding == ContentEncodingBase64 {
+				a.attachRawContent(&processed)
+			}
+			a.recordStats(&processed)
+			processed.DirOmitted = limit.add(file)
+			a.fileProcessed(file, &processed, "")
+			if a.contents != nil {
+				a.contents.add(key, file.RelPath)
+			}
+
+			if !yield(processed, nil) {
+				return
+			}
+		}
+	}
+}
+
+// saveDetectCache persists detection results. Failing to save only costs
+// speed on the next run, so it is reported in debug mode and otherwise ignored.
+func (a *App) saveDetectCache() {
+	if err := a.cache.Save(); err != nil && a.cfg.Debug {
+		fmt.Fprintf(a.warn, "Debug: Failed to save detection cache: %v\n", err)
+	}
+}
+
+// shouldSkipEmpty reports whether SkipEmpty drops this file.
+func (a *App) shouldSkipEmpty(file scanner.FileInfo) bool {
+	verdict := a.emptyVerdict(file)
+	if !verdict.Excluded {
+		return false
+	}
+
+	if a.cfg.Debug {
+		fmt.Fprintf(a.warn, "Debug: Skipping file: %s (%s)\n", file.RelPath, verdict)
+	}
+	a.stats.SkippedEmpty++
+	a.omit(file, OmitReasonEmpty)
+
+	return true
+}
+
+// emptyVerdict applies SkipEmpty. Only the leading bytes of non-empty files
+// are read.
+func (a *App) emptyVerdict(file scanner.FileInfo) scanner.Verdict {
+	verdict := scanner.Verdict{Rule: "size filter", Detail: fmt.Sprintf("%d bytes", file.Size)}
+	if !a.cfg.SkipEmpty || file.IsBinary {
+		return verdict
+	}
+
+	// Unreadable files fall through so the error is reported in output
+	if blank, err := isBlankFile(file.Path, a.warn); err == nil && blank {
+		verdict.Excluded = true
+		verdict.Detail = "empty or whitespace-only, with --skip-empty"
+	}
+
+	return verdict
+}
+
+// shouldSkipTodos reports whether Todos drops this file because none of its
+// lines are annotated.
+func (a *App) shouldSkipTodos(file *ProcessedFile) bool {
+	verdict := a.todosVerdict(file)
+	if !verdict.Excluded {
+		return false
+	}
+
+	if a.cfg.Debug {
+		fmt.Fprintf(a.warn, "Debug: Skipping file: %s (%s)\n", file.Info.RelPath, verdict)
+	}
+	a.stats.SkippedTodos++
+	a.omit(file.Info, OmitReasonNoTodos)
+
+	return true
+}
+
+// todosVerdict applies Todos. Unreadable files are kept so the error is reported.
+func (a *App) todosVerdict(file *ProcessedFile) scanner.Verdict {
+	verdict := scanner.Verdict{Rule: "todos"}
+	if !a.cfg.Todos || file.Error != nil {
+		return verdict
+	}
+
+	annotated := countTodos(file)
+	verdict.Excluded = annotated == 0
+	verdict.Detail = countLines(annotated) + " annotated"
+
+	return verdict
+}
+
+// shouldSkipPatternAll reports whether PatternAll drops this file because some
+// content pattern matched none of its lines.
+func (a *App) shouldSkipPatternAll(file *ProcessedFile) bool {
+	verdict := a.patternVerdict(file)
+	if !verdict.Excluded {
+		return false
+	}
+
+	if a.cfg.Debug {
+		fmt.Fprintf(a.warn, "Debug: Skipping file: %s (%s)\n", file.Info.RelPath, verdict)
+	}
+	a.stats.SkippedMatch++
+	a.omit(file.Info, OmitReasonPattern)
+
+	return true
+}
+
+// patternVerdict counts the lines each content pattern matches and applies
+// PatternAll. Unreadable and binary files are kept like they are without
+// PatternAll.
+func (a *App) patternVerdict(file *ProcessedFile) scanner.Verdict {
+	verdict := scanner.Verdict{Rule: "content patterns"}
+	if len(a.cfg.ContentPatterns) == 0 || file.Error != nil || file.Info.IsBinary {
+		return verdict
+	}
+
+	counts := make(map[string]int, len(a.cfg.ContentPatterns))
+	for _, line := range file.Lines {
+		for _, pattern := range line.Patterns {
+			counts[pattern]++
+		}
+	}
+	matches := make([]string, len(a.cfg.ContentPatterns))
+	for i, pattern := range a.cfg.ContentPatterns {
+		matches[i] = fmt.Sprintf("%q matches %s", pattern, countLines(counts[pattern]))
+	}
+	verdict.Detail = strings.Join(matches, ", ")
+	verdict.Excluded = a.cfg.PatternAll && !a.filter.matchesAllPatterns(file.Lines)
+	if verdict.Excluded {
+		verdict.Detail += ", with --pattern-all"
+	}
+
+	return verdict
+}
+
+// countLines formats n as "1 line" or "n lines".
+func countLines(n int) string {
+	if n == 1 {
+		return "1 line"
+	}
+
+	return fmt.Sprintf("%d lines", n)
+}
+
+// recordStats updates the run counters for a file about to be written.
+func (a *App) recordStats(file *ProcessedFile) {
+	a.stats.Files++
+	if len(a.stats.Budgets) > 0 && file.Error == nil && !file.Info.IsDir {
+		relPath, err := filepath.Rel(a.cfg.Directory, file.Info.Path)
+		if err != nil {
+			relPath = file.Info.RelPath
+		}
+		a.stats.recordBudgets(relPath, file.Info)
+	}
+
+	switch {
+	case file.Error != nil:
+		a.stats.recordError(file.ErrorCategory)
+	case file.Info.IsBinary:
+		a.stats.Binary++
+	case file.IsEmpty:
+		a.stats.Empty++
+	}
+
+	if file.ModifiedDuringRun {
+		a.stats.ModifiedDuringRun++
+	}
+	if file.Lockfile != nil {
+		a.stats.Lockfiles++
+	}
+	switch file.Reduced {
+	case ReducedSignatures:
+		a.stats.Signatures++
+	case ReducedHead:
+		a.stats.HeadExcerpts++
+	}
+
+	switch {
+	case file.Reformatted:
+		a.stats.Reformatted++
+	case file.ReformatError != nil:
+		a.stats.ReformatFailed++
+		fmt.Fprintf(a.warn, "Warning: could not reformat %s, showing it as it is: %v\n", file.Info.RelPath, file.ReformatError)
+	}
+}
+
+// writeProfile reports the stage timings collected during the run: a summary
+// on stderr with Profile, and the raw timings in ProfileJSON.
+func (a *App) writeProfile() error {
+	if a.cfg.Profile {
+		if err := a.profile.WriteSummary(a.warn); err != nil {
+			return fmt.Errorf("failed to write profile: %w", err)
+		}
+		if a.processor.contentCache != nil {
+			fmt.Fprintf(a.warn, "Content cache: %s\n", a.processor.contentCache.Stats())
+		}
+	}
+
+	if a.cfg.ProfileJSON == "" {
+		return nil
+	}
+
+	file, err := os.Create(a.cfg.ProfileJSON)
+	if err != nil {
+		return fmt.Errorf("failed to write profile: %w", err)
+	}
+	if err := a.profile.WriteJSON(file); err != nil {
+		_ = file.Close()
+
+		return fmt.Errorf("failed to write profile: %w", err)
+	}
+
+	return file.Close()
 }
@@ -3,11 +3,23 @@ package catls
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"maps"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/connerohnesorge/catls/internal/contentcache"
+	"github.com/connerohnesorge/catls/internal/profile"
+	"github.com/connerohnesorge/catls/internal/scanner"
 )
 
 func TestRelativeToIntegration(t *testing.T) {
@@ -90,8 +102,10 @@ func TestRelativeToIntegration(t *testing.T) {
 			os.Stdout = w
 
 			// Run the application
-			app := New(cfg)
-			err := app.Run(context.Background())
+			app, err := New(cfg)
+			if err == nil {
+				err = app.Run(context.Background())
+			}
 
 			// Restore stdout
 			if err := w.Close(); err != nil {
@@ -155,8 +169,10 @@ func TestRelativeToWithNestedDirectories(t *testing.T) {
 		r, w, _ := os.Pipe()
 		os.Stdout = w
 
-		app := New(cfg)
-		err := app.Run(context.Background())
+		app, err := New(cfg)
+		if err == nil {
+			err = app.Run(context.Background())
+		}
 
 		if err := w.Close(); err != nil {
 			t.Fatalf("failed to close pipe: %v", err)
@@ -204,8 +220,10 @@ func TestRelativeToWithNestedDirectories(t *testing.T) {
 		r, w, _ := os.Pipe()
 		os.Stdout = w
 
-		app := New(cfg)
-		err := app.Run(context.Background())
+		app, err := New(cfg)
+		if err == nil {
+			err = app.Run(context.Background())
+		}
 
 		if err := w.Close(); err != nil {
 			t.Fatalf("failed to close pipe: %v", err)
@@ -261,8 +279,10 @@ func TestRelativeToWithDifferentOutputFormats(t *testing.T) {
 			r, w, _ := os.Pipe()
 			os.Stdout = w
 
-			app := New(cfg)
-			err := app.Run(context.Background())
+			app, err := New(cfg)
+			if err == nil {
+				err = app.Run(context.Background())
+			}
 
 			if err := w.Close(); err != nil {
 				t.Fatalf("failed to close pipe: %v", err)
@@ -302,3 +322,1236 @@ func TestRelativeToWithDifferentOutputFormats(t *testing.T) {
 		})
 	}
 }
+
+// TestRelativeToGit checks that RelativeToGit gives the paths git ls-files
+// lists for a fixture repository, wherever in the work tree the scan starts.
+func TestRelativeToGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	root := t.TempDir()
+	git := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", root, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("git %s: %v", strings.Join(args, " "), err)
+		}
+
+		return string(out)
+	}
+
+	git("init", "-q")
+	writeTree(t, root, map[string]string{
+		"top.go":                  "package top\n",
+		"sub/a.go":                "package sub\n",
+		"sub/deep/with space.txt": "space\n",
+		"sub/deep/ünïcode.md":     "# ü\n",
+	})
+	git("add", ".")
+	git("commit", "-q", "-m", "fixture")
+
+	for _, dir := range []string{root, filepath.Join(root, "sub"), filepath.Join(root, "sub", "deep")} {
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			app, err := New(&Config{Directory: dir, Recursive: true, RelativeTo: RelativeToGit, OutputFormat: OutputFormatJSON, Output: io.Discard})
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+			var got []string
+			for file, err := range app.Files(context.Background()) {
+				if err != nil {
+					t.Fatalf("Files() unexpected error: %v", err)
+				}
+				got = append(got, filepath.ToSlash(file.Info.RelPath))
+			}
+
+			rel, _ := filepath.Rel(root, dir)
+			want := strings.Split(strings.TrimSuffix(git("ls-files", "-z", "--", filepath.ToSlash(rel)), "\x00"), "\x00")
+			slices.Sort(got)
+			slices.Sort(want)
+			if !slices.Equal(got, want) {
+				t.Errorf("paths = %q, want git ls-files %q", got, want)
+			}
+		})
+	}
+
+	outside := t.TempDir()
+	if _, err := exec.Command("git", "-C", outside, "rev-parse").CombinedOutput(); err == nil {
+		t.Skip("the temporary directory is inside a git work tree")
+	}
+	app, err := New(&Config{Directory: outside, RelativeTo: RelativeToGit, OutputFormat: OutputFormatJSON, Output: io.Discard})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if err := app.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "--relative-to git: ") {
+		t.Errorf("Run() outside a work tree = %v, want a --relative-to git error", err)
+	}
+}
+
+// runAndCapture runs the app with cfg and returns everything it wrote.
+func runAndCapture(t *testing.T, cfg *Config) (string, *App) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	cfg.Output = &buf
+
+	app, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	return buf.String(), app
+}
+
+// writeTree creates files under dir from a path -> content map.
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for path, content := range files {
+		fullPath := filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+			t.Fatalf("failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write file %s: %v", path, err)
+		}
+	}
+}
+
+func TestEmptyFileHandling(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"__init__.py": "",
+		"blank.txt":   "  \n\t\n",
+		"main.py":     "print('hi')\n",
+	})
+
+	t.Run("default renders empty marker", func(t *testing.T) {
+		output, app := runAndCapture(t, &Config{
+			Directory:    tmpDir,
+			RelativeTo:   tmpDir,
+			OutputFormat: OutputFormatMarkdown,
+		})
+
+		if got := strings.Count(output, "*Empty file*"); got != 2 {
+			t.Errorf("expected 2 empty markers, got %d\noutput:\n%s", got, output)
+		}
+		if stats := app.Stats(); stats.Empty != 2 || stats.Files != 3 {
+			t.Errorf("Stats() = %+v, want 3 files with 2 empty", stats)
+		}
+	})
+
+	t.Run("skip-empty drops blank files", func(t *testing.T) {
+		output, app := runAndCapture(t, &Config{
+			Directory:    tmpDir,
+			RelativeTo:   tmpDir,
+			OutputFormat: OutputFormatXML,
+			SkipEmpty:    true,
+		})
+
+		if strings.Contains(output, "__init__.py") || strings.Contains(output, "blank.txt") {
+			t.Errorf("blank files should be skipped\noutput:\n%s", output)
+		}
+		if !strings.Contains(output, "main.py") {
+			t.Errorf("non-empty file missing\noutput:\n%s", output)
+		}
+		if stats := app.Stats(); stats.SkippedEmpty != 2 || stats.Files != 1 {
+			t.Errorf("Stats() = %+v, want 1 file and 2 skipped", stats)
+		}
+	})
+}
+
+func TestAppFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"a.go":      "package a",
+		"b.go":      "package b",
+		"c.txt":     "notes",
+		"skip.json": "{}",
+	})
+
+	newApp := func() *App {
+		app, err := New(&Config{
+			Directory:    tmpDir,
+			RelativeTo:   tmpDir,
+			IgnoreGlobs:  []string{"*.json"},
+			OutputFormat: OutputFormatXML,
+		})
+		if err != nil {
+			t.Fatalf("New() unexpected error: %v", err)
+		}
+
+		return app
+	}
+
+	t.Run("yields filtered processed files in order", func(t *testing.T) {
+		var paths []string
+		for file, err := range newApp().Files(context.Background()) {
+			if err != nil {
+				t.Fatalf("Files() unexpected error: %v", err)
+			}
+			if len(file.Lines) == 0 {
+				t.Errorf("file %s has no processed lines", file.Info.RelPath)
+			}
+			paths = append(paths, file.Info.RelPath)
+		}
+
+		want := []string{"a.go", "b.go", "c.txt"}
+		if strings.Join(paths, ",") != strings.Join(want, ",") {
+			t.Errorf("Files() yielded %v, want %v", paths, want)
+		}
+	})
+
+	t.Run("stops when the consumer breaks", func(t *testing.T) {
+		app := newApp()
+		for range app.Files(context.Background()) {
+			break
+		}
+
+		if got := app.Stats().Files; got != 1 {
+			t.Errorf("processed %d files after early break, want 1", got)
+		}
+	})
+
+	t.Run("reports context cancellation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		var gotErr error
+		for _, err := range newApp().Files(ctx) {
+			if err != nil {
+				gotErr = err
+
+				break
+			}
+			cancel()
+		}
+		cancel()
+
+		if !errors.Is(gotErr, context.Canceled) {
+			t.Errorf("Files() error = %v, want context.Canceled", gotErr)
+		}
+	})
+}
+
+func TestConfigValidate(t *testing.T) {
+	tmpDir := t.TempDir()
+	filePath := filepath.Join(tmpDir, "file.txt")
+	writeTree(t, tmpDir, map[string]string{"file.txt": "x"})
+
+	valid := func() Config {
+		return Config{Directory: tmpDir, OutputFormat: OutputFormatXML}
+	}
+
+	tests := []struct {
+		name    string
+		mutate  func(*Config)
+		wantErr string
+	}{
+		{name: "valid config", mutate: func(*Config) {}},
+		{name: "missing directory", mutate: func(c *Config) { c.Directory = filepath.Join(tmpDir, "nope") }, wantErr: "does not exist"},
+		{name: "directory is a file", mutate: func(c *Config) { c.Directory = filePath }, wantErr: "is a file, not a directory"},
+		{name: "empty output format", mutate: func(c *Config) { c.OutputFormat = "" }, wantErr: "unsupported output format"},
+		{name: "unknown output format", mutate: func(c *Config) { c.OutputFormat = "yaml" }, wantErr: "unsupported output format: yaml"},
+		{name: "unknown line number format", mutate: func(c *Config) { c.LineNumberFormat = "roman" }, wantErr: "unsupported line number format: roman"},
+		{name: "empty line number format uses default", mutate: func(c *Config) { c.LineNumberFormat = "" }},
+		{name: "pattern with regex metacharacters", mutate: func(c *Config) { c.ContentPatterns = []string{"(*["} }},
+		{name: "globs with regex metacharacters", mutate: func(c *Config) { c.Globs = []string{"[a-z]+.go"} }},
+		{name: "fence style with xml", mutate: func(c *Config) { c.FenceStyle = FenceStyleTilde }, wantErr: "only apply to markdown"},
+		{
+			name: "unknown fence style",
+			mutate: func(c *Config) {
+				c.OutputFormat = OutputFormatMarkdown
+				c.FenceStyle = "wavy"
+			},
+			wantErr: "unsupported fence style: wavy",
+		},
+		{
+			name: "sentinel with fenced style",
+			mutate: func(c *Config) {
+				c.OutputFormat = OutputFormatMarkdown
+				c.FenceStyle = FenceStyleIndent
+				c.Sentinel = "--- {path}"
+			},
+			wantErr: "--sentinel requires --fence-style none",
+		},
+		{name: "negative readme lines", mutate: func(c *Config) { c.ReadmeFirst, c.ReadmeLines = true, -1 }, wantErr: "must not be negative"},
+		{name: "readme lines without readme first", mutate: func(c *Config) { c.ReadmeLines = 5 }, wantErr: "requires --readme-first"},
+		{
+			name: "sentinel with markdown",
+			mutate: func(c *Config) {
+				c.OutputFormat = OutputFormatMarkdown
+				c.Sentinel = "--- {path}"
+			},
+		},
+		{
+			name: "multiple errors reported together",
+			mutate: func(c *Config) {
+				c.OutputFormat = "yaml"
+				c.LineNumberFormat = "roman"
+			},
+			wantErr: "unsupported line number format",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			tt.mutate(&cfg)
+
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() unexpected error: %v", err)
+				}
+
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewRejectsInvalidConfig(t *testing.T) {
+	app, err := New(&Config{Directory: t.TempDir(), OutputFormat: "yaml"})
+	if err == nil || app != nil {
+		t.Errorf("New() = %v, %v; want nil app and an error", app, err)
+	}
+}
+
+func TestReadmeFirst(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"Makefile":         "all:",
+		"README.md":        "# Project\n\nIntro.\nMore.\n",
+		"src/Api.go":       "package src",
+		"src/README.md":    "Source code.",
+		"src/lib/util.go":  "package lib",
+		"src/lib/README":   "Helpers.",
+		"src/zeta/main.go": "package zeta",
+	})
+
+	t.Run("orders readmes ahead of their subtree", func(t *testing.T) {
+		app, err := New(&Config{
+			Directory:    tmpDir,
+			RelativeTo:   tmpDir,
+			Recursive:    true,
+			ReadmeFirst:  true,
+			OutputFormat: OutputFormatXML,
+		})
+		if err != nil {
+			t.Fatalf("New() unexpected error: %v", err)
+		}
+
+		var paths []string
+		for file, err := range app.Files(context.Background()) {
+			if err != nil {
+				t.Fatalf("Files() unexpected error: %v", err)
+			}
+			if file.IsReadme != isReadme(file.Info.RelPath) {
+				t.Errorf("%s IsReadme = %v", file.Info.RelPath, file.IsReadme)
+			}
+			paths = append(paths, filepath.ToSlash(file.Info.RelPath))
+		}
+
+		want := []string{
+			"README.md", "Makefile",
+			"src/README.md", "src/Api.go",
+			"src/lib/README", "src/lib/util.go",
+			"src/zeta/main.go",
+		}
+		if strings.Join(paths, ",") != strings.Join(want, ",") {
+			t.Errorf("order = %v\nwant    %v", paths, want)
+		}
+	})
+
+	t.Run("markdown renders readmes as limited blockquotes", func(t *testing.T) {
+		output, _ := runAndCapture(t, &Config{
+			Directory:    tmpDir,
+			RelativeTo:   tmpDir,
+			ReadmeFirst:  true,
+			ReadmeLines:  1,
+			OutputFormat: OutputFormatMarkdown,
+		})
+
+		want := "## README.md\n\n> # Project\n>\n> *(3 more lines)*\n"
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q\noutput:\n%s", want, output)
+		}
+	})
+}
+
+func TestFilteringFixtureTree(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		".env.local":            "SECRET=1",
+		"LICENSE":               "MIT",
+		"main.go":               "package main",
+		"main_test.go":          "package main",
+		"node_modules/x/lib.js": "module.exports = 1",
+		"notes.md":              "# Notes",
+		"pkg/api.go":            "package pkg",
+		"pkg/api_templ.go":      "package pkg",
+		"pkg/.cache/blob":       "cached",
+	})
+
+	tests := []struct {
+		name string
+		cfg  Config
+		want []string
+	}{
+		{
+			name: "top level only",
+			cfg:  Config{},
+			want: []string{"main.go", "main_test.go", "notes.md"},
+		},
+		{
+			name: "recursive applies default ignores",
+			cfg:  Config{Recursive: true, IgnoreDir: []string{"node_modules"}},
+			want: []string{"main.go", "main_test.go", "notes.md", "pkg/api.go"},
+		},
+		{
+			name: "hidden files with show all",
+			cfg:  Config{Recursive: true, ShowAll: true, IgnoreDir: []string{"node_modules"}},
+			want: []string{".env.local", "main.go", "main_test.go", "notes.md", "pkg/.cache/blob", "pkg/api.go"},
+		},
+		{
+			name: "globs and ignore globs",
+			cfg:  Config{Recursive: true, IgnoreDir: []string{"node_modules"}, Globs: []string{"*.go"}, IgnoreGlobs: []string{"*_test.go"}},
+			want: []string{"main.go", "pkg/api.go"},
+		},
+		{
+			name: "directory records bypass file globs",
+			cfg:  Config{Recursive: true, IgnoreDir: []string{"node_modules"}, Globs: []string{"*.md"}, IncludeDirs: true},
+			want: []string{"notes.md", "pkg"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.Directory = tmpDir
+			cfg.RelativeTo = tmpDir
+			cfg.OutputFormat = OutputFormatXML
+
+			app, err := New(&cfg)
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+
+			var got []string
+			for file, err := range app.Files(context.Background()) {
+				if err != nil {
+					t.Fatalf("Files() unexpected error: %v", err)
+				}
+				got = append(got, filepath.ToSlash(file.Info.RelPath))
+			}
+
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("files = %v\nwant    %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIncludeDirsStats(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"main.go":    "package main",
+		"pkg/api.go": "package pkg",
+	})
+	if err := os.MkdirAll(filepath.Join(tmpDir, "pkg", "empty"), 0o755); err != nil {
+		t.Fatalf("failed to create empty directory: %v", err)
+	}
+
+	output, app := runAndCapture(t, &Config{
+		Directory:    tmpDir,
+		RelativeTo:   tmpDir,
+		Recursive:    true,
+		IncludeDirs:  true,
+		OutputFormat: OutputFormatXML,
+	})
+
+	if !strings.Contains(output, `<dir path="pkg/empty"/>`) {
+		t.Errorf("expected empty directory record, got:\n%s", output)
+	}
+
+	stats := app.Stats()
+	if stats.Files != 2 || stats.Dirs != 2 {
+		t.Errorf("Stats() = %+v, want 2 files and 2 dirs", stats)
+	}
+}
+
+func TestMaxFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"a.go":     "package a",
+		"b.go":     "package b",
+		"sub/c.go": "package sub",
+	})
+
+	app, err := New(&Config{Directory: tmpDir, Recursive: true, MaxFiles: 2, OutputFormat: OutputFormatXML, Output: io.Discard})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	err = app.Run(context.Background())
+	if !errors.Is(err, scanner.ErrTooManyFiles) {
+		t.Fatalf("Run() error = %v, want ErrTooManyFiles", err)
+	}
+	if !strings.Contains(err.Error(), "--max-files") {
+		t.Errorf("Run() error %q should suggest how to narrow the scan", err)
+	}
+}
+
+func TestFailOnCaseCollision(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"README.md": "upper",
+		"Readme.md": "mixed",
+	})
+
+	for _, fail := range []bool{false, true} {
+		var warnings strings.Builder
+		app, err := New(&Config{
+			Directory:           tmpDir,
+			OutputFormat:        OutputFormatXML,
+			Output:              io.Discard,
+			Warnings:            &warnings,
+			FailOnCaseCollision: fail,
+		})
+		if err != nil {
+			t.Fatalf("New() unexpected error: %v", err)
+		}
+
+		err = app.Run(context.Background())
+		if fail != errors.Is(err, ErrCaseCollision) {
+			t.Errorf("FailOnCaseCollision=%v: Run() error = %v", fail, err)
+		}
+		if want := "Warning: paths differ only by case: README.md, Readme.md"; !strings.Contains(warnings.String(), want) {
+			t.Errorf("FailOnCaseCollision=%v: Warnings = %q, want it to contain %q", fail, warnings.String(), want)
+		}
+	}
+}
+
+func TestExecutableFilters(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("execute permission bits are not used on Windows")
+	}
+
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"build.sh":  "#!/bin/sh\necho build\n",
+		"notes.txt": "notes\n",
+	})
+	if err := os.Chmod(filepath.Join(tmpDir, "build.sh"), 0o755); err != nil {
+		t.Fatalf("failed to chmod build.sh: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(*Config)
+		want   string
+	}{
+		{name: "no filter", mutate: func(*Config) {}, want: `<file path="build.sh" executable="true">,<file path="notes.txt">`},
+		{name: "only executable", mutate: func(c *Config) { c.OnlyExecutable = true }, want: `<file path="build.sh" executable="true">`},
+		{name: "no executable", mutate: func(c *Config) { c.NoExecutable = true }, want: `<file path="notes.txt">`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			cfg := &Config{Directory: tmpDir, RelativeTo: tmpDir, OutputFormat: OutputFormatXML, Output: &buf}
+			tt.mutate(cfg)
+
+			app, err := New(cfg)
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+			if err := app.Run(context.Background()); err != nil {
+				t.Fatalf("Run() unexpected error: %v", err)
+			}
+
+			var got []string
+			for _, line := range strings.Split(buf.String(), "\n") {
+				if strings.HasPrefix(line, "<file ") {
+					got = append(got, line)
+				}
+			}
+			if strings.Join(got, ",") != tt.want {
+				t.Errorf("files = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProfileJSON(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"a.go":  "package a\n",
+		"b.txt": "notes\n",
+	})
+	profilePath := filepath.Join(t.TempDir(), "profile.json")
+
+	runAndCapture(t, &Config{
+		Directory:    tmpDir,
+		RelativeTo:   tmpDir,
+		OutputFormat: OutputFormatXML,
+		ProfileJSON:  profilePath,
+	})
+
+	data, err := os.ReadFile(profilePath)
+	if err != nil {
+		t.Fatalf("failed to read profile: %v", err)
+	}
+	var timings []profile.Timing
+	if err := json.Unmarshal(data, &timings); err != nil {
+		t.Fatalf("profile is not JSON: %v\n%s", err, data)
+	}
+
+	got := make(map[string]int)
+	for _, timing := range timings {
+		got[timing.Path+" "+string(timing.Stage)]++
+	}
+	for _, path := range []string{"a.go", "b.txt"} {
+		for _, stage := range profile.Stages {
+			if n := got[path+" "+string(stage)]; n != 1 {
+				t.Errorf("%s recorded %d times for %s, want 1", stage, n, path)
+			}
+		}
+	}
+}
+
+func TestProcessFileReusesPreviewedContent(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "a.txt")
+	modTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	write := func(content string, modTime time.Time) {
+		t.Helper()
+		writeTree(t, tmpDir, map[string]string{"a.txt": content})
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("failed to set times: %v", err)
+		}
+	}
+
+	write("preview\n", modTime)
+	cache := contentcache.New(contentcache.DefaultMaxBytes)
+	if _, _, err := cache.Load(path); err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+
+	processor := NewFileProcessor(nil, strings.ToUpper)
+	processor.contentCache = cache
+	info := scanner.FileInfo{Path: path, RelPath: "a.txt"}
+	filter := NewFileFilter(&Config{})
+
+	// Same size and time as the preview: the cached lines are used and transformed
+	write("changed\n", modTime)
+	if got := processor.ProcessFile(info, filter).Lines[0].Content; got != "PREVIEW" {
+		t.Errorf("content = %q, want the transformed cached line", got)
+	}
+
+	// The file was modified after the preview: it is read again
+	write("changed\n", modTime.Add(time.Second))
+	if got := processor.ProcessFile(info, filter).Lines[0].Content; got != "CHANGED" {
+		t.Errorf("content = %q, want the re-read line", got)
+	}
+}
+
+func TestConfirmLargeOutput(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"a.txt":   strings.Repeat("a", 60),
+		"b.txt":   strings.Repeat("b", 60),
+		"bin.dat": "\x00" + strings.Repeat("x", 500),
+	})
+
+	tests := []struct {
+		name      string
+		limit     int64
+		answer    bool
+		wantAsked int64
+		wantFiles bool
+	}{
+		{name: "under the limit", limit: 120, wantFiles: true},
+		{name: "declined", limit: 100, wantAsked: 120},
+		{name: "accepted", limit: 100, answer: true, wantAsked: 120, wantFiles: true},
+		{name: "disabled", limit: 0, wantFiles: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			var asked int64
+			app, err := New(&Config{
+				Directory:       tmpDir,
+				OutputFormat:    OutputFormatXML,
+				Output:          &out,
+				LargeOutputSize: tt.limit,
+				ConfirmLargeOutput: func(size int64) bool {
+					asked = size
+
+					return tt.answer
+				},
+			})
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+
+			if err := app.Run(context.Background()); err != nil {
+				t.Fatalf("Run() unexpected error: %v", err)
+			}
+			// Binary content is never printed, so it is not part of the projection
+			if asked != tt.wantAsked {
+				t.Errorf("asked about %d bytes, want %d", asked, tt.wantAsked)
+			}
+			if got := strings.Contains(out.String(), "a.txt"); got != tt.wantFiles {
+				t.Errorf("output has files = %v, want %v:\n%s", got, tt.wantFiles, out.String())
+			}
+			if !tt.wantFiles && out.String() != "Output cancelled.\n" {
+				t.Errorf("declined output = %q", out.String())
+			}
+		})
+	}
+}
+
+func TestOutputDirWritesEveryFormat(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"a.go":   "package a\n\n// TODO: b\n",
+		"b.md":   "# B\n",
+		"c.json": "{}\n",
+	})
+	formats := []OutputFormat{OutputFormatXML, OutputFormatJSON, OutputFormatMarkdown, OutputFormatPrompt, OutputFormatPretty}
+	formatOptions := map[string]string{"json:pretty": "false", "markdown:heading-level": "3"}
+
+	var status bytes.Buffer
+	outDir := filepath.Join(t.TempDir(), "nested", "out")
+	app, err := New(&Config{
+		Directory:     tmpDir,
+		OutputFormat:  OutputFormatXML,
+		OutputFormats: formats,
+		OutputDir:     outDir,
+		Output:        &status,
+		FormatOptions: formatOptions,
+	})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if status.Len() != 0 {
+		t.Errorf("Output received %q, want nothing", status.String())
+	}
+
+	// Each file matches what a single-format run writes to Output
+	for _, format := range formats {
+		own := make(map[string]string)
+		for key, value := range formatOptions {
+			if strings.HasPrefix(key, format.String()+":") {
+				own[key] = value
+			}
+		}
+
+		var want bytes.Buffer
+		single, err := New(&Config{Directory: tmpDir, OutputFormat: format, Output: &want, FormatOptions: own})
+		if err != nil {
+			t.Fatalf("New(%s) unexpected error: %v", format, err)
+		}
+		if err := single.Run(context.Background()); err != nil {
+			t.Fatalf("Run(%s) unexpected error: %v", format, err)
+		}
+
+		got, err := os.ReadFile(filepath.Join(outDir, OutputFileName(format)))
+		if err != nil {
+			t.Fatalf("failed to read %s output: %v", format, err)
+		}
+		if string(got) != want.String() {
+			t.Errorf("%s file differs from a single-format run:\ngot:\n%s\nwant:\n%s", format, got, want.String())
+		}
+	}
+
+	if got := OutputFileName(OutputFormatMarkdown); got != "out-markdown.md" {
+		t.Errorf("OutputFileName(markdown) = %q", got)
+	}
+}
+
+func TestGlobBraceExpansion(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"a.go":       "package a\n",
+		"b.md":       "# B\n",
+		"c.json":     "{}\n",
+		"src/d.go":   "package src\n",
+		"src/e.yaml": "e: 1\n",
+	})
+
+	var buf bytes.Buffer
+	app, err := New(&Config{
+		Directory:    tmpDir,
+		Recursive:    true,
+		OutputFormat: OutputFormatJSON,
+		Output:       &buf,
+		Globs:        []string{"*.{go,md}", "./src/*.{yaml,yml}"},
+		IgnoreGlobs:  []string{"src/{d}.go"},
+	})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	out := buf.String()
+	for _, path := range []string{"a.go", "b.md", "src/e.yaml"} {
+		if !strings.Contains(out, `"path": "`+path+`"`) {
+			t.Errorf("output is missing %s:\n%s", path, out)
+		}
+	}
+	for _, path := range []string{"c.json", "src/d.go"} {
+		if strings.Contains(out, `"path": "`+path+`"`) {
+			t.Errorf("output includes %s:\n%s", path, out)
+		}
+	}
+}
+
+func TestGlobMatchesHint(t *testing.T) {
+	if m := newGlobMatches(&Config{}); m != nil {
+		t.Errorf("newGlobMatches() without patterns = %+v, want nil", m)
+	}
+
+	m := newGlobMatches(&Config{Globs: []string{"*.go", "*.rs"}, IgnoreGlobs: []string{"*_test.go"}})
+	for _, path := range []string{"main.go", "main_test.go", "cmd/root.go", "README.md"} {
+		m.record(path)
+	}
+
+	var buf bytes.Buffer
+	m.writeHint(&buf, 4)
+	want := `Hint: none of the 4 files found passed the filters. Files matched by each pattern:
+  --globs "*.go": 3
+  --globs "*.rs": 0
+  --ignore-globs "*_test.go": 1
+`
+	if buf.String() != want {
+		t.Errorf("writeHint() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"main.go":           "package main\n\n// TODO: more\n",
+		"notes.txt":         "plain notes\n",
+		"blank.txt":         "  \n",
+		"LICENSE":           "MIT\n",
+		".env":              "KEY=1\n",
+		"src/app.py":        "print('hi')\n",
+		"vendor/lib.go":     "package lib\n",
+		"src/.cache/old.py": "print('old')\n",
+	})
+
+	tests := []struct {
+		name     string
+		path     string
+		cfg      Config
+		wantRule string // Deciding rule; empty when the file is included
+	}{
+		{name: "included", path: "main.go"},
+		{name: "hidden file", path: ".env", wantRule: "hidden file"},
+		{name: "hidden file shown", path: ".env", cfg: Config{ShowAll: true}},
+		{name: "not recursive", path: "src/app.py", wantRule: "recursion"},
+		{name: "ignored directory", path: "vendor/lib.go", cfg: Config{Recursive: true, IgnoreDir: []string{"vendor"}}, wantRule: "ignore-dir"},
+		{name: "hidden directory", path: "src/.cache/old.py", cfg: Config{Recursive: true}, wantRule: "hidden directory"},
+		{name: "default ignore glob", path: "LICENSE", wantRule: "default ignore glob"},
+		{name: "user ignore glob", path: "notes.txt", cfg: Config{IgnoreGlobs: []string{"*.txt"}}, wantRule: "user ignore glob"},
+		{name: "include glob", path: "notes.txt", cfg: Config{Globs: []string{"*.{go,py}"}}, wantRule: "include glob"},
+		{name: "type", path: "notes.txt", cfg: Config{Types: []string{"go"}}, wantRule: "type"},
+		{name: "empty file", path: "blank.txt", cfg: Config{SkipEmpty: true}, wantRule: "size filter"},
+		{name: "pattern all", path: "main.go", cfg: Config{ContentPatterns: []string{"*TODO*", "*FIXME*"}, PatternAll: true}, wantRule: "content patterns"},
+		{name: "todos", path: "notes.txt", cfg: Config{Todos: true}, wantRule: "todos"},
+		{name: "relative to directory", path: filepath.Join(tmpDir, "src", "app.py"), cfg: Config{Recursive: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.Directory = tmpDir
+			cfg.OutputFormat = OutputFormatJSON
+			var buf bytes.Buffer
+			cfg.Output = &buf
+
+			app, err := New(&cfg)
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+			explanation, err := app.Explain(tt.path)
+			if err != nil {
+				t.Fatalf("Explain() unexpected error: %v", err)
+			}
+
+			decision, excluded := explanation.Decision()
+			if decision.Rule != tt.wantRule {
+				t.Errorf("deciding rule = %q, want %q (verdicts %+v)", decision.Rule, tt.wantRule, explanation.Verdicts)
+			}
+
+			// The explanation agrees with what a run outputs
+			if err := app.Run(context.Background()); err != nil {
+				t.Fatalf("Run() unexpected error: %v", err)
+			}
+			output := strings.Contains(buf.String(), `"path": "`+filepath.ToSlash(explanation.Path)+`"`)
+			if output == excluded {
+				t.Errorf("run output %s = %v, but explanation excluded = %v", explanation.Path, output, excluded)
+			}
+
+			var text bytes.Buffer
+			if err := WriteExplanation(&text, explanation); err != nil {
+				t.Fatalf("WriteExplanation() unexpected error: %v", err)
+			}
+			wantLast := "INCLUDED"
+			if excluded {
+				wantLast = "EXCLUDED by " + decision.String()
+			}
+			if lines := strings.Split(strings.TrimSpace(text.String()), "\n"); lines[len(lines)-1] != wantLast {
+				t.Errorf("last line = %q, want %q", lines[len(lines)-1], wantLast)
+			}
+		})
+	}
+}
+
+func TestExplainOutsideDirectory(t *testing.T) {
+	app, err := New(&Config{Directory: t.TempDir(), OutputFormat: OutputFormatXML})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	outside := filepath.Join(t.TempDir(), "other.go")
+	if err := os.WriteFile(outside, []byte("package other\n"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if _, err := app.Explain(outside); err == nil || !strings.Contains(err.Error(), "is not under the scanned directory") {
+		t.Errorf("Explain() error = %v, want a path outside the directory to be rejected", err)
+	}
+}
+
+func TestDedupeContent(t *testing.T) {
+	tmpDir := t.TempDir()
+	license := "Permission is hereby granted, free of charge\n"
+	writeTree(t, tmpDir, map[string]string{
+		"a/COPYING":  license,
+		"b/COPYING":  license,
+		"c/COPYING":  license + "with changes\n",
+		"empty1.txt": "",
+		"empty2.txt": "",
+		"main.go":    "package main\n",
+	})
+	if err := os.Link(filepath.Join(tmpDir, "main.go"), filepath.Join(tmpDir, "main_link.go")); err != nil {
+		t.Skipf("hard links unsupported: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		dedupe bool
+		want   map[string]string // Path to DuplicateOf for every written file
+	}{
+		{
+			name: "off by default",
+			want: map[string]string{"a/COPYING": "", "b/COPYING": "", "c/COPYING": "", "empty1.txt": "", "empty2.txt": "", "main.go": "", "main_link.go": ""},
+		},
+		{
+			name:   "dedupe",
+			dedupe: true,
+			want:   map[string]string{"a/COPYING": "", "b/COPYING": "a/COPYING", "c/COPYING": "", "empty1.txt": "", "empty2.txt": "", "main.go": "", "main_link.go": "main.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			app, err := New(&Config{
+				Directory:     tmpDir,
+				Recursive:     true,
+				OutputFormat:  OutputFormatJSON,
+				Output:        &buf,
+				DedupeContent: tt.dedupe,
+			})
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+			if err := app.Run(context.Background()); err != nil {
+				t.Fatalf("Run() unexpected error: %v", err)
+			}
+
+			var doc struct {
+				Files []JSONFile `json:"files"`
+			}
+			if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+				t.Fatalf("output does not parse: %v", err)
+			}
+			got := make(map[string]string, len(doc.Files))
+			for _, file := range doc.Files {
+				got[filepath.ToSlash(file.Path)] = file.DuplicateOf
+				if file.DuplicateOf != "" && len(file.Lines) > 0 {
+					t.Errorf("duplicate %s repeats its content", file.Path)
+				}
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("duplicates = %v, want %v", got, tt.want)
+			}
+
+			stats := app.Stats()
+			wantDuplicates, wantBytes := 0, int64(0)
+			if tt.dedupe {
+				wantDuplicates, wantBytes = 2, int64(len(license)+len("package main\n"))
+			}
+			if stats.Duplicates != wantDuplicates || stats.DuplicateBytes != wantBytes {
+				t.Errorf("stats = %d duplicates, %d bytes, want %d and %d", stats.Duplicates, stats.DuplicateBytes, wantDuplicates, wantBytes)
+			}
+		})
+	}
+}
+
+func TestListPaths(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"a b.txt":     "spaces\n",
+		"empty.txt":   " \n",
+		"src/main.go": "package main\n",
+	}
+	if runtime.GOOS != "windows" {
+		files["line\nbreak.txt"] = "newline\n"
+	}
+	writeTree(t, tmpDir, files)
+
+	want := []string{"a b.txt"}
+	if runtime.GOOS != "windows" {
+		want = append(want, "line\nbreak.txt")
+	}
+	want = append(want, "src/main.go")
+
+	tests := []struct {
+		name       string
+		print0     bool
+		terminator string
+	}{
+		{name: "newline separated", terminator: "\n"},
+		{name: "NUL separated", print0: true, terminator: "\x00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			cfg := &Config{
+				Directory:    tmpDir,
+				Recursive:    true,
+				SkipEmpty:    true,
+				OutputFormat: OutputFormatXML,
+				Output:       &buf,
+				List:         true,
+				Print0:       tt.print0,
+			}
+			app, err := New(cfg)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if err := app.Run(context.Background()); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			var expected strings.Builder
+			for _, path := range want {
+				expected.WriteString(filepath.Join(tmpDir, filepath.FromSlash(path)) + tt.terminator)
+			}
+			if buf.String() != expected.String() {
+				t.Errorf("output = %q, want %q", buf.String(), expected.String())
+			}
+			if stats := app.Stats(); stats.Files != len(want) || stats.SkippedEmpty != 1 {
+				t.Errorf("stats = %d files, %d skipped empty, want %d and 1", stats.Files, stats.SkippedEmpty, len(want))
+			}
+		})
+	}
+
+	t.Run("status messages stay off stdout", func(t *testing.T) {
+		var buf bytes.Buffer
+		app, err := New(&Config{Directory: t.TempDir(), OutputFormat: OutputFormatXML, Output: &buf, List: true})
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		if err := app.Run(context.Background()); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("output = %q, want nothing", buf.String())
+		}
+	})
+}
+
+func TestPaths(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"main.go":          "package main\n",
+		"LICENSE":          "MIT\n",
+		".env":             "KEY=value\n",
+		"src/util.go":      "package src\n",
+		"src/util_test.go": "package src\n",
+		"other.go":         "package other\n",
+	})
+
+	tests := []struct {
+		name        string
+		paths       []string
+		ignoreGlobs []string
+		want        []string
+	}{
+		{
+			name:  "exactly the named files in order",
+			paths: []string{"src/util.go", "main.go"},
+			want:  []string{"src/util.go", "main.go"},
+		},
+		{
+			name:  "hidden and default-ignored files when named",
+			paths: []string{".env", "LICENSE"},
+			want:  []string{".env", "LICENSE"},
+		},
+		{
+			name:        "user ignore globs still apply",
+			paths:       []string{"src/util.go", "src/util_test.go"},
+			ignoreGlobs: []string{"*_test.go"},
+			want:        []string{"src/util.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Directory: tmpDir, Paths: tt.paths, IgnoreGlobs: tt.ignoreGlobs, OutputFormat: OutputFormatXML, Output: io.Discard}
+			app, err := New(cfg)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			var got []string
+			for file, err := range app.Files(context.Background()) {
+				if err != nil {
+					t.Fatalf("Files() error = %v", err)
+				}
+				got = append(got, filepath.ToSlash(file.Info.RelPath))
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("files = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFrontMatter(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"post.md":     "---\ntitle: Hi\n---\nBody\n",
+		"windows.md":  "\ufeff+++\r\ntitle = 'x'\r\n+++\r\nBody\r\n",
+		"plain.md":    "No front matter\n",
+		"unclosed.md": "---\nnever closed\n",
+		"multi.yaml":  "---\na: 1\n---\nb: 2\n",
+	})
+
+	type line struct {
+		number  int
+		content string
+	}
+	tests := []struct {
+		name        string
+		only        bool
+		strip       bool
+		want        map[string][]line
+		wantTypes   map[string]string
+		wantSkipped int
+	}{
+		{
+			name: "front matter only",
+			only: true,
+			want: map[string][]line{
+				"post.md":    {{2, "title: Hi"}},
+				"windows.md": {{2, "title = 'x'"}},
+			},
+			wantTypes:   map[string]string{"post.md": "yaml", "windows.md": "toml"},
+			wantSkipped: 3,
+		},
+		{
+			name:  "strip front matter",
+			strip: true,
+			want: map[string][]line{
+				"post.md":     {{4, "Body"}},
+				"windows.md":  {{4, "Body"}},
+				"plain.md":    {{1, "No front matter"}},
+				"unclosed.md": {{1, "---"}, {2, "never closed"}},
+				"multi.yaml":  {{1, "---"}, {2, "a: 1"}, {3, "---"}, {4, "b: 2"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Directory: tmpDir, FrontMatterOnly: tt.only, StripFrontMatter: tt.strip, OutputFormat: OutputFormatXML, Output: io.Discard}
+			app, err := New(cfg)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			got := make(map[string][]line)
+			for file, err := range app.Files(context.Background()) {
+				if err != nil {
+					t.Fatalf("Files() error = %v", err)
+				}
+				var lines []line
+				for _, l := range file.Lines {
+					lines = append(lines, line{l.LineNumber, l.Content})
+				}
+				got[file.Info.RelPath] = lines
+				if want, ok := tt.wantTypes[file.Info.RelPath]; ok && file.FileType != want {
+					t.Errorf("%s type = %q, want %q", file.Info.RelPath, file.FileType, want)
+				}
+			}
+
+			if len(got) != len(tt.want) {
+				t.Errorf("files = %v, want %v", got, tt.want)
+			}
+			for path, want := range tt.want {
+				if fmt.Sprint(got[path]) != fmt.Sprint(want) {
+					t.Errorf("%s lines = %v, want %v", path, got[path], want)
+				}
+			}
+			if skipped := app.Stats().SkippedFrontMatter; skipped != tt.wantSkipped {
+				t.Errorf("SkippedFrontMatter = %d, want %d", skipped, tt.wantSkipped)
+			}
+		})
+	}
+}
+
+func TestUTF16FilesRenderAsText(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		// "Grüße\r\n" in UTF-16LE, with and without a byte order mark
+		"bom.ps1":    "\xFF\xFEG\x00r\x00\xFC\x00\xDF\x00e\x00\r\x00\n\x00",
+		"no-bom.ps1": "G\x00r\x00\xFC\x00\xDF\x00e\x00\r\x00\n\x00",
+	})
+
+	var buf bytes.Buffer
+	app, err := New(&Config{Directory: tmpDir, OutputFormat: OutputFormatXML, Output: &buf})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	if got := strings.Count(buf.String(), "\nGrüße\n"); got != 2 {
+		t.Errorf("decoded content appears %d times, want 2:\n%s", got, buf.String())
+	}
+	if stats := app.Stats(); stats.Binary != 0 {
+		t.Errorf("Binary = %d, want 0", stats.Binary)
+	}
+}
@@ -0,0 +1,102 @@
+package catls
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+)
+
+// Version is the catls release recorded in the configuration echo. Release
+// builds set it with -ldflags "-X
+// github.com/connerohnesorge/catls/internal/catls.Version=..."; otherwise the
+// module version recorded by go install is used, or "dev".
+var Version = ""
+
+// version returns Version, falling back to the build's module version.
+func version() string {
+	if Version != "" {
+		return Version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+
+	return "dev"
+}
+
+// ConfigEcho records the settings that produced an output, so a dump found
+// later tells how it was made. The scanned directory is named by its base
+// name only, and globs under the home directory are written relative to "~".
+type ConfigEcho struct {
+	Version     string   `json:"version"`
+	Format      string   `json:"format"`
+	Root        string   `json:"root,omitempty"` // Base name of the scanned directory
+	Recursive   bool     `json:"recursive"`
+	Globs       []string `json:"globs,omitempty"`
+	IgnoreRules int      `json:"ignoreRules"` // Ignore globs, default and user, plus ignored directories
+	MaxLines    int      `json:"maxLines"`    // Longer files are truncated
+	TruncateTo  int      `json:"truncateTo"`  // Lines kept of a truncated file
+	ReadmeLines int      `json:"readmeLines,omitempty"`
+	MaxTokens   int      `json:"maxTokens,omitempty"`
+}
+
+// ConfigEchoWriter is implemented by formatters that can record a
+// ConfigEcho. With Config.ConfigEcho, App calls SetConfigEcho before
+// WriteHeader, and the formatter writes the echo ahead of any file so that
+// partial output still carries it. Formatters that do not implement it simply
+// omit the echo.
+type ConfigEchoWriter interface {
+	SetConfigEcho(echo ConfigEcho)
+}
+
+// configEcho describes the run's configuration for ConfigEchoWriter.
+func (a *App) configEcho() ConfigEcho {
+	globs := make([]string, len(a.cfg.Globs))
+	for i, glob := range a.cfg.Globs {
+		globs[i] = elideHome(glob)
+	}
+
+	return ConfigEcho{
+		Version:     version(),
+		Format:      strings.ReplaceAll(a.cfg.formatList(), " ", ""),
+		Root:        a.rootName(),
+		Recursive:   a.cfg.Recursive,
+		Globs:       globs,
+		IgnoreRules: len(a.cfg.AllIgnoreGlobs()) + len(a.cfg.IgnoreDir),
+		MaxLines:    maxDisplayLines,
+		TruncateTo:  truncateToLines,
+		ReadmeLines: a.cfg.ReadmeLines,
+		MaxTokens:   a.cfg.MaxTokens,
+	}
+}
+
+// elideHome writes path relative to "~" when it is in the home directory.
+func elideHome(path string) string {
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" || !filepath.IsAbs(home) {
+		return path
+	}
+
+	if path == home {
+		return "~"
+	}
+	if rest, ok := strings.CutPrefix(path, home+string(filepath.Separator)); ok {
+		return "~/" + filepath.ToSlash(rest)
+	}
+
+	return path
+}
+
+// configEchoComment renders echo as JSON for an XML or HTML comment. JSON
+// strings may hold "--", which ends a comment, so hyphen pairs are written
+// with an escape that decodes to the same value.
+func configEchoComment(echo ConfigEcho) string {
+	data, err := json.Marshal(echo)
+	if err != nil {
+		return ""
+	}
+
+	return "<!-- catls " + strings.ReplaceAll(string(data), "--", `-\u002d`) + " -->\n"
+}
@@ -0,0 +1,56 @@
+package catls
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConfigEchoComment(t *testing.T) {
+	echo := ConfigEcho{Version: "dev", Format: "xml", Globs: []string{"--*.go", "a---b"}, IgnoreRules: 3}
+
+	comment := configEchoComment(echo)
+	body, ok := strings.CutPrefix(comment, "<!-- catls ")
+	if !ok {
+		t.Fatalf("comment %q does not open with the catls marker", comment)
+	}
+	body, ok = strings.CutSuffix(body, " -->\n")
+	if !ok {
+		t.Fatalf("comment %q is not closed on its line", comment)
+	}
+	if strings.Contains(body, "--") {
+		t.Errorf("comment body %q contains --, which ends a comment early", body)
+	}
+
+	var decoded ConfigEcho
+	if err := json.Unmarshal([]byte(body), &decoded); err != nil {
+		t.Fatalf("comment body is not JSON: %v\n%s", err, body)
+	}
+	if !reflect.DeepEqual(decoded, echo) {
+		t.Errorf("decoded echo = %+v, want %+v", decoded, echo)
+	}
+}
+
+func TestElideHome(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: home, want: "~"},
+		{path: filepath.Join(home, "src", "*.go"), want: "~/src/*.go"},
+		{path: home + "other/*.go", want: home + "other/*.go"},
+		{path: "*.go", want: "*.go"},
+	}
+
+	for _, tt := range tests {
+		if got := elideHome(tt.path); got != tt.want {
+			t.Errorf("elideHome(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package catls
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/connerohnesorge/catls/internal/languages"
+)
+
+// CrossLinker is implemented by formatters that link mentions of other
+// included files to their sections. With CrossLink, App processes every file
+// first and calls SetCrossLinks before the first WriteFile. Formatters that do
+// not implement it write mentions as plain text.
+type CrossLinker interface {
+	SetCrossLinks(links *CrossLinks)
+}
+
+// CrossLinks finds mentions of included files in content. A mention is an
+// exact relative path, in forward-slash form and optionally led by "./",
+// delimited by characters that cannot be part of a path. Paths without a
+// directory or a known extension, such as Makefile or a single letter, are
+// never matched, as they occur too often by accident.
+type CrossLinks struct {
+	paths   map[string]string // Mentioned form to the file's RelPath
+	anchors map[string]string // RelPath to the Markdown heading anchor
+}
+
+// CrossLinkMatch is one mention found by CrossLinks.Find.
+type CrossLinkMatch struct {
+	Start int    // Byte offset of the mention in the line
+	End   int    // Byte offset just past the mention
+	Path  string // RelPath of the mentioned file
+}
+
+// NewCrossLinks indexes files, given in output order, for linking. Anchors
+// follow GitHub's heading ids for the headings MarkdownOutput writes,
+// including the numeric suffix of repeated ids.
+func NewCrossLinks(files []ProcessedFile) *CrossLinks {
+	links := &CrossLinks{
+		paths:   make(map[string]string),
+		anchors: make(map[string]string),
+	}
+
+	seen := make(map[string]int)
+	for _, file := range files {
+		heading := file.Info.RelPath
+		if file.Info.IsDir {
+			heading += "/ (directory)"
+		}
+		anchor := markdownAnchor(heading)
+		if n := seen[anchor]; n > 0 {
+			seen[anchor]++
+			anchor += "-" + strconv.Itoa(n)
+		} else {
+			seen[anchor] = 1
+		}
+
+		if file.Info.IsDir {
+			continue
+		}
+		mention := filepath.ToSlash(file.Info.RelPath)
+		if !linkable(mention) {
+			continue
+		}
+		links.paths[mention] = file.Info.RelPath
+		links.anchors[file.Info.RelPath] = anchor
+	}
+
+	return links
+}
+
+// linkable reports whether mentions of path are specific enough to link:
+// it must name a directory or have an extension of a known language.
+func linkable(path string) bool {
+	if strings.ContainsFunc(path, func(r rune) bool { return !isPathRune(r) }) {
+		return false
+	}
+
+	return strings.Contains(path, "/") || languages.DetectByExtension(path) != ""
+}
+
+// Anchor returns the Markdown heading anchor of the file at path, without
+// the leading "#".
+func (c *CrossLinks) Anchor(path string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	anchor, ok := c.anchors[path]
+
+	return anchor, ok
+}
+
+// Find returns the mentions of indexed files in line, in order. Mentions of
+// self, the RelPath of the file the line belongs to, are left out. A nil
+// CrossLinks finds nothing.
+func (c *CrossLinks) Find(line, self string) []CrossLinkMatch {
+	if c == nil || len(c.paths) == 0 {
+		return nil
+	}
+
+	var matches []CrossLinkMatch
+	for start := 0; start < len(line); {
+		r, size := utf8.DecodeRuneInString(line[start:])
+		if !isPathRune(r) {
+			start += size
+
+			continue
+		}
+
+		end := start + strings.IndexFunc(line[start:], func(r rune) bool { return !isPathRune(r) })
+		if end < start {
+			end = len(line)
+		}
+
+		// A sentence may end right after a path
+		token := strings.TrimRight(line[start:end], ".")
+		offset := start
+		if trimmed := strings.TrimPrefix(token, "./"); trimmed != token {
+			offset += len(token) - len(trimmed)
+			token = trimmed
+		}
+		if path, ok := c.paths[token]; ok && path != self {
+			matches = append(matches, CrossLinkMatch{Start: offset, End: offset + len(token), Path: path})
+		}
+		start = end
+	}
+
+	return matches
+}
+
+// References returns the distinct files mentioned in lines, in order of
+// first mention, for formats that cannot link inside content.
+func (c *CrossLinks) References(lines []FilteredLine, self string) []string {
+	var refs []string
+	seen := make(map[string]bool)
+	for _, line := range lines {
+		for _, match := range c.Find(line.Content, self) {
+			if !seen[match.Path] {
+				seen[match.Path] = true
+				refs = append(refs, match.Path)
+			}
+		}
+	}
+
+	return refs
+}
+
+// isPathRune reports whether r may appear in a linkable path.
+func isPathRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_-./", r)
+}
+
+// markdownAnchor returns the id GitHub gives a heading with this text:
+// lowercased, without punctuation other than hyphens and underscores, and
+// with spaces turned into hyphens.
+func markdownAnchor(heading string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(heading) {
+		switch {
+		case r == ' ':
+			b.WriteRune('-')
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
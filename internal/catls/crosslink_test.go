@@ -0,0 +1,106 @@
+package catls
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+func TestCrossLinksFind(t *testing.T) {
+	links := NewCrossLinks([]ProcessedFile{
+		{Info: scanner.FileInfo{RelPath: "internal/scanner/scanner.go"}},
+		{Info: scanner.FileInfo{RelPath: "main.go"}},
+		{Info: scanner.FileInfo{RelPath: "Makefile"}},
+		{Info: scanner.FileInfo{RelPath: "docs", IsDir: true}},
+		{Info: scanner.FileInfo{RelPath: "docs/guide.md"}},
+	})
+
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{name: "path in a comment", line: "// see internal/scanner/scanner.go.", want: "[7:34 internal/scanner/scanner.go]"},
+		{name: "leading dot slash", line: "run ./main.go", want: "[6:13 main.go]"},
+		{name: "several mentions", line: `"main.go", "internal/scanner/scanner.go"`, want: "[1:8 main.go 12:39 internal/scanner/scanner.go]"},
+		{name: "followed by a line number", line: "main.go:12", want: "[0:7 main.go]"},
+		{name: "part of a longer name", line: "xmain.go main.go.bak cmd/main.go", want: "[]"},
+		{name: "no directory or known extension", line: "make -f Makefile", want: "[]"},
+		{name: "directories are not files", line: "see docs", want: "[]"},
+		{name: "own path", line: "docs/guide.md", want: "[]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, match := range links.Find(tt.line, "docs/guide.md") {
+				got = append(got, fmt.Sprintf("%d:%d %s", match.Start, match.End, match.Path))
+			}
+			if fmt.Sprint(got) != tt.want {
+				t.Errorf("Find(%q) = %v, want %s", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkdownCrossLink(t *testing.T) {
+	files := []ProcessedFile{
+		{
+			Info:     scanner.FileInfo{RelPath: "README.md"},
+			IsReadme: true,
+			Lines:    []FilteredLine{{LineNumber: 1, Content: "Start at cmd/main.go, then cmd/main.go again."}},
+		},
+		{
+			Info:     scanner.FileInfo{RelPath: "cmd/main.go"},
+			FileType: "go",
+			Lines: []FilteredLine{
+				{LineNumber: 1, Content: "// Documented in README.md and lib/util.go"},
+				{LineNumber: 2, Content: "// Also lib/util.go"},
+			},
+		},
+		{Info: scanner.FileInfo{RelPath: "lib/util.go"}, FileType: "go", Lines: []FilteredLine{{LineNumber: 1, Content: "package lib"}}},
+		{Info: scanner.FileInfo{RelPath: "lib/copy.go"}, DuplicateOf: "lib/util.go"},
+	}
+
+	var buf bytes.Buffer
+	output := NewMarkdownOutput(&buf)
+	output.SetCrossLinks(NewCrossLinks(files))
+	for i := range files {
+		if err := output.WriteFile(context.Background(), &files[i], &Config{}); err != nil {
+			t.Fatalf("WriteFile() unexpected error: %v", err)
+		}
+	}
+	got := buf.String()
+
+	for _, want := range []string{
+		"> Start at [cmd/main.go](#cmdmaingo), then [cmd/main.go](#cmdmaingo) again.\n",
+		"// Documented in README.md and lib/util.go\n",
+		"```\n\nReferences:\n\n- [README.md](#readmemd)\n- [lib/util.go](#libutilgo)\n\n## lib/util.go",
+		"*Identical to [lib/util.go](#libutilgo)*\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q\noutput:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "package lib\n```\n\nReferences") {
+		t.Errorf("file without mentions has references\noutput:\n%s", got)
+	}
+}
+
+func TestMarkdownAnchorsRepeat(t *testing.T) {
+	links := NewCrossLinks([]ProcessedFile{
+		{Info: scanner.FileInfo{RelPath: "a/b.go"}},
+		{Info: scanner.FileInfo{RelPath: "ab.go"}},
+		{Info: scanner.FileInfo{RelPath: "a_b.go"}},
+	})
+
+	for path, want := range map[string]string{"a/b.go": "abgo", "ab.go": "abgo-1", "a_b.go": "a_bgo"} {
+		if got, _ := links.Anchor(path); got != want {
+			t.Errorf("Anchor(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
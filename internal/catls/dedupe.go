@@ -0,0 +1,105 @@
+package catls
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/connerohnesorge/catls/internal/fdlimit"
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+// contentIndex remembers the files a DedupeContent run has written, by
+// identity, so a hard link is recognized without reading it, and by content
+// hash.
+type contentIndex struct {
+	byIdentity map[scanner.FileIdentity]string
+	byHash     map[[sha256.Size]byte]string
+}
+
+// contentKey is what a file is recorded under once it is written.
+type contentKey struct {
+	identity    scanner.FileIdentity
+	hasIdentity bool
+	hash        [sha256.Size]byte
+	hasHash     bool
+}
+
+// newContentIndex returns an empty index.
+func newContentIndex() *contentIndex {
+	return &contentIndex{
+		byIdentity: make(map[scanner.FileIdentity]string),
+		byHash:     make(map[[sha256.Size]byte]string),
+	}
+}
+
+// lookup returns the path of a written file identical to file, or an empty
+// string and the key to record file under with add. Empty files are never
+// duplicates, since referring to another empty file saves nothing, and
+// unreadable files are left for processing to report.
+func (c *contentIndex) lookup(file scanner.FileInfo) (string, contentKey) {
+	var key contentKey
+	if file.Size == 0 {
+		return "", key
+	}
+
+	if info, err := os.Stat(file.Path); err == nil {
+		key.identity, key.hasIdentity = scanner.Identity(info)
+	}
+	if first, ok := c.byIdentity[key.identity]; key.hasIdentity && ok {
+		return first, key
+	}
+
+	hash, err := hashFile(file.Path)
+	key.hash, key.hasHash = hash, err == nil
+	if first, ok := c.byHash[key.hash]; key.hasHash && ok {
+		return first, key
+	}
+
+	return "", key
+}
+
+// add records relPath, just written, under key.
+func (c *contentIndex) add(key contentKey, relPath string) {
+	if key.hasIdentity {
+		c.byIdentity[key.identity] = relPath
+	}
+	if key.hasHash {
+		c.byHash[key.hash] = relPath
+	}
+}
+
+// hashFile returns the SHA-256 of the file at path, or why it could not be
+// read.
+func hashFile(path string) ([sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+
+	f, err := fdlimit.Open(path)
+	if err != nil {
+		return sum, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return sum, err
+	}
+	copy(sum[:], hash.Sum(nil))
+
+	return sum, nil
+}
+
+// duplicateFile returns the reference entry written in place of file, whose
+// content is identical to the written file at first.
+func (a *App) duplicateFile(file scanner.FileInfo, first string) ProcessedFile {
+	if a.cfg.Debug {
+		fmt.Fprintf(a.warn, "Debug: Writing %s as a reference to identical %s\n", file.RelPath, first)
+	}
+	a.stats.Duplicates++
+	a.stats.DuplicateBytes += file.Size
+
+	return ProcessedFile{Info: file, FileType: file.FileType, DuplicateOf: first}
+}
@@ -0,0 +1,64 @@
+package catls
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+// deterministicFiles rewrites scanned files for Deterministic: relative paths
+// use forward slashes, the executable bit is cleared because it depends on the
+// filesystem and platform, and files are sorted strictly by the rewritten path
+// with compare. Path is left alone since files are still read through it.
+func deterministicFiles(files []scanner.FileInfo, compare func(a, b string) int) []scanner.FileInfo {
+	for i := range files {
+		files[i].RelPath = filepath.ToSlash(files[i].RelPath)
+		files[i].Executable = false
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		return compare(files[i].RelPath, files[j].RelPath) < 0
+	})
+
+	return files
+}
+
+// relPathError reports a file error with the scanned path replaced by the
+// relative one, which does not depend on the working directory.
+type relPathError struct {
+	msg string
+	err error
+}
+
+func (e *relPathError) Error() string { return e.msg }
+
+func (e *relPathError) Unwrap() error { return e.err }
+
+// scrubError replaces the scanned path in file.Error with file.Info.RelPath.
+func scrubError(file *ProcessedFile) {
+	if file.Error == nil || file.Info.Path == file.Info.RelPath {
+		return
+	}
+
+	file.Error = &relPathError{
+		msg: strings.ReplaceAll(file.Error.Error(), file.Info.Path, file.Info.RelPath),
+		err: file.Error,
+	}
+}
+
+// contentSuffix returns six hex digits derived from lines and attempt, a
+// reproducible stand-in for randomSuffix.
+func contentSuffix(lines []string, attempt int) string {
+	hash := sha256.New()
+	hash.Write([]byte(strconv.Itoa(attempt)))
+	for _, line := range lines {
+		hash.Write([]byte("\n" + line))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)[:3])
+}
@@ -0,0 +1,123 @@
+package catls
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+// dirLimit applies MaxFilesPerDir. Files count against their immediate parent
+// directory as they are written, so files dropped by other filters do not use
+// up a directory's share, and files past the limit are never read.
+type dirLimit struct {
+	max     int
+	written map[string]int // Files written per directory
+	left    map[string]int // Files per directory the run has not reached yet
+}
+
+// newDirLimit prepares the limit for files in output order. It is nil, and
+// keeps everything, when max is 0.
+func newDirLimit(files []scanner.FileInfo, limit int) *dirLimit {
+	if limit <= 0 {
+		return nil
+	}
+
+	l := &dirLimit{
+		max:     limit,
+		written: make(map[string]int),
+		left:    make(map[string]int),
+	}
+	for _, file := range files {
+		if !file.IsDir {
+			l.left[filepath.Dir(file.RelPath)]++
+		}
+	}
+
+	return l
+}
+
+// full marks file as reached and reports whether its directory already has
+// max files written. It must be called once for every file, in order.
+func (l *dirLimit) full(file scanner.FileInfo) bool {
+	if l == nil {
+		return false
+	}
+
+	dir := filepath.Dir(file.RelPath)
+	l.left[dir]--
+
+	return l.written[dir] >= l.max
+}
+
+// add records a written file. When it fills its directory, it returns the
+// number of that directory's files still ahead, all of which full drops.
+func (l *dirLimit) add(file scanner.FileInfo) int {
+	if l == nil {
+		return 0
+	}
+
+	dir := filepath.Dir(file.RelPath)
+	l.written[dir]++
+	if l.written[dir] != l.max {
+		return 0
+	}
+
+	return l.left[dir]
+}
+
+// shouldSkipDirLimit reports whether MaxFilesPerDir drops this file because
+// its directory is full.
+func (a *App) shouldSkipDirLimit(limit *dirLimit, file scanner.FileInfo) bool {
+	if !limit.full(file) {
+		return false
+	}
+
+	if a.cfg.Debug {
+		fmt.Fprintf(a.warn, "Debug: Skipping file over the per-directory limit: %s\n", file.RelPath)
+	}
+	a.stats.SkippedDirLimit++
+	a.omit(file, OmitReasonDirLimit)
+
+	return true
+}
+
+// overDirLimit returns the paths of the files past the first max of their
+// directory, in scan order. Unlike a run, it cannot know which files content
+// filters drop, so it may mark files a run would write.
+func overDirLimit(files []scanner.FileInfo, limit int) map[string]bool {
+	over := make(map[string]bool)
+	if limit <= 0 {
+		return over
+	}
+
+	seen := make(map[string]int)
+	for _, file := range files {
+		if file.IsDir {
+			continue
+		}
+		dir := filepath.Dir(file.RelPath)
+		seen[dir]++
+		if seen[dir] > limit {
+			over[file.Path] = true
+		}
+	}
+
+	return over
+}
+
+// dirLimitNote describes the files MaxFilesPerDir left out after file, or is
+// empty when there are none.
+func dirLimitNote(file *ProcessedFile) string {
+	if file.DirOmitted == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("… %d more files in %s omitted", file.DirOmitted, dirLimitDir(file))
+}
+
+// dirLimitDir names the directory of file with a trailing separator, as
+// "./" for the top level.
+func dirLimitDir(file *ProcessedFile) string {
+	return filepath.Dir(file.Info.RelPath) + string(filepath.Separator)
+}
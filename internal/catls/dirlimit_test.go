@@ -0,0 +1,104 @@
+package catls
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+func TestMaxFilesPerDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"db/migrations/001.sql": "create table a;",
+		"db/migrations/002.sql": "create table b;",
+		"db/migrations/003.sql": "create table c;",
+		"db/migrations/004.sql": "create table d;",
+		"db/migrations/005.sql": "create table e;",
+		"db/schema.sql":         "-- schema",
+		"db/seed.sql":           "-- seed",
+	})
+
+	tests := []struct {
+		format OutputFormat
+		want   []string
+		absent []string
+	}{
+		{
+			format: OutputFormatXML,
+			want:   []string{`<file path="db/migrations/002.sql"`, `<omitted dir="db/migrations/" files="3"/>`, `<file path="db/seed.sql"`},
+			absent: []string{"db/migrations/003.sql", `<omitted dir="db/"`},
+		},
+		{
+			format: OutputFormatJSON,
+			want:   []string{`"omittedDirs": [`, `"dir": "db/migrations/"`, `"omitted": 3`},
+			absent: []string{"db/migrations/004.sql"},
+		},
+		{
+			format: OutputFormatMarkdown,
+			want:   []string{"*… 3 more files in db/migrations/ omitted*"},
+			absent: []string{"db/migrations/005.sql"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var buf bytes.Buffer
+			app, err := New(&Config{
+				Directory:      tmpDir,
+				Recursive:      true,
+				OutputFormat:   tt.format,
+				Output:         &buf,
+				MaxFilesPerDir: 2,
+			})
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+			if err := app.Run(context.Background()); err != nil {
+				t.Fatalf("Run() unexpected error: %v", err)
+			}
+			got := buf.String()
+
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("output missing %q:\n%s", want, got)
+				}
+			}
+			for _, absent := range tt.absent {
+				if strings.Contains(got, absent) {
+					t.Errorf("output should not contain %q:\n%s", absent, got)
+				}
+			}
+			if skipped := app.Stats().SkippedDirLimit; skipped != 3 {
+				t.Errorf("SkippedDirLimit = %d, want 3", skipped)
+			}
+		})
+	}
+}
+
+func TestOverDirLimit(t *testing.T) {
+	files := []scanner.FileInfo{
+		{Path: "/r/a.go", RelPath: "a.go"},
+		{Path: "/r/sub", RelPath: "sub", IsDir: true},
+		{Path: "/r/b.go", RelPath: "b.go"},
+		{Path: "/r/sub/c.go", RelPath: filepath.Join("sub", "c.go")},
+		{Path: "/r/sub/d.go", RelPath: filepath.Join("sub", "d.go")},
+	}
+
+	over := overDirLimit(files, 1)
+	var got []string
+	for _, file := range files {
+		if over[file.Path] {
+			got = append(got, file.Path)
+		}
+	}
+	if strings.Join(got, " ") != "/r/b.go /r/sub/d.go" {
+		t.Errorf("overDirLimit() marked %v, want [/r/b.go /r/sub/d.go]", got)
+	}
+	if len(overDirLimit(files, 0)) != 0 {
+		t.Error("overDirLimit() with no limit should mark nothing")
+	}
+}
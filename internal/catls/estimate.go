@@ -0,0 +1,169 @@
+package catls
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// estimateLargestFiles is how many of the biggest files an estimate lists.
+	estimateLargestFiles = 10
+	// bytesPerToken is the rough ratio used to estimate tokens from file sizes.
+	bytesPerToken = 4
+)
+
+// EstimateReport sizes a run from file metadata alone.
+type EstimateReport struct {
+	Files      int              `json:"files"`
+	Bytes      int64            `json:"bytes"`
+	Tokens     int64            `json:"estimatedTokens"`
+	Largest    []EstimateFile   `json:"largest"`
+	Extensions []ExtensionBytes `json:"extensions"`
+}
+
+// EstimateFile is a single file in an EstimateReport.
+type EstimateFile struct {
+	Path  string `json:"path"`
+	Bytes int64  `json:"bytes"`
+}
+
+// ExtensionBytes aggregates the files sharing one extension.
+type ExtensionBytes struct {
+	Extension string `json:"extension"` // Lowercased, with leading dot; empty for no extension
+	Files     int    `json:"files"`
+	Bytes     int64  `json:"bytes"`
+}
+
+// Estimate scans and filters like Run but only stats files, so it reports what
+// a run would cover without reading any content. Binary detection, which reads
+// files, only runs when OmitBins needs it.
+func (a *App) Estimate(ctx context.Context) (EstimateReport, error) {
+	files, _, err := a.scanFiles(ctx, !a.cfg.OmitBins)
+	if err != nil {
+		return EstimateReport{}, err
+	}
+
+	report := EstimateReport{
+		Largest:    []EstimateFile{},
+		Extensions: []ExtensionBytes{},
+	}
+	byExt := make(map[string]*ExtensionBytes)
+
+	for _, file := range files {
+		if file.IsDir {
+			continue
+		}
+
+		report.Files++
+		report.Bytes += file.Size
+		report.Largest = append(report.Largest, EstimateFile{Path: file.RelPath, Bytes: file.Size})
+
+		ext := strings.ToLower(filepath.Ext(file.RelPath))
+		if byExt[ext] == nil {
+			byExt[ext] = &ExtensionBytes{Extension: ext}
+		}
+		byExt[ext].Files++
+		byExt[ext].Bytes += file.Size
+	}
+
+	report.Tokens = report.Bytes / bytesPerToken
+
+	sort.SliceStable(report.Largest, func(i, j int) bool {
+		return report.Largest[i].Bytes > report.Largest[j].Bytes
+	})
+	if len(report.Largest) > estimateLargestFiles {
+		report.Largest = report.Largest[:estimateLargestFiles]
+	}
+
+	for _, ext := range byExt {
+		report.Extensions = append(report.Extensions, *ext)
+	}
+	sort.Slice(report.Extensions, func(i, j int) bool {
+		if report.Extensions[i].Bytes != report.Extensions[j].Bytes {
+			return report.Extensions[i].Bytes > report.Extensions[j].Bytes
+		}
+
+		return report.Extensions[i].Extension < report.Extensions[j].Extension
+	})
+
+	return report, nil
+}
+
+// WriteEstimate renders report in the given output format.
+func WriteEstimate(w io.Writer, format OutputFormat, report EstimateReport) error {
+	var b strings.Builder
+
+	switch format {
+	case OutputFormatXML:
+		writeEstimateXML(&b, report)
+	case OutputFormatJSON, OutputFormatChunks:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(report)
+	case OutputFormatMarkdown, OutputFormatPrompt, OutputFormatPretty:
+		writeEstimateMarkdown(&b, report)
+	default:
+		return fmt.Errorf("estimate does not support output format: %s", format)
+	}
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+func writeEstimateXML(b *strings.Builder, report EstimateReport) {
+	b.WriteString("<estimate>\n")
+	fmt.Fprintf(b, "<files>%d</files>\n", report.Files)
+	fmt.Fprintf(b, "<bytes>%d</bytes>\n", report.Bytes)
+	fmt.Fprintf(b, "<estimatedTokens>%d</estimatedTokens>\n", report.Tokens)
+
+	b.WriteString("<largest>\n")
+	for _, file := range report.Largest {
+		fmt.Fprintf(b, "<file path=\"%s\" bytes=\"%d\"/>\n", html.EscapeString(file.Path), file.Bytes)
+	}
+	b.WriteString("</largest>\n")
+
+	b.WriteString("<extensions>\n")
+	for _, ext := range report.Extensions {
+		fmt.Fprintf(b, "<extension name=\"%s\" files=\"%d\" bytes=\"%d\"/>\n",
+			html.EscapeString(ext.Extension), ext.Files, ext.Bytes)
+	}
+	b.WriteString("</extensions>\n")
+	b.WriteString("</estimate>\n")
+}
+
+// tableCellReplacer escapes the pipes of a Markdown table cell, which would
+// otherwise end the cell.
+var tableCellReplacer = strings.NewReplacer("|", `\|`)
+
+func writeEstimateMarkdown(b *strings.Builder, report EstimateReport) {
+	b.WriteString("# Estimate\n\n")
+	fmt.Fprintf(b, "- Files: %d\n", report.Files)
+	fmt.Fprintf(b, "- Bytes: %d\n", report.Bytes)
+	fmt.Fprintf(b, "- Estimated tokens: %d\n", report.Tokens)
+
+	if len(report.Largest) > 0 {
+		b.WriteString("\n## Largest files\n\n| Path | Bytes |\n| --- | ---: |\n")
+		for _, file := range report.Largest {
+			fmt.Fprintf(b, "| %s | %d |\n", tableCellReplacer.Replace(file.Path), file.Bytes)
+		}
+	}
+
+	if len(report.Extensions) > 0 {
+		b.WriteString("\n## Bytes by extension\n\n| Extension | Files | Bytes |\n| --- | ---: | ---: |\n")
+		for _, ext := range report.Extensions {
+			name := ext.Extension
+			if name == "" {
+				name = "(none)"
+			}
+			fmt.Fprintf(b, "| %s | %d | %d |\n", tableCellReplacer.Replace(name), ext.Files, ext.Bytes)
+		}
+	}
+}
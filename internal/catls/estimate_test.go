@@ -0,0 +1,94 @@
+package catls
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestEstimate(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"big.go":         strings.Repeat("x", 4000),
+		"small.go":       "package small",
+		"notes.md":       strings.Repeat("n", 100),
+		"Makefile":       "all:",
+		"skip/ignore.go": "package skip",
+	})
+
+	app, err := New(&Config{
+		Directory:    tmpDir,
+		RelativeTo:   tmpDir,
+		Recursive:    true,
+		IgnoreDir:    []string{"skip"},
+		OutputFormat: OutputFormatJSON,
+	})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	report, err := app.Estimate(context.Background())
+	if err != nil {
+		t.Fatalf("Estimate() unexpected error: %v", err)
+	}
+
+	if report.Files != 4 || report.Bytes != 4117 || report.Tokens != 1029 {
+		t.Errorf("Estimate() = %d files, %d bytes, %d tokens; want 4, 4117, 1029", report.Files, report.Bytes, report.Tokens)
+	}
+	if report.Largest[0].Path != "big.go" {
+		t.Errorf("largest file = %s, want big.go", report.Largest[0].Path)
+	}
+
+	exts := make(map[string]ExtensionBytes)
+	for _, ext := range report.Extensions {
+		exts[ext.Extension] = ext
+	}
+	if exts[".go"].Files != 2 || exts[".go"].Bytes != 4013 || exts[""].Files != 1 {
+		t.Errorf("extensions = %+v", report.Extensions)
+	}
+
+	for _, format := range GetSupportedFormats() {
+		var buf bytes.Buffer
+		if err := WriteEstimate(&buf, OutputFormat(format), report); err != nil {
+			t.Fatalf("WriteEstimate(%s) unexpected error: %v", format, err)
+		}
+
+		switch OutputFormat(format) {
+		case OutputFormatJSON:
+			var decoded EstimateReport
+			if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || decoded.Bytes != report.Bytes {
+				t.Errorf("json estimate does not round-trip: %v\n%s", err, buf.String())
+			}
+		case OutputFormatXML:
+			if err := xml.Unmarshal(buf.Bytes(), new(struct{})); err != nil {
+				t.Errorf("xml estimate does not parse: %v\n%s", err, buf.String())
+			}
+		case OutputFormatMarkdown:
+			if !strings.Contains(buf.String(), "| big.go | 4000 |") {
+				t.Errorf("markdown estimate missing largest file row:\n%s", buf.String())
+			}
+		}
+	}
+}
+
+func TestWriteEstimateMarkdownEscapesPipes(t *testing.T) {
+	report := EstimateReport{
+		Files:      1,
+		Bytes:      10,
+		Largest:    []EstimateFile{{Path: "a|b.go", Bytes: 10}},
+		Extensions: []ExtensionBytes{{Extension: ".x|y", Files: 1, Bytes: 10}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteEstimate(&buf, OutputFormatMarkdown, report); err != nil {
+		t.Fatalf("WriteEstimate() unexpected error: %v", err)
+	}
+	for _, row := range []string{`| a\|b.go | 10 |`, `| .x\|y | 1 | 10 |`} {
+		if !strings.Contains(buf.String(), row) {
+			t.Errorf("markdown estimate missing row %q:\n%s", row, buf.String())
+		}
+	}
+}
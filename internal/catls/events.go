@@ -0,0 +1,243 @@
+package catls
+
+import (
+	"time"
+
+	"github.com/connerohnesorge/catls/internal/profile"
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+// Events receives structured progress from Run and Files, for editors and
+// wrapper tools that embed catls. Every callback is optional, and a nil
+// *Events in Config costs only a nil check. Callbacks run on the goroutine
+// driving the run, between files, so a slow callback slows the run.
+type Events struct {
+	OnScanStart      func(ScanStartEvent)
+	OnFileDiscovered func(FileDiscoveredEvent)
+	OnFileProcessed  func(FileProcessedEvent)
+	OnStageTimed     func(StageTimedEvent)
+	OnRunComplete    func(RunCompleteEvent)
+}
+
+// ScanStartEvent is sent before the directory is scanned.
+type ScanStartEvent struct {
+	Directory string
+	Recursive bool
+}
+
+// FileDiscoveredEvent is sent while scanning, for each file that passes the
+// scan's filters, in the order files are found.
+type FileDiscoveredEvent struct {
+	File scanner.FileInfo
+}
+
+// FileOutcome tells what became of a file offered for processing.
+type FileOutcome string
+
+// Outcomes of FileProcessedEvent.
+const (
+	OutcomeWritten   FileOutcome = "written"   // Handed to the formatter, possibly as binary, empty, or unreadable
+	OutcomeDuplicate FileOutcome = "duplicate" // Written as a reference to identical content, with DedupeContent
+	OutcomeDirectory FileOutcome = "directory" // A directory record, with IncludeDirs
+	OutcomeOmitted   FileOutcome = "omitted"   // Left out for Reason
+)
+
+// FileProcessedEvent is sent once for every file selected for output, after
+// processing and before the file is written, including files that could not
+// be read. Index and Total place the file among the selected files, so
+// Index+1 of Total files are done. With List, files are listed without
+// being read, so a listed file is OutcomeWritten with no Err or Empty.
+type FileProcessedEvent struct {
+	File        scanner.FileInfo // The file as processed
+	Index       int
+	Total       int
+	Outcome     FileOutcome
+	Reason      string        // Why the file was omitted, one of the OmitReason values
+	Err         error         // Why a written file could not be read
+	ErrCategory ErrorCategory // The category of Err
+	Empty       bool          // The written file is empty or whitespace-only
+	DuplicateOf string        // RelPath of the identical file, with OutcomeDuplicate
+	Duration    time.Duration // Time spent processing the file
+}
+
+// StageTimedEvent is sent each time a stage of the pipeline finishes with a
+// file. Profile and ProfileJSON are built from these events.
+type StageTimedEvent struct {
+	Path     string // RelPath of the file
+	Stage    profile.Stage
+	Duration time.Duration
+}
+
+// RunCompleteEvent is sent when Run returns or Files finishes iterating,
+// whether or not it succeeded.
+type RunCompleteEvent struct {
+	Stats    RunStats
+	Duration time.Duration // Time since ScanStartEvent
+	Err      error         // The error Run returned or Files yielded, if any
+}
+
+// Record counts event in the stats, so a consumer of Events can keep the
+// counters App.Stats reports without parsing output.
+func (s *RunStats) Record(event FileProcessedEvent) {
+	switch event.Outcome {
+	case OutcomeDirectory:
+		s.Dirs++
+
+		return
+	case OutcomeOmitted:
+		s.recordOmitted(event.Reason)
+
+		return
+	case OutcomeDuplicate:
+		s.Duplicates++
+		s.DuplicateBytes += event.File.Size
+	}
+
+	s.Files++
+	switch {
+	case event.Err != nil:
+		s.recordError(event.ErrCategory)
+	case event.File.IsBinary:
+		s.Binary++
+	case event.Empty:
+		s.Empty++
+	}
+}
+
+// recordError counts a written file that could not be read for category.
+func (s *RunStats) recordError(category ErrorCategory) {
+	s.Errors++
+	s.ErrorsByCategory.add(category)
+}
+
+// recordOmitted counts a file left out for reason.
+func (s *RunStats) recordOmitted(reason string) {
+	switch reason {
+	case OmitReasonEmpty:
+		s.SkippedEmpty++
+	case OmitReasonNoTodos:
+		s.SkippedTodos++
+	case OmitReasonPattern:
+		s.SkippedMatch++
+	case OmitReasonNoFrontMatter:
+		s.SkippedFrontMatter++
+	case OmitReasonDirLimit:
+		s.SkippedDirLimit++
+	case OmitReasonBudget:
+		s.SkippedBudget++
+	case OmitReasonUnchanged:
+		s.SkippedUnchanged++
+	}
+}
+
+// fileProgress tracks the file being processed, for FileProcessedEvent.
+type fileProgress struct {
+	index int
+	total int
+	start time.Time
+}
+
+// startEvents sends ScanStartEvent and returns the function that sends
+// RunCompleteEvent with the run's error.
+func (a *App) startEvents() func(err error) {
+	events := a.events
+	if events == nil {
+		return func(error) {}
+	}
+
+	start := time.Now()
+	if events.OnScanStart != nil {
+		events.OnScanStart(ScanStartEvent{Directory: a.cfg.Directory, Recursive: a.cfg.Recursive})
+	}
+
+	return func(err error) {
+		if events.OnRunComplete != nil {
+			events.OnRunComplete(RunCompleteEvent{Stats: a.stats, Duration: time.Since(start), Err: err})
+		}
+	}
+}
+
+// fileDiscovered sends FileDiscoveredEvent.
+func (a *App) fileDiscovered(file scanner.FileInfo) {
+	if events := a.events; events != nil && events.OnFileDiscovered != nil {
+		events.OnFileDiscovered(FileDiscoveredEvent{File: file})
+	}
+}
+
+// startFile begins timing the index-th of total files.
+func (a *App) startFile(index, total int) {
+	if a.events == nil {
+		return
+	}
+	a.progress = fileProgress{index: index, total: total, start: time.Now()}
+}
+
+// fileProcessed sends FileProcessedEvent for the file started last.
+// processed is nil for omitted files.
+func (a *App) fileProcessed(file scanner.FileInfo, processed *ProcessedFile, reason string) {
+	events := a.events
+	if events == nil || events.OnFileProcessed == nil {
+		return
+	}
+
+	event := FileProcessedEvent{
+		File:     file,
+		Index:    a.progress.index,
+		Total:    a.progress.total,
+		Outcome:  OutcomeOmitted,
+		Reason:   reason,
+		Duration: time.Since(a.progress.start),
+	}
+	if processed != nil {
+		event.File = processed.Info
+		event.Err, event.ErrCategory = processed.Error, processed.ErrorCategory
+		event.Empty = processed.IsEmpty
+		event.DuplicateOf = processed.DuplicateOf
+		switch {
+		case processed.Info.IsDir:
+			event.Outcome = OutcomeDirectory
+		case processed.DuplicateOf != "":
+			event.Outcome = OutcomeDuplicate
+		default:
+			event.Outcome = OutcomeWritten
+		}
+	}
+	events.OnFileProcessed(event)
+}
+
+// profileEvents returns Events with the collector of Profile and ProfileJSON
+// subscribed to stage timings ahead of any OnStageTimed callback, and the
+// collector, or Events and nil when not profiling.
+func profileEvents(cfg *Config) (*Events, *profile.Collector) {
+	if !cfg.Profile && cfg.ProfileJSON == "" {
+		return cfg.Events, nil
+	}
+
+	var events Events
+	if cfg.Events != nil {
+		events = *cfg.Events
+	}
+	collector := profile.New()
+	next := events.OnStageTimed
+	events.OnStageTimed = func(event StageTimedEvent) {
+		collector.Record(profile.Timing(event))
+		if next != nil {
+			next(event)
+		}
+	}
+
+	return &events, collector
+}
+
+// stageTimer returns the collector the pipeline times its stages with,
+// which sends each timing to OnStageTimed, or nil when nothing receives
+// them.
+func stageTimer(events *Events) *profile.Collector {
+	if events == nil || events.OnStageTimed == nil {
+		return nil
+	}
+
+	return profile.NewObserver(func(timing profile.Timing) {
+		events.OnStageTimed(StageTimedEvent(timing))
+	})
+}
@@ -0,0 +1,201 @@
+package catls
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/connerohnesorge/catls/internal/profile"
+)
+
+func TestEventsReconstructStats(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"a.go":           "package a",
+		"copy.go":        "package a",
+		"blank.txt":      "  \n",
+		"sub/deep/f.bin": "\x00\x01\x02",
+		"sub/b.go":       "package sub",
+		"sub/c.go":       "package sub // c",
+		"sub/d.go":       "package sub // d",
+		"sub/deep/e.go":  "package deep",
+	})
+
+	var (
+		scans      int
+		discovered int
+		processed  []FileProcessedEvent
+		complete   []RunCompleteEvent
+		recorded   RunStats
+	)
+	events := &Events{
+		OnScanStart:      func(ScanStartEvent) { scans++ },
+		OnFileDiscovered: func(FileDiscoveredEvent) { discovered++ },
+		OnFileProcessed: func(event FileProcessedEvent) {
+			processed = append(processed, event)
+			recorded.Record(event)
+		},
+		OnRunComplete: func(event RunCompleteEvent) { complete = append(complete, event) },
+	}
+
+	app, err := New(&Config{
+		Directory:      tmpDir,
+		Recursive:      true,
+		IncludeDirs:    true,
+		SkipEmpty:      true,
+		DedupeContent:  true,
+		MaxFilesPerDir: 2,
+		OutputFormat:   OutputFormatXML,
+		Output:         io.Discard,
+		Events:         events,
+	})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	if scans != 1 || len(complete) != 1 {
+		t.Fatalf("got %d scan starts and %d completions, want 1 each", scans, len(complete))
+	}
+	if discovered == 0 {
+		t.Error("no files discovered")
+	}
+	for i, event := range processed {
+		if event.Index != i || event.Total != len(processed) {
+			t.Errorf("event %d for %s has Index %d of %d", i, event.File.RelPath, event.Index, event.Total)
+		}
+	}
+	if stats := app.Stats(); !reflect.DeepEqual(recorded, stats) || !reflect.DeepEqual(complete[0].Stats, stats) {
+		t.Errorf("recorded stats %+v, completion %+v, want %+v", recorded, complete[0].Stats, stats)
+	}
+	if recorded.Duplicates != 1 || recorded.SkippedEmpty != 1 || recorded.SkippedDirLimit != 1 || recorded.Binary != 1 || recorded.Dirs != 2 {
+		t.Errorf("stats %+v do not cover every outcome", recorded)
+	}
+}
+
+func TestEventsRunError(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{"a.go": "package a"})
+
+	var complete []RunCompleteEvent
+	app, err := New(&Config{
+		Directory:    tmpDir,
+		OutputFormat: OutputFormatXML,
+		Output:       io.Discard,
+		Events:       &Events{OnRunComplete: func(event RunCompleteEvent) { complete = append(complete, event) }},
+	})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, err := range app.Files(ctx) {
+		if err == nil {
+			t.Fatal("Files() yielded a file after cancellation")
+		}
+	}
+
+	if len(complete) != 1 || !errors.Is(complete[0].Err, context.Canceled) {
+		t.Errorf("completions %+v, want one with context.Canceled", complete)
+	}
+}
+
+func TestEventsList(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"a.go":      "package a",
+		"blank.txt": "",
+		"sub/b.go":  "package sub",
+	})
+
+	var processed []FileProcessedEvent
+	var recorded RunStats
+	app, err := New(&Config{
+		Directory:    tmpDir,
+		Recursive:    true,
+		IncludeDirs:  true,
+		SkipEmpty:    true,
+		List:         true,
+		OutputFormat: OutputFormatXML,
+		Output:       io.Discard,
+		Events: &Events{OnFileProcessed: func(event FileProcessedEvent) {
+			processed = append(processed, event)
+			recorded.Record(event)
+		}},
+	})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	if len(processed) != 4 {
+		t.Fatalf("got %d processed events, want one for each of the 4 entries", len(processed))
+	}
+	for i, event := range processed {
+		if event.Index != i || event.Total != len(processed) {
+			t.Errorf("event %d for %s has Index %d of %d", i, event.File.RelPath, event.Index, event.Total)
+		}
+	}
+	if stats := app.Stats(); !reflect.DeepEqual(recorded, stats) {
+		t.Errorf("recorded stats %+v, want %+v", recorded, stats)
+	}
+}
+
+func TestEventsStageTimed(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{"a.go": "package a", "b.go": "package b"})
+	profilePath := filepath.Join(t.TempDir(), "profile.json")
+
+	for _, profiling := range []bool{false, true} {
+		var timed []StageTimedEvent
+		cfg := &Config{
+			Directory:    tmpDir,
+			OutputFormat: OutputFormatXML,
+			Output:       io.Discard,
+			Events:       &Events{OnStageTimed: func(event StageTimedEvent) { timed = append(timed, event) }},
+		}
+		if profiling {
+			cfg.ProfileJSON = profilePath
+		}
+		app, err := New(cfg)
+		if err != nil {
+			t.Fatalf("New() unexpected error: %v", err)
+		}
+		if err := app.Run(context.Background()); err != nil {
+			t.Fatalf("Run() unexpected error: %v", err)
+		}
+
+		stages := make(map[profile.Stage]int)
+		for _, event := range timed {
+			stages[event.Stage]++
+		}
+		if stages[profile.StageRead] != 2 || stages[profile.StageFormat] != 2 {
+			t.Errorf("profiling %v: timed stages %v, want read and format for both files", profiling, stages)
+		}
+		if !profiling {
+			continue
+		}
+
+		data, err := os.ReadFile(profilePath)
+		if err != nil {
+			t.Fatalf("failed to read the profile: %v", err)
+		}
+		var written []profile.Timing
+		if err := json.Unmarshal(data, &written); err != nil {
+			t.Fatalf("profile is not JSON: %v", err)
+		}
+		if len(written) != len(timed) {
+			t.Errorf("profile has %d timings, want the %d sent as events", len(written), len(timed))
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package catls
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+// Explanation is the verdict of every rule a run applies to one file, in the
+// order the run applies them.
+type Explanation struct {
+	Path     string            // Path relative to the scanned directory, as in output
+	Verdicts []scanner.Verdict // Every rule, including those after the deciding one
+}
+
+// Decision returns the verdict that decides the file, the first exclusion,
+// and whether there is one.
+func (e Explanation) Decision() (scanner.Verdict, bool) {
+	return scanner.FirstExclusion(e.Verdicts)
+}
+
+// Explain reports why Run would or would not output the file at path, checking
+// the rules of the scanner, the file filter, and content filtering in turn. A
+// relative path is resolved against the working directory, or else against
+// Directory. Interactive selection is not simulated, and the token budget
+// and the per-directory limit, which depend on the files before this one,
+// are not checked.
+func (a *App) Explain(path string) (Explanation, error) {
+	if err := a.validateConfig(); err != nil {
+		return Explanation{}, err
+	}
+	a.addFilesToGlobs()
+
+	fullPath, err := a.explainPath(path)
+	if err != nil {
+		return Explanation{}, err
+	}
+
+	file, verdicts, err := a.scanner.Explain(a.scanConfig(false), fullPath, scanner.WithTypeDetector(a.processor.detectType))
+	if err != nil {
+		return Explanation{}, fmt.Errorf("cannot explain %s: %w", path, err)
+	}
+	if a.cfg.GitIgnore && len(a.cfg.Paths) == 0 {
+		verdict, err := a.gitIgnoreVerdict(file)
+		if err != nil {
+			return Explanation{}, err
+		}
+		verdicts = append(verdicts, verdict)
+	}
+	verdicts = append(verdicts, a.filter.Verdicts(file, a.cfg)...)
+	verdicts = append(verdicts, a.emptyVerdict(file))
+	if len(a.cfg.Allowlist) > 0 {
+		verdicts = append(verdicts, a.allowlistVerdict(file))
+	}
+
+	if err := a.resolveBlame(context.Background()); err != nil {
+		return Explanation{}, err
+	}
+
+	if len(a.cfg.ContentPatterns) > 0 || a.cfg.Todos || a.cfg.FrontMatterOnly || a.blame != nil {
+		processed := a.processor.ProcessFile(file, a.filter)
+		if a.cfg.FrontMatterOnly {
+			verdicts = append(verdicts, a.frontMatterVerdict(&processed))
+		}
+		if len(a.cfg.ContentPatterns) > 0 {
+			verdicts = append(verdicts, a.patternVerdict(&processed))
+		}
+		if a.cfg.Todos {
+			verdicts = append(verdicts, a.todosVerdict(&processed))
+		}
+		if a.blame != nil {
+			verdict, _ := a.blameVerdict(context.Background(), &processed)
+			verdicts = append(verdicts, verdict)
+		}
+	}
+
+	return Explanation{Path: file.RelPath, Verdicts: verdicts}, nil
+}
+
+// explainPath resolves path to the form Scan gives it: Directory joined with
+// the path below it.
+func (a *App) explainPath(path string) (string, error) {
+	if _, err := os.Stat(path); err != nil && !filepath.IsAbs(path) {
+		path = filepath.Join(a.cfg.Directory, path)
+	}
+
+	absDir, err := filepath.Abs(a.cfg.Directory)
+	if err != nil {
+		return "", err
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	rel, err := filepath.Rel(absDir, absPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is not under the scanned directory %s", path, a.cfg.Directory)
+	}
+
+	return filepath.Join(a.cfg.Directory, rel), nil
+}
+
+// WriteExplanation writes one line per verdict, marking exclusions, followed
+// by INCLUDED or EXCLUDED and the deciding rule.
+func WriteExplanation(w io.Writer, explanation Explanation) error {
+	width := 0
+	for _, verdict := range explanation.Verdicts {
+		width = max(width, len(verdict.Rule))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", explanation.Path)
+	for _, verdict := range explanation.Verdicts {
+		mark := "pass"
+		if verdict.Excluded {
+			mark = "FAIL"
+		}
+		line := fmt.Sprintf("  %s  %-*s  %s", mark, width, verdict.Rule, verdict.Detail)
+		b.WriteString(strings.TrimRight(line, " ") + "\n")
+	}
+
+	if decision, excluded := explanation.Decision(); excluded {
+		fmt.Fprintf(&b, "EXCLUDED by %s\n", decision)
+	} else {
+		b.WriteString("INCLUDED\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
@@ -0,0 +1,136 @@
+package catls
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+)
+
+// ErrorCategory classifies why a file could not be read, so tools consuming
+// the output can tell a permission problem from a file that vanished.
+type ErrorCategory string
+
+const (
+	// ErrPermission means the file could not be opened or read for lack of
+	// permission.
+	ErrPermission ErrorCategory = "permission"
+	// ErrNotFound means the file was removed between the scan and the read.
+	ErrNotFound ErrorCategory = "not-found"
+	// ErrTooLarge means a line of the file is too long to read.
+	ErrTooLarge ErrorCategory = "too-large"
+	// ErrDecode means the content is not valid in the encoding it was
+	// detected as.
+	ErrDecode ErrorCategory = "decode"
+	// ErrRead covers every other failure to read a file.
+	ErrRead ErrorCategory = "read"
+)
+
+// errorCategories lists every ErrorCategory, in the order they are reported.
+var errorCategories = []ErrorCategory{ErrPermission, ErrNotFound, ErrTooLarge, ErrDecode, ErrRead}
+
+// ErrorCategories returns the name of every ErrorCategory.
+func ErrorCategories() []string {
+	names := make([]string, len(errorCategories))
+	for i, category := range errorCategories {
+		names[i] = string(category)
+	}
+
+	return names
+}
+
+// ErrUnreadable is returned when a file cannot be read for one of the
+// categories in FailFast.
+var ErrUnreadable = errors.New("file could not be read")
+
+// errDecode is returned when content does not decode from the encoding it
+// was detected as.
+var errDecode = errors.New("content is not valid text")
+
+// categorizeError returns the ErrorCategory of an error reading a file.
+func categorizeError(err error) ErrorCategory {
+	switch {
+	case errors.Is(err, fs.ErrPermission):
+		return ErrPermission
+	case errors.Is(err, fs.ErrNotExist):
+		return ErrNotFound
+	case errors.Is(err, bufio.ErrTooLong):
+		return ErrTooLarge
+	case errors.Is(err, errDecode):
+		return ErrDecode
+	default:
+		return ErrRead
+	}
+}
+
+// failFast returns ErrUnreadable when file could not be read for one of the
+// categories in FailFast.
+func (a *App) failFast(file *ProcessedFile) error {
+	if file.Error == nil || !slices.Contains(a.cfg.FailFast, file.ErrorCategory) {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s (%s): %v", ErrUnreadable, file.Info.RelPath, file.ErrorCategory, file.Error)
+}
+
+// errorCategoryNote returns the category of file's error as " (permission)",
+// for text formats, or an empty string when it has none.
+func errorCategoryNote(file *ProcessedFile) string {
+	if file.ErrorCategory == "" {
+		return ""
+	}
+
+	return " (" + string(file.ErrorCategory) + ")"
+}
+
+// validateFailFast requires FailFast to name known categories.
+func (c *Config) validateFailFast() error {
+	for _, category := range c.FailFast {
+		if !slices.Contains(errorCategories, category) {
+			return fmt.Errorf("invalid --fail-fast category %q (valid: %s)", category, strings.Join(ErrorCategories(), ", "))
+		}
+	}
+
+	return nil
+}
+
+// ErrorCounts counts unreadable files by ErrorCategory.
+type ErrorCounts struct {
+	Permission int
+	NotFound   int
+	TooLarge   int
+	Decode     int
+	Read       int
+}
+
+// add counts a file that could not be read for category. Files without a
+// category, such as those a TransformFunc marked, count as ErrRead.
+func (c *ErrorCounts) add(category ErrorCategory) {
+	switch category {
+	case ErrPermission:
+		c.Permission++
+	case ErrNotFound:
+		c.NotFound++
+	case ErrTooLarge:
+		c.TooLarge++
+	case ErrDecode:
+		c.Decode++
+	default:
+		c.Read++
+	}
+}
+
+// String formats the counts as "2 permission, 1 not-found", leaving out
+// categories without files.
+func (c ErrorCounts) String() string {
+	var parts []string
+	for i, n := range []int{c.Permission, c.NotFound, c.TooLarge, c.Decode, c.Read} {
+		if n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, errorCategories[i]))
+		}
+	}
+
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,101 @@
+package catls
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+func TestCategorizeError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want ErrorCategory
+	}{
+		{&fs.PathError{Op: "open", Path: "a.go", Err: fs.ErrPermission}, ErrPermission},
+		{&fs.PathError{Op: "open", Path: "a.go", Err: fs.ErrNotExist}, ErrNotFound},
+		{bufio.ErrTooLong, ErrTooLarge},
+		{fmt.Errorf("%w: invalid utf-16le", errDecode), ErrDecode},
+		{errors.New("input/output error"), ErrRead},
+	}
+
+	for _, tt := range tests {
+		if got := categorizeError(tt.err); got != tt.want {
+			t.Errorf("categorizeError(%v) = %s, want %s", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestFileErrorCategories(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"gone.go": "package gone",
+		"kept.go": "package kept",
+		// UTF-16LE with a byte order mark, whose sample decodes but whose
+		// content later holds a control character, is read as it is
+		"broken.ps1": "\xFF\xFE" + strings.Repeat("a\x00", 600) + "\x01\x00",
+	})
+	// Removing a file once the scan selected it stands in for a race with
+	// another process
+	vanish := func(file scanner.FileInfo) bool {
+		if file.RelPath == "gone.go" {
+			_ = os.Remove(file.Path)
+		}
+
+		return true
+	}
+	run := func(failFast ...ErrorCategory) (*App, *bytes.Buffer, error) {
+		t.Helper()
+		writeTree(t, tmpDir, map[string]string{"gone.go": "package gone"})
+		var buf bytes.Buffer
+		app, err := New(&Config{
+			Directory:    tmpDir,
+			OutputFormat: OutputFormatJSON,
+			Output:       &buf,
+			IncludeFunc:  vanish,
+			FailFast:     failFast,
+		})
+		if err != nil {
+			t.Fatalf("New() unexpected error: %v", err)
+		}
+
+		return app, &buf, app.Run(context.Background())
+	}
+
+	app, output, err := run(ErrPermission)
+	if err != nil {
+		t.Fatalf("Run() with FailFast on other categories: %v", err)
+	}
+	var doc struct{ Files []JSONFile }
+	if err := json.Unmarshal(output.Bytes(), &doc); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, output)
+	}
+	categories := make(map[string]string)
+	for _, file := range doc.Files {
+		categories[file.Path] = file.ErrorCategory
+	}
+	want := map[string]string{"broken.ps1": "", "gone.go": "not-found", "kept.go": ""}
+	for path, category := range want {
+		if categories[path] != category {
+			t.Errorf("%s errorCategory = %q, want %q", path, categories[path], category)
+		}
+	}
+	if stats := app.Stats(); stats.Errors != 1 || stats.ErrorsByCategory != (ErrorCounts{NotFound: 1}) {
+		t.Errorf("Stats() = %d errors, %+v; want 1, not-found", stats.Errors, stats.ErrorsByCategory)
+	}
+	if got := app.Stats().ErrorsByCategory.String(); got != "1 not-found" {
+		t.Errorf("ErrorsByCategory.String() = %q", got)
+	}
+
+	if _, _, err := run(ErrNotFound); !errors.Is(err, ErrUnreadable) || !strings.Contains(err.Error(), "gone.go") {
+		t.Errorf("Run() with FailFast not-found error = %v, want ErrUnreadable naming gone.go", err)
+	}
+}
@@ -0,0 +1,126 @@
+package catls
+
+import (
+	"io"
+	"slices"
+
+	"github.com/connerohnesorge/catls/internal/fdlimit"
+	"github.com/connerohnesorge/catls/internal/languages"
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+// TypeUnknown selects files without a detected type in Types and ExcludeTypes.
+const TypeUnknown = "unknown"
+
+// typeSniffLen is how many leading bytes are read to find a shebang, an SVG
+// root, or a catls:lang= directive.
+const typeSniffLen = 512
+
+// ContentTypeDetector detects file types by extension, then by well-known
+// file names, then by a shebang line or an SVG root element in the leading bytes.
+type ContentTypeDetector struct{}
+
+// DetectType implements TypeDetector.
+func (d *ContentTypeDetector) DetectType(filePath string) string {
+	fileType, _, _ := d.detectWithDirective(filePath, false)
+
+	return fileType
+}
+
+// detectWithDirective returns the type DetectType would and, with
+// directives, the type a catls:lang= directive in the first lines names,
+// which takes precedence. The leading bytes are read once for both. A
+// directive naming an unknown language is ignored, its name returned as
+// unknown for the caller to warn about.
+func (*ContentTypeDetector) detectWithDirective(filePath string, directives bool) (fileType, directive, unknown string) {
+	var head []byte
+	if directives {
+		head = readHead(filePath)
+		var name string
+		if directive, name = languages.DetectByDirective(head); directive == "" {
+			unknown = name
+		}
+	}
+
+	if fileType := languages.DetectByExtension(filePath); fileType != "" {
+		return fileType, directive, unknown
+	}
+
+	if fileType := languages.DetectByFilename(filePath); fileType != "" {
+		return fileType, directive, unknown
+	}
+
+	if !directives {
+		head = readHead(filePath)
+	}
+
+	return languages.DetectByContent(head), directive, unknown
+}
+
+// readHead returns the leading bytes of a file, or nil if it cannot be read.
+func readHead(filePath string) []byte {
+	file, err := fdlimit.Open(filePath)
+	if err != nil {
+		return nil
+	}
+	defer func() { _ = file.Close() }()
+
+	head := make([]byte, typeSniffLen)
+	n, err := io.ReadFull(file, head)
+	if err != nil && n == 0 {
+		return nil
+	}
+
+	return head[:n]
+}
+
+// KnownFileTypes returns the sorted file types TypeDetector can report, plus
+// TypeUnknown. Together with the LangMap types, these are the values accepted
+// by Types and ExcludeTypes.
+func KnownFileTypes() []string {
+	types := append(languages.Names(), TypeUnknown)
+	slices.Sort(types)
+
+	return types
+}
+
+// knownFileTypes returns KnownFileTypes plus the types LangMap introduces.
+func (c *Config) knownFileTypes() []string {
+	types := KnownFileTypes()
+	for _, fileType := range c.LangMap {
+		if !slices.Contains(types, fileType) {
+			types = append(types, fileType)
+		}
+	}
+	slices.Sort(types)
+
+	return types
+}
+
+// fileTypeName returns the name Types and ExcludeTypes use for file's type.
+func fileTypeName(file scanner.FileInfo) string {
+	if file.FileType == "" {
+		return TypeUnknown
+	}
+
+	return file.FileType
+}
+
+// filtersTypes reports whether Types or ExcludeTypes restrict the selection.
+func (c *Config) filtersTypes() bool {
+	return len(c.Types) > 0 || len(c.ExcludeTypes) > 0
+}
+
+// includesType reports whether file's type passes Types and ExcludeTypes.
+func (c *Config) includesType(file scanner.FileInfo) bool {
+	if !c.filtersTypes() {
+		return true
+	}
+
+	name := fileTypeName(file)
+	if slices.Contains(c.ExcludeTypes, name) {
+		return false
+	}
+
+	return len(c.Types) == 0 || slices.Contains(c.Types, name)
+}
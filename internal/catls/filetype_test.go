@@ -0,0 +1,241 @@
+package catls
+
+import (
+	"context"
+	"io"
+	"maps"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+func TestContentTypeDetector(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]struct {
+		content string
+		want    string
+	}{
+		"main.go":       {content: "#!/bin/sh\n", want: "go"},
+		"Dockerfile":    {content: "FROM alpine\n", want: "dockerfile"},
+		"GNUmakefile":   {content: "all:\n", want: "makefile"},
+		"deploy":        {content: "#!/bin/bash\nset -e\n", want: "bash"},
+		"install":       {content: "#!/bin/sh\n", want: "bash"},
+		"tool":          {content: "#!/usr/bin/env python3\nprint()\n", want: "python"},
+		"task":          {content: "#!/usr/bin/env -S FOO=1 node --no-warnings\n", want: "javascript"},
+		"legacy":        {content: "#!/usr/bin/perl5 -w\n", want: "perl"},
+		"logo":          {content: "<?xml version=\"1.0\"?>\n<svg/>\n", want: "xml"},
+		"notes":         {content: "just text\n", want: ""},
+		"odd-shebang":   {content: "#!/opt/bin/awk -f\n", want: ""},
+		"empty-shebang": {content: "#!\n", want: ""},
+	}
+	tree := make(map[string]string, len(files))
+	for name, file := range files {
+		tree[name] = file.content
+	}
+	writeTree(t, tmpDir, tree)
+
+	detector := &ContentTypeDetector{}
+	for name, file := range files {
+		t.Run(name, func(t *testing.T) {
+			if got := detector.DetectType(filepath.Join(tmpDir, name)); got != file.want {
+				t.Errorf("DetectType(%s) = %q, want %q", name, got, file.want)
+			}
+		})
+	}
+
+	if got := detector.DetectType(filepath.Join(tmpDir, "missing")); got != "" {
+		t.Errorf("DetectType(missing) = %q, want empty", got)
+	}
+}
+
+func TestKnownFileTypes(t *testing.T) {
+	types := KnownFileTypes()
+	for _, want := range []string{TypeUnknown, "go", "bash", "dockerfile"} {
+		if !slices.Contains(types, want) {
+			t.Errorf("KnownFileTypes() missing %q: %v", want, types)
+		}
+	}
+	if !slices.IsSorted(types) {
+		t.Errorf("KnownFileTypes() is not sorted: %v", types)
+	}
+}
+
+// countingTypeDetector records how often each path is detected.
+type countingTypeDetector struct {
+	ContentTypeDetector
+	calls map[string]int
+}
+
+func (d *countingTypeDetector) DetectType(filePath string) string {
+	d.calls[filepath.Base(filePath)]++
+
+	return d.ContentTypeDetector.DetectType(filePath)
+}
+
+func TestTypeFilters(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"main.go":    "package main\n",
+		"build.sh":   "echo build\n",
+		"deploy":     "#!/usr/bin/env bash\necho deploy\n",
+		"README":     "read me\n",
+		"Dockerfile": "FROM alpine\n",
+	})
+
+	tests := []struct {
+		name    string
+		types   []string
+		exclude []string
+		want    []string
+	}{
+		{name: "no filter", want: []string{"Dockerfile", "README", "build.sh", "deploy", "main.go"}},
+		{name: "shell scripts with and without extension", types: []string{"bash"}, want: []string{"build.sh", "deploy"}},
+		{name: "several types", types: []string{"bash", "go"}, want: []string{"build.sh", "deploy", "main.go"}},
+		{name: "unknown", types: []string{TypeUnknown}, want: []string{"README"}},
+		{name: "exclude", exclude: []string{"bash", TypeUnknown}, want: []string{"Dockerfile", "main.go"}},
+		{name: "exclude wins", types: []string{"bash"}, exclude: []string{"bash"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, err := New(&Config{
+				Directory:    tmpDir,
+				RelativeTo:   tmpDir,
+				OutputFormat: OutputFormatXML,
+				Types:        tt.types,
+				ExcludeTypes: tt.exclude,
+			})
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+			detector := &countingTypeDetector{calls: make(map[string]int)}
+			app.processor.typeDetector = detector
+
+			var paths []string
+			for file, err := range app.Files(context.Background()) {
+				if err != nil {
+					t.Fatalf("Files() unexpected error: %v", err)
+				}
+				paths = append(paths, file.Info.RelPath)
+				if !file.Info.HasType || file.FileType != file.Info.FileType {
+					t.Errorf("%s: FileType = %q, scanned type %q (HasType %v)",
+						file.Info.RelPath, file.FileType, file.Info.FileType, file.Info.HasType)
+				}
+			}
+
+			if !slices.Equal(paths, tt.want) {
+				t.Errorf("Files() yielded %v, want %v", paths, tt.want)
+			}
+			for path, calls := range detector.calls {
+				if calls != 1 {
+					t.Errorf("type of %s detected %d times, want 1", path, calls)
+				}
+			}
+		})
+	}
+}
+
+func TestLangMap(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"main.go":  "package main\n",
+		"page.tpl": "{{ .Title }}\n",
+	})
+	cachePath := filepath.Join(t.TempDir(), "cache.json")
+
+	types := func(langMap map[string]string) map[string]string {
+		t.Helper()
+
+		app, err := New(&Config{
+			Directory:       tmpDir,
+			RelativeTo:      tmpDir,
+			OutputFormat:    OutputFormatXML,
+			LangMap:         langMap,
+			DetectCachePath: cachePath,
+		})
+		if err != nil {
+			t.Fatalf("New() unexpected error: %v", err)
+		}
+
+		got := make(map[string]string)
+		for file, err := range app.Files(context.Background()) {
+			if err != nil {
+				t.Fatalf("Files() unexpected error: %v", err)
+			}
+			got[file.Info.RelPath] = file.FileType
+		}
+
+		return got
+	}
+
+	// The first run caches the built-in types; overrides must not be shadowed by them
+	if got := types(nil); got["main.go"] != "go" || got["page.tpl"] != "" {
+		t.Errorf("built-in types = %v", got)
+	}
+	if got := types(map[string]string{"go": "golang", "tpl": "gotmpl"}); got["main.go"] != "golang" || got["page.tpl"] != "gotmpl" {
+		t.Errorf("overridden types = %v", got)
+	}
+}
+
+func TestTypeDirectives(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"data.txt":    "// catls:lang=json\n{\"a\": 1}\n",
+		"header.inc":  "<?php\n/* CATLS:LANG=PHP */\necho 1;\n",
+		"page.tpl":    "<!-- catls:lang=html -->\n<p>hi</p>\n",
+		"late.txt":    "1\n2\n3\n# catls:lang=json\n",
+		"cobol.txt":   "* catls:lang=cobol\n",
+		"main.go":     "package main\n",
+		"script.bash": "# catls:lang=py\nprint()\n",
+	})
+
+	detect := func(cfg *Config) map[string]string {
+		t.Helper()
+		cfg.Directory, cfg.OutputFormat, cfg.Output = tmpDir, OutputFormatXML, io.Discard
+		app, err := New(cfg)
+		if err != nil {
+			t.Fatalf("New() unexpected error: %v", err)
+		}
+		types := make(map[string]string)
+		for file, err := range app.Files(context.Background()) {
+			if err != nil {
+				t.Fatalf("Files() unexpected error: %v", err)
+			}
+			types[file.Info.RelPath] = file.FileType
+		}
+
+		return types
+	}
+
+	cache := scanner.NewMemoryDetectionCache()
+	got := detect(&Config{LangMap: map[string]string{"tpl": "gotmpl"}, DetectCache: cache})
+	want := map[string]string{
+		"data.txt":    "json",
+		"header.inc":  "php",
+		"page.tpl":    "html", // The directive beats --lang-map
+		"late.txt":    "",
+		"cobol.txt":   "",
+		"main.go":     "go",
+		"script.bash": "python",
+	}
+	if !maps.Equal(got, want) {
+		t.Errorf("types = %v, want %v", got, want)
+	}
+
+	// Turning directives off is not undone by types cached with them on
+	got = detect(&Config{LangMap: map[string]string{"tpl": "gotmpl"}, DetectCache: cache, NoDirectives: true})
+	want = map[string]string{
+		"data.txt":    "",
+		"header.inc":  "",
+		"page.tpl":    "gotmpl",
+		"late.txt":    "",
+		"cobol.txt":   "",
+		"main.go":     "go",
+		"script.bash": "bash",
+	}
+	if !maps.Equal(got, want) {
+		t.Errorf("types with NoDirectives = %v, want %v", got, want)
+	}
+}
@@ -1,81 +1,392 @@
 package catls
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"os"
+	"path"
+	"path/filepath"
 	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/connerohnesorge/catls/internal/scanner"
 )
 
+// regexPatternPrefix marks a content pattern as a regular expression rather
+// than a glob.
+const regexPatternPrefix = "re:"
+
 // FileFilter handles file and content filtering.
 type FileFilter struct {
-	contentPattern *regexp.Regexp
+	contentPatterns []contentPattern
+	maxMatches      int // Matching lines kept per file (0 means no limit)
+	todoPattern     *regexp.Regexp
+	todoContext     int
+	pathRegex       []*regexp.Regexp // Compiled PathRegex
+	excludePathRe   []*regexp.Regexp // Compiled ExcludePathRegex
+	warn            io.Writer        // Receives debug messages, Config.Warnings
+}
+
+// contentPattern is a compiled --pattern value.
+type contentPattern struct {
+	raw     string
+	re      *regexp.Regexp
+	literal []byte // Text re matches exactly, which a substring search finds faster (nil when re is not literal)
 }
 
 // FilteredLine represents a line with its original line number.
 type FilteredLine struct {
 	LineNumber int
 	Content    string
+	Keyword    string      // Todo keyword annotating this line; empty for other lines
+	Patterns   []string    // Content patterns matching this line, as configured
+	Matches    []MatchSpan // Where content patterns matched Content, sorted and merged
+	Continued  bool        // Segment of a line folded by Fold, after its first; LineNumber is the line's
+	Gap        int         // Lines left out here by BlameSince; Content is a marker, LineNumber the first left out
 }
 
 // NewFileFilter creates a new file filter.
 func NewFileFilter(cfg *Config) *FileFilter {
-	filter := &FileFilter{}
+	filter := &FileFilter{warn: cfg.warnings()}
 
-	// Compile content pattern if provided
-	if cfg.ContentPattern != "" {
-		regexPattern := scanner.WildcardToRegex(cfg.ContentPattern)
-		if compiled, err := regexp.Compile(regexPattern); err == nil {
-			filter.contentPattern = compiled
+	// Compile content patterns once; Validate reports any that fail
+	for _, pattern := range cfg.ContentPatterns {
+		if compiled, err := compileContentPattern(pattern); err == nil {
+			filter.contentPatterns = append(filter.contentPatterns, newContentPattern(pattern, compiled))
 		}
 	}
+	filter.maxMatches = cfg.MaxMatches
+
+	if cfg.Todos {
+		if compiled, err := compileTodoPattern(cfg.todoKeywords()); err == nil {
+			filter.todoPattern = compiled
+			filter.todoContext = cfg.TodoContext
+		}
+	}
+
+	filter.pathRegex = compilePathRegexes(cfg.PathRegex)
+	filter.excludePathRe = compilePathRegexes(cfg.ExcludePathRegex)
 
 	return filter
 }
 
+// newContentPattern returns the compiled --pattern value pattern.
+func newContentPattern(pattern string, re *regexp.Regexp) contentPattern {
+	compiled := contentPattern{raw: pattern, re: re}
+	if literal, complete := re.LiteralPrefix(); complete && literal != "" {
+		compiled.literal = []byte(literal)
+	}
+
+	return compiled
+}
+
+// match reports whether the pattern matches line.
+func (p contentPattern) match(line []byte) bool {
+	if p.literal != nil {
+		return bytes.Contains(line, p.literal)
+	}
+
+	return p.re.Match(line)
+}
+
+// compilePathRegexes compiles path regular expressions, skipping any that
+// fail, which Validate reports.
+func compilePathRegexes(patterns []string) []*regexp.Regexp {
+	var compiled []*regexp.Regexp
+	for _, pattern := range patterns {
+		if re, err := regexp.Compile(pattern); err == nil {
+			compiled = append(compiled, re)
+		}
+	}
+
+	return compiled
+}
+
+// filtersContent reports whether FilterContent drops lines.
+func (f *FileFilter) filtersContent() bool {
+	return len(f.contentPatterns) > 0 || f.todoPattern != nil
+}
+
+// compileContentPattern compiles a --pattern value: a regular expression when
+// prefixed with "re:", otherwise a glob.
+func compileContentPattern(pattern string) (*regexp.Regexp, error) {
+	if expr, ok := strings.CutPrefix(pattern, regexPatternPrefix); ok {
+		return regexp.Compile(expr)
+	}
+
+	// Patterns are unanchored and lines hold no newlines, so leading and
+	// trailing stars change nothing but the speed: without them a literal
+	// glob is matched by a substring search
+	expr := scanner.WildcardToRegex(pattern)
+	for strings.HasPrefix(expr, ".*") {
+		expr = expr[2:]
+	}
+	for strings.HasSuffix(expr, ".*") {
+		expr = expr[:len(expr)-2]
+	}
+
+	return regexp.Compile(expr)
+}
+
+// fileRule decides one aspect of whether a file is output.
+type fileRule func(scanner.FileInfo, *Config) scanner.Verdict
+
+// statRules are the file rules decided from the path and stat alone. They
+// run first, and also before the scanner reads a file, so the files they
+// reject are never sniffed for binary content or type.
+var statRules = []fileRule{
+	executableVerdict,
+	defaultIgnoreGlobVerdict,
+	userIgnoreGlobVerdict,
+	includeGlobVerdict,
+}
+
+// ShouldReadFile reports whether a file passes the rules that need no
+// content, so the scanner can skip detection for it. See readRules.
+func (f *FileFilter) ShouldReadFile(file scanner.FileInfo, cfg *Config) bool {
+	return f.decide(file, cfg, f.readRules())
+}
+
 // ShouldIncludeFile determines if a file should be included in output.
-func (*FileFilter) ShouldIncludeFile(file scanner.FileInfo, cfg *Config) bool {
-	// Skip binary files if requested
-	if cfg.OmitBins && file.IsBinary {
-		if cfg.Debug {
-			fmt.Fprintf(os.Stderr, "Debug: Skipping binary file: %s\n", file.RelPath)
+func (f *FileFilter) ShouldIncludeFile(file scanner.FileInfo, cfg *Config) bool {
+	return f.decide(file, cfg, f.fileRules(cfg))
+}
+
+// decide applies rules to file, logging the first exclusion in debug mode.
+func (f *FileFilter) decide(file scanner.FileInfo, cfg *Config, rules []fileRule) bool {
+	_, excluded := f.exclusion(file, cfg, rules)
+
+	return !excluded
+}
+
+// exclusion applies rules to file and returns the first verdict excluding
+// it, if any, logging it in debug mode.
+func (f *FileFilter) exclusion(file scanner.FileInfo, cfg *Config, rules []fileRule) (scanner.Verdict, bool) {
+	verdict, excluded := scanner.FirstExclusion(applyRules(file, cfg, rules, false))
+	if excluded && cfg.Debug {
+		fmt.Fprintf(f.warn, "Debug: Skipping file: %s (%s)\n", file.RelPath, verdict)
+	}
+
+	return verdict, excluded
+}
+
+// Verdicts returns the verdict of every rule ShouldIncludeFile applies to
+// file, in order, without stopping at the first exclusion.
+func (f *FileFilter) Verdicts(file scanner.FileInfo, cfg *Config) []scanner.Verdict {
+	return applyRules(file, cfg, f.fileRules(cfg), true)
+}
+
+// readRules returns the rules decided before a file is read: statRules, then
+// the path regular expressions compiled into f.
+func (f *FileFilter) readRules() []fileRule {
+	return append(slices.Clone(statRules), f.excludePathRegexVerdict, f.pathRegexVerdict)
+}
+
+// fileRules returns every file rule in order: the read rules, then those
+// needing detection, Imports when set, then IncludeFunc when set.
+func (f *FileFilter) fileRules(cfg *Config) []fileRule {
+	rules := append(f.readRules(), binaryVerdict, typeVerdict)
+	if len(cfg.Imports) > 0 {
+		rules = append(rules, importsVerdict)
+	}
+	if cfg.IncludeFunc != nil {
+		rules = append(rules, includeFuncVerdict)
+	}
+
+	return rules
+}
+
+// applyRules applies rules in order. Unless all is set, it stops at the first
+// exclusion, which decides the file.
+func applyRules(file scanner.FileInfo, cfg *Config, rules []fileRule, all bool) []scanner.Verdict {
+	verdicts := make([]scanner.Verdict, 0, len(rules))
+	for _, rule := range rules {
+		verdict := rule(file, cfg)
+		verdicts = append(verdicts, verdict)
+		if verdict.Excluded && !all {
+			break
 		}
+	}
 
-		return false
+	return verdicts
+}
+
+// includeFuncVerdict applies IncludeFunc, which must be set. It runs after
+// every built-in rule, so the hook only sees files they keep.
+func includeFuncVerdict(file scanner.FileInfo, cfg *Config) scanner.Verdict {
+	verdict := scanner.Verdict{Rule: "include hook"}
+	if !cfg.IncludeFunc(file) {
+		verdict.Excluded, verdict.Detail = true, "rejected by IncludeFunc"
 	}
 
-	// Check ignore patterns first
-	allIgnoreGlobs := cfg.AllIgnoreGlobs()
-	for _, pattern := range allIgnoreGlobs {
-		if scanner.MatchesGlobPattern(file.RelPath, pattern) {
-			if cfg.Debug {
-				fmt.Fprintf(os.Stderr, "Debug: Ignoring file: %s\n", file.RelPath)
-			}
+	return verdict
+}
 
-			return false
+// binaryVerdict applies OmitBins.
+func binaryVerdict(file scanner.FileInfo, cfg *Config) scanner.Verdict {
+	verdict := scanner.Verdict{Rule: "binary policy", Detail: "text file"}
+	if file.IsBinary {
+		verdict.Excluded = cfg.OmitBins
+		verdict.Detail = "binary file, output as a placeholder"
+		if cfg.OmitBins {
+			verdict.Detail = "binary file with --omit-bins"
 		}
 	}
 
-	// Check include patterns
+	return verdict
+}
+
+// executableVerdict applies OnlyExecutable and NoExecutable.
+func executableVerdict(file scanner.FileInfo, cfg *Config) scanner.Verdict {
+	verdict := scanner.Verdict{Rule: "executable bit"}
+	switch {
+	case cfg.OnlyExecutable && !file.Executable:
+		verdict.Excluded, verdict.Detail = true, "not executable, with --only-executable"
+	case cfg.NoExecutable && file.Executable:
+		verdict.Excluded, verdict.Detail = true, "executable, with --no-executable"
+	}
+
+	return verdict
+}
+
+// typeVerdict applies Types and ExcludeTypes.
+func typeVerdict(file scanner.FileInfo, cfg *Config) scanner.Verdict {
+	verdict := scanner.Verdict{Rule: "type"}
+	if cfg.filtersTypes() {
+		verdict.Excluded = !cfg.includesType(file)
+		verdict.Detail = fileTypeName(file)
+	}
+
+	return verdict
+}
+
+// defaultIgnoreGlobVerdict checks file against the built-in ignore patterns,
+// which do not apply to files named in Paths.
+func defaultIgnoreGlobVerdict(file scanner.FileInfo, cfg *Config) scanner.Verdict {
+	if len(cfg.Paths) > 0 {
+		return scanner.Verdict{Rule: "default ignore glob", Detail: "named explicitly"}
+	}
+
+	verdict := ignoreGlobVerdict("default ignore glob", file, cfg.defaultIgnoreGlobs())
+	if sibling, ok := appleDoubleOf(file); ok && !verdict.Excluded {
+		verdict.Excluded = true
+		verdict.Detail = "AppleDouble file of " + sibling
+	}
+
+	return verdict
+}
+
+// appleDoubleOf returns the file that file, named "._" followed by the
+// sibling's name, holds the macOS resource fork and attributes of, if that
+// sibling exists. Such files appear on drives without native support for
+// them. A "._" file without a sibling is kept, since it is not one.
+func appleDoubleOf(file scanner.FileInfo) (string, bool) {
+	name, ok := strings.CutPrefix(filepath.Base(file.Path), "._")
+	if !ok || name == "" {
+		return "", false
+	}
+	if _, err := os.Lstat(filepath.Join(filepath.Dir(file.Path), name)); err != nil {
+		return "", false
+	}
+
+	return path.Join(path.Dir(filepath.ToSlash(file.RelPath)), name), true
+}
+
+// userIgnoreGlobVerdict checks file against IgnoreGlobs.
+func userIgnoreGlobVerdict(file scanner.FileInfo, cfg *Config) scanner.Verdict {
+	return ignoreGlobVerdict("user ignore glob", file, cfg.IgnoreGlobs)
+}
+
+// ignoreGlobVerdict excludes file when it matches any of patterns.
+func ignoreGlobVerdict(rule string, file scanner.FileInfo, patterns []string) scanner.Verdict {
+	if pattern, ok := scanner.MatchingGlob(file.RelPath, patterns); ok {
+		return scanner.Verdict{Rule: rule, Excluded: true, Detail: fmt.Sprintf("matches %q", pattern)}
+	}
+
+	return scanner.Verdict{Rule: rule}
+}
+
+// includeGlobVerdict excludes file unless it matches one of Globs, if any.
+func includeGlobVerdict(file scanner.FileInfo, cfg *Config) scanner.Verdict {
+	verdict := scanner.Verdict{Rule: "include glob"}
 	if len(cfg.Globs) == 0 {
-		return true // Include everything if no specific patterns
+		return verdict // Include everything if no specific patterns
 	}
 
-	for _, pattern := range cfg.Globs {
-		if scanner.MatchesGlobPattern(file.RelPath, pattern) {
-			return true
+	if pattern, ok := scanner.MatchingGlob(file.RelPath, cfg.Globs); ok {
+		verdict.Detail = fmt.Sprintf("matches %q", pattern)
+	} else {
+		verdict.Excluded = true
+		verdict.Detail = "matches none of " + quoteList(cfg.Globs)
+	}
+
+	return verdict
+}
+
+// excludePathRegexVerdict applies ExcludePathRegex to the relative path with
+// forward slashes.
+func (f *FileFilter) excludePathRegexVerdict(file scanner.FileInfo, _ *Config) scanner.Verdict {
+	verdict := scanner.Verdict{Rule: "exclude path regex"}
+	path := filepath.ToSlash(file.RelPath)
+	for _, re := range f.excludePathRe {
+		if re.MatchString(path) {
+			verdict.Excluded = true
+			verdict.Detail = fmt.Sprintf("matches %q", re.String())
+
+			break
 		}
 	}
 
-	return false
+	return verdict
 }
 
-// FilterContent filters file content based on pattern.
+// pathRegexVerdict excludes file unless its relative path, with forward
+// slashes, matches one of PathRegex, if any.
+func (f *FileFilter) pathRegexVerdict(file scanner.FileInfo, _ *Config) scanner.Verdict {
+	verdict := scanner.Verdict{Rule: "path regex"}
+	if len(f.pathRegex) == 0 {
+		return verdict
+	}
+
+	path := filepath.ToSlash(file.RelPath)
+	patterns := make([]string, len(f.pathRegex))
+	for i, re := range f.pathRegex {
+		if re.MatchString(path) {
+			verdict.Detail = fmt.Sprintf("matches %q", re.String())
+
+			return verdict
+		}
+		patterns[i] = re.String()
+	}
+	verdict.Excluded = true
+	verdict.Detail = "matches none of " + quoteList(patterns)
+
+	return verdict
+}
+
+// quoteList quotes each of values and joins them with commas.
+func quoteList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = strconv.Quote(value)
+	}
+
+	return strings.Join(quoted, ", ")
+}
+
+// FilterContent filters file content based on the configured patterns. A line
+// is kept when any content pattern matches it.
 func (f *FileFilter) FilterContent(lines []string) []FilteredLine {
 	var result []FilteredLine
 
-	if f.contentPattern == nil {
+	if f.todoPattern != nil {
+		return filterTodos(lines, f.todoPattern, f.todoContext)
+	}
+
+	if len(f.contentPatterns) == 0 {
 		// No pattern - return all lines
 		for i, line := range lines {
 			result = append(result, FilteredLine{
@@ -87,15 +398,85 @@ func (f *FileFilter) FilterContent(lines []string) []FilteredLine {
 		return result
 	}
 
-	// Filter lines matching pattern
+	// Filter lines matching any pattern
 	for i, line := range lines {
-		if f.contentPattern.MatchString(line) {
+		if matched, spans := f.matchingPatterns(line); len(matched) > 0 {
 			result = append(result, FilteredLine{
 				LineNumber: i + 1,
 				Content:    line,
+				Patterns:   matched,
+				Matches:    spans,
 			})
 		}
 	}
 
 	return result
 }
+
+// matchingPatterns returns the content patterns matching line, as
+// configured, and where in line they matched. Empty matches, such as those
+// of an anchor, have no span.
+func (f *FileFilter) matchingPatterns(line string) ([]string, []MatchSpan) {
+	var matched []string
+	var spans []MatchSpan
+	for _, pattern := range f.contentPatterns {
+		if !pattern.re.MatchString(line) {
+			continue
+		}
+		matched = append(matched, pattern.raw)
+		for _, loc := range pattern.re.FindAllStringIndex(line, -1) {
+			if loc[1] > loc[0] {
+				spans = append(spans, MatchSpan{Start: loc[0], End: loc[1]})
+			}
+		}
+	}
+
+	return matched, mergeSpans(spans)
+}
+
+// matchesPatterns reports whether any content pattern matches line, without
+// converting it to a string.
+func (f *FileFilter) matchesPatterns(line []byte) bool {
+	for _, pattern := range f.contentPatterns {
+		if pattern.match(line) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// filtersPatterns reports whether FilterContent keeps only the lines content
+// patterns match, which MaxMatches caps.
+func (f *FileFilter) filtersPatterns() bool {
+	return len(f.contentPatterns) > 0 && f.todoPattern == nil
+}
+
+// capMatches cuts lines kept by content patterns to MaxMatches, and reports
+// whether any were cut.
+func (f *FileFilter) capMatches(lines []FilteredLine) ([]FilteredLine, bool) {
+	if !f.filtersPatterns() || f.maxMatches == 0 || len(lines) <= f.maxMatches {
+		return lines, false
+	}
+
+	return lines[:f.maxMatches], true
+}
+
+// matchesAllPatterns reports whether every content pattern matched at least
+// one of lines.
+func (f *FileFilter) matchesAllPatterns(lines []FilteredLine) bool {
+	seen := make(map[string]bool, len(f.contentPatterns))
+	for _, line := range lines {
+		for _, pattern := range line.Patterns {
+			seen[pattern] = true
+		}
+	}
+
+	for _, pattern := range f.contentPatterns {
+		if !seen[pattern.raw] {
+			return false
+		}
+	}
+
+	return true
+}
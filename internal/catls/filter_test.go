@@ -0,0 +1,351 @@
+package catls
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+func TestFilterContentPatterns(t *testing.T) {
+	lines := []string{
+		"foo only",    // 1
+		"bar only",    // 2
+		"foo and bar", // 3
+		"neither",     // 4
+		"id = 42",     // 5
+	}
+
+	tests := []struct {
+		name     string
+		patterns []string
+		want     string
+	}{
+		{
+			name:     "single glob",
+			patterns: []string{"foo*"},
+			want:     "[1:[foo*] 3:[foo*]]",
+		},
+		{
+			name:     "any pattern keeps a line",
+			patterns: []string{"*foo*", "*bar*"},
+			want:     "[1:[*foo*] 2:[*bar*] 3:[*foo* *bar*]]",
+		},
+		{
+			name:     "regex prefix",
+			patterns: []string{`re:^\w+ = \d+$`},
+			want:     `[5:[re:^\w+ = \d+$]]`,
+		},
+		{
+			// Matched by a substring search
+			name:     "literal between stars",
+			patterns: []string{"**and*"},
+			want:     "[3:[**and*]]",
+		},
+		{
+			name:     "glob metacharacters are literal",
+			patterns: []string{"(*"},
+			want:     "[]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := NewFileFilter(&Config{ContentPatterns: tt.patterns})
+
+			got := []string{}
+			for _, line := range filter.FilterContent(lines) {
+				got = append(got, fmt.Sprintf("%d:%v", line.LineNumber, line.Patterns))
+			}
+
+			if fmt.Sprint(got) != tt.want {
+				t.Errorf("FilterContent() = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatternAll(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"both.txt":     "foo here\nbar there",
+		"foo.txt":      "foo only",
+		"bar.txt":      "bar only",
+		"sameline.txt": "foo bar",
+	})
+
+	tests := []struct {
+		name       string
+		patternAll bool
+		want       []string
+	}{
+		{name: "any pattern", want: []string{"bar.txt", "both.txt", "foo.txt", "sameline.txt"}},
+		{name: "every pattern", patternAll: true, want: []string{"both.txt", "sameline.txt"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, err := New(&Config{
+				Directory:       tmpDir,
+				RelativeTo:      tmpDir,
+				OutputFormat:    OutputFormatXML,
+				ContentPatterns: []string{"*foo*", "re:ba[r]"},
+				PatternAll:      tt.patternAll,
+			})
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+
+			var paths []string
+			for file, err := range app.Files(context.Background()) {
+				if err != nil {
+					t.Fatalf("Files() unexpected error: %v", err)
+				}
+				paths = append(paths, file.Info.RelPath)
+			}
+
+			if strings.Join(paths, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Files() yielded %v, want %v", paths, tt.want)
+			}
+			if got, want := app.Stats().SkippedMatch, 4-len(tt.want); got != want {
+				t.Errorf("SkippedMatch = %d, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestPathRegex(t *testing.T) {
+	paths := []string{
+		"main.go",
+		filepath.Join("src", "app.go"),
+		filepath.Join("src", "app_test.go"),
+		filepath.Join("src", "README.md"),
+		filepath.Join("docs", "Guide.MD"),
+	}
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		want    string
+		verdict string
+	}{
+		{
+			// Paths are matched with forward slashes on every platform
+			name:    "include",
+			cfg:     Config{PathRegex: []string{`^src/`}},
+			want:    "src/app.go src/app_test.go src/README.md",
+			verdict: `path regex: matches none of "^src/"`,
+		},
+		{
+			name:    "exclude",
+			cfg:     Config{ExcludePathRegex: []string{`_test\.go$`, `\.md$`}},
+			want:    "main.go src/app.go docs/Guide.MD",
+			verdict: `exclude path regex: matches "_test\\.go$"`,
+		},
+		{
+			name: "case-insensitive escape",
+			cfg:  Config{ExcludePathRegex: []string{`(?i)\.md$`}},
+			want: "main.go src/app.go src/app_test.go",
+		},
+		{
+			name: "composes with globs",
+			cfg:  Config{Globs: []string{"*.go"}, PathRegex: []string{`^src/`}, ExcludePathRegex: []string{`_test`}},
+			want: "src/app.go",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := NewFileFilter(&tt.cfg)
+			var got []string
+			verdicts := map[string]string{}
+			for _, path := range paths {
+				file := scanner.FileInfo{Path: path, RelPath: path}
+				if filter.ShouldIncludeFile(file, &tt.cfg) {
+					got = append(got, filepath.ToSlash(path))
+				} else if verdict, ok := scanner.FirstExclusion(filter.Verdicts(file, &tt.cfg)); ok {
+					verdicts[filepath.ToSlash(path)] = verdict.String()
+				}
+			}
+
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("included %v, want %s", got, tt.want)
+			}
+			if tt.verdict != "" && verdicts["src/app_test.go"] != tt.verdict && verdicts["main.go"] != tt.verdict {
+				t.Errorf("verdicts = %v, want one of %q", verdicts, tt.verdict)
+			}
+		})
+	}
+}
+
+func TestAppleDoubleFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"report.pdf":       "%PDF",
+		"._report.pdf":     "\x00\x05\x16\x07",
+		"._orphan":         "not an AppleDouble file",
+		"docs/notes.txt":   "notes",
+		"docs/._notes.txt": "\x00\x05\x16\x07",
+	})
+
+	app, err := New(&Config{Directory: tmpDir, Recursive: true, ShowAll: true, OutputFormat: OutputFormatXML, Output: io.Discard})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	var got []string
+	for file, err := range app.Files(context.Background()) {
+		if err != nil {
+			t.Fatalf("Files() unexpected error: %v", err)
+		}
+		got = append(got, filepath.ToSlash(file.Info.RelPath))
+	}
+	if want := "._orphan docs/notes.txt report.pdf"; strings.Join(got, " ") != want {
+		t.Errorf("Files() = %v, want %s", got, want)
+	}
+
+	explanation, err := app.Explain(filepath.Join(tmpDir, "docs", "._notes.txt"))
+	if err != nil {
+		t.Fatalf("Explain() unexpected error: %v", err)
+	}
+	if verdict, _ := explanation.Decision(); verdict.String() != "default ignore glob: AppleDouble file of docs/notes.txt" {
+		t.Errorf("Decision() = %q, want the AppleDouble rule", verdict)
+	}
+}
+
+func TestMaxMatches(t *testing.T) {
+	tmpDir := t.TempDir()
+	var content strings.Builder
+	for i := 1; i <= 10; i++ {
+		if i%2 == 0 {
+			fmt.Fprintf(&content, "match %d\n", i)
+		} else {
+			fmt.Fprintf(&content, "other %d\n", i)
+		}
+	}
+	writeTree(t, tmpDir, map[string]string{"log.txt": content.String()})
+	file := scanner.FileInfo{Path: filepath.Join(tmpDir, "log.txt"), RelPath: "log.txt", HasType: true}
+
+	tests := []struct {
+		name       string
+		maxMatches int
+		processor  *FileProcessor
+		want       string
+		total      int
+		suppressed bool
+	}{
+		{name: "streamed", processor: NewFileProcessor(nil), want: "[2 4 6 8 10]", total: 10},
+		{name: "streamed capped", maxMatches: 2, processor: NewFileProcessor(nil), want: "[2 4]", total: 6, suppressed: true},
+		{name: "streamed at cap", maxMatches: 5, processor: NewFileProcessor(nil), want: "[2 4 6 8 10]", total: 10},
+		{
+			// Transformers run before matching, as for files read whole
+			name:       "streamed transformed",
+			maxMatches: 1,
+			processor:  NewFileProcessor(nil, strings.ToUpper),
+			want:       "[2]",
+			total:      4,
+			suppressed: true,
+		},
+		{
+			// Front matter handling reads the whole file, so every line is counted
+			name:       "read whole capped",
+			maxMatches: 2,
+			processor:  &FileProcessor{typeDetector: &ContentTypeDetector{}, frontMatter: frontMatterStrip},
+			want:       "[2 4]",
+			total:      10,
+			suppressed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patterns := []string{"*match*"}
+			if len(tt.processor.transformers) > 0 {
+				patterns = []string{"*MATCH*"}
+			}
+			filter := NewFileFilter(&Config{ContentPatterns: patterns, MaxMatches: tt.maxMatches})
+
+			result := tt.processor.ProcessFile(file, filter)
+			if result.Error != nil {
+				t.Fatalf("ProcessFile() unexpected error: %v", result.Error)
+			}
+			var got []int
+			for _, line := range result.Lines {
+				got = append(got, line.LineNumber)
+			}
+			if fmt.Sprint(got) != tt.want {
+				t.Errorf("Lines = %v, want %s", got, tt.want)
+			}
+			if result.TotalLines != tt.total || result.MatchesSuppressed != tt.suppressed || result.IsEmpty {
+				t.Errorf("TotalLines, MatchesSuppressed, IsEmpty = %d, %v, %v, want %d, %v, false",
+					result.TotalLines, result.MatchesSuppressed, result.IsEmpty, tt.total, tt.suppressed)
+			}
+		})
+	}
+
+	for _, format := range []OutputFormat{OutputFormatMarkdown, OutputFormatXML, OutputFormatJSON} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf strings.Builder
+			app, err := New(&Config{
+				Directory:       tmpDir,
+				OutputFormat:    format,
+				ContentPatterns: []string{"*match*"},
+				MaxMatches:      3,
+				Output:          &buf,
+			})
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+			if err := app.Run(context.Background()); err != nil {
+				t.Fatalf("Run() unexpected error: %v", err)
+			}
+
+			want := map[OutputFormat]string{
+				OutputFormatMarkdown: "*… further matches suppressed after 3*",
+				OutputFormatXML:      `<matches-suppressed after="3"/>`,
+				OutputFormatJSON:     `"matchesSuppressed": true`,
+			}[format]
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("output lacks %s:\n%s", want, buf.String())
+			}
+		})
+	}
+}
+
+// BenchmarkPatternLargeLog greps a synthetic log of about 64 MiB in which one
+// line in a thousand matches. Memory per run stays near the size of the
+// matching lines rather than growing with the file.
+func BenchmarkPatternLargeLog(b *testing.B) {
+	const lineCount = 1 << 20
+
+	path := filepath.Join(b.TempDir(), "app.log")
+	var content strings.Builder
+	for i := range lineCount {
+		level := "INFO"
+		if i%1000 == 0 {
+			level = "ERROR"
+		}
+		fmt.Fprintf(&content, "2024-01-01T00:00:00Z %-5s request %08d handled by worker in 12ms\n", level, i)
+	}
+	if err := os.WriteFile(path, []byte(content.String()), 0o644); err != nil {
+		b.Fatal(err)
+	}
+	content.Reset()
+
+	file := scanner.FileInfo{Path: path, RelPath: "app.log", HasType: true}
+	filter := NewFileFilter(&Config{ContentPatterns: []string{"* ERROR *"}})
+	processor := NewFileProcessor(nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for b.Loop() {
+		result := processor.ProcessFile(file, filter)
+		if result.Error != nil || len(result.Lines) != lineCount/1000+1 {
+			b.Fatalf("ProcessFile() = %d lines, %v", len(result.Lines), result.Error)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package catls
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+// FilterDiff compares the files two filter configurations select from the
+// same scan. Paths are relative to the scanned directory.
+type FilterDiff struct {
+	OnlyOld []FilterDiffFile // Selected by the receiver of DiffFilters alone
+	OnlyNew []FilterDiffFile // Selected by the other App alone
+	Common  int              // Selected by both
+	Found   int              // Files the scan found, before either filter
+}
+
+// FilterDiffFile is a file only one configuration selects, with the verdict
+// of the rule that drops it from the other.
+type FilterDiffFile struct {
+	Path    string
+	Verdict scanner.Verdict
+}
+
+// DiffFilters scans once with the receiver's settings and reports which of
+// the files found its file filter and that of other select. Everything
+// deciding what the scan visits, such as Recursive, IgnoreDir, or
+// GitIgnore, comes from the receiver; other contributes only its file
+// filter: globs, path regular expressions, types, and the executable,
+// binary, and Imports rules. Each file is read for detection at most once,
+// and only when a read rule of either filter lets it through. IgnoreCmd is
+// not run.
+func (a *App) DiffFilters(ctx context.Context, other *App) (FilterDiff, error) {
+	if a.cfg.ScanArtifact != nil || other.cfg.ScanArtifact != nil {
+		return FilterDiff{}, errors.New("cannot compare filters over a scan artifact")
+	}
+	if err := a.validateConfig(); err != nil {
+		return FilterDiff{}, err
+	}
+	if err := other.validateConfig(); err != nil {
+		return FilterDiff{}, err
+	}
+	if err := a.checkBroadScan(); err != nil {
+		return FilterDiff{}, err
+	}
+	a.addFilesToGlobs()
+	other.addFilesToGlobs()
+
+	scanCfg := a.scanConfig(false)
+	// A directory is pruned only when both configurations would drop
+	// everything below it
+	scanCfg.IgnoreGlobs = commonGlobs(a.cfg.AllIgnoreGlobs(), other.cfg.AllIgnoreGlobs())
+	gitIgnore, err := a.newGitIgnoreFilter()
+	if err != nil {
+		return FilterDiff{}, err
+	}
+
+	var diff FilterDiff
+	outputs := a.selfOutputPaths()
+	defaultDescend := a.scanner.DefaultShouldDescend(scanCfg)
+	descend := func(dirPath string) bool {
+		return !isSelfOutput(dirPath, outputs) && defaultDescend(dirPath) && !gitIgnore.ignored(dirPath, true)
+	}
+	defaultInclude := scanner.DefaultShouldInclude(scanCfg)
+	prefilter := func(file scanner.FileInfo) bool {
+		if len(a.cfg.Paths) == 0 && (isSelfOutput(file.Path, outputs) || !defaultInclude(file) || gitIgnore.ignored(file.Path, false)) {
+			return false
+		}
+		diff.Found++
+
+		return a.filter.ShouldReadFile(file, a.cfg) || other.filter.ShouldReadFile(file, other.cfg)
+	}
+	// The scan detects types for a; other detects them again only when its
+	// --lang-map or directives could give a different answer
+	sameTypes := maps.Equal(a.cfg.LangMap, other.cfg.LangMap) && a.cfg.NoDirectives == other.cfg.NoDirectives
+	include := func(file scanner.FileInfo) bool {
+		otherFile := file
+		if !sameTypes && file.HasType && !file.IsBinary {
+			otherFile.FileType = other.processor.detectType(file)
+		}
+		oldVerdict, oldExcluded := a.filter.exclusion(file, a.cfg, a.filter.fileRules(a.cfg))
+		newVerdict, newExcluded := other.filter.exclusion(otherFile, other.cfg, other.filter.fileRules(other.cfg))
+		relPath := filepath.ToSlash(file.RelPath)
+		switch {
+		case !oldExcluded && !newExcluded:
+			diff.Common++
+		case !oldExcluded:
+			diff.OnlyOld = append(diff.OnlyOld, FilterDiffFile{Path: relPath, Verdict: newVerdict})
+		case !newExcluded:
+			diff.OnlyNew = append(diff.OnlyNew, FilterDiffFile{Path: relPath, Verdict: oldVerdict})
+		}
+
+		return false
+	}
+
+	_, err = a.scanner.Scan(ctx, scanCfg,
+		scanner.WithDescend(descend),
+		scanner.WithPrefilter(prefilter),
+		scanner.WithInclude(include),
+		scanner.WithTypeDetector(a.processor.detectType),
+	)
+	if err != nil {
+		return FilterDiff{}, fmt.Errorf("failed to scan files: %w", err)
+	}
+
+	byPath := func(x, y FilterDiffFile) int { return strings.Compare(x.Path, y.Path) }
+	slices.SortFunc(diff.OnlyOld, byPath)
+	slices.SortFunc(diff.OnlyNew, byPath)
+
+	return diff, nil
+}
+
+// commonGlobs returns the patterns of a that b also lists.
+func commonGlobs(a, b []string) []string {
+	var common []string
+	for _, pattern := range a {
+		if slices.Contains(b, pattern) {
+			common = append(common, pattern)
+		}
+	}
+
+	return common
+}
+
+// WriteFilterDiff writes a line per file only one configuration selects,
+// "-" for the old and "+" for the new, with the rule the other drops it by,
+// followed by the counts.
+func WriteFilterDiff(w io.Writer, diff FilterDiff) error {
+	var b strings.Builder
+	for _, file := range diff.OnlyOld {
+		fmt.Fprintf(&b, "- %s\t(new: %s)\n", file.Path, file.Verdict)
+	}
+	for _, file := range diff.OnlyNew {
+		fmt.Fprintf(&b, "+ %s\t(old: %s)\n", file.Path, file.Verdict)
+	}
+	fmt.Fprintf(&b, "%d only in old, %d only in new, %d in both, of %d found\n",
+		len(diff.OnlyOld), len(diff.OnlyNew), diff.Common, diff.Found)
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
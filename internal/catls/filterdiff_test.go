@@ -0,0 +1,84 @@
+package catls
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestDiffFilters(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"main.go":          "package main\n",
+		"main_test.go":     "package main\n",
+		"README.md":        "# readme\n",
+		"docs/guide.md":    "# guide\n",
+		"docs/api/ref.md":  "# ref\n",
+		"scripts/build.sh": "#!/bin/sh\n",
+		"testdata/in.txt":  "input\n",
+	})
+
+	newApp := func(cfg Config) *App {
+		t.Helper()
+		cfg.Directory, cfg.Recursive, cfg.OutputFormat = root, true, OutputFormatJSON
+		app, err := New(&cfg)
+		if err != nil {
+			t.Fatalf("New() unexpected error: %v", err)
+		}
+
+		return app
+	}
+
+	// testdata/* prunes the old scan, but not the shared one, since the new
+	// configuration keeps those files
+	old := newApp(Config{IgnoreGlobs: []string{"*_test.go", "testdata/*"}})
+	compared := newApp(Config{IgnoreGlobs: []string{"docs/**"}, ExcludeTypes: []string{"bash"}})
+
+	diff, err := old.DiffFilters(context.Background(), compared)
+	if err != nil {
+		t.Fatalf("DiffFilters() unexpected error: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := WriteFilterDiff(&out, diff); err != nil {
+		t.Fatal(err)
+	}
+	want := `- docs/api/ref.md	(new: user ignore glob: matches "docs/**")
+- docs/guide.md	(new: user ignore glob: matches "docs/**")
+- scripts/build.sh	(new: type: bash)
++ main_test.go	(old: user ignore glob: matches "*_test.go")
++ testdata/in.txt	(old: user ignore glob: matches "testdata/*")
+3 only in old, 2 only in new, 2 in both, of 7 found
+`
+	if out.String() != want {
+		t.Errorf("WriteFilterDiff() =\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	// The compared side detects types with its own --lang-map and directives
+	typed := newApp(Config{Types: []string{"go"}})
+	for _, tt := range []struct {
+		name string
+		cfg  Config
+		want int
+	}{
+		{name: "lang map", cfg: Config{Types: []string{"go"}, LangMap: map[string]string{"sh": "go"}}, want: 1},
+		{name: "same types", cfg: Config{Types: []string{"go"}}},
+	} {
+		diff, err := typed.DiffFilters(context.Background(), newApp(tt.cfg))
+		if err != nil {
+			t.Fatalf("%s: DiffFilters() unexpected error: %v", tt.name, err)
+		}
+		if len(diff.OnlyNew) != tt.want || len(diff.OnlyOld) != 0 {
+			t.Errorf("%s: DiffFilters() = %+v, want %d files only in new", tt.name, diff, tt.want)
+		}
+	}
+
+	// Identical filters differ nowhere
+	diff, err = old.DiffFilters(context.Background(), newApp(Config{IgnoreGlobs: []string{"*_test.go", "testdata/*"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.OnlyOld) != 0 || len(diff.OnlyNew) != 0 || diff.Common != 5 {
+		t.Errorf("DiffFilters() of the same filters = %+v, want 5 common files only", diff)
+	}
+}
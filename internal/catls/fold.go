@@ -0,0 +1,74 @@
+package catls
+
+import (
+	"errors"
+	"unicode/utf8"
+)
+
+// foldMarker ends every segment of a folded line but the last.
+const foldMarker = "↩"
+
+// foldFile wraps the lines of file wider than width columns, set by Fold,
+// just before the file is written, so content patterns, todos, and cross
+// links all saw the unwrapped lines. Columns are counted in runes and a
+// segment never splits one. Each segment but the last ends with foldMarker,
+// so it is width columns wide; segments after the first are Continued and
+// carry no keyword or pattern. Match spans are split with the line.
+func foldFile(file *ProcessedFile, width int) {
+	if width <= 0 {
+		return
+	}
+
+	var folded []FilteredLine
+	for i, line := range file.Lines {
+		if utf8.RuneCountInString(line.Content) <= width {
+			if folded != nil {
+				folded = append(folded, line)
+			}
+
+			continue
+		}
+		if folded == nil {
+			folded = append(make([]FilteredLine, 0, len(file.Lines)+1), file.Lines[:i]...)
+		}
+		folded = append(folded, foldLine(line, width)...)
+	}
+	if folded != nil {
+		file.Lines = folded
+	}
+}
+
+// foldLine splits line into segments of width-1 runes and a marker, with the
+// rest of the line in the last.
+func foldLine(line FilteredLine, width int) []FilteredLine {
+	size := max(width-len([]rune(foldMarker)), 1)
+	runes := []rune(line.Content)
+
+	spans, offset := line.Matches, 0
+
+	var segments []FilteredLine
+	for len(runes) > width {
+		segment := line
+		text := string(runes[:size])
+		segment.Content = text + foldMarker
+		segment.Matches = clipSpans(spans, offset, offset+len(text))
+		segments = append(segments, segment)
+		runes = runes[size:]
+		offset += len(text)
+
+		line.Continued, line.Keyword, line.Patterns = true, "", nil
+	}
+	line.Content = string(runes)
+	line.Matches = clipSpans(spans, offset, offset+len(line.Content))
+
+	return append(segments, line)
+}
+
+// validateFold requires a non-negative Fold.
+func (c *Config) validateFold() error {
+	if c.Fold < 0 {
+		return errors.New("--fold must not be negative")
+	}
+
+	return nil
+}
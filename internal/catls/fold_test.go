@@ -0,0 +1,76 @@
+package catls
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFoldFile(t *testing.T) {
+	file := ProcessedFile{Lines: []FilteredLine{
+		{LineNumber: 1, Content: "short"},
+		{LineNumber: 2, Content: "héllo wörld ünïcode", Keyword: "TODO"},
+		{LineNumber: 3, Content: "exactly10!"},
+	}}
+	foldFile(&file, 10)
+
+	want := []FilteredLine{
+		{LineNumber: 1, Content: "short"},
+		{LineNumber: 2, Content: "héllo wör↩", Keyword: "TODO"},
+		{LineNumber: 2, Content: "ld ünïcode", Continued: true},
+		{LineNumber: 3, Content: "exactly10!"},
+	}
+	if !reflect.DeepEqual(file.Lines, want) {
+		t.Errorf("foldFile() =\n%+v\nwant\n%+v", file.Lines, want)
+	}
+
+	gutter := lineGutter{enabled: true, format: LineNumberFormatPipe, width: 4}
+	if got := gutter.Line(want[1], want[1].Content) + "\n" + gutter.Line(want[2], want[2].Content); got != "   2| héllo wör↩\n      ld ünïcode" {
+		t.Errorf("gutter of folded lines = %q", got)
+	}
+}
+
+func TestFoldKeepsPatternsOnWholeLines(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"dump.sql": "INSERT INTO t VALUES ('" + strings.Repeat("x", 40) + "needle');\n",
+		"skip.sql": "SELECT 1;\n",
+	})
+
+	var buf bytes.Buffer
+	app, err := New(&Config{
+		Directory:       tmpDir,
+		OutputFormat:    OutputFormatJSON,
+		Output:          &buf,
+		Fold:            20,
+		ContentPatterns: []string{"*xneedle*"},
+		PatternAll:      true,
+	})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	output := buf.String()
+	if strings.Contains(output, "skip.sql") || !strings.Contains(output, `"continued": true`) {
+		t.Fatalf("output should hold only dump.sql, folded:\n%s", output)
+	}
+
+	path := filepath.Join(t.TempDir(), "out.json")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+	bundle, err := ReadBundle(path)
+	if err != nil {
+		t.Fatalf("reading the folded output: %v", err)
+	}
+	if want := "INSERT INTO t VALUES ('" + strings.Repeat("x", 40) + "needle');"; bundle.Files[0].Lines[0] != want {
+		t.Errorf("ReadBundle() joined folded lines into %q, want %q", bundle.Files[0].Lines[0], want)
+	}
+}
@@ -0,0 +1,71 @@
+package catls
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+// excludedFile is a file the file filter left out of an interactive run,
+// kept so the selector can offer it.
+type excludedFile struct {
+	info     scanner.FileInfo
+	reason   string // The excluding verdict, as "rule: detail"
+	detected bool   // The binary flag and type were detected before the exclusion
+}
+
+// recordExcluded remembers a file the filter excluded, with the verdict, for
+// the interactive selector. Other runs keep nothing.
+func (a *App) recordExcluded(file scanner.FileInfo, verdict scanner.Verdict, detected bool) {
+	if !a.cfg.Interactive {
+		return
+	}
+
+	a.excluded = append(a.excluded, excludedFile{info: file, reason: verdict.String(), detected: detected})
+}
+
+// withExcluded merges the excluded files into files by relative path, in the
+// order Sort selects, which the scanner returns them in, and
+// returns the reason each excluded file was left out, by path.
+func (a *App) withExcluded(files []scanner.FileInfo) ([]scanner.FileInfo, map[string]string) {
+	reasons := make(map[string]string, len(a.excluded))
+	if len(a.excluded) == 0 {
+		return files, reasons
+	}
+
+	excluded := make([]scanner.FileInfo, 0, len(a.excluded))
+	for _, e := range a.excluded {
+		reasons[e.info.Path] = e.reason
+		excluded = append(excluded, e.info)
+	}
+	slices.SortStableFunc(excluded, func(x, y scanner.FileInfo) int {
+		return a.cfg.comparePaths(x.RelPath, y.RelPath)
+	})
+
+	merged := make([]scanner.FileInfo, 0, len(files)+len(excluded))
+	for _, f := range files {
+		for len(excluded) > 0 && a.cfg.comparePaths(excluded[0].RelPath, f.RelPath) < 0 {
+			merged = append(merged, excluded[0])
+			excluded = excluded[1:]
+		}
+		merged = append(merged, f)
+	}
+
+	return append(merged, excluded...), reasons
+}
+
+// forceInclude prepares a file the user selected despite the filter. Files
+// excluded before detection are classified here, and their type is left for
+// processing to detect.
+func (a *App) forceInclude(file *scanner.FileInfo) {
+	if a.cfg.Debug {
+		fmt.Fprintf(a.warn, "Debug: Force-including %s\n", file.RelPath)
+	}
+
+	i := slices.IndexFunc(a.excluded, func(e excludedFile) bool { return e.info.Path == file.Path })
+	if i >= 0 && !a.excluded[i].detected {
+		file.IsBinary = file.Size > 0 && (&scanner.FileBinaryDetector{}).IsBinary(file.Path)
+		file.FileType, file.HasType = "", false
+	}
+}
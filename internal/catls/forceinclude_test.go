@@ -0,0 +1,63 @@
+package catls
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestExcludedFilesForSelector(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"a.go":        "package a",
+		"a/b.go":      "package b",
+		"a/debug.log": "trace",
+		"z.log":       "trace",
+	})
+
+	for _, interactive := range []bool{false, true} {
+		app, err := New(&Config{
+			Directory:    tmpDir,
+			Recursive:    true,
+			OutputFormat: OutputFormatXML,
+			IgnoreGlobs:  []string{"*.log"},
+			Interactive:  interactive,
+		})
+		if err != nil {
+			t.Fatalf("New() unexpected error: %v", err)
+		}
+		files, _, err := app.scanFiles(context.Background(), false)
+		if err != nil {
+			t.Fatalf("scanFiles() unexpected error: %v", err)
+		}
+		if !interactive {
+			if len(app.excluded) != 0 {
+				t.Errorf("non-interactive run kept %d excluded files", len(app.excluded))
+			}
+
+			continue
+		}
+
+		merged, reasons := app.withExcluded(files)
+		var order []string
+		for _, f := range merged {
+			order = append(order, f.RelPath)
+		}
+		if got := strings.Join(order, ","); got != "a.go,a/b.go,a/debug.log,z.log" {
+			t.Errorf("merged order = %s", got)
+		}
+		for _, f := range merged {
+			if strings.HasSuffix(f.RelPath, ".log") && !strings.HasPrefix(reasons[f.Path], "user ignore glob") {
+				t.Errorf("reason for %s = %q", f.RelPath, reasons[f.Path])
+			}
+		}
+
+		// The log was excluded before detection, so forcing it detects it
+		forced := merged[2]
+		forced.IsBinary, forced.HasType, forced.FileType = true, true, "stale"
+		app.forceInclude(&forced)
+		if forced.IsBinary || forced.HasType {
+			t.Errorf("forceInclude() left stale detection: %+v", forced)
+		}
+	}
+}
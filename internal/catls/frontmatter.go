@@ -0,0 +1,136 @@
+package catls
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/connerohnesorge/catls/internal/languages"
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+// frontMatterMode selects what the processor keeps of a front matter block.
+type frontMatterMode int
+
+const (
+	frontMatterKeep  frontMatterMode = iota // Leave content alone
+	frontMatterOnly                         // Keep only the block, set by FrontMatterOnly
+	frontMatterStrip                        // Drop the block, set by StripFrontMatter
+)
+
+// frontMatterLanguages maps the line opening and closing a front matter block
+// to the language of the block.
+var frontMatterLanguages = map[string]string{
+	"---": "yaml",
+	"+++": "toml",
+}
+
+// frontMatterTypes are the file types that may open with a front matter
+// block, and frontMatterExtensions the extensions of other content files
+// that may. In data files such as YAML, a leading "---" starts a document.
+var (
+	frontMatterTypes      = map[string]bool{"markdown": true, "html": true}
+	frontMatterExtensions = map[string]bool{"mdx": true, "adoc": true, "asciidoc": true, "rst": true, "org": true, "textile": true}
+)
+
+// frontMatterModeOf returns the front matter mode selected by cfg.
+func frontMatterModeOf(cfg *Config) frontMatterMode {
+	switch {
+	case cfg.FrontMatterOnly:
+		return frontMatterOnly
+	case cfg.StripFrontMatter:
+		return frontMatterStrip
+	default:
+		return frontMatterKeep
+	}
+}
+
+// findFrontMatter looks for a front matter block at the start of lines: a
+// "---" (YAML) or "+++" (TOML) line, which may follow a UTF-8 byte order mark,
+// through the next identical line. Trailing whitespace, including a carriage
+// return left by Windows line endings, is ignored on both delimiter lines. It
+// returns the block's language and the index of its closing line.
+func findFrontMatter(lines []string) (string, int, bool) {
+	if len(lines) == 0 {
+		return "", 0, false
+	}
+
+	open := strings.TrimRight(strings.TrimPrefix(lines[0], "\ufeff"), " \t\r")
+	lang, ok := frontMatterLanguages[open]
+	if !ok {
+		return "", 0, false
+	}
+
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimRight(lines[i], " \t\r") == open {
+			return lang, i, true
+		}
+	}
+
+	return "", 0, false
+}
+
+// hasFrontMatter reports whether file is a content file, which may open
+// with a front matter block.
+func hasFrontMatter(file *ProcessedFile) bool {
+	return frontMatterTypes[file.FileType] || frontMatterExtensions[languages.Extension(file.Info.Path)]
+}
+
+// apply keeps the part of lines the mode selects and returns it
+// with the number of lines dropped before it. It records the block's
+// language in file.FrontMatter, and under frontMatterOnly highlights the
+// block as that language. Files without a block, and files other than
+// content files, are returned unchanged.
+func (m frontMatterMode) apply(file *ProcessedFile, lines []string) ([]string, int) {
+	if m == frontMatterKeep || !hasFrontMatter(file) {
+		return lines, 0
+	}
+
+	lang, end, ok := findFrontMatter(lines)
+	if !ok {
+		return lines, 0
+	}
+	file.FrontMatter = lang
+
+	if m == frontMatterOnly {
+		file.FileType = lang
+
+		return lines[1:end], 1
+	}
+
+	return lines[end+1:], end + 1
+}
+
+// shouldSkipFrontMatter reports whether FrontMatterOnly drops this file
+// because it has no front matter.
+func (a *App) shouldSkipFrontMatter(file *ProcessedFile) bool {
+	verdict := a.frontMatterVerdict(file)
+	if !verdict.Excluded {
+		return false
+	}
+
+	if a.cfg.Debug {
+		fmt.Fprintf(a.warn, "Debug: Skipping file: %s (%s)\n", file.Info.RelPath, verdict)
+	}
+	a.stats.SkippedFrontMatter++
+	a.omit(file.Info, OmitReasonNoFrontMatter)
+
+	return true
+}
+
+// frontMatterVerdict applies FrontMatterOnly. Unreadable files are kept so
+// the error is reported.
+func (a *App) frontMatterVerdict(file *ProcessedFile) scanner.Verdict {
+	verdict := scanner.Verdict{Rule: "front matter"}
+	if !a.cfg.FrontMatterOnly || file.Error != nil {
+		return verdict
+	}
+
+	if file.FrontMatter == "" {
+		verdict.Excluded = true
+		verdict.Detail = "none found, with --front-matter-only"
+	} else {
+		verdict.Detail = file.FrontMatter + " block"
+	}
+
+	return verdict
+}
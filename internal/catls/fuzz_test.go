@@ -0,0 +1,140 @@
+package catls
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+// fuzzFile builds a processed file from fuzzed input, one line per "\n" of
+// content as the processor splits it.
+func fuzzFile(path, content string) *ProcessedFile {
+	file := &ProcessedFile{
+		Info:     scanner.FileInfo{Path: path, RelPath: path},
+		FileType: "go",
+	}
+	for i, line := range strings.Split(content, "\n") {
+		file.Lines = append(file.Lines, FilteredLine{LineNumber: i + 1, Content: line})
+	}
+	file.TotalLines = len(file.Lines)
+
+	return file
+}
+
+func FuzzXMLWriteFile(f *testing.F) {
+	f.Add("main.go", "package main", false)
+	f.Add("a\nb.go", "x", false)
+	f.Add(`q"uote's<&>.go`, "a\rb", true)
+	f.Add("cdata.go", "]]>]]]>", true)
+	f.Add("‮gnp.exe", "\x00\x1b[31m\xff\xfe", false)
+	f.Add("emoji-😀.go", "𝔘𝔫𝔦𝔠𝔬𝔡𝔢", false)
+
+	f.Fuzz(func(t *testing.T, path, content string, cdata bool) {
+		var buf bytes.Buffer
+		output := NewXMLOutput(&buf)
+		output.cdata = cdata
+		ctx := context.Background()
+		if err := output.WriteHeader(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if err := output.WriteFile(ctx, fuzzFile(path, content), &Config{ShowLineNumbers: true}); err != nil {
+			t.Fatal(err)
+		}
+		if err := output.WriteFooter(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		decoder := xml.NewDecoder(&buf)
+		var paths []string
+		for {
+			token, err := decoder.Token()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				t.Fatalf("output does not parse: %v\n%q", err, buf.String())
+			}
+			if start, ok := token.(xml.StartElement); ok && start.Name.Local == "file" {
+				for _, attr := range start.Attr {
+					if attr.Name.Local == "path" {
+						paths = append(paths, attr.Value)
+					}
+				}
+			}
+		}
+
+		if want := []string{xmlSafe(path)}; len(paths) != 1 || paths[0] != want[0] {
+			t.Errorf("parsed paths = %q, want %q", paths, want)
+		}
+	})
+}
+
+// fenceLine matches a line that may open or close a fenced code block: up to
+// three spaces of indentation, a run of backticks or tildes, and the rest.
+var fenceLine = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})(.*)$")
+
+// fencedBlocks returns the line ranges of the fenced code blocks in doc, as
+// CommonMark delimits them. Unclosed blocks end at -1.
+func fencedBlocks(doc string) [][2]int {
+	lines := regexp.MustCompile("\r\n|\r|\n").Split(doc, -1)
+
+	var blocks [][2]int
+	open, openFence := -1, ""
+	for i, line := range lines {
+		match := fenceLine.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		fence, rest := match[1], match[2]
+		if open < 0 {
+			if fence[0] == '`' && strings.Contains(rest, "`") {
+				// Not a fence: an info string cannot hold backticks
+				continue
+			}
+			open, openFence = i, fence
+		} else if fence[0] == openFence[0] && len(fence) >= len(openFence) && strings.TrimRight(rest, " \t") == "" {
+			blocks = append(blocks, [2]int{open, i})
+			open = -1
+		}
+	}
+	if open >= 0 {
+		blocks = append(blocks, [2]int{open, -1})
+	}
+
+	return blocks
+}
+
+func FuzzMarkdownFence(f *testing.F) {
+	f.Add("main.go", "package main", false)
+	f.Add("a\n```\nb.go", "x", false)
+	f.Add("back`tick.go", "```\n````", false)
+	f.Add("cr.go", "lone\r```` carriage return", true)
+	f.Add("tilde.go", "~~~\n ~~~~", true)
+
+	f.Fuzz(func(t *testing.T, path, content string, tilde bool) {
+		cfg := &Config{}
+		if tilde {
+			cfg.FenceStyle = FenceStyleTilde
+		}
+
+		var buf bytes.Buffer
+		if err := NewMarkdownOutput(&buf).WriteFile(context.Background(), fuzzFile(path, content), cfg); err != nil {
+			t.Fatal(err)
+		}
+		doc := buf.String()
+
+		// The one block must close on the last line, before the final newline
+		blocks := fencedBlocks(strings.TrimSuffix(doc, "\n"))
+		lastLine := len(regexp.MustCompile("\r\n|\r|\n").Split(strings.TrimSuffix(doc, "\n"), -1)) - 1
+		if len(blocks) != 1 || blocks[0][1] != lastLine {
+			t.Errorf("fenced blocks = %v, want one closing on line %d\n%q", blocks, lastLine, doc)
+		}
+	})
+}
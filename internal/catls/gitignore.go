@@ -0,0 +1,132 @@
+package catls
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/connerohnesorge/catls/internal/gitignore"
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+// gitIgnoreRule is the name of the GitIgnore rule in explanations.
+const gitIgnoreRule = "gitignore"
+
+// gitIgnoreFilter skips the paths git ignores below the scanned directory.
+type gitIgnoreFilter struct {
+	matcher *gitignore.Matcher
+	dir     string    // Directory as configured, which scanned paths start with
+	base    string    // Directory relative to the work tree root, with slashes
+	verify  io.Writer // Receives a line per skipped path (nil unless GitIgnoreVerify)
+	warn    io.Writer // Receives patterns that fail to match
+}
+
+// newGitIgnoreFilter opens the rules of the work tree holding Directory,
+// or returns nil unless GitIgnore is set.
+func (a *App) newGitIgnoreFilter() (*gitIgnoreFilter, error) {
+	if !a.cfg.GitIgnore {
+		return nil, nil
+	}
+
+	matcher, err := gitignore.Open(a.cfg.Directory)
+	if err != nil {
+		return nil, fmt.Errorf("--gitignore: %w", err)
+	}
+	abs, err := filepath.Abs(a.cfg.Directory)
+	if err != nil {
+		return nil, err
+	}
+	base, _ := matcher.Rel(abs)
+
+	f := &gitIgnoreFilter{matcher: matcher, dir: a.cfg.Directory, base: base, warn: a.warn}
+	if a.cfg.GitIgnoreVerify {
+		f.verify = a.warn
+	}
+
+	return f, nil
+}
+
+// relPath returns fullPath, a scanned path, relative to the work tree root,
+// and relative to the scanned directory, both with slashes.
+func (f *gitIgnoreFilter) relPath(fullPath string) (string, string, bool) {
+	rel, err := filepath.Rel(f.dir, fullPath)
+	if err != nil {
+		return "", "", false
+	}
+	rel = filepath.ToSlash(rel)
+	if f.base == "." {
+		return rel, rel, true
+	}
+
+	return f.base + "/" + rel, rel, true
+}
+
+// ignored reports whether git ignores the scanned path fullPath, whose
+// parent directories the scan already checked, and writes the deciding
+// pattern to verify when it does.
+func (f *gitIgnoreFilter) ignored(fullPath string, isDir bool) bool {
+	if f == nil {
+		return false
+	}
+	rootRel, rel, ok := f.relPath(fullPath)
+	if !ok {
+		return false
+	}
+
+	match, err := f.matcher.Match(rootRel, isDir)
+	if err != nil {
+		fmt.Fprintf(f.warn, "Warning: %v\n", err)
+
+		return false
+	}
+	if match == nil || match.Negated() {
+		return false
+	}
+
+	if f.verify != nil {
+		if isDir {
+			rel += "/"
+		}
+		fmt.Fprintf(f.verify, "%s\t%s\n", match, rel)
+	}
+
+	return true
+}
+
+// gitIgnoreVerdict checks file and its parent directories against git's
+// ignore rules for an explanation.
+func (a *App) gitIgnoreVerdict(file scanner.FileInfo) (scanner.Verdict, error) {
+	verdict := scanner.Verdict{Rule: gitIgnoreRule}
+	f, err := a.newGitIgnoreFilter()
+	if err != nil {
+		return verdict, err
+	}
+	rootRel, _, ok := f.relPath(file.Path)
+	if !ok {
+		return verdict, nil
+	}
+
+	match, err := f.matcher.MatchPath(rootRel, file.IsDir)
+	switch {
+	case err != nil:
+		return verdict, err
+	case match == nil:
+		verdict.Detail = "no pattern matches"
+	case match.Negated():
+		verdict.Detail = "re-included by " + match.String()
+	default:
+		verdict.Excluded, verdict.Detail = true, match.String()
+	}
+
+	return verdict, nil
+}
+
+// validateGitIgnore requires GitIgnore for GitIgnoreVerify.
+func (c *Config) validateGitIgnore() error {
+	if c.GitIgnoreVerify && !c.GitIgnore {
+		return errors.New("--gitignore-verify requires --gitignore")
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package catls
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestGitIgnore(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	writeTree(t, home, map[string]string{".config/git/ignore": "*.tmp\n"})
+
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		".git/info/exclude":     "notes.txt\n",
+		".gitignore":            "*.log\n!keep.log\nout/\n",
+		"src/.gitignore":        "local.go\n",
+		"src/main.go":           "package main\n",
+		"src/local.go":          "package main\n",
+		"src/out/gen.go":        "package out\n",
+		"src/deep/local.go":     "package deep\n",
+		"app.log":               "log\n",
+		"keep.log":              "kept\n",
+		"scratch.tmp":           "tmp\n",
+		"notes.txt":             "notes\n",
+		"README.md":             "# readme\n",
+		"src/deep/unrelated.md": "# unrelated\n",
+	})
+
+	selected := func(dir string, gitIgnore bool) []string {
+		t.Helper()
+		app, err := New(&Config{Directory: dir, Recursive: true, GitIgnore: gitIgnore, OutputFormat: OutputFormatJSON})
+		if err != nil {
+			t.Fatalf("New() unexpected error: %v", err)
+		}
+		var paths []string
+		for file, err := range app.Files(context.Background()) {
+			if err != nil {
+				t.Fatalf("Files() unexpected error: %v", err)
+			}
+			paths = append(paths, filepath.ToSlash(file.Info.RelPath))
+		}
+		slices.Sort(paths)
+
+		return paths
+	}
+
+	want := "README.md keep.log src/deep/unrelated.md src/main.go"
+	if got := strings.Join(selected(root, true), " "); got != want {
+		t.Errorf("with --gitignore = %s, want %s", got, want)
+	}
+	// .gitignore files above the scanned directory apply too
+	if got := strings.Join(selected(filepath.Join(root, "src"), true), " "); got != "deep/unrelated.md main.go" {
+		t.Errorf("below the root = %s, want deep/unrelated.md main.go", got)
+	}
+	if got := selected(root, false); len(got) != 10 {
+		t.Errorf("without --gitignore selected %d files, want 10: %v", len(got), got)
+	}
+
+	app, err := New(&Config{Directory: root, Recursive: true, GitIgnore: true, OutputFormat: OutputFormatJSON})
+	if err != nil {
+		t.Fatal(err)
+	}
+	explanation, err := app.Explain(filepath.Join(root, "src", "out", "gen.go"))
+	if err != nil {
+		t.Fatalf("Explain() unexpected error: %v", err)
+	}
+	if decision, excluded := explanation.Decision(); !excluded || decision.String() != "gitignore: .gitignore:3:out/" {
+		t.Errorf("Explain() decision = %v, want gitignore: .gitignore:3:out/", decision)
+	}
+
+	// Outside a work tree the flag is an error rather than a silent no-op
+	if err := os.RemoveAll(filepath.Join(root, ".git")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(root), ".git")); err == nil {
+		t.Skip("the temporary directory is inside a git work tree")
+	}
+	app, err = New(&Config{Directory: root, GitIgnore: true, OutputFormat: OutputFormatJSON})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, err := range app.Files(context.Background()) {
+		if err == nil || !strings.Contains(err.Error(), "--gitignore") {
+			t.Errorf("Files() outside a work tree error = %v, want --gitignore error", err)
+		}
+
+		break
+	}
+}
+
+func TestValidateGitIgnore(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{name: "off", cfg: Config{}},
+		{name: "gitignore", cfg: Config{GitIgnore: true}},
+		{name: "verify", cfg: Config{GitIgnore: true, GitIgnoreVerify: true}},
+		{name: "verify alone", cfg: Config{GitIgnoreVerify: true}, wantErr: "requires --gitignore"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.validateGitIgnore()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateGitIgnore() unexpected error: %v", err)
+				}
+
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateGitIgnore() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
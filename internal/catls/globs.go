@@ -0,0 +1,82 @@
+package catls
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+// expandGlobs normalizes the patterns given to flag and expands their brace
+// alternatives. Errors name the flag and the offending pattern.
+func expandGlobs(flag string, patterns []string) ([]string, error) {
+	var expanded []string
+	var errs []error
+	for _, pattern := range patterns {
+		globs, err := scanner.ExpandGlob(pattern)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s pattern %q: %w", flag, pattern, err))
+
+			continue
+		}
+		expanded = append(expanded, globs...)
+	}
+
+	return expanded, errors.Join(errs...)
+}
+
+// globMatches counts the scanned files each user pattern matches, so a run
+// whose filters leave nothing can point at the pattern responsible.
+type globMatches struct {
+	flags    []string
+	patterns []string
+	counts   []int
+}
+
+// newGlobMatches returns a counter for the Globs and IgnoreGlobs in cfg, or nil
+// when there are none.
+func newGlobMatches(cfg *Config) *globMatches {
+	if len(cfg.Globs) == 0 && len(cfg.IgnoreGlobs) == 0 {
+		return nil
+	}
+
+	m := &globMatches{}
+	for _, pattern := range cfg.Globs {
+		m.flags = append(m.flags, "--globs")
+		m.patterns = append(m.patterns, pattern)
+	}
+	for _, pattern := range cfg.IgnoreGlobs {
+		m.flags = append(m.flags, "--ignore-globs")
+		m.patterns = append(m.patterns, pattern)
+	}
+	m.counts = make([]int, len(m.patterns))
+
+	return m
+}
+
+// record counts relPath against every pattern it matches.
+func (m *globMatches) record(relPath string) {
+	if m == nil {
+		return
+	}
+
+	for i, pattern := range m.patterns {
+		if scanner.MatchesGlobPattern(relPath, pattern) {
+			m.counts[i]++
+		}
+	}
+}
+
+// writeHint explains that none of the found files passed the filters and
+// lists how many files each pattern matched.
+func (m *globMatches) writeHint(w io.Writer, found int) {
+	if m == nil {
+		return
+	}
+
+	fmt.Fprintf(w, "Hint: none of the %d files found passed the filters. Files matched by each pattern:\n", found)
+	for i, pattern := range m.patterns {
+		fmt.Fprintf(w, "  %s %q: %d\n", m.flags[i], pattern, m.counts[i])
+	}
+}
@@ -0,0 +1,62 @@
+package catls
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/connerohnesorge/catls/internal/testutil"
+)
+
+// goldenCases is the flag matrix run against the fixture tree for every format.
+// Directory and RelativeTo are filled in relative to the fixture root.
+var goldenCases = []struct {
+	name       string
+	cfg        Config
+	subdir     string // Scan this subdirectory of the fixture instead of its root
+	relativeTo bool   // Show paths relative to the fixture root
+}{
+	{name: "default", cfg: Config{}},
+	{name: "recursive", cfg: Config{Recursive: true, IgnoreDir: []string{"node_modules"}}},
+	{name: "line-numbers", cfg: Config{Recursive: true, IgnoreDir: []string{"node_modules"}, ShowLineNumbers: true}},
+	{name: "globs", cfg: Config{Recursive: true, Globs: []string{"*.go", "*.py"}, IgnoreGlobs: []string{"*.log"}}},
+	{name: "pattern", cfg: Config{Recursive: true, IgnoreDir: []string{"node_modules"}, ContentPatterns: []string{"*TODO*"}, ShowLineNumbers: true}},
+	{name: "omit-bins", cfg: Config{Recursive: true, IgnoreDir: []string{"node_modules", "src", "ünïcödé"}, OmitBins: true}},
+	{name: "include-dirs", cfg: Config{Recursive: true, IgnoreDir: []string{"node_modules"}, Globs: []string{"*.go"}, IncludeDirs: true}},
+	{name: "legacy-truncation", cfg: Config{Recursive: true, ShowLineNumbers: true, LegacyTruncation: true}, subdir: "src"},
+	{name: "todos", cfg: Config{Recursive: true, IgnoreDir: []string{"node_modules"}, Todos: true, TodoContext: 1, ShowLineNumbers: true}},
+	{name: "relative-to", cfg: Config{Recursive: true}, subdir: "src", relativeTo: true},
+	{name: "config-echo", cfg: Config{Recursive: true, Globs: []string{"*.py", "*.ts"}, ConfigEcho: true}, subdir: "src"},
+}
+
+func TestGoldenOutput(t *testing.T) {
+	root := testutil.BuildFixtureTree(t)
+
+	for _, format := range GetSupportedFormats() {
+		for _, tc := range goldenCases {
+			t.Run(format+"/"+tc.name, func(t *testing.T) {
+				var buf bytes.Buffer
+
+				cfg := tc.cfg
+				cfg.Directory = filepath.Join(root, tc.subdir)
+				if tc.relativeTo {
+					cfg.RelativeTo = root
+				}
+				cfg.OutputFormat = OutputFormat(format)
+				cfg.Output = &buf
+
+				app, err := New(&cfg)
+				if err != nil {
+					t.Fatalf("New() unexpected error: %v", err)
+				}
+				if err := app.Run(context.Background()); err != nil {
+					t.Fatalf("Run() unexpected error: %v", err)
+				}
+
+				golden := filepath.Join("testdata", "golden", tc.name+"."+format+".golden")
+				testutil.AssertGolden(t, golden, buf.String())
+			})
+		}
+	}
+}
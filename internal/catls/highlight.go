@@ -0,0 +1,178 @@
+package catls
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ansiInverse and ansiNoInverse switch inverse video on and off, leaving
+// other attributes, such as syntax highlighting colors, alone.
+const (
+	ansiInverse   = "\x1b[7m"
+	ansiNoInverse = "\x1b[27m"
+)
+
+// MatchSpan is a range of a line's Content, in bytes, that a content pattern
+// matched.
+type MatchSpan struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// mergeSpans sorts spans and merges those that overlap or touch, so each
+// character is highlighted once and neighbouring matches read as one.
+func mergeSpans(spans []MatchSpan) []MatchSpan {
+	if len(spans) < 2 {
+		return spans
+	}
+	slices.SortFunc(spans, func(a, b MatchSpan) int {
+		return a.Start - b.Start
+	})
+
+	merged := spans[:1]
+	for _, span := range spans[1:] {
+		last := &merged[len(merged)-1]
+		if span.Start <= last.End {
+			last.End = max(last.End, span.End)
+
+			continue
+		}
+		merged = append(merged, span)
+	}
+
+	return merged
+}
+
+// clipSpans returns the parts of spans within content[from:to], relative to
+// from.
+func clipSpans(spans []MatchSpan, from, to int) []MatchSpan {
+	var clipped []MatchSpan
+	for _, span := range spans {
+		if span.End > from && span.Start < to {
+			clipped = append(clipped, MatchSpan{Start: max(span.Start, from) - from, End: min(span.End, to) - from})
+		}
+	}
+
+	return clipped
+}
+
+// highlightSpans renders content with escape applied to the text outside
+// spans and mark to the text inside them. Without spans it returns
+// escape(content), so lines no pattern matched are rendered as before.
+func highlightSpans(content string, spans []MatchSpan, escape, mark func(string) string) string {
+	if len(spans) == 0 {
+		return escape(content)
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, span := range spans {
+		b.WriteString(escape(content[last:span.Start]))
+		b.WriteString(mark(content[span.Start:span.End]))
+		last = span.End
+	}
+	b.WriteString(escape(content[last:]))
+
+	return b.String()
+}
+
+// plainText returns s as it is, for content written without escaping.
+func plainText(s string) string {
+	return s
+}
+
+// matchedLines lists the numbers of the lines a content pattern matched, as
+// "line 3" or "lines 3, 7", or returns "" when none did. A folded line is
+// listed once.
+func matchedLines(lines []FilteredLine) string {
+	var numbers []string
+	last := 0
+	for _, line := range lines {
+		if len(line.Matches) == 0 || line.LineNumber == last {
+			continue
+		}
+		numbers = append(numbers, strconv.Itoa(line.LineNumber))
+		last = line.LineNumber
+	}
+
+	switch len(numbers) {
+	case 0:
+		return ""
+	case 1:
+		return "line " + numbers[0]
+	default:
+		return "lines " + strings.Join(numbers, ", ")
+	}
+}
+
+// xmlMatch returns a function wrapping matched text, escaped by escape, in a
+// <match> element. Inside a CDATA section the section is closed around the
+// element, since markup there is text.
+func xmlMatch(cdata bool) func(string) string {
+	if cdata {
+		return func(text string) string {
+			return "]]><match><![CDATA[" + escapeCDATA(text) + "]]></match><![CDATA["
+		}
+	}
+
+	return func(text string) string {
+		return "<match>" + escapeXMLText(text) + "</match>"
+	}
+}
+
+// invertSpans shows spans of the visible text of s in inverse video. s may
+// already hold ANSI escape sequences, such as those of syntax highlighting;
+// they take no room in the offsets, and inverse video is switched back on
+// after each one inside a span, in case it was a reset.
+func invertSpans(s string, spans []MatchSpan) string {
+	if len(spans) == 0 {
+		return s
+	}
+
+	var b strings.Builder
+	visible, next, inside := 0, 0, false
+	for i := 0; i < len(s); {
+		if inside && visible == spans[next].End {
+			b.WriteString(ansiNoInverse)
+			inside = false
+			next++
+		}
+		if n := ansiSequenceLength(s[i:]); n > 0 {
+			b.WriteString(s[i : i+n])
+			if inside {
+				b.WriteString(ansiInverse)
+			}
+			i += n
+
+			continue
+		}
+
+		if !inside && next < len(spans) && visible == spans[next].Start {
+			b.WriteString(ansiInverse)
+			inside = true
+		}
+		b.WriteByte(s[i])
+		visible++
+		i++
+	}
+	if inside {
+		b.WriteString(ansiNoInverse)
+	}
+
+	return b.String()
+}
+
+// ansiSequenceLength returns the length of the SGR escape sequence s starts
+// with, or 0 when it does not start with one.
+func ansiSequenceLength(s string) int {
+	if !strings.HasPrefix(s, "\x1b[") {
+		return 0
+	}
+	end := strings.IndexByte(s, 'm')
+	if end < 0 || strings.Trim(s[2:end], "0123456789;") != "" {
+		return 0
+	}
+
+	return end + 1
+}
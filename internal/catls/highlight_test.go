@@ -0,0 +1,173 @@
+package catls
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMatchingPatternsSpans(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		line     string
+		want     []MatchSpan
+	}{
+		{name: "every match", patterns: []string{"*ab*"}, line: "ab ab", want: []MatchSpan{{0, 2}, {3, 5}}},
+		{name: "overlapping merged", patterns: []string{"re:abc", "re:bcd"}, line: "xabcdx", want: []MatchSpan{{1, 5}}},
+		{name: "touching merged", patterns: []string{"re:ab", "re:cd"}, line: "abcd", want: []MatchSpan{{0, 4}}},
+		{name: "nested merged", patterns: []string{"re:b", "re:abc"}, line: "abc", want: []MatchSpan{{0, 3}}},
+		{name: "empty match has no span", patterns: []string{"re:^"}, line: "abc", want: nil},
+		{name: "byte offsets", patterns: []string{"re:ö+"}, line: "héllo wöörld", want: []MatchSpan{{8, 12}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := NewFileFilter(&Config{ContentPatterns: tt.patterns})
+			lines := filter.FilterContent([]string{tt.line})
+			if len(lines) != 1 {
+				t.Fatalf("FilterContent() kept %d lines, want 1", len(lines))
+			}
+			if !reflect.DeepEqual(lines[0].Matches, tt.want) {
+				t.Errorf("Matches = %v, want %v", lines[0].Matches, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatternHighlighting(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"main.go": "a := x*y // TODO: x*y\nb := 1\n",
+	})
+
+	tests := []struct {
+		name    string
+		format  OutputFormat
+		options map[string]string // FormatOptions
+		color   bool
+		want    string
+	}{
+		{name: "xml", format: OutputFormatXML, want: "a := <match>x*y</match> // TODO: <match>x*y</match>\n"},
+		{name: "xml cdata", format: OutputFormatXML, options: map[string]string{"cdata": "true"}, want: "a := ]]><match><![CDATA[x*y]]></match><![CDATA[ // TODO:"},
+		{name: "markdown", format: OutputFormatMarkdown, want: "a := x*y // TODO: x*y\n```\n\nMatches: line 1\n"},
+		{name: "json", format: OutputFormatJSON, want: `"matches": [
+            {
+              "start": 5,
+              "end": 8
+            },
+            {
+              "start": 18,
+              "end": 21
+            }
+          ]`},
+		{name: "pretty", format: OutputFormatPretty, color: true, want: "// TODO: \x1b[7mx*y\x1b[27m"},
+		{name: "pretty without color", format: OutputFormatPretty, want: "a := x*y // TODO: x*y\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			cfg := &Config{
+				Directory:       tmpDir,
+				OutputFormat:    tt.format,
+				FormatOptions:   tt.options,
+				Output:          &buf,
+				Color:           tt.color,
+				ContentPatterns: []string{"re:x\\*y"},
+			}
+			runApp(t, cfg)
+			if !strings.Contains(buf.String(), tt.want) {
+				t.Errorf("output does not contain %q:\n%s", tt.want, buf.String())
+			}
+		})
+	}
+}
+
+func TestHighlightingWithoutPattern(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{"main.go": "a := x*y\n"})
+
+	for _, format := range []OutputFormat{OutputFormatXML, OutputFormatMarkdown, OutputFormatJSON} {
+		var buf bytes.Buffer
+		runApp(t, &Config{Directory: tmpDir, OutputFormat: format, Output: &buf})
+		if out := buf.String(); strings.Contains(out, "match") || strings.Contains(out, "**") {
+			t.Errorf("%s output is highlighted without a pattern:\n%s", format, out)
+		}
+	}
+}
+
+func TestMatchedLines(t *testing.T) {
+	match := []MatchSpan{{0, 1}}
+	tests := []struct {
+		name  string
+		lines []FilteredLine
+		want  string
+	}{
+		{name: "none", lines: []FilteredLine{{LineNumber: 1}}, want: ""},
+		{name: "one", lines: []FilteredLine{{LineNumber: 1}, {LineNumber: 2, Matches: match}}, want: "line 2"},
+		{
+			name:  "folded line once",
+			lines: []FilteredLine{{LineNumber: 3, Matches: match}, {LineNumber: 3, Continued: true, Matches: match}, {LineNumber: 7, Matches: match}},
+			want:  "lines 3, 7",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchedLines(tt.lines); got != tt.want {
+				t.Errorf("matchedLines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInvertSpans(t *testing.T) {
+	tests := []struct {
+		name  string
+		s     string
+		spans []MatchSpan
+		want  string
+	}{
+		{name: "plain", s: "abcd", spans: []MatchSpan{{1, 3}}, want: "a\x1b[7mbc\x1b[27md"},
+		{name: "to the end", s: "abcd", spans: []MatchSpan{{2, 4}}, want: "ab\x1b[7mcd\x1b[27m"},
+		{
+			name:  "highlighted",
+			s:     "\x1b[31mab\x1b[0mcd",
+			spans: []MatchSpan{{1, 3}},
+			want:  "\x1b[31ma\x1b[7mb\x1b[0m\x1b[7mc\x1b[27md",
+		},
+		{
+			name:  "reset before the span",
+			s:     "a\x1b[0m\x1b[32mb\x1b[0m",
+			spans: []MatchSpan{{1, 2}},
+			want:  "a\x1b[0m\x1b[32m\x1b[7mb\x1b[27m\x1b[0m",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := invertSpans(tt.s, tt.spans); got != tt.want {
+				t.Errorf("invertSpans() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFoldSplitsMatches(t *testing.T) {
+	file := ProcessedFile{Lines: []FilteredLine{
+		{LineNumber: 1, Content: "0123456789abcdef", Matches: []MatchSpan{{2, 4}, {7, 12}}},
+	}}
+	foldFile(&file, 6)
+
+	want := [][]MatchSpan{{{2, 4}}, {{2, 5}}, {{0, 2}}}
+	if len(file.Lines) != len(want) {
+		t.Fatalf("foldFile() made %d segments, want %d: %+v", len(file.Lines), len(want), file.Lines)
+	}
+	for i, line := range file.Lines {
+		if !reflect.DeepEqual(line.Matches, want[i]) {
+			t.Errorf("segment %d (%q) Matches = %v, want %v", i, line.Content, line.Matches, want[i])
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package catls
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+func TestIncludeAndTransformHooks(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"keep.go":        "package keep\n",
+		"team-b.go":      "package teamb\n",
+		"notes.txt":      "secret=hunter2\n",
+		"ignored.log":    "log line\n",
+		"sub/nested.txt": "token=abc\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create %s: %v", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	included := make(map[string]int)
+	transformed := make(map[string]int)
+	var buf bytes.Buffer
+	app, err := New(&Config{
+		Directory:    dir,
+		Recursive:    true,
+		OutputFormat: OutputFormatJSON,
+		Output:       &buf,
+		IgnoreGlobs:  []string{"*.log"},
+		IncludeFunc: func(file scanner.FileInfo) bool {
+			included[filepath.ToSlash(file.RelPath)]++
+
+			return !strings.HasPrefix(file.RelPath, "team-b")
+		},
+		TransformFunc: func(file *ProcessedFile) {
+			transformed[filepath.ToSlash(file.Info.RelPath)]++
+			for i, line := range file.Lines {
+				if key, _, ok := strings.Cut(line.Content, "="); ok {
+					file.Lines[i].Content = key + "=REDACTED"
+				}
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	// The ignored log never reaches the hook, and each other file reaches it once
+	wantIncluded := map[string]int{"keep.go": 1, "team-b.go": 1, "notes.txt": 1, "sub/nested.txt": 1}
+	if !reflect.DeepEqual(included, wantIncluded) {
+		t.Errorf("IncludeFunc calls = %v, want %v", included, wantIncluded)
+	}
+	wantTransformed := map[string]int{"keep.go": 1, "notes.txt": 1, "sub/nested.txt": 1}
+	if !reflect.DeepEqual(transformed, wantTransformed) {
+		t.Errorf("TransformFunc calls = %v, want %v", transformed, wantTransformed)
+	}
+
+	out := buf.String()
+	for _, want := range []string{`"secret=REDACTED"`, `"token=REDACTED"`, `"package keep"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %s:\n%s", want, out)
+		}
+	}
+	for _, unwanted := range []string{"team-b.go", "hunter2", "ignored.log"} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("output contains %s:\n%s", unwanted, out)
+		}
+	}
+
+	explanation, err := app.Explain(filepath.Join(dir, "team-b.go"))
+	if err != nil {
+		t.Fatalf("Explain() unexpected error: %v", err)
+	}
+	if decision, ok := explanation.Decision(); !ok || decision.Rule != "include hook" {
+		t.Errorf("Explain() decision = %v, want the include hook", decision)
+	}
+}
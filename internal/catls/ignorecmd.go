@@ -0,0 +1,111 @@
+package catls
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+// IgnoreCmdTimeout bounds how long IgnoreCmd may run.
+const IgnoreCmdTimeout = time.Minute
+
+// applyIgnoreCmd runs IgnoreCmd once with the relative paths of the selected
+// files on stdin, each ended by a NUL byte, and drops the files whose paths
+// it prints, separated by NUL bytes or newlines. A command that fails or
+// runs past IgnoreCmdTimeout aborts the run with its stderr. IgnoreCmd is
+// split on whitespace without honoring quotes, so arguments holding spaces
+// belong in a script.
+func (a *App) applyIgnoreCmd(ctx context.Context, files []scanner.FileInfo) ([]scanner.FileInfo, error) {
+	args := strings.Fields(a.cfg.IgnoreCmd)
+	if len(args) == 0 {
+		return files, nil
+	}
+
+	var stdin bytes.Buffer
+	for _, file := range files {
+		if !file.IsDir {
+			stdin.WriteString(file.RelPath + "\x00")
+		}
+	}
+
+	// A command given by path is relative to where catls runs, not to
+	// Directory, which the command runs in
+	name := args[0]
+	if strings.ContainsRune(name, '/') || strings.ContainsRune(name, filepath.Separator) {
+		abs, err := filepath.Abs(name)
+		if err != nil {
+			return nil, fmt.Errorf("--ignore-cmd %s: %w", name, err)
+		}
+		name = abs
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, IgnoreCmdTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, name, args[1:]...)
+	cmd.Dir = a.cfg.Directory
+	cmd.Stdin = &stdin
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s", IgnoreCmdTimeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("--ignore-cmd %s: %w: %s", args[0], err, msg)
+		}
+
+		return nil, fmt.Errorf("--ignore-cmd %s: %w", args[0], err)
+	}
+
+	ignored := make(map[string]bool)
+	for _, path := range splitCmdOutput(stdout.String()) {
+		ignored[path] = true
+	}
+
+	kept := files[:0]
+	for _, file := range files {
+		if file.IsDir || !ignored[file.RelPath] {
+			kept = append(kept, file)
+		}
+	}
+	if a.cfg.Debug {
+		fmt.Fprintf(a.warn, "Debug: --ignore-cmd excluded %d of %d files\n", len(files)-len(kept), len(files))
+	}
+
+	return kept, nil
+}
+
+// splitCmdOutput splits the output of IgnoreCmd into paths, on NUL bytes if
+// it has any and on newlines otherwise.
+func splitCmdOutput(output string) []string {
+	sep := "\n"
+	if strings.Contains(output, "\x00") {
+		sep = "\x00"
+	}
+
+	var paths []string
+	for path := range strings.SplitSeq(output, sep) {
+		if path = strings.TrimSuffix(path, "\r"); path != "" {
+			paths = append(paths, path)
+		}
+	}
+
+	return paths
+}
+
+// validateIgnoreCmd requires library callers to opt in to running a command.
+func (c *Config) validateIgnoreCmd() error {
+	if c.IgnoreCmd != "" && !c.AllowIgnoreCmd {
+		return errors.New("IgnoreCmd runs a command and requires AllowIgnoreCmd")
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package catls
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestIgnoreCmd(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the ignore command is a shell script")
+	}
+
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"keep.go":           "package keep",
+		"gen/schema.go":     "package gen",
+		"gen/schema_gen.go": "package gen",
+	})
+	bin := t.TempDir()
+	script := func(name, body string) string {
+		t.Helper()
+		path := filepath.Join(bin, name)
+		if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0o755); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+
+		return path
+	}
+
+	run := func(cfg *Config) (string, error) {
+		t.Helper()
+		var buf bytes.Buffer
+		cfg.Directory = tmpDir
+		cfg.Recursive = true
+		cfg.OutputFormat = OutputFormatXML
+		cfg.Output = &buf
+		app, err := New(cfg)
+		if err != nil {
+			return "", err
+		}
+		err = app.Run(context.Background())
+
+		return buf.String(), err
+	}
+
+	// Only the paths under gen/ reach the script's output
+	exclude := script("exclude", `tr '\0' '\n' | grep '_gen\.go$'`)
+	out, err := run(&Config{IgnoreCmd: exclude, AllowIgnoreCmd: true})
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	for _, want := range []string{"keep.go", "gen/schema.go"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %s:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "schema_gen.go") {
+		t.Errorf("output includes the file the command excluded:\n%s", out)
+	}
+
+	// A relative command path is found from the working directory, not the
+	// scanned one the command runs in
+	t.Chdir(bin)
+	out, err = run(&Config{IgnoreCmd: "./exclude", AllowIgnoreCmd: true})
+	if err != nil {
+		t.Fatalf("Run() with a relative command unexpected error: %v", err)
+	}
+	if strings.Contains(out, "schema_gen.go") {
+		t.Errorf("output includes the file the relative command excluded:\n%s", out)
+	}
+
+	failing := script("fail", "echo 'no manifest here' >&2; exit 2")
+	if _, err := run(&Config{IgnoreCmd: failing, AllowIgnoreCmd: true}); err == nil || !strings.Contains(err.Error(), "no manifest here") {
+		t.Errorf("Run() error = %v, want the command's stderr", err)
+	}
+
+	if _, err := run(&Config{IgnoreCmd: exclude}); err == nil || !strings.Contains(err.Error(), "AllowIgnoreCmd") {
+		t.Errorf("Run() error = %v, want IgnoreCmd refused without AllowIgnoreCmd", err)
+	}
+}
+
+func TestSplitCmdOutput(t *testing.T) {
+	tests := []struct {
+		output string
+		want   []string
+	}{
+		{"", nil},
+		{"a.go\nb c.go\r\n", []string{"a.go", "b c.go"}},
+		{"a.go\x00line\nbreak.go\x00", []string{"a.go", "line\nbreak.go"}},
+	}
+	for _, tt := range tests {
+		got := splitCmdOutput(tt.output)
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+			t.Errorf("splitCmdOutput(%q) = %q, want %q", tt.output, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package catls
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+// IgnoreFileName is the ignore file the command line reads from the scanned
+// directory, and that init-ignore writes.
+const IgnoreFileName = ".catlsignore"
+
+// largeDirShare is the share of a tree's bytes above which a top-level
+// directory is suggested, commented out, as worth ignoring.
+const largeDirShare = 0.25
+
+// ignoreHeuristic is a glob that, when it matches files, is likely worth
+// ignoring for the reason given.
+type ignoreHeuristic struct {
+	pattern string
+	reason  string
+}
+
+// ignoreHeuristics are the globs SuggestIgnores tries, in order; a file is
+// counted for the first one it matches. Directories already skipped by
+// default, such as node_modules and vendor, are not repeated here, and the
+// lockfiles are those SummarizeLockfiles knows.
+var ignoreHeuristics = slices.Concat([]ignoreHeuristic{
+	{"bower_components/**", "dependency directory"},
+	{"jspm_packages/**", "dependency directory"},
+	{"elm-stuff/**", "dependency directory"},
+	{"site-packages/**", "dependency directory"},
+	{"third_party/**", "vendored dependencies"},
+	{"Pods/**", "dependency directory"},
+	{"Carthage/**", "dependency directory"},
+	{"_build/**", "build output"},
+	{"DerivedData/**", "build output"},
+	{"__generated__/**", "generated code"},
+	{"__snapshots__/**", "test snapshots"},
+}, lockfileHeuristics(), []ignoreHeuristic{
+	{"*.pb.go", "generated code"},
+	{"*_pb2.py", "generated code"},
+	{"*.pb.cc", "generated code"},
+	{"*.pb.h", "generated code"},
+	{"*_generated.go", "generated code"},
+	{"*.gen.go", "generated code"},
+	{"*.g.dart", "generated code"},
+	{"*.min.js", "minified code"},
+	{"*.min.css", "minified code"},
+	{"*.map", "source maps"},
+})
+
+// lockfileHeuristics returns a heuristic for each of lockfileFormats, in
+// name order.
+func lockfileHeuristics() []ignoreHeuristic {
+	names := slices.Sorted(maps.Keys(lockfileFormats))
+	heuristics := make([]ignoreHeuristic, len(names))
+	for i, name := range names {
+		heuristics[i] = ignoreHeuristic{name, "lockfile"}
+	}
+
+	return heuristics
+}
+
+// IgnoreReport lists the globs SuggestIgnores found worth ignoring.
+type IgnoreReport struct {
+	Files       int                `json:"files"`
+	Bytes       int64              `json:"bytes"`
+	Suggestions []IgnoreSuggestion `json:"suggestions"`
+}
+
+// IgnoreSuggestion is one glob of an IgnoreReport with what it would save.
+type IgnoreSuggestion struct {
+	Pattern string `json:"pattern"`
+	Reason  string `json:"reason"`
+	Files   int    `json:"files"`
+	Bytes   int64  `json:"bytes"`
+	// Optional suggestions, such as merely large directories, are written
+	// commented out for the user to decide on.
+	Optional bool `json:"optional,omitempty"`
+}
+
+// SuggestIgnores scans like Estimate, from file metadata alone, and groups
+// the files that dependency and build directories, lockfiles, and generated
+// code heuristics match, largest first. Top-level directories holding a large
+// share of the bytes are suggested as optional.
+func (a *App) SuggestIgnores(ctx context.Context) (IgnoreReport, error) {
+	files, _, err := a.scanFiles(ctx, true)
+	if err != nil {
+		return IgnoreReport{}, err
+	}
+
+	report := IgnoreReport{Suggestions: []IgnoreSuggestion{}}
+	byPattern := make(map[string]*IgnoreSuggestion)
+	byDir := make(map[string]*IgnoreSuggestion)
+
+	for _, file := range files {
+		if file.IsDir {
+			continue
+		}
+		report.Files++
+		report.Bytes += file.Size
+
+		if h, ok := matchIgnoreHeuristic(filepath.ToSlash(file.RelPath)); ok {
+			if byPattern[h.pattern] == nil {
+				byPattern[h.pattern] = &IgnoreSuggestion{Pattern: h.pattern, Reason: h.reason}
+			}
+			byPattern[h.pattern].Files++
+			byPattern[h.pattern].Bytes += file.Size
+
+			continue
+		}
+
+		if top, _, nested := strings.Cut(filepath.ToSlash(file.RelPath), "/"); nested {
+			if byDir[top] == nil {
+				byDir[top] = &IgnoreSuggestion{Pattern: top + "/**", Optional: true}
+			}
+			byDir[top].Files++
+			byDir[top].Bytes += file.Size
+		}
+	}
+
+	for _, suggestion := range byPattern {
+		report.Suggestions = append(report.Suggestions, *suggestion)
+	}
+	for _, dir := range byDir {
+		share := float64(dir.Bytes) / float64(max(report.Bytes, 1))
+		if share < largeDirShare {
+			continue
+		}
+		dir.Reason = fmt.Sprintf("large directory, %.0f%% of bytes", share*100)
+		report.Suggestions = append(report.Suggestions, *dir)
+	}
+	sort.Slice(report.Suggestions, func(i, j int) bool {
+		si, sj := report.Suggestions[i], report.Suggestions[j]
+		if si.Optional != sj.Optional {
+			return !si.Optional
+		}
+		if si.Bytes != sj.Bytes {
+			return si.Bytes > sj.Bytes
+		}
+
+		return si.Pattern < sj.Pattern
+	})
+
+	return report, nil
+}
+
+// matchIgnoreHeuristic returns the first heuristic whose glob matches
+// relPath, matching as the ignore globs it would be written as do.
+func matchIgnoreHeuristic(relPath string) (ignoreHeuristic, bool) {
+	for _, h := range ignoreHeuristics {
+		if scanner.MatchesGlobPattern(relPath, h.pattern) {
+			return h, true
+		}
+	}
+
+	return ignoreHeuristic{}, false
+}
+
+// WriteIgnoreFile writes report as an ignore file, each glob after a comment
+// giving its reason and what it saves. Optional globs are commented out.
+func WriteIgnoreFile(w io.Writer, report IgnoreReport) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# Suggested by catls init-ignore from %d files (%d bytes).\n", report.Files, report.Bytes)
+	b.WriteString("# Each line is an ignore glob, as for --ignore-globs. Delete a line to\n")
+	b.WriteString("# include those files again, or uncomment one to skip them.\n")
+
+	for _, s := range report.Suggestions {
+		noun := "files"
+		if s.Files == 1 {
+			noun = "file"
+		}
+		fmt.Fprintf(&b, "\n# %s: saves %d bytes in %d %s\n", s.Reason, s.Bytes, s.Files, noun)
+		if s.Optional {
+			b.WriteString("# ")
+		}
+		b.WriteString(s.Pattern + "\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+// ReadIgnoreFile returns the globs of an ignore file, one per line. Blank
+// lines and lines starting with "#" are skipped.
+func ReadIgnoreFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []string
+	lines := bufio.NewScanner(f)
+	for lines.Scan() {
+		line := strings.TrimSpace(lines.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	if err := lines.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	return patterns, nil
+}
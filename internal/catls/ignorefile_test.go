@@ -0,0 +1,71 @@
+package catls
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSuggestIgnores(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"main.go":                      "package main",
+		"go.sum":                       strings.Repeat("s", 300),
+		"web/yarn.lock":                strings.Repeat("y", 200),
+		"web/app.min.js":               strings.Repeat("m", 150),
+		"web/app.js":                   "app()",
+		"api/api.pb.go":                strings.Repeat("p", 100),
+		"api/api.go":                   "package api",
+		"assets/bower_components/x.js": strings.Repeat("b", 400),
+		"assets/logo.svg":              strings.Repeat("l", 1000),
+	})
+
+	app, err := New(&Config{Directory: tmpDir, Recursive: true, OutputFormat: OutputFormatXML})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	report, err := app.SuggestIgnores(context.Background())
+	if err != nil {
+		t.Fatalf("SuggestIgnores() unexpected error: %v", err)
+	}
+
+	want := []IgnoreSuggestion{
+		{Pattern: "bower_components/**", Reason: "dependency directory", Files: 1, Bytes: 400},
+		{Pattern: "go.sum", Reason: "lockfile", Files: 1, Bytes: 300},
+		{Pattern: "yarn.lock", Reason: "lockfile", Files: 1, Bytes: 200},
+		{Pattern: "*.min.js", Reason: "minified code", Files: 1, Bytes: 150},
+		{Pattern: "*.pb.go", Reason: "generated code", Files: 1, Bytes: 100},
+		{Pattern: "assets/**", Reason: "large directory, 46% of bytes", Files: 1, Bytes: 1000, Optional: true},
+	}
+	if report.Files != 9 || report.Bytes != 2178 || !reflect.DeepEqual(report.Suggestions, want) {
+		t.Errorf("SuggestIgnores() = %d files, %d bytes, %+v\nwant 9, 2178, %+v", report.Files, report.Bytes, report.Suggestions, want)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteIgnoreFile(&buf, report); err != nil {
+		t.Fatalf("WriteIgnoreFile() error = %v", err)
+	}
+	for _, line := range []string{
+		"# Suggested by catls init-ignore from 9 files (2178 bytes).\n",
+		"\n# dependency directory: saves 400 bytes in 1 file\nbower_components/**\n",
+		"\n# large directory, 46% of bytes: saves 1000 bytes in 1 file\n# assets/**\n",
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("WriteIgnoreFile() missing %q:\n%s", line, buf.String())
+		}
+	}
+
+	path := filepath.Join(tmpDir, IgnoreFileName)
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+	patterns, err := ReadIgnoreFile(path)
+	wantPatterns := []string{"bower_components/**", "go.sum", "yarn.lock", "*.min.js", "*.pb.go"}
+	if err != nil || !reflect.DeepEqual(patterns, wantPatterns) {
+		t.Errorf("ReadIgnoreFile() = %q, %v; want %q", patterns, err, wantPatterns)
+	}
+}
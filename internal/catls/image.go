@@ -0,0 +1,45 @@
+package catls
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/connerohnesorge/catls/internal/fdlimit"
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+// EmbeddedImage is the content of a binary image carried inline by EmbedImages.
+type EmbeddedImage struct {
+	MIME string // Detected from the file's magic number, never its extension
+	Data []byte
+}
+
+// DataURI returns the image as a base64 data: URI.
+func (i *EmbeddedImage) DataURI() string {
+	return "data:" + i.MIME + ";base64," + base64.StdEncoding.EncodeToString(i.Data)
+}
+
+// embedImage attaches the content of a binary image no larger than
+// EmbedImages. Files that are not recognized images, or cannot be read, keep
+// the binary placeholder.
+func (a *App) embedImage(file *ProcessedFile) {
+	if !file.Info.IsBinary || file.Info.Size > a.cfg.EmbedImages {
+		return
+	}
+
+	data, err := fdlimit.ReadFile(file.Info.Path)
+	if err != nil {
+		if a.cfg.Debug {
+			fmt.Fprintf(a.warn, "Debug: Failed to read image %s: %v\n", file.Info.RelPath, err)
+		}
+
+		return
+	}
+
+	mime := scanner.SniffImage(data)
+	if mime == "" || int64(len(data)) > a.cfg.EmbedImages {
+		return
+	}
+
+	file.Image = &EmbeddedImage{MIME: mime, Data: data}
+}
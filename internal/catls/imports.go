@@ -0,0 +1,94 @@
+package catls
+
+import (
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+
+	"github.com/connerohnesorge/catls/internal/fdlimit"
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+// importsVerdict applies Imports, which must be set: Go files are kept when
+// they import one of the listed packages, and other files only with
+// ImportsIncludeOthers.
+func importsVerdict(file scanner.FileInfo, cfg *Config) scanner.Verdict {
+	verdict := scanner.Verdict{Rule: "imports"}
+	if file.FileType != "go" {
+		verdict.Excluded = !cfg.ImportsIncludeOthers
+		verdict.Detail = "not a Go file"
+		if verdict.Excluded {
+			verdict.Detail += ", with --imports"
+		}
+
+		return verdict
+	}
+
+	imports, err := goImports(file.Path)
+	if err != nil {
+		verdict.Excluded, verdict.Detail = true, err.Error()
+
+		return verdict
+	}
+	for _, imported := range imports {
+		for _, target := range cfg.Imports {
+			if importMatches(imported, target) {
+				verdict.Detail = fmt.Sprintf("imports %q", imported)
+
+				return verdict
+			}
+		}
+	}
+	verdict.Excluded = true
+	verdict.Detail = "imports none of " + quoteList(cfg.Imports)
+
+	return verdict
+}
+
+// goImports returns the import paths of the Go file at path, parsing no
+// further than its import declarations. The file is read through fdlimit,
+// like every other content read, rather than by the parser.
+func goImports(path string) ([]string, error) {
+	src, err := fdlimit.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read imports: %w", err)
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), path, src, parser.ImportsOnly)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse imports: %w", err)
+	}
+
+	imports := make([]string, 0, len(file.Imports))
+	for _, spec := range file.Imports {
+		if imported, err := strconv.Unquote(spec.Path.Value); err == nil {
+			imports = append(imports, imported)
+		}
+	}
+
+	return imports, nil
+}
+
+// importMatches reports whether the import path imported is target or ends
+// with it after a slash, so "internal/scanner" matches the package's full
+// module path.
+func importMatches(imported, target string) bool {
+	return imported == target || strings.HasSuffix(imported, "/"+target)
+}
+
+// validateImports rejects empty Imports paths and ImportsIncludeOthers
+// without Imports.
+func (c *Config) validateImports() error {
+	for _, target := range c.Imports {
+		if strings.Trim(target, "/") != target || target == "" {
+			return fmt.Errorf("invalid --imports %q: expected an import path such as internal/scanner", target)
+		}
+	}
+	if c.ImportsIncludeOthers && len(c.Imports) == 0 {
+		return errors.New("--imports-include-others requires --imports")
+	}
+
+	return nil
+}
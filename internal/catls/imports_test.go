@@ -0,0 +1,129 @@
+package catls
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestImports(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"go.mod":                         "module example.com/fixture\n",
+		"main.go":                        "package main\n\nimport (\n\t\"fmt\"\n\n\tscan \"example.com/fixture/internal/scanner\"\n)\n\nfunc main() { fmt.Println(scan.X) }\n",
+		"internal/scanner/scanner.go":    "package scanner\n\nconst X = 1\n",
+		"internal/scanner/walk/walk.go":  "package walk\n\nimport . \"example.com/fixture/internal/scanner\"\n\nvar _ = X\n",
+		"internal/scannerutil/util.go":   "package scannerutil\n\nimport \"strings\"\n\nvar _ = strings.ToUpper\n",
+		"internal/other/other.go":        "package other\n\nimport _ \"example.com/fixture/internal/scannerutil\"\n",
+		"internal/other/vendored.go":     "package other\n\nimport \"example.com/vendor/internal/scanner\"\n",
+		"cmd/tool/broken.go":             "package main\n\nimport (\n\t\"example.com/fixture/internal/scanner\"\n",
+		"cmd/tool/README.md":             "# tool\n",
+		"internal/scanner/testdata/a.go": "package testdata\n\nimport \"os\"\n",
+	})
+
+	tests := []struct {
+		name          string
+		imports       []string
+		includeOthers bool
+		want          []string
+	}{
+		{
+			name:    "suffix match",
+			imports: []string{"internal/scanner"},
+			want:    []string{"internal/other/vendored.go", "internal/scanner/walk/walk.go", "main.go"},
+		},
+		{
+			name:    "full import path",
+			imports: []string{"example.com/fixture/internal/scanner"},
+			want:    []string{"internal/scanner/walk/walk.go", "main.go"},
+		},
+		{
+			name:    "not a partial name",
+			imports: []string{"scanner/walk", "util"},
+		},
+		{
+			name:    "any of several",
+			imports: []string{"os", "strings"},
+			want:    []string{"internal/scanner/testdata/a.go", "internal/scannerutil/util.go"},
+		},
+		{
+			name:          "include others",
+			imports:       []string{"internal/scannerutil"},
+			includeOthers: true,
+			want:          []string{"cmd/tool/README.md", "go.mod", "internal/other/other.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Directory:            root,
+				Recursive:            true,
+				OutputFormat:         OutputFormatJSON,
+				Imports:              tt.imports,
+				ImportsIncludeOthers: tt.includeOthers,
+			}
+			app, err := New(cfg)
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+
+			var got []string
+			for file, err := range app.Files(context.Background()) {
+				if err != nil {
+					t.Fatalf("Files() unexpected error: %v", err)
+				}
+				got = append(got, filepath.ToSlash(file.Info.RelPath))
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Files() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	app, err := New(&Config{Directory: root, Recursive: true, OutputFormat: OutputFormatJSON, Imports: []string{"internal/scanner"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	explanation, err := app.Explain(filepath.Join(root, "cmd", "tool", "broken.go"))
+	if err != nil {
+		t.Fatalf("Explain() unexpected error: %v", err)
+	}
+	if decision, excluded := explanation.Decision(); !excluded || decision.Rule != "imports" || !strings.HasPrefix(decision.Detail, "cannot parse imports") {
+		t.Errorf("Explain() decision = %v, want an imports parse error", decision)
+	}
+}
+
+func TestValidateImports(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{name: "off", cfg: Config{}},
+		{name: "path", cfg: Config{Imports: []string{"internal/scanner", "fmt"}}},
+		{name: "include others", cfg: Config{Imports: []string{"fmt"}, ImportsIncludeOthers: true}},
+		{name: "empty path", cfg: Config{Imports: []string{""}}, wantErr: `invalid --imports ""`},
+		{name: "leading slash", cfg: Config{Imports: []string{"/scanner"}}, wantErr: `invalid --imports "/scanner"`},
+		{name: "include others alone", cfg: Config{ImportsIncludeOthers: true}, wantErr: "requires --imports"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.validateImports()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateImports() unexpected error: %v", err)
+				}
+
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateImports() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
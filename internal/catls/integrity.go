@@ -0,0 +1,193 @@
+package catls
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"regexp"
+	"strconv"
+)
+
+// ErrIntegrityMismatch is returned by CheckIntegrity when a document's
+// content does not match its trailer.
+var ErrIntegrityMismatch = errors.New("integrity check failed")
+
+// ErrNoIntegrityTrailer is returned by CheckIntegrity for a document that
+// does not end with a trailer: it was cut short, or written without
+// Config.Integrity.
+var ErrNoIntegrityTrailer = errors.New("no integrity trailer found; the document is incomplete or was written without --integrity")
+
+// IntegrityTrailer closes a document written with Config.Integrity so its
+// receiver can tell whether it arrived whole.
+type IntegrityTrailer struct {
+	Files  int    `json:"files"`  // Files written, not counting directory records
+	Lines  int    `json:"lines"`  // Content lines of those files
+	SHA256 string `json:"sha256"` // Hex SHA-256 of every byte written before the trailer
+}
+
+// String formats the trailer as the XML comment and Markdown footnote
+// record it.
+func (t IntegrityTrailer) String() string {
+	return fmt.Sprintf("files=%d lines=%d sha256=%s", t.Files, t.Lines, t.SHA256)
+}
+
+// IntegrityWriter is implemented by formatters that can close their output
+// with an IntegrityTrailer. With Config.Integrity, App calls SetIntegrity
+// before WriteHeader, and the formatter calls trailer as the last thing it
+// writes, so the checksum covers everything before the trailer. A format
+// that does not implement it cannot be written with Config.Integrity.
+type IntegrityWriter interface {
+	SetIntegrity(trailer func() IntegrityTrailer)
+}
+
+// Trailers as each format writes them. The checksum covers the document up
+// to the first byte each pattern matches.
+var integrityTrailers = []*regexp.Regexp{
+	// XML and prompt output: a comment after the last element
+	regexp.MustCompile(`<!-- catls-integrity files=(\d+) lines=(\d+) sha256=([0-9a-f]{64}) -->\s*$`),
+	// Markdown: a footnote after the last file
+	regexp.MustCompile(`\n\[\^catls-integrity\]: files=(\d+) lines=(\d+) sha256=([0-9a-f]{64})\s*$`),
+}
+
+// jsonIntegrityTrailer matches the "integrity" field closing a JSON document.
+var jsonIntegrityTrailer = regexp.MustCompile(`,\s*"integrity":\s*(\{[^{}]*\})\s*\}\s*$`)
+
+// integrityComment renders trailer as the comment XML and prompt output end with.
+func integrityComment(trailer IntegrityTrailer) string {
+	return "<!-- catls-integrity " + trailer.String() + " -->\n"
+}
+
+// CheckIntegrity finds the trailer of a document written with
+// Config.Integrity and verifies the checksum of everything before it. It
+// returns the trailer, and ErrIntegrityMismatch when the document changed.
+func CheckIntegrity(document []byte) (IntegrityTrailer, error) {
+	start, trailer, err := findIntegrityTrailer(document)
+	if err != nil {
+		return IntegrityTrailer{}, err
+	}
+
+	sum := sha256.Sum256(document[:start])
+	if got := hex.EncodeToString(sum[:]); got != trailer.SHA256 {
+		return trailer, fmt.Errorf("%w: the content before the trailer has SHA-256 %s, but the trailer records %s", ErrIntegrityMismatch, got, trailer.SHA256)
+	}
+
+	return trailer, nil
+}
+
+// findIntegrityTrailer returns where the trailer of document starts and
+// what it records.
+func findIntegrityTrailer(document []byte) (int, IntegrityTrailer, error) {
+	for _, pattern := range integrityTrailers {
+		match := pattern.FindSubmatchIndex(document)
+		if match == nil {
+			continue
+		}
+
+		var trailer IntegrityTrailer
+		trailer.Files, _ = strconv.Atoi(string(document[match[2]:match[3]]))
+		trailer.Lines, _ = strconv.Atoi(string(document[match[4]:match[5]]))
+		trailer.SHA256 = string(document[match[6]:match[7]])
+
+		return match[0], trailer, nil
+	}
+
+	if match := jsonIntegrityTrailer.FindSubmatchIndex(document); match != nil {
+		var trailer IntegrityTrailer
+		if err := json.Unmarshal(document[match[2]:match[3]], &trailer); err != nil {
+			return 0, IntegrityTrailer{}, fmt.Errorf("invalid integrity field: %w", err)
+		}
+
+		return match[0], trailer, nil
+	}
+
+	return 0, IntegrityTrailer{}, ErrNoIntegrityTrailer
+}
+
+// integrityTee hashes what a formatter writes to Output, for the trailer.
+type integrityTee struct {
+	w    io.Writer
+	hash hash.Hash
+}
+
+// newIntegrityTee wraps out when Integrity is set, or returns nil.
+func newIntegrityTee(cfg *Config, out io.Writer) *integrityTee {
+	if !cfg.Integrity {
+		return nil
+	}
+
+	return &integrityTee{w: out, hash: sha256.New()}
+}
+
+// Write writes p and hashes the part of it that was written.
+func (t *integrityTee) Write(p []byte) (int, error) {
+	n, err := t.w.Write(p)
+	t.hash.Write(p[:n])
+
+	return n, err
+}
+
+// sum returns the hex SHA-256 of everything written so far.
+func (t *integrityTee) sum() string {
+	return hex.EncodeToString(t.hash.Sum(nil))
+}
+
+// integrityCounts counts what a run wrote for its trailers.
+type integrityCounts struct {
+	files int
+	lines int
+}
+
+// count records a written file.
+func (c *integrityCounts) count(file *ProcessedFile) {
+	if !file.Info.IsDir {
+		c.files++
+		c.lines += len(file.Lines)
+	}
+}
+
+// setIntegrity has formatter close its output, written through tee, with a
+// trailer counting what the run wrote. It fails for formats that cannot.
+func (a *App) setIntegrity(format OutputFormat, formatter OutputFormatter, tee *integrityTee) error {
+	if tee == nil {
+		return nil
+	}
+	writer, ok := formatter.(IntegrityWriter)
+	if !ok {
+		return fmt.Errorf("--integrity is not supported by %s output", format)
+	}
+
+	tee.hash.Reset()
+	writer.SetIntegrity(func() IntegrityTrailer {
+		return IntegrityTrailer{Files: a.written.files, Lines: a.written.lines, SHA256: tee.sum()}
+	})
+
+	return nil
+}
+
+// validateIntegrity rejects Integrity with List, which writes no document,
+// and with formats whose formatter is not an IntegrityWriter.
+func (c *Config) validateIntegrity() error {
+	if !c.Integrity {
+		return nil
+	}
+	if c.List {
+		return errors.New("--integrity does not apply to --list")
+	}
+
+	for _, format := range c.formats() {
+		formatter, err := NewOutputFormatter(format, io.Discard, nil)
+		if err != nil {
+			// Reported by the format check
+			continue
+		}
+		if _, ok := formatter.(IntegrityWriter); !ok {
+			return fmt.Errorf("--integrity is not supported by %s output", format)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package catls
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIntegrity(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"a.txt":     "one\ntwo\n",
+		"sub/b.go":  "package sub\n",
+		"empty.txt": "",
+	})
+
+	tests := []struct {
+		name    string
+		format  OutputFormat
+		options FormatOptions
+	}{
+		{name: "xml", format: OutputFormatXML},
+		{name: "json", format: OutputFormatJSON},
+		{name: "json on one line", format: OutputFormatJSON, options: FormatOptions{"pretty": "false"}},
+		{name: "markdown", format: OutputFormatMarkdown},
+		{name: "prompt", format: OutputFormatPrompt},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			app, err := New(&Config{
+				Directory:     root,
+				Recursive:     true,
+				IncludeDirs:   true,
+				OutputFormat:  tt.format,
+				FormatOptions: tt.options,
+				Output:        &out,
+				Integrity:     true,
+			})
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+			if err := app.Run(context.Background()); err != nil {
+				t.Fatalf("Run() unexpected error: %v", err)
+			}
+			document := out.Bytes()
+
+			trailer, err := CheckIntegrity(document)
+			if err != nil {
+				t.Fatalf("CheckIntegrity() unexpected error: %v\n%s", err, document)
+			}
+			if trailer.Files != 3 || trailer.Lines != 3 {
+				t.Errorf("trailer = %+v, want 3 files and 3 lines", trailer)
+			}
+
+			// Whitespace added after the document, as a paste may, is ignored
+			if _, err := CheckIntegrity(append(bytes.Clone(document), "\r\n\n"...)); err != nil {
+				t.Errorf("CheckIntegrity() with trailing whitespace: %v", err)
+			}
+
+			changed := bytes.Replace(document, []byte("two"), []byte("tw0"), 1)
+			if _, err := CheckIntegrity(changed); !errors.Is(err, ErrIntegrityMismatch) {
+				t.Errorf("CheckIntegrity() of a changed document = %v, want ErrIntegrityMismatch", err)
+			}
+
+			if _, err := CheckIntegrity(document[:len(document)-20]); !errors.Is(err, ErrNoIntegrityTrailer) {
+				t.Errorf("CheckIntegrity() of a truncated document = %v, want ErrNoIntegrityTrailer", err)
+			}
+		})
+	}
+
+	// Without Integrity there is no trailer to check
+	var out bytes.Buffer
+	app, err := New(&Config{Directory: root, OutputFormat: OutputFormatXML, Output: &out})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CheckIntegrity(out.Bytes()); !errors.Is(err, ErrNoIntegrityTrailer) {
+		t.Errorf("CheckIntegrity() without a trailer = %v, want ErrNoIntegrityTrailer", err)
+	}
+}
+
+// TestIntegrityOutputDir checks that each file of OutputDir carries the
+// checksum of its own content.
+func TestIntegrityOutputDir(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"a.txt": "one\ntwo\n"})
+	outDir := filepath.Join(t.TempDir(), "out")
+
+	app, err := New(&Config{
+		Directory:     root,
+		OutputFormat:  OutputFormatXML,
+		OutputFormats: []OutputFormat{OutputFormatXML, OutputFormatJSON, OutputFormatMarkdown},
+		OutputDir:     outDir,
+		Output:        &bytes.Buffer{},
+		Integrity:     true,
+	})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	for _, format := range []OutputFormat{OutputFormatXML, OutputFormatJSON, OutputFormatMarkdown} {
+		document, err := os.ReadFile(filepath.Join(outDir, OutputFileName(format)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if trailer, err := CheckIntegrity(document); err != nil || trailer.Files != 1 || trailer.Lines != 2 {
+			t.Errorf("%s: CheckIntegrity() = %+v, %v", format, trailer, err)
+		}
+	}
+}
+
+func TestValidateIntegrity(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{name: "xml", cfg: Config{Integrity: true, OutputFormat: OutputFormatXML}},
+		{name: "pretty", cfg: Config{Integrity: true, OutputFormat: OutputFormatPretty}, wantErr: "not supported by pretty output"},
+		{
+			name:    "chunks among several formats",
+			cfg:     Config{Integrity: true, OutputFormats: []OutputFormat{OutputFormatJSON, OutputFormatChunks}},
+			wantErr: "not supported by chunks output",
+		},
+		{name: "list", cfg: Config{Integrity: true, List: true}, wantErr: "does not apply to --list"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.validateIntegrity()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateIntegrity() unexpected error: %v", err)
+				}
+
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateIntegrity() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package catls
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/connerohnesorge/catls/internal/interactive"
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+// TestInteractiveOutputStream runs the selector and reorder TUIs on a
+// scripted terminal and checks that nothing they draw reaches the
+// document, which must start exactly where the formatter starts it.
+func TestInteractiveOutputStream(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"a.txt": "a\n", "b.txt": "b\n", "c.txt": "c\n"})
+
+	tests := []struct {
+		name     string
+		cfg      Config
+		keys     string
+		wantDoc  string
+		wantSkip string // A file the session leaves out
+	}{
+		{
+			name:     "selector xml",
+			cfg:      Config{Interactive: true, OutputFormat: OutputFormatXML},
+			keys:     "x\r", // Deselect a.txt
+			wantDoc:  "<files>\n<file path=\"b.txt\">",
+			wantSkip: "a.txt",
+		},
+		{
+			name:    "selector markdown",
+			cfg:     Config{Interactive: true, OutputFormat: OutputFormatMarkdown},
+			keys:    "\r",
+			wantDoc: "## a.txt\n",
+		},
+		{
+			name:    "reorder",
+			cfg:     Config{Order: true, OutputFormat: OutputFormatXML},
+			keys:    "\r",
+			wantDoc: "<files>\n<file path=\"a.txt\">",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc, term bytes.Buffer
+			cfg := tt.cfg
+			cfg.Directory, cfg.Output = root, &doc
+			app, err := New(&cfg)
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+			app.terminal = interactive.Terminal{In: strings.NewReader(tt.keys), Out: &term}
+
+			if err := app.Run(context.Background()); err != nil {
+				t.Fatalf("Run() unexpected error: %v", err)
+			}
+			if !strings.HasPrefix(doc.String(), tt.wantDoc) {
+				t.Errorf("document starts %q, want %q", doc.String()[:min(doc.Len(), 80)], tt.wantDoc)
+			}
+			if strings.Contains(doc.String(), "\x1b[") || strings.Contains(doc.String(), "Selected") {
+				t.Errorf("document holds terminal output: %q", doc.String())
+			}
+			if tt.wantSkip != "" && strings.Contains(doc.String(), tt.wantSkip) {
+				t.Errorf("document holds %s, which the session deselected", tt.wantSkip)
+			}
+			// The alternate screen was entered and left on the terminal
+			if !strings.Contains(term.String(), "\x1b[?1049h") || !strings.Contains(term.String(), "\x1b[?1049l") {
+				t.Errorf("terminal output lacks the alternate screen: %q", term.String())
+			}
+		})
+	}
+}
+
+func TestUnselectableReason(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"logo.png": "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR",
+		"tool.bin": "\x7fELF\x00\x00\x00\x00",
+	})
+	binary := scanner.FileInfo{Path: filepath.Join(dir, "logo.png"), RelPath: "logo.png", IsBinary: true, Size: 2048}
+	other := scanner.FileInfo{Path: filepath.Join(dir, "tool.bin"), RelPath: "tool.bin", IsBinary: true, Size: 2048}
+	tests := []struct {
+		name string
+		cfg  Config
+		file scanner.FileInfo
+		want string
+	}{
+		{name: "text", file: scanner.FileInfo{RelPath: "a.go"}},
+		{name: "binary", file: binary, want: "binary, written as a placeholder"},
+		{name: "embedded image", cfg: Config{EmbedImages: 4096}, file: binary},
+		{name: "image over embed limit", cfg: Config{EmbedImages: 1024}, file: binary, want: "binary, written as a placeholder"},
+		{name: "binary that is not an image", cfg: Config{EmbedImages: 4096}, file: other, want: "binary, written as a placeholder"},
+		{name: "base64", cfg: Config{ContentEncoding: ContentEncodingBase64}, file: binary},
+		{name: "over raw limit", cfg: Config{ContentEncoding: ContentEncodingBase64, RawContentMax: 1024}, file: binary, want: "binary, over --raw-max-size, written as a placeholder"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &App{cfg: &tt.cfg}
+			if got := app.unselectableReason(tt.file); got != tt.want {
+				t.Errorf("unselectableReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
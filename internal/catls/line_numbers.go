@@ -0,0 +1,139 @@
+package catls
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// LineNumberFormat represents a preset for rendering line-number gutters.
+type LineNumberFormat string
+
+const (
+	// LineNumberFormatPipe right-aligns the number and follows it with "| ".
+	LineNumberFormatPipe LineNumberFormat = "pipe"
+	// LineNumberFormatColon right-aligns the number and follows it with ": ".
+	LineNumberFormatColon LineNumberFormat = "colon"
+	// LineNumberFormatTab writes the bare number followed by a tab.
+	LineNumberFormatTab LineNumberFormat = "tab"
+	// LineNumberFormatPadded right-aligns the number and separates it from content with two spaces.
+	LineNumberFormatPadded LineNumberFormat = "padded"
+)
+
+// minGutterWidth keeps short files aligned the same way they always have been.
+const minGutterWidth = 4
+
+// String returns the string representation of the line number format.
+func (f LineNumberFormat) String() string {
+	return string(f)
+}
+
+// IsValid checks if the line number format is supported.
+func (f LineNumberFormat) IsValid() bool {
+	switch f {
+	case LineNumberFormatPipe, LineNumberFormatColon, LineNumberFormatTab, LineNumberFormatPadded:
+		return true
+	default:
+		return false
+	}
+}
+
+// GetSupportedLineNumberFormats returns a list of all supported line number formats.
+func GetSupportedLineNumberFormats() []string {
+	return []string{
+		LineNumberFormatPipe.String(),
+		LineNumberFormatColon.String(),
+		LineNumberFormatTab.String(),
+		LineNumberFormatPadded.String(),
+	}
+}
+
+// separator returns the text placed between the number and the line content.
+func (f LineNumberFormat) separator() string {
+	switch f {
+	case LineNumberFormatColon:
+		return ": "
+	case LineNumberFormatTab:
+		return "\t"
+	case LineNumberFormatPadded:
+		return "  "
+	case LineNumberFormatPipe:
+		return "| "
+	default:
+		return "| "
+	}
+}
+
+// lineGutter renders line prefixes for a single file. Every formatter that shows
+// line numbers goes through it so the gutter looks the same in all formats.
+type lineGutter struct {
+	enabled bool
+	format  LineNumberFormat
+	width   int
+}
+
+// newLineGutter sizes the gutter from the file's total line count so numbers stay
+// aligned no matter how long the file is.
+func newLineGutter(file *ProcessedFile, cfg *Config) lineGutter {
+	format := cfg.LineNumberFormat
+	if !format.IsValid() {
+		format = LineNumberFormatPipe
+	}
+
+	return lineGutter{
+		enabled: cfg.ShowLineNumbers,
+		format:  format,
+		width:   max(minGutterWidth, len(strconv.Itoa(max(file.TotalLines, 1)))),
+	}
+}
+
+// Line renders a content line with its gutter prefix, if line numbers are enabled.
+func (g lineGutter) Line(line FilteredLine, content string) string {
+	if !g.enabled {
+		return content
+	}
+	if line.Continued || line.Gap > 0 {
+		return g.Indent() + content
+	}
+
+	if g.format == LineNumberFormatTab {
+		return fmt.Sprintf("%d%s%s", line.LineNumber, g.format.separator(), content)
+	}
+
+	return fmt.Sprintf("%*d%s%s", g.width, line.LineNumber, g.format.separator(), content)
+}
+
+// Indent returns blank space matching the gutter so continuation text, such as
+// the truncation notice, lines up with the content column.
+func (g lineGutter) Indent() string {
+	if !g.enabled {
+		return ""
+	}
+
+	if g.format == LineNumberFormatTab {
+		return "\t"
+	}
+
+	return strings.Repeat(" ", g.width+len(g.format.separator()))
+}
+
+// TruncationNotice renders the in-band "(N more lines)" marker aligned with the
+// gutter, as written by LegacyTruncation. It returns an empty string when
+// nothing was cut.
+func (g lineGutter) TruncationNotice(file *ProcessedFile) string {
+	remaining := remainingLines(file)
+	if remaining == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("%s... (%d more lines)", g.Indent(), remaining)
+}
+
+// remainingLines returns how many lines truncation left out of the output.
+func remainingLines(file *ProcessedFile) int {
+	if !file.IsTruncated {
+		return 0
+	}
+
+	return max(0, file.TotalLines-len(file.Lines))
+}
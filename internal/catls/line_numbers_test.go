@@ -0,0 +1,59 @@
+package catls
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLineGutter(t *testing.T) {
+	tests := []struct {
+		name       string
+		format     LineNumberFormat
+		totalLines int
+		lineNumber int
+		want       string
+	}{
+		{name: "pipe keeps four-column minimum", format: LineNumberFormatPipe, totalLines: 12, lineNumber: 7, want: "   7| x"},
+		{name: "pipe widens past 9999 lines", format: LineNumberFormatPipe, totalLines: 12345, lineNumber: 7, want: "    7| x"},
+		{name: "colon", format: LineNumberFormatColon, totalLines: 10, lineNumber: 3, want: "   3: x"},
+		{name: "tab is unpadded", format: LineNumberFormatTab, totalLines: 100000, lineNumber: 3, want: "3\tx"},
+		{name: "padded", format: LineNumberFormatPadded, totalLines: 10, lineNumber: 10, want: "  10  x"},
+		{name: "empty format falls back to pipe", format: "", totalLines: 1, lineNumber: 1, want: "   1| x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := &ProcessedFile{TotalLines: tt.totalLines}
+			gutter := newLineGutter(file, &Config{ShowLineNumbers: true, LineNumberFormat: tt.format})
+
+			got := gutter.Line(FilteredLine{LineNumber: tt.lineNumber, Content: "x"}, "x")
+			if got != tt.want {
+				t.Errorf("Line() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLineGutterTruncationNoticeAligned(t *testing.T) {
+	file := &ProcessedFile{
+		Lines:       []FilteredLine{{LineNumber: 1, Content: "first"}},
+		TotalLines:  20000,
+		IsTruncated: true,
+	}
+
+	gutter := newLineGutter(file, &Config{ShowLineNumbers: true, LineNumberFormat: LineNumberFormatPipe})
+	line := gutter.Line(file.Lines[0], "first")
+	notice := gutter.TruncationNotice(file)
+
+	if got, want := strings.Index(notice, "..."), strings.Index(line, "first"); got != want {
+		t.Errorf("notice starts at column %d, content starts at column %d\nline:   %q\nnotice: %q", got, want, line, notice)
+	}
+	if !strings.Contains(notice, "(19999 more lines)") {
+		t.Errorf("TruncationNotice() = %q, want remaining count", notice)
+	}
+
+	plain := newLineGutter(file, &Config{})
+	if got := plain.TruncationNotice(file); got != "... (19999 more lines)" {
+		t.Errorf("TruncationNotice() without line numbers = %q", got)
+	}
+}
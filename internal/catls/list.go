@@ -0,0 +1,51 @@
+package catls
+
+import (
+	"bufio"
+	"fmt"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+// writeList writes the path of every selected file for List, each followed by
+// a newline or, with Print0, a NUL byte. Paths are written as scanned, joined
+// to Directory, so they can be opened from the working directory. SkipEmpty
+// and the ReadmeFirst order still apply; no file content is read otherwise.
+func (a *App) writeList(files []scanner.FileInfo) error {
+	defer a.saveDetectCache()
+
+	terminator := byte('\n')
+	if a.cfg.Print0 {
+		terminator = 0
+	}
+	if a.cfg.ReadmeFirst {
+		files = readmeFirst(files)
+	}
+
+	w := bufio.NewWriter(a.out)
+	for i, file := range files {
+		a.startFile(i, len(files))
+		if !file.IsDir && a.shouldSkipEmpty(file) {
+			continue
+		}
+		if file.IsDir {
+			a.stats.Dirs++
+		} else {
+			a.stats.Files++
+		}
+		a.fileProcessed(file, &ProcessedFile{Info: file}, "")
+
+		if _, err := w.WriteString(file.Path); err != nil {
+			return fmt.Errorf("failed to write file list: %w", err)
+		}
+		if err := w.WriteByte(terminator); err != nil {
+			return fmt.Errorf("failed to write file list: %w", err)
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write file list: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,339 @@
+package catls
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/connerohnesorge/catls/internal/fdlimit"
+	"github.com/connerohnesorge/catls/internal/scanner"
+	"gopkg.in/yaml.v3"
+)
+
+// lockfileParseMax is the largest lockfile SummarizeLockfiles parses for
+// its dependencies. Larger ones are summarized by size and hash alone.
+const lockfileParseMax = 64 << 20
+
+// errNoEntries reports a lockfile in which no dependency entries were found.
+var errNoEntries = errors.New("no dependency entries found")
+
+// LockfileSummary stands in for the content of a lockfile with
+// SummarizeLockfiles. When Parsed is false the lockfile could not be read
+// for its dependencies and only its size and hash are known.
+type LockfileSummary struct {
+	Format       string   // Package manager the lockfile belongs to, such as "npm"
+	Parsed       bool     // Dependencies and Direct were read from the file
+	Dependencies int      // Locked packages, counted once per package
+	Direct       []string // Direct dependencies, sorted, when the lockfile names them
+	Size         int64
+	SHA256       string // Hex-encoded hash of the content
+}
+
+// lockfileParser counts the packages of a lockfile and returns its direct
+// dependencies, or nil when the format does not record them.
+type lockfileParser func(data []byte) (int, []string, error)
+
+// lockfileFormats maps the names of known lockfiles to their package manager
+// and parser. Lockfiles without a parser are summarized by size and hash
+// alone. init-ignore suggests ignoring every one of them.
+var lockfileFormats = map[string]struct {
+	format string
+	parse  lockfileParser
+}{
+	"package-lock.json": {"npm", parseNPMLock},
+	"yarn.lock":         {"yarn", parseYarnLock},
+	"pnpm-lock.yaml":    {"pnpm", parsePNPMLock},
+	"go.sum":            {"go", parseGoSum},
+	"Cargo.lock":        {"cargo", parseTOMLPackages},
+	"poetry.lock":       {"poetry", parseTOMLPackages},
+	"Gemfile.lock":      {"bundler", parseGemfileLock},
+	"composer.lock":     {"composer", parseComposerLock},
+	"Pipfile.lock":      {"pipenv", parsePipfileLock},
+	"flake.lock":        {"nix", parseFlakeLock},
+	"Podfile.lock":      {"cocoapods", nil},
+	"mix.lock":          {"mix", nil},
+}
+
+// summarizeLockfile returns the summary entry written in place of file, or
+// false when file is not a known lockfile. A lockfile that cannot be read is
+// an error entry; one that cannot be parsed keeps its size and hash.
+func (a *App) summarizeLockfile(file scanner.FileInfo) (ProcessedFile, bool) {
+	known, ok := lockfileFormats[filepath.Base(file.RelPath)]
+	if !ok || file.IsBinary {
+		return ProcessedFile{}, false
+	}
+
+	processed := ProcessedFile{Info: file, FileType: file.FileType}
+	summary := &LockfileSummary{Format: known.format, Size: file.Size}
+
+	if file.Size > lockfileParseMax {
+		sum, err := hashFile(file.Path)
+		if err != nil {
+			processed.Error = fmt.Errorf("failed to hash %s: %w", file.RelPath, err)
+			processed.ErrorCategory = categorizeError(err)
+
+			return processed, true
+		}
+		summary.SHA256 = hex.EncodeToString(sum[:])
+		processed.Lockfile = summary
+
+		return processed, true
+	}
+
+	data, err := fdlimit.ReadFile(file.Path)
+	if err != nil {
+		processed.Error, processed.ErrorCategory = err, categorizeError(err)
+
+		return processed, true
+	}
+	sum := sha256.Sum256(data)
+	summary.Size, summary.SHA256 = int64(len(data)), hex.EncodeToString(sum[:])
+
+	if known.parse == nil {
+		processed.Lockfile = summary
+
+		return processed, true
+	}
+	count, direct, err := known.parse(data)
+	if err == nil {
+		sort.Strings(direct)
+		summary.Parsed, summary.Dependencies, summary.Direct = true, count, direct
+	} else if a.cfg.Debug {
+		fmt.Fprintf(a.warn, "Debug: Could not parse lockfile %s, summarizing its size and hash: %v\n", file.RelPath, err)
+	}
+	processed.Lockfile = summary
+
+	return processed, true
+}
+
+// Text describes the summary in one line, for the formats without a
+// structured form of it.
+func (s *LockfileSummary) Text() string {
+	if !s.Parsed {
+		return fmt.Sprintf("%s lockfile, %d bytes, sha256 %s", s.Format, s.Size, s.SHA256)
+	}
+
+	noun := "dependencies"
+	if s.Dependencies == 1 {
+		noun = "dependency"
+	}
+
+	return fmt.Sprintf("%s lockfile, %d %s, %d bytes, sha256 %s", s.Format, s.Dependencies, noun, s.Size, s.SHA256)
+}
+
+// lockfileAttrs returns the XML and prompt attributes describing summary,
+// with a leading space.
+func lockfileAttrs(summary *LockfileSummary) string {
+	attrs := fmt.Sprintf(` format="%s"`, summary.Format)
+	if summary.Parsed {
+		attrs += fmt.Sprintf(` dependencies="%d"`, summary.Dependencies)
+	}
+
+	return attrs + fmt.Sprintf(` bytes="%d" sha256="%s"`, summary.Size, summary.SHA256)
+}
+
+// parseNPMLock reads package-lock.json. Version 2 and later list every
+// installed package under "packages", with the project's own manifest under
+// the empty key; version 1 only nests them under "dependencies".
+func parseNPMLock(data []byte) (int, []string, error) {
+	var lock struct {
+		Packages map[string]struct {
+			Dependencies         map[string]json.RawMessage `json:"dependencies"`
+			DevDependencies      map[string]json.RawMessage `json:"devDependencies"`
+			OptionalDependencies map[string]json.RawMessage `json:"optionalDependencies"`
+		} `json:"packages"`
+		Dependencies map[string]json.RawMessage `json:"dependencies"`
+	}
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return 0, nil, err
+	}
+
+	if lock.Packages == nil {
+		return len(lock.Dependencies), nil, nil
+	}
+
+	root := lock.Packages[""]
+	count := len(lock.Packages)
+	if _, ok := lock.Packages[""]; ok {
+		count--
+	}
+
+	return count, mapKeys(root.Dependencies, root.DevDependencies, root.OptionalDependencies), nil
+}
+
+// parseYarnLock counts the entries of yarn.lock, classic or Berry: each is an
+// unindented line of comma-separated descriptors ending with a colon.
+func parseYarnLock(data []byte) (int, []string, error) {
+	count := 0
+	for line := range strings.Lines(string(data)) {
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" || line[0] == ' ' || line[0] == '#' || line == "__metadata:" {
+			continue
+		}
+		if strings.HasSuffix(line, ":") {
+			count++
+		}
+	}
+
+	return nonZero(count, nil)
+}
+
+// parsePNPMLock reads pnpm-lock.yaml, taking the direct dependencies from
+// the root importer, or from the top level in lockfiles without importers.
+func parsePNPMLock(data []byte) (int, []string, error) {
+	type deps struct {
+		Dependencies         map[string]any `yaml:"dependencies"`
+		DevDependencies      map[string]any `yaml:"devDependencies"`
+		OptionalDependencies map[string]any `yaml:"optionalDependencies"`
+	}
+	var lock struct {
+		deps      `yaml:",inline"`
+		Importers map[string]deps `yaml:"importers"`
+		Packages  map[string]any  `yaml:"packages"`
+	}
+	if err := yaml.Unmarshal(data, &lock); err != nil {
+		return 0, nil, err
+	}
+
+	root := lock.deps
+	if importer, ok := lock.Importers["."]; ok {
+		root = importer
+	}
+
+	return len(lock.Packages), mapKeys(root.Dependencies, root.DevDependencies, root.OptionalDependencies), nil
+}
+
+// parseGoSum counts the distinct modules of go.sum. It cannot tell direct
+// dependencies, which only go.mod records.
+func parseGoSum(data []byte) (int, []string, error) {
+	modules := make(map[string]bool)
+	lines := bufio.NewScanner(bytes.NewReader(data))
+	for lines.Scan() {
+		if fields := strings.Fields(lines.Text()); len(fields) == 3 {
+			modules[fields[0]] = true
+		}
+	}
+
+	return nonZero(len(modules), nil)
+}
+
+// parseTOMLPackages counts the [[package]] tables of Cargo.lock and
+// poetry.lock, neither of which records which packages are direct.
+func parseTOMLPackages(data []byte) (int, []string, error) {
+	count := 0
+	for line := range strings.Lines(string(data)) {
+		if strings.TrimSpace(line) == "[[package]]" {
+			count++
+		}
+	}
+
+	return nonZero(count, nil)
+}
+
+// parseGemfileLock counts the gems listed under the specs of each source
+// section of Gemfile.lock, indented by four spaces, and takes the direct
+// dependencies from its DEPENDENCIES section.
+func parseGemfileLock(data []byte) (int, []string, error) {
+	count := 0
+	var direct []string
+	section := ""
+	for line := range strings.Lines(string(data)) {
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case line == "":
+		case line[0] != ' ':
+			section = line
+		case section == "DEPENDENCIES" && !strings.HasPrefix(line, "   "):
+			if fields := strings.Fields(line); len(fields) > 0 {
+				direct = append(direct, strings.TrimSuffix(fields[0], "!"))
+			}
+		case strings.HasPrefix(line, "    ") && !strings.HasPrefix(line, "     "):
+			count++
+		}
+	}
+
+	return nonZero(count, direct)
+}
+
+// parseComposerLock counts the packages and development packages of
+// composer.lock.
+func parseComposerLock(data []byte) (int, []string, error) {
+	var lock struct {
+		Packages    []json.RawMessage `json:"packages"`
+		PackagesDev []json.RawMessage `json:"packages-dev"`
+	}
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return 0, nil, err
+	}
+
+	return len(lock.Packages) + len(lock.PackagesDev), nil, nil
+}
+
+// parsePipfileLock counts the default and development packages of
+// Pipfile.lock.
+func parsePipfileLock(data []byte) (int, []string, error) {
+	var lock struct {
+		Default map[string]json.RawMessage `json:"default"`
+		Develop map[string]json.RawMessage `json:"develop"`
+	}
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return 0, nil, err
+	}
+
+	return len(lock.Default) + len(lock.Develop), nil, nil
+}
+
+// parseFlakeLock counts the nodes of flake.lock other than its root, whose
+// inputs are the direct dependencies.
+func parseFlakeLock(data []byte) (int, []string, error) {
+	var lock struct {
+		Nodes map[string]struct {
+			Inputs map[string]json.RawMessage `json:"inputs"`
+		} `json:"nodes"`
+		Root string `json:"root"`
+	}
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return 0, nil, err
+	}
+
+	root, ok := lock.Nodes[lock.Root]
+	if !ok {
+		return 0, nil, fmt.Errorf("root node %q not found", lock.Root)
+	}
+
+	return len(lock.Nodes) - 1, mapKeys(root.Inputs), nil
+}
+
+// mapKeys returns the distinct keys of maps.
+func mapKeys[V any](maps ...map[string]V) []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, m := range maps {
+		for key := range m {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	return keys
+}
+
+// nonZero returns count and direct, or errNoEntries when a line-based parser
+// found nothing, since that more likely means the format was not understood
+// than that nothing is locked.
+func nonZero(count int, direct []string) (int, []string, error) {
+	if count == 0 {
+		return 0, nil, errNoEntries
+	}
+
+	return count, direct, nil
+}
@@ -0,0 +1,183 @@
+package catls
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+func TestLockfileParsers(t *testing.T) {
+	tests := []struct {
+		name       string
+		parse      lockfileParser
+		data       string
+		wantCount  int
+		wantDirect []string
+		wantErr    bool
+	}{
+		{
+			name:  "npm v3",
+			parse: parseNPMLock,
+			data: `{"lockfileVersion": 3, "packages": {
+				"": {"dependencies": {"react": "^18"}, "devDependencies": {"vitest": "^1"}},
+				"node_modules/react": {}, "node_modules/loose-envify": {}, "node_modules/vitest": {}}}`,
+			wantCount:  3,
+			wantDirect: []string{"react", "vitest"},
+		},
+		{
+			name:      "npm v1",
+			parse:     parseNPMLock,
+			data:      `{"lockfileVersion": 1, "dependencies": {"a": {}, "b": {}}}`,
+			wantCount: 2,
+		},
+		{
+			name:      "yarn classic",
+			parse:     parseYarnLock,
+			data:      "# yarn lockfile v1\n\n\"@babel/core@^7.0.0\", \"@babel/core@^7.1.0\":\n  version \"7.2.0\"\n\nleft-pad@^1.3.0:\n  version \"1.3.0\"\n",
+			wantCount: 2,
+		},
+		{
+			name:       "pnpm",
+			parse:      parsePNPMLock,
+			data:       "lockfileVersion: '9.0'\nimporters:\n  .:\n    dependencies:\n      zod:\n        specifier: ^3\n        version: 3.22.0\npackages:\n  zod@3.22.0:\n    resolution: {integrity: sha512-x}\n",
+			wantCount:  1,
+			wantDirect: []string{"zod"},
+		},
+		{
+			name:      "go.sum",
+			parse:     parseGoSum,
+			data:      "github.com/a/b v1.0.0 h1:x=\ngithub.com/a/b v1.0.0/go.mod h1:y=\ngolang.org/x/term v0.1.0/go.mod h1:z=\n",
+			wantCount: 2,
+		},
+		{
+			name:      "cargo",
+			parse:     parseTOMLPackages,
+			data:      "version = 3\n\n[[package]]\nname = \"a\"\n\n[[package]]\nname = \"b\"\n",
+			wantCount: 2,
+		},
+		{
+			name:       "gemfile",
+			parse:      parseGemfileLock,
+			data:       "GEM\n  remote: https://rubygems.org/\n  specs:\n    rack (3.0.0)\n    rails (7.1.0)\n      rack (>= 2)\n\nDEPENDENCIES\n  rails (~> 7.1)\n  local!\n",
+			wantCount:  2,
+			wantDirect: []string{"local", "rails"},
+		},
+		{
+			name:       "flake",
+			parse:      parseFlakeLock,
+			data:       `{"nodes": {"nixpkgs": {}, "utils": {}, "root": {"inputs": {"nixpkgs": "nixpkgs", "utils": "utils"}}}, "root": "root"}`,
+			wantCount:  2,
+			wantDirect: []string{"nixpkgs", "utils"},
+		},
+		{name: "malformed json", parse: parseComposerLock, data: "{", wantErr: true},
+		{name: "nothing found", parse: parseTOMLPackages, data: "not a lockfile\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, direct, err := tt.parse([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if count != tt.wantCount {
+				t.Errorf("parse() count = %d, want %d", count, tt.wantCount)
+			}
+			sort.Strings(direct)
+			if strings.Join(direct, ",") != strings.Join(tt.wantDirect, ",") {
+				t.Errorf("parse() direct = %v, want %v", direct, tt.wantDirect)
+			}
+		})
+	}
+}
+
+func TestSummarizeLockfiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"main.go": "package main",
+		"go.sum":  "github.com/a/b v1.0.0 h1:x=\ngithub.com/a/b v1.0.0/go.mod h1:y=\n",
+		// Parsing fails, leaving the size and hash
+		"Cargo.lock": "garbled\n",
+	})
+
+	run := func(format OutputFormat, summarize bool) string {
+		t.Helper()
+		var buf bytes.Buffer
+		app, err := New(&Config{
+			Directory:          tmpDir,
+			OutputFormat:       format,
+			Output:             &buf,
+			SummarizeLockfiles: summarize,
+		})
+		if err != nil {
+			t.Fatalf("New() unexpected error: %v", err)
+		}
+		if err := app.Run(context.Background()); err != nil {
+			t.Fatalf("Run() unexpected error: %v", err)
+		}
+
+		return buf.String()
+	}
+
+	if out := run(OutputFormatXML, false); !strings.Contains(out, "h1:x=") || strings.Contains(out, "lockfile-summary") {
+		t.Errorf("lockfiles should be written in full by default:\n%s", out)
+	}
+
+	xml := run(OutputFormatXML, true)
+	for _, want := range []string{
+		`<lockfile-summary format="go" dependencies="1" bytes="63" sha256="`,
+		`<lockfile-summary format="cargo" bytes="8" sha256="`,
+		"package main",
+	} {
+		if !strings.Contains(xml, want) {
+			t.Errorf("XML output is missing %q:\n%s", want, xml)
+		}
+	}
+	if strings.Contains(xml, "h1:x=") {
+		t.Errorf("XML output includes lockfile content:\n%s", xml)
+	}
+
+	var doc struct {
+		Files []JSONFile `json:"files"`
+	}
+	if err := json.Unmarshal([]byte(run(OutputFormatJSON, true)), &doc); err != nil {
+		t.Fatalf("JSON output does not parse: %v", err)
+	}
+	for _, file := range doc.Files {
+		switch file.Path {
+		case "go.sum":
+			if s := file.LockfileSummary; s == nil || s.Dependencies == nil || *s.Dependencies != 1 || len(file.Lines) != 0 {
+				t.Errorf("go.sum = %+v, want a summary with 1 dependency and no lines", file)
+			}
+		case "Cargo.lock":
+			if s := file.LockfileSummary; s == nil || s.Dependencies != nil || len(s.SHA256) != 64 {
+				t.Errorf("Cargo.lock = %+v, want a summary with only size and hash", file)
+			}
+		}
+	}
+
+	for _, format := range []OutputFormat{OutputFormatMarkdown, OutputFormatPrompt, OutputFormatPretty} {
+		if out := run(format, true); !strings.Contains(out, "go lockfile, 1 dependency,") && !strings.Contains(out, `lockfile-summary="true"`) {
+			t.Errorf("%s output does not mark the summary:\n%s", format, out)
+		}
+	}
+}
+
+func TestSummarizeLockfileHashError(t *testing.T) {
+	app, err := New(&Config{Directory: t.TempDir(), OutputFormat: OutputFormatXML, SummarizeLockfiles: true})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	// Over lockfileParseMax, the lockfile is hashed without being parsed
+	file := scanner.FileInfo{Path: filepath.Join(t.TempDir(), "go.sum"), RelPath: "go.sum", Size: lockfileParseMax + 1}
+	processed, ok := app.summarizeLockfile(file)
+	if !ok || processed.Error == nil || processed.ErrorCategory != ErrNotFound {
+		t.Errorf("summarizeLockfile() of a missing lockfile = %v %q, want a not-found error", processed.Error, processed.ErrorCategory)
+	}
+}
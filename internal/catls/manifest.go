@@ -0,0 +1,282 @@
+package catls
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ManifestVersion is the manifest layout written by this release.
+const ManifestVersion = 1
+
+// ErrManifestMismatch is returned when the files selected now differ from
+// those a manifest records.
+var ErrManifestMismatch = errors.New("files differ from the manifest")
+
+// Manifest records the files a run wrote, with their hashes, and the
+// settings that selected them, so the same selection can be repeated and
+// compared later.
+type Manifest struct {
+	Version int            `json:"version"`
+	Config  ManifestConfig `json:"config"`
+	Files   []ManifestFile `json:"files"`
+}
+
+// ManifestFile is a single file in a Manifest.
+type ManifestFile struct {
+	Path    string    `json:"path"` // Relative to Directory, with forward slashes
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	SHA256  string    `json:"sha256"`
+	Xattrs  []string  `json:"xattrs,omitempty"` // Names of extended attributes, with Xattrs
+}
+
+// ManifestConfig holds the Config settings that decide which files a run
+// selects. Settings that only shape output are not recorded.
+type ManifestConfig struct {
+	Directory            string            `json:"directory"` // Absolute, so verify rescans it from any working directory
+	Files                []string          `json:"files,omitempty"`
+	Paths                []string          `json:"paths,omitempty"`
+	ShowAll              bool              `json:"all,omitempty"`
+	Recursive            bool              `json:"recursive,omitempty"`
+	OneFileSystem        bool              `json:"oneFileSystem,omitempty"`
+	SkipGitSubmodules    bool              `json:"skipGitSubmodules,omitempty"`
+	GitIgnore            bool              `json:"gitignore,omitempty"`
+	IgnoreDir            []string          `json:"ignoreDir,omitempty"`
+	Globs                []string          `json:"globs,omitempty"`
+	IgnoreGlobs          []string          `json:"ignoreGlobs,omitempty"`
+	Types                []string          `json:"types,omitempty"`
+	ExcludeTypes         []string          `json:"excludeTypes,omitempty"`
+	LangMap              map[string]string `json:"langMap,omitempty"`
+	OnlyExecutable       bool              `json:"onlyExecutable,omitempty"`
+	NoExecutable         bool              `json:"noExecutable,omitempty"`
+	Imports              []string          `json:"imports,omitempty"`
+	ImportsIncludeOthers bool              `json:"importsIncludeOthers,omitempty"`
+	OmitBins             bool              `json:"omitBins,omitempty"`
+	SkipEmpty            bool              `json:"skipEmpty,omitempty"`
+	ContentPatterns      []string          `json:"patterns,omitempty"`
+	PatternAll           bool              `json:"patternAll,omitempty"`
+	MaxMatches           int               `json:"maxMatches,omitempty"`
+	Todos                bool              `json:"todos,omitempty"`
+	TodoKeywords         []string          `json:"todoKeywords,omitempty"`
+	MaxFiles             int               `json:"maxFiles,omitempty"`
+	MaxTokens            int               `json:"maxTokens,omitempty"`
+}
+
+// NewManifestConfig records the selection settings of cfg.
+func NewManifestConfig(cfg *Config) ManifestConfig {
+	dir, err := filepath.Abs(cfg.Directory)
+	if err != nil {
+		dir = cfg.Directory
+	}
+
+	return ManifestConfig{
+		Directory:            dir,
+		Files:                cfg.Files,
+		Paths:                cfg.Paths,
+		ShowAll:              cfg.ShowAll,
+		Recursive:            cfg.Recursive,
+		OneFileSystem:        cfg.OneFileSystem,
+		SkipGitSubmodules:    cfg.SkipGitSubmodules,
+		GitIgnore:            cfg.GitIgnore,
+		IgnoreDir:            cfg.IgnoreDir,
+		Globs:                cfg.Globs,
+		IgnoreGlobs:          cfg.IgnoreGlobs,
+		Types:                cfg.Types,
+		ExcludeTypes:         cfg.ExcludeTypes,
+		LangMap:              cfg.LangMap,
+		OnlyExecutable:       cfg.OnlyExecutable,
+		NoExecutable:         cfg.NoExecutable,
+		Imports:              cfg.Imports,
+		ImportsIncludeOthers: cfg.ImportsIncludeOthers,
+		OmitBins:             cfg.OmitBins,
+		SkipEmpty:            cfg.SkipEmpty,
+		ContentPatterns:      cfg.ContentPatterns,
+		PatternAll:           cfg.PatternAll,
+		MaxMatches:           cfg.MaxMatches,
+		Todos:                cfg.Todos,
+		TodoKeywords:         cfg.TodoKeywords,
+		MaxFiles:             cfg.MaxFiles,
+		MaxTokens:            cfg.MaxTokens,
+	}
+}
+
+// Config returns a configuration that selects files like the recorded run.
+// Output settings are left at their zero values.
+func (m ManifestConfig) Config() *Config {
+	return &Config{
+		Directory:            m.Directory,
+		Files:                m.Files,
+		Paths:                m.Paths,
+		ShowAll:              m.ShowAll,
+		Recursive:            m.Recursive,
+		OneFileSystem:        m.OneFileSystem,
+		SkipGitSubmodules:    m.SkipGitSubmodules,
+		GitIgnore:            m.GitIgnore,
+		IgnoreDir:            m.IgnoreDir,
+		Globs:                m.Globs,
+		IgnoreGlobs:          m.IgnoreGlobs,
+		Types:                m.Types,
+		ExcludeTypes:         m.ExcludeTypes,
+		LangMap:              m.LangMap,
+		OnlyExecutable:       m.OnlyExecutable,
+		NoExecutable:         m.NoExecutable,
+		Imports:              m.Imports,
+		ImportsIncludeOthers: m.ImportsIncludeOthers,
+		OmitBins:             m.OmitBins,
+		SkipEmpty:            m.SkipEmpty,
+		ContentPatterns:      m.ContentPatterns,
+		PatternAll:           m.PatternAll,
+		MaxMatches:           m.MaxMatches,
+		Todos:                m.Todos,
+		TodoKeywords:         m.TodoKeywords,
+		MaxFiles:             m.MaxFiles,
+		MaxTokens:            m.MaxTokens,
+	}
+}
+
+// newManifestFile returns the manifest entry of a processed file. The hash
+// is that of the bytes the output was made from: the exact bytes written
+// with ContentEncodingBase64, or those the lines were read from. Only files
+// whose content was not read, such as binary files, are read again for it.
+func (a *App) newManifestFile(processed *ProcessedFile) (ManifestFile, error) {
+	file := processed.Info
+	relPath, err := filepath.Rel(a.cfg.Directory, file.Path)
+	if err != nil {
+		return ManifestFile{}, err
+	}
+
+	var digest string
+	switch {
+	case processed.Raw != nil:
+		sum := sha256.Sum256(processed.Raw)
+		digest = hex.EncodeToString(sum[:])
+	case processed.contentSum != nil:
+		digest = hex.EncodeToString(processed.contentSum)
+	default:
+		sum, err := hashFile(file.Path)
+		if err != nil {
+			return ManifestFile{}, fmt.Errorf("cannot hash %s for the manifest: %w", relPath, err)
+		}
+		digest = hex.EncodeToString(sum[:])
+	}
+
+	return ManifestFile{
+		Path:    filepath.ToSlash(relPath),
+		Size:    file.Size,
+		ModTime: file.ModTime.UTC(),
+		SHA256:  digest,
+	}, nil
+}
+
+// recordManifest adds a written file to the manifest of the run when
+// ManifestPath is set. Directory records and unreadable files are left out.
+func (a *App) recordManifest(file *ProcessedFile) error {
+	if a.cfg.ManifestPath == "" || file.Info.IsDir || file.Error != nil {
+		return nil
+	}
+
+	entry, err := a.newManifestFile(file)
+	if err != nil {
+		return err
+	}
+	entry.Xattrs = file.Xattrs
+	a.manifest = append(a.manifest, entry)
+
+	return nil
+}
+
+// writeRunManifest writes the manifest of the files recorded by
+// recordManifest to ManifestPath.
+func (a *App) writeRunManifest() error {
+	if a.cfg.ManifestPath == "" {
+		return nil
+	}
+
+	return WriteManifest(a.cfg.ManifestPath, a.newManifest(a.manifest))
+}
+
+// newManifest returns a manifest of files selected with the current settings.
+func (a *App) newManifest(files []ManifestFile) Manifest {
+	if files == nil {
+		files = []ManifestFile{}
+	}
+
+	return Manifest{Version: ManifestVersion, Config: NewManifestConfig(a.cfg), Files: files}
+}
+
+// BuildManifest selects and processes files like Run and returns a manifest
+// of those that would be written, without any output.
+func (a *App) BuildManifest(ctx context.Context) (Manifest, error) {
+	a.processor.hashContent = true
+
+	var files []ManifestFile
+	for processed, err := range a.Files(ctx) {
+		if err != nil {
+			return Manifest{}, err
+		}
+		if processed.Info.IsDir || processed.Error != nil {
+			continue
+		}
+
+		entry, err := a.newManifestFile(&processed)
+		if err != nil {
+			return Manifest{}, err
+		}
+		files = append(files, entry)
+	}
+
+	return a.newManifest(files), nil
+}
+
+// ReadManifest loads the manifest at path.
+func ReadManifest(path string) (Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("cannot read manifest: %w", err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return Manifest{}, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+	if manifest.Version != ManifestVersion {
+		return Manifest{}, fmt.Errorf("manifest %s has version %d, expected %d", path, manifest.Version, ManifestVersion)
+	}
+
+	return manifest, nil
+}
+
+// WriteManifest writes manifest to path as indented JSON. The file is
+// replaced in one step, so a failed write leaves an existing manifest intact.
+func WriteManifest(path string, manifest Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".catls-manifest-*")
+	if err != nil {
+		return fmt.Errorf("cannot write manifest: %w", err)
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	_, err = tmp.Write(append(data, '\n'))
+	err = errors.Join(err, tmp.Chmod(0o644), tmp.Close())
+	if err != nil {
+		return fmt.Errorf("cannot write manifest: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("cannot write manifest: %w", err)
+	}
+
+	return nil
+}
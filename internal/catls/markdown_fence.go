@@ -0,0 +1,226 @@
+package catls
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// FenceStyle represents how Markdown output delimits file content.
+type FenceStyle string
+
+const (
+	// FenceStyleBacktick wraps content in ``` fences tagged with the language.
+	FenceStyleBacktick FenceStyle = "backtick"
+	// FenceStyleTilde wraps content in ~~~ fences tagged with the language.
+	FenceStyleTilde FenceStyle = "tilde"
+	// FenceStyleIndent emits content as a four-space indented code block.
+	FenceStyleIndent FenceStyle = "indent"
+	// FenceStyleNone emits content verbatim between sentinel lines.
+	FenceStyleNone FenceStyle = "none"
+)
+
+// defaultSentinel is used for FenceStyleNone when no template is configured.
+const defaultSentinel = "----- {edge} FILE: {path} -----"
+
+// String returns the string representation of the fence style.
+func (f FenceStyle) String() string {
+	return string(f)
+}
+
+// IsValid checks if the fence style is supported.
+func (f FenceStyle) IsValid() bool {
+	switch f {
+	case FenceStyleBacktick, FenceStyleTilde, FenceStyleIndent, FenceStyleNone:
+		return true
+	default:
+		return false
+	}
+}
+
+// GetSupportedFenceStyles returns a list of all supported fence styles.
+func GetSupportedFenceStyles() []string {
+	return []string{
+		FenceStyleBacktick.String(),
+		FenceStyleTilde.String(),
+		FenceStyleIndent.String(),
+		FenceStyleNone.String(),
+	}
+}
+
+// effectiveFenceStyle resolves the configured style. A sentinel template on its
+// own implies FenceStyleNone, since sentinels replace fences.
+func effectiveFenceStyle(cfg *Config) FenceStyle {
+	switch {
+	case cfg.FenceStyle != "":
+		return cfg.FenceStyle
+	case cfg.Sentinel != "":
+		return FenceStyleNone
+	default:
+		return FenceStyleBacktick
+	}
+}
+
+// writeMarkdownBody writes file content delimited according to the fence style.
+func writeMarkdownBody(b *strings.Builder, file *ProcessedFile, language string, cfg *Config) {
+	gutter := newLineGutter(file, cfg)
+
+	var open, closing, indent string
+	switch effectiveFenceStyle(cfg) {
+	case FenceStyleTilde:
+		fence := longestRunFence(file.Lines, '~')
+		open = fence + fenceInfo(language, file)
+		closing = fence
+	case FenceStyleIndent:
+		indent = "    "
+	case FenceStyleNone:
+		template := cfg.Sentinel
+		if template == "" {
+			template = defaultSentinel
+		}
+		open = expandSentinel(template, "BEGIN", file, language)
+		closing = expandSentinel(template, "END", file, language)
+	case FenceStyleBacktick:
+		fallthrough
+	default:
+		// Use a fence longer than any backtick run in the content so it stays balanced
+		fence := longestRunFence(file.Lines, '`')
+		open = fence + fenceInfo(language, file)
+		closing = fence
+	}
+
+	// BlameSince gap markers are not content, so they are written between
+	// blocks, each block opened when content follows
+	blockOpen := false
+	openBlock := func() {
+		if open != "" {
+			b.WriteString(open + "\n")
+		}
+		blockOpen = true
+	}
+	closeBlock := func() {
+		if closing != "" {
+			b.WriteString(closing + "\n")
+		}
+		blockOpen = false
+	}
+
+	if len(file.Lines) == 0 || file.Lines[0].Gap == 0 {
+		openBlock()
+	}
+	for _, line := range file.Lines {
+		if line.Gap > 0 {
+			if blockOpen {
+				closeBlock()
+				b.WriteString("\n")
+			}
+			b.WriteString("*" + line.Content + "*\n")
+
+			continue
+		}
+		if !blockOpen {
+			b.WriteString("\n")
+			openBlock()
+		}
+		b.WriteString(indent + gutter.Line(line, line.Content) + "\n")
+	}
+
+	if cfg.LegacyTruncation {
+		if notice := gutter.TruncationNotice(file); notice != "" {
+			if !blockOpen {
+				b.WriteString("\n")
+				openBlock()
+			}
+			b.WriteString(indent + notice + "\n")
+		}
+	}
+
+	if blockOpen {
+		closeBlock()
+	}
+
+	// Markup inside a fence would show as text, so matches are listed below it
+	if matched := matchedLines(file.Lines); matched != "" {
+		fmt.Fprintf(b, "\nMatches: %s\n", matched)
+	}
+
+	// Outside the fence the notice cannot be mistaken for file content
+	if remaining := remainingLines(file); remaining > 0 && !cfg.LegacyTruncation {
+		fmt.Fprintf(b, "\n*(%d more lines)*\n", remaining)
+	}
+}
+
+// expandSentinel fills a sentinel template. Supported placeholders are {path},
+// {type}, {lines} (total line count), and {edge} (BEGIN or END).
+func expandSentinel(template, edge string, file *ProcessedFile, language string) string {
+	return strings.NewReplacer(
+		"{path}", printableText(file.Info.RelPath),
+		"{type}", language,
+		"{lines}", strconv.Itoa(file.TotalLines),
+		"{edge}", edge,
+	).Replace(template)
+}
+
+// fenceInfo returns the info string of a fence: the language and the quoted
+// file name. Neither may hold a line break or, after a backtick fence, a
+// backtick, so those are escaped.
+func fenceInfo(language string, file *ProcessedFile) string {
+	name := strconv.Quote(filepath.Base(file.Info.RelPath))
+
+	return strings.ReplaceAll(printableText(language)+" name="+name, "`", `\x60`)
+}
+
+// printableText writes the characters of s that are not printable, such as
+// line breaks, control characters, bidirectional overrides, and invalid
+// UTF-8, as Go escape sequences, so a hostile file name cannot break the line
+// it is written on or disguise what it says.
+func printableText(s string) string {
+	if utf8.ValidString(s) && strings.IndexFunc(s, isUnprintable) < 0 {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			fmt.Fprintf(&b, `\x%02x`, s[i])
+		case isUnprintable(r):
+			quoted := strconv.QuoteRune(r)
+			b.WriteString(quoted[1 : len(quoted)-1])
+		default:
+			b.WriteRune(r)
+		}
+		i += size
+	}
+
+	return b.String()
+}
+
+func isUnprintable(r rune) bool {
+	return r != ' ' && !strconv.IsPrint(r)
+}
+
+// longestRunFence returns a fence of ch that is at least three characters long
+// and longer than the longest run of ch found in lines.
+func longestRunFence(lines []FilteredLine, ch rune) string {
+	longest := 0
+	for _, line := range lines {
+		run := 0
+		for _, r := range line.Content {
+			if r != ch {
+				run = 0
+
+				continue
+			}
+			run++
+			longest = max(longest, run)
+		}
+	}
+
+	const minFence = 3
+
+	return strings.Repeat(string(ch), max(minFence, longest+1))
+}
@@ -0,0 +1,114 @@
+package catls
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/connerohnesorge/catls/internal/fdlimit"
+	"github.com/connerohnesorge/catls/internal/profile"
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+// streamsMatches reports whether ProcessFile can filter a file by content
+// patterns as it reads it, keeping only the matching lines. Reformatting,
+// front matter handling, and signatures need the whole content, and the
+// content cache stores whole files, so any of them reads the file first.
+func (p *FileProcessor) streamsMatches(filter *FileFilter) bool {
+	return filter.filtersPatterns() &&
+		p.contentCache == nil &&
+		p.reformat == nil &&
+		p.signatures == nil &&
+		p.frontMatter == frontMatterKeep
+}
+
+// processMatches fills result with the lines of file that content patterns
+// match, reading it line by line. Reading and filtering are one pass, timed
+// as the read stage.
+func (p *FileProcessor) processMatches(result *ProcessedFile, file scanner.FileInfo, filter *FileFilter) {
+	done := p.profile.Start(file.RelPath, profile.StageRead)
+	err := p.streamMatches(result, file.Path, filter)
+	done()
+	if err != nil {
+		result.Lines, result.TotalLines = nil, 0
+		result.Error, result.ErrorCategory = err, categorizeError(err)
+	}
+}
+
+// streamMatches reads a file line by line, counting its lines and keeping
+// only those a content pattern matches. Once MaxMatches lines are kept, the
+// next match stops reading: MatchesSuppressed is set and TotalLines counts
+// only the lines read.
+func (p *FileProcessor) streamMatches(result *ProcessedFile, filePath string, filter *FileFilter) error {
+	file, err := fdlimit.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			fmt.Fprintf(p.warn, "Warning: failed to close file %s: %v\n", filePath, closeErr)
+		}
+	}()
+
+	reader, err := decodeText(file)
+	if err != nil {
+		return err
+	}
+
+	// Without transformers a line is matched as read, and only the lines
+	// kept become strings
+	transformed := len(p.transformers) > 0
+	blank := true
+	sc := bufio.NewScanner(reader)
+	sc.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), bufio.MaxScanTokenSize)
+	for sc.Scan() {
+		result.TotalLines++
+		raw := sc.Bytes()
+		if !transformed {
+			if blank && len(bytes.TrimSpace(raw)) > 0 {
+				blank = false
+			}
+			if !filter.matchesPatterns(raw) {
+				continue
+			}
+		}
+
+		line := p.transform(string(raw))
+		if transformed && blank && strings.TrimSpace(line) != "" {
+			blank = false
+		}
+		matched, spans := filter.matchingPatterns(line)
+		if len(matched) == 0 {
+			continue
+		}
+		if filter.maxMatches > 0 && len(result.Lines) == filter.maxMatches {
+			result.MatchesSuppressed = true
+
+			break
+		}
+		result.Lines = append(result.Lines, FilteredLine{
+			LineNumber: result.TotalLines,
+			Content:    line,
+			Patterns:   matched,
+			Matches:    spans,
+		})
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	// Unread lines may not be blank
+	result.IsEmpty = blank && !result.MatchesSuppressed
+
+	return nil
+}
+
+// matchLimitNote says MaxMatches stopped reading file, or is empty when it
+// did not.
+func matchLimitNote(file *ProcessedFile) string {
+	if !file.MatchesSuppressed {
+		return ""
+	}
+
+	return fmt.Sprintf("… further matches suppressed after %d", len(file.Lines))
+}
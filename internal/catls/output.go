@@ -5,28 +5,102 @@ import (
 	"context"
 	"fmt"
 	"html"
+	"io"
+	"strings"
+	"sync"
+	"unicode/utf8"
 )
 
 // XMLOutput handles XML output formatting. It implements the OutputFormatter interface to write files in XML format.
 // The XML output includes file paths, types, content, and binary indicators.
-type XMLOutput struct{}
+type XMLOutput struct {
+	mu      sync.Mutex
+	w       io.Writer
+	indent  int                     // Spaces per nesting level of elements; content is never indented
+	cdata   bool                    // Wrap content in CDATA sections instead of escaping it
+	echo    string                  // Configuration echo comment written before the root element
+	project string                  // Project element written first inside the root element
+	trailer func() IntegrityTrailer // Integrity trailer written after the root element (nil for none)
+}
 
-// NewXMLOutput creates a new XML output formatter that can be used to output file listings in XML format.
-func NewXMLOutput() *XMLOutput {
-	return &XMLOutput{}
+// NewXMLOutput creates a new XML output formatter that writes file listings in XML format to w.
+func NewXMLOutput(w io.Writer) *XMLOutput {
+	return &XMLOutput{w: w}
+}
+
+// newXMLOutputWithOptions creates an XML formatter configured by the xml
+// --format-opt keys.
+func newXMLOutputWithOptions(w io.Writer, opts FormatOptions) (*XMLOutput, error) {
+	if err := opts.checkKeys(OutputFormatXML); err != nil {
+		return nil, err
+	}
+
+	x := NewXMLOutput(w)
+
+	var err error
+	if x.indent, err = opts.intOption(OutputFormatXML, "indent", 0); err != nil {
+		return nil, err
+	}
+	if x.indent < 0 {
+		return nil, fmt.Errorf("--format-opt xml:indent must not be negative, got %d", x.indent)
+	}
+	if x.cdata, err = opts.boolOption(OutputFormatXML, "cdata", false); err != nil {
+		return nil, err
+	}
+
+	return x, nil
+}
+
+// pad returns the indentation for an element nested level deep.
+func (x *XMLOutput) pad(level int) string {
+	return strings.Repeat(" ", x.indent*level)
 }
 
 // WriteHeader writes the opening XML structure. It initializes the XML document with the root element.
-func (*XMLOutput) WriteHeader(ctx context.Context) error {
+func (x *XMLOutput) WriteHeader(ctx context.Context) error {
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
 	}
 
-	fmt.Println("<files>")
+	return x.write(x.echo + "<files>\n" + x.project)
+}
+
+// SetProjectHeader renders the header as a <project> element for WriteHeader.
+func (x *XMLOutput) SetProjectHeader(header ProjectHeader) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	var b strings.Builder
+	if header.Name != "" {
+		fmt.Fprintf(&b, "%s<project name=\"%s\">\n", x.pad(1), escapeXMLAttr(header.Name))
+	} else {
+		b.WriteString(x.pad(1) + "<project>\n")
+	}
+	for _, share := range header.Languages {
+		fmt.Fprintf(&b, "%s<language name=\"%s\" bytes=\"%d\" percent=\"%d\"/>\n",
+			x.pad(2), escapeXMLAttr(share.Language), share.Bytes, share.Percent)
+	}
+	b.WriteString(x.pad(1) + "</project>\n")
+	x.project = b.String()
+}
+
+// SetIntegrity makes WriteFooter close the document with the trailer as a
+// comment after the root element.
+func (x *XMLOutput) SetIntegrity(trailer func() IntegrityTrailer) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
 
-	return nil
+	x.trailer = trailer
+}
+
+// SetConfigEcho records the echo as a comment ahead of the root element.
+func (x *XMLOutput) SetConfigEcho(echo ConfigEcho) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.echo = configEchoComment(echo)
 }
 
 // WriteFile writes a single processed file to XML output.
@@ -37,77 +111,300 @@ func (x *XMLOutput) WriteFile(ctx context.Context, file *ProcessedFile, cfg *Con
 	default:
 	}
 
-	return x.writeProcessedFile(file, cfg)
+	var b strings.Builder
+	x.writeProcessedFile(&b, file, cfg)
+	if file.DirOmitted > 0 {
+		fmt.Fprintf(&b, "%s<omitted dir=\"%s\" files=\"%d\"/>\n", x.pad(1), escapeXMLAttr(dirLimitDir(file)), file.DirOmitted)
+	}
+
+	return x.write(b.String())
 }
 
-// WriteFooter writes the closing XML structure.
-func (*XMLOutput) WriteFooter(ctx context.Context) error {
+// WriteTodoIndex writes the todo index as a <todos> element ahead of the files.
+func (x *XMLOutput) WriteTodoIndex(ctx context.Context, index TodoIndex) error {
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
 	}
 
-	fmt.Println("</files>")
+	var b strings.Builder
+	b.WriteString(x.pad(1) + "<todos>\n")
+	for _, keyword := range index {
+		fmt.Fprintf(&b, "%s<keyword name=\"%s\">\n", x.pad(2), escapeXMLAttr(keyword.Keyword))
+		for _, ref := range keyword.Refs {
+			fmt.Fprintf(&b, "%s<ref path=\"%s\" line=\"%d\"/>\n", x.pad(3), escapeXMLAttr(ref.Path), ref.Line)
+		}
+		b.WriteString(x.pad(2) + "</keyword>\n")
+	}
+	b.WriteString(x.pad(1) + "</todos>\n")
 
-	return nil
+	return x.write(b.String())
 }
 
-// writeProcessedFile writes a processed file to XML format.
+// WriteFooter writes the closing XML structure, followed by the integrity
+// trailer when one is set.
+func (x *XMLOutput) WriteFooter(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	if err := x.write("</files>\n"); err != nil || x.trailer == nil {
+		return err
+	}
+
+	return x.write(integrityComment(x.trailer()))
+}
+
+// write emits a fully rendered chunk to the underlying writer in a single call.
+func (x *XMLOutput) write(s string) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	_, err := io.WriteString(x.w, s)
+
+	return err
+}
+
+// writeProcessedFile renders a processed file in XML format.
 // It escapes special characters in file path and content to ensure valid XML.
-// Errors are written as <error> tags instead of file content.
-func (x *XMLOutput) writeProcessedFile(file *ProcessedFile, cfg *Config) error {
-	safePath := html.EscapeString(file.Info.RelPath)
-	fmt.Printf("<file path=\"%s\">\n", safePath)
+// Errors are written as <error> tags instead of file content, duplicates as a
+// <duplicate-of> tag naming the file with the content, and directory records
+// as self-closing <dir> elements. Summarized lockfiles have a
+// <lockfile-summary> instead of content. Raw content follows in <content-b64>.
+// Extended attribute names recorded by Xattrs come first, in <xattrs>.
+func (x *XMLOutput) writeProcessedFile(b *strings.Builder, file *ProcessedFile, cfg *Config) {
+	safePath := escapeXMLAttr(file.Info.RelPath)
+	if file.Info.IsDir {
+		fmt.Fprintf(b, "%s<dir path=\"%s\"/>\n", x.pad(1), safePath)
+
+		return
+	}
+
+	fmt.Fprintf(b, "%s<file path=\"%s\"%s%s%s%s>\n", x.pad(1), safePath, executableAttr(file), reformattedAttr(file), reducedAttr(file), modifiedAttr(file))
+	x.writeXattrs(b, file.Xattrs)
 
 	if file.Error != nil {
-		safeError := html.EscapeString(file.Error.Error())
-		fmt.Printf("<error>%s</error>\n", safeError)
-		fmt.Println("</file>")
+		safeError := escapeXMLText(file.Error.Error())
+		fmt.Fprintf(b, "%s<error%s>%s</error>\n", x.pad(2), errorCategoryAttr("category", file), safeError)
+		b.WriteString(x.pad(1) + "</file>\n")
 
-		return nil
+		return
 	}
 
-	if file.Info.IsBinary {
-		fmt.Println("<binary>true</binary>")
-		fmt.Println("<content>[Binary file - contents not displayed]</content>")
-	} else {
+	switch {
+	case file.DuplicateOf != "":
+		fmt.Fprintf(b, "%s<duplicate-of>%s</duplicate-of>\n", x.pad(2), escapeXMLText(file.DuplicateOf))
+	case file.Lockfile != nil:
+		x.writeLockfileSummary(b, file.Lockfile)
+	case file.Info.IsBinary:
+		b.WriteString(x.pad(2) + "<binary>true</binary>\n")
+		b.WriteString(x.pad(2) + "<content>[Binary file - contents not displayed]</content>\n")
+	case file.IsEmpty:
 		if file.FileType != "" {
-			fmt.Printf("<type>%s</type>\n", html.EscapeString(file.FileType))
+			fmt.Fprintf(b, "%s<type>%s</type>\n", x.pad(2), escapeXMLText(file.FileType))
+		}
+		b.WriteString(x.pad(2) + "<empty>true</empty>\n")
+	default:
+		if file.FileType != "" {
+			fmt.Fprintf(b, "%s<type>%s</type>\n", x.pad(2), escapeXMLText(file.FileType))
+		}
+		if file.IsReadme {
+			b.WriteString(x.pad(2) + "<readme>true</readme>\n")
 		}
 
-		if err := x.writeContent(file, cfg); err != nil {
-			return err
+		x.writeContent(b, file, cfg)
+		if file.MatchesSuppressed {
+			fmt.Fprintf(b, "%s<matches-suppressed after=\"%d\"/>\n", x.pad(2), len(file.Lines))
 		}
 	}
+	if encoded := rawBase64(file); encoded != nil {
+		fmt.Fprintf(b, "%s<content-b64 bytes=\"%d\">%s</content-b64>\n", x.pad(2), len(file.Raw), *encoded)
+	}
 
-	fmt.Println("</file>")
+	b.WriteString(x.pad(1) + "</file>\n")
+}
 
-	return nil
+// writeXattrs renders the names of a file's extended attributes, if any, as
+// <xattr> children of <xattrs>.
+func (x *XMLOutput) writeXattrs(b *strings.Builder, names []string) {
+	if len(names) == 0 {
+		return
+	}
+
+	b.WriteString(x.pad(2) + "<xattrs>\n")
+	for _, name := range names {
+		fmt.Fprintf(b, "%s<xattr>%s</xattr>\n", x.pad(3), escapeXMLText(name))
+	}
+	b.WriteString(x.pad(2) + "</xattrs>\n")
 }
 
-// writeContent writes the content section of a file.
-// It handles line numbering if configured and truncates content if necessary.
-// The content is wrapped in <content> tags.
-func (*XMLOutput) writeContent(file *ProcessedFile, cfg *Config) error {
-	fmt.Println("<content>")
+// writeLockfileSummary renders the <lockfile-summary> written in place of a
+// lockfile's content, listing its direct dependencies when known.
+func (x *XMLOutput) writeLockfileSummary(b *strings.Builder, summary *LockfileSummary) {
+	if len(summary.Direct) == 0 {
+		fmt.Fprintf(b, "%s<lockfile-summary%s/>\n", x.pad(2), lockfileAttrs(summary))
+
+		return
+	}
+
+	fmt.Fprintf(b, "%s<lockfile-summary%s>\n", x.pad(2), lockfileAttrs(summary))
+	for _, dep := range summary.Direct {
+		fmt.Fprintf(b, "%s<direct>%s</direct>\n", x.pad(3), escapeXMLText(dep))
+	}
+	b.WriteString(x.pad(2) + "</lockfile-summary>\n")
+}
+
+// writeContent renders the content section of a file.
+// It handles line numbering if configured. Truncation is reported through
+// truncated and remaining-lines attributes on <content>, or inside the content
+// with LegacyTruncation. Content lines are never indented, and with cdata they
+// are written verbatim inside a CDATA section. Text content patterns matched
+// is wrapped in <match> elements, and BlameSince gap markers are <gap>
+// elements giving the number of lines left out.
+func (x *XMLOutput) writeContent(b *strings.Builder, file *ProcessedFile, cfg *Config) {
+	b.WriteString(x.pad(2))
+	remaining := remainingLines(file)
+	if remaining > 0 && !cfg.LegacyTruncation {
+		fmt.Fprintf(b, "<content truncated=\"true\" remaining-lines=\"%d\">", remaining)
+	} else {
+		b.WriteString("<content>")
+	}
+
+	escape := escapeXMLText
+	if x.cdata {
+		b.WriteString("<![CDATA[")
+		escape = escapeCDATA
+	}
+	b.WriteString("\n")
 
+	gutter := newLineGutter(file, cfg)
+	match := xmlMatch(x.cdata)
 	for _, line := range file.Lines {
-		if cfg.ShowLineNumbers {
-			fmt.Printf("%4d| %s\n", line.LineNumber, line.Content)
-		} else {
-			fmt.Println(line.Content)
+		if line.Gap > 0 {
+			b.WriteString(gutter.Line(line, xmlGap(line, x.cdata)) + "\n")
+
+			continue
 		}
+		b.WriteString(gutter.Line(line, highlightSpans(line.Content, line.Matches, escape, match)) + "\n")
 	}
 
-	if file.IsTruncated {
-		remainingLines := file.TotalLines - len(file.Lines)
-		if remainingLines > 0 {
-			fmt.Printf("... (%d more lines)\n", remainingLines)
+	if cfg.LegacyTruncation {
+		if notice := gutter.TruncationNotice(file); notice != "" {
+			b.WriteString(notice + "\n")
 		}
 	}
 
-	fmt.Println("</content>")
+	if x.cdata {
+		b.WriteString("]]>")
+	}
+	b.WriteString("</content>\n")
+}
+
+// xmlGap renders a BlameSince gap marker as a <gap> element, leaving and
+// reentering the CDATA section with cdata.
+func xmlGap(line FilteredLine, cdata bool) string {
+	gap := fmt.Sprintf(`<gap lines="%d">%s</gap>`, line.Gap, escapeXMLText(line.Content))
+	if cdata {
+		return "]]>" + gap + "<![CDATA["
+	}
+
+	return gap
+}
+
+// executableAttr returns the executable attribute for files that have it, with
+// a leading space, or an empty string.
+func executableAttr(file *ProcessedFile) string {
+	if !file.Info.Executable {
+		return ""
+	}
+
+	return ` executable="true"`
+}
+
+// reformattedAttr returns the reformatted attribute of a file tag when
+// PrettyJSON or PrettyYAML re-indented its content, the reformat-error
+// attribute when they could not parse it, and otherwise an empty string.
+func reformattedAttr(file *ProcessedFile) string {
+	switch {
+	case file.Reformatted:
+		return ` reformatted="true"`
+	case file.ReformatError != nil:
+		return fmt.Sprintf(` reformat-error="%s"`, escapeXMLAttr(file.ReformatError.Error()))
+	}
+
+	return ""
+}
+
+// reducedAttr returns the reduced attribute of a file tag, naming how
+// Signatures reduced its content, empty when the content is whole.
+func reducedAttr(file *ProcessedFile) string {
+	if file.Reduced == "" {
+		return ""
+	}
+
+	return fmt.Sprintf(` reduced="%s"`, file.Reduced)
+}
+
+// errorCategoryAttr returns an attribute named name giving the category of
+// file's error, empty when it has none.
+func errorCategoryAttr(name string, file *ProcessedFile) string {
+	if file.ErrorCategory == "" {
+		return ""
+	}
+
+	return fmt.Sprintf(` %s="%s"`, name, file.ErrorCategory)
+}
+
+// escapeCDATA splits any "]]>" in s across two CDATA sections, the only
+// sequence a CDATA section cannot contain, after replacing characters XML
+// does not allow.
+func escapeCDATA(s string) string {
+	return strings.ReplaceAll(xmlSafe(s), "]]>", "]]]]><![CDATA[>")
+}
 
-	return nil
+// escapeXMLText escapes s for element content. Carriage returns are written
+// as references, since parsers turn a literal one into a newline.
+func escapeXMLText(s string) string {
+	return strings.ReplaceAll(html.EscapeString(xmlSafe(s)), "\r", "&#13;")
+}
+
+// escapeXMLAttr escapes s for an attribute value. Whitespace other than
+// spaces is written as references, since parsers turn a literal tab or line
+// break in an attribute into a space.
+func escapeXMLAttr(s string) string {
+	return strings.NewReplacer("\t", "&#9;", "\n", "&#10;", "\r", "&#13;").Replace(html.EscapeString(xmlSafe(s)))
+}
+
+// xmlSafe replaces invalid UTF-8 and the characters XML 1.0 does not allow,
+// such as NUL and most other control characters, with U+FFFD. They cannot be
+// written even as character references.
+func xmlSafe(s string) string {
+	if utf8.ValidString(s) && strings.IndexFunc(s, isXMLInvalid) < 0 {
+		return s
+	}
+
+	return strings.Map(func(r rune) rune {
+		if isXMLInvalid(r) {
+			return utf8.RuneError
+		}
+
+		return r
+	}, s)
+}
+
+// isXMLInvalid reports whether r falls outside the Char production of XML 1.0.
+func isXMLInvalid(r rune) bool {
+	switch {
+	case r == '\t' || r == '\n' || r == '\r':
+		return false
+	case r < 0x20:
+		return true
+	case r >= 0xD800 && r <= 0xDFFF, r == 0xFFFE, r == 0xFFFF:
+		return true
+	default:
+		return r > utf8.MaxRune
+	}
 }
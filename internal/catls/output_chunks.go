@@ -0,0 +1,204 @@
+package catls
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// DefaultChunkLines is the number of lines in a chunk when ChunkLines is not set.
+const DefaultChunkLines = 120
+
+// Statuses of the record ChunksOutput writes for a file it does not split.
+const (
+	chunkStatusBinary    = "binary"
+	chunkStatusError     = "error"
+	chunkStatusEmpty     = "empty"
+	chunkStatusDuplicate = "duplicate"
+	chunkStatusLockfile  = "lockfile"
+	chunkStatusDirectory = "directory"
+	chunkStatusNoContent = "no content"
+)
+
+// ChunksOutput writes JSON Lines for embedding pipelines: each file is split
+// into chunks of ChunkLines lines overlapping by ChunkOverlap, one record per
+// chunk. A chunk's hash depends only on its content, so it is the same in
+// every run while the file is unchanged. Files with no lines to split, such
+// as binary files and files that could not be read, get one status record.
+type ChunksOutput struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// ChunkRecord is a line of ChunksOutput holding a chunk of a file.
+type ChunkRecord struct {
+	SchemaVersion int    `json:"schema_version"`
+	Path          string `json:"path"`
+	ChunkIndex    int    `json:"chunk_index"`
+	StartLine     int    `json:"start_line"`
+	EndLine       int    `json:"end_line"`
+	Content       string `json:"content"`
+	SHA256        string `json:"sha256"` // Hex SHA-256 of Content
+}
+
+// ChunkStatusRecord is the line of ChunksOutput standing for a file that is
+// not split, saying why.
+type ChunkStatusRecord struct {
+	SchemaVersion int    `json:"schema_version"`
+	Path          string `json:"path"`
+	Status        string `json:"status"`
+	Error         string `json:"error,omitempty"`
+	DuplicateOf   string `json:"duplicate_of,omitempty"`
+}
+
+// NewChunksOutput creates a new chunks output formatter that writes to w.
+func NewChunksOutput(w io.Writer) *ChunksOutput {
+	return &ChunksOutput{w: w}
+}
+
+// WriteHeader is a no-op; JSON Lines have no header.
+func (*ChunksOutput) WriteHeader(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	return nil
+}
+
+// WriteFile writes the chunks of a processed file, or its status record.
+func (o *ChunksOutput) WriteFile(ctx context.Context, file *ProcessedFile, cfg *Config) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	var b strings.Builder
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	if status := chunkStatus(file); status != nil {
+		if err := encoder.Encode(status); err != nil {
+			return err
+		}
+	} else {
+		for _, record := range chunkRecords(file, cfg) {
+			if err := encoder.Encode(record); err != nil {
+				return err
+			}
+		}
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	_, err := io.WriteString(o.w, b.String())
+
+	return err
+}
+
+// WriteFooter is a no-op; JSON Lines have no footer.
+func (*ChunksOutput) WriteFooter(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	return nil
+}
+
+// chunkStatus returns the status record of a file with no lines to split,
+// or nil.
+func chunkStatus(file *ProcessedFile) *ChunkStatusRecord {
+	status := &ChunkStatusRecord{SchemaVersion: SchemaVersion, Path: file.Info.RelPath}
+	switch {
+	case file.Info.IsDir:
+		status.Status = chunkStatusDirectory
+	case file.Error != nil:
+		status.Status, status.Error = chunkStatusError, file.Error.Error()
+	case file.Info.IsBinary:
+		status.Status = chunkStatusBinary
+	case file.DuplicateOf != "":
+		status.Status, status.DuplicateOf = chunkStatusDuplicate, file.DuplicateOf
+	case file.Lockfile != nil:
+		status.Status = chunkStatusLockfile
+	case file.IsEmpty:
+		status.Status = chunkStatusEmpty
+	case len(file.Lines) == 0:
+		status.Status = chunkStatusNoContent
+	default:
+		return nil
+	}
+
+	return status
+}
+
+// chunkRecords splits the lines of file into chunks of ChunkLines lines,
+// each starting ChunkLines-ChunkOverlap lines after the one before. A chunk
+// is never empty, and the last one ends with the last line.
+func chunkRecords(file *ProcessedFile, cfg *Config) []ChunkRecord {
+	size, step := cfg.chunkLines(), cfg.chunkLines()-cfg.ChunkOverlap
+	var records []ChunkRecord
+	for start := 0; ; start += step {
+		end := min(start+size, len(file.Lines))
+		lines := file.Lines[start:end]
+
+		var content strings.Builder
+		for i, line := range lines {
+			if i > 0 {
+				content.WriteByte('\n')
+			}
+			content.WriteString(line.Content)
+		}
+		sum := sha256.Sum256([]byte(content.String()))
+		records = append(records, ChunkRecord{
+			SchemaVersion: SchemaVersion,
+			Path:          file.Info.RelPath,
+			ChunkIndex:    len(records),
+			StartLine:     lines[0].LineNumber,
+			EndLine:       lines[len(lines)-1].LineNumber,
+			Content:       content.String(),
+			SHA256:        hex.EncodeToString(sum[:]),
+		})
+
+		if end == len(file.Lines) {
+			return records
+		}
+	}
+}
+
+// validateChunks requires chunk settings that make progress, and only with
+// chunks output.
+func (c *Config) validateChunks() error {
+	if c.ChunkLines < 0 {
+		return fmt.Errorf("--chunk-lines must not be negative, got %d", c.ChunkLines)
+	}
+	if c.ChunkOverlap < 0 {
+		return fmt.Errorf("--chunk-overlap must not be negative, got %d", c.ChunkOverlap)
+	}
+	if (c.ChunkLines > 0 || c.ChunkOverlap > 0) && !c.writesFormat(OutputFormatChunks) {
+		return errors.New("--chunk-lines and --chunk-overlap require --format chunks")
+	}
+	if c.ChunkOverlap >= c.chunkLines() {
+		return fmt.Errorf("--chunk-overlap must be less than --chunk-lines (%d), got %d", c.chunkLines(), c.ChunkOverlap)
+	}
+
+	return nil
+}
+
+// chunkLines returns ChunkLines, or its default.
+func (c *Config) chunkLines() int {
+	if c.ChunkLines > 0 {
+		return c.ChunkLines
+	}
+
+	return DefaultChunkLines
+}
@@ -0,0 +1,205 @@
+package catls
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+func TestChunkRecords(t *testing.T) {
+	lines := make([]FilteredLine, 10)
+	for i := range lines {
+		lines[i] = FilteredLine{LineNumber: i + 1, Content: fmt.Sprintf("line %d", i+1)}
+	}
+	file := &ProcessedFile{Info: scanner.FileInfo{RelPath: "a.txt"}, Lines: lines, TotalLines: len(lines)}
+
+	tests := []struct {
+		name    string
+		size    int
+		overlap int
+		want    string
+	}{
+		{name: "one chunk", size: 20, want: "1-10"},
+		{name: "even split", size: 5, want: "1-5 6-10"},
+		{name: "short last chunk", size: 4, want: "1-4 5-8 9-10"},
+		{name: "overlap", size: 4, overlap: 2, want: "1-4 3-6 5-8 7-10"},
+		{name: "overlap leaving a tail", size: 6, overlap: 1, want: "1-6 6-10"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records := chunkRecords(file, &Config{ChunkLines: tt.size, ChunkOverlap: tt.overlap})
+			var got []string
+			for i, record := range records {
+				if record.ChunkIndex != i {
+					t.Errorf("chunk %d has index %d", i, record.ChunkIndex)
+				}
+				if want := strings.Count(record.Content, "\n") + 1; want != record.EndLine-record.StartLine+1 {
+					t.Errorf("chunk %d spans lines %d-%d but holds %d", i, record.StartLine, record.EndLine, want)
+				}
+				got = append(got, fmt.Sprintf("%d-%d", record.StartLine, record.EndLine))
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("chunks = %s, want %s", strings.Join(got, " "), tt.want)
+			}
+		})
+	}
+}
+
+func TestChunksOutput(t *testing.T) {
+	tmpDir := t.TempDir()
+	long := strings.Repeat("text\n", 1500)
+	writeTree(t, tmpDir, map[string]string{
+		"a.txt":    "one\ntwo\nthree\n",
+		"long.txt": long,
+		"copy.txt": "one\ntwo\nthree\n",
+		"empty.md": "",
+		"blob.bin": "\x00\x01\x02",
+	})
+
+	run := func() []map[string]any {
+		t.Helper()
+		var out bytes.Buffer
+		app, err := New(&Config{Directory: tmpDir, OutputFormat: OutputFormatChunks, Output: &out, ChunkLines: 2, ChunkOverlap: 1})
+		if err != nil {
+			t.Fatalf("New() unexpected error: %v", err)
+		}
+		if err := app.Run(context.Background()); err != nil {
+			t.Fatalf("Run() unexpected error: %v", err)
+		}
+
+		var records []map[string]any
+		for line := range strings.Lines(out.String()) {
+			var record map[string]any
+			if err := json.Unmarshal([]byte(line), &record); err != nil {
+				t.Fatalf("record %q does not parse: %v", line, err)
+			}
+			records = append(records, record)
+		}
+
+		return records
+	}
+
+	first, second := run(), run()
+	if fmt.Sprint(first) != fmt.Sprint(second) {
+		t.Error("two runs over unchanged files wrote different chunks")
+	}
+
+	byPath := make(map[string][]map[string]any)
+	for _, record := range first {
+		path := record["path"].(string)
+		byPath[path] = append(byPath[path], record)
+	}
+
+	if got := byPath["a.txt"]; len(got) != 2 || got[0]["content"] != "one\ntwo" || got[1]["content"] != "two\nthree" {
+		t.Errorf("a.txt chunks = %v, want two overlapping chunks", got)
+	}
+	if got, want := byPath["a.txt"][0]["sha256"], byPath["copy.txt"][0]["sha256"]; got != want {
+		t.Errorf("identical chunks hash to %v and %v", got, want)
+	}
+	// Long files are split whole, not truncated to their first 100 lines
+	if got := byPath["long.txt"]; len(got) != 1499 || got[len(got)-1]["end_line"] != float64(1500) {
+		t.Errorf("long.txt has %d chunks, want 1499 ending at line 1500", len(got))
+	}
+	for path, status := range map[string]string{"empty.md": chunkStatusEmpty, "blob.bin": chunkStatusBinary} {
+		if got := byPath[path]; len(got) != 1 || got[0]["status"] != status {
+			t.Errorf("%s records = %v, want one with status %q", path, got, status)
+		}
+	}
+
+	var out bytes.Buffer
+	runFormatter(t, NewChunksOutput(&out), []ProcessedFile{{
+		Info:  scanner.FileInfo{RelPath: "locked.txt"},
+		Error: errors.New("permission denied"),
+	}}, &Config{})
+	if got := strings.TrimSpace(out.String()); got != `{"schema_version":3,"path":"locked.txt","status":"error","error":"permission denied"}` {
+		t.Errorf("error record = %s", got)
+	}
+}
+
+func TestChunksWithOtherFormats(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{"long.txt": strings.Repeat("text\n", 1500)})
+	outDir := t.TempDir()
+
+	app, err := New(&Config{
+		Directory:     tmpDir,
+		OutputFormat:  OutputFormatJSON,
+		OutputFormats: []OutputFormat{OutputFormatJSON, OutputFormatChunks},
+		OutputDir:     outDir,
+		ChunkLines:    500,
+	})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	// JSON truncates the long file as a JSON run alone would
+	data, err := os.ReadFile(filepath.Join(outDir, OutputFileName(OutputFormatJSON)))
+	if err != nil {
+		t.Fatalf("failed to read JSON output: %v", err)
+	}
+	var doc struct {
+		Files []JSONFile `json:"files"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("JSON output does not parse: %v", err)
+	}
+	if len(doc.Files) != 1 || !doc.Files[0].Truncated || len(doc.Files[0].Lines) != truncateToLines {
+		t.Errorf("JSON file = truncated %v with %d lines, want truncated to %d", doc.Files[0].Truncated, len(doc.Files[0].Lines), truncateToLines)
+	}
+
+	// Chunks still split the whole file
+	data, err = os.ReadFile(filepath.Join(outDir, OutputFileName(OutputFormatChunks)))
+	if err != nil {
+		t.Fatalf("failed to read chunks output: %v", err)
+	}
+	records := strings.Split(strings.TrimSpace(string(data)), "\n")
+	var last map[string]any
+	if err := json.Unmarshal([]byte(records[len(records)-1]), &last); err != nil {
+		t.Fatalf("chunk record does not parse: %v", err)
+	}
+	if last["end_line"] != float64(1500) {
+		t.Errorf("last chunk ends at line %v, want 1500", last["end_line"])
+	}
+}
+
+func TestValidateChunks(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{name: "defaults", cfg: Config{OutputFormat: OutputFormatChunks}},
+		{name: "overlap", cfg: Config{OutputFormat: OutputFormatChunks, ChunkLines: 10, ChunkOverlap: 9}},
+		{name: "overlap as long as a chunk", cfg: Config{OutputFormat: OutputFormatChunks, ChunkLines: 10, ChunkOverlap: 10}, wantErr: "less than --chunk-lines"},
+		{name: "negative lines", cfg: Config{OutputFormat: OutputFormatChunks, ChunkLines: -1}, wantErr: "must not be negative"},
+		{name: "other format", cfg: Config{OutputFormat: OutputFormatJSON, ChunkLines: 10}, wantErr: "require --format chunks"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.validateChunks()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateChunks() unexpected error: %v", err)
+				}
+
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateChunks() error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
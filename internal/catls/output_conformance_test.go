@@ -0,0 +1,676 @@
+package catls
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+// conformanceFiles is the scripted sequence every formatter is run through.
+func conformanceFiles() []ProcessedFile {
+	truncated := make([]FilteredLine, 100)
+	for i := range truncated {
+		truncated[i] = FilteredLine{LineNumber: i + 1, Content: "line"}
+	}
+
+	return []ProcessedFile{
+		{
+			Info:    scanner.FileInfo{Path: "/tmp/empty.txt", RelPath: "empty.txt"},
+			IsEmpty: true,
+		},
+		{
+			Info: scanner.FileInfo{Path: "/tmp/image.png", RelPath: "image.png", IsBinary: true},
+		},
+		{
+			Info:          scanner.FileInfo{Path: "/tmp/locked.go", RelPath: "locked.go"},
+			Error:         errors.New("permission denied <&>"),
+			ErrorCategory: ErrPermission,
+		},
+		{
+			Info:        scanner.FileInfo{Path: "/tmp/big.go", RelPath: "big.go"},
+			FileType:    "go",
+			Lines:       truncated,
+			TotalLines:  2000,
+			IsTruncated: true,
+		},
+		{
+			Info:        scanner.FileInfo{Path: "/tmp/copy.go", RelPath: "copy.go"},
+			FileType:    "go",
+			DuplicateOf: "big.go",
+		},
+		{
+			Info:     scanner.FileInfo{Path: "/tmp/we ird&<>\".md", RelPath: "we ird&<>\".md"},
+			FileType: "markdown",
+			Lines: []FilteredLine{
+				{LineNumber: 1, Content: "<tag attr=\"x\">&amp;</tag>"},
+				{LineNumber: 2, Content: "```go"},
+				{LineNumber: 3, Content: "]]> \t unicode: héllo 世界 🚀"},
+				{LineNumber: 4, Content: "```"},
+			},
+			TotalLines: 4,
+		},
+	}
+}
+
+// formatValidators maps every supported format to an invariant check on its full output.
+var formatValidators = map[OutputFormat]func(t *testing.T, output string, files []ProcessedFile){
+	OutputFormatXML:      validateXMLOutput,
+	OutputFormatJSON:     validateJSONOutput,
+	OutputFormatMarkdown: validateMarkdownOutput,
+	OutputFormatPrompt:   validatePromptOutput,
+	OutputFormatPretty:   validatePrettyOutput,
+	OutputFormatChunks:   validateChunksOutput,
+}
+
+func TestFormatterConformance(t *testing.T) {
+	for _, name := range GetSupportedFormats() {
+		format := OutputFormat(name)
+
+		for _, lineNumbers := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/line-numbers=%v", name, lineNumbers), func(t *testing.T) {
+				validate, ok := formatValidators[format]
+				if !ok {
+					t.Fatalf("no conformance validator registered for format %q", format)
+				}
+
+				var buf bytes.Buffer
+				formatter, err := NewOutputFormatter(format, &buf, nil)
+				if err != nil {
+					t.Fatalf("NewOutputFormatter(%q) error: %v", format, err)
+				}
+
+				files := conformanceFiles()
+				runFormatter(t, formatter, files, &Config{ShowLineNumbers: lineNumbers})
+
+				validate(t, buf.String(), files)
+			})
+		}
+	}
+}
+
+func TestFormatterIsolation(t *testing.T) {
+	for _, name := range GetSupportedFormats() {
+		t.Run(name, func(t *testing.T) {
+			format := OutputFormat(name)
+			files := conformanceFiles()
+
+			var reference bytes.Buffer
+			formatter, err := NewOutputFormatter(format, &reference, nil)
+			if err != nil {
+				t.Fatalf("NewOutputFormatter(%q) error: %v", format, err)
+			}
+			runFormatter(t, formatter, files, &Config{})
+
+			// Independent instances driven concurrently must not observe each other's state.
+			const workers = 8
+			outputs := make([]bytes.Buffer, workers)
+			var wg sync.WaitGroup
+			for i := range workers {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					f, _ := NewOutputFormatter(format, &outputs[i], nil)
+					runFormatter(t, f, files, &Config{})
+				}()
+			}
+			wg.Wait()
+
+			for i := range outputs {
+				if outputs[i].String() != reference.String() {
+					t.Errorf("worker %d output differs from sequential output", i)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatterWriteErrors(t *testing.T) {
+	for _, name := range GetSupportedFormats() {
+		t.Run(name, func(t *testing.T) {
+			formatter, err := NewOutputFormatter(OutputFormat(name), failingWriter{}, nil)
+			if err != nil {
+				t.Fatalf("NewOutputFormatter(%q) error: %v", name, err)
+			}
+
+			ctx := context.Background()
+			errs := []error{formatter.WriteHeader(ctx)}
+			for _, file := range conformanceFiles() {
+				errs = append(errs, formatter.WriteFile(ctx, &file, &Config{}))
+			}
+			errs = append(errs, formatter.WriteFooter(ctx))
+
+			if errors.Join(errs...) == nil {
+				t.Error("expected write failure to be reported, got nil errors")
+			}
+		})
+	}
+}
+
+func TestFormatterCancelledContext(t *testing.T) {
+	for _, name := range GetSupportedFormats() {
+		t.Run(name, func(t *testing.T) {
+			formatter, err := NewOutputFormatter(OutputFormat(name), io.Discard, nil)
+			if err != nil {
+				t.Fatalf("NewOutputFormatter(%q) error: %v", name, err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			files := conformanceFiles()
+			if err := formatter.WriteHeader(ctx); !errors.Is(err, context.Canceled) {
+				t.Errorf("WriteHeader() error = %v, want context.Canceled", err)
+			}
+			if err := formatter.WriteFile(ctx, &files[0], &Config{}); !errors.Is(err, context.Canceled) {
+				t.Errorf("WriteFile() error = %v, want context.Canceled", err)
+			}
+			if err := formatter.WriteFooter(ctx); !errors.Is(err, context.Canceled) {
+				t.Errorf("WriteFooter() error = %v, want context.Canceled", err)
+			}
+		})
+	}
+}
+
+// runFormatter drives a formatter through header, files, and footer.
+func runFormatter(t *testing.T, formatter OutputFormatter, files []ProcessedFile, cfg *Config) {
+	t.Helper()
+
+	ctx := context.Background()
+	if err := formatter.WriteHeader(ctx); err != nil {
+		t.Errorf("WriteHeader() error: %v", err)
+	}
+	for i := range files {
+		if err := formatter.WriteFile(ctx, &files[i], cfg); err != nil {
+			t.Errorf("WriteFile(%s) error: %v", files[i].Info.RelPath, err)
+		}
+	}
+	if err := formatter.WriteFooter(ctx); err != nil {
+		t.Errorf("WriteFooter() error: %v", err)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func validateXMLOutput(t *testing.T, output string, files []ProcessedFile) {
+	t.Helper()
+
+	var doc struct {
+		Files []struct {
+			Path  string `xml:"path,attr"`
+			Error struct {
+				Text     string `xml:",chardata"`
+				Category string `xml:"category,attr"`
+			} `xml:"error"`
+			Binary      bool   `xml:"binary"`
+			Empty       bool   `xml:"empty"`
+			DuplicateOf string `xml:"duplicate-of"`
+			Content     struct {
+				Text      string `xml:",chardata"`
+				Truncated bool   `xml:"truncated,attr"`
+				Remaining int    `xml:"remaining-lines,attr"`
+			} `xml:"content"`
+		} `xml:"file"`
+	}
+	if err := xml.Unmarshal([]byte(output), &doc); err != nil {
+		t.Fatalf("xml output does not parse: %v\noutput:\n%s", err, output)
+	}
+
+	if len(doc.Files) != len(files) {
+		t.Fatalf("xml output has %d files, want %d", len(doc.Files), len(files))
+	}
+	for i, f := range doc.Files {
+		want := files[i]
+		if f.Path != want.Info.RelPath {
+			t.Errorf("xml file %d path = %q, want %q", i, f.Path, want.Info.RelPath)
+		}
+		if (want.Error != nil) != (f.Error.Text != "") || f.Error.Category != string(want.ErrorCategory) {
+			t.Errorf("xml file %d error = %+v, want error %v (%s)", i, f.Error, want.Error, want.ErrorCategory)
+		}
+		if f.Binary != want.Info.IsBinary {
+			t.Errorf("xml file %d binary = %v, want %v", i, f.Binary, want.Info.IsBinary)
+		}
+		if f.Empty != want.IsEmpty {
+			t.Errorf("xml file %d empty = %v, want %v", i, f.Empty, want.IsEmpty)
+		}
+		if f.DuplicateOf != want.DuplicateOf {
+			t.Errorf("xml file %d duplicate-of = %q, want %q", i, f.DuplicateOf, want.DuplicateOf)
+		}
+		if f.Content.Truncated != want.IsTruncated || f.Content.Remaining != remainingLines(&want) {
+			t.Errorf("xml file %d truncated=%v remaining-lines=%d, want %v and %d",
+				i, f.Content.Truncated, f.Content.Remaining, want.IsTruncated, remainingLines(&want))
+		}
+		if strings.Contains(f.Content.Text, "more lines)") {
+			t.Errorf("xml file %d has a truncation notice inside its content", i)
+		}
+		for _, line := range want.Lines {
+			if !strings.Contains(f.Content.Text, line.Content) {
+				t.Errorf("xml file %d content missing line %q", i, line.Content)
+			}
+		}
+	}
+}
+
+func validateJSONOutput(t *testing.T, output string, files []ProcessedFile) {
+	t.Helper()
+
+	var doc struct {
+		Files []JSONFile `json:"files"`
+	}
+	if err := json.Unmarshal([]byte(output), &doc); err != nil {
+		t.Fatalf("json output does not parse: %v\noutput:\n%s", err, output)
+	}
+
+	if len(doc.Files) != len(files) {
+		t.Fatalf("json output has %d files, want %d", len(doc.Files), len(files))
+	}
+	for i, f := range doc.Files {
+		want := files[i]
+		if f.Path != want.Info.RelPath {
+			t.Errorf("json file %d path = %q, want %q", i, f.Path, want.Info.RelPath)
+		}
+		if (want.Error != nil) != (f.Error != nil) || f.ErrorCategory != string(want.ErrorCategory) {
+			t.Errorf("json file %d error = %v (%s), want error %v (%s)", i, f.Error, f.ErrorCategory, want.Error, want.ErrorCategory)
+		}
+		if f.Empty != want.IsEmpty {
+			t.Errorf("json file %d empty = %v, want %v", i, f.Empty, want.IsEmpty)
+		}
+		if f.DuplicateOf != want.DuplicateOf {
+			t.Errorf("json file %d duplicateOf = %q, want %q", i, f.DuplicateOf, want.DuplicateOf)
+		}
+		if f.Truncated != want.IsTruncated || f.Remaining != remainingLines(&want) {
+			t.Errorf("json file %d truncated = %v remainingLines = %d, want %v and %d",
+				i, f.Truncated, f.Remaining, want.IsTruncated, remainingLines(&want))
+		}
+		if want.Error == nil && len(f.Lines) != len(want.Lines) {
+			t.Errorf("json file %d has %d lines, want %d", i, len(f.Lines), len(want.Lines))
+		}
+	}
+}
+
+func validateChunksOutput(t *testing.T, output string, files []ProcessedFile) {
+	t.Helper()
+
+	var records []map[string]any
+	for line := range strings.Lines(output) {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("chunks output line does not parse: %v\nline: %s", err, line)
+		}
+		records = append(records, record)
+	}
+
+	i := 0
+	for _, want := range files {
+		if i == len(records) {
+			t.Fatalf("chunks output ends before %s", want.Info.RelPath)
+		}
+		if path := records[i]["path"]; path != want.Info.RelPath {
+			t.Fatalf("chunks record %d path = %v, want %q", i, path, want.Info.RelPath)
+		}
+		if status, ok := records[i]["status"]; ok {
+			if len(want.Lines) > 0 && want.Error == nil {
+				t.Errorf("chunks output gives %s status %v, want its lines", want.Info.RelPath, status)
+			}
+			i++
+
+			continue
+		}
+
+		lines := 0
+		for ; i < len(records) && records[i]["path"] == want.Info.RelPath; i++ {
+			lines += int(records[i]["end_line"].(float64)-records[i]["start_line"].(float64)) + 1
+		}
+		if lines != len(want.Lines) {
+			t.Errorf("chunks of %s cover %d lines, want %d", want.Info.RelPath, lines, len(want.Lines))
+		}
+	}
+	if i != len(records) {
+		t.Errorf("chunks output has %d records after the last file", len(records)-i)
+	}
+}
+
+func validateMarkdownOutput(t *testing.T, output string, files []ProcessedFile) {
+	t.Helper()
+
+	var headings []string
+	openFence := ""
+	for _, line := range strings.Split(output, "\n") {
+		if openFence != "" {
+			if line == openFence {
+				openFence = ""
+			}
+			if strings.Contains(line, "more lines)") {
+				t.Errorf("markdown truncation notice is inside a fence: %q", line)
+			}
+
+			continue
+		}
+
+		if strings.HasPrefix(line, "```") {
+			openFence = line[:len(line)-len(strings.TrimLeft(line, "`"))]
+
+			continue
+		}
+
+		if heading, ok := strings.CutPrefix(line, "## "); ok {
+			headings = append(headings, heading)
+		}
+	}
+
+	if openFence != "" {
+		t.Fatalf("markdown output has an unterminated %q fence\noutput:\n%s", openFence, output)
+	}
+
+	if len(headings) != len(files) {
+		t.Fatalf("markdown output has %d headings outside fences, want %d", len(headings), len(files))
+	}
+	for i, heading := range headings {
+		if heading != files[i].Info.RelPath {
+			t.Errorf("markdown heading %d = %q, want %q", i, heading, files[i].Info.RelPath)
+		}
+	}
+}
+
+// promptOpenTag matches the line opening a prompt file block.
+var promptOpenTag = regexp.MustCompile(`^<(file(?:-[0-9a-f]{6})?) path="([^"]*)"([^>]*?)(/?)>$`)
+
+func validatePromptOutput(t *testing.T, output string, files []ProcessedFile) {
+	t.Helper()
+
+	preamble := fmt.Sprintf("The following %d files", len(files))
+	if !strings.HasPrefix(output, preamble) {
+		t.Errorf("prompt output does not start with %q\noutput:\n%s", preamble, output)
+	}
+
+	var paths []string
+	closing := ""
+	var content []string
+	for _, line := range strings.Split(output, "\n") {
+		if closing != "" {
+			if line == closing {
+				want := files[len(paths)-1]
+				for _, l := range want.Lines {
+					if !strings.Contains(strings.Join(content, "\n"), l.Content) {
+						t.Errorf("prompt file %s content missing line %q", want.Info.RelPath, l.Content)
+					}
+				}
+				closing, content = "", nil
+			} else {
+				content = append(content, line)
+			}
+
+			continue
+		}
+
+		match := promptOpenTag.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		paths = append(paths, html.UnescapeString(match[2]))
+		want := files[len(paths)-1]
+		if selfClosing := match[4] == "/"; selfClosing != (want.Error != nil || want.Info.IsBinary || want.DuplicateOf != "") {
+			t.Errorf("prompt file %s self-closing = %v", want.Info.RelPath, selfClosing)
+		}
+		if want.DuplicateOf != "" && !strings.Contains(match[3], `duplicate-of="`+want.DuplicateOf+`"`) {
+			t.Errorf("prompt file %s attributes %q, want duplicate-of %q", want.Info.RelPath, match[3], want.DuplicateOf)
+		}
+		if want.IsTruncated != strings.Contains(match[3], `truncated="true"`) {
+			t.Errorf("prompt file %s attributes %q, want truncated %v", want.Info.RelPath, match[3], want.IsTruncated)
+		}
+		if match[4] == "" {
+			closing = "</" + match[1] + ">"
+		}
+	}
+
+	if closing != "" {
+		t.Fatalf("prompt output has an unterminated block, want %q\noutput:\n%s", closing, output)
+	}
+
+	if len(paths) != len(files) {
+		t.Fatalf("prompt output has %d file blocks, want %d", len(paths), len(files))
+	}
+	for i, path := range paths {
+		if path != files[i].Info.RelPath {
+			t.Errorf("prompt block %d path = %q, want %q", i, path, files[i].Info.RelPath)
+		}
+	}
+}
+
+func validatePrettyOutput(t *testing.T, output string, files []ProcessedFile) {
+	t.Helper()
+
+	if strings.Contains(output, "\x1b") {
+		t.Errorf("pretty output without Color contains escape sequences:\n%s", output)
+	}
+
+	var paths []string
+	var content []string
+	checkContent := func() {
+		if len(paths) == 0 {
+			return
+		}
+		want := files[len(paths)-1]
+		for _, l := range want.Lines {
+			if !strings.Contains(strings.Join(content, "\n"), l.Content) {
+				t.Errorf("pretty file %s content missing line %q", want.Info.RelPath, l.Content)
+			}
+		}
+		if remaining := remainingLines(&want); remaining > 0 && !strings.Contains(strings.Join(content, "\n"), fmt.Sprintf("%d more lines", remaining)) {
+			t.Errorf("pretty file %s does not report %d more lines", want.Info.RelPath, remaining)
+		}
+	}
+	for _, line := range strings.Split(output, "\n") {
+		header, ok := strings.CutPrefix(line, "── ")
+		if !ok {
+			content = append(content, line)
+
+			continue
+		}
+
+		checkContent()
+		path, _, _ := strings.Cut(header, " · ")
+		paths = append(paths, path)
+		content = nil
+	}
+	checkContent()
+
+	if len(paths) != len(files) {
+		t.Fatalf("pretty output has %d headers, want %d\noutput:\n%s", len(paths), len(files), output)
+	}
+	for i, path := range paths {
+		if path != files[i].Info.RelPath {
+			t.Errorf("pretty header %d path = %q, want %q", i, path, files[i].Info.RelPath)
+		}
+	}
+}
+
+func TestRegisterFormat(t *testing.T) {
+	custom := FormatInfo{
+		Name:        "custom",
+		Description: "Test-only format",
+		New:         func(w io.Writer, _ FormatOptions) (OutputFormatter, error) { return NewJSONOutput(w), nil },
+	}
+	if err := RegisterFormat(custom); err != nil {
+		t.Fatalf("RegisterFormat() unexpected error: %v", err)
+	}
+	t.Cleanup(func() {
+		formatRegistry.mu.Lock()
+		defer formatRegistry.mu.Unlock()
+		formatRegistry.formats = formatRegistry.formats[:len(formatRegistry.formats)-1]
+	})
+
+	if !OutputFormat("custom").IsValid() {
+		t.Error("registered format should be valid")
+	}
+	if got := GetSupportedFormats(); got[len(got)-1] != "custom" {
+		t.Errorf("GetSupportedFormats() = %v, want custom last", got)
+	}
+
+	var buf bytes.Buffer
+	if err := RenderSample(context.Background(), "custom", &buf); err != nil {
+		t.Fatalf("RenderSample() unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "example/hello.go") {
+		t.Errorf("RenderSample() output missing sample path:\n%s", buf.String())
+	}
+
+	for _, bad := range []FormatInfo{custom, {Name: "", New: custom.New}, {Name: "nil-constructor"}} {
+		if err := RegisterFormat(bad); err == nil {
+			t.Errorf("RegisterFormat(%q) expected error", bad.Name)
+		}
+	}
+}
+
+func TestFormatOptions(t *testing.T) {
+	file := ProcessedFile{
+		Info:       scanner.FileInfo{Path: "/tmp/main.go", RelPath: "main.go"},
+		FileType:   "go",
+		Lines:      []FilteredLine{{LineNumber: 1, Content: "a < b ]]> c"}},
+		TotalLines: 1,
+	}
+	dir := ProcessedFile{Info: scanner.FileInfo{Path: "/tmp/docs", RelPath: "docs", IsDir: true}}
+
+	tests := []struct {
+		name    string
+		format  OutputFormat
+		raw     map[string]string
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "xml indent",
+			format: OutputFormatXML,
+			raw:    map[string]string{"xml:indent": "2"},
+			want:   "<files>\n  <file path=\"main.go\">\n    <type>go</type>\n    <content>\na &lt; b ]]&gt; c\n</content>\n  </file>\n  <dir path=\"docs\"/>\n</files>\n",
+		},
+		{
+			name:   "xml cdata",
+			format: OutputFormatXML,
+			raw:    map[string]string{"cdata": "true"},
+			want:   "<content><![CDATA[\na < b ]]]]><![CDATA[> c\n]]></content>\n",
+		},
+		{
+			name:   "markdown heading level",
+			format: OutputFormatMarkdown,
+			raw:    map[string]string{"markdown:heading-level": "3"},
+			want:   "### main.go\n",
+		},
+		{
+			name:   "markdown directory below heading level",
+			format: OutputFormatMarkdown,
+			raw:    map[string]string{"markdown:heading-level": "3"},
+			want:   "#### docs/ (directory)\n",
+		},
+		{
+			name:   "json compact",
+			format: OutputFormatJSON,
+			raw:    map[string]string{"json:pretty": "false"},
+			want:   `{"schemaVersion":3,"files":[{"path":"main.go","type":"go"`,
+		},
+		{
+			name:   "json pretty by default",
+			format: OutputFormatJSON,
+			want:   "{\n  \"schemaVersion\": 3,\n  \"files\": [\n",
+		},
+		{
+			name:    "unknown key",
+			format:  OutputFormatXML,
+			raw:     map[string]string{"xml:colour": "red"},
+			wantErr: "unknown --format-opt xml:colour for xml output",
+		},
+		{
+			name:    "option for another format",
+			format:  OutputFormatXML,
+			raw:     map[string]string{"markdown:heading-level": "3"},
+			wantErr: "--format-opt markdown:heading-level does not apply to xml output",
+		},
+		{
+			name:    "unknown format prefix",
+			format:  OutputFormatXML,
+			raw:     map[string]string{"yaml:indent": "2"},
+			wantErr: "names unknown output format yaml",
+		},
+		{
+			name:    "bad integer",
+			format:  OutputFormatXML,
+			raw:     map[string]string{"indent": "wide"},
+			wantErr: `expects an integer, got "wide"`,
+		},
+		{
+			name:    "bad boolean",
+			format:  OutputFormatJSON,
+			raw:     map[string]string{"pretty": "sometimes"},
+			wantErr: `expects true or false, got "sometimes"`,
+		},
+		{
+			name:    "heading level out of range",
+			format:  OutputFormatMarkdown,
+			raw:     map[string]string{"heading-level": "6"},
+			wantErr: "must be between 1 and 5, got 6",
+		},
+		{
+			name:    "prompt has no options",
+			format:  OutputFormatPrompt,
+			raw:     map[string]string{"indent": "2"},
+			wantErr: "unknown --format-opt prompt:indent",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := ParseFormatOptions(tt.format, tt.raw)
+			var formatter OutputFormatter
+			var buf bytes.Buffer
+			if err == nil {
+				formatter, err = NewOutputFormatter(tt.format, &buf, opts)
+			}
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			runFormatter(t, formatter, []ProcessedFile{file, dir}, &Config{})
+			if !strings.Contains(buf.String(), tt.want) {
+				t.Errorf("output = %q, want it to contain %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatOptionsPrefixedKeyWins(t *testing.T) {
+	raw := map[string]string{"xml:indent": "2", "indent": "4"}
+
+	// Map order varies between runs, so parse often enough to see both
+	for range 50 {
+		opts, err := ParseFormatOptions(OutputFormatXML, raw)
+		if err != nil {
+			t.Fatalf("ParseFormatOptions() unexpected error: %v", err)
+		}
+		if opts["indent"] != "2" {
+			t.Fatalf("indent = %q, want the prefixed value 2", opts["indent"])
+		}
+	}
+}
@@ -1,27 +1,329 @@
 // Package catls implements the core functionality for concatenating and formatting file listings.
 package catls
 
-import "fmt"
-
-// NewOutputFormatter creates an output formatter for the specified format.
-func NewOutputFormatter(format OutputFormat) (OutputFormatter, error) {
-	switch format {
-	case OutputFormatXML:
-		return NewXMLOutput(), nil
-	case OutputFormatJSON:
-		return NewJSONOutput(), nil
-	case OutputFormatMarkdown:
-		return NewMarkdownOutput(), nil
-	default:
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+// FormatInfo describes a registered output format.
+type FormatInfo struct {
+	Name          OutputFormat
+	Description   string         // One-line summary shown by `catls formats`
+	Extension     string         // Extension of files holding this format, without the dot
+	Options       []string       // Flags that change this format's output
+	FormatOptions []FormatOption // Keys accepted through --format-opt
+	// New creates a formatter writing to w. It must reject keys in opts that
+	// the format does not recognize, and values it cannot parse.
+	New func(w io.Writer, opts FormatOptions) (OutputFormatter, error)
+}
+
+// FileExtension returns Extension, or "txt" for formats registered without one.
+func (info FormatInfo) FileExtension() string {
+	if info.Extension == "" {
+		return "txt"
+	}
+
+	return info.Extension
+}
+
+// FormatOptions are format-specific settings given with --format-opt, keyed
+// by option name without the format prefix.
+type FormatOptions map[string]string
+
+// FormatOption documents a key a format accepts through --format-opt.
+type FormatOption struct {
+	Key         string
+	Value       string // Placeholder for the value, such as N or BOOL
+	Description string
+}
+
+// formatRegistry holds every known output format in registration order.
+var formatRegistry = struct {
+	mu      sync.RWMutex
+	formats []FormatInfo
+}{}
+
+func init() {
+	for _, info := range []FormatInfo{
+		{
+			Name:        OutputFormatXML,
+			Extension:   "xml",
+			Description: "XML document with one <file> element per file",
+			Options: []string{
+				"--line-numbers", "--line-number-format", "--todos", "--max-tokens", "--no-config-echo",
+				"--project-header", "--content-encoding", "--integrity",
+			},
+			FormatOptions: []FormatOption{
+				{Key: "indent", Value: "N", Description: "Indent nested elements by N spaces (default 0)"},
+				{Key: "cdata", Value: "BOOL", Description: "Wrap content in CDATA sections instead of escaping it"},
+			},
+			New: func(w io.Writer, opts FormatOptions) (OutputFormatter, error) {
+				return newXMLOutputWithOptions(w, opts)
+			},
+		},
+		{
+			Name:        OutputFormatJSON,
+			Extension:   "json",
+			Description: "Single JSON object with a files array; lines always carry their numbers",
+			Options: []string{
+				"--todos", "--max-tokens", "--no-config-echo", "--project-header", "--content-encoding",
+				"--integrity",
+			},
+			FormatOptions: []FormatOption{
+				{Key: "pretty", Value: "BOOL", Description: "Indent the document (default true; false writes one line)"},
+			},
+			New: func(w io.Writer, opts FormatOptions) (OutputFormatter, error) {
+				return newJSONOutputWithOptions(w, opts)
+			},
+		},
+		{
+			Name:        OutputFormatMarkdown,
+			Extension:   "md",
+			Description: "A heading per file followed by a syntax-highlighted code block",
+			Options: []string{
+				"--line-numbers", "--line-number-format", "--fence-style", "--sentinel",
+				"--todos", "--max-tokens", "--embed-images", "--no-config-echo", "--project-header",
+				"--integrity",
+			},
+			FormatOptions: []FormatOption{
+				{Key: "heading-level", Value: "N", Description: "Heading level of file headings, 1-5 (default 2)"},
+			},
+			New: func(w io.Writer, opts FormatOptions) (OutputFormatter, error) {
+				return newMarkdownOutputWithOptions(w, opts)
+			},
+		},
+		{
+			Name:        OutputFormatPrompt,
+			Extension:   "txt",
+			Description: "LLM-ready text: a preamble, one <file> block per file, and a list of omitted files",
+			Options:     []string{"--line-numbers", "--line-number-format", "--max-tokens", "--project-header", "--integrity"},
+			New: func(w io.Writer, opts FormatOptions) (OutputFormatter, error) {
+				if err := opts.checkKeys(OutputFormatPrompt); err != nil {
+					return nil, err
+				}
+
+				return NewPromptOutput(w), nil
+			},
+		},
+		{
+			Name:        OutputFormatPretty,
+			Extension:   "txt",
+			Description: "Terminal view: a header line per file and its content, syntax highlighted with --color",
+			Options:     []string{"--line-numbers", "--line-number-format", "--color", "--theme", "--project-header"},
+			New: func(w io.Writer, opts FormatOptions) (OutputFormatter, error) {
+				if err := opts.checkKeys(OutputFormatPretty); err != nil {
+					return nil, err
+				}
+
+				return NewPrettyOutput(w), nil
+			},
+		},
+		{
+			Name:        OutputFormatChunks,
+			Extension:   "jsonl",
+			Description: "JSON Lines for embedding pipelines: one record per overlapping chunk of each file",
+			Options:     []string{"--chunk-lines", "--chunk-overlap"},
+			New: func(w io.Writer, opts FormatOptions) (OutputFormatter, error) {
+				if err := opts.checkKeys(OutputFormatChunks); err != nil {
+					return nil, err
+				}
+
+				return NewChunksOutput(w), nil
+			},
+		},
+	} {
+		if err := RegisterFormat(info); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterFormat makes an output format available to NewOutputFormatter,
+// validation, and `catls formats`. Names must be unique.
+func RegisterFormat(info FormatInfo) error {
+	if info.Name == "" {
+		return errors.New("output format name must not be empty")
+	}
+	if info.New == nil {
+		return fmt.Errorf("output format %s has no constructor", info.Name)
+	}
+
+	formatRegistry.mu.Lock()
+	defer formatRegistry.mu.Unlock()
+
+	for _, existing := range formatRegistry.formats {
+		if existing.Name == info.Name {
+			return fmt.Errorf("output format %s is already registered", info.Name)
+		}
+	}
+
+	formatRegistry.formats = append(formatRegistry.formats, info)
+
+	return nil
+}
+
+// Formats returns every registered output format in registration order.
+func Formats() []FormatInfo {
+	formatRegistry.mu.RLock()
+	defer formatRegistry.mu.RUnlock()
+
+	return append([]FormatInfo(nil), formatRegistry.formats...)
+}
+
+// LookupFormat returns the registration for format.
+func LookupFormat(format OutputFormat) (FormatInfo, bool) {
+	formatRegistry.mu.RLock()
+	defer formatRegistry.mu.RUnlock()
+
+	for _, info := range formatRegistry.formats {
+		if info.Name == format {
+			return info, true
+		}
+	}
+
+	return FormatInfo{}, false
+}
+
+// NewOutputFormatter creates an output formatter for the specified format that
+// writes to w. opts may be nil; unrecognized options are an error.
+func NewOutputFormatter(format OutputFormat, w io.Writer, opts FormatOptions) (OutputFormatter, error) {
+	info, ok := LookupFormat(format)
+	if !ok {
 		return nil, fmt.Errorf("unsupported output format: %s", format)
 	}
+
+	return info.New(w, opts)
+}
+
+// ParseFormatOptions selects the --format-opt values that apply to format.
+// Keys may be written as "format:key" or, for the selected format, as a bare
+// "key"; when both are given, the prefixed key wins, whatever their order.
+// Prefixed keys naming another registered format are an error, since they
+// would otherwise be silently ignored.
+func ParseFormatOptions(format OutputFormat, raw map[string]string) (FormatOptions, error) {
+	opts := make(FormatOptions, len(raw))
+	prefixed := make(map[string]bool, len(raw))
+	for key, value := range raw {
+		prefix, name, found := strings.Cut(key, ":")
+		if !found {
+			if !prefixed[key] {
+				opts[key] = value
+			}
+
+			continue
+		}
+
+		if OutputFormat(prefix) != format {
+			if _, ok := LookupFormat(OutputFormat(prefix)); ok {
+				return nil, fmt.Errorf("--format-opt %s does not apply to %s output", key, format)
+			}
+
+			return nil, fmt.Errorf("--format-opt %s names unknown output format %s", key, prefix)
+		}
+		opts[name] = value
+		prefixed[name] = true
+	}
+
+	return opts, nil
+}
+
+// checkKeys rejects any option not listed in the registration of format.
+func (o FormatOptions) checkKeys(format OutputFormat) error {
+	info, _ := LookupFormat(format)
+
+	for key := range o {
+		known := false
+		for _, option := range info.FormatOptions {
+			known = known || option.Key == key
+		}
+		if !known {
+			return fmt.Errorf("unknown --format-opt %s:%s for %s output", format, key, format)
+		}
+	}
+
+	return nil
+}
+
+// intOption parses an integer option, returning fallback when it is unset.
+func (o FormatOptions) intOption(format OutputFormat, key string, fallback int) (int, error) {
+	value, ok := o[key]
+	if !ok {
+		return fallback, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("--format-opt %s:%s expects an integer, got %q", format, key, value)
+	}
+
+	return n, nil
+}
+
+// boolOption parses a boolean option, returning fallback when it is unset.
+func (o FormatOptions) boolOption(format OutputFormat, key string, fallback bool) (bool, error) {
+	value, ok := o[key]
+	if !ok {
+		return fallback, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("--format-opt %s:%s expects true or false, got %q", format, key, value)
+	}
+
+	return b, nil
 }
 
 // GetSupportedFormats returns a list of all supported output formats.
 func GetSupportedFormats() []string {
-	return []string{
-		OutputFormatXML.String(),
-		OutputFormatJSON.String(),
-		OutputFormatMarkdown.String(),
+	formats := Formats()
+	names := make([]string, len(formats))
+	for i, info := range formats {
+		names[i] = info.Name.String()
+	}
+
+	return names
+}
+
+// sampleFile is the canned file rendered by RenderSample.
+func sampleFile() ProcessedFile {
+	return ProcessedFile{
+		Info:     scanner.FileInfo{Path: "example/hello.go", RelPath: "example/hello.go"},
+		FileType: "go",
+		Lines: []FilteredLine{
+			{LineNumber: 1, Content: "package main"},
+			{LineNumber: 2, Content: ""},
+			{LineNumber: 3, Content: `func main() { println("hello") }`},
+		},
+		TotalLines: 3,
 	}
-}
\ No newline at end of file
+}
+
+// RenderSample writes a small canned file through the given format so its
+// structure can be inspected.
+func RenderSample(ctx context.Context, format OutputFormat, w io.Writer) error {
+	formatter, err := NewOutputFormatter(format, w, nil)
+	if err != nil {
+		return err
+	}
+
+	file := sampleFile()
+	cfg := &Config{OutputFormat: format}
+
+	if err := formatter.WriteHeader(ctx); err != nil {
+		return err
+	}
+	if err := formatter.WriteFile(ctx, &file, cfg); err != nil {
+		return err
+	}
+
+	return formatter.WriteFooter(ctx)
+}
@@ -3,6 +3,27 @@ package catls
 import "context"
 
 // OutputFormatter defines the interface for different output formats.
+//
+// The contract every formatter must honor:
+//
+//   - Ordering: WriteHeader is called exactly once before any WriteFile call,
+//     WriteFile is called once per file in output order, and WriteFooter is
+//     called exactly once after the last WriteFile. Formatters must emit files
+//     in the order they are received.
+//   - Single writer: a formatter owns its io.Writer and all of its state. Two
+//     formatter instances never share state, so separate instances may be used
+//     from separate goroutines. A single instance serializes its own calls, but
+//     callers are still responsible for delivering files in order.
+//   - Errors: a ProcessedFile carrying an Error is rendered in-band and is not
+//     a formatter error. A non-nil return means the output itself could not be
+//     produced (write failure or cancelled context), and the caller should stop.
+//   - Directories: a ProcessedFile whose Info.IsDir is set is a structural
+//     record with no content and must be rendered distinctly from files.
+//   - Streaming: WriteFile writes the file, or everything needed to write it,
+//     before returning, and keeps nothing of its content afterwards. Memory
+//     therefore grows with the largest file, never with the total output;
+//     only small per-file metadata, such as omitted-directory counts, may be
+//     kept for WriteFooter.
 type OutputFormatter interface {
 	// WriteHeader writes the opening structure for the output format.
 	WriteHeader(ctx context.Context) error
@@ -21,6 +42,9 @@ const (
 	OutputFormatXML      OutputFormat = "xml"
 	OutputFormatJSON     OutputFormat = "json"
 	OutputFormatMarkdown OutputFormat = "markdown"
+	OutputFormatPrompt   OutputFormat = "prompt"
+	OutputFormatPretty   OutputFormat = "pretty"
+	OutputFormatChunks   OutputFormat = "chunks"
 )
 
 // String returns the string representation of the output format.
@@ -28,12 +52,9 @@ func (f OutputFormat) String() string {
 	return string(f)
 }
 
-// IsValid checks if the output format is supported.
+// IsValid checks if the output format is registered.
 func (f OutputFormat) IsValid() bool {
-	switch f {
-	case OutputFormatXML, OutputFormatJSON, OutputFormatMarkdown:
-		return true
-	default:
-		return false
-	}
-}
\ No newline at end of file
+	_, ok := LookupFormat(f)
+
+	return ok
+}
@@ -1,53 +1,135 @@
 package catls
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
-	"os"
+	"io"
+	"strings"
+	"sync"
 )
 
-// JSONOutput handles JSON output formatting.
+// JSONOutput handles JSON output formatting. The document is streamed: the
+// fields ahead of the files are written with the header, each file object is
+// encoded and written as it arrives, and the closing fields are written with
+// the footer, so no more than one file is held in memory at a time.
 type JSONOutput struct {
-	files []JSONFile
+	mu        sync.Mutex
+	w         io.Writer
+	buf       bytes.Buffer            // Holds the value being encoded, one at a time
+	started   bool                    // The opening brace and leading fields are written
+	fields    int                     // Top-level fields written so far
+	filesOpen bool                    // The "files" array is open
+	files     int                     // File objects written so far
+	todos     TodoIndex               // Todo index that arrived after the files began, written in the footer
+	meta      *ConfigEcho             // Configuration echo, written ahead of the files
+	proj      *ProjectHeader          // Project header, written ahead of the files
+	dirs      []JSONOmittedDir        // Directories cut short by MaxFilesPerDir
+	pretty    bool                    // Indent the document; otherwise it is written on one line
+	trailer   func() IntegrityTrailer // Integrity trailer written as the last field (nil for none)
 }
 
-// JSONFile represents a file in JSON format.
+// jsonBufferKeep is the largest encoding buffer kept for the next file; one
+// grown by a very large file is released rather than held for the run.
+const jsonBufferKeep = 1 << 20
+
+// jsonKindDirectory is the JSONFile kind of directory records.
+const jsonKindDirectory = "directory"
+
+// JSONFile represents a file in JSON format. Directory records carry
+// Kind "directory"; Kind is omitted for regular files.
 type JSONFile struct {
-	Path       string     `json:"path"`
-	Type       string     `json:"type,omitempty"`
-	Binary     bool       `json:"binary"`
-	Error      *string    `json:"error,omitempty"`
-	Lines      []JSONLine `json:"lines,omitempty"`
-	TotalLines int        `json:"totalLines"`
-	Truncated  bool       `json:"truncated"`
+	Path              string               `json:"path"`
+	Kind              string               `json:"kind,omitempty"`
+	Type              string               `json:"type,omitempty"`
+	Binary            bool                 `json:"binary"`
+	Executable        bool                 `json:"executable,omitempty"`
+	Xattrs            []string             `json:"xattrs,omitempty"` // Names of extended attributes, with Xattrs
+	Empty             bool                 `json:"empty,omitempty"`
+	Readme            bool                 `json:"readme,omitempty"`
+	Reformatted       bool                 `json:"reformatted,omitempty"`       // Lines are re-indented JSON or YAML, not the text on disk
+	ReformatError     string               `json:"reformatError,omitempty"`     // Why the content could not be re-indented and is shown as it is
+	Reduced           string               `json:"reduced,omitempty"`           // How Signatures reduced the content, "signatures" or "head"
+	ModifiedDuringRun bool                 `json:"modifiedDuringRun,omitempty"` // The file changed between the scan and the read
+	DuplicateOf       string               `json:"duplicateOf,omitempty"`       // Path of the earlier file with identical content
+	LockfileSummary   *JSONLockfileSummary `json:"lockfileSummary,omitempty"`   // Written in place of the lines of a lockfile
+	Error             *string              `json:"error,omitempty"`
+	ErrorCategory     string               `json:"errorCategory,omitempty"` // Why Error occurred, such as "permission"
+	Lines             []JSONLine           `json:"lines,omitempty"`
+	TotalLines        int                  `json:"totalLines"`
+	Truncated         bool                 `json:"truncated"`
+	Remaining         int                  `json:"remainingLines,omitempty"`    // Lines left out when Truncated
+	MatchesSuppressed bool                 `json:"matchesSuppressed,omitempty"` // MaxMatches left out further matching lines
+	ContentB64        *string              `json:"contentB64,omitempty"`        // Exact bytes, base64-encoded, with ContentEncodingBase64
+}
+
+// JSONLockfileSummary describes a lockfile whose content SummarizeLockfiles
+// left out. Dependencies is omitted when the lockfile could not be parsed.
+type JSONLockfileSummary struct {
+	Format       string   `json:"format"`
+	Dependencies *int     `json:"dependencies,omitempty"`
+	Direct       []string `json:"direct,omitempty"`
+	Bytes        int64    `json:"bytes"`
+	SHA256       string   `json:"sha256"`
+}
+
+// JSONOmittedDir records the files MaxFilesPerDir left out of a directory.
+type JSONOmittedDir struct {
+	Dir     string `json:"dir"`     // Directory path with a trailing separator
+	Omitted int    `json:"omitted"` // Files left out after the last one written
 }
 
 // JSONLine represents a line of content with its number.
 type JSONLine struct {
-	Number  int    `json:"number"`
-	Content string `json:"content"`
+	Number    int         `json:"number"`
+	Content   string      `json:"content"`
+	Keyword   string      `json:"keyword,omitempty"`   // Todo keyword annotating the line
+	Continued bool        `json:"continued,omitempty"` // Segment of a line folded by Fold, after its first
+	Gap       int         `json:"gap,omitempty"`       // Lines left out here by BlameSince; Content is a marker
+	Matches   []MatchSpan `json:"matches,omitempty"`   // Byte ranges of Content that content patterns matched
 }
 
-// NewJSONOutput creates a new JSON output formatter.
-func NewJSONOutput() *JSONOutput {
+// NewJSONOutput creates a new JSON output formatter that writes to w.
+func NewJSONOutput(w io.Writer) *JSONOutput {
 	return &JSONOutput{
-		files: make([]JSONFile, 0),
+		w:      w,
+		pretty: true,
 	}
 }
 
-// WriteHeader writes the opening JSON structure (no-op for JSON).
-func (*JSONOutput) WriteHeader(ctx context.Context) error {
+// newJSONOutputWithOptions creates a JSON formatter configured by the json
+// --format-opt keys.
+func newJSONOutputWithOptions(w io.Writer, opts FormatOptions) (*JSONOutput, error) {
+	if err := opts.checkKeys(OutputFormatJSON); err != nil {
+		return nil, err
+	}
+
+	o := NewJSONOutput(w)
+
+	var err error
+	if o.pretty, err = opts.boolOption(OutputFormatJSON, "pretty", true); err != nil {
+		return nil, err
+	}
+
+	return o, nil
+}
+
+// WriteHeader opens the document and writes the configuration echo and
+// project header, which must be set before it.
+func (o *JSONOutput) WriteHeader(ctx context.Context) error {
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
 	}
 
-	// JSON requires complete structure, so we don't write anything here
-	return nil
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.start()
 }
 
-// WriteFile accumulates a processed file for later JSON output.
+// WriteFile encodes a processed file and writes it to the "files" array.
 func (o *JSONOutput) WriteFile(ctx context.Context, file *ProcessedFile, _ *Config) error {
 	select {
 	case <-ctx.Done():
@@ -55,11 +137,29 @@ func (o *JSONOutput) WriteFile(ctx context.Context, file *ProcessedFile, _ *Conf
 	default:
 	}
 
+	if file.Info.IsDir {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+
+		return o.writeFile(JSONFile{Path: file.Info.RelPath, Kind: jsonKindDirectory})
+	}
+
 	jsonFile := JSONFile{
-		Path:       file.Info.RelPath,
-		Binary:     file.Info.IsBinary,
-		TotalLines: file.TotalLines,
-		Truncated:  file.IsTruncated,
+		Path:              file.Info.RelPath,
+		Binary:            file.Info.IsBinary,
+		Executable:        file.Info.Executable,
+		Xattrs:            file.Xattrs,
+		TotalLines:        file.TotalLines,
+		Truncated:         file.IsTruncated,
+		Remaining:         remainingLines(file),
+		MatchesSuppressed: file.MatchesSuppressed,
+		Empty:             file.IsEmpty,
+		Readme:            file.IsReadme,
+		Reformatted:       file.Reformatted,
+		Reduced:           file.Reduced,
+		ModifiedDuringRun: file.ModifiedDuringRun,
+		DuplicateOf:       file.DuplicateOf,
+		ContentB64:        rawBase64(file),
 	}
 
 	// Set file type if available and not binary
@@ -67,27 +167,95 @@ func (o *JSONOutput) WriteFile(ctx context.Context, file *ProcessedFile, _ *Conf
 		jsonFile.Type = file.FileType
 	}
 
+	if file.ReformatError != nil {
+		jsonFile.ReformatError = file.ReformatError.Error()
+	}
+
 	// Handle errors
 	if file.Error != nil {
 		errorMsg := file.Error.Error()
 		jsonFile.Error = &errorMsg
-	} else if !file.Info.IsBinary {
-		// Add lines for non-binary files without errors
+		jsonFile.ErrorCategory = string(file.ErrorCategory)
+	} else if summary := file.Lockfile; summary != nil {
+		jsonFile.LockfileSummary = &JSONLockfileSummary{
+			Format: summary.Format,
+			Direct: summary.Direct,
+			Bytes:  summary.Size,
+			SHA256: summary.SHA256,
+		}
+		if summary.Parsed {
+			jsonFile.LockfileSummary.Dependencies = &summary.Dependencies
+		}
+	} else if !file.Info.IsBinary && !file.IsEmpty {
+		// Add lines for non-binary, non-empty files without errors
 		jsonFile.Lines = make([]JSONLine, len(file.Lines))
 		for i, line := range file.Lines {
 			jsonFile.Lines[i] = JSONLine{
-				Number:  line.LineNumber,
-				Content: line.Content,
+				Number:    line.LineNumber,
+				Content:   line.Content,
+				Keyword:   line.Keyword,
+				Continued: line.Continued,
+				Gap:       line.Gap,
+				Matches:   line.Matches,
 			}
 		}
 	}
 
-	o.files = append(o.files, jsonFile)
+	o.mu.Lock()
+	defer o.mu.Unlock()
 
-	return nil
+	if file.DirOmitted > 0 {
+		o.dirs = append(o.dirs, JSONOmittedDir{Dir: dirLimitDir(file), Omitted: file.DirOmitted})
+	}
+
+	return o.writeFile(jsonFile)
 }
 
-// WriteFooter writes the complete JSON structure.
+// SetConfigEcho stores the echo for the top-level "meta" field.
+func (o *JSONOutput) SetConfigEcho(echo ConfigEcho) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.meta = &echo
+}
+
+// SetProjectHeader stores the header for the top-level "project" field.
+func (o *JSONOutput) SetProjectHeader(header ProjectHeader) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.proj = &header
+}
+
+// WriteTodoIndex writes the top-level "todos" field ahead of the files, or
+// keeps it for the footer once the files have begun.
+func (o *JSONOutput) WriteTodoIndex(ctx context.Context, index TodoIndex) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if len(index) == 0 {
+		return nil
+	}
+	if o.filesOpen {
+		o.todos = index
+
+		return nil
+	}
+	if err := o.start(); err != nil {
+		return err
+	}
+
+	return o.writeField("todos", index)
+}
+
+// WriteFooter closes the "files" array, writes the fields that follow it,
+// the integrity trailer last, and closes the document.
 func (o *JSONOutput) WriteFooter(ctx context.Context) error {
 	select {
 	case <-ctx.Done():
@@ -95,14 +263,161 @@ func (o *JSONOutput) WriteFooter(ctx context.Context) error {
 	default:
 	}
 
-	output := struct {
-		Files []JSONFile `json:"files"`
-	}{
-		Files: o.files,
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if err := o.start(); err != nil {
+		return err
+	}
+	if !o.filesOpen {
+		if err := o.writeField("files", []JSONFile{}); err != nil {
+			return err
+		}
+	} else if err := o.writeString(o.indent(1) + "]"); err != nil {
+		return err
 	}
+	if len(o.todos) > 0 {
+		if err := o.writeField("todos", o.todos); err != nil {
+			return err
+		}
+	}
+	if len(o.dirs) > 0 {
+		if err := o.writeField("omittedDirs", o.dirs); err != nil {
+			return err
+		}
+	}
+	if o.trailer != nil {
+		// The checksum covers everything up to the field's separator
+		if err := o.writeField("integrity", o.trailer()); err != nil {
+			return err
+		}
+	}
+
+	return o.writeString(o.indent(0) + "}\n")
+}
+
+// SetIntegrity makes WriteFooter write the trailer as the last top-level
+// field, "integrity".
+func (o *JSONOutput) SetIntegrity(trailer func() IntegrityTrailer) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.trailer = trailer
+}
+
+// start writes the opening brace, the schema version, and the fields set
+// ahead of the files, once.
+func (o *JSONOutput) start() error {
+	if o.started {
+		return nil
+	}
+	o.started = true
+
+	if err := o.writeString("{"); err != nil {
+		return err
+	}
+	if err := o.writeField("schemaVersion", SchemaVersion); err != nil {
+		return err
+	}
+	if o.meta != nil {
+		if err := o.writeField("meta", o.meta); err != nil {
+			return err
+		}
+	}
+	if o.proj != nil {
+		if err := o.writeField("project", o.proj); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeFile writes one object of the "files" array, opening the array for
+// the first.
+func (o *JSONOutput) writeFile(file JSONFile) error {
+	if err := o.start(); err != nil {
+		return err
+	}
+	if !o.filesOpen {
+		if err := o.writeString(o.separator() + o.key("files") + "["); err != nil {
+			return err
+		}
+		o.fields++
+		o.filesOpen = true
+	}
+
+	sep := o.indent(2)
+	if o.files > 0 {
+		sep = "," + sep
+	}
+	o.files++
+
+	return o.writeValue(sep, file, 2)
+}
+
+// writeField writes a top-level field.
+func (o *JSONOutput) writeField(name string, value any) error {
+	sep := o.separator() + o.key(name)
+	o.fields++
+
+	return o.writeValue(sep, value, 1)
+}
+
+// separator returns what precedes the next top-level field.
+func (o *JSONOutput) separator() string {
+	if o.fields > 0 {
+		return "," + o.indent(1)
+	}
+
+	return o.indent(1)
+}
+
+// key returns a top-level field name with its colon.
+func (o *JSONOutput) key(name string) string {
+	if o.pretty {
+		return `"` + name + `": `
+	}
+
+	return `"` + name + `":`
+}
+
+// indent returns the line break and indentation of a value nested depth
+// levels deep, or nothing when the document is written on one line.
+func (o *JSONOutput) indent(depth int) string {
+	if !o.pretty {
+		return ""
+	}
+
+	return "\n" + strings.Repeat("  ", depth)
+}
+
+// writeValue encodes value, nested depth levels deep, and writes it after
+// prefix. Only this one value is ever held in the buffer.
+func (o *JSONOutput) writeValue(prefix string, value any, depth int) error {
+	o.buf.Reset()
+	o.buf.WriteString(prefix)
+	encoder := json.NewEncoder(&o.buf)
+	if o.pretty {
+		encoder.SetIndent(strings.Repeat("  ", depth), "  ")
+	}
+	if err := encoder.Encode(value); err != nil {
+		return err
+	}
+	// Encode ends the value with a newline, which the next separator supplies
+	o.buf.Truncate(o.buf.Len() - 1)
+
+	_, err := o.w.Write(o.buf.Bytes())
+	if o.buf.Cap() > jsonBufferKeep {
+		o.buf = bytes.Buffer{}
+	}
+
+	return err
+}
 
-	encoder := json.NewEncoder(os.Stdout)
-	encoder.SetIndent("", "  ")
+// writeString writes s to the output.
+func (o *JSONOutput) writeString(s string) error {
+	_, err := io.WriteString(o.w, s)
 
-	return encoder.Encode(output)
+	return err
 }
@@ -0,0 +1,74 @@
+package catls
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+// countingWriter discards what is written and counts the bytes.
+type countingWriter struct{ n int64 }
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+
+	return len(p), nil
+}
+
+// TestJSONOutputStreams writes a synthetic 1GB corpus and checks the heap
+// never holds more than a bounded slice of it, so the formatter cannot be
+// accumulating files until the footer.
+func TestJSONOutputStreams(t *testing.T) {
+	if testing.Short() {
+		t.Skip("writes 1GB of JSON")
+	}
+
+	const (
+		fileCount   = 1024
+		lineCount   = 1 << 10
+		heapCeiling = 100 << 20
+	)
+	var out countingWriter
+	o := NewJSONOutput(&out)
+	ctx := context.Background()
+	if err := o.WriteHeader(ctx); err != nil {
+		t.Fatalf("WriteHeader() unexpected error: %v", err)
+	}
+
+	var stats runtime.MemStats
+	for i := range fileCount {
+		// Every file has its own content, as files read from disk do, so
+		// holding on to files would hold on to their bytes
+		lines := make([]FilteredLine, lineCount)
+		for n := range lines {
+			lines[n] = FilteredLine{LineNumber: n + 1, Content: strings.Repeat(string(rune('a'+n%26)), 1023)}
+		}
+		file := &ProcessedFile{
+			Info:       scanner.FileInfo{RelPath: fmt.Sprintf("gen/file%04d.txt", i), Size: lineCount << 10},
+			FileType:   "text",
+			Lines:      lines,
+			TotalLines: lineCount,
+		}
+		if err := o.WriteFile(ctx, file, &Config{}); err != nil {
+			t.Fatalf("WriteFile() unexpected error: %v", err)
+		}
+		if i%128 == 127 {
+			runtime.GC()
+			runtime.ReadMemStats(&stats)
+			if stats.HeapAlloc > heapCeiling {
+				t.Fatalf("heap holds %d bytes after %d files, over the %d byte ceiling", stats.HeapAlloc, i+1, heapCeiling)
+			}
+		}
+	}
+	if err := o.WriteFooter(ctx); err != nil {
+		t.Fatalf("WriteFooter() unexpected error: %v", err)
+	}
+
+	if out.n < 1<<30 {
+		t.Errorf("wrote %d bytes, want at least 1GB", out.n)
+	}
+}
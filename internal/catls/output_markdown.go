@@ -4,36 +4,122 @@ package catls
 import (
 	"context"
 	"fmt"
-	"path/filepath"
+	"io"
 	"strings"
+	"sync"
+
+	"github.com/connerohnesorge/catls/internal/languages"
 )
 
+// imageAltReplacer escapes a path for the alt text of a Markdown image, where
+// a bracket would end the text early.
+var imageAltReplacer = strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`)
+
 // MarkdownOutput handles Markdown output formatting. It implements the OutputFormatter interface to generate
 // Markdown-formatted output with syntax-highlighted code blocks. The formatter intelligently detects programming
 // languages for proper syntax highlighting based on file types and extensions.
 type MarkdownOutput struct {
+	mu sync.Mutex
+	w  io.Writer
 	// firstFile tracks whether this is the first file being written to avoid extra spacing.
 	firstFile bool
+	// headingLevel is the heading level of file headings; directory records use
+	// the level below.
+	headingLevel int
+	// echo is the configuration echo comment written at the top (empty for none).
+	echo string
+	// project is the project header paragraph written at the top (empty for none).
+	project string
+	// links turns mentions of other files into links to their headings (nil
+	// leaves them as text).
+	links *CrossLinks
+	// trailer is the integrity trailer written as a closing footnote (nil
+	// for none).
+	trailer func() IntegrityTrailer
 }
 
-// NewMarkdownOutput creates a new Markdown output formatter for generating syntax-highlighted file listings.
-// The formatter tracks the first file to avoid unnecessary spacing at the beginning of output.
-func NewMarkdownOutput() *MarkdownOutput {
+// NewMarkdownOutput creates a new Markdown output formatter for generating syntax-highlighted file listings
+// written to w. The formatter tracks the first file to avoid unnecessary spacing at the beginning of output.
+func NewMarkdownOutput(w io.Writer) *MarkdownOutput {
 	return &MarkdownOutput{
-		firstFile: true,
+		w:            w,
+		firstFile:    true,
+		headingLevel: defaultMarkdownHeadingLevel,
 	}
 }
 
-// WriteHeader writes the opening Markdown structure (no-op for Markdown). Markdown doesn't require headers.
-func (*MarkdownOutput) WriteHeader(ctx context.Context) error {
+const (
+	defaultMarkdownHeadingLevel = 2
+	// maxMarkdownHeadingLevel leaves room for directory records one level below.
+	maxMarkdownHeadingLevel = 5
+)
+
+// newMarkdownOutputWithOptions creates a Markdown formatter configured by the
+// markdown --format-opt keys.
+func newMarkdownOutputWithOptions(w io.Writer, opts FormatOptions) (*MarkdownOutput, error) {
+	if err := opts.checkKeys(OutputFormatMarkdown); err != nil {
+		return nil, err
+	}
+
+	o := NewMarkdownOutput(w)
+
+	var err error
+	if o.headingLevel, err = opts.intOption(OutputFormatMarkdown, "heading-level", defaultMarkdownHeadingLevel); err != nil {
+		return nil, err
+	}
+	if o.headingLevel < 1 || o.headingLevel > maxMarkdownHeadingLevel {
+		return nil, fmt.Errorf("--format-opt markdown:heading-level must be between 1 and %d, got %d",
+			maxMarkdownHeadingLevel, o.headingLevel)
+	}
+
+	return o, nil
+}
+
+// WriteHeader writes the configuration echo, if any, as an HTML comment that
+// renderers hide, followed by the project header paragraph, if any. Markdown
+// needs no other header.
+func (o *MarkdownOutput) WriteHeader(ctx context.Context) error {
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
 	}
 
-	// No header needed for Markdown
-	return nil
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	header := o.echo
+	if o.project != "" {
+		if header != "" {
+			header += "\n"
+		}
+		header += o.project + "\n"
+	}
+	if header == "" {
+		return nil
+	}
+	// Files that follow are separated from the header like from each other
+	o.firstFile = false
+
+	_, err := io.WriteString(o.w, header)
+
+	return err
+}
+
+// SetProjectHeader records the header for WriteHeader.
+func (o *MarkdownOutput) SetProjectHeader(header ProjectHeader) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.project = printableText(header.String())
+}
+
+// SetConfigEcho records the echo for WriteHeader.
+func (o *MarkdownOutput) SetConfigEcho(echo ConfigEcho) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.echo = configEchoComment(echo)
 }
 
 // WriteFile writes a single processed file to Markdown output.
@@ -44,200 +130,232 @@ func (o *MarkdownOutput) WriteFile(ctx context.Context, file *ProcessedFile, cfg
 	default:
 	}
 
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var b strings.Builder
+
 	// Add spacing between files (except for the first file)
 	if !o.firstFile {
-		fmt.Println()
+		b.WriteString("\n")
 	}
 	o.firstFile = false
 
+	o.renderFile(&b, file, cfg)
+	if note := matchLimitNote(file); note != "" {
+		b.WriteString("\n*" + note + "*\n")
+	}
+	if note := dirLimitNote(file); note != "" {
+		b.WriteString("\n*" + note + "*\n")
+	}
+
+	_, err := io.WriteString(o.w, b.String())
+
+	return err
+}
+
+// renderFile renders the heading and body of a single file.
+func (o *MarkdownOutput) renderFile(b *strings.Builder, file *ProcessedFile, cfg *Config) {
+	// Directory records are a heading stub one level below files
+	if file.Info.IsDir {
+		fmt.Fprintf(b, "%s %s/ (directory)\n", strings.Repeat("#", o.headingLevel+1), printableText(file.Info.RelPath))
+
+		return
+	}
+
 	// Write file header
-	fmt.Printf("## %s\n\n", file.Info.RelPath)
+	fmt.Fprintf(b, "%s %s\n\n", strings.Repeat("#", o.headingLevel), printableText(file.Info.RelPath))
+	if file.Info.Executable {
+		b.WriteString("*Executable*\n\n")
+	}
+	if file.Reformatted {
+		b.WriteString("*Reformatted*\n\n")
+	} else if file.ReformatError != nil {
+		fmt.Fprintf(b, "*Not reformatted: %s*\n\n", printableText(file.ReformatError.Error()))
+	}
+	switch file.Reduced {
+	case ReducedSignatures:
+		b.WriteString("*Signatures only*\n\n")
+	case ReducedHead:
+		b.WriteString("*Head excerpt*\n\n")
+	}
+	if file.ModifiedDuringRun {
+		b.WriteString("*Modified during run*\n\n")
+	}
 
 	// Handle errors
 	if file.Error != nil {
-		fmt.Printf("**Error:** %s\n\n", file.Error.Error())
+		fmt.Fprintf(b, "**Error%s:** %s\n\n", errorCategoryNote(file), file.Error.Error())
 
-		return nil
+		return
+	}
+
+	// Duplicates point at the file whose content they share
+	if file.DuplicateOf != "" {
+		fmt.Fprintf(b, "*Identical to %s*\n", o.link(file.DuplicateOf))
+
+		return
+	}
+
+	// Lockfiles are described rather than shown
+	if summary := file.Lockfile; summary != nil {
+		fmt.Fprintf(b, "*Lockfile summary, not file content:* %s\n", summary.Text())
+		if len(summary.Direct) > 0 {
+			fmt.Fprintf(b, "\nDirect dependencies: %s\n", strings.Join(summary.Direct, ", "))
+		}
+
+		return
 	}
 
-	// Handle binary files
+	// Handle binary files, showing small images inline when embedded
+	if file.Image != nil {
+		fmt.Fprintf(b, "![%s](%s)\n", imageAltReplacer.Replace(file.Info.RelPath), file.Image.DataURI())
+
+		return
+	}
 	if file.Info.IsBinary {
-		fmt.Println("*Binary file - contents not displayed*")
+		b.WriteString("*Binary file - contents not displayed*\n")
 
-		return nil
+		return
+	}
+
+	// Handle empty and whitespace-only files
+	if file.IsEmpty {
+		b.WriteString("*Empty file*\n")
+
+		return
+	}
+
+	// READMEs read as documentation, so render them as prose
+	if file.IsReadme {
+		o.writeQuote(b, file)
+
+		return
 	}
 
 	// Determine language for syntax highlighting
-	language := o.getLanguageForSyntaxHighlighting(file.FileType, file.Info.RelPath)
+	language := languages.HighlightName(file.FileType)
+
+	writeMarkdownBody(b, file, language, cfg)
 
-	// Write code block with content
-	fmt.Printf("```%s name=\"%s\"\n", language, filepath.Base(file.Info.RelPath))
+	// Links inside a code block would show as text, so they are listed below it
+	if refs := o.links.References(file.Lines, file.Info.RelPath); len(refs) > 0 {
+		b.WriteString("\nReferences:\n\n")
+		for _, ref := range refs {
+			fmt.Fprintf(b, "- %s\n", o.link(ref))
+		}
+	}
+}
 
-	// Write content lines
+// writeQuote renders file content as a blockquote so it reads as prose, with
+// mentions of other files linked.
+func (o *MarkdownOutput) writeQuote(b *strings.Builder, file *ProcessedFile) {
 	for _, line := range file.Lines {
-		if cfg.ShowLineNumbers {
-			fmt.Printf("%4d| %s\n", line.LineNumber, line.Content)
+		if strings.TrimSpace(line.Content) == "" {
+			b.WriteString(">\n")
 		} else {
-			fmt.Println(line.Content)
+			b.WriteString("> " + o.linkMentions(line.Content, file.Info.RelPath) + "\n")
 		}
 	}
 
-	// Handle truncation
-	if file.IsTruncated {
-		remainingLines := file.TotalLines - len(file.Lines)
-		if remainingLines > 0 {
-			fmt.Printf("... (%d more lines)\n", remainingLines)
+	if remaining := remainingLines(file); remaining > 0 {
+		fmt.Fprintf(b, ">\n> *(%d more lines)*\n", remaining)
+	}
+}
+
+// WriteTodoIndex writes the todo index as a section at the top of the output,
+// one bullet per keyword listing path:line references.
+func (o *MarkdownOutput) WriteTodoIndex(ctx context.Context, index TodoIndex) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var b strings.Builder
+	b.WriteString("# Todo index\n\n")
+	if len(index) == 0 {
+		b.WriteString("*No annotations found.*\n")
+	}
+	for _, keyword := range index {
+		refs := make([]string, len(keyword.Refs))
+		for i, ref := range keyword.Refs {
+			refs[i] = fmt.Sprintf("`%s:%d`", ref.Path, ref.Line)
 		}
+		fmt.Fprintf(&b, "- **%s**: %s\n", keyword.Keyword, strings.Join(refs, ", "))
 	}
 
-	fmt.Println("```")
+	// Files that follow are separated from the index like from each other
+	o.firstFile = false
+
+	_, err := io.WriteString(o.w, b.String())
 
-	return nil
+	return err
 }
 
-// WriteFooter writes the closing Markdown structure (no-op for Markdown).
-func (*MarkdownOutput) WriteFooter(ctx context.Context) error {
+// WriteFooter writes the integrity trailer as a footnote when one is set;
+// Markdown needs no other footer.
+func (o *MarkdownOutput) WriteFooter(ctx context.Context) error {
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
 	}
 
-	// No footer needed for Markdown
-	return nil
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.trailer == nil {
+		return nil
+	}
+	_, err := io.WriteString(o.w, "\n[^catls-integrity]: "+o.trailer().String()+"\n")
+
+	return err
 }
 
-// Language constants for syntax highlighting.
-const (
-	langBash       = "bash"
-	langRuby       = "ruby"
-	langPython     = "python"
-	langJavaScript = "javascript"
-	langTypeScript = "typescript"
-	langHTML       = "html"
-	langNix        = "nix"
-	langCSS        = "css"
-	langSCSS       = "scss"
-	langJSON       = "json"
-	langMarkdown   = "markdown"
-	langXML        = "xml"
-	langC          = "c"
-	langCPP        = "cpp"
-	langTOML       = "toml"
-	langJava       = "java"
-	langRust       = "rust"
-	langGo         = "go"
-	langPHP        = "php"
-	langPerl       = "perl"
-	langSQL        = "sql"
-	langYAML       = "yaml"
-	langDockerfile = "dockerfile"
-	langMakefile   = "makefile"
-	langText       = "text"
-)
+// SetIntegrity makes WriteFooter close the document with the trailer.
+func (o *MarkdownOutput) SetIntegrity(trailer func() IntegrityTrailer) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
 
-// getLanguageForSyntaxHighlighting maps file types to syntax highlighting languages.
-func (o *MarkdownOutput) getLanguageForSyntaxHighlighting(fileType, filePath string) string {
-	// Use the detected file type first
-	if fileType != "" {
-		lang := o.languageFromFileType(fileType)
-		if lang != "" {
-			return lang
-		}
-	}
+	o.trailer = trailer
+}
 
-	// Fallback to extension-based detection
-	return o.languageFromExtension(filePath)
+// SetCrossLinks makes the formatter link mentions of the files in links: in
+// READMEs and duplicate notes directly, and in code as a list of references
+// below the block.
+func (o *MarkdownOutput) SetCrossLinks(links *CrossLinks) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.links = links
 }
 
-// languageFromFileType returns the language for a detected file type.
-func (*MarkdownOutput) languageFromFileType(fileType string) string {
-	langMap := map[string]string{
-		langBash:       langBash,
-		langRuby:       langRuby,
-		langPython:     langPython,
-		langJavaScript: langJavaScript,
-		langTypeScript: langTypeScript,
-		langHTML:       langHTML,
-		langNix:        langNix,
-		langCSS:        langCSS,
-		"scss":         langSCSS,
-		"sass":         langSCSS,
-		langJSON:       langJSON,
-		langMarkdown:   langMarkdown,
-		langXML:        langXML,
-		langC:          langC,
-		langCPP:        langCPP,
-		langTOML:       langTOML,
-		langJava:       langJava,
-		langRust:       langRust,
-		langGo:         langGo,
-		langPHP:        langPHP,
-		langPerl:       langPerl,
-		langSQL:        langSQL,
-		"templ":        langGo,
-		langYAML:       langYAML,
-		langDockerfile: langDockerfile,
-		langMakefile:   langMakefile,
-	}
-
-	if lang, ok := langMap[fileType]; ok {
-		return lang
-	}
-
-	return ""
+// link returns a link to the heading of the file at path, or path itself
+// when it is not linked.
+func (o *MarkdownOutput) link(path string) string {
+	anchor, ok := o.links.Anchor(path)
+	if !ok {
+		return path
+	}
+
+	return fmt.Sprintf("[%s](#%s)", path, anchor)
 }
 
-// languageFromExtension returns the language based on file extension.
-func (*MarkdownOutput) languageFromExtension(filePath string) string {
-	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(filePath), "."))
-
-	extMap := map[string]string{
-		"sh":             langBash,
-		langBash:         langBash,
-		"zsh":            langBash,
-		"rb":             langRuby,
-		"py":             langPython,
-		"js":             langJavaScript,
-		"jsx":            langJavaScript,
-		"ts":             langTypeScript,
-		"tsx":            langTypeScript,
-		langHTML:         langHTML,
-		"htm":            langHTML,
-		langNix:          langNix,
-		langCSS:          langCSS,
-		"scss":           langSCSS,
-		"sass":           langSCSS,
-		langJSON:         langJSON,
-		"md":             langMarkdown,
-		langMarkdown:     langMarkdown,
-		langXML:          langXML,
-		langC:            langC,
-		"h":              langC,
-		langCPP:          langCPP,
-		"cxx":            langCPP,
-		"cc":             langCPP,
-		"hpp":            langCPP,
-		"hxx":            langCPP,
-		langTOML:         langTOML,
-		langJava:         langJava,
-		"rs":             langRust,
-		langGo:           langGo,
-		langPHP:          langPHP,
-		"pl":             langPerl,
-		langSQL:          langSQL,
-		"templ":          langGo,
-		"yml":            langYAML,
-		langYAML:         langYAML,
-		langDockerfile:   langDockerfile,
-		langMakefile:     langMakefile,
-		"txt":            langText,
-		"":               langText,
-	}
-
-	if lang, ok := extMap[ext]; ok {
-		return lang
-	}
-
-	return langText
+// linkMentions links the mentions of other files in a line of self.
+func (o *MarkdownOutput) linkMentions(content, self string) string {
+	var b strings.Builder
+	last := 0
+	for _, match := range o.links.Find(content, self) {
+		b.WriteString(content[last:match.Start])
+		anchor, _ := o.links.Anchor(match.Path)
+		fmt.Fprintf(&b, "[%s](#%s)", content[match.Start:match.End], anchor)
+		last = match.End
+	}
+	b.WriteString(content[last:])
+
+	return b.String()
 }
@@ -0,0 +1,174 @@
+package catls
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+func TestMarkdownFenceStyles(t *testing.T) {
+	file := &ProcessedFile{
+		Info:     scanner.FileInfo{Path: "/tmp/main.go", RelPath: "src/main.go"},
+		FileType: "go",
+		Lines: []FilteredLine{
+			{LineNumber: 1, Content: "package main"},
+			{LineNumber: 2, Content: "// ~~~ tildes"},
+		},
+		TotalLines: 2,
+	}
+
+	tests := []struct {
+		name  string
+		cfg   Config
+		want  string
+		avoid string
+	}{
+		{
+			name: "backtick is the default",
+			cfg:  Config{},
+			want: "```go name=\"main.go\"\npackage main\n// ~~~ tildes\n```\n",
+		},
+		{
+			name: "tilde fence outgrows tilde runs",
+			cfg:  Config{FenceStyle: FenceStyleTilde},
+			want: "~~~~go name=\"main.go\"\npackage main\n// ~~~ tildes\n~~~~\n",
+		},
+		{
+			name:  "indent has no fence lines",
+			cfg:   Config{FenceStyle: FenceStyleIndent},
+			want:  "\n    package main\n    // ~~~ tildes\n",
+			avoid: "```",
+		},
+		{
+			name: "none uses the default sentinel",
+			cfg:  Config{FenceStyle: FenceStyleNone},
+			want: "----- BEGIN FILE: src/main.go -----\npackage main\n// ~~~ tildes\n----- END FILE: src/main.go -----\n",
+		},
+		{
+			name:  "custom sentinel implies none",
+			cfg:   Config{Sentinel: "=== {path} ({type}, {lines} lines) ==="},
+			want:  "=== src/main.go (go, 2 lines) ===\npackage main\n// ~~~ tildes\n=== src/main.go (go, 2 lines) ===\n",
+			avoid: "```",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			cfg := tt.cfg
+			if err := NewMarkdownOutput(&buf).WriteFile(context.Background(), file, &cfg); err != nil {
+				t.Fatalf("WriteFile() unexpected error: %v", err)
+			}
+
+			output := buf.String()
+			if !strings.Contains(output, tt.want) {
+				t.Errorf("output missing %q\noutput:\n%s", tt.want, output)
+			}
+			if tt.avoid != "" && strings.Contains(output, tt.avoid) {
+				t.Errorf("output should not contain %q\noutput:\n%s", tt.avoid, output)
+			}
+		})
+	}
+}
+
+func TestMarkdownTruncationNotice(t *testing.T) {
+	file := &ProcessedFile{
+		Info:        scanner.FileInfo{Path: "/tmp/big.log", RelPath: "big.log"},
+		Lines:       []FilteredLine{{LineNumber: 1, Content: "first"}},
+		TotalLines:  5,
+		IsTruncated: true,
+	}
+
+	tests := []struct {
+		name string
+		cfg  Config
+		want string
+	}{
+		{
+			name: "notice follows the fence",
+			cfg:  Config{},
+			want: "```text name=\"big.log\"\nfirst\n```\n\n*(4 more lines)*\n",
+		},
+		{
+			name: "notice follows the end sentinel",
+			cfg:  Config{FenceStyle: FenceStyleNone},
+			want: "first\n----- END FILE: big.log -----\n\n*(4 more lines)*\n",
+		},
+		{
+			name: "legacy notice stays inside the fence",
+			cfg:  Config{LegacyTruncation: true},
+			want: "first\n... (4 more lines)\n```\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			cfg := tt.cfg
+			if err := NewMarkdownOutput(&buf).WriteFile(context.Background(), file, &cfg); err != nil {
+				t.Fatalf("WriteFile() unexpected error: %v", err)
+			}
+
+			if output := buf.String(); !strings.HasSuffix(output, tt.want) {
+				t.Errorf("output should end with %q\noutput:\n%s", tt.want, output)
+			}
+		})
+	}
+}
+
+func TestMarkdownEmbedImages(t *testing.T) {
+	pngHeader := "\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR"
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"small.png":    pngHeader,
+		"shot [1].png": pngHeader,
+		"large.png":    pngHeader + strings.Repeat("\x00", 200),
+		"fake.png":     "\x00\x01\x02 not an image",
+		"renamed.dat":  "GIF89a\x01\x00\x01\x00\x00\x00",
+		"icon.svg":     "<svg xmlns=\"http://www.w3.org/2000/svg\">\n<circle r=\"1\"/>\n</svg>\n",
+	})
+
+	var buf bytes.Buffer
+	app, err := New(&Config{
+		Directory:    tmpDir,
+		RelativeTo:   tmpDir,
+		OutputFormat: OutputFormatMarkdown,
+		Output:       &buf,
+		EmbedImages:  100,
+	})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	sections := make(map[string]string)
+	for _, section := range strings.Split(buf.String(), "## ")[1:] {
+		path, body, _ := strings.Cut(section, "\n")
+		sections[path] = body
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "small.png", want: "![small.png](data:image/png;base64,iVBORw0KGgoAAAANSUhEUg==)"},
+		{path: "shot [1].png", want: `![shot \[1\].png](data:image/png;base64,`},
+		{path: "large.png", want: "*Binary file - contents not displayed*"},
+		{path: "fake.png", want: "*Binary file - contents not displayed*"},
+		{path: "renamed.dat", want: "![renamed.dat](data:image/gif;base64,"},
+		{path: "icon.svg", want: "```xml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if !strings.Contains(sections[tt.path], tt.want) {
+				t.Errorf("section for %s = %q, want it to contain %q", tt.path, sections[tt.path], tt.want)
+			}
+		})
+	}
+}
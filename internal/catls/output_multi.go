@@ -0,0 +1,221 @@
+package catls
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// formats returns every format the run writes: OutputFormats when set,
+// otherwise OutputFormat alone.
+func (c *Config) formats() []OutputFormat {
+	if len(c.OutputFormats) > 0 {
+		return c.OutputFormats
+	}
+
+	return []OutputFormat{c.OutputFormat}
+}
+
+// writesFormat reports whether the run writes format.
+func (c *Config) writesFormat(format OutputFormat) bool {
+	return slices.Contains(c.formats(), format)
+}
+
+// formatList names the formats the run writes, for error messages.
+func (c *Config) formatList() string {
+	names := make([]string, len(c.formats()))
+	for i, format := range c.formats() {
+		names[i] = format.String()
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// formatOptions selects the --format-opt values for format. When the run
+// writes several formats, keys prefixed with another of them are left to that
+// format instead of being rejected.
+func (c *Config) formatOptions(format OutputFormat) (FormatOptions, error) {
+	raw := c.FormatOptions
+	if len(c.OutputFormats) > 1 {
+		raw = make(map[string]string, len(c.FormatOptions))
+		for key, value := range c.FormatOptions {
+			prefix, _, found := strings.Cut(key, ":")
+			if found && OutputFormat(prefix) != format && c.writesFormat(OutputFormat(prefix)) {
+				continue
+			}
+			raw[key] = value
+		}
+	}
+
+	return ParseFormatOptions(format, raw)
+}
+
+// OutputFileName returns the name of the file OutputDir receives for format,
+// such as out-markdown.md.
+func OutputFileName(format OutputFormat) string {
+	info, _ := LookupFormat(format)
+
+	return "out-" + format.String() + "." + info.FileExtension()
+}
+
+// openOutputDir creates OutputDir with one file and formatter per format and
+// points a.output at all of them. The returned function closes the files.
+func (a *App) openOutputDir() (func() error, error) {
+	if err := os.MkdirAll(a.cfg.OutputDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	var files []*os.File
+	closeFiles := func() error {
+		var errs []error
+		for _, file := range files {
+			errs = append(errs, file.Close())
+		}
+
+		return errors.Join(errs...)
+	}
+
+	multi := &multiOutput{}
+	for _, format := range a.cfg.formats() {
+		opts, err := a.cfg.formatOptions(format)
+		if err != nil {
+			return nil, errors.Join(err, closeFiles())
+		}
+
+		file, err := os.Create(filepath.Join(a.cfg.OutputDir, OutputFileName(format)))
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("failed to create output file: %w", err), closeFiles())
+		}
+		files = append(files, file)
+
+		tee := newIntegrityTee(a.cfg, file)
+		var out io.Writer = file
+		if tee != nil {
+			out = tee
+		}
+		formatter, err := NewOutputFormatter(format, out, opts)
+		if err != nil {
+			return nil, errors.Join(err, closeFiles())
+		}
+		if err := a.setIntegrity(format, formatter, tee); err != nil {
+			return nil, errors.Join(err, closeFiles())
+		}
+		multi.formats = append(multi.formats, format)
+		multi.formatters = append(multi.formatters, formatter)
+	}
+	a.output = multi
+
+	return closeFiles, nil
+}
+
+// multiOutput fans every call out to one formatter per format, in order, so
+// several formats are written from a single pass over the files. It stops at
+// the first formatter that fails and names its format in the error.
+type multiOutput struct {
+	formats    []OutputFormat
+	formatters []OutputFormatter
+}
+
+// each calls fn on every formatter until one fails.
+func (m *multiOutput) each(fn func(OutputFormatter) error) error {
+	for i, formatter := range m.formatters {
+		if err := fn(formatter); err != nil {
+			return fmt.Errorf("%s output: %w", m.formats[i], err)
+		}
+	}
+
+	return nil
+}
+
+// WriteHeader writes every format's header.
+func (m *multiOutput) WriteHeader(ctx context.Context) error {
+	return m.each(func(f OutputFormatter) error {
+		return f.WriteHeader(ctx)
+	})
+}
+
+// WriteFile writes the file to every format. A long file kept whole for
+// chunks output is truncated for the others, as a run of theirs alone would.
+func (m *multiOutput) WriteFile(ctx context.Context, file *ProcessedFile, cfg *Config) error {
+	truncated := file
+	if file.truncatable {
+		view := *file
+		truncateLines(&view)
+		truncated = &view
+	}
+
+	for i, formatter := range m.formatters {
+		written := truncated
+		if m.formats[i] == OutputFormatChunks {
+			written = file
+		}
+		if err := formatter.WriteFile(ctx, written, cfg); err != nil {
+			return fmt.Errorf("%s output: %w", m.formats[i], err)
+		}
+	}
+
+	return nil
+}
+
+// WriteFooter writes every format's footer.
+func (m *multiOutput) WriteFooter(ctx context.Context) error {
+	return m.each(func(f OutputFormatter) error {
+		return f.WriteFooter(ctx)
+	})
+}
+
+// WriteRunSummary passes the summary to the formats that report it.
+func (m *multiOutput) WriteRunSummary(ctx context.Context, summary RunSummary) error {
+	return m.each(func(f OutputFormatter) error {
+		if writer, ok := f.(RunSummaryWriter); ok {
+			return writer.WriteRunSummary(ctx, summary)
+		}
+
+		return nil
+	})
+}
+
+// WriteTodoIndex passes the index to the formats that render it.
+func (m *multiOutput) WriteTodoIndex(ctx context.Context, index TodoIndex) error {
+	return m.each(func(f OutputFormatter) error {
+		if writer, ok := f.(TodoIndexWriter); ok {
+			return writer.WriteTodoIndex(ctx, index)
+		}
+
+		return nil
+	})
+}
+
+// SetConfigEcho passes the echo, naming each format alone, to the formats
+// that record it.
+func (m *multiOutput) SetConfigEcho(echo ConfigEcho) {
+	for i, f := range m.formatters {
+		if writer, ok := f.(ConfigEchoWriter); ok {
+			echo.Format = m.formats[i].String()
+			writer.SetConfigEcho(echo)
+		}
+	}
+}
+
+// SetProjectHeader passes the header to the formats that write it.
+func (m *multiOutput) SetProjectHeader(header ProjectHeader) {
+	for _, f := range m.formatters {
+		if writer, ok := f.(ProjectHeaderWriter); ok {
+			writer.SetProjectHeader(header)
+		}
+	}
+}
+
+// SetCrossLinks passes the links to the formats that use them.
+func (m *multiOutput) SetCrossLinks(links *CrossLinks) {
+	for _, f := range m.formatters {
+		if linker, ok := f.(CrossLinker); ok {
+			linker.SetCrossLinks(links)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package catls
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/connerohnesorge/catls/internal/languages"
+)
+
+const (
+	// DefaultTheme is the highlighting theme used when Config.Theme is empty.
+	// It suits dark terminal backgrounds.
+	DefaultTheme = "monokai"
+	// LightTheme is the highlighting theme suggested for light terminal backgrounds.
+	LightTheme = "github"
+
+	// highlightMaxLines and highlightMaxBytes bound the files that are
+	// highlighted. Larger files are printed plain, since lexing them would
+	// stall output.
+	highlightMaxLines = 1000
+	highlightMaxBytes = 256 << 10
+
+	ansiBold  = "\x1b[1m"
+	ansiDim   = "\x1b[2m"
+	ansiReset = "\x1b[0m"
+)
+
+// PrettyOutput renders files for reading in a terminal: a header line per file
+// followed by its content. With Config.Color, headers are styled and content
+// is syntax highlighted according to the file's detected type.
+type PrettyOutput struct {
+	mu      sync.Mutex
+	w       io.Writer
+	files   int
+	project string // Project header line written by WriteHeader
+}
+
+// NewPrettyOutput creates a new pretty output formatter that writes to w.
+func NewPrettyOutput(w io.Writer) *PrettyOutput {
+	return &PrettyOutput{w: w}
+}
+
+// WriteHeader writes the project header, if any; pretty output has no
+// document structure.
+func (p *PrettyOutput) WriteHeader(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.project == "" {
+		return nil
+	}
+	_, err := io.WriteString(p.w, p.project+"\n")
+
+	return err
+}
+
+// SetProjectHeader records the header for WriteHeader.
+func (p *PrettyOutput) SetProjectHeader(header ProjectHeader) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.project = printableText(header.String())
+}
+
+// WriteFile renders a processed file as a header line and its content,
+// separated from the previous file by a blank line.
+func (p *PrettyOutput) WriteFile(ctx context.Context, file *ProcessedFile, cfg *Config) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var b strings.Builder
+	if p.files > 0 || p.project != "" {
+		b.WriteString("\n")
+	}
+	p.files++
+	writePrettyFile(&b, file, cfg)
+	if note := dirLimitNote(file); note != "" {
+		if cfg.Color {
+			note = ansiDim + note + ansiReset
+		}
+		b.WriteString(note + "\n")
+	}
+
+	_, err := io.WriteString(p.w, b.String())
+
+	return err
+}
+
+// WriteFooter is a no-op; pretty output has no document structure.
+func (*PrettyOutput) WriteFooter(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	return nil
+}
+
+// writePrettyFile renders one file. Directory records, duplicates, binary
+// files, and errors are a header line alone.
+func writePrettyFile(b *strings.Builder, file *ProcessedFile, cfg *Config) {
+	style := func(code, text string) string {
+		if !cfg.Color {
+			return text
+		}
+
+		return code + text + ansiReset
+	}
+
+	path := file.Info.RelPath
+	var details []string
+	switch {
+	case file.Info.IsDir:
+		path += "/"
+		details = append(details, "directory")
+	case file.Error != nil:
+		details = append(details, "error"+errorCategoryNote(file)+": "+file.Error.Error())
+	case file.DuplicateOf != "":
+		details = append(details, "identical to "+file.DuplicateOf)
+	case file.Lockfile != nil:
+		details = append(details, "lockfile summary")
+	case file.Info.IsBinary:
+		details = append(details, "binary")
+	case file.IsEmpty:
+		details = append(details, "empty")
+	default:
+		if file.FileType != "" {
+			details = append(details, file.FileType)
+		}
+		noun := "lines"
+		if file.TotalLines == 1 {
+			noun = "line"
+		}
+		details = append(details, fmt.Sprintf("%d %s", file.TotalLines, noun))
+	}
+	if file.Info.Executable {
+		details = append(details, "executable")
+	}
+	if file.IsReadme {
+		details = append(details, "readme")
+	}
+	if file.Reformatted {
+		details = append(details, "reformatted")
+	} else if file.ReformatError != nil {
+		details = append(details, "not reformatted: "+file.ReformatError.Error())
+	}
+	switch file.Reduced {
+	case ReducedSignatures:
+		details = append(details, "signatures only")
+	case ReducedHead:
+		details = append(details, "head excerpt")
+	}
+	if file.ModifiedDuringRun {
+		details = append(details, "modified during run")
+	}
+
+	fmt.Fprintf(b, "%s %s\n", style(ansiBold, "── "+path), style(ansiDim, "· "+strings.Join(details, " · ")))
+	if file.Error == nil && file.Lockfile != nil {
+		b.WriteString(style(ansiDim, "   "+file.Lockfile.Text()) + "\n")
+		if len(file.Lockfile.Direct) > 0 {
+			b.WriteString(style(ansiDim, "   direct: "+strings.Join(file.Lockfile.Direct, ", ")) + "\n")
+		}
+
+		return
+	}
+	if file.Info.IsDir || file.Error != nil || file.DuplicateOf != "" || file.Info.IsBinary {
+		return
+	}
+
+	contents := make([]string, len(file.Lines))
+	for i, line := range file.Lines {
+		contents[i] = line.Content
+	}
+	if cfg.Color {
+		if highlighted := highlightLines(contents, file.FileType, cfg.Theme); highlighted != nil {
+			contents = highlighted
+		}
+	}
+
+	gutter := newLineGutter(file, cfg)
+	for i, line := range file.Lines {
+		if cfg.Color {
+			contents[i] = invertSpans(closeSGR(contents[i]), line.Matches)
+		}
+		b.WriteString(gutter.Line(line, contents[i]) + "\n")
+	}
+
+	if cfg.LegacyTruncation {
+		if notice := gutter.TruncationNotice(file); notice != "" {
+			b.WriteString(notice + "\n")
+		}
+	} else if remaining := remainingLines(file); remaining > 0 {
+		b.WriteString(gutter.Indent() + style(ansiDim, fmt.Sprintf("… %d more lines", remaining)) + "\n")
+	}
+	if note := matchLimitNote(file); note != "" {
+		b.WriteString(gutter.Indent() + style(ansiDim, note) + "\n")
+	}
+}
+
+// closeSGR ends s with a reset when it holds escape sequences but does not
+// already end with one, so the gutter of the next line is never colored by
+// them. Highlighted lines already end with a reset; content may carry its own.
+func closeSGR(s string) string {
+	if !strings.Contains(s, "\x1b[") || strings.HasSuffix(s, ansiReset) {
+		return s
+	}
+
+	return s + ansiReset
+}
+
+// highlightLines returns lines with ANSI syntax highlighting for fileType in
+// the named theme, one output line per input line with colors reset at each
+// line end. It returns nil when lines should be printed plain: the type has no
+// lexer, the file is too large, or highlighting failed.
+func highlightLines(lines []string, fileType, theme string) []string {
+	if len(lines) == 0 || len(lines) > highlightMaxLines {
+		return nil
+	}
+
+	text := strings.Join(lines, "\n") + "\n"
+	if len(text) > highlightMaxBytes {
+		return nil
+	}
+
+	lexer := lexers.Get(languages.HighlightName(fileType))
+	if lexer == nil || lexer.Config().Name == "plaintext" {
+		return nil
+	}
+	if theme == "" {
+		theme = DefaultTheme
+	}
+
+	iterator, err := chroma.Coalesce(lexer).Tokenise(nil, text)
+	if err != nil {
+		return nil
+	}
+
+	var out strings.Builder
+	if err := formatters.TTY256.Format(&out, styles.Get(theme), iterator); err != nil {
+		return nil
+	}
+
+	highlighted := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(highlighted) != len(lines) {
+		return nil
+	}
+
+	return highlighted
+}
+
+// GetSupportedThemes returns the names of the highlighting themes accepted by
+// Config.Theme.
+func GetSupportedThemes() []string {
+	return styles.Names()
+}
@@ -0,0 +1,111 @@
+package catls
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+func TestPrettyHighlighting(t *testing.T) {
+	goLines := []FilteredLine{
+		{LineNumber: 1, Content: "package main"},
+		{LineNumber: 2, Content: "/* a comment"},
+		{LineNumber: 3, Content: "   spanning lines */"},
+		{LineNumber: 4, Content: `func main() { println("hi") }`},
+	}
+	longLines := make([]FilteredLine, highlightMaxLines+1)
+	for i := range longLines {
+		longLines[i] = FilteredLine{LineNumber: i + 1, Content: "x := 1"}
+	}
+
+	tests := []struct {
+		name        string
+		fileType    string
+		lines       []FilteredLine
+		color       bool
+		highlighted bool
+	}{
+		{name: "known type", fileType: "go", lines: goLines, color: true, highlighted: true},
+		{name: "color off", fileType: "go", lines: goLines},
+		{name: "unknown type", fileType: "unknown", lines: goLines, color: true},
+		{name: "plain text", fileType: "text", lines: goLines, color: true},
+		{name: "over the line threshold", fileType: "go", lines: longLines, color: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := ProcessedFile{
+				Info:       scanner.FileInfo{Path: "/tmp/main.go", RelPath: "main.go"},
+				FileType:   tt.fileType,
+				Lines:      tt.lines,
+				TotalLines: len(tt.lines),
+			}
+
+			var buf bytes.Buffer
+			runFormatter(t, NewPrettyOutput(&buf), []ProcessedFile{file}, &Config{Color: tt.color, ShowLineNumbers: true})
+
+			rows := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+			if len(rows) != len(tt.lines)+1 {
+				t.Fatalf("output has %d rows, want a header and %d lines", len(rows), len(tt.lines))
+			}
+			if header := rows[0]; tt.color != strings.Contains(header, ansiBold) {
+				t.Errorf("header %q styled = %v, want %v", header, !tt.color, tt.color)
+			}
+
+			content := rows[1:]
+			for i, row := range content {
+				want := fmt.Sprintf("%4d| %s", tt.lines[i].LineNumber, tt.lines[i].Content)
+				if got := ansi.Strip(row); got != want {
+					t.Errorf("line %d = %q, want %q", i+1, got, want)
+				}
+				// Every highlighted line closes its own colors, so a gutter never inherits one
+				if strings.Contains(row, "\x1b[") && !strings.HasSuffix(row, ansiReset) {
+					t.Errorf("line %d does not reset its colors: %q", i+1, row)
+				}
+			}
+			if got := strings.Contains(strings.Join(content, "\n"), "\x1b["); got != tt.highlighted {
+				t.Errorf("content highlighted = %v, want %v", got, tt.highlighted)
+			}
+		})
+	}
+}
+
+func TestPrettyResetsBeforeGutter(t *testing.T) {
+	file := ProcessedFile{
+		Info:     scanner.FileInfo{Path: "/tmp/log.txt", RelPath: "log.txt"},
+		FileType: "text",
+		Lines: []FilteredLine{
+			{LineNumber: 1, Content: "\x1b[31mred, never reset"},
+			{LineNumber: 2, Content: "plain"},
+		},
+		TotalLines: 2,
+	}
+
+	var buf bytes.Buffer
+	runFormatter(t, NewPrettyOutput(&buf), []ProcessedFile{file}, &Config{Color: true, ShowLineNumbers: true})
+
+	want := "   1| \x1b[31mred, never reset" + ansiReset + "\n   2| plain\n"
+	if got := buf.String(); !strings.HasSuffix(got, want) {
+		t.Errorf("output = %q, want it to end with %q", got, want)
+	}
+}
+
+func TestPrettyThemes(t *testing.T) {
+	lines := []string{`func main() { println("hi") }`}
+
+	dark := highlightLines(lines, "go", DefaultTheme)
+	light := highlightLines(lines, "go", LightTheme)
+	if dark == nil || light == nil {
+		t.Fatal("highlightLines() returned nil for go")
+	}
+	if dark[0] == light[0] {
+		t.Errorf("themes %s and %s render identically: %q", DefaultTheme, LightTheme, dark[0])
+	}
+	if got := highlightLines(lines, "go", ""); got[0] != dark[0] {
+		t.Errorf("empty theme = %q, want %s", got[0], DefaultTheme)
+	}
+}
@@ -0,0 +1,285 @@
+package catls
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"sync"
+)
+
+// promptTag is the element name that delimits files in prompt output.
+const promptTag = "file"
+
+// PromptOutput formats files for pasting into an LLM prompt: a preamble, one
+// <file> block per file, and an epilogue listing omitted files. The preamble
+// states the file count, so blocks are accumulated and written with the footer.
+type PromptOutput struct {
+	mu      sync.Mutex
+	w       io.Writer
+	blocks  strings.Builder
+	files   int
+	summary RunSummary
+	project string                  // Project header line opening the output
+	trailer func() IntegrityTrailer // Integrity trailer closing the output (nil for none)
+}
+
+// NewPromptOutput creates a new prompt output formatter that writes to w.
+func NewPromptOutput(w io.Writer) *PromptOutput {
+	return &PromptOutput{w: w}
+}
+
+// WriteHeader is a no-op; the preamble is written with the footer.
+func (*PromptOutput) WriteHeader(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	return nil
+}
+
+// WriteFile renders a processed file as a delimited block.
+func (p *PromptOutput) WriteFile(ctx context.Context, file *ProcessedFile, cfg *Config) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.blocks.Len() > 0 {
+		p.blocks.WriteString("\n")
+	}
+	if !file.Info.IsDir {
+		p.files++
+	}
+	writePromptFile(&p.blocks, file, cfg)
+	if note := matchLimitNote(file); note != "" {
+		p.blocks.WriteString(note + "\n")
+	}
+	if note := dirLimitNote(file); note != "" {
+		p.blocks.WriteString(note + "\n")
+	}
+
+	return nil
+}
+
+// SetProjectHeader records the header that opens the output.
+func (p *PromptOutput) SetProjectHeader(header ProjectHeader) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.project = header.String()
+}
+
+// WriteRunSummary records the repository root and omitted files for the
+// preamble and epilogue.
+func (p *PromptOutput) WriteRunSummary(ctx context.Context, summary RunSummary) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	p.mu.Lock()
+	p.summary = summary
+	p.mu.Unlock()
+
+	return nil
+}
+
+// WriteFooter writes the preamble, the accumulated file blocks, the
+// epilogue, and the integrity trailer when one is set.
+func (p *PromptOutput) WriteFooter(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var b strings.Builder
+	if p.project != "" {
+		b.WriteString(p.project + "\n\n")
+	}
+	writePromptPreamble(&b, p.files, p.summary)
+	if p.blocks.Len() > 0 {
+		b.WriteString("\n")
+		b.WriteString(p.blocks.String())
+	}
+	writePromptEpilogue(&b, p.summary)
+	if p.trailer == nil {
+		_, err := io.WriteString(p.w, b.String())
+
+		return err
+	}
+
+	b.WriteString("\n")
+	if _, err := io.WriteString(p.w, b.String()); err != nil {
+		return err
+	}
+	_, err := io.WriteString(p.w, integrityComment(p.trailer()))
+
+	return err
+}
+
+// SetIntegrity makes WriteFooter close the output with the trailer as a
+// comment, after a blank line.
+func (p *PromptOutput) SetIntegrity(trailer func() IntegrityTrailer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.trailer = trailer
+}
+
+func writePromptPreamble(b *strings.Builder, files int, summary RunSummary) {
+	noun := "files"
+	if files == 1 {
+		noun = "file"
+	}
+
+	if summary.Root != "" {
+		fmt.Fprintf(b, "The following %d %s are from the repository %s.", files, noun, summary.Root)
+	} else {
+		fmt.Fprintf(b, "The following %d %s are from a repository.", files, noun)
+	}
+	fmt.Fprintf(b, " Each file starts with a <%s path=\"...\"> line and ends with the matching </%s> line;"+
+		" if a file's content contains that delimiter, its tag carries a unique suffix such as <%s-1a2b3c>.\n",
+		promptTag, promptTag, promptTag)
+}
+
+func writePromptEpilogue(b *strings.Builder, summary RunSummary) {
+	if len(summary.Omitted) == 0 {
+		return
+	}
+
+	b.WriteString("\n")
+	if summary.MaxTokens > 0 {
+		fmt.Fprintf(b, "%d files were omitted (token budget %d, about %d tokens included):\n",
+			len(summary.Omitted), summary.MaxTokens, summary.Tokens)
+	} else {
+		fmt.Fprintf(b, "%d files were omitted:\n", len(summary.Omitted))
+	}
+	for _, file := range summary.Omitted {
+		fmt.Fprintf(b, "- %s (%s)\n", file.Path, file.Reason)
+	}
+}
+
+// writePromptFile renders one file block. Content is written verbatim; the
+// block's tag is uniquified instead when the content could be mistaken for it.
+// Directory records, duplicates, lockfile summaries, binary files, and errors
+// are self-closing tags.
+func writePromptFile(b *strings.Builder, file *ProcessedFile, cfg *Config) {
+	safePath := html.EscapeString(file.Info.RelPath)
+	if file.Info.IsDir {
+		fmt.Fprintf(b, "<dir path=\"%s\"/>\n", safePath)
+
+		return
+	}
+
+	switch {
+	case file.Error != nil:
+		fmt.Fprintf(b, "<%s path=\"%s\"%s error=\"%s\"%s/>\n",
+			promptTag, safePath, executableAttr(file), html.EscapeString(file.Error.Error()), errorCategoryAttr("error-category", file))
+
+		return
+	case file.DuplicateOf != "":
+		fmt.Fprintf(b, "<%s path=\"%s\"%s duplicate-of=\"%s\"/>\n",
+			promptTag, safePath, executableAttr(file), html.EscapeString(file.DuplicateOf))
+
+		return
+	case file.Lockfile != nil:
+		fmt.Fprintf(b, "<%s path=\"%s\"%s lockfile-summary=\"true\"%s", promptTag, safePath, executableAttr(file), lockfileAttrs(file.Lockfile))
+		if len(file.Lockfile.Direct) > 0 {
+			fmt.Fprintf(b, " direct=\"%s\"", html.EscapeString(strings.Join(file.Lockfile.Direct, ",")))
+		}
+		b.WriteString("/>\n")
+
+		return
+	case file.Info.IsBinary:
+		fmt.Fprintf(b, "<%s path=\"%s\"%s binary=\"true\"/>\n", promptTag, safePath, executableAttr(file))
+
+		return
+	}
+
+	gutter := newLineGutter(file, cfg)
+	lines := make([]string, 0, len(file.Lines)+1)
+	for _, line := range file.Lines {
+		lines = append(lines, gutter.Line(line, line.Content))
+	}
+	if cfg.LegacyTruncation {
+		if notice := gutter.TruncationNotice(file); notice != "" {
+			lines = append(lines, notice)
+		}
+	}
+
+	tag := promptBoundary(lines, cfg.Deterministic)
+
+	fmt.Fprintf(b, "<%s path=\"%s\"", tag, safePath)
+	if file.FileType != "" {
+		fmt.Fprintf(b, " lang=\"%s\"", html.EscapeString(file.FileType))
+	}
+	b.WriteString(executableAttr(file))
+	if file.IsEmpty {
+		b.WriteString(" empty=\"true\"")
+	}
+	if file.IsReadme {
+		b.WriteString(" readme=\"true\"")
+	}
+	b.WriteString(reformattedAttr(file))
+	b.WriteString(reducedAttr(file))
+	b.WriteString(modifiedAttr(file))
+	if remaining := remainingLines(file); remaining > 0 && !cfg.LegacyTruncation {
+		fmt.Fprintf(b, " truncated=\"true\" remaining-lines=\"%d\"", remaining)
+	}
+	b.WriteString(">\n")
+
+	for _, line := range lines {
+		b.WriteString(line + "\n")
+	}
+	fmt.Fprintf(b, "</%s>\n", tag)
+}
+
+// promptBoundary returns the tag delimiting content: promptTag, or promptTag
+// with a suffix when any line contains an opening or closing form of it. The
+// suffix is random unless deterministic, in which case it is derived from lines.
+func promptBoundary(lines []string, deterministic bool) string {
+	tag := promptTag
+	for attempt := 0; containsPromptTag(lines, tag); attempt++ {
+		if deterministic {
+			tag = promptTag + "-" + contentSuffix(lines, attempt)
+		} else {
+			tag = promptTag + "-" + randomSuffix()
+		}
+	}
+
+	return tag
+}
+
+// containsPromptTag reports whether any line contains <tag or </tag.
+func containsPromptTag(lines []string, tag string) bool {
+	for _, line := range lines {
+		if strings.Contains(line, "<"+tag) || strings.Contains(line, "</"+tag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// randomSuffix returns six random hex digits.
+func randomSuffix() string {
+	buf := make([]byte, 3)
+	_, _ = rand.Read(buf)
+
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,100 @@
+package catls
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+func TestPromptBoundary(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		unique  bool
+	}{
+		{name: "plain content", content: "package main"},
+		{name: "closing tag", content: "</file>", unique: true},
+		{name: "opening tag", content: `<file path="x">`, unique: true},
+		{name: "similar word", content: "profile"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			out := NewPromptOutput(&buf)
+			file := &ProcessedFile{
+				Info:       scanner.FileInfo{Path: "/tmp/a.txt", RelPath: "a.txt"},
+				FileType:   "text",
+				Lines:      []FilteredLine{{LineNumber: 1, Content: tt.content}},
+				TotalLines: 1,
+			}
+			runFormatter(t, out, []ProcessedFile{*file}, &Config{})
+
+			open := regexp.MustCompile(`(?m)^<(file(?:-[0-9a-f]{6})?) path="a.txt" lang="text">$`).FindStringSubmatch(buf.String())
+			if open == nil {
+				t.Fatalf("no opening tag in output:\n%s", buf.String())
+			}
+			tag := open[1]
+
+			if tt.unique == (tag == promptTag) {
+				t.Errorf("tag = %q, want uniquified %v", tag, tt.unique)
+			}
+			if strings.Contains(tt.content, tag) && tag != promptTag {
+				t.Errorf("uniquified tag %q still occurs in content", tag)
+			}
+			if want := "\n" + tt.content + "\n</" + tag + ">\n"; !strings.Contains(buf.String(), want) {
+				t.Errorf("output missing content closed by %q:\n%s", tag, buf.String())
+			}
+		})
+	}
+}
+
+func TestPromptMaxTokens(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"a.txt":     strings.Repeat("a", 39),  // 10 tokens with its newline
+		"b.txt":     strings.Repeat("b", 199), // 50 tokens
+		"c.txt":     strings.Repeat("c", 79),  // 20 tokens
+		"empty.txt": "",
+	})
+
+	var buf bytes.Buffer
+	app, err := New(&Config{
+		Directory:    tmpDir,
+		RelativeTo:   tmpDir,
+		OutputFormat: OutputFormatPrompt,
+		Output:       &buf,
+		SkipEmpty:    true,
+		MaxTokens:    40,
+	})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	output := buf.String()
+	for _, want := range []string{
+		"The following 2 files are from the repository ",
+		`<file path="a.txt"`,
+		`<file path="c.txt"`,
+		"2 files were omitted (token budget 40, about 30 tokens included):\n" +
+			"- b.txt (over token budget)\n" +
+			"- empty.txt (empty)\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, `<file path="b.txt"`) {
+		t.Errorf("file over the budget was written:\n%s", output)
+	}
+	if got := app.Stats().SkippedBudget; got != 1 {
+		t.Errorf("SkippedBudget = %d, want 1", got)
+	}
+}
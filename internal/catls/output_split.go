@@ -0,0 +1,302 @@
+package catls
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// OutputIndexName is the file OutputTemplate runs write at the root of
+// OutputDir, listing every file they generated.
+const OutputIndexName = "catls-index.json"
+
+// OutputTemplateData is what OutputTemplate is executed with for each file
+// and format.
+type OutputTemplateData struct {
+	RelPath string // Path of the source file, with forward slashes
+	Dir     string // Directory of RelPath, "." at the root
+	Base    string // Last element of RelPath
+	Stem    string // Base without its extension
+	Type    string // Detected file type, empty if unknown
+	Format  string // Output format, such as markdown
+	Ext     string // File extension of Format, such as md
+}
+
+// OutputIndex is the document written to OutputIndexName.
+type OutputIndex struct {
+	Files []OutputIndexEntry `json:"files"`
+}
+
+// OutputIndexEntry names a generated file and the source file it renders.
+type OutputIndexEntry struct {
+	Source string `json:"source"`
+	Output string `json:"output"` // Relative to OutputDir, with forward slashes
+	Format string `json:"format"`
+}
+
+// parseOutputTemplate parses OutputTemplate.
+func parseOutputTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("output").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --output-template: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+// splitOutput writes each file to its own self-contained document under
+// OutputDir, named by OutputTemplate: every document has the header and
+// footer of its format around a single file. Run-wide sections, such as the
+// todo index and the run summary, are left out.
+type splitOutput struct {
+	root    *os.Root // OutputDir; names cannot escape it, even through symlinks
+	tmpl    *template.Template
+	formats []OutputFormat
+	options []FormatOptions
+	echo    *ConfigEcho
+	header  *ProjectHeader
+	sources map[string]string // Case-folded generated name -> the source file it renders, "" for the index
+	dirs    map[string]string // Case-folded directory of a generated name -> the first source file needing it
+	index   OutputIndex
+}
+
+// openSplitOutput opens OutputDir for OutputTemplate and points a.output at
+// a splitOutput. The returned function closes the directory.
+func (a *App) openSplitOutput() (func() error, error) {
+	if rel, ok := relInside(resolveScanDir(a.cfg.Directory), a.cfg.OutputDir); ok && rel == "." && len(a.cfg.Paths) == 0 {
+		return nil, errors.New("--output-template needs an --output-dir outside the scanned directory")
+	}
+	tmpl, err := parseOutputTemplate(a.cfg.OutputTemplate)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(a.cfg.OutputDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	root, err := os.OpenRoot(a.cfg.OutputDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open output directory: %w", err)
+	}
+
+	split := &splitOutput{
+		root:    root,
+		tmpl:    tmpl,
+		sources: map[string]string{strings.ToLower(OutputIndexName): ""},
+		dirs:    map[string]string{},
+	}
+	for _, format := range a.cfg.formats() {
+		opts, err := a.cfg.formatOptions(format)
+		if err != nil {
+			return nil, errors.Join(err, root.Close())
+		}
+		split.formats = append(split.formats, format)
+		split.options = append(split.options, opts)
+	}
+	a.output = split
+
+	return root.Close, nil
+}
+
+// WriteHeader is a no-op; each document gets its own header.
+func (*splitOutput) WriteHeader(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// WriteFile writes file to one new document per format. Every name is
+// checked before any document is written. Directory records have no
+// document.
+func (s *splitOutput) WriteFile(ctx context.Context, file *ProcessedFile, cfg *Config) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if file.Info.IsDir {
+		return nil
+	}
+
+	names := make([]string, len(s.formats))
+	for i, format := range s.formats {
+		name, err := s.outputName(file, format)
+		if err != nil {
+			return err
+		}
+		names[i] = name
+	}
+	for i, format := range s.formats {
+		name := names[i]
+		if err := s.writeDocument(ctx, name, file, cfg, format, s.options[i]); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		s.index.Files = append(s.index.Files, OutputIndexEntry{
+			Source: filepath.ToSlash(file.Info.RelPath),
+			Output: filepath.ToSlash(name),
+			Format: format.String(),
+		})
+	}
+
+	return nil
+}
+
+// outputName executes the template for file in format and checks that the
+// name stays inside OutputDir and is not already taken, either as a
+// document or as the directory of one. Names are compared case-folded, since
+// on case-insensitive filesystems, such as those of macOS and Windows, names
+// differing only in case are the same file.
+func (s *splitOutput) outputName(file *ProcessedFile, format OutputFormat) (string, error) {
+	relPath := filepath.ToSlash(file.Info.RelPath)
+	base := path.Base(relPath)
+	info, _ := LookupFormat(format)
+	data := OutputTemplateData{
+		RelPath: relPath,
+		Dir:     path.Dir(relPath),
+		Base:    base,
+		Stem:    strings.TrimSuffix(base, path.Ext(base)),
+		Type:    file.FileType,
+		Format:  format.String(),
+		Ext:     info.FileExtension(),
+	}
+
+	var b strings.Builder
+	if err := s.tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("--output-template for %s: %w", relPath, err)
+	}
+	name := filepath.Clean(filepath.FromSlash(b.String()))
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("--output-template renders %s as %q, which is outside --output-dir", relPath, b.String())
+	}
+
+	key := strings.ToLower(name)
+	if other, taken := s.sources[key]; taken {
+		if other == "" {
+			return "", fmt.Errorf("--output-template renders %s as %s, the name of the index", relPath, name)
+		}
+
+		return "", fmt.Errorf("--output-template renders both %s and %s as %s", other, relPath, name)
+	}
+	if other, taken := s.dirs[key]; taken {
+		return "", fmt.Errorf("--output-template renders %s as %s, a directory of the document for %s", relPath, name, other)
+	}
+	for dir := filepath.Dir(key); dir != "."; dir = filepath.Dir(dir) {
+		other, taken := s.sources[dir]
+		if !taken {
+			continue
+		}
+		if other == "" {
+			return "", fmt.Errorf("--output-template renders %s as %s, inside the index", relPath, name)
+		}
+
+		return "", fmt.Errorf("--output-template renders %s as %s, inside the document for %s", relPath, name, other)
+	}
+	s.sources[key] = relPath
+	for dir := filepath.Dir(key); dir != "."; dir = filepath.Dir(dir) {
+		if _, ok := s.dirs[dir]; !ok {
+			s.dirs[dir] = relPath
+		}
+	}
+
+	return name, nil
+}
+
+// writeDocument writes file alone, with the header and footer of format, to
+// name under OutputDir, creating its directories.
+func (s *splitOutput) writeDocument(ctx context.Context, name string, file *ProcessedFile, cfg *Config, format OutputFormat, opts FormatOptions) (err error) {
+	if err := s.mkdirAll(filepath.Dir(name)); err != nil {
+		return err
+	}
+	out, err := s.root.Create(name)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, out.Close())
+	}()
+
+	formatter, err := NewOutputFormatter(format, out, opts)
+	if err != nil {
+		return err
+	}
+	if writer, ok := formatter.(ConfigEchoWriter); ok && s.echo != nil {
+		echo := *s.echo
+		echo.Format = format.String()
+		writer.SetConfigEcho(echo)
+	}
+	if writer, ok := formatter.(ProjectHeaderWriter); ok && s.header != nil {
+		writer.SetProjectHeader(*s.header)
+	}
+
+	if err := formatter.WriteHeader(ctx); err != nil {
+		return err
+	}
+	if err := formatter.WriteFile(ctx, file, cfg); err != nil {
+		return err
+	}
+
+	return formatter.WriteFooter(ctx)
+}
+
+// mkdirAll creates dir and its parents under OutputDir.
+func (s *splitOutput) mkdirAll(dir string) error {
+	if dir == "." {
+		return nil
+	}
+	if err := s.mkdirAll(filepath.Dir(dir)); err != nil {
+		return err
+	}
+	if err := s.root.Mkdir(dir, 0o755); err != nil && !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+
+	return nil
+}
+
+// WriteFooter writes the index of the generated files.
+func (s *splitOutput) WriteFooter(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if s.index.Files == nil {
+		s.index.Files = []OutputIndexEntry{}
+	}
+
+	out, err := s.root.Create(OutputIndexName)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+
+	return errors.Join(encoder.Encode(s.index), out.Close())
+}
+
+// SetConfigEcho keeps the echo for every document.
+func (s *splitOutput) SetConfigEcho(echo ConfigEcho) {
+	s.echo = &echo
+}
+
+// SetProjectHeader keeps the header for every document.
+func (s *splitOutput) SetProjectHeader(header ProjectHeader) {
+	s.header = &header
+}
+
+// validateOutputTemplate requires OutputDir for OutputTemplate, a template
+// that parses, and no Integrity, whose trailer covers a single document.
+func (c *Config) validateOutputTemplate() error {
+	if c.OutputTemplate == "" {
+		return nil
+	}
+	if c.OutputDir == "" {
+		return errors.New("--output-template requires --output-dir")
+	}
+	if c.Integrity {
+		return errors.New("--integrity does not apply to --output-template")
+	}
+	_, err := parseOutputTemplate(c.OutputTemplate)
+
+	return err
+}
@@ -0,0 +1,144 @@
+package catls
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOutputTemplate(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"README.md":      "# Project\n",
+		"src/main.go":    "package main\n",
+		"src/lib/lib.go": "package lib\n",
+	})
+	outDir := filepath.Join(t.TempDir(), "docs")
+
+	cfg := &Config{
+		Directory:      dir,
+		Recursive:      true,
+		IncludeDirs:    true,
+		OutputFormat:   OutputFormatMarkdown,
+		OutputFormats:  []OutputFormat{OutputFormatMarkdown, OutputFormatXML},
+		OutputDir:      outDir,
+		OutputTemplate: "{{.Dir}}/{{.Stem}}.{{.Ext}}",
+		ConfigEcho:     true,
+	}
+	app, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	// Each document is self-contained, holding its one file
+	for name, want := range map[string]string{
+		"README.md":       "## README.md",
+		"src/main.md":     "package main",
+		"src/lib/lib.md":  "package lib",
+		"src/main.xml":    `<file path="src/main.go">`,
+		"src/lib/lib.xml": `<file path="src/lib/lib.go">`,
+	} {
+		data, err := os.ReadFile(filepath.Join(outDir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Errorf("%s not written: %v", name, err)
+
+			continue
+		}
+		if !strings.Contains(string(data), want) {
+			t.Errorf("%s does not contain %q:\n%s", name, want, data)
+		}
+		if strings.HasSuffix(name, ".xml") && (!strings.Contains(string(data), "<files>") || !strings.HasSuffix(string(data), "</files>\n")) {
+			t.Errorf("%s is not a whole document:\n%s", name, data)
+		}
+		if strings.Count(string(data), "<file path=") > 1 {
+			t.Errorf("%s holds more than one file:\n%s", name, data)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(outDir, OutputIndexName))
+	if err != nil {
+		t.Fatalf("index not written: %v", err)
+	}
+	var index OutputIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		t.Fatalf("invalid index: %v", err)
+	}
+	if len(index.Files) != 6 {
+		t.Errorf("index lists %d outputs, want 6: %+v", len(index.Files), index.Files)
+	}
+	if got := index.Files[0]; got != (OutputIndexEntry{Source: "README.md", Output: "README.md", Format: "markdown"}) {
+		t.Errorf("first index entry = %+v", got)
+	}
+}
+
+func TestOutputTemplateErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		wantErr  string
+	}{
+		{name: "collision", template: "{{.Base}}.md", wantErr: "renders both a/x.go and b/x.go as x.go.md"},
+		{name: "collision in case", template: `{{if eq .Dir "a"}}X{{else}}x{{end}}.md`, wantErr: "renders both a/x.go and b/x.go as x.md"},
+		{name: "document as directory", template: `{{if eq .Dir "a"}}n{{else}}n/x{{end}}`, wantErr: "renders b/x.go as n/x, inside the document for a/x.go"},
+		{name: "directory as document", template: `{{if eq .Dir "a"}}n/x{{else}}N{{end}}`, wantErr: "renders b/x.go as N, a directory of the document for a/x.go"},
+		{name: "index as directory", template: OutputIndexName + "/{{.RelPath}}", wantErr: "inside the index"},
+		{name: "escape", template: "../{{.RelPath}}", wantErr: `renders a/x.go as "../a/x.go", which is outside --output-dir`},
+		{name: "absolute", template: "/tmp/{{.RelPath}}", wantErr: "outside --output-dir"},
+		{name: "index", template: OutputIndexName, wantErr: "the name of the index"},
+		{name: "unknown field", template: "{{.Path}}", wantErr: "can't evaluate field Path"},
+	}
+
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"a/x.go": "package a\n", "b/x.go": "package b\n"})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outDir := t.TempDir()
+			cfg := &Config{
+				Directory:      dir,
+				Recursive:      true,
+				OutputFormat:   OutputFormatMarkdown,
+				OutputDir:      outDir,
+				OutputTemplate: tt.template,
+			}
+			app, err := New(cfg)
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+			if err := app.Run(context.Background()); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Run() error = %v, want %q", err, tt.wantErr)
+			}
+			if _, err := os.Stat(filepath.Join(filepath.Dir(outDir), "a")); err == nil {
+				t.Error("a file was written outside --output-dir")
+			}
+		})
+	}
+}
+
+func TestOutputTemplateChecksNamesFirst(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"x.go": "package x\n"})
+	outDir := t.TempDir()
+	cfg := &Config{
+		Directory:      dir,
+		OutputFormat:   OutputFormatMarkdown,
+		OutputFormats:  []OutputFormat{OutputFormatMarkdown, OutputFormatXML},
+		OutputDir:      outDir,
+		OutputTemplate: "{{.Stem}}.out",
+	}
+	app, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if err := app.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "renders both x.go and x.go as x.out") {
+		t.Fatalf("Run() error = %v, want a collision", err)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "x.out")); err == nil {
+		t.Error("x.out was written before the collision was found")
+	}
+}
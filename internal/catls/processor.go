@@ -2,17 +2,45 @@ package catls
 
 import (
 	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"errors"
 	"fmt"
+	"hash"
+	"io"
 	"os"
-	"path/filepath"
 	"strings"
 
+	"github.com/connerohnesorge/catls/internal/contentcache"
+	"github.com/connerohnesorge/catls/internal/fdlimit"
+	"github.com/connerohnesorge/catls/internal/languages"
+	"github.com/connerohnesorge/catls/internal/profile"
 	"github.com/connerohnesorge/catls/internal/scanner"
 )
 
+const (
+	// maxDisplayLines is the longest file shown in full; longer ones are truncated.
+	maxDisplayLines = 1000
+	// truncateToLines is how many lines of a truncated file are shown.
+	truncateToLines = 100
+)
+
 // FileProcessor handles file content processing.
 type FileProcessor struct {
 	typeDetector TypeDetector
+	detectCache  *scanner.DetectionCache
+	transformers []LineTransformer
+	langMap      map[string]string                   // Extension overrides consulted before typeDetector
+	profile      *profile.Collector                  // Records per-file stage timings (nil disables profiling)
+	contentCache *contentcache.Cache                 // Lines already read by the interactive preview or an earlier serve request (nil disables reuse)
+	frontMatter  frontMatterMode                     // What to keep of a leading front matter block
+	reformat     *reformatter                        // Re-indents JSON and YAML content (nil leaves it alone)
+	signatures   *signatureReducer                   // Reduces source files to their signatures (nil leaves them alone)
+	untruncated  bool                                // Keep long files whole for chunks output, which splits them itself, marking them truncatable
+	listXattrs   func(path string) ([]string, error) // Lists extended attribute names (nil unless Xattrs)
+	directives   bool                                // Honor catls:lang= directives above all other detection
+	hashContent  bool                                // Hash the bytes lines are read from, for the manifest
+	warn         io.Writer                           // Receives warnings about files being read
 }
 
 // ProcessedFile represents a file after processing.
@@ -22,7 +50,39 @@ type ProcessedFile struct {
 	Lines       []FilteredLine
 	TotalLines  int
 	IsTruncated bool
-	Error       error
+	IsEmpty     bool           // File has no content or only whitespace
+	IsReadme    bool           // File is a directory README placed by ReadmeFirst
+	Image       *EmbeddedImage // Inline copy of a small binary image, set by EmbedImages
+	DuplicateOf string         // Path of the earlier file with identical content, set by DedupeContent; the file has no lines
+	FrontMatter string         // Language of the leading front matter block, "yaml" or "toml", when FrontMatterOnly or StripFrontMatter found one
+	DirOmitted  int            // Files of the same directory that MaxFilesPerDir leaves out after this one
+	Raw         []byte         // Exact bytes of the file, set by ContentEncodingBase64 for files up to RawContentMax
+	Reformatted bool           // Lines are the content re-indented by PrettyJSON or PrettyYAML, not the text on disk
+	Reduced     string         // How Signatures reduced the content, ReducedSignatures or ReducedHead; empty when it is whole
+	// ReformatError is why PrettyJSON or PrettyYAML left the content as it is
+	// on disk, when it did not parse.
+	ReformatError error
+	Error         error
+	ErrorCategory ErrorCategory // Why Error occurred, set with it
+	// ModifiedDuringRun is set by StrictSnapshot when the file changed
+	// between the scan and the read.
+	ModifiedDuringRun bool
+	// Lockfile replaces the content of a known lockfile with
+	// SummarizeLockfiles; the file has no lines.
+	Lockfile *LockfileSummary
+	// Xattrs are the names of the file's extended attributes, set by Xattrs.
+	Xattrs []string
+	// MatchesSuppressed is set when MaxMatches left out further lines content
+	// patterns match. A file filtered as it is read stops there, and its
+	// TotalLines counts only the lines read.
+	MatchesSuppressed bool
+
+	// contentSum is the SHA-256 of the bytes Lines were read from, when the
+	// processor hashes content and read the whole file itself.
+	contentSum []byte
+	// truncatable is set when Lines were kept whole for chunks output,
+	// which splits them itself, though other formats truncate them.
+	truncatable bool
 }
 
 // TypeDetector defines interface for detecting file types.
@@ -33,10 +93,16 @@ type TypeDetector interface {
 	DetectType(filePath string) string
 }
 
-// NewFileProcessor creates a new file processor.
-func NewFileProcessor() *FileProcessor {
+// NewFileProcessor creates a new file processor. Type detection results are
+// memoized in detectCache when it is non-nil, and transformers are applied in
+// order to every line read.
+func NewFileProcessor(detectCache *scanner.DetectionCache, transformers ...LineTransformer) *FileProcessor {
 	return &FileProcessor{
-		typeDetector: &ExtensionTypeDetector{},
+		typeDetector: &ContentTypeDetector{},
+		detectCache:  detectCache,
+		transformers: transformers,
+		directives:   true,
+		warn:         os.Stderr,
 	}
 }
 
@@ -50,60 +116,304 @@ func (p *FileProcessor) ProcessFile(file scanner.FileInfo, filter *FileFilter) P
 		return result
 	}
 
-	// Detect file type
-	result.FileType = p.typeDetector.DetectType(file.Path)
+	// Scans by App detect the type up front; other callers pay for it here
+	if file.HasType {
+		result.FileType = file.FileType
+	} else {
+		result.FileType = p.detectType(file)
+	}
+
+	if p.streamsMatches(filter) {
+		p.processMatches(&result, file, filter)
+
+		return result
+	}
 
 	// Read file content
-	lines, err := p.readFileLines(file.Path)
+	done := p.profile.Start(file.RelPath, profile.StageRead)
+	lines, sum, err := p.readLines(file.Path)
+	done()
 	if err != nil {
-		result.Error = err
+		result.Error, result.ErrorCategory = err, categorizeError(err)
 
 		return result
 	}
 
+	result.contentSum = sum
+	lines = p.reformat.apply(&result, lines)
+	lines, skipped := p.frontMatter.apply(&result, lines)
 	result.TotalLines = len(lines)
+	result.IsEmpty = isBlankLines(lines)
+
+	if reduced, ok := p.signatures.apply(&result, lines, skipped); ok {
+		result.Lines = reduced
+
+		return result
+	}
 
 	// Apply content filtering
+	done = p.profile.Start(file.RelPath, profile.StageFilter)
 	filteredLines := filter.FilterContent(lines)
+	filteredLines, result.MatchesSuppressed = filter.capMatches(filteredLines)
+	done()
+	for i := range filteredLines {
+		// Number lines as in the file, before any front matter was dropped
+		filteredLines[i].LineNumber += skipped
+	}
 
 	// Check if we need to truncate for display
-	const maxDisplayLines = 1000
-	const truncateToLines = 100
+	result.Lines = filteredLines
+	if len(filteredLines) > maxDisplayLines && !filter.filtersContent() {
+		if p.untruncated {
+			result.truncatable = true
+		} else {
+			truncateLines(&result)
+		}
+	}
+
+	return result
+}
 
-	if len(filteredLines) > maxDisplayLines && filter.contentPattern == nil {
-		result.Lines = filteredLines[:truncateToLines]
-		result.IsTruncated = true
+// truncateLines keeps the first truncateToLines lines of a file too long to
+// show in full.
+func truncateLines(file *ProcessedFile) {
+	file.Lines = file.Lines[:truncateToLines]
+	file.IsTruncated = true
+	file.truncatable = false
+}
+
+// detectType returns the file type, using the detection cache when the file is
+// unchanged. A catls:lang= directive in the file wins, unless directives are
+// off; extension overrides come next and are never cached, since they change
+// between runs.
+func (p *FileProcessor) detectType(file scanner.FileInfo) string {
+	defer p.profile.Start(file.RelPath, profile.StageDetectType)()
+
+	mapped, isMapped := p.langMap[languages.Extension(file.Path)]
+	if isMapped && !p.directives {
+		return mapped
+	}
+
+	entry, ok := p.detectCache.Lookup(file.Path, file.Size, file.ModTime)
+	if !ok || !entry.HasType || (p.directives && !entry.HasDirective) {
+		entry = p.detectEntry(file)
+	}
+
+	switch {
+	case p.directives && entry.Directive != "":
+		return entry.Directive
+	case isMapped:
+		return mapped
+	default:
+		return entry.FileType
+	}
+}
+
+// detectEntry runs the type detector on file, searching it for a directive
+// too when directives are honored, and caches the result.
+func (p *FileProcessor) detectEntry(file scanner.FileInfo) scanner.DetectionEntry {
+	var fileType, directive string
+	if detector, ok := p.typeDetector.(*ContentTypeDetector); ok {
+		var unknown string
+		if fileType, directive, unknown = detector.detectWithDirective(file.Path, p.directives); unknown != "" {
+			fmt.Fprintf(p.warn, "Warning: Ignoring catls:lang=%s in %s: unknown language\n", unknown, file.Path)
+		}
 	} else {
-		result.Lines = filteredLines
+		fileType = p.typeDetector.DetectType(file.Path)
 	}
 
-	return result
+	entry := scanner.DetectionEntry{FileType: fileType, HasType: true, Directive: directive, HasDirective: p.directives}
+	p.detectCache.Store(file.Path, file.Size, file.ModTime, func(e *scanner.DetectionEntry) {
+		e.FileType, e.HasType = fileType, true
+		if p.directives {
+			e.Directive, e.HasDirective = directive, true
+		}
+	})
+
+	return entry
 }
 
-// readFileLines reads all lines from a file.
-func (*FileProcessor) readFileLines(filePath string) ([]string, error) {
-	file, err := os.Open(filePath)
+// readLines returns the transformed lines of a file, reusing the content
+// cache when it holds the whole file at its current size and modification
+// time, and storing the file's lines there when it does not. With
+// hashContent it also returns the SHA-256 of the bytes read, or nil when
+// the lines came from the cache.
+func (p *FileProcessor) readLines(filePath string) ([]string, []byte, error) {
+	var sum hash.Hash
+	if p.hashContent {
+		sum = sha256.New()
+	}
+	if p.contentCache == nil {
+		return p.readFileLines(filePath, p.transform, sum)
+	}
+
+	info, err := os.Stat(filePath)
 	if err != nil {
-		return nil, err
+		return p.readFileLines(filePath, p.transform, sum)
+	}
+	lines, ok := p.contentCache.Lookup(filePath, info.Size(), info.ModTime())
+	var digest []byte
+	if !ok {
+		// The file is stat'ed before it is read, so a change while reading
+		// leaves an entry that the next lookup finds stale
+		if lines, digest, err = p.readFileLines(filePath, nil, sum); err != nil {
+			return nil, nil, err
+		}
+		p.contentCache.Store(filePath, info.Size(), info.ModTime(), lines)
+	}
+
+	// The cache hands out copies, so the lines can be transformed in place
+	for i, line := range lines {
+		lines[i] = p.transform(line)
+	}
+
+	return lines, digest, nil
+}
+
+// transform applies the line transformers in order.
+func (p *FileProcessor) transform(line string) string {
+	for _, transform := range p.transformers {
+		line = transform(line)
+	}
+
+	return line
+}
+
+// readFileLines reads all lines from a file and applies transform to each
+// (nil keeps them as read). When sum is not nil, the bytes read are hashed
+// with it and the digest is returned.
+func (p *FileProcessor) readFileLines(filePath string, transform func(string) string, sum hash.Hash) ([]string, []byte, error) {
+	file, err := fdlimit.Open(filePath)
+	if err != nil {
+		return nil, nil, err
 	}
 	defer func() {
 		if closeErr := file.Close(); closeErr != nil {
 			// Log close error - in a real app you'd use a proper logger
-			fmt.Fprintf(os.Stderr, "Warning: failed to close file %s: %v\n", filePath, closeErr)
+			fmt.Fprintf(p.warn, "Warning: failed to close file %s: %v\n", filePath, closeErr)
 		}
 	}()
 
+	var in io.Reader = file
+	if sum != nil {
+		in = io.TeeReader(file, sum)
+	}
+	reader, err := decodeText(in)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	var lines []string
-	sc := bufio.NewScanner(file)
+	sc := bufio.NewScanner(reader)
 	for sc.Scan() {
-		lines = append(lines, sc.Text())
+		line := sc.Text()
+		if transform != nil {
+			line = transform(line)
+		}
+		lines = append(lines, line)
 	}
 
 	if err := sc.Err(); err != nil {
+		return nil, nil, err
+	}
+	if sum == nil {
+		return lines, nil, nil
+	}
+
+	return lines, sum.Sum(nil), nil
+}
+
+// readRaw returns the exact bytes of a file, without decoding or splitting
+// lines. It fails with errRawTooLarge when the file holds more than limit
+// bytes, as one that grew since the scan may.
+func (p *FileProcessor) readRaw(filePath string, limit int64) ([]byte, error) {
+	file, err := fdlimit.Open(filePath)
+	if err != nil {
 		return nil, err
 	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			fmt.Fprintf(p.warn, "Warning: failed to close file %s: %v\n", filePath, closeErr)
+		}
+	}()
+
+	data, err := io.ReadAll(io.LimitReader(file, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, errRawTooLarge
+	}
+
+	return data, nil
+}
+
+// decodeText returns the content of r as UTF-8. UTF-16 and UTF-32 text,
+// recognized by scanner.SniffEncoding, is decoded whole; anything else,
+// including UTF-8 with a byte order mark, is read as it is.
+func decodeText(r io.Reader) (io.Reader, error) {
+	reader := bufio.NewReader(r)
+	sample, _ := reader.Peek(1024)
+	encoding, bomLen := scanner.SniffEncoding(sample)
+	if encoding == scanner.EncodingNone || encoding == scanner.EncodingUTF8 {
+		return reader, nil
+	}
 
-	return lines, nil
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if text, ok := scanner.DecodeText(data, encoding, bomLen); ok {
+		return strings.NewReader(text), nil
+	}
+
+	return bytes.NewReader(data), nil
+}
+
+// isBlankLines reports whether every line consists solely of whitespace.
+func isBlankLines(lines []string) bool {
+	for _, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isBlankFile reports whether the file at path is empty or holds only
+// whitespace, warning warn if it cannot be closed.
+// It reads in small chunks and stops at the first non-whitespace byte, so large
+// files with content are rejected after the first read.
+func isBlankFile(path string, warn io.Writer) (bool, error) {
+	file, err := fdlimit.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			fmt.Fprintf(warn, "Warning: failed to close file %s: %v\n", path, closeErr)
+		}
+	}()
+
+	const chunkSize = 4096
+	reader := bufio.NewReaderSize(file, chunkSize)
+	for {
+		b, err := reader.ReadByte()
+		if errors.Is(err, io.EOF) {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		switch b {
+		case ' ', '\t', '\n', '\r', '\v', '\f':
+			continue
+		default:
+			return false, nil
+		}
+	}
 }
 
 // ExtensionTypeDetector detects file types based on extensions.
@@ -111,51 +421,5 @@ type ExtensionTypeDetector struct{}
 
 // DetectType implements TypeDetector.
 func (*ExtensionTypeDetector) DetectType(filePath string) string {
-	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(filePath), "."))
-
-	typeMap := map[string]string{
-		"sh":           langBash,
-		langBash:       langBash,
-		"rb":           langRuby,
-		"py":           langPython,
-		"js":           langJavaScript,
-		"ts":           langTypeScript,
-		"jsx":          langJavaScript,
-		"tsx":          langTypeScript,
-		langHTML:       langHTML,
-		"htm":          langHTML,
-		langNix:        langNix,
-		langCSS:        langCSS,
-		"scss":         langSCSS,
-		"sass":         langSCSS,
-		langJSON:       langJSON,
-		"md":           langMarkdown,
-		langMarkdown:   langMarkdown,
-		langXML:        langXML,
-		langC:          langC,
-		langCPP:        langCPP,
-		"cxx":          langCPP,
-		"cc":           langCPP,
-		"h":            langC,
-		"hpp":          langCPP,
-		"hxx":          langCPP,
-		langTOML:       langTOML,
-		langJava:       langJava,
-		"rs":           langRust,
-		langGo:         langGo,
-		langPHP:        langPHP,
-		"pl":           langPerl,
-		langSQL:        langSQL,
-		"templ":        langGo,
-		"yml":          langYAML,
-		langYAML:       langYAML,
-		langDockerfile: langDockerfile,
-		langMakefile:   langMakefile,
-	}
-
-	if fileType, exists := typeMap[ext]; exists {
-		return fileType
-	}
-
-	return ""
+	return languages.DetectByExtension(filePath)
 }
@@ -0,0 +1,231 @@
+package catls
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+// projectLanguages is how many languages a ProjectHeader names at most.
+const projectLanguages = 5
+
+// otherLanguage labels files without a detected type in a ProjectHeader.
+const otherLanguage = "other"
+
+// ProjectHeader introduces the project a run covers: its name and the share
+// of each language among the text files selected for output, by bytes.
+type ProjectHeader struct {
+	Name      string          `json:"name,omitempty"`
+	Manifest  string          `json:"manifest,omitempty"` // File the name came from; empty for the directory name
+	Languages []LanguageShare `json:"languages"`
+}
+
+// LanguageShare is one language's share of a project.
+type LanguageShare struct {
+	Language string `json:"language"`
+	Bytes    int64  `json:"bytes"`
+	Percent  int    `json:"percent"` // Rounded; languages rounding to 0 are left out
+}
+
+// ProjectHeaderWriter is implemented by formatters that can introduce the
+// output with a ProjectHeader. With ProjectHeader, App calls SetProjectHeader
+// before WriteHeader, and the formatter writes the header ahead of any file.
+type ProjectHeaderWriter interface {
+	SetProjectHeader(header ProjectHeader)
+}
+
+// String renders the header as one sentence, such as "Project: catls,
+// primary languages: go (72%), markdown (20%)."
+func (h ProjectHeader) String() string {
+	shares := make([]string, len(h.Languages))
+	for i, share := range h.Languages {
+		shares[i] = fmt.Sprintf("%s (%d%%)", share.Language, share.Percent)
+	}
+	languages := "none"
+	if len(shares) > 0 {
+		languages = strings.Join(shares, ", ")
+	}
+
+	if h.Name == "" {
+		return "Primary languages: " + languages + "."
+	}
+
+	return "Project: " + h.Name + ", primary languages: " + languages + "."
+}
+
+// projectHeader describes the project for the files about to be written.
+// Binary files and directory records do not count toward the languages.
+func (a *App) projectHeader(files []scanner.FileInfo) ProjectHeader {
+	header := ProjectHeader{Languages: languageShares(files)}
+	header.Name, header.Manifest = projectName(a.cfg.Directory)
+	if header.Name == "" {
+		header.Name = a.rootName()
+	}
+
+	return header
+}
+
+// languageShares totals file sizes by detected type and returns the largest
+// shares, largest first.
+func languageShares(files []scanner.FileInfo) []LanguageShare {
+	bytesByLanguage := make(map[string]int64)
+	var total int64
+	for _, file := range files {
+		if file.IsDir || file.IsBinary {
+			continue
+		}
+		language := cmp.Or(file.FileType, otherLanguage)
+		bytesByLanguage[language] += file.Size
+		total += file.Size
+	}
+	if total == 0 {
+		return []LanguageShare{}
+	}
+
+	shares := make([]LanguageShare, 0, len(bytesByLanguage))
+	for language, size := range bytesByLanguage {
+		percent := int((size*100 + total/2) / total)
+		if percent > 0 {
+			shares = append(shares, LanguageShare{Language: language, Bytes: size, Percent: percent})
+		}
+	}
+	slices.SortFunc(shares, func(a, b LanguageShare) int {
+		return cmp.Or(cmp.Compare(b.Bytes, a.Bytes), cmp.Compare(a.Language, b.Language))
+	})
+
+	return shares[:min(len(shares), projectLanguages)]
+}
+
+// projectManifests lists the manifests that name a project, in the order
+// they are consulted, with the parser of each.
+var projectManifests = []struct {
+	name  string
+	parse func(data []byte) string
+}{
+	{"go.mod", goModuleName},
+	{"package.json", packageJSONName},
+	{"Cargo.toml", tomlSectionName("package")},
+	{"pyproject.toml", tomlSectionName("project", "tool.poetry")},
+}
+
+// projectName returns the project name from the first manifest in dir that
+// has one, with the manifest's file name.
+func projectName(dir string) (string, string) {
+	for _, manifest := range projectManifests {
+		data, err := os.ReadFile(filepath.Join(dir, manifest.name))
+		if err != nil {
+			continue
+		}
+		if name := manifest.parse(data); name != "" {
+			return name, manifest.name
+		}
+	}
+
+	return "", ""
+}
+
+// goModuleName returns the module path of a go.mod file.
+func goModuleName(data []byte) string {
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "//")
+		rest, ok := strings.CutPrefix(strings.TrimSpace(line), "module")
+		if !ok || rest == "" || (rest[0] != ' ' && rest[0] != '\t' && rest[0] != '"') {
+			continue
+		}
+		rest = strings.TrimSpace(rest)
+		if unquoted, err := strconv.Unquote(rest); err == nil {
+			return unquoted
+		}
+
+		return rest
+	}
+
+	return ""
+}
+
+// packageJSONName returns the "name" field of a package.json file.
+func packageJSONName(data []byte) string {
+	var pkg struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(pkg.Name)
+}
+
+// tomlSectionName returns a parser for the name key of the first of sections
+// present in a TOML file. Only the basic and literal single-line strings
+// manifests use are understood.
+func tomlSectionName(sections ...string) func(data []byte) string {
+	return func(data []byte) string {
+		names := make(map[string]string)
+		section := ""
+		sc := bufio.NewScanner(bytes.NewReader(data))
+		for sc.Scan() {
+			line := strings.TrimSpace(sc.Text())
+			if strings.HasPrefix(line, "[") {
+				section = strings.TrimSpace(strings.Trim(line, "[]"))
+
+				continue
+			}
+
+			key, value, ok := strings.Cut(line, "=")
+			if !ok || strings.TrimSpace(key) != "name" || names[section] != "" {
+				continue
+			}
+			if name := tomlString(strings.TrimSpace(value)); name != "" {
+				names[section] = name
+			}
+		}
+
+		for _, section := range sections {
+			if name := names[section]; name != "" {
+				return name
+			}
+		}
+
+		return ""
+	}
+}
+
+// tomlString returns the value of a single-line TOML string, ignoring a
+// trailing comment, or empty for anything else.
+func tomlString(value string) string {
+	if strings.HasPrefix(value, "'") {
+		if end := strings.Index(value[1:], "'"); end >= 0 {
+			return value[1 : end+1]
+		}
+
+		return ""
+	}
+
+	if !strings.HasPrefix(value, `"`) {
+		return ""
+	}
+	for end := 1; end < len(value); end++ {
+		switch value[end] {
+		case '\\':
+			end++
+		case '"':
+			if unquoted, err := strconv.Unquote(value[:end+1]); err == nil {
+				return unquoted
+			}
+
+			return ""
+		}
+	}
+
+	return ""
+}
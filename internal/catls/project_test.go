@@ -0,0 +1,141 @@
+package catls
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+func TestProjectName(t *testing.T) {
+	tests := []struct {
+		name         string
+		files        map[string]string
+		want         string
+		wantManifest string
+	}{
+		{
+			name:         "go module",
+			files:        map[string]string{"go.mod": "// comment\nmodule github.com/me/tool // trailing\n\ngo 1.24\n"},
+			want:         "github.com/me/tool",
+			wantManifest: "go.mod",
+		},
+		{
+			name:         "quoted go module",
+			files:        map[string]string{"go.mod": "module \"example.com/quoted\"\n"},
+			want:         "example.com/quoted",
+			wantManifest: "go.mod",
+		},
+		{
+			name:         "package.json",
+			files:        map[string]string{"package.json": `{"version": "1.0.0", "name": "@me/web"}`},
+			want:         "@me/web",
+			wantManifest: "package.json",
+		},
+		{
+			name: "cargo package, not a dependency",
+			files: map[string]string{"Cargo.toml": "[dependencies]\nname = \"wrong\"\n\n" +
+				"[package]\nname = \"crab\" # the crate\nversion = \"0.1.0\"\n"},
+			want:         "crab",
+			wantManifest: "Cargo.toml",
+		},
+		{
+			name:         "pyproject",
+			files:        map[string]string{"pyproject.toml": "[project]\nname = 'snake'\n"},
+			want:         "snake",
+			wantManifest: "pyproject.toml",
+		},
+		{
+			name:         "poetry",
+			files:        map[string]string{"pyproject.toml": "[tool.poetry]\nname = \"verse\"\n"},
+			want:         "verse",
+			wantManifest: "pyproject.toml",
+		},
+		{
+			name: "first manifest with a name wins",
+			files: map[string]string{
+				"go.mod":       "go 1.24\n",
+				"package.json": `{"name": "front"}`,
+			},
+			want:         "front",
+			wantManifest: "package.json",
+		},
+		{name: "invalid package.json", files: map[string]string{"package.json": "{"}},
+		{name: "no manifest"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTree(t, dir, tt.files)
+
+			name, manifest := projectName(dir)
+			if name != tt.want || manifest != tt.wantManifest {
+				t.Errorf("projectName() = %q, %q; want %q, %q", name, manifest, tt.want, tt.wantManifest)
+			}
+		})
+	}
+}
+
+func TestLanguageShares(t *testing.T) {
+	shares := languageShares([]scanner.FileInfo{
+		{RelPath: "a.go", FileType: "go", Size: 700},
+		{RelPath: "b.go", FileType: "go", Size: 20},
+		{RelPath: "README.md", FileType: "markdown", Size: 200},
+		{RelPath: "LICENSE", Size: 76},
+		{RelPath: "tiny.sh", FileType: "bash", Size: 4},
+		{RelPath: "logo.png", IsBinary: true, Size: 10000},
+		{RelPath: "docs", IsDir: true},
+	})
+
+	if got := fmt.Sprint(shares); got != "[{go 720 72} {markdown 200 20} {other 76 8}]" {
+		t.Errorf("languageShares() = %s", got)
+	}
+	if got := (ProjectHeader{Name: "tool", Languages: shares}).String(); got != "Project: tool, primary languages: go (72%), markdown (20%), other (8%)." {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestProjectHeaderFormats(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"go.mod":  "module example.com/tool\n",
+		"main.go": "package main\n",
+	})
+
+	tests := []struct {
+		format OutputFormat
+		want   string
+	}{
+		{OutputFormatXML, "<files>\n<project name=\"example.com/tool\">\n<language "},
+		{OutputFormatJSON, "\"project\": {\n    \"name\": \"example.com/tool\",\n    \"manifest\": \"go.mod\",\n    \"languages\": ["},
+		{OutputFormatMarkdown, "Project: example.com/tool, primary languages: "},
+		{OutputFormatPrompt, "Project: example.com/tool, primary languages: "},
+		{OutputFormatPretty, "Project: example.com/tool, primary languages: "},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var buf bytes.Buffer
+			app, err := New(&Config{Directory: tmpDir, OutputFormat: tt.format, Output: &buf, ProjectHeader: true})
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+			if err := app.Run(context.Background()); err != nil {
+				t.Fatalf("Run() unexpected error: %v", err)
+			}
+			got := buf.String()
+
+			at := strings.Index(got, tt.want)
+			if at < 0 {
+				t.Fatalf("output missing %q:\n%s", tt.want, got)
+			}
+			if file := strings.Index(got, "main.go"); file >= 0 && file < at {
+				t.Errorf("project header comes after the first file:\n%s", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package catls
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// ContentEncoding selects how JSON and XML output carry file content.
+type ContentEncoding string
+
+const (
+	// ContentEncodingText writes content as lines, split and truncated for reading.
+	ContentEncodingText ContentEncoding = "text"
+	// ContentEncodingBase64 also writes the exact bytes of every file up to
+	// RawContentMax, binary files included, base64-encoded, so the file can be
+	// rebuilt byte for byte.
+	ContentEncodingBase64 ContentEncoding = "base64"
+)
+
+// DefaultRawContentMax is the largest file ContentEncodingBase64 encodes
+// when RawContentMax is not set.
+const DefaultRawContentMax = 1 << 20
+
+// errRawTooLarge is returned when a file is larger than RawContentMax.
+var errRawTooLarge = errors.New("file is larger than the raw content limit")
+
+// IsValid checks if the content encoding is supported. Empty means
+// ContentEncodingText.
+func (e ContentEncoding) IsValid() bool {
+	switch e {
+	case "", ContentEncodingText, ContentEncodingBase64:
+		return true
+	default:
+		return false
+	}
+}
+
+// GetSupportedContentEncodings returns a list of all supported content encodings.
+func GetSupportedContentEncodings() []string {
+	return []string{string(ContentEncodingText), string(ContentEncodingBase64)}
+}
+
+// rawContentMax returns RawContentMax, or its default.
+func (c *Config) rawContentMax() int64 {
+	if c.RawContentMax > 0 {
+		return c.RawContentMax
+	}
+
+	return DefaultRawContentMax
+}
+
+// attachRawContent reads the exact bytes of a file for ContentEncodingBase64.
+// Files larger than RawContentMax, or that cannot be read, keep only their
+// lines or the binary placeholder.
+func (a *App) attachRawContent(file *ProcessedFile) {
+	limit := a.cfg.rawContentMax()
+	if file.Error != nil || file.Info.Size > limit {
+		return
+	}
+
+	data, err := a.processor.readRaw(file.Info.Path, limit)
+	if err != nil {
+		if a.cfg.Debug {
+			fmt.Fprintf(a.warn, "Debug: Not encoding %s: %v\n", file.Info.RelPath, err)
+		}
+
+		return
+	}
+
+	file.Raw = data
+}
+
+// rawBase64 returns the raw content of file base64-encoded, or nil when it
+// has none.
+func rawBase64(file *ProcessedFile) *string {
+	if file.Raw == nil {
+		return nil
+	}
+	encoded := base64.StdEncoding.EncodeToString(file.Raw)
+
+	return &encoded
+}
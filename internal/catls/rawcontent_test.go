@@ -0,0 +1,122 @@
+package catls
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestContentEncodingBase64RoundTrip(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string][]byte{
+		"no-newline.txt": []byte("first\nlast line without a newline"),
+		"crlf.txt":       []byte("dos\r\nline endings\r\n"),
+		"blank.txt":      []byte("  \n\t\n"),
+		"empty.txt":      {},
+		"long.txt":       []byte(strings.Repeat("line\n", 1500)),
+		"tabs.go":        []byte("package main\n\nfunc main() {\n\tprintln(\"\\x1b[31m\")\n}\n"),
+		"image.bin":      {0x89, 'P', 'N', 'G', 0x00, 0x00, 0xFF, '\n', 0x00, 0x01},
+		"huge.txt":       bytes.Repeat([]byte("x"), 10000),
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), content, 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	run := func(format OutputFormat) string {
+		t.Helper()
+		var buf bytes.Buffer
+		app, err := New(&Config{
+			Directory:       tmpDir,
+			OutputFormat:    format,
+			Output:          &buf,
+			ContentEncoding: ContentEncodingBase64,
+			RawContentMax:   8192,
+		})
+		if err != nil {
+			t.Fatalf("New() unexpected error: %v", err)
+		}
+		if err := app.Run(context.Background()); err != nil {
+			t.Fatalf("Run() unexpected error: %v", err)
+		}
+
+		return buf.String()
+	}
+
+	check := func(t *testing.T, encoded map[string]string) {
+		t.Helper()
+		for name, content := range files {
+			got, ok := encoded[name]
+			if len(content) > 8192 {
+				if ok {
+					t.Errorf("%s is over the cap but was encoded", name)
+				}
+
+				continue
+			}
+			if !ok {
+				t.Errorf("%s was not encoded", name)
+
+				continue
+			}
+
+			decoded, err := base64.StdEncoding.DecodeString(got)
+			if err != nil {
+				t.Errorf("%s: invalid base64: %v", name, err)
+			} else if !bytes.Equal(decoded, content) {
+				t.Errorf("%s decodes to %q, want %q", name, decoded, content)
+			}
+		}
+	}
+
+	t.Run("json", func(t *testing.T) {
+		var doc struct {
+			Files []JSONFile `json:"files"`
+		}
+		if err := json.Unmarshal([]byte(run(OutputFormatJSON)), &doc); err != nil {
+			t.Fatalf("output is not JSON: %v", err)
+		}
+
+		encoded := make(map[string]string)
+		for _, file := range doc.Files {
+			if file.ContentB64 != nil {
+				encoded[file.Path] = *file.ContentB64
+			}
+		}
+		check(t, encoded)
+	})
+
+	t.Run("xml", func(t *testing.T) {
+		var doc struct {
+			Files []struct {
+				Path string `xml:"path,attr"`
+				Raw  *struct {
+					Bytes int    `xml:"bytes,attr"`
+					Data  string `xml:",chardata"`
+				} `xml:"content-b64"`
+			} `xml:"file"`
+		}
+		if err := xml.Unmarshal([]byte(run(OutputFormatXML)), &doc); err != nil {
+			t.Fatalf("output is not XML: %v", err)
+		}
+
+		encoded := make(map[string]string)
+		for _, file := range doc.Files {
+			if file.Raw == nil {
+				continue
+			}
+			encoded[file.Path] = file.Raw.Data
+			if want := len(files[file.Path]); file.Raw.Bytes != want {
+				t.Errorf("%s: bytes=%d, want %d", file.Path, file.Raw.Bytes, want)
+			}
+		}
+		check(t, encoded)
+	})
+}
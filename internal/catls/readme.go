@@ -0,0 +1,105 @@
+package catls
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+// readmeExtensions are the extensions of documentation formats a README is
+// written in. README with any other extension, such as readme.go, is code.
+var readmeExtensions = map[string]bool{
+	"":          true,
+	".md":       true,
+	".markdown": true,
+	".mdx":      true,
+	".rst":      true,
+	".txt":      true,
+	".text":     true,
+	".adoc":     true,
+	".asciidoc": true,
+	".org":      true,
+	".textile":  true,
+	".rdoc":     true,
+	".pod":      true,
+	".html":     true,
+}
+
+// isReadme reports whether the file is a directory README such as README.md,
+// readme.txt, or a bare README.
+func isReadme(relPath string) bool {
+	base := strings.ToLower(filepath.Base(relPath))
+	ext := filepath.Ext(base)
+
+	return strings.TrimSuffix(base, ext) == "readme" && readmeExtensions[ext]
+}
+
+// readmeFirst moves each directory's README files ahead of everything else in
+// that directory's subtree, keeping the relative order of all other files.
+// Ancestor READMEs come before nested ones, so a repository reads top-down.
+func readmeFirst(files []scanner.FileInfo) []scanner.FileInfo {
+	readmes := make(map[string][]scanner.FileInfo)
+	for _, file := range files {
+		if !file.IsDir && isReadme(file.RelPath) {
+			dir := filepath.Dir(file.RelPath)
+			readmes[dir] = append(readmes[dir], file)
+		}
+	}
+
+	if len(readmes) == 0 {
+		return files
+	}
+
+	result := make([]scanner.FileInfo, 0, len(files))
+	emitted := make(map[string]bool, len(readmes))
+
+	for _, file := range files {
+		for _, dir := range ancestorDirs(filepath.Dir(file.RelPath)) {
+			if emitted[dir] {
+				continue
+			}
+			if dirReadmes, ok := readmes[dir]; ok {
+				result = append(result, dirReadmes...)
+				emitted[dir] = true
+			}
+		}
+
+		if file.IsDir || !isReadme(file.RelPath) {
+			result = append(result, file)
+		}
+	}
+
+	return result
+}
+
+// ancestorDirs returns dir and its parents ordered from the outermost down.
+func ancestorDirs(dir string) []string {
+	var dirs []string
+	for {
+		dirs = append(dirs, dir)
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	for i, j := 0, len(dirs)-1; i < j; i, j = i+1, j-1 {
+		dirs[i], dirs[j] = dirs[j], dirs[i]
+	}
+
+	return dirs
+}
+
+// limitReadme trims a README to the configured number of lines, marking it as
+// truncated so formatters show how much was left out.
+func limitReadme(file *ProcessedFile, maxLines int) {
+	if maxLines <= 0 || len(file.Lines) <= maxLines {
+		return
+	}
+
+	file.Lines = file.Lines[:maxLines]
+	file.IsTruncated = true
+}
@@ -0,0 +1,26 @@
+package catls
+
+import "testing"
+
+func TestIsReadme(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"README", true},
+		{"README.md", true},
+		{"docs/Readme.rst", true},
+		{"readme.txt", true},
+		{"README.adoc", true},
+		{"readme.go", false},
+		{"pkg/readme.py", false},
+		{"README.md.bak", false},
+		{"READMEFIRST.md", false},
+	}
+
+	for _, tt := range tests {
+		if got := isReadme(tt.path); got != tt.want {
+			t.Errorf("isReadme(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
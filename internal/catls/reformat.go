@@ -0,0 +1,135 @@
+package catls
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPrettyMaxSize is the largest file PrettyJSON and PrettyYAML
+// reformat when PrettyMaxSize is not set. Larger files, typically lockfiles,
+// are shown as they are.
+const DefaultPrettyMaxSize = 512 << 10
+
+// prettyIndent is the indentation of reformatted JSON and YAML.
+const prettyIndent = 2
+
+// reformatter re-indents the content of JSON and YAML files before it is
+// filtered and numbered, set by PrettyJSON and PrettyYAML. A nil reformatter
+// leaves content alone.
+type reformatter struct {
+	json    bool
+	yaml    bool
+	maxSize int64
+}
+
+// newReformatter returns the reformatter selected by cfg, or nil when neither
+// PrettyJSON nor PrettyYAML is set.
+func newReformatter(cfg *Config) *reformatter {
+	if !cfg.PrettyJSON && !cfg.PrettyYAML {
+		return nil
+	}
+
+	return &reformatter{
+		json:    cfg.PrettyJSON,
+		yaml:    cfg.PrettyYAML,
+		maxSize: cfg.prettyMaxSize(),
+	}
+}
+
+// prettyMaxSize returns PrettyMaxSize, or its default.
+func (c *Config) prettyMaxSize() int64 {
+	if c.PrettyMaxSize > 0 {
+		return c.PrettyMaxSize
+	}
+
+	return DefaultPrettyMaxSize
+}
+
+// apply returns the reformatted lines of a JSON or YAML file and sets
+// file.Reformatted. Content that does not parse is returned unchanged with
+// the reason in file.ReformatError. Other types, blank files, and files
+// larger than maxSize are left alone.
+func (r *reformatter) apply(file *ProcessedFile, lines []string) []string {
+	if r == nil || file.Info.Size > r.maxSize || isBlankLines(lines) {
+		return lines
+	}
+
+	var format func(string) (string, error)
+	switch {
+	case file.FileType == "json" && r.json:
+		format = prettyJSON
+	case file.FileType == "yaml" && r.yaml:
+		format = prettyYAML
+	default:
+		return lines
+	}
+
+	// A byte order mark is kept as part of the first line, but no parser wants it
+	content := strings.Join(lines, "\n")
+	pretty, err := format(strings.TrimPrefix(content, "\ufeff"))
+	if err != nil {
+		file.ReformatError = err
+
+		return lines
+	}
+	pretty = strings.TrimSuffix(pretty, "\n")
+	if pretty == content {
+		return lines
+	}
+	file.Reformatted = true
+
+	return strings.Split(pretty, "\n")
+}
+
+// prettyJSON indents a JSON document, keeping its keys in their order.
+func prettyJSON(content string) (string, error) {
+	var b bytes.Buffer
+	if err := json.Indent(&b, []byte(content), "", strings.Repeat(" ", prettyIndent)); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+// prettyYAML re-indents every document of a YAML stream in block style,
+// keeping keys in their order and comments where they were.
+func prettyYAML(content string) (string, error) {
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(prettyIndent)
+
+	dec := yaml.NewDecoder(strings.NewReader(content))
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		blockStyle(&doc)
+		if err := enc.Encode(&doc); err != nil {
+			return "", err
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+// blockStyle turns the flow mappings and sequences under node, such as
+// {a: 1} and [1, 2], into block style.
+func blockStyle(node *yaml.Node) {
+	node.Style &^= yaml.FlowStyle
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
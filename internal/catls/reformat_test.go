@@ -0,0 +1,140 @@
+package catls
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+func TestReformatterApply(t *testing.T) {
+	r := &reformatter{json: true, yaml: true, maxSize: 64}
+
+	tests := []struct {
+		name     string
+		fileType string
+		size     int64
+		lines    []string
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name:     "minified json",
+			fileType: "json",
+			lines:    []string{`{"b":1,"a":[true,null]}`},
+			want:     []string{"{", `  "b": 1,`, `  "a": [`, "    true,", "    null", "  ]", "}"},
+		},
+		{
+			name:     "json with a byte order mark",
+			fileType: "json",
+			lines:    []string{"\ufeff[1]"},
+			want:     []string{"[", "  1", "]"},
+		},
+		{
+			name:     "flow yaml with a comment",
+			fileType: "yaml",
+			lines:    []string{"# servers", "hosts: {web: [a, b], db: c}"},
+			want:     []string{"# servers", "hosts:", "  web:", "    - a", "    - b", "  db: c"},
+		},
+		{
+			name:     "yaml documents",
+			fileType: "yaml",
+			lines:    []string{"a: [1]", "---", "b: 2"},
+			want:     []string{"a:", "  - 1", "---", "b: 2"},
+		},
+		{name: "already indented", fileType: "json", lines: []string{"[", "  1", "]"}, want: []string{"[", "  1", "]"}},
+		{name: "invalid json", fileType: "json", lines: []string{`{"a":`}, want: []string{`{"a":`}, wantErr: true},
+		{name: "invalid yaml", fileType: "yaml", lines: []string{"a: [1"}, want: []string{"a: [1"}, wantErr: true},
+		{name: "over the cap", fileType: "json", size: 65, lines: []string{`{"a":1}`}, want: []string{`{"a":1}`}},
+		{name: "other type", fileType: "go", lines: []string{"package a"}, want: []string{"package a"}},
+		{name: "blank", fileType: "json", lines: []string{""}, want: []string{""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := ProcessedFile{Info: scanner.FileInfo{Size: tt.size}, FileType: tt.fileType}
+			got := r.apply(&file, tt.lines)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("apply() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+			if (file.ReformatError != nil) != tt.wantErr {
+				t.Errorf("apply() ReformatError = %v, wantErr %v", file.ReformatError, tt.wantErr)
+			}
+			if wantReformatted := !tt.wantErr && !slices.Equal(got, tt.lines); file.Reformatted != wantReformatted {
+				t.Errorf("apply() Reformatted = %v, want %v", file.Reformatted, wantReformatted)
+			}
+		})
+	}
+
+	jsonOnly := &reformatter{json: true, maxSize: 64}
+	file := ProcessedFile{FileType: "yaml"}
+	if got := jsonOnly.apply(&file, []string{"a: {b: 1}"}); len(got) != 1 || file.Reformatted {
+		t.Errorf("apply() without yaml reformatted a YAML file: %q", got)
+	}
+}
+
+func TestPrettyJSONRun(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"config.json": `{"name":"catls","tags":["a","b"]}`,
+		"broken.json": `{"name":`,
+		"plain.txt":   `{"name":"catls"}`,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	app, err := New(&Config{
+		Directory:       dir,
+		OutputFormat:    OutputFormatXML,
+		Output:          &buf,
+		PrettyJSON:      true,
+		ShowLineNumbers: true,
+	})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		`<file path="config.json" reformatted="true">`,
+		`   3|   &#34;tags&#34;: [`,
+		`<file path="broken.json" reformat-error="unexpected end of JSON input">`,
+		`<file path="plain.txt">`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if stats := app.Stats(); stats.Reformatted != 1 || stats.ReformatFailed != 1 {
+		t.Errorf("Stats() = %d reformatted, %d failed, want 1 and 1", stats.Reformatted, stats.ReformatFailed)
+	}
+
+	for format, want := range map[OutputFormat]string{
+		OutputFormatJSON:     `"reformatError": "unexpected end of JSON input"`,
+		OutputFormatMarkdown: "*Not reformatted: unexpected end of JSON input*",
+		OutputFormatPretty:   "not reformatted: unexpected end of JSON input",
+	} {
+		buf.Reset()
+		app, err := New(&Config{Directory: dir, OutputFormat: format, Output: &buf, PrettyJSON: true})
+		if err != nil {
+			t.Fatalf("New() unexpected error: %v", err)
+		}
+		if err := app.Run(context.Background()); err != nil {
+			t.Fatalf("Run() unexpected error: %v", err)
+		}
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("%s output missing %q:\n%s", format, want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package catls
+
+// runState is what one Run or Files iteration accumulates. A run starts
+// with fresh state and releases it when it ends, keeping only the counters
+// Stats reports, so an App run repeatedly holds nothing of earlier runs.
+type runState struct {
+	stats    RunStats
+	tokens   int             // Estimated tokens written so far
+	contents *contentIndex   // Files written so far, by content (nil unless DedupeContent)
+	omitted  []OmittedFile   // Files left out of the output so far
+	manifest []ManifestFile  // Files written so far (nil unless ManifestPath is set)
+	progress fileProgress    // File being processed, for Events
+	excluded []excludedFile  // Files the filter left out, offered by the selector (nil unless Interactive)
+	blame    *blameCutoff    // Resolved BlameSince (nil unless set)
+	written  integrityCounts // Files and lines written so far, for Integrity trailers
+}
+
+// beginRun discards whatever an earlier run left behind.
+func (a *App) beginRun() {
+	a.runState = runState{stats: RunStats{Budgets: newBudgetUsage(a.cfg.SizeBudgets)}}
+}
+
+// endRun releases the state of the run that just ended, keeping its counters.
+func (a *App) endRun() {
+	a.runState = runState{stats: a.stats}
+}
@@ -0,0 +1,89 @@
+package catls
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"testing"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+// TestRepeatedRunsStayFlat runs the pipeline many times in one process, as
+// catls serve does, and checks that neither goroutines nor the heap grow
+// with the number of runs.
+func TestRepeatedRunsStayFlat(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs the pipeline 1000 times")
+	}
+
+	tmpDir := t.TempDir()
+	tree := make(map[string]string)
+	for i := range 8 {
+		tree[fmt.Sprintf("pkg%d/file%d.go", i%4, i)] = fmt.Sprintf("package pkg\n\n// TODO: item %d\nfunc F%d() {}\n", i, i)
+	}
+	tree["README.md"] = "# fixture\n"
+	writeTree(t, tmpDir, tree)
+
+	cache := scanner.NewMemoryDetectionCache()
+	reused, err := New(&Config{Directory: tmpDir, Recursive: true, OutputFormat: OutputFormatJSON, Output: io.Discard})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	run := func(i int) {
+		t.Helper()
+		app, err := New(&Config{
+			Directory:     tmpDir,
+			Recursive:     true,
+			OutputFormat:  OutputFormatJSON,
+			Output:        io.Discard,
+			DedupeContent: true,
+			Todos:         i%2 == 0,
+			DetectCache:   cache,
+		})
+		if err != nil {
+			t.Fatalf("New() unexpected error: %v", err)
+		}
+		if err := app.Run(context.Background()); err != nil {
+			t.Fatalf("Run() unexpected error: %v", err)
+		}
+
+		// An App iterated again starts from scratch
+		count := 0
+		for _, err := range reused.Files(context.Background()) {
+			if err != nil {
+				t.Fatalf("Files() unexpected error: %v", err)
+			}
+			count++
+		}
+		if stats := reused.Stats(); stats.Files != count || count != len(tree) {
+			t.Fatalf("run %d: Stats().Files = %d for %d files, want %d", i, stats.Files, count, len(tree))
+		}
+	}
+	measure := func() (int, uint64) {
+		runtime.GC()
+		var mem runtime.MemStats
+		runtime.ReadMemStats(&mem)
+
+		return runtime.NumGoroutine(), mem.HeapAlloc
+	}
+
+	// Warm up lazily initialized packages before taking the baseline
+	for i := range 50 {
+		run(i)
+	}
+	goroutines, heap := measure()
+	for i := range 1000 {
+		run(i)
+	}
+	gotGoroutines, gotHeap := measure()
+
+	if gotGoroutines > goroutines {
+		t.Errorf("goroutines grew from %d to %d over 1000 runs", goroutines, gotGoroutines)
+	}
+	const slack = 1 << 20
+	if gotHeap > heap+slack {
+		t.Errorf("heap grew from %d to %d bytes over 1000 runs", heap, gotHeap)
+	}
+}
@@ -0,0 +1,207 @@
+package catls
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+// ScanArtifactVersion is the scan artifact layout written by this release.
+const ScanArtifactVersion = 1
+
+// ScanArtifact is the file list of a scan, written by 'catls scan' so the
+// files can be filtered, processed, and formatted again without rescanning.
+// See Config.ScanArtifact.
+type ScanArtifact struct {
+	Version   int                `json:"version"`
+	Root      string             `json:"root"` // Absolute path of the scanned directory
+	ScannedAt time.Time          `json:"scannedAt"`
+	Config    ManifestConfig     `json:"config"` // Selection settings of the scan
+	Files     []ScanArtifactFile `json:"files"`
+}
+
+// ScanArtifactFile is the scanner.FileInfo of a file in a ScanArtifact.
+type ScanArtifactFile struct {
+	Path       string    `json:"path"` // Relative to Root, with forward slashes
+	IsDir      bool      `json:"isDir,omitempty"`
+	IsBinary   bool      `json:"isBinary,omitempty"`
+	Executable bool      `json:"executable,omitempty"`
+	Size       int64     `json:"size"`
+	ModTime    time.Time `json:"modTime"`
+	FileType   string    `json:"fileType,omitempty"`
+	HasType    bool      `json:"hasType,omitempty"`
+}
+
+// Scan scans and filters like Run and returns the files found as a scan
+// artifact, without reading their content beyond binary and type detection.
+func (a *App) Scan(ctx context.Context) (ScanArtifact, error) {
+	if a.cfg.ScanArtifact != nil {
+		return ScanArtifact{}, errors.New("cannot scan a scan artifact")
+	}
+	if a.cfg.RelativeTo != "" {
+		return ScanArtifact{}, errors.New("--relative-to does not apply to a scan artifact, whose paths are relative to its root")
+	}
+
+	root, err := filepath.Abs(a.cfg.Directory)
+	if err != nil {
+		return ScanArtifact{}, err
+	}
+	files, _, err := a.scanFiles(ctx, false)
+	if err != nil {
+		return ScanArtifact{}, err
+	}
+
+	artifact := ScanArtifact{
+		Version:   ScanArtifactVersion,
+		Root:      root,
+		ScannedAt: time.Now().UTC(),
+		Config:    NewManifestConfig(a.cfg),
+		Files:     make([]ScanArtifactFile, 0, len(files)),
+	}
+	for _, file := range files {
+		relPath, err := filepath.Rel(a.cfg.Directory, file.Path)
+		if err != nil {
+			return ScanArtifact{}, err
+		}
+		artifact.Files = append(artifact.Files, ScanArtifactFile{
+			Path:       filepath.ToSlash(relPath),
+			IsDir:      file.IsDir,
+			IsBinary:   file.IsBinary,
+			Executable: file.Executable,
+			Size:       file.Size,
+			ModTime:    file.ModTime.UTC(),
+			FileType:   file.FileType,
+			HasType:    file.HasType,
+		})
+	}
+
+	return artifact, nil
+}
+
+// WriteScanArtifact writes artifact to w as indented JSON.
+func WriteScanArtifact(w io.Writer, artifact ScanArtifact) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(artifact)
+}
+
+// ReadScanArtifact loads the scan artifact at path.
+func ReadScanArtifact(path string) (*ScanArtifact, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read scan artifact: %w", err)
+	}
+
+	var artifact ScanArtifact
+	if err := json.Unmarshal(data, &artifact); err != nil {
+		return nil, fmt.Errorf("invalid scan artifact %s: %w", path, err)
+	}
+	if artifact.Version != ScanArtifactVersion {
+		return nil, fmt.Errorf("scan artifact %s has version %d, expected %d", path, artifact.Version, ScanArtifactVersion)
+	}
+	if !filepath.IsAbs(artifact.Root) {
+		return nil, fmt.Errorf("scan artifact %s has no absolute root", path)
+	}
+
+	return &artifact, nil
+}
+
+// artifactFiles returns the files of ScanArtifact that pass the file filter,
+// in place of a scan. Each file is checked against the filesystem first:
+// files that no longer exist are kept so they are reported as unreadable,
+// and files whose size or modification time changed take the current ones
+// and have their binary flag detected again. found counts every file of
+// the artifact.
+func (a *App) artifactFiles(ctx context.Context) ([]scanner.FileInfo, int, error) {
+	artifact := a.cfg.ScanArtifact
+	detector := &scanner.FileBinaryDetector{}
+	rules := a.filter.fileRules(a.cfg)
+
+	var files []scanner.FileInfo
+	missing, changed := 0, 0
+	for _, entry := range artifact.Files {
+		if err := ctx.Err(); err != nil {
+			return nil, 0, err
+		}
+
+		file := scanner.FileInfo{
+			Path:       filepath.Join(artifact.Root, filepath.FromSlash(entry.Path)),
+			RelPath:    filepath.FromSlash(entry.Path),
+			IsBinary:   entry.IsBinary,
+			IsDir:      entry.IsDir,
+			Executable: entry.Executable,
+			Size:       entry.Size,
+			ModTime:    entry.ModTime,
+			FileType:   entry.FileType,
+			HasType:    entry.HasType,
+		}
+
+		info, err := os.Stat(file.Path)
+		switch {
+		case err != nil:
+			// Reading the file reports why it is gone
+			missing++
+			file.IsBinary = false
+		case !file.IsDir && (info.Size() != file.Size || !info.ModTime().Equal(file.ModTime)):
+			changed++
+			file.Size, file.ModTime = info.Size(), info.ModTime()
+			file.IsBinary = file.Size > 0 && detector.IsBinary(file.Path)
+		}
+		if !file.IsDir && !file.HasType {
+			file.FileType, file.HasType = a.processor.detectType(file), true
+		}
+
+		if verdict, excluded := a.filter.exclusion(file, a.cfg, rules); excluded {
+			a.recordExcluded(file, verdict, true)
+
+			continue
+		}
+		a.fileDiscovered(file)
+		files = append(files, file)
+	}
+
+	if missing > 0 {
+		fmt.Fprintf(a.warn, "Warning: %d files in the scan artifact no longer exist\n", missing)
+	}
+	if changed > 0 {
+		fmt.Fprintf(a.warn, "Warning: %d files changed since the scan at %s\n", changed, artifact.ScannedAt.Format(time.RFC3339))
+	}
+
+	files, err := a.applyIgnoreCmd(ctx, files)
+	if err != nil {
+		return nil, 0, err
+	}
+	if a.cfg.Deterministic {
+		files = deterministicFiles(files, a.cfg.comparePaths)
+	}
+
+	return files, len(artifact.Files), nil
+}
+
+// validateScanArtifact requires Directory to be the root of ScanArtifact
+// and rejects the settings that only apply to scanning.
+func (c *Config) validateScanArtifact() error {
+	if c.ScanArtifact == nil {
+		return nil
+	}
+
+	if dir, err := filepath.Abs(c.Directory); err != nil || dir != c.ScanArtifact.Root {
+		return fmt.Errorf("directory %s is not the root of the scan artifact, %s", c.Directory, c.ScanArtifact.Root)
+	}
+	if len(c.Paths) > 0 || len(c.Files) > 0 {
+		return errors.New("a scan artifact cannot be combined with file arguments")
+	}
+	if c.RelativeTo != "" {
+		return errors.New("--relative-to does not apply to a scan artifact, whose paths are relative to its root")
+	}
+
+	return nil
+}
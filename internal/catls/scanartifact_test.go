@@ -0,0 +1,134 @@
+package catls
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScanArtifact(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"a.go":       "package a\n",
+		"sub/b.md":   "# B\n",
+		"gone.txt":   "soon removed\n",
+		"grows.txt":  "short\n",
+		".hidden.go": "package hidden\n",
+	})
+
+	app, err := New(&Config{Directory: tmpDir, Recursive: true, OutputFormat: OutputFormatJSON})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	artifact, err := app.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan() unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteScanArtifact(&buf, artifact); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "scan.json")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadScanArtifact(path)
+	if err != nil {
+		t.Fatalf("ReadScanArtifact() unexpected error: %v", err)
+	}
+	var paths []string
+	for _, file := range read.Files {
+		paths = append(paths, file.Path)
+		if !file.HasType {
+			t.Errorf("%s: type not recorded", file.Path)
+		}
+	}
+	if got := strings.Join(paths, " "); got != "a.go gone.txt grows.txt sub/b.md" {
+		t.Errorf("artifact files = %s", got)
+	}
+
+	if err := os.Remove(filepath.Join(tmpDir, "gone.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "grows.txt"), []byte("longer now\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	render := func(globs ...string) map[string]map[string]any {
+		t.Helper()
+		var out bytes.Buffer
+		app, err := New(&Config{Directory: read.Root, ScanArtifact: read, Globs: globs, OutputFormat: OutputFormatJSON, Output: &out})
+		if err != nil {
+			t.Fatalf("New() unexpected error: %v", err)
+		}
+		if err := app.Run(context.Background()); err != nil {
+			t.Fatalf("Run() unexpected error: %v", err)
+		}
+
+		var doc struct {
+			Files []map[string]any `json:"files"`
+		}
+		if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+			t.Fatalf("output is not JSON: %v", err)
+		}
+		files := make(map[string]map[string]any)
+		for _, file := range doc.Files {
+			files[file["path"].(string)] = file
+		}
+
+		return files
+	}
+
+	files := render()
+	if len(files) != 4 {
+		t.Errorf("rendered %d files, want the 4 scanned", len(files))
+	}
+	if files["gone.txt"]["error"] == nil {
+		t.Errorf("gone.txt = %v, want an error entry", files["gone.txt"])
+	}
+	if lines := files["grows.txt"]["lines"].([]any); lines[0].(map[string]any)["content"] != "longer now" {
+		t.Errorf("grows.txt lines = %v, want the current content", lines)
+	}
+
+	// Filters narrow the scanned files again
+	if files := render("*.md"); len(files) != 1 || files["sub/b.md"] == nil {
+		t.Errorf("rendered %v, want only sub/b.md", files)
+	}
+}
+
+func TestValidateScanArtifact(t *testing.T) {
+	root := t.TempDir()
+	artifact := &ScanArtifact{Version: ScanArtifactVersion, Root: root}
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{name: "root", cfg: Config{Directory: root, ScanArtifact: artifact}},
+		{name: "other directory", cfg: Config{Directory: t.TempDir(), ScanArtifact: artifact}, wantErr: "not the root of the scan artifact"},
+		{name: "named files", cfg: Config{Directory: root, ScanArtifact: artifact, Paths: []string{"a.go"}}, wantErr: "file arguments"},
+		{name: "relative to", cfg: Config{Directory: root, ScanArtifact: artifact, RelativeTo: "."}, wantErr: "--relative-to"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.validateScanArtifact()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateScanArtifact() unexpected error: %v", err)
+				}
+
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateScanArtifact() error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package catls
+
+import (
+	"embed"
+	"fmt"
+	"strings"
+)
+
+// SchemaVersion is the version of the JSON and chunks output layouts. Every
+// JSON document records it as "schemaVersion" and every chunks record as
+// "schema_version". It is bumped whenever a field is added, removed, or
+// changes meaning, together with the const in the embedded schemas. The
+// manifest has its own version, ManifestVersion.
+const SchemaVersion = 3
+
+// SchemaManifest names the manifest's schema for Schema.
+const SchemaManifest = "manifest"
+
+// schemas holds the JSON Schema of each machine-readable output.
+//
+//go:embed schemas/*.schema.json
+var schemas embed.FS
+
+// Schema returns the JSON Schema describing the output of name: "json",
+// "chunks", or SchemaManifest. A chunks schema describes one line of the
+// output.
+func Schema(name string) ([]byte, error) {
+	data, err := schemas.ReadFile("schemas/" + name + ".schema.json")
+	if err != nil {
+		return nil, fmt.Errorf("no schema for %s output; schemas exist for %s", name, strings.Join(GetSchemaNames(), ", "))
+	}
+
+	return data, nil
+}
+
+// GetSchemaNames returns the outputs Schema describes.
+func GetSchemaNames() []string {
+	return []string{string(OutputFormatJSON), string(OutputFormatChunks), SchemaManifest}
+}
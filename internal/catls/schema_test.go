@@ -0,0 +1,282 @@
+package catls
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/connerohnesorge/catls/internal/testutil"
+)
+
+// schemaSums records the SHA-256 of each released schema version. A schema
+// whose content changes must also change version, so downstream tools can
+// rely on a version meaning one layout.
+const schemaSums = "testdata/schemas.sum"
+
+// loadSchema parses the embedded schema of name.
+func loadSchema(t *testing.T, name string) *testutil.Schema {
+	t.Helper()
+
+	data, err := Schema(name)
+	if err != nil {
+		t.Fatalf("Schema(%q) unexpected error: %v", name, err)
+	}
+	schema, err := testutil.ParseSchema(data)
+	if err != nil {
+		t.Fatalf("schema %s: %v", name, err)
+	}
+
+	return schema
+}
+
+// TestSchemaValidatesOutput runs the fixture tree through settings that
+// between them write every optional field, and validates each document
+// against the embedded schema.
+func TestSchemaValidatesOutput(t *testing.T) {
+	root := testutil.BuildFixtureTree(t)
+	testutil.WriteFile(t, root, "web/package-lock.json", `{"lockfileVersion": 3, "packages": {"": {"dependencies": {"a": "1"}}, "node_modules/a": {}}}`+"\n")
+
+	configs := map[string]Config{
+		"default": {},
+		"everything": {
+			Recursive:          true,
+			IncludeDirs:        true,
+			ShowLineNumbers:    true,
+			ConfigEcho:         true,
+			ProjectHeader:      true,
+			Todos:              true,
+			MaxFilesPerDir:     1,
+			SummarizeLockfiles: true,
+			ReadmeFirst:        true,
+			ContentEncoding:    ContentEncodingBase64,
+			Integrity:          true,
+		},
+		"patterns": {Recursive: true, ContentPatterns: []string{"*note*"}, MaxMatches: 1},
+	}
+
+	for _, format := range []OutputFormat{OutputFormatJSON, OutputFormatChunks} {
+		schema := loadSchema(t, string(format))
+		for name, cfg := range configs {
+			if format == OutputFormatChunks {
+				// JSON only
+				cfg.Integrity, cfg.ContentEncoding = false, ""
+			}
+			t.Run(string(format)+"/"+name, func(t *testing.T) {
+				var buf bytes.Buffer
+				cfg.Directory = root
+				cfg.OutputFormat = format
+				cfg.Output = &buf
+				cfg.ManifestPath = filepath.Join(t.TempDir(), "manifest.json")
+				runApp(t, &cfg)
+
+				documents := []string{buf.String()}
+				if format == OutputFormatChunks {
+					documents = strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+				}
+				for i, document := range documents {
+					for _, violation := range schema.ValidateJSON([]byte(document)) {
+						t.Errorf("document %d: %s", i, violation)
+					}
+				}
+
+				manifest, err := os.ReadFile(cfg.ManifestPath)
+				if err != nil {
+					t.Fatalf("failed to read manifest: %v", err)
+				}
+				for _, violation := range loadSchema(t, SchemaManifest).ValidateJSON(manifest) {
+					t.Errorf("manifest: %s", violation)
+				}
+			})
+		}
+	}
+}
+
+// runApp runs catls with cfg and fails the test on error.
+func runApp(t *testing.T, cfg *Config) {
+	t.Helper()
+
+	app, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+}
+
+// TestSchemaCoversTypes checks that the schemas list exactly the fields of
+// the types each output encodes, so a field cannot be added to one without
+// the other, even when no fixture writes it.
+func TestSchemaCoversTypes(t *testing.T) {
+	tests := []struct {
+		schema string
+		def    string // Definition in $defs, or "" for the root
+		value  any
+	}{
+		{schema: "json", def: "configEcho", value: ConfigEcho{}},
+		{schema: "json", def: "projectHeader", value: ProjectHeader{}},
+		{schema: "json", def: "file", value: JSONFile{}},
+		{schema: "json", def: "todoKeyword", value: TodoKeywordRefs{}},
+		{schema: "json", def: "omittedDir", value: JSONOmittedDir{}},
+		{schema: "json", def: "integrity", value: IntegrityTrailer{}},
+		{schema: "chunks", def: "chunk", value: ChunkRecord{}},
+		{schema: "chunks", def: "status", value: ChunkStatusRecord{}},
+		{schema: SchemaManifest, value: Manifest{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.schema+"/"+tt.def, func(t *testing.T) {
+			schema := loadSchema(t, tt.schema)
+			node := schema.Root()
+			if tt.def != "" {
+				defs, _ := node["$defs"].(map[string]any)
+				node, _ = defs[tt.def].(map[string]any)
+			}
+			for _, problem := range compareSchemaType(schema, node, reflect.TypeOf(tt.value), tt.def) {
+				t.Error(problem)
+			}
+		})
+	}
+}
+
+// compareSchemaType lists where the properties of node differ from the JSON
+// fields of typ, recursing into nested structs, slices, and pointers.
+func compareSchemaType(schema *testutil.Schema, node map[string]any, typ reflect.Type, path string) []string {
+	node = schema.Resolve(node)
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	switch {
+	case node == nil:
+		return []string{path + ": no schema"}
+	case typ.Kind() == reflect.Slice:
+		items, _ := node["items"].(map[string]any)
+
+		return compareSchemaType(schema, items, typ.Elem(), path+"[]")
+	case typ.Kind() != reflect.Struct || typ == reflect.TypeOf(time.Time{}):
+		return nil
+	}
+
+	properties, _ := node["properties"].(map[string]any)
+	var problems []string
+	fields := make(map[string]bool, typ.NumField())
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		fields[name] = true
+		property, ok := properties[name].(map[string]any)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: field %q of %s is not in the schema", path, name, typ.Name()))
+
+			continue
+		}
+		problems = append(problems, compareSchemaType(schema, property, field.Type, path+"."+name)...)
+	}
+	for name := range properties {
+		if !fields[name] {
+			problems = append(problems, fmt.Sprintf("%s: schema property %q is not a field of %s", path, name, typ.Name()))
+		}
+	}
+	slices.Sort(problems)
+
+	return problems
+}
+
+// TestSchemaVersions requires a new version for every change to a schema,
+// and that each schema pins the version its output records. New versions
+// are added to testdata/schemas.sum with UPDATE_GOLDEN=1; a recorded
+// version is never rewritten.
+func TestSchemaVersions(t *testing.T) {
+	versions := map[string]int{
+		string(OutputFormatJSON):   SchemaVersion,
+		string(OutputFormatChunks): SchemaVersion,
+		SchemaManifest:             ManifestVersion,
+	}
+	pinned := map[string][]string{
+		string(OutputFormatJSON):   {"properties", "schemaVersion"},
+		string(OutputFormatChunks): {"$defs", "schemaVersion"},
+		SchemaManifest:             {"properties", "version"},
+	}
+
+	recorded := readSchemaSums(t)
+	var added []string
+	for _, name := range GetSchemaNames() {
+		version := versions[name]
+		node := loadSchema(t, name).Root()
+		for _, key := range pinned[name] {
+			node, _ = node[key].(map[string]any)
+		}
+		if got := fmt.Sprint(node["const"]); got != strconv.Itoa(version) {
+			t.Errorf("%s schema pins version %s, but the output records %d", name, got, version)
+		}
+
+		data, _ := Schema(name)
+		sum := sha256.Sum256(data)
+		key := fmt.Sprintf("%s %d", name, version)
+		switch want, ok := recorded[key]; {
+		case !ok && os.Getenv(testutil.UpdateGoldenEnv) != "":
+			added = append(added, key+" "+hex.EncodeToString(sum[:]))
+		case !ok:
+			t.Errorf("%s version %d is not recorded in %s (run with %s=1 to add it)", name, version, schemaSums, testutil.UpdateGoldenEnv)
+		case want != hex.EncodeToString(sum[:]):
+			t.Errorf("%s schema changed since version %d was released; bump its version", name, version)
+		}
+	}
+
+	if len(added) > 0 {
+		f, err := os.OpenFile(schemaSums, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", schemaSums, err)
+		}
+		defer f.Close()
+		for _, line := range added {
+			fmt.Fprintln(f, line)
+		}
+	}
+}
+
+// readSchemaSums reads testdata/schemas.sum as "name version" -> hex SHA-256.
+func readSchemaSums(t *testing.T) map[string]string {
+	t.Helper()
+
+	sums := make(map[string]string)
+	f, err := os.Open(schemaSums)
+	if os.IsNotExist(err) {
+		return sums
+	}
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", schemaSums, err)
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 3 {
+			sums[fields[0]+" "+fields[1]] = fields[2]
+		}
+	}
+
+	return sums
+}
+
+func TestSchemaUnknownOutput(t *testing.T) {
+	if _, err := Schema("xml"); err == nil || !strings.Contains(err.Error(), "json, chunks, manifest") {
+		t.Errorf("Schema(xml) error = %v", err)
+	}
+}
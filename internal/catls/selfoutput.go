@@ -0,0 +1,80 @@
+package catls
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// selfOutputPaths returns the outputs of the run that lie inside the scanned
+// directory, joined onto Directory as the scan reaches them: OutputDir, or
+// its files when it is the directory itself, and ManifestPath. Left in the
+// scan, each run would include the output of the one before. Scans of
+// explicit Paths take what they are given.
+func (a *App) selfOutputPaths() []string {
+	if len(a.cfg.Paths) > 0 {
+		return nil
+	}
+
+	scanDir := resolveScanDir(a.cfg.Directory)
+	var outputs []string
+	if a.cfg.OutputDir != "" {
+		switch rel, ok := relInside(scanDir, a.cfg.OutputDir); {
+		case ok && rel == ".":
+			for _, format := range a.cfg.formats() {
+				outputs = append(outputs, OutputFileName(format))
+			}
+		case ok:
+			outputs = append(outputs, rel)
+		}
+	}
+	if a.cfg.ManifestPath != "" {
+		if rel, ok := relInside(scanDir, a.cfg.ManifestPath); ok && rel != "." {
+			outputs = append(outputs, rel)
+		}
+	}
+
+	paths := make([]string, len(outputs))
+	for i, rel := range outputs {
+		paths[i] = filepath.Join(a.cfg.Directory, rel)
+		if a.cfg.Debug {
+			fmt.Fprintf(a.warn, "Debug: Ignoring output inside the scanned directory: %s\n", paths[i])
+		}
+	}
+
+	return paths
+}
+
+// relInside returns path relative to dir, an absolute path with symlinks
+// resolved, if path lies inside it.
+func relInside(dir, path string) (string, bool) {
+	rel, err := filepath.Rel(dir, resolveOutputPath(path))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+
+	return rel, true
+}
+
+// resolveOutputPath is resolveScanDir for an output that may not exist yet,
+// resolving its parent directory instead.
+func resolveOutputPath(path string) string {
+	if _, err := os.Lstat(path); err == nil {
+		return resolveScanDir(path)
+	}
+
+	return filepath.Join(resolveScanDir(filepath.Dir(path)), filepath.Base(path))
+}
+
+// isSelfOutput reports whether path is one of outputs or lies below one.
+func isSelfOutput(path string, outputs []string) bool {
+	path = filepath.Clean(path)
+	for _, output := range outputs {
+		if path == output || strings.HasPrefix(path, output+string(filepath.Separator)) {
+			return true
+		}
+	}
+
+	return false
+}
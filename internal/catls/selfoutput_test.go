@@ -0,0 +1,72 @@
+package catls
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunSkipsItsOwnOutput(t *testing.T) {
+	tests := []struct {
+		name string
+		// outputs returns the scan directory and OutputDir and ManifestPath
+		// for a tree at dir
+		outputs func(t *testing.T, dir string) (scanDir, outputDir, manifest string)
+	}{
+		{"absolute", func(_ *testing.T, dir string) (string, string, string) {
+			return dir, filepath.Join(dir, "out"), filepath.Join(dir, "manifest.json")
+		}},
+		{"relative", func(t *testing.T, dir string) (string, string, string) {
+			t.Chdir(dir)
+
+			return ".", "out", "manifest.json"
+		}},
+		{"scan directory itself", func(_ *testing.T, dir string) (string, string, string) {
+			return dir, dir, filepath.Join(dir, "manifest.json")
+		}},
+		{"through a symlink", func(t *testing.T, dir string) (string, string, string) {
+			link := filepath.Join(t.TempDir(), "link")
+			if err := os.Symlink(dir, link); err != nil {
+				t.Skipf("symlinks not supported: %v", err)
+			}
+
+			return dir, filepath.Join(link, "out"), filepath.Join(link, "manifest.json")
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTree(t, dir, map[string]string{"main.go": "package main"})
+			scanDir, outputDir, manifest := tt.outputs(t, dir)
+
+			for range 2 {
+				app, err := New(&Config{
+					Directory:    scanDir,
+					Recursive:    true,
+					OutputFormat: OutputFormatXML,
+					OutputDir:    outputDir,
+					ManifestPath: manifest,
+					Output:       io.Discard,
+				})
+				if err != nil {
+					t.Fatalf("New() unexpected error: %v", err)
+				}
+				if err := app.Run(context.Background()); err != nil {
+					t.Fatalf("Run() unexpected error: %v", err)
+				}
+			}
+
+			output, err := os.ReadFile(filepath.Join(outputDir, OutputFileName(OutputFormatXML)))
+			if err != nil {
+				t.Fatalf("failed to read output: %v", err)
+			}
+			if !strings.Contains(string(output), "main.go") || strings.Contains(string(output), "out-xml.xml") || strings.Contains(string(output), "manifest.json") {
+				t.Errorf("second run output includes the first run's output:\n%s", output)
+			}
+		})
+	}
+}
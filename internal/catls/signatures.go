@@ -0,0 +1,291 @@
+package catls
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// SignaturesFallback selects what Signatures writes for files of a language
+// it cannot reduce.
+type SignaturesFallback string
+
+const (
+	// SignaturesFallbackFull writes such files whole.
+	SignaturesFallbackFull SignaturesFallback = "full"
+	// SignaturesFallbackHead writes their first SignaturesHeadLines lines.
+	SignaturesFallbackHead SignaturesFallback = "head"
+)
+
+// SignaturesHeadLines is how many lines SignaturesFallbackHead keeps.
+const SignaturesHeadLines = 20
+
+// Reductions recorded in ProcessedFile.Reduced.
+const (
+	// ReducedSignatures marks content reduced to declarations and doc comments.
+	ReducedSignatures = "signatures"
+	// ReducedHead marks content cut to its first lines by SignaturesFallbackHead.
+	ReducedHead = "head"
+)
+
+// elidedBody replaces the body of a function or block Signatures leaves out.
+const elidedBody = "{ … }"
+
+// GetSupportedSignaturesFallbacks returns the values SignaturesFallback accepts.
+func GetSupportedSignaturesFallbacks() []string {
+	return []string{string(SignaturesFallbackFull), string(SignaturesFallbackHead)}
+}
+
+// IsValid reports whether f is a supported fallback; empty means
+// SignaturesFallbackFull.
+func (f SignaturesFallback) IsValid() bool {
+	switch f {
+	case "", SignaturesFallbackFull, SignaturesFallbackHead:
+		return true
+	default:
+		return false
+	}
+}
+
+// signatureExtractors reduce the lines of a file of each supported type to
+// its signatures. They report false when the content cannot be reduced.
+var signatureExtractors = map[string]func(lines []string) ([]FilteredLine, bool){
+	"go":         goSignatures,
+	"python":     pythonSignatures,
+	"javascript": scriptSignatures,
+	"typescript": scriptSignatures,
+}
+
+// signatureReducer replaces the content of source files with their
+// signatures before it is filtered, set by Signatures. A nil
+// signatureReducer leaves content alone.
+type signatureReducer struct {
+	fallback SignaturesFallback
+}
+
+// newSignatureReducer returns the reducer selected by cfg, or nil when
+// Signatures is not set.
+func newSignatureReducer(cfg *Config) *signatureReducer {
+	if !cfg.Signatures {
+		return nil
+	}
+
+	return &signatureReducer{fallback: cfg.SignaturesFallback}
+}
+
+// apply returns the reduced lines of file, numbered as in the file after
+// the skipped lines of front matter, and sets file.Reduced. It reports
+// false when the content stays whole: for blank files, and for unsupported
+// types unless the fallback is SignaturesFallbackHead.
+func (r *signatureReducer) apply(file *ProcessedFile, lines []string, skipped int) ([]FilteredLine, bool) {
+	if r == nil || isBlankLines(lines) {
+		return nil, false
+	}
+
+	var reduced []FilteredLine
+	extract, ok := signatureExtractors[file.FileType]
+	if ok {
+		reduced, ok = extract(lines)
+	}
+	switch {
+	case ok:
+		file.Reduced = ReducedSignatures
+	case r.fallback == SignaturesFallbackHead:
+		head := lines[:min(len(lines), SignaturesHeadLines)]
+		reduced = make([]FilteredLine, len(head))
+		for i, line := range head {
+			reduced[i] = FilteredLine{LineNumber: i + 1, Content: line}
+		}
+		file.Reduced = ReducedHead
+		file.IsTruncated = len(lines) > len(head)
+	default:
+		return nil, false
+	}
+
+	for i := range reduced {
+		reduced[i].LineNumber += skipped
+	}
+
+	return reduced, true
+}
+
+// signatureLines collects the lines of a file an extractor keeps, by index,
+// with the content of some of them shortened.
+type signatureLines struct {
+	lines []string
+	kept  map[int]string
+}
+
+func newSignatureLines(lines []string) *signatureLines {
+	return &signatureLines{lines: lines, kept: make(map[int]string)}
+}
+
+// keep keeps the lines from index from through to, leaving any already
+// shortened as they are.
+func (s *signatureLines) keep(from, to int) {
+	for i := max(from, 0); i <= to && i < len(s.lines); i++ {
+		if _, ok := s.kept[i]; !ok {
+			s.kept[i] = s.lines[i]
+		}
+	}
+}
+
+// replace keeps line i with content in place of its text.
+func (s *signatureLines) replace(i int, content string) {
+	s.kept[i] = content
+}
+
+// result returns the kept lines in order, numbered from 1. A blank line
+// that separated a kept block from the one before is kept too, so
+// declarations stay apart.
+func (s *signatureLines) result() []FilteredLine {
+	indices := slices.Sorted(maps.Keys(s.kept))
+	result := make([]FilteredLine, 0, len(indices))
+	for k, i := range indices {
+		if k > 0 && indices[k-1] < i-1 && strings.TrimSpace(s.lines[i-1]) == "" {
+			result = append(result, FilteredLine{LineNumber: i})
+		}
+		result = append(result, FilteredLine{LineNumber: i + 1, Content: s.kept[i]})
+	}
+
+	return result
+}
+
+// goSignatures keeps the package clause, imports, type and constant
+// declarations, the first line of each variable, and function signatures
+// with their bodies elided, each with its doc comment, plus build
+// constraints. It reports false for content go/parser rejects.
+func goSignatures(lines []string) ([]FilteredLine, bool) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", strings.Join(lines, "\n"), parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, false
+	}
+
+	s := newSignatureLines(lines)
+	line := func(pos token.Pos) int { return fset.Position(pos).Line - 1 }
+	keepNode := func(node ast.Node) {
+		if node != nil && !isNilCommentGroup(node) {
+			s.keep(line(node.Pos()), line(node.End()))
+		}
+	}
+
+	for _, group := range file.Comments {
+		if group.End() < file.Package && strings.HasPrefix(group.List[0].Text, "//go:build") {
+			keepNode(group)
+		}
+	}
+	keepNode(file.Doc)
+	s.keep(line(file.Package), line(file.Name.End()))
+
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			keepNode(decl.Doc)
+			if decl.Body == nil {
+				keepNode(decl)
+
+				continue
+			}
+			brace := fset.Position(decl.Body.Lbrace)
+			s.keep(line(decl.Pos()), brace.Line-1)
+			s.replace(brace.Line-1, lines[brace.Line-1][:brace.Column-1]+elidedBody)
+		case *ast.GenDecl:
+			keepNode(decl.Doc)
+			if decl.Tok != token.VAR {
+				keepNode(decl)
+
+				continue
+			}
+			goVarSignatures(s, decl, line)
+		}
+	}
+
+	return s.result(), true
+}
+
+// goVarSignatures keeps the first line of each variable in decl, with the
+// rest of a value spanning several lines elided.
+func goVarSignatures(s *signatureLines, decl *ast.GenDecl, line func(token.Pos) int) {
+	if decl.Lparen.IsValid() {
+		s.keep(line(decl.Pos()), line(decl.Lparen))
+		s.keep(line(decl.Rparen), line(decl.Rparen))
+	}
+
+	for _, spec := range decl.Specs {
+		spec, ok := spec.(*ast.ValueSpec)
+		if !ok {
+			continue
+		}
+		if spec.Doc != nil {
+			s.keep(line(spec.Doc.Pos()), line(spec.Doc.End()))
+		}
+
+		first, last := line(spec.Pos()), line(spec.End())
+		if !decl.Lparen.IsValid() {
+			first = line(decl.Pos())
+		}
+		s.keep(first, first)
+		if last > first {
+			s.replace(first, elideRest(s.lines[first]))
+		}
+	}
+}
+
+// elideRest marks the end of a line whose statement continues on lines
+// left out, closing a block it opens.
+func elideRest(line string) string {
+	line = strings.TrimRight(line, " \t")
+	if trimmed, ok := strings.CutSuffix(line, "{"); ok {
+		return trimmed + elidedBody
+	}
+
+	return line + " …"
+}
+
+// isNilCommentGroup reports whether node is a nil *ast.CommentGroup, which
+// a nil check on the interface misses.
+func isNilCommentGroup(node ast.Node) bool {
+	group, ok := node.(*ast.CommentGroup)
+
+	return ok && group == nil
+}
+
+// validateSignatures checks the Signatures settings. Signatures replaces
+// content before it is filtered, so it rules out content filters, and
+// line numbers no longer match reformatted or blamed lines.
+func (c *Config) validateSignatures() error {
+	if !c.SignaturesFallback.IsValid() {
+		return fmt.Errorf("unsupported signatures fallback: %s (supported: %s)",
+			c.SignaturesFallback, strings.Join(GetSupportedSignaturesFallbacks(), ", "))
+	}
+
+	if !c.Signatures {
+		if c.SignaturesFallback != "" {
+			return errors.New("--signatures-fallback requires --signatures")
+		}
+
+		return nil
+	}
+
+	for _, conflict := range []struct {
+		set  bool
+		flag string
+	}{
+		{len(c.ContentPatterns) > 0, "--pattern"},
+		{c.Todos, "--todos"},
+		{c.FrontMatterOnly, "--front-matter-only"},
+		{c.BlameSince != "", "--blame-since"},
+	} {
+		if conflict.set {
+			return fmt.Errorf("--signatures cannot be combined with %s", conflict.flag)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,439 @@
+package catls
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// pyDefPattern matches a Python function or class definition.
+	pyDefPattern = regexp.MustCompile(`^\s*(async\s+def|def|class)\s`)
+	// pyImportPattern matches a Python import statement.
+	pyImportPattern = regexp.MustCompile(`^\s*(import|from)\s`)
+	// pyDocstringPattern matches the opening quotes of a docstring.
+	pyDocstringPattern = regexp.MustCompile(`^\s*[rRuUbB]?("""|''')`)
+
+	// scriptFuncPattern matches a variable holding a function or an arrow function.
+	scriptFuncPattern = regexp.MustCompile(`^(const|let|var)\s+[\w$]+\s*(:[^=]+)?=\s*(async\s+)?(function\b|\(|[\w$]+\s*=>|<)`)
+	// scriptMethodPattern matches a method, constructor, or accessor in a class body.
+	scriptMethodPattern = regexp.MustCompile(`^((public|private|protected|static|readonly|async|override|abstract|get|set)\s+)*\*?[#\w$]+\??\s*(<[^>]*>)?\s*\(`)
+	// scriptPropertyPattern matches a property declared in a class body.
+	scriptPropertyPattern = regexp.MustCompile(`^((public|private|protected|static|readonly|override|abstract|declare)\s+)*[#\w$]+[?!]?\s*[:=;]`)
+	// scriptModifierPattern matches the keywords that may open a declaration
+	// without changing its kind.
+	scriptModifierPattern = regexp.MustCompile(`^(export|default|declare|abstract|async)\s+`)
+)
+
+// pythonSignatures keeps imports, class lines, function signatures with
+// their bodies elided, docstrings, and the decorators and comments directly
+// above a definition. Definitions inside function bodies are left out with
+// the body; those inside classes are kept.
+func pythonSignatures(lines []string) ([]FilteredLine, bool) {
+	s := newSignatureLines(lines)
+	masked, inString := maskTripleQuoted(lines)
+	code := make([]string, len(lines))
+	for i, line := range masked {
+		code[i] = codeOnly(line, true)
+	}
+
+	i := 0
+	for i < len(lines) && (strings.TrimSpace(lines[i]) == "" || strings.HasPrefix(strings.TrimSpace(lines[i]), "#")) {
+		i++
+	}
+	i = keepDocstring(s, i)
+
+	above := -1
+	for i < len(lines) {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "" || inString[i]:
+			above = -1
+			i++
+		case strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "@"):
+			if above < 0 {
+				above = i
+			}
+			i = bracketEnd(code, i) + 1
+		case pyImportPattern.MatchString(line):
+			end := bracketEnd(code, i)
+			s.keep(i, end)
+			above = -1
+			i = end + 1
+		case pyDefPattern.MatchString(line):
+			start := i
+			if above >= 0 {
+				start = above
+			}
+			above = -1
+			end := bracketEnd(code, i)
+			s.keep(start, end)
+			isClass := strings.HasPrefix(strings.TrimSpace(pyDefPattern.FindStringSubmatch(line)[1]), "class")
+			if !strings.HasSuffix(strings.TrimRight(code[end], " \t"), ":") {
+				// A definition and its body on one line
+				i = end + 1
+
+				continue
+			}
+			next := keepDocstring(s, end+1)
+			if isClass {
+				i = next
+
+				continue
+			}
+			s.replace(end, lines[end][:len(strings.TrimRight(code[end], " \t"))]+" …")
+			indent := indentOf(line)
+			for i = next; i < len(lines); i++ {
+				if strings.TrimSpace(lines[i]) != "" && !inString[i] && indentOf(lines[i]) <= indent {
+					break
+				}
+			}
+		default:
+			above = -1
+			i = bracketEnd(code, i) + 1
+		}
+	}
+
+	return s.result(), true
+}
+
+// keepDocstring keeps the docstring opening at or after the blank lines
+// from index i and returns the index after it, or i when there is none.
+func keepDocstring(s *signatureLines, i int) int {
+	j := i
+	for j < len(s.lines) && strings.TrimSpace(s.lines[j]) == "" {
+		j++
+	}
+	if j == len(s.lines) {
+		return i
+	}
+	match := pyDocstringPattern.FindStringSubmatchIndex(s.lines[j])
+	if match == nil {
+		return i
+	}
+
+	quotes := s.lines[j][match[2]:match[3]]
+	end := j
+	if !strings.Contains(s.lines[j][match[3]:], quotes) {
+		for end = j + 1; end < len(s.lines) && !strings.Contains(s.lines[end], quotes); end++ {
+		}
+	}
+	s.keep(j, end)
+
+	return end + 1
+}
+
+// maskTripleQuoted returns lines with the content of Python's triple-quoted
+// strings blanked, and reports the lines that start inside one, which are
+// never code whatever they hold. The quotes closing a string opened on an
+// earlier line are blanked too, since codeOnly does not follow strings
+// across lines.
+func maskTripleQuoted(lines []string) ([]string, []bool) {
+	masked := make([]string, len(lines))
+	inString := make([]bool, len(lines))
+	open := ""
+	for i, line := range lines {
+		inString[i] = open != ""
+		continued := inString[i]
+		out := []byte(line)
+		var quote byte
+		for j := 0; j < len(out); j++ {
+			switch {
+			case open != "" && strings.HasPrefix(line[j:], open):
+				if continued {
+					copy(out[j:], "   ")
+				}
+				open = ""
+				j += 2
+			case open != "":
+				if out[j] == '\\' && j+1 < len(out) {
+					out[j] = ' '
+					j++
+				}
+				out[j] = ' '
+			case quote != 0:
+				if out[j] == '\\' {
+					j++
+				} else if out[j] == quote {
+					quote = 0
+				}
+			case strings.HasPrefix(line[j:], `"""`) || strings.HasPrefix(line[j:], "'''"):
+				open = line[j : j+3]
+				continued = false
+				j += 2
+			case out[j] == '"' || out[j] == '\'':
+				quote = out[j]
+			case out[j] == '#':
+				j = len(out)
+			}
+		}
+		masked[i] = string(out)
+	}
+
+	return masked, inString
+}
+
+// indentOf returns the width of the leading whitespace of line.
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
+
+// bracketEnd returns the index of the line that closes every bracket opened
+// from line i on, or i when the line is balanced. code holds lines passed
+// through codeOnly.
+func bracketEnd(code []string, i int) int {
+	depth := 0
+	for j := i; j < len(code); j++ {
+		for _, r := range code[j] {
+			switch r {
+			case '(', '[', '{':
+				depth++
+			case ')', ']', '}':
+				depth--
+			}
+		}
+		if depth <= 0 {
+			return j
+		}
+	}
+
+	return len(code) - 1
+}
+
+// scriptSignatures keeps the imports, interfaces, type aliases and enums of
+// JavaScript and TypeScript, class lines and their members, and function,
+// method and arrow function signatures with their bodies elided, each with
+// the comments and decorators directly above it. Exported variables keep
+// their first line; other statements are left out.
+func scriptSignatures(lines []string) ([]FilteredLine, bool) {
+	s := newSignatureLines(lines)
+	code := make([]string, len(lines))
+	for i, line := range lines {
+		code[i] = codeOnly(line, false)
+	}
+
+	// classes holds the brace depth of each open class body
+	var classes []int
+	depth, above := 0, -1
+	for i := 0; i < len(lines); {
+		trimmed := strings.TrimSpace(lines[i])
+		inClass := len(classes) > 0 && depth == classes[len(classes)-1]
+		switch {
+		case trimmed == "":
+			above = -1
+			i++
+
+			continue
+		case strings.HasPrefix(trimmed, "/*"):
+			if above < 0 {
+				above = i
+			}
+			for i < len(lines) && !strings.Contains(lines[i], "*/") {
+				i++
+			}
+			i++
+
+			continue
+		case strings.HasPrefix(trimmed, "//") || strings.HasPrefix(trimmed, "@"):
+			if above < 0 {
+				above = i
+			}
+			i = bracketEnd(code, i) + 1
+
+			continue
+		case inClass && strings.HasPrefix(trimmed, "}"):
+			s.keep(i, i)
+			classes = classes[:len(classes)-1]
+			depth--
+			above = -1
+			i++
+
+			continue
+		}
+
+		start := i
+		if above >= 0 {
+			start = above
+		}
+		above = -1
+
+		decl := strings.TrimSpace(code[i])
+		exported := strings.HasPrefix(decl, "export")
+		for {
+			rest := scriptModifierPattern.ReplaceAllString(decl, "")
+			if rest == decl {
+				break
+			}
+			decl = rest
+		}
+
+		switch {
+		case !inClass && (strings.HasPrefix(decl, "import") && !strings.HasPrefix(decl, "import(") ||
+			exported && strings.Contains(decl, " from ")):
+			end := bracketEnd(code, i)
+			s.keep(start, end)
+			i = end + 1
+		case !inClass && isScriptBlockDecl(decl):
+			end := bracketEnd(code, i)
+			s.keep(start, end)
+			i = end + 1
+		case !inClass && strings.HasPrefix(decl, "class "):
+			open := i
+			for open < len(code)-1 && !strings.Contains(code[open], "{") {
+				open++
+			}
+			s.keep(start, open)
+			if bracketEnd(code, open) > open {
+				depth++
+				classes = append(classes, depth)
+			}
+			i = open + 1
+		case !inClass && (strings.HasPrefix(decl, "function") || scriptFuncPattern.MatchString(decl)),
+			inClass && scriptMethodPattern.MatchString(decl):
+			i = elideBody(s, code, start, i)
+		case inClass && scriptPropertyPattern.MatchString(decl),
+			!inClass && exported:
+			end := bracketEnd(code, i)
+			s.keep(start, i)
+			if end > i {
+				s.replace(i, elideRest(lines[i]))
+			}
+			i = end + 1
+		default:
+			i = bracketEnd(code, i) + 1
+		}
+	}
+
+	return s.result(), true
+}
+
+// isScriptBlockDecl reports whether decl, stripped of modifiers, opens a
+// declaration kept whole: an interface, type alias, enum, or namespace.
+func isScriptBlockDecl(decl string) bool {
+	for _, prefix := range []string{"interface ", "type ", "enum ", "const enum ", "namespace ", "module ", "global "} {
+		if strings.HasPrefix(decl, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// elideBody keeps the lines from start through the one where the body of
+// the function declared at line decl opens, with the body replaced by
+// elidedBody, and returns the index after the body. Declarations without a
+// body, such as overloads and arrow functions returning an expression, are
+// kept through their end.
+func elideBody(s *signatureLines, code []string, start, decl int) int {
+	parens := 0
+	for j := decl; j < len(code); j++ {
+		for c, r := range code[j] {
+			switch r {
+			case '(', '[':
+				parens++
+			case ')', ']':
+				parens--
+			case '{':
+				if parens == 0 {
+					s.keep(start, j)
+					s.replace(j, s.lines[j][:c]+elidedBody)
+
+					return braceEnd(code, j, c) + 1
+				}
+			case ';':
+				if parens == 0 {
+					s.keep(start, j)
+
+					return j + 1
+				}
+			}
+		}
+
+		line := strings.TrimSpace(code[j])
+		if parens == 0 && strings.Contains(line, "=>") && !strings.HasSuffix(line, "=>") {
+			end := bracketEnd(code, j)
+			s.keep(start, j)
+			if end > j {
+				s.replace(j, elideRest(s.lines[j]))
+			}
+
+			return end + 1
+		}
+	}
+	s.keep(start, len(code)-1)
+
+	return len(code)
+}
+
+// braceEnd returns the index of the line holding the brace that closes the
+// one at column c of line i.
+func braceEnd(code []string, i, c int) int {
+	depth := 0
+	for j := i; j < len(code); j++ {
+		line := code[j]
+		if j == i {
+			line = line[c:]
+		}
+		for _, r := range line {
+			switch r {
+			case '{':
+				depth++
+			case '}':
+				depth--
+				if depth == 0 {
+					return j
+				}
+			}
+		}
+	}
+
+	return len(code) - 1
+}
+
+// codeOnly returns line with the content of string literals and comments
+// replaced by spaces, keeping byte offsets, so brackets inside them are not
+// counted. Comments start with # for Python, and are // and /* */ for
+// JavaScript and TypeScript. Strings and comments spanning lines are not
+// followed.
+func codeOnly(line string, python bool) string {
+	out := []byte(line)
+	blank := func(from, to int) {
+		for j := from; j < to && j < len(out); j++ {
+			out[j] = ' '
+		}
+	}
+
+	var quote byte
+	for i := 0; i < len(out); i++ {
+		b := out[i]
+		switch {
+		case quote != 0:
+			switch b {
+			case '\\':
+				blank(i, i+2)
+				i++
+			case quote:
+				quote = 0
+			default:
+				out[i] = ' '
+			}
+		case b == '"' || b == '\'' || b == '`' && !python:
+			quote = b
+		case python && b == '#', !python && strings.HasPrefix(line[i:], "//"):
+			blank(i, len(out))
+
+			return string(out)
+		case !python && strings.HasPrefix(line[i:], "/*"):
+			end := strings.Index(line[i+2:], "*/")
+			if end < 0 {
+				blank(i, len(out))
+
+				return string(out)
+			}
+			blank(i, i+end+4)
+			i += end + 3
+		}
+	}
+
+	return string(out)
+}
@@ -0,0 +1,406 @@
+package catls
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestGoSignaturesOwnFiles reduces every Go file of this repository and
+// checks each function keeps its signature but no line of its body, while
+// type declarations are kept whole.
+func TestGoSignaturesOwnFiles(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("..", "..", "*", "*", "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := filepath.Glob(filepath.Join("..", "..", "*", "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths = append(paths, root...)
+	if len(paths) < 20 {
+		t.Fatalf("found only %d Go files in the repository", len(paths))
+	}
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+
+		reduced, ok := goSignatures(lines)
+		if !ok {
+			t.Errorf("%s: goSignatures() could not parse the file", path)
+
+			continue
+		}
+		kept := make(map[int]string, len(reduced))
+		for _, line := range reduced {
+			kept[line.LineNumber] = line.Content
+		}
+
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, path, data, parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := kept[fset.Position(file.Package).Line]; !strings.HasPrefix(got, "package ") {
+			t.Errorf("%s: package clause missing, got %q", path, got)
+		}
+
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if decl.Body == nil {
+					continue
+				}
+				open, closing := fset.Position(decl.Body.Lbrace), fset.Position(decl.Body.Rbrace)
+				signature, ok := kept[open.Line]
+				if !ok || !strings.HasSuffix(signature, elidedBody) {
+					t.Errorf("%s:%d: signature of %s = %q, want it ending in %q", path, open.Line, decl.Name.Name, signature, elidedBody)
+				}
+				if first := fset.Position(decl.Pos()).Line; kept[first] != lines[first-1] && first != open.Line {
+					t.Errorf("%s:%d: first line of %s = %q, want it as in the file", path, first, decl.Name.Name, kept[first])
+				}
+				for line := open.Line + 1; line <= closing.Line; line++ {
+					if content, ok := kept[line]; ok && content != "" {
+						t.Errorf("%s:%d: body line of %s kept: %q", path, line, decl.Name.Name, content)
+					}
+				}
+				if decl.Doc != nil {
+					if line := fset.Position(decl.Doc.Pos()).Line; kept[line] != lines[line-1] {
+						t.Errorf("%s:%d: doc comment of %s missing", path, line, decl.Name.Name)
+					}
+				}
+			case *ast.GenDecl:
+				if decl.Tok != token.TYPE && decl.Tok != token.IMPORT {
+					continue
+				}
+				for line := fset.Position(decl.Pos()).Line; line <= fset.Position(decl.End()).Line; line++ {
+					if kept[line] != lines[line-1] {
+						t.Errorf("%s:%d: line of a %s declaration = %q, want %q", path, line, decl.Tok, kept[line], lines[line-1])
+					}
+				}
+			}
+		}
+	}
+
+	// A known signature, spelled out
+	data, err := os.ReadFile("processor.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reduced, _ := goSignatures(strings.Split(string(data), "\n"))
+	var got []string
+	for _, line := range reduced {
+		got = append(got, line.Content)
+	}
+	want := "func (p *FileProcessor) ProcessFile(file scanner.FileInfo, filter *FileFilter) ProcessedFile { … }"
+	if !strings.Contains(strings.Join(got, "\n"), "\n"+want+"\n") {
+		t.Errorf("processor.go signatures lack %q", want)
+	}
+}
+
+func TestGoSignatures(t *testing.T) {
+	src := `//go:build linux
+
+// Package a does things.
+package a
+
+import "fmt"
+
+// Limit caps things.
+const Limit = 3
+
+var (
+	// names are known.
+	names = map[string]bool{
+		"a": true,
+	}
+	count int
+)
+
+// Greet says hello.
+func Greet(name string,
+	loud bool) {
+	fmt.Println(name)
+}
+
+func external() int
+`
+	want := []string{
+		"1://go:build linux",
+		"2:",
+		"3:// Package a does things.",
+		"4:package a",
+		"5:",
+		`6:import "fmt"`,
+		"7:",
+		"8:// Limit caps things.",
+		"9:const Limit = 3",
+		"10:",
+		"11:var (",
+		"12:	// names are known.",
+		"13:	names = map[string]bool{ … }",
+		"16:	count int",
+		"17:)",
+		"18:",
+		"19:// Greet says hello.",
+		"20:func Greet(name string,",
+		"21:	loud bool) { … }",
+		"24:",
+		"25:func external() int",
+	}
+
+	reduced, ok := goSignatures(strings.Split(strings.TrimSuffix(src, "\n"), "\n"))
+	if !ok {
+		t.Fatal("goSignatures() could not parse the source")
+	}
+	if got := numberedLines(reduced); got != strings.Join(want, "\n") {
+		t.Errorf("goSignatures() =\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+	}
+
+	if _, ok := goSignatures([]string{"package a", "func {"}); ok {
+		t.Error("goSignatures() reduced a file that does not parse")
+	}
+}
+
+func TestPythonSignatures(t *testing.T) {
+	src := `"""Module doc."""
+import os
+from typing import (
+    List,
+)
+
+LIMIT = 3
+
+# Adds things.
+@cache
+def add(a: int,
+        b: int) -> int:  # sums
+    """Return a + b."""
+    def inner():
+        pass
+    return a + b
+
+class Box(Base):
+    """A box."""
+
+    size = 1
+
+    async def open(self):
+        return "{"
+
+def short(): pass
+`
+	want := []string{
+		`1:"""Module doc."""`,
+		"2:import os",
+		"3:from typing import (",
+		"4:    List,",
+		"5:)",
+		"8:",
+		"9:# Adds things.",
+		"10:@cache",
+		"11:def add(a: int,",
+		"12:        b: int) -> int: …",
+		`13:    """Return a + b."""`,
+		"17:",
+		"18:class Box(Base):",
+		`19:    """A box."""`,
+		"22:",
+		"23:    async def open(self): …",
+		"25:",
+		"26:def short(): pass",
+	}
+
+	reduced, _ := pythonSignatures(strings.Split(strings.TrimSuffix(src, "\n"), "\n"))
+	if got := numberedLines(reduced); got != strings.Join(want, "\n") {
+		t.Errorf("pythonSignatures() =\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+	}
+}
+
+func TestPythonSignaturesTripleQuotedStrings(t *testing.T) {
+	src := `def render():
+    return """
+def fake():
+    pass
+"""
+
+TEMPLATE = '''
+class Fake:
+'''
+
+def real(): pass
+`
+	want := []string{
+		"1:def render(): …",
+		"10:",
+		"11:def real(): pass",
+	}
+
+	reduced, _ := pythonSignatures(strings.Split(strings.TrimSuffix(src, "\n"), "\n"))
+	if got := numberedLines(reduced); got != strings.Join(want, "\n") {
+		t.Errorf("pythonSignatures() =\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+	}
+}
+
+func TestScriptSignatures(t *testing.T) {
+	src := `import { a } from "./a";
+
+/** Greets. */
+export function greet(name: string): string {
+  return "}";
+}
+
+export interface Props {
+  a: string;
+}
+
+const internal = 5;
+
+export const config = {
+  a: 1,
+};
+
+export const handler = async (req: Request) => {
+  return req;
+};
+
+export class Widget extends Base {
+  #count = 0;
+
+  constructor(name: string) {
+    super();
+  }
+
+  // Renders.
+  render(): void {
+    log("{");
+  }
+}
+`
+	want := []string{
+		`1:import { a } from "./a";`,
+		"2:",
+		"3:/** Greets. */",
+		"4:export function greet(name: string): string { … }",
+		"7:",
+		"8:export interface Props {",
+		"9:  a: string;",
+		"10:}",
+		"13:",
+		"14:export const config = { … }",
+		"17:",
+		"18:export const handler = async (req: Request) => { … }",
+		"21:",
+		"22:export class Widget extends Base {",
+		"23:  #count = 0;",
+		"24:",
+		"25:  constructor(name: string) { … }",
+		"28:",
+		"29:  // Renders.",
+		"30:  render(): void { … }",
+		"33:}",
+	}
+
+	reduced, _ := scriptSignatures(strings.Split(strings.TrimSuffix(src, "\n"), "\n"))
+	if got := numberedLines(reduced); got != strings.Join(want, "\n") {
+		t.Errorf("scriptSignatures() =\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+	}
+}
+
+func TestSignatures(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"main.go":   "package main\n\nfunc main() {\n\tprintln()\n}\n",
+		"notes.txt": strings.Repeat("note\n", SignaturesHeadLines+5),
+	})
+
+	for _, tt := range []struct {
+		fallback  SignaturesFallback
+		wantNotes int
+	}{
+		{fallback: "", wantNotes: SignaturesHeadLines + 5},
+		{fallback: SignaturesFallbackHead, wantNotes: SignaturesHeadLines},
+	} {
+		var out bytes.Buffer
+		app, err := New(&Config{Directory: tmpDir, OutputFormat: OutputFormatJSON, Output: &out, Signatures: true, SignaturesFallback: tt.fallback})
+		if err != nil {
+			t.Fatalf("New() unexpected error: %v", err)
+		}
+		files := make(map[string]ProcessedFile)
+		for file, err := range app.Files(context.Background()) {
+			if err != nil {
+				t.Fatalf("Files() unexpected error: %v", err)
+			}
+			files[file.Info.RelPath] = file
+		}
+
+		if got := numberedLines(files["main.go"].Lines); got != "1:package main\n2:\n3:func main() { … }" {
+			t.Errorf("fallback %q: main.go lines =\n%s", tt.fallback, got)
+		}
+		if files["main.go"].Reduced != ReducedSignatures {
+			t.Errorf("fallback %q: main.go Reduced = %q, want %q", tt.fallback, files["main.go"].Reduced, ReducedSignatures)
+		}
+		notes := files["notes.txt"]
+		if len(notes.Lines) != tt.wantNotes || notes.IsTruncated != (tt.fallback == SignaturesFallbackHead) {
+			t.Errorf("fallback %q: notes.txt has %d lines, truncated %v", tt.fallback, len(notes.Lines), notes.IsTruncated)
+		}
+		if wantReduced := map[SignaturesFallback]string{SignaturesFallbackHead: ReducedHead}[tt.fallback]; notes.Reduced != wantReduced {
+			t.Errorf("fallback %q: notes.txt Reduced = %q, want %q", tt.fallback, notes.Reduced, wantReduced)
+		}
+	}
+}
+
+func TestValidateSignatures(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{name: "signatures", cfg: Config{Signatures: true, SignaturesFallback: SignaturesFallbackHead}},
+		{name: "unknown fallback", cfg: Config{Signatures: true, SignaturesFallback: "tail"}, wantErr: "unsupported signatures fallback"},
+		{name: "fallback alone", cfg: Config{SignaturesFallback: SignaturesFallbackFull}, wantErr: "requires --signatures"},
+		{name: "with pattern", cfg: Config{Signatures: true, ContentPatterns: []string{"TODO"}}, wantErr: "--pattern"},
+		{name: "with todos", cfg: Config{Signatures: true, Todos: true}, wantErr: "--todos"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.validateSignatures()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateSignatures() unexpected error: %v", err)
+				}
+
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateSignatures() error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// numberedLines joins lines as number:content, one per line.
+func numberedLines(lines []FilteredLine) string {
+	var b strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		fmt.Fprintf(&b, "%d:%s", line.LineNumber, line.Content)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,180 @@
+package catls
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+// ErrBudgetExceeded is returned when the written files exceed one of
+// SizeBudgets and BudgetWarnOnly is not set.
+var ErrBudgetExceeded = errors.New("size budget exceeded")
+
+// SizeBudget caps the total size of the written files a target matches.
+type SizeBudget struct {
+	// Target is a glob, matched against relative paths and file names like
+	// Globs, or with Dir a directory relative to Directory.
+	Target string
+	// Dir makes Target a directory: every file below it counts.
+	Dir bool
+	// Limit is the most bytes the matching files may add up to.
+	Limit int64
+}
+
+// String returns the target as written in a --budget rule, a directory
+// ending with a slash.
+func (b SizeBudget) String() string {
+	if b.Dir {
+		return b.Target + "/"
+	}
+
+	return b.Target
+}
+
+// matches reports whether a file at relPath counts against the budget.
+func (b SizeBudget) matches(relPath string) bool {
+	if b.Dir {
+		return b.Target == "." || strings.HasPrefix(relPath, b.Target+"/")
+	}
+
+	return scanner.MatchesGlobPattern(relPath, b.Target)
+}
+
+// BudgetUsage is how much of a SizeBudget the written files used.
+type BudgetUsage struct {
+	Budget SizeBudget
+	Bytes  int64 // Total size of the matching files, as scanned
+	Files  int   // Number of matching files
+}
+
+// Exceeded reports whether the matching files are larger than the budget allows.
+func (u BudgetUsage) Exceeded() bool {
+	return u.Bytes > u.Budget.Limit
+}
+
+// NewSizeBudget returns the budget for target, a glob or, ending with a
+// slash, a directory. A leading "./" is dropped from directories, and the
+// directory "./" or "/" stands for every file.
+func NewSizeBudget(target string, limit int64) (SizeBudget, error) {
+	if target == "" {
+		return SizeBudget{}, errors.New("budget target must not be empty")
+	}
+	if limit < 0 {
+		return SizeBudget{}, fmt.Errorf("budget for %s must not be negative", target)
+	}
+
+	dir, isDir := strings.CutSuffix(target, "/")
+	if !isDir {
+		return SizeBudget{Target: target, Limit: limit}, nil
+	}
+
+	return SizeBudget{Target: path.Clean("./" + dir), Dir: true, Limit: limit}, nil
+}
+
+// newBudgetUsage returns zero usage for each budget, in order.
+func newBudgetUsage(budgets []SizeBudget) []BudgetUsage {
+	if len(budgets) == 0 {
+		return nil
+	}
+
+	usage := make([]BudgetUsage, len(budgets))
+	for i, budget := range budgets {
+		usage[i].Budget = budget
+	}
+
+	return usage
+}
+
+// recordBudgets counts a written file against every budget that matches it,
+// by its path relative to Directory, so RelativeTo does not move targets.
+func (s *RunStats) recordBudgets(relPath string, file scanner.FileInfo) {
+	relPath = filepath.ToSlash(relPath)
+	for i := range s.Budgets {
+		if s.Budgets[i].Budget.matches(relPath) {
+			s.Budgets[i].Bytes += file.Size
+			s.Budgets[i].Files++
+		}
+	}
+}
+
+// writeBudgetReport writes the size of the files each budget matched
+// against its limit, one rule per line, and returns ErrBudgetExceeded
+// naming the exceeded rules unless BudgetWarnOnly.
+func (a *App) writeBudgetReport(w io.Writer) error {
+	if len(a.stats.Budgets) == 0 {
+		return nil
+	}
+
+	width := 0
+	for _, usage := range a.stats.Budgets {
+		width = max(width, len(usage.Budget.String()))
+	}
+
+	var exceeded []string
+	fmt.Fprintln(w, "Size budgets:")
+	for _, usage := range a.stats.Budgets {
+		verdict := "ok"
+		if usage.Exceeded() {
+			verdict = "EXCEEDED"
+			exceeded = append(exceeded, usage.Budget.String())
+		}
+		fmt.Fprintf(w, "  %-*s  %s of %s in %s  %s\n", width, usage.Budget,
+			FormatByteSize(usage.Bytes), FormatByteSize(usage.Budget.Limit), pluralFiles(usage.Files), verdict)
+	}
+
+	if len(exceeded) == 0 || a.cfg.BudgetWarnOnly {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s", ErrBudgetExceeded, strings.Join(exceeded, ", "))
+}
+
+// pluralFiles returns "1 file" or "N files".
+func pluralFiles(n int) string {
+	if n == 1 {
+		return "1 file"
+	}
+
+	return strconv.Itoa(n) + " files"
+}
+
+// FormatByteSize renders n with the largest binary unit that keeps it at
+// least 1, such as "512B", "1.5MB", or "14MB". Values of 10 or more are
+// rounded to whole units.
+func FormatByteSize(n int64) string {
+	units := []string{"KB", "MB", "GB"}
+	if n < 1<<10 {
+		return strconv.FormatInt(n, 10) + "B"
+	}
+
+	value := float64(n)
+	unit := ""
+	for _, next := range units {
+		if value < 1<<10 {
+			break
+		}
+		value /= 1 << 10
+		unit = next
+	}
+
+	if value >= 10 {
+		return fmt.Sprintf("%.0f%s", value, unit)
+	}
+
+	return strings.TrimSuffix(fmt.Sprintf("%.1f", value), ".0") + unit
+}
+
+// validateSizeBudgets requires budgets for BudgetWarnOnly.
+func (c *Config) validateSizeBudgets() error {
+	if c.BudgetWarnOnly && len(c.SizeBudgets) == 0 {
+		return errors.New("--budget-warn-only requires --budget")
+	}
+
+	return nil
+}
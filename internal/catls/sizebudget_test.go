@@ -0,0 +1,159 @@
+package catls
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+func TestNewSizeBudget(t *testing.T) {
+	tests := []struct {
+		target  string
+		want    SizeBudget
+		matches []string
+		misses  []string
+	}{
+		{target: "*.md", want: SizeBudget{Target: "*.md", Limit: 10}, matches: []string{"README.md", "docs/a.md"}, misses: []string{"main.go"}},
+		{target: "docs/", want: SizeBudget{Target: "docs", Dir: true, Limit: 10}, matches: []string{"docs/a.md", "docs/x/b.go"}, misses: []string{"docs.md", "src/docs/a.md"}},
+		{target: "./docs/api/", want: SizeBudget{Target: "docs/api", Dir: true, Limit: 10}, matches: []string{"docs/api/a.md"}, misses: []string{"docs/a.md"}},
+		{target: "./", want: SizeBudget{Target: ".", Dir: true, Limit: 10}, matches: []string{"a.go", "x/y.go"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			got, err := NewSizeBudget(tt.target, 10)
+			if err != nil {
+				t.Fatalf("NewSizeBudget() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("NewSizeBudget() = %+v, want %+v", got, tt.want)
+			}
+			for _, path := range tt.matches {
+				if !got.matches(path) {
+					t.Errorf("%s does not match %s", got, path)
+				}
+			}
+			for _, path := range tt.misses {
+				if got.matches(path) {
+					t.Errorf("%s matches %s", got, path)
+				}
+			}
+		})
+	}
+
+	if _, err := NewSizeBudget("", 10); err == nil {
+		t.Error("NewSizeBudget() accepted an empty target")
+	}
+}
+
+func TestSizeBudgets(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"README.md":     strings.Repeat("r", 100),
+		"docs/guide.md": strings.Repeat("g", 300),
+		"docs/logo.txt": strings.Repeat("l", 50),
+		"main.go":       "package main\n",
+	})
+
+	for _, tt := range []struct {
+		name     string
+		warnOnly bool
+		wantErr  bool
+	}{
+		{name: "exceeded", wantErr: true},
+		{name: "warn only", warnOnly: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			mdBudget, _ := NewSizeBudget("*.md", 1000)
+			docsBudget, _ := NewSizeBudget("docs/", 200)
+			app, err := New(&Config{
+				Directory:      tmpDir,
+				Recursive:      true,
+				OutputFormat:   OutputFormatJSON,
+				Output:         &bytes.Buffer{},
+				SizeBudgets:    []SizeBudget{mdBudget, docsBudget},
+				BudgetWarnOnly: tt.warnOnly,
+			})
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+
+			err = app.Run(context.Background())
+			if got := errors.Is(err, ErrBudgetExceeded); got != tt.wantErr {
+				t.Fatalf("Run() error = %v, want budget exceeded %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !strings.HasSuffix(err.Error(), ": docs/") {
+				t.Errorf("Run() error = %v, want it to name docs/ only", err)
+			}
+
+			want := []BudgetUsage{
+				{Budget: mdBudget, Bytes: 400, Files: 2},
+				{Budget: docsBudget, Bytes: 350, Files: 2},
+			}
+			stats := app.Stats()
+			if len(stats.Budgets) != len(want) {
+				t.Fatalf("Budgets = %+v, want %+v", stats.Budgets, want)
+			}
+			for i := range want {
+				if stats.Budgets[i] != want[i] {
+					t.Errorf("Budgets[%d] = %+v, want %+v", i, stats.Budgets[i], want[i])
+				}
+			}
+
+			var report bytes.Buffer
+			_ = app.writeBudgetReport(&report)
+			wantReport := "Size budgets:\n  *.md   400B of 1000B in 2 files  ok\n  docs/  350B of 200B in 2 files  EXCEEDED\n"
+			if report.String() != wantReport {
+				t.Errorf("report =\n%s\nwant\n%s", report.String(), wantReport)
+			}
+		})
+	}
+}
+
+func TestSizeBudgetsCountWrittenFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"docs/guide.md": strings.Repeat("g", 300),
+		"docs/gone.md":  strings.Repeat("x", 500),
+	})
+	// Removing a file once the scan selected it makes it an error record
+	vanish := func(file scanner.FileInfo) bool {
+		if file.RelPath == filepath.Join(filepath.Base(tmpDir), "docs", "gone.md") {
+			_ = os.Remove(file.Path)
+		}
+
+		return true
+	}
+
+	allBudget, _ := NewSizeBudget("./", 1000)
+	docsBudget, _ := NewSizeBudget("docs/", 1000)
+	mdBudget, _ := NewSizeBudget("*.md", 1000)
+	app, err := New(&Config{
+		Directory:    tmpDir,
+		Recursive:    true,
+		IncludeDirs:  true,
+		RelativeTo:   filepath.Dir(tmpDir),
+		OutputFormat: OutputFormatJSON,
+		Output:       &bytes.Buffer{},
+		IncludeFunc:  vanish,
+		SizeBudgets:  []SizeBudget{allBudget, docsBudget, mdBudget},
+	})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	for i, usage := range app.Stats().Budgets {
+		if usage.Bytes != 300 || usage.Files != 1 {
+			t.Errorf("Budgets[%d] = %+v, want 300 bytes in 1 file", i, usage)
+		}
+	}
+}
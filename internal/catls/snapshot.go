@@ -0,0 +1,57 @@
+package catls
+
+import (
+	"os"
+
+	"github.com/connerohnesorge/catls/internal/longpath"
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+// processFile re-stats file before processing it, since a build running
+// alongside may have rewritten or removed it since the scan. The metadata is
+// updated to match what is read, so sizes in the output, the token budget,
+// and the manifest describe the content written; StrictSnapshot also marks
+// the file as modified. A file that vanished is reported as ErrNotFound.
+func (a *App) processFile(file scanner.FileInfo) ProcessedFile {
+	modified, err := restat(&file)
+	if err != nil {
+		return ProcessedFile{Info: file, Error: err, ErrorCategory: categorizeError(err)}
+	}
+
+	processed, ok := ProcessedFile{}, false
+	if a.cfg.SummarizeLockfiles {
+		processed, ok = a.summarizeLockfile(file)
+	}
+	if !ok {
+		processed = a.processor.ProcessFile(file, a.filter)
+	}
+	processed.ModifiedDuringRun = modified && a.cfg.StrictSnapshot
+	a.recordXattrs(&processed)
+
+	return processed
+}
+
+// restat updates the size and modification time of file from the
+// filesystem and reports whether either changed since the scan.
+func restat(file *scanner.FileInfo) (bool, error) {
+	info, err := os.Stat(longpath.Fix(file.Path))
+	if err != nil {
+		return false, err
+	}
+	if info.Size() == file.Size && info.ModTime().Equal(file.ModTime) {
+		return false, nil
+	}
+	file.Size, file.ModTime = info.Size(), info.ModTime()
+
+	return true, nil
+}
+
+// modifiedAttr returns the modified-during-run attribute of a file tag,
+// empty unless StrictSnapshot found the file changed since the scan.
+func modifiedAttr(file *ProcessedFile) string {
+	if !file.ModifiedDuringRun {
+		return ""
+	}
+
+	return ` modified-during-run="true"`
+}
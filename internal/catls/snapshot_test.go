@@ -0,0 +1,74 @@
+package catls
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+func TestFilesChangedDuringRun(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	for _, strict := range []bool{false, true} {
+		writeTree(t, tmpDir, map[string]string{
+			"built.go": "package built",
+			"stale.go": "package stale",
+			"same.go":  "package same",
+		})
+		// Rewriting and removing files once the scan recorded them stands in
+		// for a build running alongside
+		rebuild := func(file scanner.FileInfo) bool {
+			switch file.RelPath {
+			case "built.go":
+				writeTree(t, tmpDir, map[string]string{"built.go": "package built\n\nfunc Built() {}\n"})
+				later := file.ModTime.Add(time.Second)
+				if err := os.Chtimes(file.Path, later, later); err != nil {
+					t.Fatalf("failed to touch %s: %v", file.Path, err)
+				}
+			case "stale.go":
+				_ = os.Remove(file.Path)
+			}
+
+			return true
+		}
+
+		sizes := make(map[string]int64)
+		var buf bytes.Buffer
+		app, err := New(&Config{
+			Directory:      tmpDir,
+			OutputFormat:   OutputFormatXML,
+			Output:         &buf,
+			StrictSnapshot: strict,
+			IncludeFunc:    rebuild,
+			TransformFunc:  func(file *ProcessedFile) { sizes[file.Info.RelPath] = file.Info.Size },
+		})
+		if err != nil {
+			t.Fatalf("New() unexpected error: %v", err)
+		}
+		if err := app.Run(context.Background()); err != nil {
+			t.Fatalf("Run() unexpected error: %v", err)
+		}
+
+		output := buf.String()
+		if !strings.Contains(output, "func Built() {}") || sizes["built.go"] != int64(len("package built\n\nfunc Built() {}\n")) {
+			t.Errorf("strict=%v: built.go size %d and output do not match the rewritten file:\n%s", strict, sizes["built.go"], output)
+		}
+		if !strings.Contains(output, `<error category="not-found">`) {
+			t.Errorf("strict=%v: stale.go is not reported as not found:\n%s", strict, output)
+		}
+
+		marked := strings.Count(output, `modified-during-run="true"`)
+		stats := app.Stats()
+		if strict && (marked != 1 || !strings.Contains(output, `<file path="built.go" modified-during-run="true">`) || stats.ModifiedDuringRun != 1) {
+			t.Errorf("StrictSnapshot marked %d files, %d in stats; want built.go only:\n%s", marked, stats.ModifiedDuringRun, output)
+		}
+		if !strict && (marked != 0 || stats.ModifiedDuringRun != 0) {
+			t.Errorf("without StrictSnapshot %d files are marked, want none", marked)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package catls
+
+import (
+	"strings"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+// SortOrder selects how files are ordered by their relative paths.
+type SortOrder string
+
+const (
+	// SortName orders files by the bytes of their paths, so "File10.go"
+	// comes before "File2.go" and uppercase before lowercase.
+	SortName SortOrder = "name"
+	// SortNatural orders files with scanner.NaturalCompare: numbers by value
+	// and letters without regard to case, so "file2.go" comes before
+	// "File10.go".
+	SortNatural SortOrder = "natural"
+)
+
+// IsValid checks if the sort order is supported. Empty means SortName.
+func (s SortOrder) IsValid() bool {
+	switch s {
+	case "", SortName, SortNatural:
+		return true
+	default:
+		return false
+	}
+}
+
+// GetSupportedSortOrders returns a list of all supported sort orders.
+func GetSupportedSortOrders() []string {
+	return []string{string(SortName), string(SortNatural)}
+}
+
+// comparePaths compares two relative paths in the order Sort selects.
+func (c *Config) comparePaths(a, b string) int {
+	if c.Sort == SortNatural {
+		return scanner.NaturalCompare(a, b)
+	}
+
+	return strings.Compare(a, b)
+}
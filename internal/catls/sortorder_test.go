@@ -0,0 +1,77 @@
+package catls
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestSortOrder(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"File10.go":      "package a",
+		"file2.go":       "package a",
+		"README.md":      "# a",
+		"notes.log":      "trace",
+		"sub/Part10.txt": "x",
+		"sub/part9.txt":  "x",
+	})
+
+	tests := []struct {
+		name string
+		cfg  Config
+		want string
+	}{
+		{
+			name: "name",
+			want: "File10.go,README.md,file2.go,sub/Part10.txt,sub/part9.txt",
+		},
+		{
+			name: "natural",
+			cfg:  Config{Sort: SortNatural},
+			want: "file2.go,File10.go,README.md,sub/part9.txt,sub/Part10.txt",
+		},
+		{
+			name: "natural deterministic",
+			cfg:  Config{Sort: SortNatural, Deterministic: true},
+			want: "file2.go,File10.go,README.md,sub/part9.txt,sub/Part10.txt",
+		},
+		{
+			// Excluded files offered by the selector merge in the same order
+			name: "natural interactive",
+			cfg:  Config{Sort: SortNatural, Interactive: true},
+			want: "file2.go,File10.go,notes.log,README.md,sub/part9.txt,sub/Part10.txt",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.Directory, cfg.Recursive, cfg.OutputFormat = tmpDir, true, OutputFormatXML
+			cfg.IgnoreGlobs = []string{"*.log"}
+			app, err := New(&cfg)
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+			files, _, err := app.scanFiles(context.Background(), false)
+			if err != nil {
+				t.Fatalf("scanFiles() unexpected error: %v", err)
+			}
+			if cfg.Interactive {
+				files, _ = app.withExcluded(files)
+			}
+
+			var got []string
+			for _, file := range files {
+				got = append(got, file.RelPath)
+			}
+			if strings.Join(got, ",") != tt.want {
+				t.Errorf("order = %s, want %s", strings.Join(got, ","), tt.want)
+			}
+		})
+	}
+
+	if err := (&Config{Sort: "size"}).validateSort(); err == nil || !strings.Contains(err.Error(), "unsupported sort order") {
+		t.Errorf("validateSort() = %v, want unsupported sort order", err)
+	}
+}
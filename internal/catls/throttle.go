@@ -0,0 +1,45 @@
+package catls
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/connerohnesorge/catls/internal/throttle"
+)
+
+// throttledOutput is Output slowed to Throttle. Writes give up when the
+// context of the run writing them is done, rather than waiting out the rate.
+type throttledOutput struct {
+	w   *throttle.Writer
+	ctx context.Context // Context of the current run, set by bind
+}
+
+// newThrottledOutput wraps out when Throttle is set, or returns nil.
+func newThrottledOutput(cfg *Config, out io.Writer) *throttledOutput {
+	if cfg.Throttle <= 0 {
+		return nil
+	}
+
+	return &throttledOutput{w: throttle.NewWriter(out, cfg.Throttle), ctx: context.Background()}
+}
+
+// bind makes writes stop when ctx is done.
+func (t *throttledOutput) bind(ctx context.Context) {
+	if t != nil {
+		t.ctx = ctx
+	}
+}
+
+func (t *throttledOutput) Write(p []byte) (int, error) {
+	return t.w.WriteContext(t.ctx, p)
+}
+
+// validateThrottle rejects a negative Throttle.
+func (c *Config) validateThrottle() error {
+	if c.Throttle < 0 {
+		return errors.New("--throttle must not be negative")
+	}
+
+	return nil
+}
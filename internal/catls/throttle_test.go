@@ -0,0 +1,53 @@
+package catls
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestThrottle(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"a.txt": strings.Repeat("a", 4096)})
+
+	var want bytes.Buffer
+	app, err := New(&Config{Directory: root, OutputFormat: OutputFormatJSON, Output: &want})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var got bytes.Buffer
+	app, err = New(&Config{Directory: root, OutputFormat: OutputFormatJSON, Output: &got, Throttle: 1 << 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if got.String() != want.String() {
+		t.Errorf("throttled output differs:\n%s\nwant:\n%s", got.String(), want.String())
+	}
+
+	// At 64 bytes a second the file would take a minute; cancelling ends the
+	// run without waiting for the rate
+	app, err = New(&Config{Directory: root, OutputFormat: OutputFormatJSON, Output: &bytes.Buffer{}, Throttle: 64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = app.Run(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Run() error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Run() took %v after its context ended", elapsed)
+	}
+}
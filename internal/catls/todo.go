@@ -0,0 +1,139 @@
+package catls
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+// DefaultTodoKeywords are the annotation markers Todos looks for when no
+// keywords are configured.
+var DefaultTodoKeywords = []string{"TODO", "FIXME", "HACK", "XXX"}
+
+// TodoRef points at a single annotated line.
+type TodoRef struct {
+	Path string `json:"path"`
+	Line int    `json:"line"`
+}
+
+// TodoKeywordRefs lists every line annotated with one keyword.
+type TodoKeywordRefs struct {
+	Keyword string    `json:"keyword"`
+	Refs    []TodoRef `json:"refs"`
+}
+
+// TodoIndex maps each keyword to the lines it annotates, ordered like the
+// configured keywords. Keywords without matches are left out.
+type TodoIndex []TodoKeywordRefs
+
+// TodoIndexWriter is implemented by formatters that can render a TodoIndex.
+// In Todos mode, App calls WriteTodoIndex after WriteHeader and before the
+// first WriteFile. Formatters that do not implement it simply omit the index.
+type TodoIndexWriter interface {
+	WriteTodoIndex(ctx context.Context, index TodoIndex) error
+}
+
+// todoKeywords returns the configured keywords or the defaults.
+func (c *Config) todoKeywords() []string {
+	if len(c.TodoKeywords) == 0 {
+		return DefaultTodoKeywords
+	}
+
+	return c.TodoKeywords
+}
+
+// compileTodoPattern builds a regexp matching any keyword as a whole word and
+// capturing which one matched. A word boundary is required only on the
+// sides where the keyword starts or ends with a word character, so keywords
+// such as @todo, TODO:, or !!! match too.
+func compileTodoPattern(keywords []string) (*regexp.Regexp, error) {
+	quoted := make([]string, len(keywords))
+	for i, keyword := range keywords {
+		quoted[i] = regexp.QuoteMeta(keyword)
+		if keyword == "" {
+			continue
+		}
+		if isWordByte(keyword[0]) {
+			quoted[i] = `\b` + quoted[i]
+		}
+		if isWordByte(keyword[len(keyword)-1]) {
+			quoted[i] += `\b`
+		}
+	}
+
+	return regexp.Compile(`(` + strings.Join(quoted, "|") + `)`)
+}
+
+// isWordByte reports whether b is a word character as \b sees it.
+func isWordByte(b byte) bool {
+	return b == '_' || '0' <= b && b <= '9' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z'
+}
+
+// filterTodos keeps annotated lines, tagged with their keyword, plus context
+// lines on either side.
+func filterTodos(lines []string, pattern *regexp.Regexp, contextLines int) []FilteredLine {
+	keywords := make([]string, len(lines))
+	keep := make([]bool, len(lines))
+
+	for i, line := range lines {
+		match := pattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		keywords[i] = match[1]
+		for j := max(0, i-contextLines); j <= min(len(lines)-1, i+contextLines); j++ {
+			keep[j] = true
+		}
+	}
+
+	var result []FilteredLine
+	for i, line := range lines {
+		if keep[i] {
+			result = append(result, FilteredLine{
+				LineNumber: i + 1,
+				Content:    line,
+				Keyword:    keywords[i],
+			})
+		}
+	}
+
+	return result
+}
+
+// countTodos returns how many lines of file carry a keyword.
+func countTodos(file *ProcessedFile) int {
+	count := 0
+	for _, line := range file.Lines {
+		if line.Keyword != "" {
+			count++
+		}
+	}
+
+	return count
+}
+
+// BuildTodoIndex aggregates the annotated lines of files by keyword, ordered
+// like keywords.
+func BuildTodoIndex(files []ProcessedFile, keywords []string) TodoIndex {
+	refs := make(map[string][]TodoRef, len(keywords))
+	for _, file := range files {
+		for _, line := range file.Lines {
+			if line.Keyword != "" {
+				refs[line.Keyword] = append(refs[line.Keyword], TodoRef{
+					Path: file.Info.RelPath,
+					Line: line.LineNumber,
+				})
+			}
+		}
+	}
+
+	var index TodoIndex
+	for _, keyword := range keywords {
+		if len(refs[keyword]) > 0 {
+			index = append(index, TodoKeywordRefs{Keyword: keyword, Refs: refs[keyword]})
+		}
+	}
+
+	return index
+}
@@ -0,0 +1,143 @@
+package catls
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+func TestFilterTodos(t *testing.T) {
+	lines := []string{
+		"package main",        // 1
+		"",                    // 2
+		"// TODO: first",      // 3
+		"func a() {}",         // 4
+		"func b() {}",         // 5
+		"func c() {}",         // 6
+		"// FIXME(me) second", // 7
+		"// TODOS is not one", // 8
+		"// NOTE: custom",     // 9
+		"x := 1 // XXX tail",  // 10
+	}
+
+	tests := []struct {
+		name     string
+		keywords []string
+		context  int
+		want     string
+	}{
+		{
+			name: "default keywords without context",
+			want: "[3:TODO 7:FIXME 10:XXX]",
+		},
+		{
+			name:    "context merges overlapping ranges",
+			context: 1,
+			want:    "[2: 3:TODO 4: 6: 7:FIXME 8: 9: 10:XXX]",
+		},
+		{
+			name:     "custom keywords",
+			keywords: []string{"NOTE"},
+			want:     "[9:NOTE]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keywords := tt.keywords
+			if keywords == nil {
+				keywords = DefaultTodoKeywords
+			}
+			pattern, err := compileTodoPattern(keywords)
+			if err != nil {
+				t.Fatalf("compileTodoPattern() unexpected error: %v", err)
+			}
+
+			var got []string
+			for _, line := range filterTodos(lines, pattern, tt.context) {
+				got = append(got, fmt.Sprintf("%d:%s", line.LineNumber, line.Keyword))
+			}
+
+			if fmt.Sprint(got) != tt.want {
+				t.Errorf("filterTodos() = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileTodoPatternBoundaries(t *testing.T) {
+	pattern, err := compileTodoPattern([]string{"@todo", "TODO:", "!!!", "FIX"})
+	if err != nil {
+		t.Fatalf("compileTodoPattern() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"// @todo lowercase", "@todo"},
+		{"x@todo: glued on the left", "@todo"},
+		{"// TODO: colon", "TODO:"},
+		{"// NOTTODO: inside a word", ""},
+		{"// !!! urgent", "!!!"},
+		{"// FIX this", "FIX"},
+		{"// FIXME is another word", ""},
+		{"// @todos runs on", ""},
+	}
+
+	for _, tt := range tests {
+		got := ""
+		if match := pattern.FindStringSubmatch(tt.line); match != nil {
+			got = match[1]
+		}
+		if got != tt.want {
+			t.Errorf("match in %q = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestBuildTodoIndex(t *testing.T) {
+	files := []ProcessedFile{
+		{
+			Info:  scanner.FileInfo{RelPath: "a.go"},
+			Lines: []FilteredLine{{LineNumber: 2, Keyword: "FIXME"}, {LineNumber: 3}, {LineNumber: 4, Keyword: "TODO"}},
+		},
+		{
+			Info:  scanner.FileInfo{RelPath: "b.go"},
+			Lines: []FilteredLine{{LineNumber: 9, Keyword: "TODO"}},
+		},
+	}
+
+	index := BuildTodoIndex(files, DefaultTodoKeywords)
+
+	got := fmt.Sprint(index)
+	want := "[{TODO [{a.go 4} {b.go 9}]} {FIXME [{a.go 2}]}]"
+	if got != want {
+		t.Errorf("BuildTodoIndex() = %s, want %s", got, want)
+	}
+}
+
+func TestTodosSkipsFilesWithoutAnnotations(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"clean.go": "package clean\n",
+		"dirty.go": "package dirty\n\n// HACK: temporary\n",
+	})
+
+	output, app := runAndCapture(t, &Config{
+		Directory:    tmpDir,
+		RelativeTo:   tmpDir,
+		Todos:        true,
+		OutputFormat: OutputFormatMarkdown,
+	})
+
+	want := "# Todo index\n\n- **HACK**: `dirty.go:3`\n\n## dirty.go\n\n```go name=\"dirty.go\"\n// HACK: temporary\n```\n"
+	if output != want {
+		t.Errorf("output = %q\nwant     %q", output, want)
+	}
+
+	if stats := app.Stats(); stats.Files != 1 || stats.SkippedTodos != 1 {
+		t.Errorf("Stats() = %+v, want 1 file and 1 skipped", stats)
+	}
+}
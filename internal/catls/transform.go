@@ -0,0 +1,84 @@
+package catls
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// LineTransformer rewrites a single line after it is read and before content
+// filtering, so patterns match the cleaned text.
+type LineTransformer func(line string) string
+
+// lineTransformers returns the transformers enabled by cfg in the order they
+// run: carriage returns and escape sequences are removed before tabs are
+// expanded, so tab stops are computed on visible text, and trailing whitespace
+// is trimmed last.
+func lineTransformers(cfg *Config) []LineTransformer {
+	var transformers []LineTransformer
+
+	if cfg.NormalizeCRLF || cfg.Deterministic {
+		transformers = append(transformers, NormalizeCR)
+	}
+	if cfg.StripANSI {
+		transformers = append(transformers, StripANSI)
+	}
+	if cfg.ExpandTabs > 0 {
+		transformers = append(transformers, ExpandTabs(cfg.ExpandTabs))
+	}
+	if cfg.TrimTrailing {
+		transformers = append(transformers, TrimTrailing)
+	}
+
+	return transformers
+}
+
+// NormalizeCR resolves the carriage returns left in a line. The line reader
+// already splits on \r\n, so these are CR-based progress redraws: each one
+// returns to the start of the line, and the text after the last one that is
+// followed by any replaces what came before, as a terminal shows it.
+// Splitting there instead would renumber the lines after it.
+func NormalizeCR(line string) string {
+	line = strings.TrimRight(line, "\r")
+	if i := strings.LastIndexByte(line, '\r'); i >= 0 {
+		return line[i+1:]
+	}
+
+	return line
+}
+
+// StripANSI removes ANSI escape sequences such as colors and cursor movement.
+func StripANSI(line string) string {
+	return ansi.Strip(line)
+}
+
+// TrimTrailing removes trailing whitespace.
+func TrimTrailing(line string) string {
+	return strings.TrimRight(line, " \t\r\v\f")
+}
+
+// ExpandTabs returns a transformer that replaces tabs with spaces up to the
+// next multiple of width columns. Columns are counted in runes.
+func ExpandTabs(width int) LineTransformer {
+	return func(line string) string {
+		if !strings.Contains(line, "\t") {
+			return line
+		}
+
+		var b strings.Builder
+		column := 0
+		for _, r := range line {
+			if r == '\t' {
+				spaces := width - column%width
+				b.WriteString(strings.Repeat(" ", spaces))
+				column += spaces
+
+				continue
+			}
+			b.WriteRune(r)
+			column++
+		}
+
+		return b.String()
+	}
+}
@@ -0,0 +1,57 @@
+package catls
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+func TestLineTransformers(t *testing.T) {
+	tests := []struct {
+		name      string
+		transform LineTransformer
+		in        string
+		want      string
+	}{
+		{name: "expand tabs to stops", transform: ExpandTabs(4), in: "a\tbc\tdefg\th", want: "a   bc  defg    h"},
+		{name: "expand leading tabs", transform: ExpandTabs(2), in: "\t\tx", want: "    x"},
+		{name: "expand counts runes", transform: ExpandTabs(4), in: "é\tx", want: "é   x"},
+		{name: "strip color codes", transform: StripANSI, in: "\x1b[31merror\x1b[0m: \x1b[1mbad\x1b[0m", want: "error: bad"},
+		{name: "strip leaves plain text", transform: StripANSI, in: "plain [31m text", want: "plain [31m text"},
+		{name: "trim trailing", transform: TrimTrailing, in: "code \t  ", want: "code"},
+		{name: "trim keeps leading", transform: TrimTrailing, in: "  indented", want: "  indented"},
+		{name: "normalize bare CR", transform: NormalizeCR, in: "10%\r20%\rdone\r", want: "done"},
+		{name: "normalize keeps the last segment", transform: NormalizeCR, in: "a\rb", want: "b"},
+		{name: "normalize without CR", transform: NormalizeCR, in: "plain", want: "plain"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.transform(tt.in); got != tt.want {
+				t.Errorf("transform(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransformersRunBeforeFiltering(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "build.log")
+	content := "\x1b[32mok\x1b[0m step one  \r\n\x1b[31mFAIL\x1b[0m\tstep two\r\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write log: %v", err)
+	}
+
+	cfg := &Config{StripANSI: true, ExpandTabs: 8, TrimTrailing: true, NormalizeCRLF: true, ContentPatterns: []string{"FAIL    step two"}}
+	processor := NewFileProcessor(nil, lineTransformers(cfg)...)
+
+	processed := processor.ProcessFile(scanner.FileInfo{Path: path, RelPath: "build.log"}, NewFileFilter(cfg))
+	if processed.Error != nil {
+		t.Fatalf("ProcessFile() unexpected error: %v", processed.Error)
+	}
+
+	if len(processed.Lines) != 1 || processed.Lines[0].Content != "FAIL    step two" {
+		t.Errorf("ProcessFile() lines = %+v, want only the cleaned FAIL line", processed.Lines)
+	}
+}
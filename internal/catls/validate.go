@@ -0,0 +1,515 @@
+package catls
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/connerohnesorge/catls/internal/interactive"
+)
+
+// Validate checks the configuration for errors that would otherwise surface
+// partway through output. All problems are reported together.
+func (c *Config) Validate() error {
+	return errors.Join(
+		c.validateDirectory(),
+		c.validateOutputFormat(),
+		c.validateOutputDir(),
+		c.validateOutputTemplate(),
+		c.validateFormatOptions(),
+		c.validateLineNumberFormat(),
+		c.validatePatterns(),
+		c.validateFenceOptions(),
+		c.validateReadmeOptions(),
+		c.validateMaxFiles(),
+		c.validateMaxFilesPerDir(),
+		c.validateMaxTokens(),
+		c.validateTodoOptions(),
+		c.validateExpandTabs(),
+		c.validateEmbedImages(),
+		c.validateContentEncoding(),
+		c.validatePretty(),
+		c.validateExecutableFilters(),
+		c.validateTypes(),
+		c.validateDeterministic(),
+		c.validateTheme(),
+		c.validateTUITheme(),
+		c.validateSort(),
+		c.validateList(),
+		c.validateFrontMatter(),
+		c.validateFailFast(),
+		c.validateFold(),
+		c.validateIgnoreCmd(),
+		c.validateBlame(),
+		c.validateXattrs(),
+		c.validateSignatures(),
+		c.validateChunks(),
+		c.validateSizeBudgets(),
+		c.validateAllowlist(),
+		c.validateScanArtifact(),
+		c.validateGitIgnore(),
+		c.validateImports(),
+		c.validateThrottle(),
+		c.validateIntegrity(),
+	)
+}
+
+// validateDirectory requires Directory to be an existing directory.
+func (c *Config) validateDirectory() error {
+	info, err := os.Stat(c.Directory)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("directory '%s' does not exist", c.Directory)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot access directory '%s': %w", c.Directory, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("'%s' is a file, not a directory", c.Directory)
+	}
+
+	return nil
+}
+
+// validateOutputFormat requires every output format to be registered and
+// listed once.
+func (c *Config) validateOutputFormat() error {
+	var errs []error
+	seen := make(map[OutputFormat]bool)
+	for _, format := range c.formats() {
+		switch {
+		case !format.IsValid():
+			errs = append(errs, fmt.Errorf("unsupported output format: %s (supported: %s)",
+				format, strings.Join(GetSupportedFormats(), ", ")))
+		case seen[format]:
+			errs = append(errs, fmt.Errorf("output format %s is listed more than once", format))
+		}
+		seen[format] = true
+	}
+
+	if len(c.OutputFormats) > 0 && !slices.Contains(c.OutputFormats, c.OutputFormat) {
+		errs = append(errs, fmt.Errorf("output formats %s do not include the output format %s", c.formatList(), c.OutputFormat))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateOutputDir requires an output directory for several formats, since
+// they cannot share Output, and rejects a path that is not a directory.
+func (c *Config) validateOutputDir() error {
+	if len(c.OutputFormats) > 1 && c.OutputDir == "" {
+		return fmt.Errorf("writing several formats (%s) requires --output-dir", c.formatList())
+	}
+
+	if c.OutputDir == "" {
+		return nil
+	}
+	if info, err := os.Stat(c.OutputDir); err == nil && !info.IsDir() {
+		return fmt.Errorf("--output-dir '%s' is a file, not a directory", c.OutputDir)
+	}
+
+	return nil
+}
+
+// validateLineNumberFormat accepts an empty format, which means the default preset.
+func (c *Config) validateLineNumberFormat() error {
+	if c.LineNumberFormat == "" || c.LineNumberFormat.IsValid() {
+		return nil
+	}
+
+	return fmt.Errorf("unsupported line number format: %s (supported: %s)",
+		c.LineNumberFormat, strings.Join(GetSupportedLineNumberFormats(), ", "))
+}
+
+// validatePatterns ensures every content pattern and glob compiles.
+func (c *Config) validatePatterns() error {
+	var errs []error
+
+	for _, pattern := range c.ContentPatterns {
+		if _, err := compileContentPattern(pattern); err != nil {
+			errs = append(errs, fmt.Errorf("invalid --pattern %q: %w", pattern, err))
+		}
+	}
+	if c.PatternAll && len(c.ContentPatterns) == 0 {
+		errs = append(errs, errors.New("--pattern-all requires --pattern"))
+	}
+	if c.MaxMatches < 0 {
+		errs = append(errs, fmt.Errorf("--max-matches must not be negative, got %d", c.MaxMatches))
+	} else if c.MaxMatches > 0 && len(c.ContentPatterns) == 0 {
+		errs = append(errs, errors.New("--max-matches requires --pattern"))
+	}
+
+	for _, pattern := range c.PathRegex {
+		if _, err := regexp.Compile(pattern); err != nil {
+			errs = append(errs, fmt.Errorf("invalid --path-regex %q: %w", pattern, err))
+		}
+	}
+	for _, pattern := range c.ExcludePathRegex {
+		if _, err := regexp.Compile(pattern); err != nil {
+			errs = append(errs, fmt.Errorf("invalid --exclude-path-regex %q: %w", pattern, err))
+		}
+	}
+
+	if _, err := expandGlobs("--globs", c.Globs); err != nil {
+		errs = append(errs, err)
+	}
+
+	if _, err := expandGlobs("--ignore-globs", c.IgnoreGlobs); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateFormatOptions builds a throwaway formatter per selected format so
+// each rejects unknown --format-opt keys and bad values before any output.
+func (c *Config) validateFormatOptions() error {
+	if len(c.FormatOptions) == 0 {
+		return nil
+	}
+
+	var errs []error
+	for _, format := range c.formats() {
+		if !format.IsValid() {
+			continue
+		}
+
+		opts, err := c.formatOptions(format)
+		if err == nil {
+			_, err = NewOutputFormatter(format, io.Discard, opts)
+		}
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateFenceOptions only allows fence options with Markdown output, where they
+// have an effect, and only allows a sentinel when content is not fenced.
+func (c *Config) validateFenceOptions() error {
+	if c.FenceStyle == "" && c.Sentinel == "" {
+		return nil
+	}
+
+	if !c.writesFormat(OutputFormatMarkdown) {
+		return fmt.Errorf("--fence-style and --sentinel only apply to markdown output, not %s", c.formatList())
+	}
+
+	if c.FenceStyle != "" && !c.FenceStyle.IsValid() {
+		return fmt.Errorf("unsupported fence style: %s (supported: %s)",
+			c.FenceStyle, strings.Join(GetSupportedFenceStyles(), ", "))
+	}
+
+	if c.Sentinel != "" && effectiveFenceStyle(c) != FenceStyleNone {
+		return fmt.Errorf("--sentinel requires --fence-style none, not %s", c.FenceStyle)
+	}
+
+	return nil
+}
+
+// validateEmbedImages only allows a non-negative image size cap with Markdown
+// output, the only format that can render an embedded image.
+func (c *Config) validateEmbedImages() error {
+	if c.EmbedImages < 0 {
+		return fmt.Errorf("--embed-images must not be negative, got %d", c.EmbedImages)
+	}
+
+	if c.EmbedImages > 0 && !c.writesFormat(OutputFormatMarkdown) {
+		return fmt.Errorf("--embed-images only applies to markdown output, not %s", c.formatList())
+	}
+
+	return nil
+}
+
+// validateContentEncoding requires a known content encoding, with base64
+// only for JSON and XML output, the formats that can carry the bytes, and a
+// non-negative raw content cap only with base64.
+func (c *Config) validateContentEncoding() error {
+	if !c.ContentEncoding.IsValid() {
+		return fmt.Errorf("unsupported content encoding: %s (supported: %s)",
+			c.ContentEncoding, strings.Join(GetSupportedContentEncodings(), ", "))
+	}
+	if c.RawContentMax < 0 {
+		return fmt.Errorf("--raw-max-size must not be negative, got %d", c.RawContentMax)
+	}
+
+	if c.ContentEncoding != ContentEncodingBase64 {
+		if c.RawContentMax > 0 {
+			return errors.New("--raw-max-size requires --content-encoding base64")
+		}
+
+		return nil
+	}
+	if !c.writesFormat(OutputFormatJSON) && !c.writesFormat(OutputFormatXML) {
+		return fmt.Errorf("--content-encoding base64 only applies to json and xml output, not %s", c.formatList())
+	}
+
+	return nil
+}
+
+// validatePretty requires a non-negative reformatting cap, and one only with
+// PrettyJSON or PrettyYAML.
+func (c *Config) validatePretty() error {
+	if c.PrettyMaxSize < 0 {
+		return fmt.Errorf("--pretty-max-size must not be negative, got %d", c.PrettyMaxSize)
+	}
+	if c.PrettyMaxSize > 0 && !c.PrettyJSON && !c.PrettyYAML {
+		return errors.New("--pretty-max-size requires --pretty-json or --pretty-yaml")
+	}
+
+	return nil
+}
+
+// validateExecutableFilters rejects asking for only executables and no executables.
+func (c *Config) validateExecutableFilters() error {
+	if c.OnlyExecutable && c.NoExecutable {
+		return errors.New("--only-executable cannot be combined with --no-executable")
+	}
+
+	return nil
+}
+
+// validateTypes requires every --type and --exclude-type to name a known
+// type, and every --lang-map entry to map an extension to a type.
+func (c *Config) validateTypes() error {
+	known := c.knownFileTypes()
+
+	var errs []error
+	check := func(flag string, types []string) {
+		for _, fileType := range types {
+			if !slices.Contains(known, fileType) {
+				errs = append(errs, fmt.Errorf("unknown %s %q (known: %s)", flag, fileType, strings.Join(known, ", ")))
+			}
+		}
+	}
+	check("--type", c.Types)
+	check("--exclude-type", c.ExcludeTypes)
+
+	for _, ext := range slices.Sorted(maps.Keys(c.LangMap)) {
+		if fileType := c.LangMap[ext]; ext == "" || fileType == "" || fileType == TypeUnknown || strings.ContainsAny(ext, `./\`) {
+			errs = append(errs, fmt.Errorf("invalid --lang-map %s=%s: expected ext=lang", ext, fileType))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateDeterministic rejects options that reorder files away from strict
+// path order or depend on a person at the terminal.
+func (c *Config) validateDeterministic() error {
+	if !c.Deterministic {
+		return nil
+	}
+
+	var conflicts []string
+	if c.ReadmeFirst {
+		conflicts = append(conflicts, "--readme-first")
+	}
+	if c.Order {
+		conflicts = append(conflicts, "--order")
+	}
+	if c.Interactive {
+		conflicts = append(conflicts, "--interactive")
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("--deterministic cannot be combined with %s", strings.Join(conflicts, ", "))
+}
+
+// validateReadmeOptions requires a non-negative line limit that only accompanies ReadmeFirst.
+func (c *Config) validateReadmeOptions() error {
+	if c.ReadmeLines < 0 {
+		return fmt.Errorf("--readme-lines must not be negative, got %d", c.ReadmeLines)
+	}
+
+	if c.ReadmeLines > 0 && !c.ReadmeFirst {
+		return errors.New("--readme-lines requires --readme-first")
+	}
+
+	return nil
+}
+
+// validateMaxFiles rejects a negative file or depth limit.
+func (c *Config) validateMaxFiles() error {
+	if c.MaxFiles < 0 {
+		return fmt.Errorf("--max-files must not be negative, got %d", c.MaxFiles)
+	}
+	if c.MaxDepth < 0 {
+		return fmt.Errorf("--max-depth must not be negative, got %d", c.MaxDepth)
+	}
+
+	return nil
+}
+
+// validateMaxFilesPerDir rejects a negative per-directory limit.
+func (c *Config) validateMaxFilesPerDir() error {
+	if c.MaxFilesPerDir < 0 {
+		return fmt.Errorf("--max-files-per-dir must not be negative, got %d", c.MaxFilesPerDir)
+	}
+
+	return nil
+}
+
+// validateMaxTokens rejects a negative token budget.
+func (c *Config) validateMaxTokens() error {
+	if c.MaxTokens < 0 {
+		return fmt.Errorf("--max-tokens must not be negative, got %d", c.MaxTokens)
+	}
+
+	return nil
+}
+
+// validateTodoOptions checks the Todos settings and rejects combining Todos with
+// a content pattern, since both decide which lines are shown.
+func (c *Config) validateTodoOptions() error {
+	if c.TodoContext < 0 {
+		return fmt.Errorf("--todo-context must not be negative, got %d", c.TodoContext)
+	}
+
+	if !c.Todos {
+		if c.TodoContext > 0 {
+			return errors.New("--todo-context requires --todos")
+		}
+
+		return nil
+	}
+
+	if len(c.ContentPatterns) > 0 {
+		return errors.New("--todos cannot be combined with --pattern")
+	}
+
+	for _, keyword := range c.TodoKeywords {
+		if strings.TrimSpace(keyword) == "" {
+			return errors.New("--todo-keywords must not contain empty keywords")
+		}
+	}
+
+	return nil
+}
+
+// validateExpandTabs rejects a negative tab width.
+func (c *Config) validateExpandTabs() error {
+	if c.ExpandTabs < 0 {
+		return fmt.Errorf("--expand-tabs must not be negative, got %d", c.ExpandTabs)
+	}
+
+	return nil
+}
+
+// validateTheme only allows a theme with pretty output, where it has an
+// effect, and requires it to name a known theme.
+func (c *Config) validateTheme() error {
+	if c.Theme == "" {
+		return nil
+	}
+
+	if !c.writesFormat(OutputFormatPretty) {
+		return fmt.Errorf("--theme only applies to pretty output, not %s", c.formatList())
+	}
+	if !slices.Contains(GetSupportedThemes(), c.Theme) {
+		return fmt.Errorf("unknown theme: %s (supported: %s)", c.Theme, strings.Join(GetSupportedThemes(), ", "))
+	}
+
+	return nil
+}
+
+// validateTUITheme requires a known TUI theme.
+func (c *Config) validateTUITheme() error {
+	if !c.TUITheme.IsValid() {
+		return fmt.Errorf("unknown TUI theme: %s (supported: %s)",
+			c.TUITheme, strings.Join(interactive.GetSupportedThemes(), ", "))
+	}
+
+	return nil
+}
+
+// validateSort requires a known sort order.
+func (c *Config) validateSort() error {
+	if !c.Sort.IsValid() {
+		return fmt.Errorf("unsupported sort order: %s (supported: %s)",
+			c.Sort, strings.Join(GetSupportedSortOrders(), ", "))
+	}
+
+	return nil
+}
+
+// validateList requires List for Print0 and rejects combining List with
+// settings that shape file content or formatted output, which a path listing
+// would silently ignore, and with the selection TUIs, which draw on stdout.
+func (c *Config) validateList() error {
+	if !c.List {
+		if c.Print0 {
+			return errors.New("--print0 requires --list")
+		}
+
+		return nil
+	}
+
+	var conflicts []string
+	for _, option := range []struct {
+		set  bool
+		flag string
+	}{
+		{c.Interactive, "--interactive"},
+		{c.Order, "--order"},
+		{c.ShowLineNumbers, "--line-numbers"},
+		{c.LineNumberFormat != "" && c.LineNumberFormat != LineNumberFormatPipe, "--line-number-format"}, // pipe is the default
+		{len(c.ContentPatterns) > 0, "--pattern"},
+		{c.Todos, "--todos"},
+		{c.BlameSince != "", "--blame-since"},
+		{c.Xattrs, "--xattrs"},
+		{c.FenceStyle != "", "--fence-style"},
+		{c.Sentinel != "", "--sentinel"},
+		{c.ReadmeLines > 0, "--readme-lines"},
+		{c.ExpandTabs > 0, "--expand-tabs"},
+		{c.StripANSI, "--strip-ansi"},
+		{c.TrimTrailing, "--trim-trailing"},
+		{c.NormalizeCRLF, "--normalize-crlf"},
+		{len(c.FormatOptions) > 0, "--format-opt"},
+		{c.EmbedImages > 0, "--embed-images"},
+		{c.ContentEncoding == ContentEncodingBase64, "--content-encoding"},
+		{c.RawContentMax > 0, "--raw-max-size"},
+		{c.PrettyJSON, "--pretty-json"},
+		{c.PrettyYAML, "--pretty-yaml"},
+		{c.PrettyMaxSize > 0, "--pretty-max-size"},
+		{c.LegacyTruncation, "--legacy-truncation"},
+		{c.MaxTokens > 0, "--max-tokens"},
+		{c.MaxFilesPerDir > 0, "--max-files-per-dir"},
+		{c.ProjectHeader, "--project-header"},
+		{c.DedupeContent, "--dedupe-content"},
+		{c.FrontMatterOnly, "--front-matter-only"},
+		{c.StripFrontMatter, "--strip-front-matter"},
+		{c.CrossLink, "--cross-link"},
+		{c.OutputDir != "", "--output-dir"},
+		{c.ManifestPath != "", "--manifest"},
+		{len(c.OutputFormats) > 1, "--format"},
+		{c.Theme != "", "--theme"},
+		{c.RelativeTo != "", "--relative-to"},
+		{c.Signatures, "--signatures"},
+		{len(c.SizeBudgets) > 0, "--budget"},
+	} {
+		if option.set {
+			conflicts = append(conflicts, option.flag)
+		}
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("--list writes only paths and cannot be combined with %s", strings.Join(conflicts, ", "))
+}
+
+// validateFrontMatter rejects keeping only the front matter and removing it.
+func (c *Config) validateFrontMatter() error {
+	if c.FrontMatterOnly && c.StripFrontMatter {
+		return errors.New("--front-matter-only cannot be combined with --strip-front-matter")
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+package catls
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+// VerifyReport lists how the files selected now differ from a manifest.
+type VerifyReport struct {
+	Manifest  string   `json:"manifest"`
+	Added     []string `json:"added"`   // Selected now but not in the manifest
+	Removed   []string `json:"removed"` // In the manifest but no longer selected
+	Changed   []string `json:"changed"` // In both, with a different hash
+	Unchanged int      `json:"unchanged"`
+}
+
+// Differences returns the number of added, removed, and changed files.
+func (r VerifyReport) Differences() int {
+	return len(r.Added) + len(r.Removed) + len(r.Changed)
+}
+
+// DiffManifests compares the current manifest with the recorded one. Files
+// are matched by path and compared by hash; a changed modification time alone
+// is not a difference. Paths keep the order of the manifest they come from.
+func DiffManifests(recorded, current Manifest) VerifyReport {
+	report := VerifyReport{Added: []string{}, Removed: []string{}, Changed: []string{}}
+
+	hashes := make(map[string]string, len(current.Files))
+	for _, file := range current.Files {
+		hashes[file.Path] = file.SHA256
+	}
+
+	seen := make(map[string]bool, len(recorded.Files))
+	for _, file := range recorded.Files {
+		seen[file.Path] = true
+		hash, ok := hashes[file.Path]
+		switch {
+		case !ok:
+			report.Removed = append(report.Removed, file.Path)
+		case hash != file.SHA256:
+			report.Changed = append(report.Changed, file.Path)
+		default:
+			report.Unchanged++
+		}
+	}
+	for _, file := range current.Files {
+		if !seen[file.Path] {
+			report.Added = append(report.Added, file.Path)
+		}
+	}
+
+	return report
+}
+
+// WriteVerifyReport renders report in the given format. An empty format
+// writes a short summary for people: one line per difference and a total.
+func WriteVerifyReport(w io.Writer, format OutputFormat, report VerifyReport) error {
+	var b strings.Builder
+
+	switch format {
+	case "":
+		writeVerifySummary(&b, report)
+	case OutputFormatXML:
+		writeVerifyXML(&b, report)
+	case OutputFormatJSON, OutputFormatChunks:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(report)
+	case OutputFormatMarkdown, OutputFormatPrompt, OutputFormatPretty:
+		writeVerifyMarkdown(&b, report)
+	default:
+		return fmt.Errorf("verify does not support output format: %s", format)
+	}
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+func writeVerifySummary(b *strings.Builder, report VerifyReport) {
+	for _, group := range verifyGroups(report) {
+		for _, path := range group.paths {
+			fmt.Fprintf(b, "%-7s  %s\n", group.name, path)
+		}
+	}
+
+	if report.Differences() == 0 {
+		fmt.Fprintf(b, "OK: %d files match %s\n", report.Unchanged, report.Manifest)
+
+		return
+	}
+	fmt.Fprintf(b, "%d differences from %s: %d added, %d removed, %d changed, %d unchanged\n",
+		report.Differences(), report.Manifest, len(report.Added), len(report.Removed), len(report.Changed), report.Unchanged)
+}
+
+func writeVerifyXML(b *strings.Builder, report VerifyReport) {
+	fmt.Fprintf(b, "<verify manifest=\"%s\" unchanged=\"%d\">\n", html.EscapeString(report.Manifest), report.Unchanged)
+	for _, group := range verifyGroups(report) {
+		for _, path := range group.paths {
+			fmt.Fprintf(b, "<%s path=\"%s\"/>\n", group.name, html.EscapeString(path))
+		}
+	}
+	b.WriteString("</verify>\n")
+}
+
+func writeVerifyMarkdown(b *strings.Builder, report VerifyReport) {
+	b.WriteString("# Verify\n\n")
+	fmt.Fprintf(b, "- Manifest: %s\n", report.Manifest)
+	fmt.Fprintf(b, "- Added: %d\n", len(report.Added))
+	fmt.Fprintf(b, "- Removed: %d\n", len(report.Removed))
+	fmt.Fprintf(b, "- Changed: %d\n", len(report.Changed))
+	fmt.Fprintf(b, "- Unchanged: %d\n", report.Unchanged)
+
+	if report.Differences() > 0 {
+		b.WriteString("\n## Differences\n\n| Change | Path |\n| --- | --- |\n")
+		for _, group := range verifyGroups(report) {
+			for _, path := range group.paths {
+				fmt.Fprintf(b, "| %s | %s |\n", group.name, path)
+			}
+		}
+	}
+}
+
+// verifyGroup is one kind of difference and the paths that have it.
+type verifyGroup struct {
+	name  string
+	paths []string
+}
+
+func verifyGroups(report VerifyReport) []verifyGroup {
+	return []verifyGroup{
+		{name: "added", paths: report.Added},
+		{name: "removed", paths: report.Removed},
+		{name: "changed", paths: report.Changed},
+	}
+}
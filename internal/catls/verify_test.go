@@ -0,0 +1,166 @@
+package catls
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffManifests(t *testing.T) {
+	recorded := Manifest{Files: []ManifestFile{
+		{Path: "a.go", SHA256: "aa"},
+		{Path: "b.go", SHA256: "bb"},
+		{Path: "c.go", SHA256: "cc"},
+	}}
+	current := Manifest{Files: []ManifestFile{
+		{Path: "b.go", SHA256: "b2"},
+		{Path: "c.go", SHA256: "cc"},
+		{Path: "d.go", SHA256: "dd"},
+	}}
+
+	report := DiffManifests(recorded, current)
+	want := VerifyReport{Added: []string{"d.go"}, Removed: []string{"a.go"}, Changed: []string{"b.go"}, Unchanged: 1}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("DiffManifests() = %+v, want %+v", report, want)
+	}
+	if report.Differences() != 3 {
+		t.Errorf("Differences() = %d, want 3", report.Differences())
+	}
+
+	if clean := DiffManifests(recorded, recorded); clean.Differences() != 0 || clean.Unchanged != 3 {
+		t.Errorf("DiffManifests() of identical manifests = %+v, want 3 unchanged", clean)
+	}
+}
+
+func TestWriteVerifyReport(t *testing.T) {
+	report := VerifyReport{Manifest: "m.json", Added: []string{"new.go"}, Removed: []string{}, Changed: []string{"a&b.go"}, Unchanged: 2}
+
+	tests := []struct {
+		format OutputFormat
+		want   []string
+	}{
+		{format: "", want: []string{"added    new.go\n", "changed  a&b.go\n", "2 differences from m.json: 1 added, 0 removed, 1 changed, 2 unchanged\n"}},
+		{format: OutputFormatXML, want: []string{`<verify manifest="m.json" unchanged="2">`, `<added path="new.go"/>`, `<changed path="a&amp;b.go"/>`}},
+		{format: OutputFormatMarkdown, want: []string{"- Changed: 1\n", "| added | new.go |\n"}},
+		{format: OutputFormatJSON, want: []string{`"added": [`, `"removed": []`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteVerifyReport(&buf, tt.format, report); err != nil {
+				t.Fatalf("WriteVerifyReport() error = %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("output = %q, want %q", buf.String(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestRunWritesManifest(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"src/main.go": "package main\n", "notes.txt": "notes\n"})
+	path := filepath.Join(t.TempDir(), "manifest.json")
+
+	cfg := &Config{Directory: dir, Recursive: true, Globs: []string{"*.go"}, OutputFormat: OutputFormatXML, Output: &bytes.Buffer{}, ManifestPath: path}
+	app, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("manifest not written: %v", err)
+	}
+	var written Manifest
+	if err := json.Unmarshal(data, &written); err != nil {
+		t.Fatalf("manifest is not JSON: %v", err)
+	}
+	sum := sha256.Sum256([]byte("package main\n"))
+	if len(written.Files) != 1 || written.Files[0].Path != "src/main.go" || written.Files[0].SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("manifest files = %+v, want only src/main.go with its hash", written.Files)
+	}
+	if !written.Config.Recursive || !reflect.DeepEqual(written.Config.Globs, []string{"*.go"}) {
+		t.Errorf("manifest config = %+v, want the recursive *.go selection", written.Config)
+	}
+
+	recorded, err := ReadManifest(path)
+	if err != nil {
+		t.Fatalf("ReadManifest() error = %v", err)
+	}
+	cfg = recorded.Config.Config()
+	cfg.OutputFormat = OutputFormatXML
+	replay, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	current, err := replay.BuildManifest(context.Background())
+	if err != nil {
+		t.Fatalf("BuildManifest() error = %v", err)
+	}
+	if report := DiffManifests(recorded, current); report.Differences() != 0 {
+		t.Errorf("replaying the recorded config found differences: %+v", report)
+	}
+}
+
+// editingWriter rewrites path once output holding marker is written, as
+// another process editing the file mid-run would.
+type editingWriter struct {
+	path, marker string
+	edited       bool
+}
+
+func (w *editingWriter) Write(p []byte) (int, error) {
+	if !w.edited && bytes.Contains(p, []byte(w.marker)) {
+		w.edited = true
+		if err := os.WriteFile(w.path, []byte("package edited\n"), 0o644); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+func TestManifestHashesWrittenContent(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"main.go": "package main\n"})
+	path := filepath.Join(t.TempDir(), "manifest.json")
+
+	out := &editingWriter{path: filepath.Join(dir, "main.go"), marker: "package main"}
+	cfg := &Config{Directory: dir, OutputFormat: OutputFormatXML, Output: out, ManifestPath: path}
+	app, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := app.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !out.edited {
+		t.Fatal("the file was not edited during the run")
+	}
+
+	written, err := ReadManifest(path)
+	if err != nil {
+		t.Fatalf("ReadManifest() error = %v", err)
+	}
+	sum := sha256.Sum256([]byte("package main\n"))
+	if len(written.Files) != 1 || written.Files[0].SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("manifest files = %+v, want the hash of the content written", written.Files)
+	}
+	if !filepath.IsAbs(written.Config.Directory) {
+		t.Errorf("manifest directory = %q, want an absolute path", written.Config.Directory)
+	}
+}
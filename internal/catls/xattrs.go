@@ -0,0 +1,42 @@
+package catls
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/connerohnesorge/catls/internal/longpath"
+	"github.com/connerohnesorge/catls/internal/xattr"
+)
+
+// recordXattrs lists the names of the extended attributes of file for
+// Xattrs. A file whose attributes cannot be listed is written without them,
+// with a warning.
+func (a *App) recordXattrs(file *ProcessedFile) {
+	if a.processor.listXattrs == nil || file.Error != nil {
+		return
+	}
+
+	names, err := a.processor.listXattrs(longpath.Fix(file.Info.Path))
+	if err != nil {
+		fmt.Fprintf(a.warn, "Warning: Cannot list extended attributes of %s: %v\n", file.Info.RelPath, err)
+
+		return
+	}
+	file.Xattrs = names
+}
+
+// validateXattrs requires a platform with extended attributes and an output
+// that records them: json or xml, or the manifest.
+func (c *Config) validateXattrs() error {
+	if !c.Xattrs {
+		return nil
+	}
+	if !xattr.Supported {
+		return fmt.Errorf("--xattrs is not supported on %s", runtime.GOOS)
+	}
+	if !c.writesFormat(OutputFormatJSON) && !c.writesFormat(OutputFormatXML) && c.ManifestPath == "" {
+		return fmt.Errorf("--xattrs only applies to json and xml output or --manifest, not %s", c.formatList())
+	}
+
+	return nil
+}
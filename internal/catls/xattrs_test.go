@@ -0,0 +1,82 @@
+package catls
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestXattrs(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeTree(t, tmpDir, map[string]string{
+		"downloaded.sh": "echo hi",
+		"plain.txt":     "plain",
+		"locked.txt":    "locked",
+	})
+
+	// A fake lister stands in for the platform, so this runs everywhere
+	fake := func(path string) ([]string, error) {
+		switch filepath.Base(path) {
+		case "downloaded.sh":
+			return []string{"com.apple.provenance", "com.apple.quarantine"}, nil
+		case "locked.txt":
+			return nil, errors.New("permission denied")
+		}
+
+		return nil, nil
+	}
+	run := func(format OutputFormat) string {
+		t.Helper()
+		var buf bytes.Buffer
+		app, err := New(&Config{Directory: tmpDir, OutputFormat: format, Output: &buf})
+		if err != nil {
+			t.Fatalf("New() unexpected error: %v", err)
+		}
+		app.processor.listXattrs = fake
+		if err := app.Run(context.Background()); err != nil {
+			t.Fatalf("Run() unexpected error: %v", err)
+		}
+
+		return buf.String()
+	}
+
+	var doc struct {
+		Files []JSONFile `json:"files"`
+	}
+	if err := json.Unmarshal([]byte(run(OutputFormatJSON)), &doc); err != nil {
+		t.Fatalf("output is not JSON: %v", err)
+	}
+	got := map[string]string{}
+	for _, file := range doc.Files {
+		got[file.Path] = strings.Join(file.Xattrs, ",")
+	}
+	want := map[string]string{"downloaded.sh": "com.apple.provenance,com.apple.quarantine", "plain.txt": "", "locked.txt": ""}
+	for path, names := range want {
+		if got[path] != names {
+			t.Errorf("xattrs of %s = %q, want %q", path, got[path], names)
+		}
+	}
+
+	xml := run(OutputFormatXML)
+	if !strings.Contains(xml, "<xattrs>\n") || !strings.Contains(xml, "<xattr>com.apple.quarantine</xattr>") {
+		t.Errorf("XML output is missing the attribute names:\n%s", xml)
+	}
+	if strings.Count(xml, "<xattrs>") != 1 {
+		t.Errorf("XML output lists attributes for files without any:\n%s", xml)
+	}
+}
+
+func TestValidateXattrs(t *testing.T) {
+	cfg := &Config{Directory: t.TempDir(), OutputFormat: OutputFormatMarkdown, Xattrs: true}
+	if err := cfg.validateXattrs(); err == nil {
+		t.Error("validateXattrs() accepted markdown output without a manifest")
+	}
+	cfg.ManifestPath = filepath.Join(cfg.Directory, "manifest.json")
+	if err := cfg.validateXattrs(); err != nil && !strings.Contains(err.Error(), "not supported") {
+		t.Errorf("validateXattrs() unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,232 @@
+// Package contentcache holds the lines of recently read files so a file
+// previewed by the interactive selector is not read again when it is
+// processed, and files unchanged between the requests of catls serve are
+// read once.
+package contentcache
+
+import (
+	"bufio"
+	"container/list"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/connerohnesorge/catls/internal/fdlimit"
+)
+
+const (
+	// FullFileLimit is the largest file whose every line is cached. Larger
+	// files only have their first PreviewLines lines cached, which is enough
+	// for a preview but not for processing.
+	FullFileLimit = 256 << 10
+	// PreviewLines is how many lines are cached for files over FullFileLimit.
+	PreviewLines = 200
+	// DefaultMaxBytes is the default memory cap of a cache.
+	DefaultMaxBytes = 32 << 20
+	// lineOverhead approximates the memory a cached line costs beyond its bytes.
+	lineOverhead = 16
+)
+
+// Cache is an LRU cache of file lines keyed by path and validated against the
+// file's size and modification time. It is safe for concurrent use, and
+// lines are copied in and out, so evicting or replacing an entry never
+// changes lines a caller holds. A nil *Cache caches nothing.
+type Cache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	used     int64
+	order    *list.List // Front is most recently used
+	entries  map[string]*list.Element
+	stats    Stats
+}
+
+// Stats counts the lookups a cache served and what it holds.
+type Stats struct {
+	Hits      int64 // Lookups served from the cache
+	Misses    int64 // Lookups of files not cached, or cached at another size or time
+	Evictions int64 // Entries dropped to stay under the memory cap
+	Files     int   // Files cached now
+	Bytes     int64 // Approximate memory the cached lines take
+}
+
+// String summarizes s on one line.
+func (s Stats) String() string {
+	return fmt.Sprintf("%d hits, %d misses, %d evicted; %d files, %d bytes cached", s.Hits, s.Misses, s.Evictions, s.Files, s.Bytes)
+}
+
+// entry is the cached content of one file.
+type entry struct {
+	path     string
+	size     int64
+	modTime  time.Time
+	lines    []string
+	complete bool // lines hold the whole file
+	cost     int64
+}
+
+// New creates a cache that evicts least recently used files once their lines
+// take more than maxBytes.
+func New(maxBytes int64) *Cache {
+	return &Cache{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// Load returns the leading lines of path, reading the file unless an entry
+// for its current size and modification time is cached. complete reports
+// whether lines hold the whole file. Lines are split like bufio.ScanLines.
+func (c *Cache) Load(path string) (lines []string, complete bool, err error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if e, ok := c.lookup(path, info.Size(), info.ModTime()); ok {
+		return e.lines, e.complete, nil
+	}
+
+	complete = info.Size() <= FullFileLimit
+	maxLines := -1
+	if !complete {
+		maxLines = PreviewLines
+	}
+	lines, err = readLines(path, maxLines)
+	if err != nil {
+		return nil, false, err
+	}
+
+	c.store(&entry{path: path, size: info.Size(), modTime: info.ModTime(), lines: slices.Clone(lines), complete: complete})
+
+	return lines, complete, nil
+}
+
+// Lookup returns every line of path if the whole file is cached for the given
+// size and modification time. Entries for another size or time are dropped.
+func (c *Cache) Lookup(path string, size int64, modTime time.Time) ([]string, bool) {
+	e, ok := c.lookup(path, size, modTime)
+	if !ok || !e.complete {
+		return nil, false
+	}
+
+	return e.lines, true
+}
+
+// Store caches lines as every line of path at the given size and
+// modification time, which the caller read before the lines. Lines larger
+// than the whole cache are not stored.
+func (c *Cache) Store(path string, size int64, modTime time.Time, lines []string) {
+	if c == nil {
+		return
+	}
+
+	c.store(&entry{path: path, size: size, modTime: modTime, lines: slices.Clone(lines), complete: true})
+}
+
+// Stats returns the counters of c; a nil cache has none.
+func (c *Cache) Stats() Stats {
+	if c == nil {
+		return Stats{}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Files, stats.Bytes = len(c.entries), c.used
+
+	return stats
+}
+
+// lookup returns a copy of the entry for path if it matches size and
+// modTime, marking it most recently used. A stale entry is removed.
+func (c *Cache) lookup(path string, size int64, modTime time.Time) (entry, bool) {
+	if c == nil {
+		return entry{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[path]
+	if !ok {
+		c.stats.Misses++
+
+		return entry{}, false
+	}
+
+	e := elem.Value.(*entry)
+	if e.size != size || !e.modTime.Equal(modTime) {
+		c.stats.Misses++
+		c.remove(elem)
+
+		return entry{}, false
+	}
+	c.stats.Hits++
+	c.order.MoveToFront(elem)
+
+	found := *e
+	found.lines = slices.Clone(e.lines)
+
+	return found, true
+}
+
+// store caches e, evicting least recently used entries to stay under
+// maxBytes. Entries larger than the whole cache are not stored.
+func (c *Cache) store(e *entry) {
+	if c == nil {
+		return
+	}
+
+	for _, line := range e.lines {
+		e.cost += int64(len(line)) + lineOverhead
+	}
+	if e.cost > c.maxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[e.path]; ok {
+		c.remove(elem)
+	}
+	for c.used+e.cost > c.maxBytes {
+		c.remove(c.order.Back())
+		c.stats.Evictions++
+	}
+
+	c.entries[e.path] = c.order.PushFront(e)
+	c.used += e.cost
+}
+
+// remove drops elem from the cache. The caller holds c.mu.
+func (c *Cache) remove(elem *list.Element) {
+	e := c.order.Remove(elem).(*entry)
+	delete(c.entries, e.path)
+	c.used -= e.cost
+}
+
+// readLines reads up to maxLines lines of path (all lines if maxLines < 0).
+func readLines(path string, maxLines int) ([]string, error) {
+	file, err := fdlimit.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	var lines []string
+	sc := bufio.NewScanner(file)
+	for (maxLines < 0 || len(lines) < maxLines) && sc.Scan() {
+		lines = append(lines, sc.Text())
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	return lines, nil
+}
@@ -0,0 +1,240 @@
+package contentcache
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, content string, modTime time.Time) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("failed to set times of %s: %v", path, err)
+	}
+}
+
+func TestLoadAndLookup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.txt")
+	modTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	writeFile(t, path, "one\r\ntwo\n", modTime)
+
+	c := New(DefaultMaxBytes)
+	lines, complete, err := c.Load(path)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if strings.Join(lines, "|") != "one|two" || !complete {
+		t.Errorf("Load() = %q, complete %v", lines, complete)
+	}
+
+	// Same size and time: served from the cache even though the bytes changed
+	writeFile(t, path, "ONE\r\nTWO\n", modTime)
+	if got, ok := c.Lookup(path, 9, modTime); !ok || got[0] != "one" {
+		t.Errorf("Lookup() = %q, %v, want the cached lines", got, ok)
+	}
+
+	// A new modification time invalidates the entry
+	if _, ok := c.Lookup(path, 9, modTime.Add(time.Second)); ok {
+		t.Error("Lookup() served an entry with a different modification time")
+	}
+	if _, ok := c.Lookup(path, 9, modTime); ok {
+		t.Error("stale entry was not dropped")
+	}
+}
+
+func TestLoadLargeFileIsPartial(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "big.txt")
+	line := strings.Repeat("x", 1023) + "\n"
+	modTime := time.Now()
+	writeFile(t, path, strings.Repeat(line, FullFileLimit/len(line)+1), modTime)
+
+	c := New(DefaultMaxBytes)
+	lines, complete, err := c.Load(path)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if complete || len(lines) != PreviewLines {
+		t.Errorf("Load() returned %d lines, complete %v; want %d partial lines", len(lines), complete, PreviewLines)
+	}
+
+	info, _ := os.Stat(path)
+	if _, ok := c.Lookup(path, info.Size(), info.ModTime()); ok {
+		t.Error("Lookup() served a partial entry")
+	}
+}
+
+func TestEviction(t *testing.T) {
+	dir := t.TempDir()
+	modTime := time.Now()
+	paths := make([]string, 3)
+	for i := range paths {
+		paths[i] = filepath.Join(dir, string(rune('a'+i))+".txt")
+		writeFile(t, paths[i], strings.Repeat("y", 84)+"\n", modTime)
+	}
+
+	// Each file costs 100 bytes, so only two fit
+	c := New(200)
+	for _, path := range paths[:2] {
+		if _, _, err := c.Load(path); err != nil {
+			t.Fatalf("Load() unexpected error: %v", err)
+		}
+	}
+	// Touch a.txt so b.txt is the least recently used
+	if _, ok := c.Lookup(paths[0], 85, modTime); !ok {
+		t.Fatal("a.txt was not cached")
+	}
+	if _, _, err := c.Load(paths[2]); err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+
+	for i, want := range []bool{true, false, true} {
+		if _, ok := c.Lookup(paths[i], 85, modTime); ok != want {
+			t.Errorf("%s cached = %v, want %v", filepath.Base(paths[i]), ok, want)
+		}
+	}
+	if c.used > c.maxBytes {
+		t.Errorf("cache uses %d bytes, cap %d", c.used, c.maxBytes)
+	}
+
+	// A file larger than the whole cache is read but not stored
+	tiny := New(10)
+	if _, _, err := tiny.Load(paths[0]); err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if len(tiny.entries) != 0 {
+		t.Errorf("oversized entry was stored")
+	}
+}
+
+func TestStoreAndStats(t *testing.T) {
+	modTime := time.Now()
+	line := strings.Repeat("z", 84)
+
+	// Each entry costs 100 bytes, so storing c.txt evicts a.txt
+	c := New(200)
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		c.Store(name, 85, modTime, []string{line})
+	}
+	if _, ok := c.Lookup("a.txt", 85, modTime); ok {
+		t.Error("a.txt was not evicted")
+	}
+	if _, ok := c.Lookup("c.txt", 85, modTime); !ok {
+		t.Error("c.txt was not cached")
+	}
+	if _, ok := c.Lookup("c.txt", 86, modTime); ok {
+		t.Error("Lookup() served an entry with a different size")
+	}
+
+	want := Stats{Hits: 1, Misses: 2, Evictions: 1, Files: 1, Bytes: 100}
+	if got := c.Stats(); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+	if got := want.String(); got != "1 hits, 2 misses, 1 evicted; 1 files, 100 bytes cached" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+// TestCopyOnRead checks that lines handed in or out of the cache are copies,
+// so a caller changing them, or an eviction, never alters what another
+// caller holds.
+func TestCopyOnRead(t *testing.T) {
+	modTime := time.Now()
+	c := New(DefaultMaxBytes)
+
+	stored := []string{"one", "two"}
+	c.Store("a.txt", 8, modTime, stored)
+	stored[0] = "changed"
+
+	first, _ := c.Lookup("a.txt", 8, modTime)
+	first[1] = "changed"
+	second, ok := c.Lookup("a.txt", 8, modTime)
+	if !ok || strings.Join(second, "|") != "one|two" {
+		t.Errorf("Lookup() = %q, %v, want the stored lines unchanged", second, ok)
+	}
+}
+
+func TestLoadCopiesOnMiss(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.txt")
+	modTime := time.Now().Truncate(time.Second)
+	writeFile(t, path, "one\ntwo\n", modTime)
+	c := New(DefaultMaxBytes)
+
+	loaded, _, err := c.Load(path)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	loaded[0] = "changed"
+
+	cached, ok := c.Lookup(path, 8, modTime)
+	if !ok || strings.Join(cached, "|") != "one|two" {
+		t.Errorf("Lookup() = %q, %v, want the loaded lines unchanged", cached, ok)
+	}
+}
+
+// TestConcurrentEviction looks files up while others are stored and evicted,
+// and checks every hit holds the lines stored for its file whole. Run it with
+// -race to check the locking.
+func TestConcurrentEviction(t *testing.T) {
+	modTime := time.Now()
+	c := New(1024)
+	lines := func(name string) []string {
+		out := make([]string, 8)
+		for i := range out {
+			out[i] = name
+		}
+
+		return out
+	}
+
+	var wg sync.WaitGroup
+	for worker := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 200 {
+				name := string(rune('a' + (worker+i)%16))
+				if got, ok := c.Lookup(name, 8, modTime); ok {
+					if strings.Join(got, "") != strings.Repeat(name, 8) {
+						t.Errorf("Lookup(%s) = %q", name, got)
+
+						return
+					}
+					got[0] = "scribbled"
+
+					continue
+				}
+				c.Store(name, 8, modTime, lines(name))
+			}
+		}()
+	}
+	wg.Wait()
+
+	if stats := c.Stats(); stats.Bytes > 1024 || stats.Evictions == 0 {
+		t.Errorf("Stats() = %+v, want evictions within 1024 bytes", stats)
+	}
+}
+
+func TestNilCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.txt")
+	writeFile(t, path, "hello\n", time.Now())
+
+	var c *Cache
+	lines, complete, err := c.Load(path)
+	if err != nil || !complete || len(lines) != 1 {
+		t.Errorf("Load() = %q, %v, %v", lines, complete, err)
+	}
+	if _, ok := c.Lookup(path, 6, time.Now()); ok {
+		t.Error("nil cache served an entry")
+	}
+	c.Store(path, 6, time.Now(), lines)
+	if stats := c.Stats(); stats != (Stats{}) {
+		t.Errorf("Stats() = %+v, want none", stats)
+	}
+}
@@ -0,0 +1,165 @@
+// Package fdlimit opens files for reading with a process-wide bound on how
+// many are open at once, and retries errors that are expected to clear on
+// their own, such as an interrupted system call or a momentarily exhausted
+// descriptor table. Everything catls reads goes through it, so concurrent
+// scans, as served by catls serve, cannot run the process out of descriptors.
+package fdlimit
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/connerohnesorge/catls/internal/longpath"
+)
+
+const (
+	// DefaultMaxOpen is the bound used until SetMaxOpen is called.
+	DefaultMaxOpen = 64
+	// maxRetries is how many times a transient error is retried.
+	maxRetries = 2
+	// retryDelay is the wait before the first retry; it doubles for each one.
+	retryDelay = 10 * time.Millisecond
+)
+
+// limiter is a counting semaphore whose size can change while in use.
+type limiter struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+	open int
+	max  int
+}
+
+var files = newLimiter(DefaultMaxOpen)
+
+func newLimiter(limit int) *limiter {
+	l := &limiter{max: limit}
+	l.cond = sync.NewCond(&l.mu)
+
+	return l
+}
+
+// SetMaxOpen bounds how many files are open through this package at once.
+// Values below 1 restore DefaultMaxOpen. Files already open stay open, and
+// callers waiting for a slot are woken to check the new bound.
+func SetMaxOpen(limit int) {
+	if limit < 1 {
+		limit = DefaultMaxOpen
+	}
+
+	files.mu.Lock()
+	files.max = limit
+	files.mu.Unlock()
+	files.cond.Broadcast()
+}
+
+func (l *limiter) acquire() {
+	l.mu.Lock()
+	for l.open >= l.max {
+		l.cond.Wait()
+	}
+	l.open++
+	l.mu.Unlock()
+}
+
+func (l *limiter) release() {
+	l.mu.Lock()
+	l.open--
+	l.mu.Unlock()
+	l.cond.Signal()
+}
+
+// File is an open file holding one of the slots bounded by SetMaxOpen until
+// it is closed.
+type File struct {
+	*os.File
+	release sync.Once
+}
+
+// Open opens the named file for reading like os.Open, first waiting for a
+// free slot. Transient errors are retried with backoff; an error that
+// persists is returned and frees the slot.
+func Open(name string) (*File, error) {
+	files.acquire()
+
+	var file *os.File
+	err := retry(func() error {
+		var err error
+		file, err = os.Open(longpath.Fix(name))
+
+		return err
+	})
+	if err != nil {
+		files.release()
+
+		return nil, err
+	}
+
+	return &File{File: file}, nil
+}
+
+// Read reads like os.File.Read, retrying transient errors that occur before
+// any data is read.
+func (f *File) Read(b []byte) (int, error) {
+	var n int
+	var err error
+	_ = retry(func() error {
+		n, err = f.File.Read(b)
+		if n > 0 {
+			// Data was consumed, so a retry would skip it
+			return nil
+		}
+
+		return err
+	})
+
+	return n, err
+}
+
+// Close closes the file and frees its slot. Closing twice frees it once.
+func (f *File) Close() error {
+	err := f.File.Close()
+	f.release.Do(files.release)
+
+	return err
+}
+
+// ReadFile reads the named file like os.ReadFile, within the bound on open
+// files.
+func ReadFile(name string) ([]byte, error) {
+	file, err := Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	return io.ReadAll(file)
+}
+
+// retry runs attempt until it succeeds, fails with an error that is not
+// transient, or has been retried maxRetries times.
+func retry(attempt func() error) error {
+	delay := retryDelay
+	err := attempt()
+	for i := 0; i < maxRetries && isTransient(err); i++ {
+		time.Sleep(delay)
+		delay *= 2
+		err = attempt()
+	}
+
+	return err
+}
+
+// isTransient reports whether err is expected to clear on its own: an
+// interrupted or would-block call, or a descriptor table that is full for now.
+func isTransient(err error) bool {
+	return errors.Is(err, syscall.EINTR) ||
+		errors.Is(err, syscall.EAGAIN) ||
+		errors.Is(err, syscall.EMFILE) ||
+		errors.Is(err, syscall.ENFILE)
+}
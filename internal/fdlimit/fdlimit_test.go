@@ -0,0 +1,138 @@
+package fdlimit
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		errs         []error // Returned by successive attempts; nil once exhausted
+		wantAttempts int
+		wantErr      error
+	}{
+		{name: "success", wantAttempts: 1},
+		{name: "transient then success", errs: []error{syscall.EINTR, syscall.EAGAIN}, wantAttempts: 3},
+		{name: "fd table full", errs: []error{&fs.PathError{Op: "open", Path: "x", Err: syscall.EMFILE}}, wantAttempts: 2},
+		{name: "persistent", errs: []error{syscall.EMFILE, syscall.EMFILE, syscall.EMFILE, syscall.EMFILE}, wantAttempts: 3, wantErr: syscall.EMFILE},
+		{name: "not transient", errs: []error{fs.ErrNotExist}, wantAttempts: 1, wantErr: fs.ErrNotExist},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			err := retry(func() error {
+				attempts++
+				if attempts <= len(tt.errs) {
+					return tt.errs[attempts-1]
+				}
+
+				return nil
+			})
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("retry() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMaxOpenBound(t *testing.T) {
+	paths := writeFiles(t, 200)
+	const limit = 8
+	SetMaxOpen(limit)
+	t.Cleanup(func() { SetMaxOpen(0) })
+
+	readConcurrently(t, paths, 50, limit, 0)
+
+	if files.open != 0 {
+		t.Errorf("%d slots still held after every file was closed", files.open)
+	}
+}
+
+func TestCloseTwice(t *testing.T) {
+	paths := writeFiles(t, 1)
+	file, err := Open(paths[0])
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	_ = file.Close()
+	_ = file.Close()
+
+	if files.open != 0 {
+		t.Errorf("open = %d after closing twice, want 0", files.open)
+	}
+}
+
+// writeFiles creates n small files and returns their paths.
+func writeFiles(t *testing.T, n int) []string {
+	t.Helper()
+
+	dir := t.TempDir()
+	paths := make([]string, n)
+	for i := range paths {
+		paths[i] = filepath.Join(dir, fmt.Sprintf("file%05d.txt", i))
+		if err := os.WriteFile(paths[i], []byte(fmt.Sprintf("file %d\n", i)), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", paths[i], err)
+		}
+	}
+
+	return paths
+}
+
+// readConcurrently reads every path from the given number of goroutines, each
+// keeping its file open for hold, failing on any error or when more than
+// limit files are open.
+func readConcurrently(t *testing.T, paths []string, workers, limit int, hold time.Duration) {
+	t.Helper()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := w; i < len(paths); i += workers {
+				file, err := Open(paths[i])
+				if err != nil {
+					errs <- err
+
+					return
+				}
+				files.mu.Lock()
+				open := files.open
+				files.mu.Unlock()
+				if open > limit {
+					errs <- fmt.Errorf("%d files open, limit %d", open, limit)
+					_ = file.Close()
+
+					return
+				}
+				time.Sleep(hold)
+				_, err = file.Read(make([]byte, 64))
+				_ = file.Close()
+				if err != nil {
+					errs <- err
+
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
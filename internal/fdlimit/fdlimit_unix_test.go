@@ -0,0 +1,38 @@
+//go:build unix
+
+package fdlimit
+
+import (
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestLoweredRlimit opens thousands of files from many goroutines with the
+// descriptor limit lowered below the number of goroutines. Each file is held
+// open longer than the retries wait, so the run fails with EMFILE unless the
+// bound holds.
+func TestLoweredRlimit(t *testing.T) {
+	paths := writeFiles(t, 3000)
+
+	var original syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &original); err != nil {
+		t.Skipf("cannot read the descriptor limit: %v", err)
+	}
+	const soft = 128
+	if original.Max < soft {
+		t.Skipf("hard descriptor limit %d is below %d", original.Max, soft)
+	}
+	lowered := syscall.Rlimit{Cur: soft, Max: original.Max}
+	if err := syscall.Setrlimit(syscall.RLIMIT_NOFILE, &lowered); err != nil {
+		t.Skipf("cannot lower the descriptor limit: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = syscall.Setrlimit(syscall.RLIMIT_NOFILE, &original)
+	})
+
+	SetMaxOpen(DefaultMaxOpen)
+	t.Cleanup(func() { SetMaxOpen(0) })
+
+	readConcurrently(t, paths, 2*soft, DefaultMaxOpen, 50*time.Millisecond)
+}
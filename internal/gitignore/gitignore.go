@@ -0,0 +1,325 @@
+// Package gitignore matches paths against git's ignore rules: the global
+// excludes file, $GIT_DIR/info/exclude, and the .gitignore files of a work
+// tree, with the precedence git gives them.
+package gitignore
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ErrNotWorkTree is returned by Open for a directory outside any git work tree.
+var ErrNotWorkTree = errors.New("not inside a git work tree")
+
+// Pattern is a line of an ignore file.
+type Pattern struct {
+	Source string // File the pattern was read from, relative to the work tree root when inside it
+	Line   int    // Line number in Source, from 1
+	Text   string // The line as written, without trailing spaces
+
+	base    string // Directory the pattern is relative to, relative to the root with slashes ("" for the root)
+	negate  bool   // The pattern re-includes what an earlier one excluded
+	dirOnly bool   // The pattern matches only directories
+	regex   *regexp.Regexp
+}
+
+// Negated reports whether the pattern re-includes paths, as "!" does.
+func (p *Pattern) Negated() bool {
+	return p.negate
+}
+
+// String formats the pattern as git check-ignore -v does: source, line,
+// and pattern separated by colons.
+func (p *Pattern) String() string {
+	return fmt.Sprintf("%s:%d:%s", p.Source, p.Line, p.Text)
+}
+
+// matches reports whether the path relPath, relative to the root with
+// slashes, matches the pattern.
+func (p *Pattern) matches(relPath string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+	if p.base != "" {
+		rest, ok := strings.CutPrefix(relPath, p.base+"/")
+		if !ok {
+			return false
+		}
+		relPath = rest
+	}
+
+	return p.regex.MatchString(relPath)
+}
+
+// Matcher decides which paths of a work tree git ignores. The .gitignore
+// file of a directory is read the first time a path below it is matched.
+type Matcher struct {
+	root     string                // Absolute path of the work tree
+	excludes []*Pattern            // The global excludes file, then info/exclude
+	dirs     map[string][]*Pattern // Patterns of each directory's .gitignore, by directory relative to root
+}
+
+// Open returns the matcher of the work tree containing dir. The global
+// excludes file is core.excludesFile from the repository's or the user's
+// git configuration, or else $XDG_CONFIG_HOME/git/ignore, which defaults
+// to ~/.config/git/ignore.
+func Open(dir string) (*Matcher, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	root, gitDir, err := findWorkTree(abs)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", dir, err)
+	}
+	commonDir := commonGitDir(gitDir)
+
+	m := &Matcher{root: root, dirs: make(map[string][]*Pattern)}
+	if path := excludesFile(commonDir); path != "" {
+		if m.excludes, err = m.readPatterns(path, ""); err != nil {
+			return nil, err
+		}
+	}
+	info, err := m.readPatterns(filepath.Join(commonDir, "info", "exclude"), "")
+	if err != nil {
+		return nil, err
+	}
+	m.excludes = append(m.excludes, info...)
+
+	return m, nil
+}
+
+// Root returns the absolute path of the work tree.
+func (m *Matcher) Root() string {
+	return m.root
+}
+
+// Rel returns the path of absPath relative to the root with slashes, and
+// whether it is inside the work tree.
+func (m *Matcher) Rel(absPath string) (string, bool) {
+	rel, err := filepath.Rel(m.root, absPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+
+	return filepath.ToSlash(rel), true
+}
+
+// Match returns the pattern that decides relPath, a path relative to the
+// root with slashes, or nil when none matches. Later patterns win over
+// earlier ones in the same file, .gitignore files win over info/exclude
+// and the global excludes file, and deeper .gitignore files win over those
+// above them. The path is ignored when the pattern is not negated. Parent
+// directories are not checked; see MatchPath.
+func (m *Matcher) Match(relPath string, isDir bool) (*Pattern, error) {
+	if relPath == "." || relPath == "" {
+		return nil, nil
+	}
+
+	var match *Pattern
+	last := func(patterns []*Pattern) {
+		for _, p := range patterns {
+			if p.matches(relPath, isDir) {
+				match = p
+			}
+		}
+	}
+
+	last(m.excludes)
+	dir := ""
+	for {
+		patterns, err := m.dirPatterns(dir)
+		if err != nil {
+			return nil, err
+		}
+		last(patterns)
+
+		next, _, found := strings.Cut(strings.TrimPrefix(relPath[len(dir):], "/"), "/")
+		if !found {
+			return match, nil
+		}
+		dir = path.Join(dir, next)
+	}
+}
+
+// MatchPath is Match for a path whose parent directories were not checked
+// first, as git does while walking: an ignored parent directory decides
+// every path below it, which no pattern can re-include.
+func (m *Matcher) MatchPath(relPath string, isDir bool) (*Pattern, error) {
+	parts := strings.Split(relPath, "/")
+	for i := 1; i < len(parts); i++ {
+		match, err := m.Match(strings.Join(parts[:i], "/"), true)
+		if err != nil {
+			return nil, err
+		}
+		if match != nil && !match.negate {
+			return match, nil
+		}
+	}
+
+	return m.Match(relPath, isDir)
+}
+
+// dirPatterns returns the patterns of the .gitignore in dir, relative to
+// the root, reading it once.
+func (m *Matcher) dirPatterns(dir string) ([]*Pattern, error) {
+	if patterns, ok := m.dirs[dir]; ok {
+		return patterns, nil
+	}
+
+	patterns, err := m.readPatterns(filepath.Join(m.root, filepath.FromSlash(dir), ".gitignore"), dir)
+	if err != nil {
+		return nil, err
+	}
+	m.dirs[dir] = patterns
+
+	return patterns, nil
+}
+
+// readPatterns reads the ignore file at path, whose patterns are relative
+// to base. A missing file has no patterns.
+func (m *Matcher) readPatterns(file, base string) ([]*Pattern, error) {
+	f, err := os.Open(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read ignore file: %w", err)
+	}
+	defer f.Close()
+
+	source := file
+	if rel, ok := m.Rel(file); ok {
+		source = rel
+	}
+
+	var patterns []*Pattern
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if p := parsePattern(scanner.Text()); p != nil {
+			p.Source, p.Line, p.base = source, line, base
+			patterns = append(patterns, p)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read %s: %w", source, err)
+	}
+
+	return patterns, nil
+}
+
+// parsePattern parses a line of an ignore file, or returns nil for blank
+// lines and comments.
+func parsePattern(line string) *Pattern {
+	line = strings.TrimSuffix(line, "\r")
+	// Trailing spaces are dropped unless escaped with a backslash
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+
+	p := &Pattern{Text: line}
+	glob := line
+	if rest, ok := strings.CutPrefix(glob, "!"); ok {
+		p.negate, glob = true, rest
+	}
+	if rest, ok := strings.CutSuffix(glob, "/"); ok {
+		p.dirOnly, glob = true, rest
+	}
+	if glob == "" {
+		return nil
+	}
+
+	// A slash before the end anchors the pattern to its directory;
+	// otherwise it matches a name at any depth below it
+	prefix := "(?:.*/)?"
+	if strings.Contains(glob, "/") {
+		prefix, glob = "", strings.TrimPrefix(glob, "/")
+	}
+
+	regex, err := regexp.Compile("^" + prefix + globRegex(glob) + "$")
+	if err != nil {
+		return nil
+	}
+	p.regex = regex
+
+	return p
+}
+
+// globRegex converts a gitignore glob to a regular expression: "*" and "?"
+// do not match a slash, "**/" matches any number of directories, a trailing
+// "/**" everything inside, and a backslash quotes the next character.
+func globRegex(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		atSegment := i == 0 || glob[i-1] == '/'
+		switch c := glob[i]; {
+		case atSegment && strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case atSegment && glob[i:] == "**":
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			for i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+			}
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			class, n := charClass(glob[i:])
+			if n == 0 {
+				b.WriteString(`\[`)
+
+				continue
+			}
+			b.WriteString(class)
+			i += n - 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+
+	return b.String()
+}
+
+// charClass converts the bracket expression at the start of glob to a
+// regular expression class that never matches a slash, and returns it with
+// the length of the expression, or 0 when the bracket is not closed.
+func charClass(glob string) (string, int) {
+	var b strings.Builder
+	b.WriteString("[")
+	i := 1
+	if i < len(glob) && (glob[i] == '!' || glob[i] == '^') {
+		b.WriteString("^/")
+		i++
+	}
+	for start := i; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case c == ']' && i > start:
+			return b.String() + "]", i + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		case c == '\\' || c == '[' || c == ']' || c == '^':
+			b.WriteString(`\` + string(c))
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return "", 0
+}
@@ -0,0 +1,199 @@
+package gitignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParsePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		isDir   bool
+		want    bool
+	}{
+		{pattern: "*.log", path: "a.log", want: true},
+		{pattern: "*.log", path: "deep/dir/a.log", want: true},
+		{pattern: "*.log", path: "a.log/x", want: false},
+		{pattern: "/root.txt", path: "root.txt", want: true},
+		{pattern: "/root.txt", path: "sub/root.txt", want: false},
+		{pattern: "doc/*.md", path: "doc/a.md", want: true},
+		{pattern: "doc/*.md", path: "doc/sub/a.md", want: false},
+		{pattern: "doc/*.md", path: "x/doc/a.md", want: false},
+		{pattern: "build/", path: "build", isDir: true, want: true},
+		{pattern: "build/", path: "build", want: false},
+		{pattern: "build/", path: "src/build", isDir: true, want: true},
+		{pattern: "**/cache", path: "cache", isDir: true, want: true},
+		{pattern: "**/cache", path: "a/b/cache", want: true},
+		{pattern: "logs/**", path: "logs/a/b.txt", want: true},
+		{pattern: "logs/**", path: "logs", isDir: true, want: false},
+		{pattern: "a/**/b", path: "a/b", want: true},
+		{pattern: "a/**/b", path: "a/x/y/b", want: true},
+		{pattern: "a/**/b", path: "a/xb", want: false},
+		{pattern: "file?.txt", path: "file1.txt", want: true},
+		{pattern: "file?.txt", path: "file/.txt", want: false},
+		{pattern: "[abc].go", path: "b.go", want: true},
+		{pattern: "[!abc].go", path: "b.go", want: false},
+		{pattern: "[!abc].go", path: "d.go", want: true},
+		{pattern: "[]].go", path: "].go", want: true},
+		{pattern: `\#hash`, path: "#hash", want: true},
+		{pattern: `\!bang`, path: "!bang", want: true},
+		{pattern: "trailing   ", path: "trailing", want: true},
+		{pattern: `space\ `, path: "space ", want: true},
+		{pattern: "日本/*.txt", path: "日本/a.txt", want: true},
+		{pattern: "a.c", path: "abc", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			p := parsePattern(tt.pattern)
+			if p == nil {
+				t.Fatalf("parsePattern(%q) = nil", tt.pattern)
+			}
+			if got := p.matches(tt.path, tt.isDir); got != tt.want {
+				t.Errorf("%q matches %q = %v, want %v (regex %s)", tt.pattern, tt.path, got, tt.want, p.regex)
+			}
+		})
+	}
+
+	for _, line := range []string{"", "   ", "# comment", "!", "/"} {
+		if p := parsePattern(line); p != nil {
+			t.Errorf("parsePattern(%q) = %v, want nil", line, p)
+		}
+	}
+}
+
+// TestMatcherLayering checks git's precedence across the global excludes
+// file in a temporary HOME, info/exclude, and nested .gitignore files.
+func TestMatcherLayering(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	root := t.TempDir()
+
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	globalIgnore := filepath.Join(home, ".config", "git", "ignore")
+	write(globalIgnore, "*.tmp\n*.swp\nglobal-only\n")
+	write(filepath.Join(root, ".git", "info", "exclude"), "# local\n*.local\n!keep.tmp\n")
+	write(filepath.Join(root, ".gitignore"), "*.log\n!important.swp\n")
+	write(filepath.Join(root, "sub", ".gitignore"), "!debug.log\nsecret/\n")
+
+	m, err := Open(filepath.Join(root, "sub"))
+	if err != nil {
+		t.Fatalf("Open() unexpected error: %v", err)
+	}
+	if m.Root() != root {
+		t.Errorf("Root() = %q, want %q", m.Root(), root)
+	}
+
+	tests := []struct {
+		path   string
+		isDir  bool
+		source string // "" when not ignored
+		line   int
+	}{
+		{path: "a.tmp", source: globalIgnore, line: 1},
+		{path: "sub/global-only", source: globalIgnore, line: 3},
+		{path: "x.local", source: ".git/info/exclude", line: 2},
+		// info/exclude re-includes over the global excludes file
+		{path: "keep.tmp"},
+		// .gitignore re-includes over both
+		{path: "important.swp"},
+		{path: "app.log", source: ".gitignore", line: 1},
+		// A deeper .gitignore wins over the one above it
+		{path: "sub/debug.log"},
+		{path: "debug.log", source: ".gitignore", line: 1},
+		{path: "sub/secret", isDir: true, source: "sub/.gitignore", line: 2},
+		{path: "secret", isDir: true},
+		{path: "sub/main.go"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			match, err := m.Match(tt.path, tt.isDir)
+			if err != nil {
+				t.Fatalf("Match() unexpected error: %v", err)
+			}
+			if tt.source == "" {
+				if match != nil && !match.Negated() {
+					t.Errorf("Match() = %v, want %s not ignored", match, tt.path)
+				}
+
+				return
+			}
+			if match == nil || match.Negated() || match.Source != tt.source || match.Line != tt.line {
+				t.Errorf("Match() = %v, want %s:%d", match, tt.source, tt.line)
+			}
+		})
+	}
+
+	// A file below an ignored directory cannot be re-included
+	write(filepath.Join(root, "sub", "secret", ".gitignore"), "!*\n")
+	match, err := m.MatchPath("sub/secret/key.pem", false)
+	if err != nil || match == nil || match.String() != "sub/.gitignore:2:secret/" {
+		t.Errorf("MatchPath() = %v, %v, want sub/.gitignore:2:secret/", match, err)
+	}
+}
+
+func TestExcludesFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	gitDir := t.TempDir()
+
+	if got, want := excludesFile(gitDir), filepath.Join(home, ".config", "git", "ignore"); got != want {
+		t.Errorf("default excludesFile() = %q, want %q", got, want)
+	}
+
+	xdg := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	if got, want := excludesFile(gitDir), filepath.Join(xdg, "git", "ignore"); got != want {
+		t.Errorf("XDG excludesFile() = %q, want %q", got, want)
+	}
+
+	config := "[user]\n\tname = x\n[core]\n\texcludesFile = ~/global-ignore ; comment\n"
+	if err := os.WriteFile(filepath.Join(home, ".gitconfig"), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := excludesFile(gitDir), filepath.Join(home, "global-ignore"); got != want {
+		t.Errorf("~/.gitconfig excludesFile() = %q, want %q", got, want)
+	}
+
+	// The repository's config wins over the user's
+	config = "[Core]\n\tEXCLUDESFILE = \"/repo/ignore\"\n"
+	if err := os.WriteFile(filepath.Join(gitDir, "config"), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := excludesFile(gitDir); got != "/repo/ignore" {
+		t.Errorf("repository excludesFile() = %q, want /repo/ignore", got)
+	}
+}
+
+func TestOpenOutsideWorkTree(t *testing.T) {
+	dir := t.TempDir()
+	if _, _, err := findWorkTree(dir); err == nil {
+		t.Skip("the temporary directory is inside a git work tree")
+	}
+	if _, err := Open(dir); err == nil {
+		t.Error("Open() outside a work tree succeeded")
+	}
+
+	// A .git file, as worktrees and submodules have, points at the git directory
+	gitDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".git"), []byte("gitdir: "+gitDir+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	root, got, err := findWorkTree(dir)
+	if err != nil || root != dir || got != gitDir {
+		t.Errorf("findWorkTree() = %q, %q, %v, want %q, %q", root, got, err, dir, gitDir)
+	}
+}
@@ -0,0 +1,168 @@
+package gitignore
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// findWorkTree returns the nearest directory at or above dir holding a .git
+// directory, or a .git file pointing at one as worktrees and submodules
+// have, and the git directory it uses.
+func findWorkTree(dir string) (string, string, error) {
+	for {
+		dotGit := filepath.Join(dir, ".git")
+		info, err := os.Stat(dotGit)
+		switch {
+		case err == nil && info.IsDir():
+			return dir, dotGit, nil
+		case err == nil:
+			if gitDir, ok := readGitFile(dotGit); ok {
+				return dir, gitDir, nil
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", ErrNotWorkTree
+		}
+		dir = parent
+	}
+}
+
+// WorkTreeRoot returns the absolute path of the git work tree containing
+// dir: the nearest directory at or above it holding .git.
+func WorkTreeRoot(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	root, _, err := findWorkTree(abs)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", dir, err)
+	}
+
+	return root, nil
+}
+
+// readGitFile returns the git directory a .git file points at with its
+// "gitdir:" line, resolved against the file's directory.
+func readGitFile(path string) (string, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !ok {
+		return "", false
+	}
+	gitDir = strings.TrimSpace(gitDir)
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(filepath.Dir(path), gitDir)
+	}
+
+	return gitDir, true
+}
+
+// commonGitDir returns the directory holding the config and info/exclude
+// shared by every worktree of gitDir's repository: the one its commondir
+// file names, or gitDir itself.
+func commonGitDir(gitDir string) string {
+	data, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err != nil {
+		return gitDir
+	}
+	common := strings.TrimSpace(string(data))
+	if !filepath.IsAbs(common) {
+		common = filepath.Join(gitDir, common)
+	}
+
+	return common
+}
+
+// excludesFile returns the global excludes file: core.excludesFile as the
+// repository's config, ~/.gitconfig, or $XDG_CONFIG_HOME/git/config sets
+// it, in that order of precedence, or else the default location.
+func excludesFile(commonDir string) string {
+	home, _ := os.UserHomeDir()
+	xdg := os.Getenv("XDG_CONFIG_HOME")
+	if xdg == "" && home != "" {
+		xdg = filepath.Join(home, ".config")
+	}
+
+	configs := []string{filepath.Join(commonDir, "config")}
+	if home != "" {
+		configs = append(configs, filepath.Join(home, ".gitconfig"))
+	}
+	if xdg != "" {
+		configs = append(configs, filepath.Join(xdg, "git", "config"))
+	}
+	for _, config := range configs {
+		if value, ok := configValue(config, "core", "excludesfile"); ok {
+			return expandHome(value, home)
+		}
+	}
+
+	if xdg == "" {
+		return ""
+	}
+
+	return filepath.Join(xdg, "git", "ignore")
+}
+
+// expandHome replaces a leading "~/" in path with home, as git does for
+// path settings.
+func expandHome(path, home string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok && home != "" {
+		return filepath.Join(home, rest)
+	}
+
+	return path
+}
+
+// configValue returns the last value of key in section of the git config
+// file at path. Section and key names are matched case-insensitively;
+// subsections, includes, and escapes other than surrounding quotes are not
+// supported.
+func configValue(path, section, key string) (string, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	var value string
+	found, inSection := false, false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if name, ok := strings.CutPrefix(line, "["); ok {
+			name, _, _ = strings.Cut(name, "]")
+			inSection = strings.EqualFold(strings.TrimSpace(name), section)
+
+			continue
+		}
+		if !inSection {
+			continue
+		}
+
+		name, val, _ := strings.Cut(line, "=")
+		if !strings.EqualFold(strings.TrimSpace(name), key) {
+			continue
+		}
+		val = strings.TrimSpace(val)
+		if unquoted, ok := strings.CutPrefix(val, `"`); ok {
+			val, _, _ = strings.Cut(unquoted, `"`)
+		} else if i := strings.IndexAny(val, "#;"); i >= 0 {
+			val = strings.TrimSpace(val[:i])
+		}
+		value, found = val, true
+	}
+
+	return value, found
+}
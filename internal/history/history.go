@@ -0,0 +1,201 @@
+// Package history keeps a local record of catls runs, so a run can be listed
+// and replayed later. Runs are recorded only when asked for, and the record
+// never leaves the machine.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxSize is the size at which the history file is rotated: it replaces
+	// the previous rotation, and a new file is started.
+	MaxSize = 1 << 20
+	// rotatedSuffix names the rotated history file after the current one.
+	rotatedSuffix = ".1"
+	// maxLine bounds a line read back, so a damaged file cannot exhaust memory.
+	maxLine = 1 << 20
+)
+
+// Entry is one recorded run. Paths are stored as Relativize leaves them, so
+// the record names no more of the filesystem than it needs to.
+type Entry struct {
+	Time        time.Time `json:"time"`
+	Dir         string    `json:"dir"`         // Working directory of the run
+	Args        []string  `json:"args"`        // Positional arguments
+	Flags       []string  `json:"flags"`       // Flags given, each as --name=value
+	Files       int       `json:"files"`       // Files written
+	OutputBytes int64     `json:"outputBytes"` // Bytes written to stdout, or -1 with --output-dir
+}
+
+// DefaultPath returns the per-user location of the history:
+// $XDG_STATE_HOME/catls/history.jsonl when XDG_STATE_HOME is set, and
+// otherwise ~/.local/state on Unix systems other than macOS, or the user
+// configuration directory on macOS and Windows.
+func DefaultPath() (string, error) {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		var err error
+		if dir, err = defaultStateDir(); err != nil {
+			return "", err
+		}
+	}
+
+	return filepath.Join(dir, "catls", "history.jsonl"), nil
+}
+
+// defaultStateDir returns where per-user state goes without XDG_STATE_HOME.
+func defaultStateDir() (string, error) {
+	switch runtime.GOOS {
+	case "darwin", "ios", "windows", "plan9":
+		return os.UserConfigDir()
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".local", "state"), nil
+}
+
+// Append adds entry to the history at path, creating it and its directory
+// as needed. A file that has reached MaxSize is rotated first.
+func Append(path string, entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("cannot create history directory: %w", err)
+	}
+	if info, err := os.Stat(path); err == nil && info.Size()+int64(len(line)) > MaxSize {
+		if err := os.Rename(path, path+rotatedSuffix); err != nil {
+			return fmt.Errorf("cannot rotate history: %w", err)
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("cannot open history: %w", err)
+	}
+	if _, err := file.Write(line); err != nil {
+		_ = file.Close()
+
+		return fmt.Errorf("cannot write history: %w", err)
+	}
+
+	return file.Close()
+}
+
+// List returns the runs recorded at path and in its rotation for the
+// working directory dir, as Relativize stores it, newest first. A missing
+// history is empty; lines that cannot be parsed are skipped.
+func List(path, dir string) ([]Entry, error) {
+	var entries []Entry
+	for _, name := range []string{path + rotatedSuffix, path} {
+		read, err := readEntries(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range read {
+			if entry.Dir == dir {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	slices.Reverse(entries)
+
+	return entries, nil
+}
+
+// readEntries reads the entries of one history file.
+func readEntries(path string) ([]Entry, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read history: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var entries []Entry
+	lines := bufio.NewScanner(file)
+	lines.Buffer(nil, maxLine)
+	for lines.Scan() {
+		var entry Entry
+		if json.Unmarshal(lines.Bytes(), &entry) == nil {
+			entries = append(entries, entry)
+		}
+	}
+	if err := lines.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read history: %w", err)
+	}
+
+	return entries, nil
+}
+
+// Relativize returns the absolute path p as the history stores it: relative
+// to dir when p is dir or below it, under "~" when it is below the home
+// directory home, and unchanged otherwise. Relative paths are returned as
+// they are, since they are already relative to the run's directory.
+func Relativize(p, dir, home string) string {
+	if !filepath.IsAbs(p) {
+		return p
+	}
+	if dir != "" {
+		if rel, ok := below(p, dir); ok {
+			return rel
+		}
+	}
+	if home != "" {
+		if rel, ok := below(p, home); ok {
+			if rel == "." {
+				return "~"
+			}
+
+			return "~" + string(filepath.Separator) + rel
+		}
+	}
+
+	return p
+}
+
+// below returns p relative to dir when p is dir or inside it.
+func below(p, dir string) (string, bool) {
+	rel, err := filepath.Rel(dir, p)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+
+	return rel, true
+}
+
+// Resolve reverses the home part of Relativize, expanding a leading "~"
+// to home. Paths relative to the run's directory are left to be resolved
+// against it.
+func Resolve(p, home string) string {
+	if p == "~" {
+		return home
+	}
+	if rest, ok := strings.CutPrefix(p, "~"+string(filepath.Separator)); ok {
+		return filepath.Join(home, rest)
+	}
+
+	return p
+}
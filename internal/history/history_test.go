@@ -0,0 +1,120 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppendList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "catls", "history.jsonl")
+
+	start := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	for i, dir := range []string{"~/a", "~/b", "~/a"} {
+		entry := Entry{Time: start.Add(time.Duration(i) * time.Hour), Dir: dir, Flags: []string{"--recursive=true"}, Files: i}
+		if err := Append(path, entry); err != nil {
+			t.Fatalf("Append() unexpected error: %v", err)
+		}
+	}
+
+	entries, err := List(path, "~/a")
+	if err != nil {
+		t.Fatalf("List() unexpected error: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Files != 2 || entries[1].Files != 0 {
+		t.Errorf("List() = %+v, want the runs in ~/a, newest first", entries)
+	}
+	if !entries[0].Time.Equal(start.Add(2*time.Hour)) || !reflect.DeepEqual(entries[0].Flags, []string{"--recursive=true"}) {
+		t.Errorf("List()[0] = %+v, want the entry as appended", entries[0])
+	}
+
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm()&0o077 != 0 {
+		t.Errorf("history file is readable by others: %v, %v", info, err)
+	}
+
+	if entries, err := List(filepath.Join(t.TempDir(), "missing.jsonl"), "~/a"); err != nil || len(entries) != 0 {
+		t.Errorf("List() of a missing history = %v, %v, want none", entries, err)
+	}
+}
+
+func TestAppendRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+
+	entry := Entry{Dir: "~/a", Args: []string{strings.Repeat("x", 4096)}}
+	appended := 0
+	for {
+		if err := Append(path, entry); err != nil {
+			t.Fatalf("Append() unexpected error: %v", err)
+		}
+		appended++
+		if _, err := os.Stat(path + rotatedSuffix); err == nil {
+			break
+		}
+		if appended > 2*MaxSize/4096 {
+			t.Fatal("the history was never rotated")
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || info.Size() > MaxSize {
+		t.Fatalf("the current history is %v after rotating: %v", info, err)
+	}
+	rotated, _ := os.Stat(path + rotatedSuffix)
+	if rotated.Size() > MaxSize {
+		t.Errorf("the rotated history is %d bytes, over MaxSize", rotated.Size())
+	}
+
+	// Both files are listed
+	entries, err := List(path, "~/a")
+	if err != nil || len(entries) != appended {
+		t.Errorf("List() = %d entries, %v, want %d", len(entries), err, appended)
+	}
+}
+
+func TestRelativize(t *testing.T) {
+	root := t.TempDir()
+	home := filepath.Join(root, "home", "me")
+	dir := filepath.Join(home, "src", "tool")
+	outside := filepath.Join(root, "etc", "catls")
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: dir, want: "."},
+		{path: filepath.Join(dir, "out"), want: "out"},
+		{path: filepath.Join(home, "notes"), want: filepath.FromSlash("~/notes")},
+		{path: home, want: "~"},
+		{path: outside, want: outside},
+		{path: filepath.Join(home, "src", "toolbox"), want: filepath.FromSlash("~/src/toolbox")},
+		{path: "docs", want: "docs"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got := Relativize(tt.path, dir, home)
+			if got != tt.want {
+				t.Errorf("Relativize(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+			if back := Resolve(got, home); filepath.IsAbs(tt.path) && strings.HasPrefix(got, "~") && back != tt.path {
+				t.Errorf("Resolve(%q) = %q, want %q", got, back, tt.path)
+			}
+		})
+	}
+}
+
+func TestDefaultPathHonorsXDGStateHome(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", dir)
+
+	path, err := DefaultPath()
+	if err != nil {
+		t.Fatalf("DefaultPath() unexpected error: %v", err)
+	}
+	if want := filepath.Join(dir, "catls", "history.jsonl"); path != want {
+		t.Errorf("DefaultPath() = %q, want %q", path, want)
+	}
+}
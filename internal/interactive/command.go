@@ -0,0 +1,146 @@
+package interactive
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+// commandAction is what a command does to the files its pattern matches.
+type commandAction string
+
+// Actions of the command prompt. Each may be abbreviated to its first letter.
+const (
+	actionSelect   commandAction = "select"   // Select matching files
+	actionDeselect commandAction = "deselect" // Deselect matching files
+	actionOnly     commandAction = "only"     // Select matching files and deselect the rest
+)
+
+// command is a parsed command prompt entry.
+type command struct {
+	action   commandAction
+	pattern  string
+	patterns []string // pattern with its brace alternatives expanded
+}
+
+// parseCommand parses "ACTION PATTERN", where ACTION is select, deselect, or
+// only, or their first letters. A pattern alone selects, and the pattern runs
+// to the end of the line, so it may contain spaces. Patterns are globs
+// matched against relative paths as --globs matches them, so "*.test.go"
+// matches in any directory and braces list alternatives.
+func parseCommand(input string) (command, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return command{}, errors.New("usage: " + commandUsage)
+	}
+
+	cmd := command{action: actionSelect}
+	word, rest, _ := strings.Cut(input, " ")
+	for _, action := range []commandAction{actionSelect, actionDeselect, actionOnly} {
+		if word == string(action) || word == string(action[:1]) {
+			cmd.action = action
+			input = strings.TrimSpace(rest)
+
+			break
+		}
+	}
+	if input == "" {
+		return command{}, fmt.Errorf("usage: %s PATTERN", cmd.action)
+	}
+
+	patterns, err := scanner.ExpandGlob(input)
+	if err != nil {
+		return command{}, fmt.Errorf("invalid pattern %q: %w", input, err)
+	}
+	cmd.pattern, cmd.patterns = input, patterns
+
+	return cmd, nil
+}
+
+// apply runs the command on files and returns how many files the pattern
+// matched and how many changed selection. Excluded files are left alone, so
+// a pattern never force-includes them, and Unselectable files are never
+// selected by one.
+func (c command) apply(files []FileItem) (int, int) {
+	matched, changed := 0, 0
+	for i := range files {
+		if files[i].Excluded != "" {
+			continue
+		}
+		_, match := scanner.MatchingGlob(files[i].RelPath, c.patterns)
+		if match {
+			matched++
+		}
+
+		selected := files[i].Selected
+		switch {
+		case match:
+			selected = c.action != actionDeselect
+		case c.action == actionOnly:
+			selected = false
+		}
+		if selected && files[i].Unselectable != "" {
+			selected = files[i].Selected
+		}
+		if selected != files[i].Selected {
+			files[i].Selected = selected
+			changed++
+		}
+	}
+
+	return matched, changed
+}
+
+// commandUsage is shown in the empty command prompt.
+const commandUsage = "select|deselect|only PATTERN"
+
+// openCommand shows the command prompt in place of the footer.
+func (m *Model) openCommand() {
+	m.commanding = true
+	m.commandLine = ""
+}
+
+// handleCommandKey edits the command prompt. Enter runs the command and esc
+// closes the prompt; the result is reported in the status line.
+func (m *Model) handleCommandKey(msg tea.KeyMsg) {
+	switch msg.Type {
+	case tea.KeyEsc, tea.KeyCtrlC:
+		m.commanding = false
+	case tea.KeyEnter:
+		m.commanding = false
+		m.runCommand(m.commandLine)
+	case tea.KeyBackspace:
+		line := []rune(m.commandLine)
+		if len(line) > 0 {
+			m.commandLine = string(line[:len(line)-1])
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		m.commandLine += string(msg.Runes)
+	}
+}
+
+// renderCommand renders the command prompt with its cursor.
+func (m *Model) renderCommand() string {
+	prompt := ":" + m.commandLine + m.styles.Cursor.Render("█")
+	if m.commandLine == "" {
+		prompt += " " + m.styles.Dim.Render(commandUsage)
+	}
+
+	return prompt
+}
+
+// runCommand parses and applies input, reporting the outcome in the status line.
+func (m *Model) runCommand(input string) {
+	cmd, err := parseCommand(input)
+	if err != nil {
+		m.status = err.Error()
+
+		return
+	}
+
+	matched, changed := cmd.apply(m.files)
+	m.status = fmt.Sprintf("%s %s: %d matching, %d changed", cmd.action, cmd.pattern, matched, changed)
+}
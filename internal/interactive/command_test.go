@@ -0,0 +1,125 @@
+package interactive
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		input       string
+		wantAction  commandAction
+		wantPattern string
+		wantErr     string
+	}{
+		{input: "select *.test.go", wantAction: actionSelect, wantPattern: "*.test.go"},
+		{input: "  d  docs/*  ", wantAction: actionDeselect, wantPattern: "docs/*"},
+		{input: "only *.{go,md}", wantAction: actionOnly, wantPattern: "*.{go,md}"},
+		{input: "*.go", wantAction: actionSelect, wantPattern: "*.go"},
+		{input: "select my notes.txt", wantAction: actionSelect, wantPattern: "my notes.txt"},
+		{input: "", wantErr: "usage: select|deselect|only PATTERN"},
+		{input: "only", wantErr: "usage: only PATTERN"},
+		{input: "s *.{go", wantErr: "invalid pattern"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			cmd, err := parseCommand(tt.input)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseCommand(%q) error = %v, want %q", tt.input, err, tt.wantErr)
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseCommand(%q) unexpected error: %v", tt.input, err)
+			}
+			if cmd.action != tt.wantAction || cmd.pattern != tt.wantPattern {
+				t.Errorf("parseCommand(%q) = %s %q, want %s %q", tt.input, cmd.action, cmd.pattern, tt.wantAction, tt.wantPattern)
+			}
+		})
+	}
+}
+
+func TestCommandApply(t *testing.T) {
+	tests := []struct {
+		input       string
+		want        string // Selection after the command, one mark per file
+		wantMatched int
+		wantChanged int
+	}{
+		{input: "select *_test.go", want: "xxxx", wantMatched: 2, wantChanged: 1},
+		{input: "deselect *.go", want: "---x", wantMatched: 3, wantChanged: 2},
+		{input: "only *.go", want: "xxx-", wantMatched: 3, wantChanged: 2},
+		{input: "only cmd/*", want: "--x-", wantMatched: 1, wantChanged: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			files := []FileItem{
+				{RelPath: "main.go", Selected: true},
+				{RelPath: "main_test.go"},
+				{RelPath: "cmd/root_test.go", Selected: true},
+				{RelPath: "README.md", Selected: true},
+			}
+
+			cmd, err := parseCommand(tt.input)
+			if err != nil {
+				t.Fatalf("parseCommand(%q) unexpected error: %v", tt.input, err)
+			}
+			matched, changed := cmd.apply(files)
+
+			var got strings.Builder
+			for _, file := range files {
+				if file.Selected {
+					got.WriteByte('x')
+				} else {
+					got.WriteByte('-')
+				}
+			}
+			if got.String() != tt.want || matched != tt.wantMatched || changed != tt.wantChanged {
+				t.Errorf("%q: selection %s, %d matched, %d changed; want %s, %d, %d",
+					tt.input, got.String(), matched, changed, tt.want, tt.wantMatched, tt.wantChanged)
+			}
+		})
+	}
+}
+
+func TestCommandPrompt(t *testing.T) {
+	m := NewModel([]FileItem{
+		{RelPath: "a.go", Selected: true},
+		{RelPath: "b.md", Selected: true},
+	}, nil)
+	m.Update(tea.WindowSizeMsg{Width: 80, Height: 20})
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(":")})
+	// Keys bound in the list, like "d", "o", and "q", are typed into the prompt
+	for _, r := range "d *.mdx" {
+		m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	if view := m.View(); !strings.Contains(view, ":d *.md") {
+		t.Fatalf("prompt not shown in the footer:\n%s", view)
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.quitting || m.confirmed || m.commanding {
+		t.Fatal("enter should run the command and close the prompt, not the selector")
+	}
+	if !m.files[0].Selected || m.files[1].Selected {
+		t.Errorf("selection = %v, %v; want only a.go selected", m.files[0].Selected, m.files[1].Selected)
+	}
+	if want := "deselect *.md: 1 matching, 1 changed"; m.status != want {
+		t.Errorf("status = %q, want %q", m.status, want)
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(":")})
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.quitting || m.commanding {
+		t.Error("esc should close the prompt without quitting")
+	}
+}
@@ -0,0 +1,69 @@
+package interactive
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// editorFinishedMsg reports that the editor opened on files[index] exited.
+type editorFinishedMsg struct {
+	index int
+	err   error
+}
+
+// editorCommand returns the user's editor command line from $VISUAL or
+// $EDITOR, or nil when neither is set. The value may carry arguments, as in
+// "code --wait".
+func editorCommand() []string {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if fields := strings.Fields(os.Getenv(name)); len(fields) > 0 {
+			return fields
+		}
+	}
+
+	return nil
+}
+
+// openEditor suspends the program and opens the file under the cursor in the
+// editor. The program resumes when the editor exits.
+func (m *Model) openEditor() tea.Cmd {
+	if len(m.editor) == 0 || len(m.rows) == 0 {
+		return nil
+	}
+
+	index := m.rows[m.cursor]
+	args := append(slices.Clone(m.editor[1:]), m.files[index].Path)
+	cmd := exec.Command(m.editor[0], args...)
+
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return editorFinishedMsg{index: index, err: err}
+	})
+}
+
+// finishEdit refreshes the size, modification time, and binary flag of the
+// edited file, since the edit may have changed them. Failures are shown in
+// the footer rather than ending the selector.
+func (m *Model) finishEdit(msg editorFinishedMsg) {
+	if msg.err != nil {
+		m.status = fmt.Sprintf("editor failed: %v", msg.err)
+
+		return
+	}
+
+	file := &m.files[msg.index]
+	info, err := os.Stat(file.Path)
+	if err != nil {
+		m.status = fmt.Sprintf("cannot refresh %s: %v", file.RelPath, err)
+
+		return
+	}
+
+	file.Size, file.ModTime = info.Size(), info.ModTime()
+	// Zero-byte files are text, as they are when scanning
+	file.IsBinary = info.Size() > 0 && m.binary.IsBinary(file.Path)
+}
@@ -0,0 +1,106 @@
+package interactive
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestEditorCommand(t *testing.T) {
+	tests := []struct {
+		name   string
+		visual string
+		editor string
+		want   []string
+	}{
+		{name: "neither set"},
+		{name: "editor", editor: "vi", want: []string{"vi"}},
+		{name: "visual wins", visual: "code --wait", editor: "vi", want: []string{"code", "--wait"}},
+		{name: "blank visual falls back", visual: "  ", editor: "nano", want: []string{"nano"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("VISUAL", tt.visual)
+			t.Setenv("EDITOR", tt.editor)
+
+			m := NewModel([]FileItem{{Path: "/tmp/a.go", RelPath: "a.go"}}, nil)
+			if strings.Join(m.editor, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("editor = %q, want %q", m.editor, tt.want)
+			}
+
+			m.Update(tea.WindowSizeMsg{Width: 200, Height: 10})
+			_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("e")})
+			footer := ansi.Strip(m.View())
+			if tt.want == nil {
+				if cmd != nil {
+					t.Error("edit key returned a command without an editor")
+				}
+				if !strings.Contains(footer, "set $EDITOR to edit") {
+					t.Errorf("footer does not explain the disabled edit key:\n%s", footer)
+				}
+			} else {
+				if cmd == nil {
+					t.Error("edit key returned no command")
+				}
+				if !strings.Contains(footer, "[e edit]") {
+					t.Errorf("footer does not list the edit key:\n%s", footer)
+				}
+			}
+		})
+	}
+}
+
+func TestFinishEditRefreshesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.txt")
+	if err := os.WriteFile(path, []byte("text\n"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	m := NewModel([]FileItem{{Path: path, RelPath: "a.txt", Size: 5}}, nil)
+	m.Update(tea.WindowSizeMsg{Width: 80, Height: 10})
+
+	// The edit turned the file into binary data
+	if err := os.WriteFile(path, []byte("\x00\x01\x02\x03binary"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	m.Update(editorFinishedMsg{index: 0})
+
+	info, _ := os.Stat(path)
+	file := m.files[0]
+	if file.Size != info.Size() || !file.ModTime.Equal(info.ModTime()) || !file.IsBinary {
+		t.Errorf("file after edit = %+v, want size %d, binary", file, info.Size())
+	}
+	if view := ansi.Strip(m.View()); !strings.Contains(view, "a.txt (binary)") {
+		t.Errorf("list does not show the refreshed binary flag:\n%s", view)
+	}
+}
+
+func TestFinishEditReportsFailures(t *testing.T) {
+	m := NewModel([]FileItem{{Path: filepath.Join(t.TempDir(), "gone.txt"), RelPath: "gone.txt", Size: 3}}, nil)
+	m.Update(tea.WindowSizeMsg{Width: 80, Height: 10})
+
+	m.Update(editorFinishedMsg{index: 0, err: errors.New("exit status 1")})
+	if view := ansi.Strip(m.View()); !strings.Contains(view, "editor failed: exit status 1") {
+		t.Errorf("footer does not report the editor failure:\n%s", view)
+	}
+
+	// The next key press restores the key help
+	m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	if view := ansi.Strip(m.View()); strings.Contains(view, "editor failed") {
+		t.Errorf("failure still shown after a key press:\n%s", view)
+	}
+
+	m.Update(editorFinishedMsg{index: 0})
+	if view := ansi.Strip(m.View()); !strings.Contains(view, "cannot refresh gone.txt") {
+		t.Errorf("footer does not report the failed refresh:\n%s", view)
+	}
+	if m.files[0].Size != 3 {
+		t.Errorf("size = %d, want the value from before the edit", m.files[0].Size)
+	}
+}
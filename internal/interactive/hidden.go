@@ -0,0 +1,84 @@
+package interactive
+
+import (
+	"fmt"
+	"strings"
+)
+
+// listedRows returns the indices of the files to list: every file while
+// hidden files are shown, and otherwise those the run's filters keep plus
+// excluded files already force-included.
+func (m *Model) listedRows() []int {
+	rows := make([]int, 0, len(m.files))
+	for i, file := range m.files {
+		if m.showHidden || file.Excluded == "" || file.Selected {
+			rows = append(rows, i)
+		}
+	}
+
+	return rows
+}
+
+// toggleHidden shows or hides the files the run's filters exclude, keeping
+// the cursor on the same file, or the nearest listed one after it.
+func (m *Model) toggleHidden() {
+	current := -1
+	if len(m.rows) > 0 {
+		current = m.rows[m.cursor]
+	}
+
+	m.showHidden = !m.showHidden
+	m.rows = m.listedRows()
+
+	m.cursor = 0
+	for row, i := range m.rows {
+		if i >= current {
+			m.cursor = row
+
+			break
+		}
+		m.cursor = row
+	}
+	m.ensureCursorVisible()
+}
+
+// hiddenCount returns how many files the run's filters exclude.
+func (m *Model) hiddenCount() int {
+	count := 0
+	for _, file := range m.files {
+		if file.Excluded != "" {
+			count++
+		}
+	}
+
+	return count
+}
+
+// renderHeader counts the selected files the filters keep apart from those
+// force-included, and the excluded files not listed.
+func (m *Model) renderHeader() string {
+	selected, forced, total, hidden := 0, 0, 0, 0
+	for _, file := range m.files {
+		switch {
+		case file.Excluded == "":
+			total++
+			if file.Selected {
+				selected++
+			}
+		case file.Selected:
+			forced++
+		case !m.showHidden:
+			hidden++
+		}
+	}
+
+	parts := []string{fmt.Sprintf("selected: %d/%d", selected, total)}
+	if forced > 0 {
+		parts = append(parts, fmt.Sprintf("force-included: %d", forced))
+	}
+	if hidden > 0 {
+		parts = append(parts, fmt.Sprintf("hidden: %d", hidden))
+	}
+
+	return "Select files (" + strings.Join(parts, ", ") + ")"
+}
@@ -0,0 +1,84 @@
+package interactive
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestHiddenFilesForceInclude(t *testing.T) {
+	files := []FileItem{
+		{RelPath: "a.go", Selected: true},
+		{RelPath: "b.log", Excluded: `user ignore glob: matches "*.log"`},
+		{RelPath: "c.go", Selected: true},
+	}
+	m := NewModel(files, nil)
+	m.Update(tea.WindowSizeMsg{Width: 80, Height: 20})
+	press := func(keys string) {
+		m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(keys)})
+	}
+
+	if got := ansi.Strip(m.renderContent()); strings.Contains(got, "b.log") {
+		t.Errorf("excluded file is listed before pressing h:\n%s", got)
+	}
+	if got := m.renderHeader(); got != "Select files (selected: 2/2, hidden: 1)" {
+		t.Errorf("header = %q", got)
+	}
+
+	// Select all and glob commands leave the excluded file alone
+	press("h")
+	press("a")
+	m.runCommand("select *.log")
+	if got := m.SelectedFiles(); len(got) != 2 {
+		t.Errorf("bulk selection force-included a file: %+v", got)
+	}
+	if got := ansi.Strip(m.renderContent()); !strings.Contains(got, `b.log (excluded: user ignore glob: matches "*.log")`) {
+		t.Errorf("excluded file is not listed with its reason:\n%s", got)
+	}
+
+	press("j")
+	press(" ")
+	if got := m.renderHeader(); got != "Select files (selected: 2/2, force-included: 1)" {
+		t.Errorf("header = %q", got)
+	}
+
+	// Hiding again keeps the force-included file listed and the cursor on it
+	press("h")
+	if m.files[m.rows[m.cursor]].RelPath != "b.log" {
+		t.Errorf("cursor moved to %s after hiding", m.files[m.rows[m.cursor]].RelPath)
+	}
+
+	var forced []string
+	for _, f := range m.SelectedFiles() {
+		if f.ForceIncluded {
+			forced = append(forced, f.RelPath)
+		}
+	}
+	if strings.Join(forced, ",") != "b.log" {
+		t.Errorf("force-included files = %v, want [b.log]", forced)
+	}
+}
+
+func TestHiddenToggleCursor(t *testing.T) {
+	files := []FileItem{
+		{RelPath: "a.go"},
+		{RelPath: "b.log", Excluded: "type: excluded"},
+		{RelPath: "c.log", Excluded: "type: excluded"},
+		{RelPath: "d.go"},
+	}
+	m := NewModel(files, nil)
+	m.Update(tea.WindowSizeMsg{Width: 80, Height: 20})
+	m.toggleHidden()
+	m.cursor = 2 // c.log
+
+	m.toggleHidden()
+	if got := m.files[m.rows[m.cursor]].RelPath; got != "d.go" {
+		t.Errorf("cursor on %s after hiding c.log, want the next listed file d.go", got)
+	}
+
+	if NewModel([]FileItem{{RelPath: "a.go"}}, nil).keys.Hidden.Enabled() {
+		t.Error("hidden key is enabled with nothing excluded")
+	}
+}
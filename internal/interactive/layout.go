@@ -0,0 +1,59 @@
+package interactive
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// compactHeight is the terminal height below which the footer collapses to
+// compactHelp and the preview pane is not shown, so the list keeps its rows.
+const compactHeight = 8
+
+// compactHelp replaces the key help strip on short terminals.
+const compactHelp = "? for help"
+
+// compact reports whether the terminal is too short for the full layout.
+func (m *Model) compact() bool {
+	return m.height < compactHeight
+}
+
+// listHeight returns the rows of the file list: what the header, footer,
+// and preview pane leave, and at least one.
+func (m *Model) listHeight() int {
+	if m.compact() {
+		return max(m.height-2, 1)
+	}
+
+	return max(m.height-4-m.previewHeight(), 1)
+}
+
+// renderHelp renders the full-screen help overlay: every enabled binding
+// with its keys, cut to the terminal size.
+func (m *Model) renderHelp() string {
+	bindings := []key.Binding{
+		m.keys.Up, m.keys.Down, m.keys.PageUp, m.keys.PageDown, m.keys.Top, m.keys.Bottom, m.keys.Toggle, m.keys.SelectAll, m.keys.DeselectAll,
+		m.keys.ForceAll, m.keys.Preview, m.keys.Edit, m.keys.Command, m.keys.Hidden, m.keys.Confirm, m.keys.Quit,
+	}
+
+	rows := []string{m.styles.Header.Render("Keys"), ""}
+	for _, b := range bindings {
+		if b.Enabled() {
+			rows = append(rows, fmt.Sprintf("%-12s %s", b.Help().Key, b.Help().Desc))
+		}
+	}
+	rows = append(rows, "", m.styles.Dim.Render("Press any key to return"))
+
+	if m.height > 0 && len(rows) > m.height {
+		rows = rows[:m.height]
+	}
+	if m.width > 0 {
+		for i, row := range rows {
+			rows[i] = ansi.Truncate(row, m.width, ellipsis)
+		}
+	}
+
+	return strings.Join(rows, "\n")
+}
@@ -0,0 +1,85 @@
+package interactive
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestResizeKeepsCursorVisible(t *testing.T) {
+	files := make([]FileItem, 50)
+	for i := range files {
+		files[i] = FileItem{RelPath: fmt.Sprintf("file%02d.go", i), Selected: true}
+	}
+	m := NewModel(files, nil)
+	m.Update(tea.WindowSizeMsg{Width: 80, Height: 20})
+	for range 30 {
+		m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("j")})
+	}
+
+	visible := func(step string) {
+		t.Helper()
+		top, height := m.viewport.YOffset, m.viewport.Height
+		if m.cursor < top || m.cursor >= top+height {
+			t.Errorf("%s: cursor %d outside rows %d-%d", step, m.cursor, top, top+height-1)
+		}
+		if !strings.Contains(ansi.Strip(m.View()), "> [x] file30.go") {
+			t.Errorf("%s: cursor row not drawn:\n%s", step, ansi.Strip(m.View()))
+		}
+	}
+	visible("initial")
+	offset := m.viewport.YOffset
+
+	m.Update(tea.WindowSizeMsg{Width: 100, Height: 20})
+	if m.viewport.YOffset != offset {
+		t.Errorf("width change moved the scroll position from %d to %d", offset, m.viewport.YOffset)
+	}
+
+	m.Update(tea.WindowSizeMsg{Width: 80, Height: 10})
+	visible("shrunk")
+
+	m.Update(tea.WindowSizeMsg{Width: 80, Height: 5})
+	visible("compact")
+
+	// Everything fits, so no offset is left scrolling past the end
+	m.Update(tea.WindowSizeMsg{Width: 80, Height: 60})
+	visible("grown")
+	if m.viewport.YOffset != 0 {
+		t.Errorf("grown: YOffset = %d, want 0 with every row on screen", m.viewport.YOffset)
+	}
+}
+
+func TestSmallTerminalLayout(t *testing.T) {
+	files := []FileItem{{RelPath: "a.go"}, {RelPath: "b.go"}, {RelPath: "c.go"}}
+	m := NewModel(files, nil)
+	m.preview = true
+
+	for height := 3; height < compactHeight; height++ {
+		m.Update(tea.WindowSizeMsg{Width: 40, Height: height})
+		lines := strings.Split(ansi.Strip(m.View()), "\n")
+		if len(lines) > height {
+			t.Errorf("height %d: view has %d lines:\n%s", height, len(lines), strings.Join(lines, "\n"))
+		}
+		if last := lines[len(lines)-1]; last != compactHelp {
+			t.Errorf("height %d: footer = %q, want %q", height, last, compactHelp)
+		}
+	}
+
+	m.Update(tea.WindowSizeMsg{Width: 40, Height: 20})
+	if strings.Contains(m.View(), compactHelp) {
+		t.Error("full-height footer collapsed to the compact hint")
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("?")})
+	if view := ansi.Strip(m.View()); !strings.Contains(view, "space/x") || !strings.Contains(view, "Press any key") {
+		t.Errorf("help overlay missing bindings:\n%s", view)
+	}
+	// The key closing the overlay is not also acted on
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("j")})
+	if m.help || m.cursor != 0 {
+		t.Errorf("help = %v, cursor = %d after closing the overlay", m.help, m.cursor)
+	}
+}
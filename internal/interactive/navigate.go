@@ -0,0 +1,70 @@
+package interactive
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxCount caps a typed count prefix; longer counts stop growing.
+const maxCount = 1_000_000
+
+// typeCount adds a digit key to the count prefix and reports whether it
+// did. A leading zero is not a count.
+func (m *Model) typeCount(msg tea.KeyMsg) bool {
+	if msg.Type != tea.KeyRunes || len(msg.Runes) != 1 || msg.Alt {
+		return false
+	}
+	digit := msg.Runes[0]
+	if digit < '0' || digit > '9' || digit == '0' && m.count == 0 {
+		return false
+	}
+	m.count = min(m.count*10+int(digit-'0'), maxCount)
+
+	return true
+}
+
+// renderCount renders the footer shown while a count is typed.
+func (m *Model) renderCount() string {
+	return fmt.Sprintf("%d (G or g to jump to row %d, j/k to move %d rows)", m.count, m.count, m.count)
+}
+
+// moveCursor shifts the cursor by delta, clamped to the list bounds.
+func (m *Model) moveCursor(delta int) {
+	m.jumpTo(m.cursor + delta)
+}
+
+// jumpTo moves the cursor to row, clamped to the list bounds, and scrolls
+// it into view.
+func (m *Model) jumpTo(row int) {
+	if len(m.rows) == 0 {
+		return
+	}
+	m.cursor = min(max(row, 0), len(m.rows)-1)
+	m.ensureCursorVisible()
+}
+
+// page moves the cursor by pages viewport heights and scrolls the viewport
+// as far, so the cursor keeps its place on screen until the list ends.
+func (m *Model) page(pages int) {
+	if !m.ready {
+		m.moveCursor(pages)
+
+		return
+	}
+	step := pages * max(m.viewport.Height, 1)
+	m.viewport.SetYOffset(m.viewport.YOffset + step)
+	m.moveCursor(step)
+}
+
+// followViewport moves the cursor onto the nearest row still on screen
+// after the viewport scrolled on its own, as with the mouse wheel, so the
+// next key does not jump back to where the cursor was left.
+func (m *Model) followViewport() {
+	if len(m.rows) == 0 {
+		return
+	}
+	top := m.viewport.YOffset
+	bottom := min(top+m.viewport.Height, len(m.rows)) - 1
+	m.cursor = min(max(m.cursor, top), bottom)
+}
@@ -0,0 +1,118 @@
+package interactive
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestNavigationKeys(t *testing.T) {
+	files := make([]FileItem, 1000)
+	for i := range files {
+		files[i] = FileItem{RelPath: fmt.Sprintf("file%04d.go", i), Selected: true}
+	}
+	m := NewModel(files, nil)
+	// 20 rows leave a 16-row list under the header and footer
+	m.Update(tea.WindowSizeMsg{Width: 80, Height: 20})
+	height := m.viewport.Height
+
+	press := func(keys ...string) {
+		t.Helper()
+		for _, k := range keys {
+			switch k {
+			case "pgup":
+				m.Update(tea.KeyMsg{Type: tea.KeyPgUp})
+			case "pgdown":
+				m.Update(tea.KeyMsg{Type: tea.KeyPgDown})
+			case "ctrl+d":
+				m.Update(tea.KeyMsg{Type: tea.KeyCtrlD})
+			case "ctrl+u":
+				m.Update(tea.KeyMsg{Type: tea.KeyCtrlU})
+			case "esc":
+				m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+			default:
+				m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)})
+			}
+		}
+	}
+
+	tests := []struct {
+		name   string
+		keys   []string
+		cursor int
+	}{
+		{name: "page down", keys: []string{"pgdown"}, cursor: height},
+		{name: "ctrl+d twice", keys: []string{"ctrl+d", "ctrl+d"}, cursor: 2 * height},
+		{name: "page up past the top", keys: []string{"j", "pgup"}, cursor: 0},
+		{name: "ctrl+u", keys: []string{"G", "ctrl+u"}, cursor: 999 - height},
+		{name: "page down past the end", keys: []string{"G", "k", "pgdown"}, cursor: 999},
+		{name: "bottom", keys: []string{"G"}, cursor: 999},
+		{name: "top", keys: []string{"G", "g"}, cursor: 0},
+		{name: "count jump", keys: []string{"4", "2", "G"}, cursor: 41},
+		{name: "count jump with g", keys: []string{"1", "0", "0", "g"}, cursor: 99},
+		{name: "count past the end", keys: []string{"5", "0", "0", "0", "G"}, cursor: 999},
+		{name: "count moves", keys: []string{"1", "0", "j", "3", "k"}, cursor: 7},
+		{name: "count pages", keys: []string{"3", "pgdown"}, cursor: 3 * height},
+		{name: "leading zero is no count", keys: []string{"0", "G"}, cursor: 999},
+		{name: "escape drops the count", keys: []string{"4", "2", "esc", "G"}, cursor: 999},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			press("g")
+			press(tt.keys...)
+			if m.quitting {
+				t.Fatal("selector quit")
+			}
+			if m.cursor != tt.cursor {
+				t.Errorf("cursor = %d, want %d", m.cursor, tt.cursor)
+			}
+			top := m.viewport.YOffset
+			if m.cursor < top || m.cursor >= top+height {
+				t.Errorf("cursor %d outside rows %d-%d", m.cursor, top, top+height-1)
+			}
+			want := fmt.Sprintf("> [x] file%04d.go", tt.cursor)
+			if !strings.Contains(ansi.Strip(m.View()), want) {
+				t.Errorf("cursor row %q not drawn", want)
+			}
+		})
+	}
+}
+
+func TestNavigationFollowsViewport(t *testing.T) {
+	files := make([]FileItem, 100)
+	for i := range files {
+		files[i] = FileItem{RelPath: fmt.Sprintf("file%02d.go", i)}
+	}
+	m := NewModel(files, nil)
+	m.Update(tea.WindowSizeMsg{Width: 80, Height: 20})
+
+	// Keys the viewport binds for itself no longer scroll it
+	for _, k := range []string{"f", "b", "d", "u", " "} {
+		m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)})
+	}
+	if m.viewport.YOffset != 0 {
+		t.Errorf("YOffset = %d after keys, want 0", m.viewport.YOffset)
+	}
+
+	// The wheel scrolls the viewport, taking the cursor along
+	for range 10 {
+		m.Update(tea.MouseMsg{Button: tea.MouseButtonWheelDown, Action: tea.MouseActionPress})
+	}
+	top := m.viewport.YOffset
+	if top == 0 || m.cursor != top {
+		t.Fatalf("after scrolling to row %d the cursor is on row %d", top, m.cursor)
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("j")})
+	if m.cursor != top+1 || m.viewport.YOffset != top {
+		t.Errorf("j moved the cursor to %d and the viewport to %d, want %d and %d", m.cursor, m.viewport.YOffset, top+1, top)
+	}
+
+	m.Update(tea.MouseMsg{Button: tea.MouseButtonWheelUp, Action: tea.MouseActionPress})
+	if bottom := m.viewport.YOffset + m.viewport.Height - 1; m.cursor > bottom {
+		t.Errorf("cursor %d below the last row on screen, %d", m.cursor, bottom)
+	}
+}
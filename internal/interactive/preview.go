@@ -0,0 +1,83 @@
+package interactive
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// previewTabWidth is the number of spaces a tab occupies in the preview pane.
+const previewTabWidth = 4
+
+// previewHeight returns the rows taken by the preview pane: half of the space
+// below the header, or none when the pane is hidden or the terminal is too
+// short for it.
+func (m *Model) previewHeight() int {
+	if !m.preview || m.compact() {
+		return 0
+	}
+
+	return max(m.height-4, 0) / 2
+}
+
+// renderPreview renders the preview pane: a title row naming the file under
+// the cursor followed by its leading lines, padded to previewHeight rows.
+// Binary files are never read.
+func (m *Model) renderPreview() string {
+	height := m.previewHeight()
+	if height == 0 || len(m.rows) == 0 {
+		return strings.Repeat("\n", max(height-1, 0))
+	}
+
+	file := m.files[m.rows[m.cursor]]
+	rows := []string{m.styles.Dim.Render(m.fit("── " + file.RelPath + " "))}
+
+	switch lines, err := m.previewLines(file); {
+	case file.IsBinary:
+		rows = append(rows, m.styles.Dim.Render(m.fit("(binary file, no preview)")))
+	case err != nil:
+		rows = append(rows, m.styles.Warning.Render(m.fit(err.Error())))
+	default:
+		for _, line := range lines[:min(len(lines), height-1)] {
+			rows = append(rows, m.fit(line))
+		}
+	}
+
+	for len(rows) < height {
+		rows = append(rows, "")
+	}
+
+	return strings.Join(rows[:height], "\n")
+}
+
+// previewLines returns the leading lines of a text file through the content
+// cache, so confirming the selection does not read the file again.
+func (m *Model) previewLines(file FileItem) ([]string, error) {
+	if file.IsBinary {
+		return nil, nil
+	}
+
+	lines, _, err := m.cache.Load(file.Path)
+
+	return lines, err
+}
+
+// fit makes a line of file content safe to draw: escape sequences and other
+// control characters are removed, tabs are expanded, and the result is cut to
+// the terminal width.
+func (m *Model) fit(line string) string {
+	line = strings.ReplaceAll(ansi.Strip(line), "\t", strings.Repeat(" ", previewTabWidth))
+	line = strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return -1
+		}
+
+		return r
+	}, line)
+
+	if m.width > 0 {
+		line = ansi.Truncate(line, m.width, ellipsis)
+	}
+
+	return line
+}
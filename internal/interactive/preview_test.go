@@ -0,0 +1,64 @@
+package interactive
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/connerohnesorge/catls/internal/contentcache"
+)
+
+func TestPreviewPane(t *testing.T) {
+	dir := t.TempDir()
+	textPath := filepath.Join(dir, "main.go")
+	content := "package main\n\n\tfunc main() {}\x1b[31m red\x1b[0m\r\n" + strings.Repeat("// padding line\n", 50)
+	if err := os.WriteFile(textPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	cache := contentcache.New(contentcache.DefaultMaxBytes)
+	files := []FileItem{
+		{Path: textPath, RelPath: "main.go"},
+		{Path: filepath.Join(dir, "missing.bin"), RelPath: "missing.bin", IsBinary: true},
+	}
+	m := NewModel(files, cache)
+	m.Update(tea.WindowSizeMsg{Width: 30, Height: 24})
+	before := strings.Count(m.View(), "\n")
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p")})
+	view := m.View()
+
+	if got := strings.Count(view, "\n"); got != before {
+		t.Errorf("preview changed the view height from %d to %d lines", before, got)
+	}
+	for i, line := range strings.Split(view, "\n") {
+		if got := ansi.StringWidth(line); got > 30 {
+			t.Errorf("view line %d is %d cells wide: %q", i, got, ansi.Strip(line))
+		}
+	}
+	plain := ansi.Strip(view)
+	for _, want := range []string{"── main.go", "package main", "    func main() {} red"} {
+		if !strings.Contains(plain, want) {
+			t.Errorf("preview missing %q:\n%s", want, plain)
+		}
+	}
+
+	info, _ := os.Stat(textPath)
+	if _, ok := cache.Lookup(textPath, info.Size(), info.ModTime()); !ok {
+		t.Error("previewed file was not cached")
+	}
+
+	// Binary files are described, never read
+	m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	if plain := ansi.Strip(m.View()); !strings.Contains(plain, "(binary file, no preview)") || strings.Contains(plain, "no such file") {
+		t.Errorf("binary preview:\n%s", plain)
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p")})
+	if strings.Contains(ansi.Strip(m.View()), "──") {
+		t.Error("preview still shown after toggling it off")
+	}
+}
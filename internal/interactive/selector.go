@@ -4,11 +4,14 @@ package interactive
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/connerohnesorge/catls/internal/contentcache"
+	"github.com/connerohnesorge/catls/internal/scanner"
 )
 
 // FileItem represents a file in the selector.
@@ -16,16 +19,41 @@ type FileItem struct {
 	Path     string
 	RelPath  string
 	IsBinary bool
+	Size     int64     // Size in bytes; refreshed after the file is edited
+	ModTime  time.Time // Modification time; refreshed after the file is edited
 	Selected bool
+	// OverDirLimit marks files past --max-files-per-dir; they start deselected
+	OverDirLimit bool
+	// Excluded is why the run's filters leave the file out. Excluded files
+	// are listed only while hidden files are shown, and start deselected
+	Excluded string
+	// ForceIncluded is set by SelectedFiles on excluded files the user
+	// selected anyway, which bypass the filters for this run
+	ForceIncluded bool
+	// Unselectable, when set, is why the run could not write the file
+	// usefully, such as a binary written only as a placeholder. It is shown
+	// beside the file; toggling the file only repeats it, and only ForceAll
+	// selects it in bulk
+	Unselectable string
 }
 
 // KeyMap defines the keybindings for the selector.
 type KeyMap struct {
 	Up          key.Binding
 	Down        key.Binding
+	PageUp      key.Binding
+	PageDown    key.Binding
+	Top         key.Binding
+	Bottom      key.Binding
 	Toggle      key.Binding
 	SelectAll   key.Binding
 	DeselectAll key.Binding
+	ForceAll    key.Binding
+	Preview     key.Binding
+	Edit        key.Binding
+	Command     key.Binding
+	Hidden      key.Binding
+	Help        key.Binding
 	Confirm     key.Binding
 	Quit        key.Binding
 }
@@ -41,6 +69,22 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("down", "j"),
 			key.WithHelp("↓/j", "down"),
 		),
+		PageUp: key.NewBinding(
+			key.WithKeys("pgup", "ctrl+u"),
+			key.WithHelp("pgup/ctrl+u", "page up"),
+		),
+		PageDown: key.NewBinding(
+			key.WithKeys("pgdown", "ctrl+d"),
+			key.WithHelp("pgdn/ctrl+d", "page down"),
+		),
+		Top: key.NewBinding(
+			key.WithKeys("g", "home"),
+			key.WithHelp("g/home", "first row (Ng: row N)"),
+		),
+		Bottom: key.NewBinding(
+			key.WithKeys("G", "end"),
+			key.WithHelp("G/end", "last row (NG: row N)"),
+		),
 		Toggle: key.NewBinding(
 			key.WithKeys(" ", "x"),
 			key.WithHelp("space/x", "toggle"),
@@ -53,6 +97,30 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("A"),
 			key.WithHelp("A", "deselect all"),
 		),
+		ForceAll: key.NewBinding(
+			key.WithKeys("ctrl+a"),
+			key.WithHelp("ctrl+a", "select all, even unselectable"),
+		),
+		Preview: key.NewBinding(
+			key.WithKeys("p"),
+			key.WithHelp("p", "preview"),
+		),
+		Edit: key.NewBinding(
+			key.WithKeys("e"),
+			key.WithHelp("e", "edit"),
+		),
+		Command: key.NewBinding(
+			key.WithKeys(":"),
+			key.WithHelp(":", "glob"),
+		),
+		Hidden: key.NewBinding(
+			key.WithKeys("h"),
+			key.WithHelp("h", "hidden"),
+		),
+		Help: key.NewBinding(
+			key.WithKeys("?"),
+			key.WithHelp("?", "help"),
+		),
 		Confirm: key.NewBinding(
 			key.WithKeys("enter"),
 			key.WithHelp("enter", "confirm"),
@@ -67,7 +135,8 @@ func DefaultKeyMap() KeyMap {
 // Model is the bubbletea model for the file selector.
 type Model struct {
 	files     []FileItem
-	cursor    int
+	rows      []int // Indices into files of the listed rows
+	cursor    int   // Index into rows
 	keys      KeyMap
 	viewport  viewport.Model
 	ready     bool
@@ -75,21 +144,46 @@ type Model struct {
 	height    int
 	quitting  bool
 	confirmed bool
+	preview   bool                   // Show the head of the file under the cursor
+	cache     *contentcache.Cache    // Holds previewed content for later processing
+	editor    []string               // Editor command line; empty disables editing
+	binary    scanner.BinaryDetector // Reclassifies files after they are edited
+	status    string                 // Replaces the footer until the next key press
+	// commanding shows the command prompt, which receives every key
+	commanding  bool
+	commandLine string
+	showHidden  bool // List the files the run's filters exclude
+	help        bool // Show the full-screen help overlay, which receives every key
+	count       int  // Typed count prefix, as in vim's 42G; 0 when none
+	styles      Styles
 }
 
-// NewModel creates a new file selector model.
-func NewModel(files []FileItem) Model {
-	return Model{
-		files: files,
-		keys:  DefaultKeyMap(),
+// NewModel creates a new file selector model. Previewed files are read
+// through cache, which may be nil. Files are edited with $VISUAL or $EDITOR;
+// with neither set, the edit key is disabled.
+func NewModel(files []FileItem, cache *contentcache.Cache) Model {
+	m := Model{
+		files:  files,
+		keys:   DefaultKeyMap(),
+		cache:  cache,
+		editor: editorCommand(),
+		binary: &scanner.FileBinaryDetector{},
+		styles: DefaultStyles(),
 	}
+	m.keys.Edit.SetEnabled(len(m.editor) > 0)
+	m.keys.Hidden.SetEnabled(m.hiddenCount() > 0)
+	m.rows = m.listedRows()
+
+	return m
 }
 
-// SelectedFiles returns the list of selected files.
+// SelectedFiles returns the list of selected files. Selected excluded files
+// are marked ForceIncluded.
 func (m *Model) SelectedFiles() []FileItem {
 	var selected []FileItem
 	for _, f := range m.files {
 		if f.Selected {
+			f.ForceIncluded = f.Excluded != ""
 			selected = append(selected, f)
 		}
 	}
@@ -114,6 +208,8 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.resize(typed.Width, typed.Height)
 
 		return m, nil
+	case editorFinishedMsg:
+		m.finishEdit(typed)
 	}
 
 	if !m.ready {
@@ -121,9 +217,18 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	}
 
 	m.viewport.SetContent(m.renderContent())
+	if _, ok := msg.(tea.KeyMsg); ok {
+		// The cursor drives the viewport; its own paging keys would
+		// scroll away from the cursor
+		return m, nil
+	}
 
 	var cmd tea.Cmd
+	offset := m.viewport.YOffset
 	m.viewport, cmd = m.viewport.Update(msg)
+	if m.viewport.YOffset != offset {
+		m.followViewport()
+	}
 
 	return m, cmd
 }
@@ -131,7 +236,34 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 // handleKey routes a key event. The returned bool is true when the caller
 // should immediately return with the supplied command.
 func (m *Model) handleKey(msg tea.KeyMsg) (tea.Cmd, bool) {
+	m.status = ""
+
+	if m.help {
+		// Any key closes the overlay without acting on the list
+		m.help = false
+
+		return nil, true
+	}
+
+	if m.commanding {
+		// Typed keys must not also scroll the viewport
+		m.handleCommandKey(msg)
+		if m.ready {
+			m.viewport.SetContent(m.renderContent())
+		}
+
+		return nil, true
+	}
+
+	if m.typeCount(msg) {
+		return nil, true
+	}
+	count := m.count
+	m.count = 0
+
 	switch {
+	case count > 0 && msg.Type == tea.KeyEsc:
+		// Escape drops a typed count rather than quitting
 	case key.Matches(msg, m.keys.Quit):
 		m.quitting = true
 
@@ -141,52 +273,99 @@ func (m *Model) handleKey(msg tea.KeyMsg) (tea.Cmd, bool) {
 
 		return tea.Quit, true
 	case key.Matches(msg, m.keys.Up):
-		m.moveCursor(-1)
+		m.moveCursor(-max(count, 1))
 	case key.Matches(msg, m.keys.Down):
-		m.moveCursor(1)
+		m.moveCursor(max(count, 1))
+	case key.Matches(msg, m.keys.PageUp):
+		m.page(-max(count, 1))
+	case key.Matches(msg, m.keys.PageDown):
+		m.page(max(count, 1))
+	case key.Matches(msg, m.keys.Top):
+		m.jumpTo(max(count, 1) - 1)
+	case key.Matches(msg, m.keys.Bottom):
+		if count == 0 {
+			count = len(m.rows)
+		}
+		m.jumpTo(count - 1)
 	case key.Matches(msg, m.keys.Toggle):
 		m.toggleCurrent()
 	case key.Matches(msg, m.keys.SelectAll):
 		m.setAll(true)
+	case key.Matches(msg, m.keys.ForceAll):
+		m.forceAll()
 	case key.Matches(msg, m.keys.DeselectAll):
 		m.setAll(false)
+	case key.Matches(msg, m.keys.Preview):
+		m.preview = !m.preview
+		m.resize(m.width, m.height)
+	case key.Matches(msg, m.keys.Edit):
+		return m.openEditor(), true
+	case key.Matches(msg, m.keys.Command):
+		m.openCommand()
+	case key.Matches(msg, m.keys.Hidden):
+		m.toggleHidden()
+	case key.Matches(msg, m.keys.Help):
+		m.help = true
+
+		return nil, true
 	}
 
 	return nil, false
 }
 
-// moveCursor shifts the cursor by delta, clamped to the list bounds.
-func (m *Model) moveCursor(delta int) {
-	next := m.cursor + delta
-	if next < 0 || next >= len(m.files) {
+// toggleCurrent flips the selection state of the row under the cursor.
+// Selecting an excluded file force-includes it. An Unselectable file is
+// left alone, and the status line says why.
+func (m *Model) toggleCurrent() {
+	if len(m.rows) == 0 {
 		return
 	}
-	m.cursor = next
-	m.ensureCursorVisible()
-}
+	file := &m.files[m.rows[m.cursor]]
+	if file.Unselectable != "" && !file.Selected {
+		m.status = fmt.Sprintf("%s cannot be selected: %s", file.RelPath, file.Unselectable)
 
-// toggleCurrent flips the selection state of the row under the cursor.
-func (m *Model) toggleCurrent() {
-	if len(m.files) == 0 {
 		return
 	}
-	m.files[m.cursor].Selected = !m.files[m.cursor].Selected
+	file.Selected = !file.Selected
 }
 
-// setAll sets every file's selected flag to the same value.
+// setAll sets the selected flag of every listed file to the same value.
+// Excluded files are never selected in bulk, only one at a time, and
+// Unselectable files only by ForceAll.
 func (m *Model) setAll(selected bool) {
-	for i := range m.files {
-		m.files[i].Selected = selected
+	for _, i := range m.rows {
+		if !selected || m.files[i].Excluded == "" && m.files[i].Unselectable == "" {
+			m.files[i].Selected = selected
+		}
+	}
+}
+
+// forceAll selects every listed file the filters keep, including
+// Unselectable ones.
+func (m *Model) forceAll() {
+	for _, i := range m.rows {
+		if m.files[i].Excluded == "" {
+			m.files[i].Selected = true
+		}
 	}
 }
 
-// resize initializes or updates the internal viewport for the given size.
+// resize initializes the internal viewport for the given size, or resizes
+// it in place so the scroll position carries over. The preview pane, when
+// shown, takes the lower half.
 func (m *Model) resize(w, h int) {
 	m.width = w
 	m.height = h
-	m.viewport = viewport.New(w, h-4)
+	if m.ready {
+		m.viewport.Width, m.viewport.Height = w, m.listHeight()
+	} else {
+		m.viewport = viewport.New(w, m.listHeight())
+		m.ready = true
+	}
 	m.viewport.SetContent(m.renderContent())
-	m.ready = true
+	// A taller viewport may now reach past the last row
+	m.viewport.SetYOffset(m.viewport.YOffset)
+	m.ensureCursorVisible()
 }
 
 // ensureCursorVisible scrolls the viewport so the cursor row stays on screen.
@@ -205,17 +384,6 @@ func (m *Model) ensureCursorVisible() {
 	}
 }
 
-// Styling used to render the selector. Colors are standard 16-color indices so
-// the UI adapts to the user's terminal theme.
-var (
-	headerStyle   = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12"))
-	selectedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
-	cursorStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("14"))
-	dimStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
-	binaryStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("11"))
-	normalStyle   = lipgloss.NewStyle()
-)
-
 // View implements tea.Model.
 func (m *Model) View() string {
 	if !m.ready {
@@ -226,26 +394,56 @@ func (m *Model) View() string {
 		return "Cancelled.\n"
 	}
 
+	if m.help {
+		return m.renderHelp()
+	}
+
 	if m.confirmed {
 		selected := m.SelectedFiles()
 
 		return fmt.Sprintf("Selected %d file(s).\n", len(selected))
 	}
 
-	selectedCount := len(m.SelectedFiles())
-	header := headerStyle.Render(fmt.Sprintf("Select files (selected: %d/%d)", selectedCount, len(m.files)))
+	header := m.styles.Header.Render(m.renderHeader())
 	content := m.viewport.View()
 	footer := fmt.Sprintf(
-		"%s %s %s %s %s %s",
+		"%s %s %s %s %s %s %s %s %s",
 		m.renderKeyHelp(m.keys.Up),
 		m.renderKeyHelp(m.keys.Down),
 		m.renderKeyHelp(m.keys.Toggle),
 		m.renderKeyHelp(m.keys.SelectAll),
 		m.renderKeyHelp(m.keys.DeselectAll),
+		m.renderKeyHelp(m.keys.Preview),
+		m.renderEditHelp(),
+		m.renderKeyHelp(m.keys.Command),
 		m.renderKeyHelp(m.keys.Confirm),
 	)
+	if m.keys.Hidden.Enabled() {
+		footer += " " + m.renderKeyHelp(m.keys.Hidden)
+	}
+	if m.compact() {
+		footer = compactHelp
+	}
+	footerStyle := m.styles.Dim
+	switch {
+	case m.commanding:
+		footer, footerStyle = m.renderCommand(), m.styles.Normal
+	case m.count > 0:
+		footer, footerStyle = m.renderCount(), m.styles.Normal
+	case m.status != "":
+		footer, footerStyle = m.status, m.styles.Warning
+	}
 
-	return fmt.Sprintf("%s\n%s\n%s", header, content, dimStyle.Render(footer))
+	if m.width > 0 {
+		header = ansi.Truncate(header, m.width, ellipsis)
+		footer = ansi.Truncate(footer, m.width, ellipsis)
+	}
+
+	if m.previewHeight() > 0 {
+		content += "\n" + m.renderPreview()
+	}
+
+	return fmt.Sprintf("%s\n%s\n%s", header, content, footerStyle.Render(footer))
 }
 
 // renderKeyHelp formats a single key binding for the footer help strip.
@@ -253,48 +451,111 @@ func (*Model) renderKeyHelp(k key.Binding) string {
 	return fmt.Sprintf("[%s %s]", k.Keys()[0], k.Help().Desc)
 }
 
-// renderContent produces the scrollable body: one row per file with cursor,
-// checkbox, and path (dimmed for binaries).
+// renderEditHelp formats the edit binding, or explains how to enable it.
+func (m *Model) renderEditHelp() string {
+	if !m.keys.Edit.Enabled() {
+		return "[e: set $EDITOR to edit]"
+	}
+
+	return m.renderKeyHelp(m.keys.Edit)
+}
+
+// Fixed-width columns preceding the path on every row: cursor, space,
+// checkbox, space.
+const (
+	cursorWidth    = 1
+	checkboxWidth  = 3
+	rowPrefixWidth = cursorWidth + 1 + checkboxWidth + 1
+	binarySuffix   = " (binary)"
+	overDirSuffix  = " (over per-dir limit)"
+	excludedSuffix = " (excluded: %s)"
+	reasonSuffix   = " (%s)"
+
+	// minPathWidth is the narrowest path column worth showing beside the
+	// binary annotation.
+	minPathWidth = 8
+)
+
+// renderContent produces the scrollable body: one row per listed file with
+// cursor, checkbox, and path (colored for binaries, dimmed for excluded and
+// unselectable files).
 func (m *Model) renderContent() string {
 	var b strings.Builder
 
-	for i, file := range m.files {
-		cursor := " "
-		if i == m.cursor {
-			cursor = cursorStyle.Render(">")
-		}
+	for row, i := range m.rows {
+		b.WriteString(m.renderRow(m.files[i], row == m.cursor))
+		b.WriteString("\n")
+	}
 
-		checkbox := "[ ]"
-		if file.Selected {
-			checkbox = selectedStyle.Render("[x]")
-		}
+	return b.String()
+}
 
-		path := file.RelPath
-		style := normalStyle
-		if file.IsBinary {
-			style = binaryStyle
-			path = file.RelPath + dimStyle.Render(" (binary)")
+// renderRow renders a single file row. The cursor and checkbox columns have a
+// fixed width and the path is middle-truncated to the remaining viewport width
+// before styling, so escape sequences are never cut.
+func (m *Model) renderRow(file FileItem, isCursor bool) string {
+	cursor := " "
+	if isCursor {
+		cursor = m.styles.Cursor.Render(">")
+	}
+
+	checkbox := "[ ]"
+	if file.Selected {
+		checkbox = m.styles.Selected.Render("[x]")
+	}
+
+	suffix := ""
+	style := m.styles.Normal
+	switch {
+	case file.Excluded != "":
+		suffix = fmt.Sprintf(excludedSuffix, file.Excluded)
+		style = m.styles.Dim
+	case file.Unselectable != "":
+		suffix = fmt.Sprintf(reasonSuffix, file.Unselectable)
+		style = m.styles.Dim
+	case file.IsBinary:
+		suffix = binarySuffix
+		style = m.styles.Warning
+	case file.OverDirLimit:
+		suffix = overDirSuffix
+	}
+
+	path := file.RelPath
+	if m.width > 0 {
+		available := m.width - rowPrefixWidth
+		if available-ansi.StringWidth(suffix) < minPathWidth {
+			// Too narrow for the annotation; the color still marks binaries
+			suffix = ""
 		}
+		path = truncateMiddle(path, available-ansi.StringWidth(suffix))
+	}
 
-		b.WriteString(fmt.Sprintf("%s %s %s\n", cursor, checkbox, style.Render(path)))
+	row := cursor + " " + checkbox + " " + style.Render(path)
+	if suffix != "" {
+		row += m.styles.Dim.Render(suffix)
 	}
 
-	return b.String()
+	return row
 }
 
-// SelectFiles launches the interactive file selector and returns the selected files.
-// Returns nil if the user cancels or no files are selected.
-func SelectFiles(files []FileItem) ([]FileItem, error) {
+// SelectFiles launches the interactive file selector on term and returns the
+// selected files once it has released the terminal. Returns nil if the user
+// cancels or no files are selected. Files previewed during selection are left
+// in cache, which may be nil.
+func SelectFiles(files []FileItem, cache *contentcache.Cache, term Terminal) ([]FileItem, error) {
 	if len(files) == 0 {
 		return nil, nil
 	}
 
 	for i := range files {
-		files[i].Selected = true
+		files[i].Selected = !files[i].OverDirLimit && files[i].Excluded == "" && files[i].Unselectable == ""
 	}
 
-	m := NewModel(files)
-	p := tea.NewProgram(&m, tea.WithAltScreen())
+	m := NewModel(files, cache)
+	opts, styles, release := term.ProgramOptions()
+	defer release()
+	m.styles = styles
+	p := tea.NewProgram(&m, opts...)
 
 	if _, err := p.Run(); err != nil {
 		return nil, fmt.Errorf("failed to run selector: %w", err)
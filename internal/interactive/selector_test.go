@@ -0,0 +1,177 @@
+package interactive
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// trickyPaths exercises wide, combining, and emoji characters alongside plain
+// long paths.
+var trickyPaths = []string{
+	"main.go",
+	"src/very/deeply/nested/directory/structure/with/a/long/file_name.go",
+	"文档/项目/说明/非常长的文件名称示例.md",
+	"日本語のディレクトリ/サブディレクトリ/ファイル名がとても長いです.txt",
+	"emoji/🚀🚀🚀/🎉-party-🎉/👩‍💻-engineer.txt",
+	"combining/café/résumé/naïve-file-name.txt",
+	"한국어/디렉터리/파일이름이아주길어서잘려야합니다.go",
+	"a-single-component-without-any-slashes-that-is-really-quite-long.go",
+	"mixed/ASCII和中文混合的路径/emoji😀and-combining-é/end.go",
+}
+
+func TestRenderedRowsFitViewport(t *testing.T) {
+	widths := []int{12, 20, 24, 40, 80}
+
+	for _, width := range widths {
+		files := make([]FileItem, 0, len(trickyPaths)*2)
+		for _, p := range trickyPaths {
+			files = append(files,
+				FileItem{RelPath: p, Selected: true},
+				FileItem{RelPath: p, IsBinary: true},
+			)
+		}
+
+		m := NewModel(files, nil)
+		m.Update(tea.WindowSizeMsg{Width: width, Height: 40})
+
+		for i, line := range strings.Split(strings.TrimSuffix(m.renderContent(), "\n"), "\n") {
+			if got := ansi.StringWidth(line); got > width {
+				t.Errorf("width %d: row %d is %d cells wide: %q", width, i, got, ansi.Strip(line))
+			}
+		}
+
+		for i, line := range strings.Split(m.View(), "\n") {
+			if got := ansi.StringWidth(line); got > width {
+				t.Errorf("width %d: view line %d is %d cells wide: %q", width, i, got, ansi.Strip(line))
+			}
+		}
+	}
+}
+
+func TestRenderRowFixedColumns(t *testing.T) {
+	m := NewModel(nil, nil)
+	m.width = 30
+
+	for _, p := range trickyPaths {
+		row := ansi.Strip(m.renderRow(FileItem{RelPath: p, Selected: true}, true))
+		if !strings.HasPrefix(row, "> [x] ") {
+			t.Errorf("row for %q lost its fixed columns: %q", p, row)
+		}
+
+		row = ansi.Strip(m.renderRow(FileItem{RelPath: p}, false))
+		if !strings.HasPrefix(row, "  [ ] ") {
+			t.Errorf("row for %q lost its fixed columns: %q", p, row)
+		}
+	}
+}
+
+func TestTruncateMiddle(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		maxWidth int
+		want     string
+	}{
+		{
+			name:     "fits unchanged",
+			path:     "src/main.go",
+			maxWidth: 20,
+			want:     "src/main.go",
+		},
+		{
+			name:     "keeps head and trailing components",
+			path:     "src/a/b/deeply/nested/file.go",
+			maxWidth: 27,
+			want:     "src/…/deeply/nested/file.go",
+		},
+		{
+			name:     "drops components that no longer fit",
+			path:     "src/a/b/deeply/nested/file.go",
+			maxWidth: 24,
+			want:     "src/…/nested/file.go",
+		},
+		{
+			name:     "falls back to cutting the name",
+			path:     "src/an-extremely-long-file-name.go",
+			maxWidth: 12,
+			want:     "src/a…ame.go",
+		},
+		{
+			name:     "single cell",
+			path:     "src/main.go",
+			maxWidth: 1,
+			want:     "…",
+		},
+		{
+			name:     "no room",
+			path:     "src/main.go",
+			maxWidth: 0,
+			want:     "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncateMiddle(tt.path, tt.maxWidth)
+			if got != tt.want {
+				t.Errorf("truncateMiddle(%q, %d) = %q, want %q", tt.path, tt.maxWidth, got, tt.want)
+			}
+		})
+	}
+
+	for _, p := range trickyPaths {
+		for w := 0; w <= 30; w++ {
+			if got := ansi.StringWidth(truncateMiddle(p, w)); got > w {
+				t.Errorf("truncateMiddle(%q, %d) is %d cells wide", p, w, got)
+			}
+		}
+	}
+}
+
+func TestUnselectableFiles(t *testing.T) {
+	m := NewModel([]FileItem{
+		{RelPath: "a.go"},
+		{RelPath: "logo.png", IsBinary: true, Unselectable: "binary, written as a placeholder"},
+		{RelPath: "b.go"},
+	}, nil)
+	m.Update(tea.WindowSizeMsg{Width: 80, Height: 20})
+	press := func(keys string) {
+		m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(keys)})
+	}
+
+	if got := ansi.Strip(m.renderContent()); !strings.Contains(got, "logo.png (binary, written as a placeholder)") {
+		t.Errorf("unselectable file is not listed with its reason:\n%s", got)
+	}
+
+	// Toggling is a no-op that explains itself until the next key
+	press("j")
+	press(" ")
+	if m.files[1].Selected {
+		t.Error("toggle selected an unselectable file")
+	}
+	if view := ansi.Strip(m.View()); !strings.Contains(view, "logo.png cannot be selected: binary, written as a placeholder") {
+		t.Errorf("footer does not explain the no-op:\n%s", view)
+	}
+
+	press("a")
+	if got := len(m.SelectedFiles()); got != 2 {
+		t.Errorf("select all selected %d files, want 2", got)
+	}
+	if view := ansi.Strip(m.View()); strings.Contains(view, "cannot be selected") {
+		t.Errorf("status still shown after a key press:\n%s", view)
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyCtrlA})
+	if !m.files[1].Selected {
+		t.Error("ctrl+a did not select the unselectable file")
+	}
+
+	// Once selected, it can still be deselected one at a time
+	press(" ")
+	if m.files[1].Selected {
+		t.Error("toggle did not deselect the unselectable file")
+	}
+}
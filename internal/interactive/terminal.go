@@ -0,0 +1,45 @@
+package interactive
+
+import (
+	"io"
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Terminal is where a TUI reads keys and draws. Stdout is never drawn on,
+// since it carries the document written once the TUI ends.
+type Terminal struct {
+	In  io.Reader // Nil reads stdin, or the controlling terminal when stdin is not one
+	Out io.Writer // Nil draws on the controlling terminal, or stderr when there is none
+
+	Theme Theme // Colors of the TUI; empty means ThemeAuto
+}
+
+// ProgramOptions returns the options running a bubbletea program on t, with
+// the alternate screen, the styles of t.Theme as the terminal drawn on
+// renders them, and a function releasing what they opened, to call once the
+// program has returned and so released the terminal.
+func (t Terminal) ProgramOptions() ([]tea.ProgramOption, Styles, func()) {
+	opts := []tea.ProgramOption{tea.WithAltScreen()}
+	if t.In != nil {
+		opts = append(opts, tea.WithInput(t.In))
+	}
+
+	if t.Out != nil {
+		return append(opts, tea.WithOutput(t.Out)), t.styles(t.Out), func() {}
+	}
+	// Opened for reading too, so the background query can read the reply
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return append(opts, tea.WithOutput(os.Stderr)), t.styles(os.Stderr), func() {}
+	}
+
+	return append(opts, tea.WithOutput(tty)), t.styles(tty), func() { _ = tty.Close() }
+}
+
+// styles returns the styles of t.Theme for a TUI drawn on out.
+func (t Terminal) styles(out io.Writer) Styles {
+	return NewStyles(t.Theme, lipgloss.NewRenderer(out))
+}
@@ -0,0 +1,115 @@
+package interactive
+
+import (
+	"io"
+	"os"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// Theme selects how the TUIs are colored.
+type Theme string
+
+// Supported themes.
+const (
+	// ThemeAuto colors for the terminal's background, or styles with bold
+	// and underline alone when the terminal has no colors or NO_COLOR is set.
+	ThemeAuto  Theme = "auto"
+	ThemeDark  Theme = "dark"  // Colors for a dark background
+	ThemeLight Theme = "light" // Colors for a light background
+	ThemeMono  Theme = "mono"  // Bold and underline only, for any terminal
+)
+
+// IsValid reports whether t is a supported theme; empty means ThemeAuto.
+func (t Theme) IsValid() bool {
+	switch t {
+	case "", ThemeAuto, ThemeDark, ThemeLight, ThemeMono:
+		return true
+	default:
+		return false
+	}
+}
+
+// GetSupportedThemes returns the names of the supported themes.
+func GetSupportedThemes() []string {
+	return []string{string(ThemeAuto), string(ThemeDark), string(ThemeLight), string(ThemeMono)}
+}
+
+// Styles are what the TUIs draw with, each named for its role.
+type Styles struct {
+	Header   lipgloss.Style // Title line
+	Selected lipgloss.Style // Checked box of a selected file
+	Cursor   lipgloss.Style // Row marker and prompt cursor
+	Dim      lipgloss.Style // Help, hints, and rows that cannot be chosen
+	Warning  lipgloss.Style // Binary files and status messages
+	Grabbed  lipgloss.Style // Row being moved by the reorder TUI
+	Normal   lipgloss.Style
+}
+
+// Colors of each role for light and dark backgrounds. Profiles with fewer
+// colors than true color get the nearest color they have.
+var (
+	headerColor   = lipgloss.AdaptiveColor{Light: "#005fd7", Dark: "#5fafff"}
+	selectedColor = lipgloss.AdaptiveColor{Light: "#008700", Dark: "#5fd75f"}
+	cursorColor   = lipgloss.AdaptiveColor{Light: "#0087af", Dark: "#5fd7ff"}
+	dimColor      = lipgloss.AdaptiveColor{Light: "#6c6c6c", Dark: "#8a8a8a"}
+	warningColor  = lipgloss.AdaptiveColor{Light: "#af5f00", Dark: "#ffd75f"}
+	grabbedColor  = lipgloss.AdaptiveColor{Light: "#8700af", Dark: "#d787ff"}
+)
+
+// NewStyles returns the styles of theme as r renders them. ThemeAuto takes
+// the background r detects, and becomes ThemeMono when r has no colors,
+// which includes NO_COLOR being set. ThemeDark and ThemeLight are explicit
+// requests for color, so they render with at least 16 colors.
+//
+// ThemeAuto asks the terminal for its background here, falling back to
+// COLORFGBG, so call NewStyles before a TUI starts: once it reads the
+// keyboard, the terminal's reply would arrive as keystrokes.
+func NewStyles(theme Theme, r *lipgloss.Renderer) Styles {
+	noColor := r.ColorProfile() == termenv.Ascii || os.Getenv("NO_COLOR") != ""
+	switch {
+	case theme == ThemeMono, (theme == "" || theme == ThemeAuto) && noColor:
+		return monoStyles()
+	case theme == ThemeDark || theme == ThemeLight:
+		r.SetHasDarkBackground(theme == ThemeDark)
+		if r.ColorProfile() == termenv.Ascii {
+			r.SetColorProfile(termenv.ANSI)
+		}
+	default:
+		r.HasDarkBackground()
+	}
+
+	return Styles{
+		Header:   r.NewStyle().Bold(true).Foreground(headerColor),
+		Selected: r.NewStyle().Foreground(selectedColor),
+		Cursor:   r.NewStyle().Foreground(cursorColor),
+		Dim:      r.NewStyle().Foreground(dimColor),
+		Warning:  r.NewStyle().Foreground(warningColor),
+		Grabbed:  r.NewStyle().Bold(true).Foreground(grabbedColor),
+		Normal:   r.NewStyle(),
+	}
+}
+
+// monoStyles tells roles apart by bold and underline alone. Their renderer
+// claims 16 colors, since one without colors drops every attribute.
+func monoStyles() Styles {
+	r := lipgloss.NewRenderer(io.Discard)
+	r.SetColorProfile(termenv.ANSI)
+
+	return Styles{
+		Header:   r.NewStyle().Bold(true),
+		Selected: r.NewStyle().Bold(true),
+		Cursor:   r.NewStyle().Bold(true),
+		Dim:      r.NewStyle(),
+		Warning:  r.NewStyle().Underline(true),
+		Grabbed:  r.NewStyle().Bold(true).Underline(true),
+		Normal:   r.NewStyle(),
+	}
+}
+
+// DefaultStyles returns the ThemeAuto styles of lipgloss's default renderer,
+// which describes stdout.
+func DefaultStyles() Styles {
+	return NewStyles(ThemeAuto, lipgloss.DefaultRenderer())
+}
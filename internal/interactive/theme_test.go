@@ -0,0 +1,71 @@
+package interactive
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+
+	"github.com/connerohnesorge/catls/internal/testutil"
+)
+
+// themeFrame renders the selector with styles for theme on a renderer with
+// profile and background forced, as a terminal would report them.
+func themeFrame(t *testing.T, theme Theme, profile termenv.Profile, dark bool) string {
+	t.Helper()
+
+	r := lipgloss.NewRenderer(io.Discard)
+	r.SetColorProfile(profile)
+	r.SetHasDarkBackground(dark)
+
+	files := []FileItem{
+		{RelPath: "main.go", Selected: true},
+		{RelPath: "notes.md"},
+		{RelPath: "logo.png", IsBinary: true, Unselectable: "binary, written as a placeholder"},
+	}
+	m := NewModel(files, nil)
+	m.styles = NewStyles(theme, r)
+	m.Update(tea.WindowSizeMsg{Width: 80, Height: 12})
+
+	return m.View()
+}
+
+func TestThemeFrames(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+
+	tests := []struct {
+		name    string
+		theme   Theme
+		profile termenv.Profile
+		dark    bool
+	}{
+		{name: "auto-truecolor-dark", theme: ThemeAuto, profile: termenv.TrueColor, dark: true},
+		{name: "auto-256color-light", theme: ThemeAuto, profile: termenv.ANSI256, dark: false},
+		{name: "auto-16color-dark", theme: ThemeAuto, profile: termenv.ANSI, dark: true},
+		{name: "auto-no-color", theme: ThemeAuto, profile: termenv.Ascii},
+		{name: "light-on-dark-terminal", theme: ThemeLight, profile: termenv.TrueColor, dark: true},
+		{name: "dark-without-color", theme: ThemeDark, profile: termenv.Ascii},
+		{name: "mono", theme: ThemeMono, profile: termenv.TrueColor, dark: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := themeFrame(t, tt.theme, tt.profile, tt.dark)
+			testutil.AssertGolden(t, filepath.Join("testdata", "golden", "theme-"+tt.name+".golden"), got)
+		})
+	}
+}
+
+func TestThemeHonorsNoColor(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+
+	if got, want := themeFrame(t, ThemeAuto, termenv.TrueColor, true), themeFrame(t, ThemeMono, termenv.TrueColor, true); got != want {
+		t.Errorf("NO_COLOR frame =\n%q\nwant the mono frame\n%q", got, want)
+	}
+	if got := themeFrame(t, ThemeDark, termenv.TrueColor, true); got == themeFrame(t, ThemeMono, termenv.TrueColor, true) {
+		t.Error("an explicit dark theme should keep its colors under NO_COLOR")
+	}
+}
@@ -0,0 +1,87 @@
+package interactive
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// ellipsis marks the elided middle of a truncated path.
+const ellipsis = "…"
+
+// truncateMiddle shortens path to at most maxWidth terminal cells. It prefers
+// keeping the first directory and as many trailing components as fit
+// ("src/…/nested/file.go"), and falls back to cutting the middle of the string
+// when even the file name does not fit. Widths are measured in display cells,
+// so wide and combining characters are accounted for.
+func truncateMiddle(path string, maxWidth int) string {
+	if ansi.StringWidth(path) <= maxWidth {
+		return path
+	}
+
+	if maxWidth <= 0 {
+		return ""
+	}
+
+	if maxWidth == 1 {
+		return ellipsis
+	}
+
+	if short, ok := truncatePathComponents(path, maxWidth); ok {
+		return short
+	}
+
+	return truncateCells(path, maxWidth)
+}
+
+// truncatePathComponents keeps the leading component and the longest suffix of
+// whole components that fits in maxWidth. It fails if not even the last
+// component fits next to the leading one.
+func truncatePathComponents(path string, maxWidth int) (string, bool) {
+	parts := strings.Split(path, "/")
+	if len(parts) < 3 {
+		return "", false
+	}
+
+	head := parts[0] + "/" + ellipsis + "/"
+	budget := maxWidth - ansi.StringWidth(head)
+
+	tail := ""
+	for i := len(parts) - 1; i > 0; i-- {
+		candidate := parts[i]
+		if tail != "" {
+			candidate += "/" + tail
+		}
+
+		if ansi.StringWidth(candidate) > budget {
+			break
+		}
+		tail = candidate
+	}
+
+	if tail == "" {
+		return "", false
+	}
+
+	return head + tail, true
+}
+
+// truncateCells cuts the middle of s, keeping roughly equal halves on either
+// side of the ellipsis. Grapheme clusters are never split, so the result may be
+// one cell narrower than maxWidth when a wide character straddles the cut.
+func truncateCells(s string, maxWidth int) string {
+	keep := maxWidth - ansi.StringWidth(ellipsis)
+	left := keep / 2
+	right := keep - left
+
+	head := ansi.Truncate(s, left, "")
+
+	cut := ansi.StringWidth(s) - right
+	tail := ansi.TruncateLeft(s, cut, "")
+	for ansi.StringWidth(tail) > right {
+		cut++
+		tail = ansi.TruncateLeft(s, cut, "")
+	}
+
+	return head + ellipsis + tail
+}
@@ -0,0 +1,221 @@
+// Package languages is the single table of file types catls recognizes: how
+// each is detected and what syntax highlighters call it. Adding a language is
+// a one-entry change here.
+package languages
+
+import (
+	"bytes"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+// Language describes one file type.
+type Language struct {
+	Name         string   // Type reported by detection and shown in output
+	Highlight    string   // Code fence name for syntax highlighters (empty means Name)
+	Extensions   []string // Lowercase extensions without the dot
+	Filenames    []string // Lowercase base names of files with no telling extension
+	Interpreters []string // Shebang interpreter names without version suffixes
+}
+
+// Type names referenced outside the table.
+const (
+	Text = "text" // Highlight name for files without a detected type
+	XML  = "xml"
+)
+
+// table lists every recognized language, sorted by name.
+var table = []Language{
+	{Name: "bash", Extensions: []string{"sh", "bash", "zsh", "ksh"},
+		Filenames:    []string{".bashrc", ".bash_profile", ".profile", ".zshrc"},
+		Interpreters: []string{"sh", "bash", "dash", "ksh", "zsh"}},
+	{Name: "c", Extensions: []string{"c", "h"}},
+	{Name: "clojure", Extensions: []string{"clj", "cljs", "cljc", "edn"}, Interpreters: []string{"bb"}},
+	{Name: "cmake", Extensions: []string{"cmake"}, Filenames: []string{"cmakelists.txt"}},
+	{Name: "cpp", Extensions: []string{"cpp", "cxx", "cc", "hpp", "hxx"}},
+	{Name: "css", Extensions: []string{"css"}},
+	{Name: "dart", Extensions: []string{"dart"}},
+	{Name: "dockerfile", Extensions: []string{"dockerfile"}, Filenames: []string{"dockerfile", "containerfile"}},
+	{Name: "elixir", Extensions: []string{"ex", "exs"}, Interpreters: []string{"elixir"}},
+	{Name: "go", Extensions: []string{"go", "templ"}},
+	{Name: "gradle", Highlight: "groovy", Extensions: []string{"gradle"}},
+	{Name: "graphql", Extensions: []string{"graphql", "gql"}},
+	{Name: "haskell", Extensions: []string{"hs", "lhs"}, Interpreters: []string{"runhaskell"}},
+	{Name: "html", Extensions: []string{"html", "htm"}},
+	{Name: "java", Extensions: []string{"java"}},
+	{Name: "javascript", Extensions: []string{"js", "jsx", "mjs", "cjs"}, Interpreters: []string{"node"}},
+	{Name: "json", Extensions: []string{"json"}},
+	{Name: "kotlin", Extensions: []string{"kt", "kts"}},
+	{Name: "lua", Extensions: []string{"lua"}, Interpreters: []string{"lua", "luajit"}},
+	{Name: "makefile", Extensions: []string{"makefile", "mk"}, Filenames: []string{"makefile", "gnumakefile"}},
+	{Name: "markdown", Extensions: []string{"md", "markdown"}},
+	{Name: "nix", Extensions: []string{"nix"}, Interpreters: []string{"nix", "nix-env", "nix-shell"}},
+	{Name: "perl", Extensions: []string{"pl", "pm"}, Interpreters: []string{"perl"}},
+	{Name: "php", Extensions: []string{"php"}, Interpreters: []string{"php"}},
+	{Name: "protobuf", Extensions: []string{"proto"}},
+	{Name: "python", Extensions: []string{"py", "pyi"}, Interpreters: []string{"python"}},
+	{Name: "r", Extensions: []string{"r"}, Interpreters: []string{"rscript"}},
+	{Name: "ruby", Extensions: []string{"rb"}, Filenames: []string{"gemfile", "rakefile"}, Interpreters: []string{"ruby"}},
+	{Name: "rust", Extensions: []string{"rs"}},
+	{Name: "sass", Extensions: []string{"sass"}},
+	{Name: "scala", Extensions: []string{"scala", "sc"}, Interpreters: []string{"scala"}},
+	{Name: "scss", Extensions: []string{"scss"}},
+	{Name: "sql", Extensions: []string{"sql"}},
+	{Name: "svelte", Extensions: []string{"svelte"}},
+	{Name: "swift", Extensions: []string{"swift"}, Interpreters: []string{"swift"}},
+	{Name: "terraform", Highlight: "hcl", Extensions: []string{"tf", "tfvars"}},
+	{Name: "toml", Extensions: []string{"toml"}},
+	{Name: "typescript", Extensions: []string{"ts", "tsx", "mts", "cts"}, Interpreters: []string{"deno", "ts-node"}},
+	{Name: "vue", Extensions: []string{"vue"}},
+	{Name: XML, Extensions: []string{"xml"}},
+	{Name: "yaml", Extensions: []string{"yaml", "yml"}},
+	{Name: "zig", Extensions: []string{"zig"}},
+}
+
+// Lookup tables built from table.
+var (
+	byName        = make(map[string]*Language)
+	byExtension   = make(map[string]string)
+	byFilename    = make(map[string]string)
+	byInterpreter = make(map[string]string)
+)
+
+func init() {
+	for i := range table {
+		lang := &table[i]
+		byName[lang.Name] = lang
+		for _, ext := range lang.Extensions {
+			byExtension[ext] = lang.Name
+		}
+		for _, name := range lang.Filenames {
+			byFilename[name] = lang.Name
+		}
+		for _, interpreter := range lang.Interpreters {
+			byInterpreter[interpreter] = lang.Name
+		}
+	}
+}
+
+// Names returns the name of every language, sorted.
+func Names() []string {
+	names := make([]string, 0, len(table))
+	for _, lang := range table {
+		names = append(names, lang.Name)
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+// DetectByExtension returns the type of path judged by its extension, or an
+// empty string if the extension is not recognized.
+func DetectByExtension(path string) string {
+	return byExtension[Extension(path)]
+}
+
+// DetectByFilename returns the type of path judged by its base name, for files
+// such as Dockerfile whose names say more than their extensions.
+func DetectByFilename(path string) string {
+	return byFilename[strings.ToLower(filepath.Base(path))]
+}
+
+// DetectByContent returns the type of a file judged by its leading bytes: the
+// interpreter named by a shebang line, or an SVG root element.
+func DetectByContent(head []byte) string {
+	if line, ok := bytes.CutPrefix(head, []byte("#!")); ok {
+		if end := bytes.IndexByte(line, '\n'); end >= 0 {
+			line = line[:end]
+		}
+
+		return detectByShebang(string(line))
+	}
+
+	if scanner.SniffImage(head) == scanner.MIMETypeSVG {
+		return XML
+	}
+
+	return ""
+}
+
+// directivePattern matches a catls:lang= directive, whatever comment syntax
+// surrounds it.
+var directivePattern = regexp.MustCompile(`(?i)\bcatls:lang=([a-z0-9_+#]+)`)
+
+// directiveLines is how many leading lines are searched for a directive.
+const directiveLines = 3
+
+// DetectByDirective returns the type named by a catls:lang= directive in the
+// first three lines of head, such as "// catls:lang=json", along with the
+// name as written. Names are matched case-insensitively against language
+// names, then extensions. The type is empty when there is no directive or
+// it names no known language.
+func DetectByDirective(head []byte) (fileType, name string) {
+	lines := bytes.SplitN(head, []byte("\n"), directiveLines+1)
+	for _, line := range lines[:min(directiveLines, len(lines))] {
+		match := directivePattern.FindSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		name = string(match[1])
+		lower := strings.ToLower(name)
+		if _, ok := byName[lower]; ok {
+			return lower, name
+		}
+
+		return byExtension[lower], name
+	}
+
+	return "", ""
+}
+
+// detectByShebang returns the type of the interpreter named by a shebang line
+// without its leading "#!", such as "/usr/bin/env -S python3 -u".
+func detectByShebang(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ""
+	}
+
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" {
+		// Skip env's own flags and variable assignments
+		interpreter = ""
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "-") && !strings.Contains(field, "=") {
+				interpreter = filepath.Base(field)
+
+				break
+			}
+		}
+	}
+
+	// python3, python3.12, and perl5 name the same interpreters
+	interpreter = strings.ToLower(strings.TrimRight(interpreter, "0123456789."))
+
+	return byInterpreter[interpreter]
+}
+
+// HighlightName returns the code fence name for a detected type: the
+// language's highlighter name, the type itself for types outside the table
+// (such as --lang-map targets), or Text for files without a type.
+func HighlightName(fileType string) string {
+	if fileType == "" {
+		return Text
+	}
+
+	if lang, ok := byName[fileType]; ok && lang.Highlight != "" {
+		return lang.Highlight
+	}
+
+	return fileType
+}
+
+// Extension returns the lowercase extension of path without the dot.
+func Extension(path string) string {
+	return strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+}
@@ -0,0 +1,170 @@
+package languages
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDetectByExtension(t *testing.T) {
+	tests := map[string]string{
+		"main.go":          "go",
+		"App.kt":           "kotlin",
+		"build.gradle.kts": "kotlin",
+		"View.swift":       "swift",
+		"main.zig":         "zig",
+		"Main.hs":          "haskell",
+		"init.lua":         "lua",
+		"app.ex":           "elixir",
+		"test.exs":         "elixir",
+		"core.clj":         "clojure",
+		"Main.scala":       "scala",
+		"main.dart":        "dart",
+		"analysis.R":       "r",
+		"api.proto":        "protobuf",
+		"schema.graphql":   "graphql",
+		"main.tf":          "terraform",
+		"App.vue":          "vue",
+		"Page.svelte":      "svelte",
+		"deps.cmake":       "cmake",
+		"build.gradle":     "gradle",
+		"style.sass":       "sass",
+		"style.scss":       "scss",
+		"prompt.zsh":       "bash",
+		"notes.txt":        "",
+		"Makefile":         "",
+	}
+
+	for path, want := range tests {
+		t.Run(path, func(t *testing.T) {
+			if got := DetectByExtension(path); got != want {
+				t.Errorf("DetectByExtension(%q) = %q, want %q", path, got, want)
+			}
+		})
+	}
+}
+
+func TestDetectByFilename(t *testing.T) {
+	tests := map[string]string{
+		"Dockerfile":          "dockerfile",
+		"sub/Containerfile":   "dockerfile",
+		"GNUmakefile":         "makefile",
+		"CMakeLists.txt":      "cmake",
+		"Gemfile":             "ruby",
+		"home/.bashrc":        "bash",
+		"Dockerfile.template": "",
+		"main.go":             "",
+	}
+
+	for path, want := range tests {
+		t.Run(path, func(t *testing.T) {
+			if got := DetectByFilename(path); got != want {
+				t.Errorf("DetectByFilename(%q) = %q, want %q", path, got, want)
+			}
+		})
+	}
+}
+
+func TestDetectByContent(t *testing.T) {
+	tests := []struct {
+		name string
+		head string
+		want string
+	}{
+		{name: "sh", head: "#!/bin/sh\necho\n", want: "bash"},
+		{name: "env python with version", head: "#!/usr/bin/env python3.12\n", want: "python"},
+		{name: "env flags and assignments", head: "#!/usr/bin/env -S FOO=1 node --no-warnings\n", want: "javascript"},
+		{name: "interpreter arguments", head: "#!/usr/bin/perl5 -w\n", want: "perl"},
+		{name: "elixir script", head: "#!/usr/bin/env elixir\n", want: "elixir"},
+		{name: "unknown interpreter", head: "#!/usr/bin/awk -f\n", want: ""},
+		{name: "bare shebang", head: "#!\n", want: ""},
+		{name: "svg", head: "<?xml version=\"1.0\"?>\n<svg xmlns=\"http://www.w3.org/2000/svg\"/>\n", want: XML},
+		{name: "plain text", head: "hello\n", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectByContent([]byte(tt.head)); got != tt.want {
+				t.Errorf("DetectByContent(%q) = %q, want %q", tt.head, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectByDirective(t *testing.T) {
+	tests := []struct {
+		name     string
+		head     string
+		want     string
+		wantName string
+	}{
+		{name: "line comment", head: "// catls:lang=json\n{}\n", want: "json", wantName: "json"},
+		{name: "case-insensitive", head: "# CATLS:LANG=Python\n", want: "python", wantName: "Python"},
+		{name: "block comment", head: "<?php /* catls:lang=php*/ ?>\n", want: "php", wantName: "php"},
+		{name: "html comment", head: "<!-- catls:lang=html -->\n", want: "html", wantName: "html"},
+		{name: "extension", head: "; catls:lang=ts\n", want: "typescript", wantName: "ts"},
+		{name: "third line", head: "a\nb\n-- catls:lang=sql\n", want: "sql", wantName: "sql"},
+		{name: "fourth line", head: "a\nb\nc\n-- catls:lang=sql\n", want: "", wantName: ""},
+		{name: "unknown", head: "# catls:lang=cobol\n", want: "", wantName: "cobol"},
+		{name: "none", head: "plain text\n", want: "", wantName: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotName := DetectByDirective([]byte(tt.head))
+			if got != tt.want || gotName != tt.wantName {
+				t.Errorf("DetectByDirective(%q) = %q, %q, want %q, %q", tt.head, got, gotName, tt.want, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestHighlightName(t *testing.T) {
+	tests := map[string]string{
+		"go":        "go",
+		"terraform": "hcl",
+		"gradle":    "groovy",
+		"sass":      "sass",
+		"gotmpl":    "gotmpl",
+		"":          Text,
+	}
+
+	for fileType, want := range tests {
+		if got := HighlightName(fileType); got != want {
+			t.Errorf("HighlightName(%q) = %q, want %q", fileType, got, want)
+		}
+	}
+}
+
+// TestTableIsConsistent guards against two languages claiming the same
+// extension, file name, or interpreter, which would make detection depend on
+// table order.
+func TestTableIsConsistent(t *testing.T) {
+	names := make([]string, 0, len(table))
+	seen := map[string]string{}
+	claim := func(kind, key, name string) {
+		if other, ok := seen[kind+":"+key]; ok {
+			t.Errorf("%s %q claimed by both %s and %s", kind, key, other, name)
+		}
+		seen[kind+":"+key] = name
+	}
+
+	for _, lang := range table {
+		names = append(names, lang.Name)
+		for _, ext := range lang.Extensions {
+			claim("extension", ext, lang.Name)
+		}
+		for _, filename := range lang.Filenames {
+			claim("file name", filename, lang.Name)
+		}
+		for _, interpreter := range lang.Interpreters {
+			claim("interpreter", interpreter, lang.Name)
+		}
+	}
+
+	if !slices.IsSorted(names) {
+		t.Errorf("table is not sorted by name: %v", names)
+	}
+	if !slices.Equal(Names(), names) {
+		t.Errorf("Names() = %v, want %v", Names(), names)
+	}
+}
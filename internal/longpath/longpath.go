@@ -0,0 +1,4 @@
+// Package longpath lets Windows open paths longer than its legacy 260
+// character limit, as deeply nested generated trees have. Elsewhere it does
+// nothing.
+package longpath
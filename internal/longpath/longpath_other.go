@@ -0,0 +1,9 @@
+//go:build !windows
+
+package longpath
+
+// Fix returns path unchanged: only Windows limits path length below what
+// the filesystem supports.
+func Fix(path string) string {
+	return path
+}
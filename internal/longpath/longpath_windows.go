@@ -0,0 +1,33 @@
+//go:build windows
+
+package longpath
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// maxShortPath is the longest path Windows APIs accept without the
+// extended-length prefix. Directories allow 12 characters less, leaving room
+// for an 8.3 file name.
+const maxShortPath = 260 - 12
+
+// Fix returns path in its extended-length form (\\?\C:\... or
+// \\?\UNC\server\share\...) when it is too long for the legacy API limit.
+// The os package does the same for absolute paths, but leaves relative ones,
+// such as those below a scan of ".", alone.
+func Fix(path string) string {
+	if len(path) < maxShortPath || strings.HasPrefix(path, `\\?\`) {
+		return path
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	if unc, ok := strings.CutPrefix(abs, `\\`); ok {
+		return `\\?\UNC\` + unc
+	}
+
+	return `\\?\` + abs
+}
@@ -0,0 +1,31 @@
+//go:build windows
+
+package longpath
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFix(t *testing.T) {
+	deep := strings.Repeat(`\level`, 50)
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{name: "short", path: `C:\src\main.go`, want: `C:\src\main.go`},
+		{name: "long", path: `C:\src` + deep, want: `\\?\C:\src` + deep},
+		{name: "long UNC", path: `\\server\share` + deep, want: `\\?\UNC\server\share` + deep},
+		{name: "already extended", path: `\\?\C:\src` + deep, want: `\\?\C:\src` + deep},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Fix(tt.path); got != tt.want {
+				t.Errorf("Fix() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+// Package profile records how long each stage of a run spends on each file.
+// A nil *Collector is valid and records nothing, so instrumented code pays
+// only a nil check when profiling is off.
+package profile
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Stage names a step of the per-file pipeline.
+type Stage string
+
+// Stages in pipeline order.
+const (
+	StageDetectBinary Stage = "detect-binary"
+	StageDetectType   Stage = "detect-type"
+	StageRead         Stage = "read"
+	StageFilter       Stage = "filter"
+	StageFormat       Stage = "format"
+)
+
+// Stages lists every stage in pipeline order.
+var Stages = []Stage{StageDetectBinary, StageDetectType, StageRead, StageFilter, StageFormat}
+
+// slowestFiles is how many files Summary lists per stage.
+const slowestFiles = 10
+
+// Timing is the wall time one stage spent on one file.
+type Timing struct {
+	Path     string        `json:"path"`
+	Stage    Stage         `json:"stage"`
+	Duration time.Duration `json:"durationNs"`
+}
+
+// Collector accumulates timings. It is safe for concurrent use.
+type Collector struct {
+	mu      sync.Mutex
+	timings []Timing
+	observe func(Timing) // Receives each timing instead of the collector keeping it
+}
+
+// New creates an empty collector.
+func New() *Collector {
+	return &Collector{}
+}
+
+// NewObserver creates a collector that passes each timing to observe, one at
+// a time, instead of keeping it.
+func NewObserver(observe func(Timing)) *Collector {
+	return &Collector{observe: observe}
+}
+
+// noop is returned by Start on a nil collector, so disabled profiling allocates nothing.
+func noop() {}
+
+// Start begins timing stage for path and returns the function that records it.
+// Typical use is defer c.Start(path, stage)().
+func (c *Collector) Start(path string, stage Stage) func() {
+	if c == nil {
+		return noop
+	}
+
+	start := time.Now()
+
+	return func() {
+		c.Record(Timing{Path: path, Stage: stage, Duration: time.Since(start)})
+	}
+}
+
+// Record adds a timing measured elsewhere.
+func (c *Collector) Record(timing Timing) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.observe != nil {
+		c.observe(timing)
+
+		return
+	}
+	c.timings = append(c.timings, timing)
+}
+
+// Timings returns a copy of the recorded timings in the order they finished.
+func (c *Collector) Timings() []Timing {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return slices.Clone(c.timings)
+}
+
+// WriteJSON writes the raw timings as a JSON array.
+func (c *Collector) WriteJSON(w io.Writer) error {
+	timings := c.Timings()
+	if timings == nil {
+		timings = []Timing{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(timings)
+}
+
+// WriteSummary writes aggregate totals per stage followed by the slowest files
+// of each stage that recorded anything.
+func (c *Collector) WriteSummary(w io.Writer) error {
+	byStage := make(map[Stage][]Timing)
+	for _, timing := range c.Timings() {
+		byStage[timing.Stage] = append(byStage[timing.Stage], timing)
+	}
+
+	var total time.Duration
+	for _, timings := range byStage {
+		for _, timing := range timings {
+			total += timing.Duration
+		}
+	}
+
+	if _, err := fmt.Fprintf(w, "Profile: %s across all stages\n", total.Round(time.Microsecond)); err != nil {
+		return err
+	}
+	for _, stage := range Stages {
+		timings := byStage[stage]
+		var stageTotal time.Duration
+		for _, timing := range timings {
+			stageTotal += timing.Duration
+		}
+		if _, err := fmt.Fprintf(w, "  %-14s %6d files %12s\n", stage, len(timings), stageTotal.Round(time.Microsecond)); err != nil {
+			return err
+		}
+	}
+
+	for _, stage := range Stages {
+		timings := byStage[stage]
+		if len(timings) == 0 {
+			continue
+		}
+
+		slices.SortStableFunc(timings, func(a, b Timing) int {
+			return cmp.Compare(b.Duration, a.Duration)
+		})
+		if _, err := fmt.Fprintf(w, "Slowest files in %s:\n", stage); err != nil {
+			return err
+		}
+		for _, timing := range timings[:min(len(timings), slowestFiles)] {
+			if _, err := fmt.Fprintf(w, "  %12s  %s\n", timing.Duration.Round(time.Microsecond), timing.Path); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package profile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNilCollector(t *testing.T) {
+	var c *Collector
+
+	c.Start("a.go", StageRead)()
+	if got := c.Timings(); got != nil {
+		t.Errorf("Timings() = %v, want nil", got)
+	}
+
+	var buf bytes.Buffer
+	if err := c.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON() unexpected error: %v", err)
+	}
+	if got := strings.TrimSpace(buf.String()); got != "[]" {
+		t.Errorf("WriteJSON() = %q, want []", got)
+	}
+
+	if allocs := testing.AllocsPerRun(100, func() { c.Start("a.go", StageRead)() }); allocs != 0 {
+		t.Errorf("disabled Start allocates %.0f times, want 0", allocs)
+	}
+}
+
+func TestCollectorConcurrent(t *testing.T) {
+	c := New()
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Start(fmt.Sprintf("f%d", i), Stages[i%len(Stages)])()
+		}()
+	}
+	wg.Wait()
+
+	if got := len(c.Timings()); got != 50 {
+		t.Errorf("recorded %d timings, want 50", got)
+	}
+}
+
+func TestWriteSummary(t *testing.T) {
+	c := New()
+	c.timings = []Timing{
+		{Path: "slow.go", Stage: StageRead, Duration: 3 * time.Millisecond},
+		{Path: "fast.go", Stage: StageRead, Duration: time.Millisecond},
+		{Path: "fast.go", Stage: StageFormat, Duration: 2 * time.Millisecond},
+	}
+	for i := range 12 {
+		c.timings = append(c.timings, Timing{Path: fmt.Sprintf("bin%02d", i), Stage: StageDetectBinary, Duration: time.Duration(i) * time.Microsecond})
+	}
+
+	var buf bytes.Buffer
+	if err := c.WriteSummary(&buf); err != nil {
+		t.Fatalf("WriteSummary() unexpected error: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"Profile: 6.066ms across all stages\n",
+		"  read                2 files          4ms\n",
+		"  filter              0 files           0s\n",
+		"Slowest files in read:\n           3ms  slow.go\n           1ms  fast.go\n",
+		"Slowest files in format:\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("summary missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Slowest files in filter") {
+		t.Errorf("summary lists an empty stage:\n%s", out)
+	}
+	if strings.Contains(out, "bin01") || !strings.Contains(out, "bin02") {
+		t.Errorf("summary should list only the 10 slowest binary detections:\n%s", out)
+	}
+
+	buf.Reset()
+	if err := c.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON() unexpected error: %v", err)
+	}
+	var timings []Timing
+	if err := json.Unmarshal(buf.Bytes(), &timings); err != nil {
+		t.Fatalf("WriteJSON() output is not JSON: %v", err)
+	}
+	if len(timings) != len(c.timings) || timings[0] != c.timings[0] {
+		t.Errorf("round-tripped timings = %v", timings)
+	}
+}
+
+func TestObserver(t *testing.T) {
+	var observed []Timing
+	c := NewObserver(func(timing Timing) { observed = append(observed, timing) })
+
+	c.Start("a.go", StageRead)()
+	c.Record(Timing{Path: "b.go", Stage: StageFormat, Duration: time.Second})
+
+	if len(observed) != 2 || observed[0].Path != "a.go" || observed[1] != (Timing{Path: "b.go", Stage: StageFormat, Duration: time.Second}) {
+		t.Errorf("observed %v, want a.go then b.go", observed)
+	}
+	if got := c.Timings(); len(got) != 0 {
+		t.Errorf("Timings() = %v, want none kept", got)
+	}
+}
@@ -9,8 +9,8 @@ import (
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/charmbracelet/lipgloss"
 
+	"github.com/connerohnesorge/catls/internal/interactive"
 	"github.com/connerohnesorge/catls/internal/scanner"
 )
 
@@ -71,6 +71,9 @@ type Model struct {
 	height    int
 	quitting  bool
 	confirmed bool
+	// styles draw the TUI; the grabbed style is deliberately distinct from
+	// the cursor style so the current mode is unambiguous
+	styles interactive.Styles
 }
 
 // NewModel creates a new reorder model seeded with the given files.
@@ -79,8 +82,9 @@ func NewModel(files []scanner.FileInfo) Model {
 	copy(ordered, files)
 
 	return Model{
-		files: ordered,
-		keys:  DefaultKeyMap(),
+		files:  ordered,
+		keys:   DefaultKeyMap(),
+		styles: interactive.DefaultStyles(),
 	}
 }
 
@@ -223,17 +227,6 @@ func (m *Model) ensureCursorVisible() {
 	}
 }
 
-// Styling used to render the reorder TUI. The grabbed style is deliberately
-// distinct from the cursor style so the current mode is unambiguous.
-var (
-	headerStyle  = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12"))
-	cursorStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("14"))
-	grabbedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("13")).Bold(true)
-	dimStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
-	binaryStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("11"))
-	normalStyle  = lipgloss.NewStyle()
-)
-
 // View implements tea.Model.
 func (m *Model) View() string {
 	if !m.ready {
@@ -250,9 +243,9 @@ func (m *Model) View() string {
 
 	mode := "browse"
 	if m.grabbed {
-		mode = grabbedStyle.Render("GRABBED")
+		mode = m.styles.Grabbed.Render("GRABBED")
 	}
-	header := headerStyle.Render(fmt.Sprintf("Reorder files (%d) — mode: %s", len(m.files), mode))
+	header := m.styles.Header.Render(fmt.Sprintf("Reorder files (%d) — mode: %s", len(m.files), mode))
 	content := m.viewport.View()
 	footer := fmt.Sprintf(
 		"%s %s %s %s %s %s",
@@ -264,7 +257,7 @@ func (m *Model) View() string {
 		m.renderKeyHelp(m.keys.Confirm),
 	)
 
-	return fmt.Sprintf("%s\n%s\n%s", header, content, dimStyle.Render(footer))
+	return fmt.Sprintf("%s\n%s\n%s", header, content, m.styles.Dim.Render(footer))
 }
 
 // renderKeyHelp formats a single key binding for the footer help strip.
@@ -281,20 +274,20 @@ func (m *Model) renderContent() string {
 		cursor := " "
 		if i == m.cursor {
 			if m.grabbed {
-				cursor = grabbedStyle.Render("#")
+				cursor = m.styles.Grabbed.Render("#")
 			} else {
-				cursor = cursorStyle.Render(">")
+				cursor = m.styles.Cursor.Render(">")
 			}
 		}
 
 		path := file.RelPath
-		style := normalStyle
+		style := m.styles.Normal
 		if file.IsBinary {
-			style = binaryStyle
-			path = file.RelPath + dimStyle.Render(" (binary)")
+			style = m.styles.Warning
+			path = file.RelPath + m.styles.Dim.Render(" (binary)")
 		}
 		if i == m.cursor && m.grabbed {
-			style = grabbedStyle
+			style = m.styles.Grabbed
 		}
 
 		b.WriteString(fmt.Sprintf("%s %3d  %s\n", cursor, i+1, style.Render(path)))
@@ -303,15 +296,18 @@ func (m *Model) renderContent() string {
 	return b.String()
 }
 
-// Reorder launches the reorder TUI and returns the new ordering.
-// Returns nil if the user cancels.
-func Reorder(files []scanner.FileInfo) ([]scanner.FileInfo, error) {
+// Reorder launches the reorder TUI on term and returns the new ordering once
+// it has released the terminal. Returns nil if the user cancels.
+func Reorder(files []scanner.FileInfo, term interactive.Terminal) ([]scanner.FileInfo, error) {
 	if len(files) == 0 {
 		return files, nil
 	}
 
 	m := NewModel(files)
-	p := tea.NewProgram(&m, tea.WithAltScreen())
+	opts, styles, release := term.ProgramOptions()
+	defer release()
+	m.styles = styles
+	p := tea.NewProgram(&m, opts...)
 
 	if _, err := p.Run(); err != nil {
 		return nil, fmt.Errorf("failed to run reorder TUI: %w", err)
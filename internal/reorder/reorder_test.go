@@ -5,6 +5,7 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 
+	"github.com/connerohnesorge/catls/internal/interactive"
 	"github.com/connerohnesorge/catls/internal/scanner"
 )
 
@@ -159,7 +160,7 @@ func TestConfirm(t *testing.T) {
 }
 
 func TestReorderEmpty(t *testing.T) {
-	got, err := Reorder(nil)
+	got, err := Reorder(nil, interactive.Terminal{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -2,12 +2,29 @@ package scanner
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"runtime"
 	"strings"
+	"sync"
+
+	"github.com/connerohnesorge/catls/internal/fdlimit"
+)
+
+const (
+	// fileBatchSize is the most paths passed to one run of the file command.
+	fileBatchSize = 64
+	// fileBatchBytes bounds the length of the paths passed to one run, well
+	// below the command line limits of every platform.
+	fileBatchBytes = 16 << 10
 )
 
+// fileCommands is a semaphore bounding how many runs of the file command
+// are in flight at once, across every scan in the process.
+var fileCommands = make(chan struct{}, runtime.NumCPU())
+
 // BinaryDetector defines the interface for detecting binary files.
 // BinaryDetector is used to determine if a file contains binary data.
 type BinaryDetector interface {
@@ -16,28 +33,187 @@ type BinaryDetector interface {
 	IsBinary(path string) bool
 }
 
+// BatchBinaryDetector is a BinaryDetector that classifies many files at
+// once, for detectors whose cost is per call rather than per file.
+type BatchBinaryDetector interface {
+	BinaryDetector
+	// IsBinaryBatch reports IsBinary for each of paths, in order.
+	IsBinaryBatch(paths []string) []bool
+}
+
 // FileBinaryDetector implements BinaryDetector using file command and byte analysis.
 type FileBinaryDetector struct{}
 
 // IsBinary detects if a file is binary using the file command as primary method
-// and falls back to byte analysis.
+// and falls back to byte analysis. SVG images are text, whatever file reports.
 func (d *FileBinaryDetector) IsBinary(path string) bool {
-	// Try using the file command first
-	cmd := exec.Command("file", path)
-	output, err := cmd.Output()
-	if err == nil {
-		return !strings.Contains(strings.ToLower(string(output)), "text")
+	sample, err := readSample(path)
+	if isText(sample, err) {
+		return false
+	}
+
+	return d.describe(path, sample, err)
+}
+
+// IsBinaryBatch classifies paths like IsBinary, passing them to the file
+// command in batches. Batches run concurrently, bounded by fileCommands. A
+// path whose description cannot be matched to it, or every path of a batch
+// when file does not take the options used, is classified on its own. Each
+// file is opened once, for a sample that every check but file's reuses.
+func (d *FileBinaryDetector) IsBinaryBatch(paths []string) []bool {
+	results := make([]bool, len(paths))
+	samples := make([][]byte, len(paths))
+	sampleErrs := make([]error, len(paths))
+	var queued []int
+	for i, path := range paths {
+		samples[i], sampleErrs[i] = readSample(path)
+		if !isText(samples[i], sampleErrs[i]) {
+			queued = append(queued, i)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, batch := range fileBatches(paths, queued) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			names := make([]string, len(batch))
+			for j, i := range batch {
+				names[j] = paths[i]
+			}
+			descriptions, err := runFile(append([]string{"-N", "-r", "-0", "--"}, names...))
+			var found map[int]string
+			if err == nil {
+				found = parseFileOutput(descriptions, names)
+			}
+
+			for j, i := range batch {
+				switch description, ok := found[j]; {
+				case ok:
+					results[i] = !isTextDescription(description)
+				case errors.Is(err, exec.ErrNotFound):
+					results[i] = hasZeroByte(samples[i], sampleErrs[i])
+				default:
+					results[i] = d.describe(paths[i], samples[i], sampleErrs[i])
+				}
+			}
+		}()
 	}
+	wg.Wait()
+
+	return results
+}
+
+// isText reports whether a file is text before the file command is asked,
+// from its sample as readSample returned it: SVG images are text, whatever
+// file reports, and UTF-16 and UTF-32 text is full of zero bytes, which both
+// file and byte analysis take for binary.
+func isText(sample []byte, err error) bool {
+	return err == nil && (SniffImage(sample) == MIMETypeSVG || isEncodedText(sample))
+}
+
+// describe classifies the file at path by a run of the file command of its
+// own, falling back to byte analysis of its sample when file cannot be run.
+// Brief output leaves the path out, so nothing in it can be mistaken for the
+// description.
+func (*FileBinaryDetector) describe(path string, sample []byte, sampleErr error) bool {
+	if output, err := runFile([]string{"-b", "--", path}); err == nil {
+		return !isTextDescription(string(output))
+	}
+
+	return hasZeroByte(sample, sampleErr)
+}
+
+// isTextDescription reports whether a description by the file command is
+// of text.
+func isTextDescription(description string) bool {
+	return strings.Contains(strings.ToLower(description), "text")
+}
+
+// runFile runs the file command with args once a slot of fileCommands is
+// free, returning its output.
+func runFile(args []string) ([]byte, error) {
+	fileCommands <- struct{}{}
+	defer func() { <-fileCommands }()
 
-	// Fallback to byte analysis
-	return d.isBinaryByBytes(path)
+	return exec.Command("file", args...).Output()
 }
 
-// isBinaryByBytes checks for null bytes in the first chunkSize bytes of a file.
+// fileBatches splits the indexes of paths in queued into batches of at most
+// fileBatchSize paths and fileBatchBytes bytes, keeping their order.
+func fileBatches(paths []string, queued []int) [][]int {
+	var batches [][]int
+	var batch []int
+	size := 0
+	for _, i := range queued {
+		if len(batch) == fileBatchSize || (len(batch) > 0 && size+len(paths[i]) > fileBatchBytes) {
+			batches = append(batches, batch)
+			batch, size = nil, 0
+		}
+		batch = append(batch, i)
+		size += len(paths[i]) + 1
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+
+	return batches
+}
+
+// parseFileOutput maps each of names to its description in the output of
+// file -N -r -0, which writes each name raw, a NUL, ": ", the description,
+// and a newline. Names may hold colons and newlines, and descriptions may
+// quote names, so output is split at the NULs alone: every piece between
+// two of them ends with a newline and the next name. Names whose piece is
+// not found are left out.
+func parseFileOutput(output []byte, names []string) map[int]string {
+	pieces := strings.Split(string(output), "\x00")
+	if len(pieces) != len(names)+1 || pieces[0] != names[0] {
+		return nil
+	}
+
+	found := make(map[int]string, len(names))
+	for i := range names {
+		piece := pieces[i+1]
+		if i+1 < len(names) {
+			var ok bool
+			if piece, ok = strings.CutSuffix(piece, "\n"+names[i+1]); !ok {
+				// The rest can no longer be matched to names
+				break
+			}
+		}
+		if description, ok := strings.CutPrefix(strings.TrimSuffix(piece, "\n"), ":"); ok {
+			found[i] = strings.TrimSpace(description)
+		}
+	}
+
+	return found
+}
+
+// isBinaryByBytes checks for null bytes in the first sampleSize bytes of a file.
 func (*FileBinaryDetector) isBinaryByBytes(path string) bool {
-	file, err := os.Open(path)
+	return hasZeroByte(readSample(path))
+}
+
+// hasZeroByte reports whether sample, as readSample returned it, holds a
+// null byte. A file that could not be read is assumed binary.
+func hasZeroByte(sample []byte, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	return bytes.Contains(sample, []byte{0})
+}
+
+// sampleSize is how much of a file binary detection looks at.
+const sampleSize = 1024
+
+// readSample reads up to sampleSize leading bytes of the file at path.
+func readSample(path string) ([]byte, error) {
+	file, err := fdlimit.Open(path)
 	if err != nil {
-		return true // Assume binary if we can't read it
+		return nil, err
 	}
 	defer func() {
 		if closeErr := file.Close(); closeErr != nil {
@@ -46,12 +222,11 @@ func (*FileBinaryDetector) isBinaryByBytes(path string) bool {
 		}
 	}()
 
-	const bufferSize = 1024
-	chunk := make([]byte, bufferSize)
+	chunk := make([]byte, sampleSize)
 	n, err := file.Read(chunk)
 	if err != nil {
-		return true
+		return nil, err
 	}
 
-	return bytes.Contains(chunk[:n], []byte{0})
-}
\ No newline at end of file
+	return chunk[:n], nil
+}
@@ -0,0 +1,200 @@
+//go:build unix
+
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeFile stands in for the file command: .bin files are data, and others
+// are text. Each run appends its first argument to $FAKE_FILE_LOG, and with
+// $FAKE_FILE_NO_PRINT0 set it rejects -0 as older versions do.
+const fakeFile = `#!/bin/sh
+printf '%s\n' "$1" >> "$FAKE_FILE_LOG"
+describe() {
+	case "$1" in
+	*.bin) printf 'data' ;;
+	*) printf 'ASCII text' ;;
+	esac
+}
+if [ "$1" = "-b" ]; then
+	describe "$3"
+	printf '\n'
+	exit 0
+fi
+if [ -n "$FAKE_FILE_NO_PRINT0" ]; then
+	echo "file: invalid option -- '0'" >&2
+	exit 1
+fi
+shift 4
+for name; do
+	printf '%s\0: ' "$name"
+	describe "$name"
+	printf '\n'
+done
+`
+
+// installFakeFile puts fakeFile first on PATH and returns its log.
+func installFakeFile(tb testing.TB) string {
+	tb.Helper()
+
+	bin := tb.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "file"), []byte(fakeFile), 0o755); err != nil {
+		tb.Fatalf("failed to write the fake file command: %v", err)
+	}
+	log := filepath.Join(bin, "runs.log")
+	tb.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	tb.Setenv("FAKE_FILE_LOG", log)
+
+	return log
+}
+
+// fileRuns returns the first argument of each run of the fake file command.
+func fileRuns(t *testing.T, log string) []string {
+	t.Helper()
+
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatalf("failed to read the fake file log: %v", err)
+	}
+
+	return strings.Fields(string(data))
+}
+
+func TestFileCommandBatches(t *testing.T) {
+	names := []string{"a:b.txt", "x: data.txt", "new\nline.bin", "textures.bin", "new\nline.txt", "plain.txt"}
+	for i := range 100 {
+		names = append(names, fmt.Sprintf("f%03d.txt", i))
+	}
+
+	tests := []struct {
+		name     string
+		noPrint0 bool
+		wantRuns map[string]int
+	}{
+		{name: "batched", wantRuns: map[string]int{"-N": 2}},
+		{name: "without print0", noPrint0: true, wantRuns: map[string]int{"-N": 2, "-b": len(names)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := installFakeFile(t)
+			if tt.noPrint0 {
+				t.Setenv("FAKE_FILE_NO_PRINT0", "1")
+			}
+
+			dir := t.TempDir()
+			for _, name := range names {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte("content\n"), 0o644); err != nil {
+					t.Fatalf("failed to write %q: %v", name, err)
+				}
+			}
+
+			files, err := New().Scan(context.Background(), &Config{Directory: dir})
+			if err != nil {
+				t.Fatalf("Scan() unexpected error: %v", err)
+			}
+			if len(files) != len(names) {
+				t.Fatalf("Scan() found %d files, want %d", len(files), len(names))
+			}
+			for _, file := range files {
+				if want := strings.HasSuffix(file.RelPath, ".bin"); file.IsBinary != want {
+					t.Errorf("%q IsBinary = %v, want %v", file.RelPath, file.IsBinary, want)
+				}
+			}
+
+			runs := make(map[string]int)
+			for _, run := range fileRuns(t, log) {
+				runs[run]++
+			}
+			if fmt.Sprint(runs) != fmt.Sprint(tt.wantRuns) {
+				t.Errorf("file ran %v, want %v", runs, tt.wantRuns)
+			}
+		})
+	}
+}
+
+func TestFileCommandStopsAtMaxFiles(t *testing.T) {
+	log := installFakeFile(t)
+
+	dir := t.TempDir()
+	for i := range 1000 {
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("f%04d.txt", i)), []byte("content\n"), 0o644); err != nil {
+			t.Fatalf("failed to write file %d: %v", i, err)
+		}
+	}
+
+	_, err := New().Scan(context.Background(), &Config{Directory: dir, MaxFiles: 10})
+	if !errors.Is(err, ErrTooManyFiles) {
+		t.Fatalf("Scan() error = %v, want ErrTooManyFiles", err)
+	}
+	// One batch covers the files up to the one past the limit
+	if runs := fileRuns(t, log); len(runs) != 1 {
+		t.Errorf("file ran %d times, want 1", len(runs))
+	}
+}
+
+func TestParseFileOutput(t *testing.T) {
+	names := []string{"a\nb", "c: d"}
+	tests := []struct {
+		name   string
+		output string
+		want   map[int]string
+	}{
+		{
+			name:   "every name",
+			output: "a\nb\x00: data, from 'a\nb'\nc: d\x00: ASCII text\n",
+			want:   map[int]string{0: "data, from 'a\nb'", 1: "ASCII text"},
+		},
+		{name: "names escaped", output: "a\\012b\x00: data\nc: d\x00: ASCII text\n"},
+		{name: "description missing", output: "a\nb\x00: data\n"},
+		{
+			name:   "later name changed",
+			output: "a\nb\x00: data\nc:d\x00: ASCII text\n",
+			want:   map[int]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseFileOutput([]byte(tt.output), names); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("parseFileOutput() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// BenchmarkFileCommand classifies 10,000 files with a run of the file
+// command per file and in batches.
+func BenchmarkFileCommand(b *testing.B) {
+	installFakeFile(b)
+
+	dir := b.TempDir()
+	paths := make([]string, 10000)
+	for i := range paths {
+		paths[i] = filepath.Join(dir, fmt.Sprintf("f%05d.txt", i))
+		if err := os.WriteFile(paths[i], []byte("content\n"), 0o644); err != nil {
+			b.Fatalf("failed to write file %d: %v", i, err)
+		}
+	}
+
+	detector := &FileBinaryDetector{}
+	b.Run("single", func(b *testing.B) {
+		for b.Loop() {
+			for _, path := range paths {
+				detector.IsBinary(path)
+			}
+		}
+	})
+	b.Run("batched", func(b *testing.B) {
+		for b.Loop() {
+			detector.IsBinaryBatch(paths)
+		}
+	})
+}
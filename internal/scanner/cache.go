@@ -0,0 +1,214 @@
+package scanner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// detectionCacheVersion is bumped whenever the on-disk layout or the meaning of
+// a cached value changes, so stale caches are discarded rather than trusted.
+const detectionCacheVersion = 4
+
+// MaxDetectionEntries caps the entries a DetectionCache holds, so a cache
+// kept across many runs, in memory or on disk, stops growing with every
+// path it has ever seen. Beyond it, storing a new path evicts another.
+const MaxDetectionEntries = 100_000
+
+// DetectionEntry is the cached detection result for a single file.
+type DetectionEntry struct {
+	Size      int64  `json:"size"`
+	ModTime   int64  `json:"mtime"` // Modification time in Unix nanoseconds
+	IsBinary  bool   `json:"binary"`
+	HasBinary bool   `json:"hasBinary,omitempty"` // IsBinary has been detected
+	FileType  string `json:"type,omitempty"`
+	HasType   bool   `json:"hasType,omitempty"` // FileType has been detected, even if empty
+	// Directive is the type a catls:lang= directive in the file names;
+	// HasDirective is set once the file has been searched for one.
+	Directive    string `json:"directive,omitempty"`
+	HasDirective bool   `json:"hasDirective,omitempty"`
+}
+
+// DetectionCache memoizes binary and type detection keyed by absolute path and
+// invalidated by size and modification time. A cache without a backing path
+// only memoizes within the current run. It is safe for concurrent use.
+type DetectionCache struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]DetectionEntry
+	dirty   bool
+}
+
+type detectionCacheFile struct {
+	Version int                       `json:"version"`
+	Entries map[string]DetectionEntry `json:"entries"`
+}
+
+// NewMemoryDetectionCache returns a cache that memoizes results for one run only.
+func NewMemoryDetectionCache() *DetectionCache {
+	return &DetectionCache{
+		entries: make(map[string]DetectionEntry),
+	}
+}
+
+// LoadDetectionCache opens the on-disk cache at path. A missing, unreadable, or
+// outdated cache file yields an empty cache that will be rewritten on Save.
+func LoadDetectionCache(path string) *DetectionCache {
+	cache := NewMemoryDetectionCache()
+	cache.path = path
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cache
+	}
+
+	var stored detectionCacheFile
+	if err := json.Unmarshal(data, &stored); err != nil || stored.Version != detectionCacheVersion {
+		return cache
+	}
+
+	if stored.Entries != nil {
+		cache.entries = stored.Entries
+		for key := range cache.entries {
+			if len(cache.entries) <= MaxDetectionEntries {
+				break
+			}
+			delete(cache.entries, key)
+			cache.dirty = true
+		}
+	}
+
+	return cache
+}
+
+// DefaultDetectionCachePath returns the per-user location of the detection cache.
+func DefaultDetectionCachePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "catls", "detect.json"), nil
+}
+
+// Lookup returns the cached entry for path if its size and modification time
+// still match.
+func (c *DetectionCache) Lookup(path string, size int64, modTime time.Time) (DetectionEntry, bool) {
+	if c == nil {
+		return DetectionEntry{}, false
+	}
+
+	key := cacheKey(path)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || entry.Size != size || entry.ModTime != modTime.UnixNano() {
+		return DetectionEntry{}, false
+	}
+
+	return entry, true
+}
+
+// Store records a detection result for path, replacing any stale entry.
+func (c *DetectionCache) Store(path string, size int64, modTime time.Time, update func(*DetectionEntry)) {
+	if c == nil {
+		return
+	}
+
+	key := cacheKey(path)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || entry.Size != size || entry.ModTime != modTime.UnixNano() {
+		entry = DetectionEntry{Size: size, ModTime: modTime.UnixNano()}
+	}
+	if !ok {
+		c.evict()
+	}
+
+	update(&entry)
+	c.entries[key] = entry
+	c.dirty = true
+}
+
+// evict makes room for one more entry if the cache is full. Map order
+// makes the choice of entry arbitrary, which is enough to bound the cache.
+func (c *DetectionCache) evict() {
+	if len(c.entries) < MaxDetectionEntries {
+		return
+	}
+	for key := range c.entries {
+		delete(c.entries, key)
+
+		return
+	}
+}
+
+// Save writes the cache back to disk if anything changed. Memory-only caches
+// are never written.
+func (c *DetectionCache) Save() error {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.path == "" || !c.dirty {
+		return nil
+	}
+
+	data, err := json.Marshal(detectionCacheFile{
+		Version: detectionCacheVersion,
+		Entries: c.entries,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	// Write to a sibling temp file and rename so readers never see a partial cache.
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), ".detect-*.json")
+	if err != nil {
+		return err
+	}
+
+	_, writeErr := tmp.Write(data)
+	closeErr := tmp.Close()
+	if err := errors.Join(writeErr, closeErr); err != nil {
+		_ = os.Remove(tmp.Name())
+
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		_ = os.Remove(tmp.Name())
+
+		return err
+	}
+
+	c.dirty = false
+
+	return nil
+}
+
+// cacheKey normalizes path so the same file reached through different relative
+// spellings shares one entry.
+func cacheKey(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+
+	return path
+}
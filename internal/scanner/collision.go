@@ -0,0 +1,36 @@
+package scanner
+
+import (
+	"sort"
+	"strings"
+)
+
+// CaseCollisions groups files whose relative paths are equal when case is
+// ignored, which would overwrite each other on a case-insensitive filesystem.
+// Only RelPaths are compared, so the result is the same on every platform.
+// Paths within a group are sorted, groups are ordered by their first path, and
+// directory records are ignored.
+func CaseCollisions(files []FileInfo) [][]string {
+	byFolded := make(map[string][]string)
+	for _, file := range files {
+		if file.IsDir {
+			continue
+		}
+		folded := strings.ToLower(file.RelPath)
+		byFolded[folded] = append(byFolded[folded], file.RelPath)
+	}
+
+	var groups [][]string
+	for _, paths := range byFolded {
+		if len(paths) > 1 {
+			sort.Strings(paths)
+			groups = append(groups, paths)
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i][0] < groups[j][0]
+	})
+
+	return groups
+}
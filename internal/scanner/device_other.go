@@ -0,0 +1,21 @@
+//go:build !unix && !windows
+
+package scanner
+
+import "os"
+
+// deviceID is unsupported on this platform, so filesystem boundaries are never detected.
+func deviceID(os.FileInfo) (uint64, bool) {
+	return 0, false
+}
+
+// Identity is unsupported on this platform, so hard links are never recognized.
+func Identity(os.FileInfo) (FileIdentity, bool) {
+	return FileIdentity{}, false
+}
+
+// dirIdentity is unsupported on this platform, so cycles through links are
+// only ended by MaxDepth.
+func dirIdentity(string, os.FileInfo) (FileIdentity, bool) {
+	return FileIdentity{}, false
+}
@@ -0,0 +1,46 @@
+//go:build unix
+
+package scanner
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/connerohnesorge/catls/internal/longpath"
+)
+
+// deviceID returns the ID of the device holding the file described by info.
+// The boolean is false when the platform does not expose device IDs.
+func deviceID(info os.FileInfo) (uint64, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+
+	return uint64(stat.Dev), true //nolint:unconvert // Dev is int32 on some platforms
+}
+
+// Identity returns the device and inode of the file described by info, which
+// hard links to the same file share. The boolean is false when the platform
+// does not expose inodes.
+func Identity(info os.FileInfo) (FileIdentity, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return FileIdentity{}, false
+	}
+
+	return FileIdentity{Device: uint64(stat.Dev), Inode: uint64(stat.Ino)}, true //nolint:unconvert // Dev and Ino vary in width across platforms
+}
+
+// dirIdentity returns the identity of the directory at dirPath, following
+// links. info, when not nil, is its already resolved FileInfo.
+func dirIdentity(dirPath string, info os.FileInfo) (FileIdentity, bool) {
+	if info == nil {
+		var err error
+		if info, err = os.Stat(longpath.Fix(dirPath)); err != nil {
+			return FileIdentity{}, false
+		}
+	}
+
+	return Identity(info)
+}
@@ -0,0 +1,50 @@
+//go:build windows
+
+package scanner
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/connerohnesorge/catls/internal/longpath"
+)
+
+// deviceID is unsupported on Windows, so filesystem boundaries are never detected.
+func deviceID(os.FileInfo) (uint64, bool) {
+	return 0, false
+}
+
+// Identity is unsupported on Windows, where FileInfo carries no file index,
+// so hard links are never recognized.
+func Identity(os.FileInfo) (FileIdentity, bool) {
+	return FileIdentity{}, false
+}
+
+// dirIdentity returns the volume serial number and file index of the
+// directory at dirPath, following symlinks and junctions. Opening a
+// directory takes FILE_FLAG_BACKUP_SEMANTICS; info is not consulted, since
+// it holds neither number.
+func dirIdentity(dirPath string, _ os.FileInfo) (FileIdentity, bool) {
+	name, err := windows.UTF16PtrFromString(longpath.Fix(dirPath))
+	if err != nil {
+		return FileIdentity{}, false
+	}
+	handle, err := windows.CreateFile(name, 0,
+		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE,
+		nil, windows.OPEN_EXISTING, windows.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return FileIdentity{}, false
+	}
+	defer windows.CloseHandle(handle)
+
+	var data windows.ByHandleFileInformation
+	if err := windows.GetFileInformationByHandle(handle, &data); err != nil {
+		return FileIdentity{}, false
+	}
+
+	return FileIdentity{
+		Device: uint64(data.VolumeSerialNumber),
+		Inode:  uint64(data.FileIndexHigh)<<32 | uint64(data.FileIndexLow),
+	}, true
+}
@@ -0,0 +1,150 @@
+package scanner
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Encoding is a Unicode encoding recognized from the start of a file.
+type Encoding string
+
+// Encodings recognized by SniffEncoding.
+const (
+	EncodingNone    Encoding = ""         // Nothing recognized; the bytes are read as they are
+	EncodingUTF8    Encoding = "utf-8"    // UTF-8 with a byte order mark
+	EncodingUTF16LE Encoding = "utf-16le" // UTF-16, little endian, as PowerShell writes it
+	EncodingUTF16BE Encoding = "utf-16be"
+	EncodingUTF32LE Encoding = "utf-32le"
+	EncodingUTF32BE Encoding = "utf-32be"
+)
+
+// byteOrderMarks maps each encoding to its byte order mark. UTF-32LE must be
+// checked before UTF-16LE, whose mark begins its own.
+var byteOrderMarks = []struct {
+	encoding Encoding
+	mark     []byte
+}{
+	{EncodingUTF32LE, []byte{0xFF, 0xFE, 0x00, 0x00}},
+	{EncodingUTF32BE, []byte{0x00, 0x00, 0xFE, 0xFF}},
+	{EncodingUTF8, []byte{0xEF, 0xBB, 0xBF}},
+	{EncodingUTF16LE, []byte{0xFF, 0xFE}},
+	{EncodingUTF16BE, []byte{0xFE, 0xFF}},
+}
+
+// SniffEncoding recognizes the encoding of sample, the leading bytes of a
+// file, and returns it with the length of its byte order mark. Without a
+// mark, UTF-16 is recognized from mostly-ASCII text, which leaves a zero in
+// every other byte and never two in a row.
+func SniffEncoding(sample []byte) (Encoding, int) {
+	for _, bom := range byteOrderMarks {
+		if bytes.HasPrefix(sample, bom.mark) {
+			return bom.encoding, len(bom.mark)
+		}
+	}
+
+	if len(sample) < 2 {
+		return EncodingNone, 0
+	}
+
+	var evenZeros, oddZeros int
+	for i := 0; i+1 < len(sample); i += 2 {
+		switch {
+		case sample[i] == 0 && sample[i+1] == 0:
+			return EncodingNone, 0
+		case sample[i] == 0:
+			evenZeros++
+		case sample[i+1] == 0:
+			oddZeros++
+		}
+	}
+
+	// Nearly every unit of ASCII-heavy text has its high byte zero
+	units := len(sample) / 2
+	switch {
+	case oddZeros*4 >= units*3 && evenZeros == 0:
+		return EncodingUTF16LE, 0
+	case evenZeros*4 >= units*3 && oddZeros == 0:
+		return EncodingUTF16BE, 0
+	default:
+		return EncodingNone, 0
+	}
+}
+
+// DecodeText decodes data, which starts with a mark of bomLen bytes, from
+// encoding to UTF-8. It reports false when data is not valid in encoding or
+// decodes to control characters that do not occur in text. A code unit cut
+// off by the end of data is dropped, so a sample of a file may be decoded.
+func DecodeText(data []byte, encoding Encoding, bomLen int) (string, bool) {
+	data = data[bomLen:]
+
+	var runes []rune
+	switch encoding {
+	case EncodingNone, EncodingUTF8:
+		for i := 0; i < len(data); {
+			r, size := utf8.DecodeRune(data[i:])
+			if r == utf8.RuneError && size == 1 {
+				if utf8.FullRune(data[i:]) {
+					return "", false
+				}
+
+				// A sequence cut off by the end of a sample
+				break
+			}
+			runes = append(runes, r)
+			i += size
+		}
+	case EncodingUTF16LE, EncodingUTF16BE:
+		order := byteOrder(encoding)
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			units[i] = order.Uint16(data[2*i:])
+		}
+		if n := len(units); n > 0 && utf16.IsSurrogate(rune(units[n-1])) && units[n-1] < 0xDC00 {
+			// A pair split by the end of a sample
+			units = units[:n-1]
+		}
+		runes = utf16.Decode(units)
+	case EncodingUTF32LE, EncodingUTF32BE:
+		order := byteOrder(encoding)
+		runes = make([]rune, len(data)/4)
+		for i := range runes {
+			runes[i] = rune(order.Uint32(data[4*i:]))
+		}
+	}
+
+	var b strings.Builder
+	for _, r := range runes {
+		if r == utf8.RuneError || !utf8.ValidRune(r) || (unicode.IsControl(r) && !strings.ContainsRune("\t\n\v\f\r\x1b", r)) {
+			return "", false
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String(), true
+}
+
+// byteOrder returns the byte order of a UTF-16 or UTF-32 encoding.
+func byteOrder(encoding Encoding) binary.ByteOrder {
+	if encoding == EncodingUTF16LE || encoding == EncodingUTF32LE {
+		return binary.LittleEndian
+	}
+
+	return binary.BigEndian
+}
+
+// isEncodedText reports whether sample is text in an encoding other than
+// plain UTF-8 or ASCII: it has a byte order mark or looks like UTF-16, and
+// decodes cleanly.
+func isEncodedText(sample []byte) bool {
+	encoding, bomLen := SniffEncoding(sample)
+	if encoding == EncodingNone {
+		return false
+	}
+	_, ok := DecodeText(sample, encoding, bomLen)
+
+	return ok
+}
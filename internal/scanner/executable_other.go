@@ -0,0 +1,10 @@
+//go:build !windows
+
+package scanner
+
+import "os"
+
+// isExecutable reports whether any execute permission bit is set.
+func isExecutable(_ string, info os.FileInfo) bool {
+	return info.Mode().Perm()&0o111 != 0
+}
@@ -0,0 +1,23 @@
+//go:build windows
+
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// executableExtensions are the extensions treated as executable on Windows,
+// where permission bits carry no meaning.
+var executableExtensions = map[string]bool{
+	".bat": true,
+	".cmd": true,
+	".ps1": true,
+	".exe": true,
+}
+
+// isExecutable infers executability from the file extension.
+func isExecutable(path string, _ os.FileInfo) bool {
+	return executableExtensions[strings.ToLower(filepath.Ext(path))]
+}
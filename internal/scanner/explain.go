@@ -0,0 +1,96 @@
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// Explain makes the checks Scan would make on its way to the regular file at
+// path, which is cfg.Directory joined with a relative path the way Scan builds
+// paths. It returns the file as Scan would report it, together with the
+// verdict of every rule. Unlike Scan it does not stop at an exclusion, so each
+// rule is reported. The rules for directories are merged across the
+// directories between cfg.Directory and the file, keeping the first
+// exclusion. Only the rules of DefaultShouldDescend and DefaultShouldInclude
+// are checked; predicates set by options are not.
+func (s *Scanner) Explain(cfg *Config, path string, opts ...Option) (FileInfo, []Verdict, error) {
+	var walk walkOptions
+	for _, opt := range opts {
+		opt(&walk)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return FileInfo{}, nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return FileInfo{}, nil, fmt.Errorf("%s is not a regular file", path)
+	}
+
+	relPath, err := filepath.Rel(cfg.Directory, path)
+	if err != nil {
+		return FileInfo{}, nil, err
+	}
+
+	ctx := &scanContext{cfg: cfg, base: newPathBase(cfg), walk: walk}
+	ctx.setRootDevice()
+
+	var dirs [][]Verdict
+	for dir := filepath.Dir(relPath); dir != "."; dir = filepath.Dir(dir) {
+		dirPath := filepath.Join(cfg.Directory, dir)
+		dirInfo, err := os.Stat(dirPath)
+		if err != nil {
+			return FileInfo{}, nil, err
+		}
+		verdicts := append(s.DescendVerdicts(dirPath, cfg), append(boundaryVerdicts(dirPath, dirInfo, ctx), linkVerdict(dirPath, cfg))...)
+		for i := range verdicts {
+			// Name the directory, since the verdicts of several are merged
+			if verdicts[i].Detail != "" {
+				verdicts[i].Detail = dir + " " + verdicts[i].Detail
+			}
+		}
+		dirs = append(dirs, verdicts)
+	}
+	// Report the directory nearest the root first, as Scan reaches it first
+	slices.Reverse(dirs)
+
+	depth := Verdict{Rule: "recursion"}
+	switch {
+	case len(dirs) > 0 && !cfg.Recursive:
+		depth.Excluded = true
+		depth.Detail = "in a subdirectory and --recursive is not set"
+	case cfg.MaxDepth > 0 && len(dirs) > cfg.MaxDepth:
+		depth.Excluded = true
+		depth.Detail = fmt.Sprintf("%d levels deep, below --max-depth %d", len(dirs), cfg.MaxDepth)
+	}
+	verdicts := append([]Verdict{depth}, mergeVerdicts(dirs)...)
+
+	file, err := s.fileInfo(path, info, ctx)
+	if err != nil {
+		return FileInfo{}, nil, errors.Join(fmt.Errorf("cannot resolve the relative path of %s", path), err)
+	}
+
+	return file, append(verdicts, IncludeVerdicts(file, cfg)...), nil
+}
+
+// mergeVerdicts combines the verdicts several directories received from the
+// same rules, keeping for each rule the first exclusion.
+func mergeVerdicts(dirs [][]Verdict) []Verdict {
+	if len(dirs) == 0 {
+		return nil
+	}
+
+	merged := slices.Clone(dirs[0])
+	for _, verdicts := range dirs[1:] {
+		for i, verdict := range verdicts {
+			if !merged[i].Excluded && verdict.Excluded {
+				merged[i] = verdict
+			}
+		}
+	}
+
+	return merged
+}
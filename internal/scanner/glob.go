@@ -0,0 +1,66 @@
+package scanner
+
+import (
+	"errors"
+	"strings"
+)
+
+// ExpandGlob normalizes a glob pattern and expands its brace alternatives, so
+// "./src/*.{go,md}" becomes "src/*.go" and "src/*.md". Braces may nest. It
+// fails on empty patterns and unbalanced braces, which the matcher would
+// otherwise take literally and never match.
+func ExpandGlob(pattern string) ([]string, error) {
+	pattern = strings.TrimPrefix(strings.TrimSpace(pattern), "./")
+	if pattern == "" {
+		return nil, errors.New("empty pattern")
+	}
+
+	return expandBraces(pattern)
+}
+
+// expandBraces expands the first brace group in pattern and recurses into
+// each result, which expands the groups after it.
+func expandBraces(pattern string) ([]string, error) {
+	open := strings.IndexAny(pattern, "{}")
+	if open < 0 {
+		return []string{pattern}, nil
+	}
+	if pattern[open] == '}' {
+		return nil, errors.New("unexpected '}' without a matching '{'")
+	}
+
+	// Find the matching close brace and the commas at this depth
+	depth, commas, end := 0, []int{}, -1
+	for i := open; i < len(pattern) && end < 0; i++ {
+		switch pattern[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				end = i
+			}
+		case ',':
+			if depth == 1 {
+				commas = append(commas, i)
+			}
+		}
+	}
+	if end < 0 {
+		return nil, errors.New("unclosed '{'")
+	}
+
+	prefix, suffix := pattern[:open], pattern[end+1:]
+	start := open + 1
+	var expanded []string
+	for _, stop := range append(commas, end) {
+		results, err := expandBraces(prefix + pattern[start:stop] + suffix)
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, results...)
+		start = stop + 1
+	}
+
+	return expanded, nil
+}
@@ -0,0 +1,79 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/connerohnesorge/catls/internal/testutil"
+)
+
+// scanGoldenCases walk the fixture tree with the default predicates built
+// from Config, and with predicates wrapping them, so a change to what the
+// walker selects shows up as a golden diff. Directory is filled in with the
+// fixture root.
+var scanGoldenCases = []struct {
+	name string
+	cfg  Config
+	opts func(s *Scanner, cfg *Config) []Option
+}{
+	{name: "default", cfg: Config{}},
+	{name: "recursive", cfg: Config{Recursive: true}},
+	{name: "show-all", cfg: Config{Recursive: true, ShowAll: true}},
+	{name: "ignore-dir", cfg: Config{Recursive: true, IgnoreDir: []string{"node_modules", "src"}}},
+	{name: "globs", cfg: Config{Recursive: true, Globs: []string{"*.go", "*.md"}, IgnoreGlobs: []string{"docs/*"}}},
+	{name: "include-dirs", cfg: Config{Recursive: true, IncludeDirs: true, IgnoreDir: []string{"node_modules"}}},
+	{
+		name: "predicates",
+		cfg:  Config{Recursive: true},
+		opts: func(s *Scanner, cfg *Config) []Option {
+			descend, include := s.DefaultShouldDescend(cfg), DefaultShouldInclude(cfg)
+
+			return []Option{
+				WithDescend(func(dir string) bool {
+					return descend(dir) && filepath.Base(dir) != "lib"
+				}),
+				WithInclude(func(file FileInfo) bool {
+					return include(file) && !strings.HasSuffix(file.Path, ".txt")
+				}),
+			}
+		},
+	},
+}
+
+func TestScanGolden(t *testing.T) {
+	root := testutil.BuildFixtureTree(t)
+
+	for _, tc := range scanGoldenCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := New()
+			cfg := tc.cfg
+			cfg.Directory = root
+			var opts []Option
+			if tc.opts != nil {
+				opts = tc.opts(s, &cfg)
+			}
+
+			files, err := s.Scan(context.Background(), &cfg, opts...)
+			if err != nil {
+				t.Fatalf("Scan() unexpected error: %v", err)
+			}
+
+			var b strings.Builder
+			for _, file := range files {
+				kind := "file"
+				switch {
+				case file.IsDir:
+					kind = "dir"
+				case file.IsBinary:
+					kind = "binary"
+				}
+				fmt.Fprintf(&b, "%-6s %s\n", kind, filepath.ToSlash(file.RelPath))
+			}
+
+			testutil.AssertGolden(t, filepath.Join("testdata", "golden", "scan-"+tc.name+".golden"), b.String())
+		})
+	}
+}
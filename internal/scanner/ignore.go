@@ -1,28 +1,156 @@
 package scanner
 
 import (
+	"fmt"
+	"path"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 )
 
-// shouldIgnoreDir determines if a directory should be ignored.
-func (s *Scanner) shouldIgnoreDir(dirPath string, cfg *Config) bool {
+// maxGlobRegexes caps globRegexes. Patterns come from configuration, so
+// the cap is only reached by a process that runs with many configurations,
+// such as catls serve over hours; the cache then starts over.
+const maxGlobRegexes = 1024
+
+// globRegexes holds compiled glob regular expressions, shared by every scan
+// in the process, since globs are matched against every path scanned.
+var globRegexes = struct {
+	sync.Mutex
+	m map[string]globRegex
+}{m: make(map[string]globRegex)}
+
+type globRegex struct {
+	re  *regexp.Regexp
+	err error
+}
+
+// compileGlobRegex compiles expr, a glob converted by WildcardToRegex,
+// reusing an earlier compilation.
+func compileGlobRegex(expr string) (*regexp.Regexp, error) {
+	globRegexes.Lock()
+	defer globRegexes.Unlock()
+
+	if cached, ok := globRegexes.m[expr]; ok {
+		return cached.re, cached.err
+	}
+	if len(globRegexes.m) >= maxGlobRegexes {
+		clear(globRegexes.m)
+	}
+	re, err := regexp.Compile(expr)
+	globRegexes.m[expr] = globRegex{re: re, err: err}
+
+	return re, err
+}
+
+// ignoreDirVerdict checks dirPath against IgnoreDir.
+func (s *Scanner) ignoreDirVerdict(dirPath string, cfg *Config) Verdict {
 	realDirPath, err := filepath.Abs(dirPath)
 	if err != nil {
 		realDirPath = dirPath
 	}
 
-	// Check ignore directories
 	for _, ignoreDir := range cfg.IgnoreDir {
 		if s.matchesIgnoreDir(dirPath, realDirPath, ignoreDir) {
-			return true
+			return Verdict{Rule: "ignore-dir", Excluded: true, Detail: fmt.Sprintf("matches %q", ignoreDir)}
 		}
 	}
 
-	// Check ignore globs
+	return Verdict{Rule: "ignore-dir"}
+}
+
+// ignoreGlobVerdict checks dirPath against IgnoreGlobs. Like pruning, it is
+// off when a negation pattern could bring back files below the directory.
+func ignoreGlobVerdict(dirPath string, cfg *Config, base *pathBase) Verdict {
+	if negationReaches(dirPath, cfg.IgnoreGlobs, base) {
+		return Verdict{Rule: "directory ignore glob"}
+	}
+	if pattern, ok := MatchingGlob(dirPath, cfg.IgnoreGlobs); ok {
+		return Verdict{Rule: "directory ignore glob", Excluded: true, Detail: fmt.Sprintf("matches %q", pattern)}
+	}
+
+	return Verdict{Rule: "directory ignore glob"}
+}
+
+// pruneGlobRule names the verdict of directory-shaped ignore globs.
+const pruneGlobRule = "ignore glob prune"
+
+// ignoreGlobPruneVerdict checks dirPath against the directory-shaped
+// IgnoreGlobs, those ending in "/*" or "/**". When the directory's relative
+// path, with a trailing separator, matches the part of such a glob before
+// "/*", the glob would drop every file below it, so the directory is not
+// entered at all. A negation pattern which could bring back some of those
+// files keeps the directory.
+func ignoreGlobPruneVerdict(dirPath string, cfg *Config, base *pathBase) Verdict {
+	verdict := Verdict{Rule: pruneGlobRule}
+	if negationReaches(dirPath, cfg.IgnoreGlobs, base) {
+		return verdict
+	}
+
+	var relPath string
 	for _, pattern := range cfg.IgnoreGlobs {
-		if MatchesGlobPattern(dirPath, pattern) {
+		prefix, ok := dirGlobPrefix(pattern)
+		if !ok {
+			continue
+		}
+		if relPath == "" {
+			rel, err := base.rel(dirPath)
+			if err != nil {
+				return verdict
+			}
+			relPath = rel + string(filepath.Separator)
+		}
+
+		regex, err := compileGlobRegex(WildcardToRegex(prefix) + "/")
+		if err == nil && regex.MatchString(relPath) {
+			verdict.Excluded = true
+			verdict.Detail = fmt.Sprintf("matches %q", pattern)
+
+			return verdict
+		}
+	}
+
+	return verdict
+}
+
+// dirGlobPrefix returns pattern without its "/*" or "/**" suffix, and whether
+// it has one.
+func dirGlobPrefix(pattern string) (string, bool) {
+	for _, suffix := range []string{"/**", "/*"} {
+		if prefix, ok := strings.CutSuffix(pattern, suffix); ok && prefix != "" {
+			return prefix, true
+		}
+	}
+
+	return "", false
+}
+
+// negationReaches reports whether a pattern among patterns starting with
+// "!", which re-includes what other patterns exclude as in .gitignore, could
+// bring back files below dirPath. As in .gitignore, a negation without a
+// slash matches names at any depth and so reaches every directory, while one
+// with a slash is anchored at the directory relative paths start from and
+// reaches dirPath only when its leading segments match the directory's.
+func negationReaches(dirPath string, patterns []string, base *pathBase) bool {
+	var dirSegments []string
+	for _, pattern := range patterns {
+		negated, ok := strings.CutPrefix(pattern, "!")
+		if !ok {
+			continue
+		}
+		negated = strings.TrimPrefix(strings.TrimSuffix(negated, "/"), "/")
+		if !strings.Contains(negated, "/") {
+			return true
+		}
+		if dirSegments == nil {
+			rel, err := base.rel(dirPath)
+			if err != nil || rel == "." {
+				return true
+			}
+			dirSegments = strings.Split(filepath.ToSlash(rel), "/")
+		}
+		if segmentsReach(strings.Split(negated, "/"), dirSegments) {
 			return true
 		}
 	}
@@ -30,6 +158,23 @@ func (s *Scanner) shouldIgnoreDir(dirPath string, cfg *Config) bool {
 	return false
 }
 
+// segmentsReach reports whether a path matching the glob segments could lie
+// below or at the directory made of dirSegments. A "**" segment matches any
+// number of directories, so it reaches every directory below the segments
+// before it; a malformed segment is assumed to match.
+func segmentsReach(segments, dirSegments []string) bool {
+	for i, segment := range segments {
+		if i == len(dirSegments) || strings.Contains(segment, "**") {
+			return true
+		}
+		if matched, err := path.Match(segment, dirSegments[i]); err == nil && !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
 // matchesIgnoreDir checks if a directory matches an ignore pattern.
 func (*Scanner) matchesIgnoreDir(dirPath, realDirPath, ignoreDir string) bool {
 	// Simple directory name match
@@ -64,8 +209,7 @@ func (*Scanner) matchesIgnoreDir(dirPath, realDirPath, ignoreDir string) bool {
 
 // MatchesGlobPattern checks if a file path matches a glob pattern.
 func MatchesGlobPattern(filePath, pattern string) bool {
-	regexPattern := WildcardToRegex(pattern)
-	regex, err := regexp.Compile(regexPattern)
+	regex, err := compileGlobRegex(WildcardToRegex(pattern))
 	if err != nil {
 		return false
 	}
@@ -85,6 +229,17 @@ func MatchesGlobPattern(filePath, pattern string) bool {
 	return regex.MatchString(filePath)
 }
 
+// MatchingGlob returns the first of patterns that filePath matches.
+func MatchingGlob(filePath string, patterns []string) (string, bool) {
+	for _, pattern := range patterns {
+		if MatchesGlobPattern(filePath, pattern) {
+			return pattern, true
+		}
+	}
+
+	return "", false
+}
+
 // WildcardToRegex converts a glob pattern to a regex pattern.
 func WildcardToRegex(pattern string) string {
 	escaped := regexp.QuoteMeta(pattern)
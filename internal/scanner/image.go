@@ -0,0 +1,107 @@
+package scanner
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/connerohnesorge/catls/internal/fdlimit"
+)
+
+// MIMETypeSVG is the MIME type SniffImage reports for SVG documents.
+const MIMETypeSVG = "image/svg+xml"
+
+// sniffLen is how many leading bytes are read to recognize an image.
+const sniffLen = 512
+
+// imageSignature identifies an image format by bytes at fixed offsets.
+type imageSignature struct {
+	mime   string
+	magic  []byte
+	offset int
+	// extra must also appear at extraOffset, for containers such as RIFF.
+	extra       []byte
+	extraOffset int
+}
+
+// imageSignatures is the magic-number table used to recognize raster images.
+var imageSignatures = []imageSignature{
+	{mime: "image/png", magic: []byte("\x89PNG\r\n\x1a\n")},
+	{mime: "image/jpeg", magic: []byte{0xFF, 0xD8, 0xFF}},
+	{mime: "image/gif", magic: []byte("GIF87a")},
+	{mime: "image/gif", magic: []byte("GIF89a")},
+	{mime: "image/webp", magic: []byte("RIFF"), extra: []byte("WEBP"), extraOffset: 8},
+	{mime: "image/bmp", magic: []byte("BM")},
+	{mime: "image/x-icon", magic: []byte{0x00, 0x00, 0x01, 0x00}},
+}
+
+// SniffImage returns the MIME type of the image whose leading bytes are head,
+// or an empty string if head is not a recognized image. Extensions are never
+// consulted.
+func SniffImage(head []byte) string {
+	for _, sig := range imageSignatures {
+		if !hasBytesAt(head, sig.magic, sig.offset) {
+			continue
+		}
+		if sig.extra != nil && !hasBytesAt(head, sig.extra, sig.extraOffset) {
+			continue
+		}
+
+		return sig.mime
+	}
+
+	if isSVG(head) {
+		return MIMETypeSVG
+	}
+
+	return ""
+}
+
+// SniffImageFile reads the leading bytes of path and returns its image MIME
+// type, or an empty string if it is not a recognized image or cannot be read.
+func SniffImageFile(path string) string {
+	file, err := fdlimit.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer func() { _ = file.Close() }()
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(file, head)
+	if err != nil && n == 0 {
+		return ""
+	}
+
+	return SniffImage(head[:n])
+}
+
+func hasBytesAt(head, want []byte, offset int) bool {
+	return len(head) >= offset+len(want) && bytes.Equal(head[offset:offset+len(want)], want)
+}
+
+// isSVG reports whether head starts an SVG document: an <svg root element,
+// optionally preceded by a byte order mark, an XML declaration, comments, or a
+// doctype.
+func isSVG(head []byte) bool {
+	rest := bytes.TrimPrefix(head, []byte("\xEF\xBB\xBF"))
+	for {
+		rest = bytes.TrimLeft(rest, " \t\r\n")
+
+		var end []byte
+		switch {
+		case bytes.HasPrefix(rest, []byte("<?")):
+			end = []byte("?>")
+		case bytes.HasPrefix(rest, []byte("<!--")):
+			end = []byte("-->")
+		case bytes.HasPrefix(rest, []byte("<!")):
+			end = []byte(">")
+		default:
+			return bytes.HasPrefix(rest, []byte("<svg"))
+		}
+
+		i := bytes.Index(rest, end)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(end):]
+	}
+}
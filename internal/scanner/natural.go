@@ -0,0 +1,71 @@
+package scanner
+
+import (
+	"cmp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// NaturalCompare orders paths the way people read them: runs of ASCII digits
+// compare by their numeric value, so "file2" sorts before "file10", and other
+// characters compare by their lower case, so "Zebra" sorts after "apple".
+// Paths equal under those rules, such as "a01" and "a1" or "README" and
+// "readme", fall back to comparing their bytes, so the order is total and the
+// same on every run.
+func NaturalCompare(a, b string) int {
+	if c := naturalCompare(a, b); c != 0 {
+		return c
+	}
+
+	return strings.Compare(a, b)
+}
+
+// naturalCompare is NaturalCompare without the byte-wise tie-break.
+func naturalCompare(a, b string) int {
+	for a != "" && b != "" {
+		if isDigit(a[0]) && isDigit(b[0]) {
+			na, nb := digitRun(a), digitRun(b)
+			if c := compareNumbers(a[:na], b[:nb]); c != 0 {
+				return c
+			}
+			a, b = a[na:], b[nb:]
+
+			continue
+		}
+
+		ra, na := utf8.DecodeRuneInString(a)
+		rb, nb := utf8.DecodeRuneInString(b)
+		if c := cmp.Compare(unicode.ToLower(ra), unicode.ToLower(rb)); c != 0 {
+			return c
+		}
+		a, b = a[na:], b[nb:]
+	}
+
+	// A path that is a prefix of the other comes first
+	return cmp.Compare(len(a), len(b))
+}
+
+// isDigit reports whether c is an ASCII digit.
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// digitRun returns the length of the run of digits s starts with.
+func digitRun(s string) int {
+	n := 0
+	for n < len(s) && isDigit(s[n]) {
+		n++
+	}
+
+	return n
+}
+
+// compareNumbers compares two runs of digits by value. Runs of any length
+// are compared without parsing, so they never overflow.
+func compareNumbers(a, b string) int {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+
+	return cmp.Or(cmp.Compare(len(a), len(b)), strings.Compare(a, b))
+}
@@ -0,0 +1,68 @@
+package scanner
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNaturalCompare(t *testing.T) {
+	// Each pair is in order: a sorts strictly before b
+	tests := []struct {
+		a, b string
+	}{
+		// Numbers compare by value
+		{a: "file2.go", b: "file10.go"},
+		{a: "File2.go", b: "file10.go"},
+		{a: "v1.9.0", b: "v1.10.0"},
+		{a: "a9b", b: "a10a"},
+		{a: "x99999999999999999999999", b: "x100000000000000000000000"},
+		// Letters compare without case; equal keys fall back to the bytes
+		{a: "apple", b: "Banana"},
+		{a: "Apple", b: "apple"},
+		{a: "README", b: "readme"},
+		{a: "a01", b: "a1"},
+		{a: "a1", b: "a001b"},
+		// A prefix comes first
+		{a: "file", b: "file1"},
+		{a: "file1", b: "file1a"},
+		{a: "file1a", b: "file2"},
+		{a: "", b: "a"},
+		// Digits come before letters, as they do in bytes
+		{a: "1a", b: "a1"},
+		{a: "a/b.go", b: "a/c.go"},
+		{a: "a-b/x", b: "a/x"},
+		// Unicode letters compare by their lower case
+		{a: "Äpfel", b: "äpfel"},
+		{a: "äpfel", b: "Öl"},
+		{a: "Émile2", b: "émile10"},
+		{a: "日本1", b: "日本02x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" "+tt.b, func(t *testing.T) {
+			if got := NaturalCompare(tt.a, tt.b); got >= 0 {
+				t.Errorf("NaturalCompare(%q, %q) = %d, want < 0", tt.a, tt.b, got)
+			}
+			if got := NaturalCompare(tt.b, tt.a); got <= 0 {
+				t.Errorf("NaturalCompare(%q, %q) = %d, want > 0", tt.b, tt.a, got)
+			}
+			if got := NaturalCompare(tt.a, tt.a); got != 0 {
+				t.Errorf("NaturalCompare(%q, %q) = %d, want 0", tt.a, tt.a, got)
+			}
+		})
+	}
+
+	// Any input order sorts the same way
+	want := []string{"file", "File1.go", "file1.go", "file2.go", "File10.go", "file10.go", "file010x.go", "src/a.go"}
+	for _, input := range [][]string{
+		slices.Clone(want),
+		{"src/a.go", "file010x.go", "file10.go", "File10.go", "file2.go", "file1.go", "File1.go", "file"},
+		{"file10.go", "file", "file2.go", "src/a.go", "File1.go", "file010x.go", "File10.go", "file1.go"},
+	} {
+		slices.SortFunc(input, NaturalCompare)
+		if strings.Join(input, " ") != strings.Join(want, " ") {
+			t.Errorf("sorted = %v, want %v", input, want)
+		}
+	}
+}
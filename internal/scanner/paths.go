@@ -0,0 +1,58 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/connerohnesorge/catls/internal/longpath"
+)
+
+// includeAll is the include predicate of a Paths scan without WithInclude.
+func includeAll(FileInfo) bool {
+	return true
+}
+
+// scanPaths reports the files in cfg.Paths in the order given instead of
+// walking cfg.Directory. Nothing is descended into, so only the prefilter,
+// the include predicate, and MaxFiles apply. Every path must name a regular file.
+// The files are detected together once all of them are found, in chunks
+// that stop at MaxFiles.
+func (s *Scanner) scanPaths(ctx context.Context, cfg *Config, walk walkOptions) ([]FileInfo, error) {
+	var files []FileInfo
+	scanCtx := &scanContext{cfg: cfg, base: newPathBase(cfg), walk: walk, files: &files, run: ctx}
+
+	var pending []pendingFile
+
+	for _, path := range cfg.Paths {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		fullPath := filepath.Join(cfg.Directory, path)
+		info, err := os.Stat(longpath.Fix(fullPath))
+		if err != nil {
+			return nil, err
+		}
+		if !info.Mode().IsRegular() {
+			return nil, fmt.Errorf("%s is not a regular file", fullPath)
+		}
+
+		file, err := scanCtx.statFile(fullPath, info)
+		if err != nil {
+			return nil, err
+		}
+		if walk.shouldRead(file) {
+			pending = append(pending, pendingFile{file: file, info: info})
+		}
+	}
+
+	if err := s.addFiles(pending, scanCtx); err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
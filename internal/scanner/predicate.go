@@ -0,0 +1,180 @@
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DescendFunc decides whether the walker enters the directory at dirPath.
+type DescendFunc func(dirPath string) bool
+
+// IncludeFunc decides whether a discovered regular file is returned by Scan.
+type IncludeFunc func(file FileInfo) bool
+
+// TypeFunc returns the type of a discovered regular file, or an empty string
+// if it is unknown.
+type TypeFunc func(file FileInfo) string
+
+// Option customizes a single Scan call.
+type Option func(*walkOptions)
+
+type walkOptions struct {
+	shouldDescend DescendFunc
+	shouldRead    IncludeFunc
+	shouldInclude IncludeFunc
+	detectType    TypeFunc
+}
+
+// WithDescend replaces the default directory predicate. Wrap
+// DefaultShouldDescend to extend the default behavior instead of replacing it.
+func WithDescend(fn DescendFunc) Option {
+	return func(o *walkOptions) {
+		o.shouldDescend = fn
+	}
+}
+
+// WithInclude replaces the default file predicate. Wrap DefaultShouldInclude
+// to extend the default behavior instead of replacing it.
+func WithInclude(fn IncludeFunc) Option {
+	return func(o *walkOptions) {
+		o.shouldInclude = fn
+	}
+}
+
+// WithPrefilter replaces the default predicate run on every regular file
+// before its content is read for binary and type detection. The file it sees
+// has no IsBinary, FileType, or HasType yet; a file it rejects is never read
+// and never reaches the include predicate. Wrap DefaultShouldRead to extend
+// the default behavior instead of replacing it.
+func WithPrefilter(fn IncludeFunc) Option {
+	return func(o *walkOptions) {
+		o.shouldRead = fn
+	}
+}
+
+// WithTypeDetector sets FileType and HasType on every non-binary file before
+// the include predicate sees it, so files can be filtered by type and the type
+// is detected only once. Binary files get HasType with an empty FileType.
+func WithTypeDetector(fn TypeFunc) Option {
+	return func(o *walkOptions) {
+		o.detectType = fn
+	}
+}
+
+// Verdict is the outcome of one rule deciding whether a path is scanned or
+// output. Predicates that collect verdicts can explain a decision, not just
+// make it.
+type Verdict struct {
+	Rule     string // Rule that was checked, such as "hidden file"
+	Excluded bool   // The rule excludes the path
+	Detail   string // What the rule found, such as the matching pattern
+}
+
+// String formats the verdict as "rule: detail" for debug output.
+func (v Verdict) String() string {
+	if v.Detail == "" {
+		return v.Rule
+	}
+
+	return v.Rule + ": " + v.Detail
+}
+
+// FirstExclusion returns the first of verdicts that excludes the path.
+func FirstExclusion(verdicts []Verdict) (Verdict, bool) {
+	for _, verdict := range verdicts {
+		if verdict.Excluded {
+			return verdict, true
+		}
+	}
+
+	return Verdict{}, false
+}
+
+// DefaultShouldDescend returns the directory predicate used when no WithDescend
+// option is given: hidden directories are skipped unless ShowAll is set, and
+// IgnoreDir and IgnoreGlobs prune matching directories. Directory-shaped
+// globs such as "testdata/*" prune the directories whose files they match.
+func (s *Scanner) DefaultShouldDescend(cfg *Config) DescendFunc {
+	base := newQuietPathBase(cfg)
+
+	return func(dirPath string) bool {
+		verdict, excluded := FirstExclusion(s.descendVerdicts(dirPath, cfg, base))
+		if excluded && cfg.Debug {
+			if verdict.Rule == pruneGlobRule {
+				fmt.Fprintf(os.Stderr, "Debug: Pruned directory via glob: %s (%s)\n", dirPath, verdict.Detail)
+			} else {
+				fmt.Fprintf(os.Stderr, "Debug: Ignoring directory: %s (%s)\n", dirPath, verdict)
+			}
+		}
+
+		return !excluded
+	}
+}
+
+// DescendVerdicts returns the verdict of every rule DefaultShouldDescend
+// applies to dirPath, in order.
+func (s *Scanner) DescendVerdicts(dirPath string, cfg *Config) []Verdict {
+	return s.descendVerdicts(dirPath, cfg, newQuietPathBase(cfg))
+}
+
+// descendVerdicts is DescendVerdicts with the base that relative paths of
+// directories are computed from.
+func (s *Scanner) descendVerdicts(dirPath string, cfg *Config, base *pathBase) []Verdict {
+	return []Verdict{
+		hiddenVerdict("hidden directory", dirPath, cfg),
+		s.ignoreDirVerdict(dirPath, cfg),
+		ignoreGlobVerdict(dirPath, cfg, base),
+		ignoreGlobPruneVerdict(dirPath, cfg, base),
+	}
+}
+
+// DefaultShouldInclude returns the file predicate used when no WithInclude
+// option is given: hidden files are skipped unless ShowAll is set.
+func DefaultShouldInclude(cfg *Config) IncludeFunc {
+	return func(file FileInfo) bool {
+		_, excluded := FirstExclusion(IncludeVerdicts(file, cfg))
+
+		return !excluded
+	}
+}
+
+// DefaultShouldRead returns the default prefilter: files matching one of
+// cfg.Globs, or every file when there are none, are read. Matching needs only
+// the path, so a narrow glob spares the detection of every other file.
+func DefaultShouldRead(cfg *Config) IncludeFunc {
+	return func(file FileInfo) bool {
+		if len(cfg.Globs) == 0 {
+			return true
+		}
+		_, ok := MatchingGlob(file.RelPath, cfg.Globs)
+
+		return ok
+	}
+}
+
+// IncludeVerdicts returns the verdict of every rule DefaultShouldInclude
+// applies to file, in order.
+func IncludeVerdicts(file FileInfo, cfg *Config) []Verdict {
+	return []Verdict{hiddenVerdict("hidden file", file.Path, cfg)}
+}
+
+// hiddenVerdict checks whether path is a dotfile that ShowAll does not reveal.
+func hiddenVerdict(rule, path string, cfg *Config) Verdict {
+	verdict := Verdict{Rule: rule}
+	if isHidden(path) {
+		verdict.Excluded = !cfg.ShowAll
+		verdict.Detail = "starts with a dot"
+		if cfg.ShowAll {
+			verdict.Detail += ", shown by --all"
+		}
+	}
+
+	return verdict
+}
+
+// isHidden reports whether the final element of path is a dotfile.
+func isHidden(path string) bool {
+	return strings.HasPrefix(filepath.Base(path), ".")
+}
@@ -3,18 +3,50 @@ package scanner
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
+	"time"
+
+	"github.com/connerohnesorge/catls/internal/longpath"
+	"github.com/connerohnesorge/catls/internal/profile"
+)
+
+// ErrTooManyFiles is returned by Scan when more than Config.MaxFiles files are found.
+var ErrTooManyFiles = errors.New("too many files")
+
+const (
+	// dirBatchSize is the number of entries read from a directory at a time.
+	dirBatchSize = 1024
+	// sortedDirLimit is the largest directory whose entries are collected and
+	// sorted before processing. Larger directories are processed batch by batch
+	// in directory order; Scan sorts its result, so output order is unaffected.
+	sortedDirLimit = 4096
 )
 
 // FileInfo represents information about a discovered file.
 type FileInfo struct {
-	Path     string // Path to the file
-	RelPath  string // Relative path to the file
-	IsBinary bool   // Whether the file is a binary file.
+	Path       string    // Path to the file
+	RelPath    string    // Relative path to the file
+	IsBinary   bool      // Whether the file is a binary file.
+	IsDir      bool      // Whether this is a directory record emitted by IncludeDirs
+	Executable bool      // Any execute permission bit is set; inferred from the extension on Windows
+	Size       int64     // Size in bytes at scan time
+	ModTime    time.Time // Modification time at scan time
+	FileType   string    // Type reported by the WithTypeDetector function ("" when unknown)
+	HasType    bool      // FileType was detected during Scan
+}
+
+// FileIdentity identifies a file on disk by device and inode, so hard links
+// to one file share an identity. See Identity.
+type FileIdentity struct {
+	Device uint64
+	Inode  uint64
 }
 
 // Config holds scanner configuration.
@@ -24,11 +56,26 @@ type Config struct {
 	Recursive   bool     // Recursive option
 	IgnoreDir   []string // IgnoreDir option
 	IgnoreGlobs []string // IgnoreGlobs option
+	Globs       []string // Only read and report files matching one of these (empty means all); see DefaultShouldRead
 	Debug       bool     // Debug logging
 	RelativeTo  string   // Base directory for relative paths (empty means use Directory)
+
+	OneFileSystem     bool // Do not descend into directories on a different device than Directory
+	SkipGitSubmodules bool // Do not descend into directories that contain a .git file (gitlink)
+	FollowSymlinks    bool // Descend into symlinked directories and junctions, skipping ones scanned otherwise
+	IncludeDirs       bool // Also return a record for every traversed directory below Directory
+	MaxFiles          int  // Stop with ErrTooManyFiles once more records than this are found (0 means no limit)
+	MaxDepth          int  // Do not descend into directories more than this many levels below Directory (0 means no limit)
+	SkipBinaryCheck   bool // Leave IsBinary false instead of reading file contents
+	NaturalSort       bool // Order files with NaturalCompare instead of by the bytes of their paths
+
+	Paths []string // Report exactly these files, relative to Directory and in this order, instead of walking it
+
+	DetectCache *DetectionCache    // Detection results to consult before sniffing (nil disables caching)
+	Profile     *profile.Collector // Records binary detection time per file (nil disables profiling)
 }
 
-// Scanner handles file discovery and filtering.
+// Scanner walks a directory tree and reports the files it finds.
 type Scanner struct {
 	binaryDetector BinaryDetector
 }
@@ -40,8 +87,31 @@ func New() *Scanner {
 	}
 }
 
-// Scan discovers files according to configuration.
-func (s *Scanner) Scan(ctx context.Context, cfg *Config) ([]FileInfo, error) {
+// Scan discovers files according to configuration. Which directories are
+// entered and which files are read and returned is decided by predicates;
+// without options they are DefaultShouldDescend, DefaultShouldRead, and
+// DefaultShouldInclude. Filesystem and submodule boundaries from cfg apply
+// regardless of the predicates. Directory records requested by IncludeDirs
+// bypass the file predicates, since file filters do not apply to them.
+// With Paths, see scanPaths.
+func (s *Scanner) Scan(ctx context.Context, cfg *Config, opts ...Option) ([]FileInfo, error) {
+	walk := walkOptions{
+		shouldDescend: s.DefaultShouldDescend(cfg),
+		shouldRead:    DefaultShouldRead(cfg),
+		shouldInclude: DefaultShouldInclude(cfg),
+	}
+	if len(cfg.Paths) > 0 {
+		// Files named explicitly are not hidden from their caller
+		walk.shouldInclude = includeAll
+	}
+	for _, opt := range opts {
+		opt(&walk)
+	}
+
+	if len(cfg.Paths) > 0 {
+		return s.scanPaths(ctx, cfg, walk)
+	}
+
 	var files []FileInfo
 	maxDepth := 1
 	if cfg.Recursive {
@@ -52,17 +122,28 @@ func (s *Scanner) Scan(ctx context.Context, cfg *Config) ([]FileInfo, error) {
 
 	scanCtx := &scanContext{
 		cfg:   cfg,
+		base:  newPathBase(cfg),
+		walk:  walk,
 		stack: &stack,
 		files: &files,
+		run:   ctx,
 	}
 
-	for len(stack) > 0 {
+	scanCtx.setRootDevice()
+
+	for len(stack) > 0 || len(scanCtx.links) > 0 {
 		select {
 		case <-ctx.Done():
 			return nil, ctx.Err()
 		default:
 		}
 
+		if len(stack) == 0 {
+			scanCtx.followLink()
+
+			continue
+		}
+
 		current := stack[len(stack)-1]
 		stack = stack[:len(stack)-1]
 
@@ -70,11 +151,17 @@ func (s *Scanner) Scan(ctx context.Context, cfg *Config) ([]FileInfo, error) {
 			continue
 		}
 
-		s.scanDirectory(current.path, current.depth, scanCtx)
+		if err := s.scanDirectory(current.path, current.depth, scanCtx); err != nil {
+			return nil, err
+		}
 	}
 
+	compare := strings.Compare
+	if cfg.NaturalSort {
+		compare = NaturalCompare
+	}
 	sort.Slice(files, func(i, j int) bool {
-		return files[i].RelPath < files[j].RelPath
+		return compare(files[i].RelPath, files[j].RelPath) < 0
 	})
 
 	return files, nil
@@ -87,81 +174,601 @@ type dirEntry struct {
 
 type scanContext struct {
 	cfg   *Config
+	base  *pathBase
+	walk  walkOptions
 	stack *[]dirEntry
 	files *[]FileInfo
+
+	rootDevice    uint64
+	hasRootDevice bool
+	depthCapped   bool // A directory below MaxDepth was skipped and reported
+
+	visited map[FileIdentity]bool // Directories queued so far, with FollowSymlinks
+	links   []dirEntry            // Linked directories not yet followed
+
+	run context.Context // Cancels the scan between detection chunks
 }
 
-func (s *Scanner) scanDirectory(path string, depth int, ctx *scanContext) {
-	entries, err := os.ReadDir(path)
+// setRootDevice records the device of the scan root for OneFileSystem.
+func (ctx *scanContext) setRootDevice() {
+	if !ctx.cfg.OneFileSystem {
+		return
+	}
+
+	if info, err := os.Stat(ctx.cfg.Directory); err == nil {
+		ctx.rootDevice, ctx.hasRootDevice = deviceID(info)
+	}
+}
+
+// scanDirectory processes the entries of one directory. Entries are read in
+// batches, so a directory with a huge number of entries is never held in
+// memory at once.
+func (s *Scanner) scanDirectory(path string, depth int, ctx *scanContext) error {
+	dir, err := os.Open(longpath.Fix(path))
 	if err != nil {
 		if ctx.cfg.Debug {
 			fmt.Fprintf(os.Stderr, "Error accessing directory %s: %v\n", path, err)
 		}
 
-		return
+		return nil
+	}
+	defer func() {
+		_ = dir.Close()
+	}()
+
+	// The scan root is implied by the output, so only subdirectories get a record
+	if ctx.cfg.IncludeDirs && depth > 0 {
+		if err := s.addDirRecord(path, ctx); err != nil {
+			return err
+		}
+	}
+
+	var pending []os.DirEntry
+	streaming := false
+
+	for {
+		batch, readErr := dir.ReadDir(dirBatchSize)
+
+		if !streaming {
+			// Sort entries for consistent output while the directory is small
+			pending = append(pending, batch...)
+			if len(pending) > sortedDirLimit {
+				if ctx.cfg.Debug {
+					fmt.Fprintf(os.Stderr, "Debug: Streaming large directory: %s\n", path)
+				}
+				streaming = true
+				batch = pending
+				pending = nil
+			}
+		}
+
+		if streaming {
+			if err := s.processEntries(path, batch, depth, ctx); err != nil {
+				return err
+			}
+		}
+
+		if readErr != nil {
+			if !errors.Is(readErr, io.EOF) && ctx.cfg.Debug {
+				fmt.Fprintf(os.Stderr, "Error reading directory %s: %v\n", path, readErr)
+			}
+
+			break
+		}
 	}
 
-	// Sort entries for consistent output
-	entryNames := make([]string, 0, len(entries))
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].Name() < pending[j].Name()
+	})
+
+	return s.processEntries(path, pending, depth, ctx)
+}
+
+// processEntries queues the subdirectories among entries and adds the files,
+// detecting the files together so a batch detector sees them at once.
+func (s *Scanner) processEntries(dirPath string, entries []os.DirEntry, depth int, ctx *scanContext) error {
+	var files []pendingFile
 	for _, entry := range entries {
-		entryNames = append(entryNames, entry.Name())
+		if file, ok := s.processEntry(filepath.Join(dirPath, entry.Name()), entry, depth, ctx); ok {
+			files = append(files, file)
+		}
 	}
-	sort.Strings(entryNames)
 
-	for _, entryName := range entryNames {
-		if entryName == "." || entryName == ".." {
-			continue
+	return s.addFiles(files, ctx)
+}
+
+// addFiles detects files and adds those the include predicate accepts. They
+// are detected in chunks, enough to keep every run of the file command full,
+// so a cancelled scan or one past MaxFiles stops without detecting the rest.
+func (s *Scanner) addFiles(files []pendingFile, ctx *scanContext) error {
+	for len(files) > 0 {
+		if ctx.run != nil {
+			if err := ctx.run.Err(); err != nil {
+				return err
+			}
 		}
 
-		if !ctx.cfg.ShowAll && strings.HasPrefix(entryName, ".") {
-			continue
+		// At most one batch more than MaxFiles allows is detected
+		size := cap(fileCommands) * fileBatchSize
+		if ctx.cfg.MaxFiles > 0 {
+			size = min(size, max(ctx.cfg.MaxFiles-len(*ctx.files)+1, fileBatchSize))
 		}
+		chunk := files[:min(size, len(files))]
+		files = files[len(chunk):]
 
-		fullPath := filepath.Join(path, entryName)
-		s.processEntry(fullPath, depth, ctx)
+		s.detectFiles(chunk, ctx)
+		for _, file := range chunk {
+			if !ctx.walk.shouldInclude(file.file) {
+				continue
+			}
+			if err := ctx.add(file.file); err != nil {
+				return err
+			}
+		}
 	}
+
+	return nil
 }
 
-func (s *Scanner) processEntry(fullPath string, currentDepth int, ctx *scanContext) {
-	info, err := os.Stat(fullPath)
+// processEntry queues entry when it is a directory to descend into, and
+// returns it when it is a file to read.
+func (s *Scanner) processEntry(fullPath string, entry os.DirEntry, currentDepth int, ctx *scanContext) (pendingFile, bool) {
+	// Decisions start from the entry itself, as Lstat reports it: a symlink
+	// or junction is a link even when it points at a directory.
+	link := isLink(fullPath, entry.Type())
+
+	// Prune ignored directories before paying for a stat. Links are checked
+	// after resolving below.
+	if entry.IsDir() && !link && !ctx.walk.shouldDescend(fullPath) {
+		return pendingFile{}, false
+	}
+
+	info, err := entryInfo(fullPath, entry, link)
 	if err != nil {
-		return
+		return pendingFile{}, false
 	}
 
 	if info.IsDir() {
-		if !s.shouldIgnoreDir(fullPath, ctx.cfg) {
+		switch {
+		case link && !ctx.walk.shouldDescend(fullPath):
+			// Linked directory skipped by the descend predicate
+		case link && !ctx.cfg.FollowSymlinks:
+			if ctx.cfg.Debug {
+				fmt.Fprintf(os.Stderr, "Debug: Not descending into %s (symlinked directory; use --follow-symlinks)\n", fullPath)
+			}
+		case s.crossesBoundary(fullPath, info, ctx):
+			// Traversal stops here; crossesBoundary logs the reason
+		case ctx.cfg.MaxDepth > 0 && currentDepth+1 > ctx.cfg.MaxDepth:
+			ctx.warnDepth(fullPath)
+		case link:
+			ctx.links = append(ctx.links, dirEntry{fullPath, currentDepth + 1})
+		case !ctx.visit(fullPath, info):
+			ctx.debugRepeat(fullPath)
+		default:
 			*ctx.stack = append(*ctx.stack, dirEntry{fullPath, currentDepth + 1})
-		} else if ctx.cfg.Debug {
-			fmt.Fprintf(os.Stderr, "Debug: Ignoring directory: %s\n", fullPath)
 		}
 	} else if info.Mode().IsRegular() {
-		relPath, err := s.getRelativePath(fullPath, ctx.cfg)
-		if err != nil {
-			return
+		file, err := ctx.statFile(fullPath, info)
+		if err == nil && ctx.walk.shouldRead(file) {
+			return pendingFile{file: file, info: info}, true
+		}
+	}
+
+	return pendingFile{}, false
+}
+
+// isLink reports whether the entry at fullPath, of the type mode, is a
+// symlink or a junction. Go reports junctions as irregular, as it does other
+// reparse points such as cloud file placeholders, so an irregular entry is a
+// link only when it can be read as one.
+func isLink(fullPath string, mode os.FileMode) bool {
+	switch {
+	case mode&os.ModeSymlink != 0:
+		return true
+	case mode&os.ModeIrregular != 0:
+		_, err := os.Readlink(longpath.Fix(fullPath))
+
+		return err == nil
+	}
+
+	return false
+}
+
+// entryInfo returns the FileInfo of a directory entry, following links.
+// ReadDir already has everything but the target of a link, so only links
+// cost a stat.
+func entryInfo(fullPath string, entry os.DirEntry, link bool) (os.FileInfo, error) {
+	if link {
+		return os.Stat(longpath.Fix(fullPath))
+	}
+
+	return entry.Info()
+}
+
+// visit records the directory at dirPath as queued and reports whether it
+// was not queued before. Without FollowSymlinks no link is followed, so no
+// directory can repeat and nothing is recorded. Directories are told apart
+// by device and inode, or by volume serial number and file index on
+// Windows; one that cannot be identified is queued, leaving MaxDepth to end
+// a cycle. info, when not nil, is the directory's FileInfo.
+func (ctx *scanContext) visit(dirPath string, info os.FileInfo) bool {
+	if !ctx.cfg.FollowSymlinks {
+		return true
+	}
+	if ctx.visited == nil {
+		ctx.visited = make(map[FileIdentity]bool)
+		if id, ok := dirIdentity(ctx.cfg.Directory, nil); ok {
+			ctx.visited[id] = true
+		}
+	}
+
+	id, ok := dirIdentity(dirPath, info)
+	if !ok {
+		return true
+	}
+	if ctx.visited[id] {
+		return false
+	}
+	ctx.visited[id] = true
+
+	return true
+}
+
+// followLink queues the first by path of the linked directories found so
+// far, unless its target was already queued. Links wait until every
+// directory queued before them is scanned, so a directory is listed under
+// its real path whenever the scan reaches it, whatever the names of the
+// links to it, and a link back to a scanned directory, such as a cycle, is
+// skipped.
+func (ctx *scanContext) followLink() {
+	first := 0
+	for i, link := range ctx.links {
+		if link.path < ctx.links[first].path {
+			first = i
+		}
+	}
+	link := ctx.links[first]
+	ctx.links = slices.Delete(ctx.links, first, first+1)
+
+	if !ctx.visit(link.path, nil) {
+		ctx.debugRepeat(link.path)
+
+		return
+	}
+	*ctx.stack = append(*ctx.stack, link)
+}
+
+// debugRepeat notes, with Debug, a directory skipped for being queued
+// already by another path.
+func (ctx *scanContext) debugRepeat(dirPath string) {
+	if ctx.cfg.Debug {
+		fmt.Fprintf(os.Stderr, "Debug: Not descending into %s (already scanned; a link forms a cycle or repeats a directory)\n", dirPath)
+	}
+}
+
+// warnDepth reports, once per scan, that a directory was skipped for being
+// more than MaxDepth levels deep.
+func (ctx *scanContext) warnDepth(dirPath string) {
+	if ctx.depthCapped {
+		return
+	}
+	ctx.depthCapped = true
+
+	fmt.Fprintf(os.Stderr, "Warning: not descending into %s or other directories more than %d levels deep; raise --max-depth to scan them\n",
+		dirPath, ctx.cfg.MaxDepth)
+}
+
+// fileInfo describes the regular file at fullPath as Scan reports it, with
+// binary and type detection applied.
+func (s *Scanner) fileInfo(fullPath string, info os.FileInfo, ctx *scanContext) (FileInfo, error) {
+	file, err := ctx.statFile(fullPath, info)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	s.detect(&file, info, ctx)
+
+	return file, nil
+}
+
+// statFile describes the regular file at fullPath from what its stat tells,
+// before anything is read from it.
+func (ctx *scanContext) statFile(fullPath string, info os.FileInfo) (FileInfo, error) {
+	relPath, err := ctx.base.rel(fullPath)
+	if err != nil {
+		return FileInfo{}, err
+	}
+
+	return FileInfo{
+		Path:       fullPath,
+		RelPath:    relPath,
+		Executable: isExecutable(fullPath, info),
+		Size:       info.Size(),
+		ModTime:    info.ModTime(),
+	}, nil
+}
+
+// pendingFile is a file found by a scan, waiting for detection.
+type pendingFile struct {
+	file FileInfo
+	info os.FileInfo
+}
+
+// detectFiles applies detection to files like detect. With a
+// BatchBinaryDetector the files the detection cache does not know are
+// classified in one call; with Profile they are not, so each file keeps a
+// timing of its own.
+func (s *Scanner) detectFiles(files []pendingFile, ctx *scanContext) {
+	batch, ok := s.binaryDetector.(BatchBinaryDetector)
+	if !ok || ctx.cfg.SkipBinaryCheck || ctx.cfg.Profile != nil {
+		for i := range files {
+			s.detect(&files[i].file, files[i].info, ctx)
+		}
+
+		return
+	}
+
+	var unknown []int
+	var paths []string
+	for i := range files {
+		file := &files[i]
+		if isBinary, ok := cachedBinary(file.file.Path, file.info, ctx.cfg.DetectCache); ok {
+			file.file.IsBinary = isBinary
+		} else {
+			unknown = append(unknown, i)
+			paths = append(paths, file.file.Path)
+		}
+	}
+	if len(paths) > 0 {
+		for j, isBinary := range batch.IsBinaryBatch(paths) {
+			file := &files[unknown[j]]
+			file.file.IsBinary = isBinary
+			storeBinary(file.file.Path, file.info, ctx.cfg.DetectCache, isBinary)
+		}
+	}
+
+	for i := range files {
+		s.detectType(&files[i].file, ctx)
+	}
+}
+
+// detect applies binary and type detection to file, reading its content
+// unless the detection cache already knows it.
+func (s *Scanner) detect(file *FileInfo, info os.FileInfo, ctx *scanContext) {
+	if !ctx.cfg.SkipBinaryCheck {
+		done := ctx.cfg.Profile.Start(file.RelPath, profile.StageDetectBinary)
+		file.IsBinary = s.detectBinary(file.Path, info, ctx.cfg.DetectCache)
+		done()
+	}
+	s.detectType(file, ctx)
+}
+
+// detectType applies type detection to file once its binary flag is known.
+func (*Scanner) detectType(file *FileInfo, ctx *scanContext) {
+	if ctx.walk.detectType != nil {
+		// Binary files have no type; they are left unknown
+		if !file.IsBinary {
+			file.FileType = ctx.walk.detectType(*file)
+		}
+		file.HasType = true
+	}
+}
+
+// add appends a record to the results, enforcing MaxFiles.
+func (ctx *scanContext) add(file FileInfo) error {
+	if ctx.cfg.MaxFiles > 0 && len(*ctx.files) >= ctx.cfg.MaxFiles {
+		return fmt.Errorf("%w: more than %d under %s", ErrTooManyFiles, ctx.cfg.MaxFiles, ctx.cfg.Directory)
+	}
+
+	*ctx.files = append(*ctx.files, file)
+
+	return nil
+}
+
+// addDirRecord appends a structural record for a traversed directory.
+func (s *Scanner) addDirRecord(dirPath string, ctx *scanContext) error {
+	relPath, err := ctx.base.rel(dirPath)
+	if err != nil {
+		return nil
+	}
+
+	dir := FileInfo{
+		Path:    dirPath,
+		RelPath: relPath,
+		IsDir:   true,
+	}
+	if info, err := os.Stat(longpath.Fix(dirPath)); err == nil {
+		dir.ModTime = info.ModTime()
+	}
+
+	return ctx.add(dir)
+}
+
+// detectBinary classifies a file, consulting the detection cache first so an
+// unchanged file is never sniffed twice.
+func (s *Scanner) detectBinary(path string, info os.FileInfo, cache *DetectionCache) bool {
+	if isBinary, ok := cachedBinary(path, info, cache); ok {
+		return isBinary
+	}
+
+	isBinary := s.binaryDetector.IsBinary(path)
+	storeBinary(path, info, cache, isBinary)
+
+	return isBinary
+}
+
+// cachedBinary returns the binary flag of a file when it is known without
+// asking the detector: from the detection cache, or because the file is
+// empty.
+func cachedBinary(path string, info os.FileInfo, cache *DetectionCache) (bool, bool) {
+	// Zero-byte files carry no data to classify; `file` reports them as
+	// "empty", which would otherwise be mistaken for binary.
+	if info.Size() == 0 {
+		return false, true
+	}
+
+	if entry, ok := cache.Lookup(path, info.Size(), info.ModTime()); ok && entry.HasBinary {
+		return entry.IsBinary, true
+	}
+
+	return false, false
+}
+
+// storeBinary records the binary flag the detector gave a file in cache.
+func storeBinary(path string, info os.FileInfo, cache *DetectionCache, isBinary bool) {
+	cache.Store(path, info.Size(), info.ModTime(), func(e *DetectionEntry) {
+		e.IsBinary = isBinary
+		e.HasBinary = true
+	})
+}
+
+// crossesBoundary reports whether descending into dirPath would leave the
+// scanned tree, either onto another filesystem or into a git submodule. The scan
+// root itself is never checked, so passing a submodule explicitly still works.
+func (*Scanner) crossesBoundary(dirPath string, info os.FileInfo, ctx *scanContext) bool {
+	verdict, excluded := FirstExclusion(boundaryVerdicts(dirPath, info, ctx))
+	if excluded && ctx.cfg.Debug {
+		fmt.Fprintf(os.Stderr, "Debug: Not descending into %s (%s)\n", dirPath, verdict)
+	}
+
+	return excluded
+}
+
+// boundaryVerdicts checks dirPath against OneFileSystem and SkipGitSubmodules.
+func boundaryVerdicts(dirPath string, info os.FileInfo, ctx *scanContext) []Verdict {
+	device := Verdict{Rule: "filesystem boundary"}
+	if ctx.cfg.OneFileSystem && ctx.hasRootDevice {
+		if dev, ok := deviceID(info); ok && dev != ctx.rootDevice {
+			device.Excluded = true
+			device.Detail = "on another filesystem than " + ctx.cfg.Directory
 		}
+	}
+
+	submodule := Verdict{Rule: "git submodule"}
+	if ctx.cfg.SkipGitSubmodules && isGitSubmodule(dirPath) {
+		submodule.Excluded = true
+		submodule.Detail = "is a submodule checkout"
+	}
 
-		isBinary := s.binaryDetector.IsBinary(fullPath)
+	return []Verdict{device, submodule}
+}
 
-		*ctx.files = append(*ctx.files, FileInfo{
-			Path:     fullPath,
-			RelPath:  relPath,
-			IsBinary: isBinary,
-		})
+// linkVerdict checks dirPath against FollowSymlinks: a symlinked directory
+// or junction is not entered without it.
+func linkVerdict(dirPath string, cfg *Config) Verdict {
+	verdict := Verdict{Rule: "symlink"}
+	info, err := os.Lstat(longpath.Fix(dirPath))
+	if err == nil && isLink(dirPath, info.Mode()) && !cfg.FollowSymlinks {
+		verdict.Excluded = true
+		verdict.Detail = "is a symlinked directory and --follow-symlinks is not set"
 	}
+
+	return verdict
+}
+
+// isGitSubmodule reports whether dirPath is a submodule checkout. Submodules
+// have a .git file pointing at the parent's module store instead of a .git directory.
+func isGitSubmodule(dirPath string) bool {
+	info, err := os.Lstat(longpath.Fix(filepath.Join(dirPath, ".git")))
+
+	return err == nil && info.Mode().IsRegular()
 }
 
-// getRelativePath returns the relative path from base directory.
+// getRelativePath returns the relative path from base directory. Paths with
+// a NUL byte, which no filesystem allows in a name, are rejected, since
+// formats and tools reading the output may treat NUL as the end of the path.
 func (*Scanner) getRelativePath(fullPath string, cfg *Config) (string, error) {
-	baseDir := cfg.Directory
+	return newPathBase(cfg).rel(fullPath)
+}
 
-	// If RelativeTo is set, use it instead of the scan directory
-	if cfg.RelativeTo != "" {
-		baseDir = cfg.RelativeTo
+// pathBase computes the paths Scan reports. With RelativeTo, the base and the
+// scan directory are compared with symlinks resolved, so reaching a project
+// through a link does not turn its paths into "../" chains out of the base.
+type pathBase struct {
+	dir  string // Scan directory as configured
+	base string // Base directory as configured
+
+	relativeTo   bool   // Paths are relative to RelativeTo rather than dir
+	resolvedDir  string // dir, absolute and with symlinks resolved
+	resolvedBase string // base, absolute and with symlinks resolved
+	warned       bool   // The escaping base warning has been printed
+}
+
+// newPathBase prepares the base for cfg, resolving symlinks once per scan.
+func newPathBase(cfg *Config) *pathBase {
+	b := &pathBase{dir: cfg.Directory, base: cfg.Directory}
+	switch cfg.RelativeTo {
+	case "":
+		return b
+	case ".":
+		// Paths are shown as they were found
+		b.base = "."
+
+		return b
+	}
+
+	b.base, b.relativeTo = cfg.RelativeTo, true
+	b.resolvedDir = resolvePath(cfg.Directory)
+	b.resolvedBase = resolvePath(cfg.RelativeTo)
+
+	return b
+}
+
+// newQuietPathBase is newPathBase for paths computed to make decisions rather
+// than to report, which leave the warning to the paths Scan reports.
+func newQuietPathBase(cfg *Config) *pathBase {
+	b := newPathBase(cfg)
+	b.warned = true
+
+	return b
+}
+
+// rel returns fullPath, a path under the scan directory, relative to the
+// base. A path that would still leave the base is reported as a cleaned
+// absolute path instead, with a warning the first time, so no output path
+// climbs out of the base for a consumer joining it onto one.
+func (b *pathBase) rel(fullPath string) (string, error) {
+	if strings.ContainsRune(fullPath, 0) {
+		return "", fmt.Errorf("path %q contains a NUL byte", fullPath)
 	}
 
-	if baseDir == "." {
+	if b.base == "." {
 		return fullPath, nil
 	}
+	if !b.relativeTo {
+		return filepath.Rel(b.base, fullPath)
+	}
+
+	var target string
+	if inner, err := filepath.Rel(b.dir, fullPath); err == nil && (inner == "." || filepath.IsLocal(inner)) {
+		// Entries below the scan directory keep the names they were found by
+		target = filepath.Join(b.resolvedDir, inner)
+	} else {
+		target = resolvePath(fullPath)
+	}
+
+	rel, err := filepath.Rel(b.resolvedBase, target)
+	if err == nil && (rel == "." || filepath.IsLocal(rel)) {
+		return rel, nil
+	}
+
+	if !b.warned {
+		b.warned = true
+		fmt.Fprintf(os.Stderr, "Warning: %s is not under --relative-to %s; showing absolute paths\n",
+			b.dir, b.base)
+	}
+
+	return target, nil
+}
+
+// resolvePath returns path made absolute and cleaned, with symlinks resolved
+// when it exists.
+func resolvePath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
 
-	return filepath.Rel(baseDir, fullPath)
+	return filepath.Clean(path)
 }
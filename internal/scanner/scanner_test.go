@@ -1,8 +1,15 @@
 package scanner
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestGetRelativePath(t *testing.T) {
@@ -159,3 +166,975 @@ func TestGetRelativePathWithTempDir(t *testing.T) {
 		}
 	})
 }
+
+func TestRelativeToSymlinkedRoots(t *testing.T) {
+	tmpDir := t.TempDir()
+	home := filepath.Join(tmpDir, "home", "me")
+	if err := os.MkdirAll(filepath.Join(home, "proj", "src"), 0o755); err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(home, "proj", "src", "main.go"), []byte("package main"), 0o644); err != nil {
+		t.Fatalf("failed to write main.go: %v", err)
+	}
+	other := filepath.Join(tmpDir, "other")
+	if err := os.Mkdir(other, 0o755); err != nil {
+		t.Fatalf("failed to create other: %v", err)
+	}
+	for link, target := range map[string]string{"projlink": "home/me/proj", "homelink": "home/me"} {
+		if err := os.Symlink(filepath.Join(tmpDir, target), filepath.Join(tmpDir, link)); err != nil {
+			t.Skipf("symlinks not supported: %v", err)
+		}
+	}
+
+	resolved, err := filepath.EvalSymlinks(filepath.Join(home, "proj", "src", "main.go"))
+	if err != nil {
+		t.Fatalf("failed to resolve main.go: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		directory  string
+		relativeTo string
+		want       string
+	}{
+		{name: "symlinked root, real ancestor", directory: "projlink", relativeTo: "home", want: "me/proj/src/main.go"},
+		{name: "real root, symlinked ancestor", directory: "home/me/proj", relativeTo: "homelink", want: "proj/src/main.go"},
+		{name: "symlinked root and ancestor", directory: "projlink/src", relativeTo: "homelink", want: "proj/src/main.go"},
+		{name: "base is the root through a link", directory: "home/me/proj", relativeTo: "projlink", want: "src/main.go"},
+		{name: "base below the root", directory: "projlink", relativeTo: "home/me/proj/src", want: "main.go"},
+		{name: "unrelated base", directory: "projlink", relativeTo: "other", want: resolved},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := New().Scan(context.Background(), &Config{
+				Directory:  filepath.Join(tmpDir, tt.directory),
+				RelativeTo: filepath.Join(tmpDir, tt.relativeTo),
+				Recursive:  true,
+			})
+			if err != nil {
+				t.Fatalf("Scan() unexpected error: %v", err)
+			}
+			if len(files) != 1 {
+				t.Fatalf("Scan() = %v, want main.go alone", files)
+			}
+
+			got := files[0].RelPath
+			if got != filepath.FromSlash(tt.want) {
+				t.Errorf("RelPath = %q, want %q", got, tt.want)
+			}
+			if strings.Contains(got, "..") {
+				t.Errorf("RelPath %q leaves the base", got)
+			}
+		})
+	}
+}
+
+func FuzzRelPath(f *testing.F) {
+	f.Add("/home/user/project", "src/main.go")
+	f.Add(".", "notes\nfile.go")
+	f.Add("project", "a\x00b")
+	f.Add("", "../outside.go")
+	f.Add("a/../b", "./c/../d.go")
+
+	s := New()
+	f.Fuzz(func(t *testing.T, base, name string) {
+		full := filepath.Join(base, name)
+		rel, err := s.getRelativePath(full, &Config{Directory: base})
+		if err != nil {
+			return
+		}
+
+		if strings.ContainsRune(rel, 0) {
+			t.Errorf("getRelativePath(%q) under %q = %q, which contains NUL", full, base, rel)
+		}
+		if !filepath.IsLocal(name) {
+			return
+		}
+		if !filepath.IsLocal(rel) && rel != "." {
+			t.Errorf("getRelativePath(%q) under %q = %q, which escapes the base", full, base, rel)
+		}
+		if back := filepath.Join(base, rel); back != full && base != "." {
+			t.Errorf("getRelativePath(%q) under %q = %q, which joins back to %q", full, base, rel, back)
+		}
+	})
+}
+
+func TestScanPrunesDirectoryGlobs(t *testing.T) {
+	tmpDir := t.TempDir()
+	project := filepath.Join(tmpDir, "proj")
+	for _, path := range []string{
+		"main.go",
+		"testdata.go",
+		"testdata/golden.txt",
+		"testdata/deep/input.txt",
+		"testdata_old/notes.txt",
+		"src/lib.go",
+		"src/testdata/fixture.txt",
+		"build/out.bin",
+	} {
+		fullPath := filepath.Join(project, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+			t.Fatalf("failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(path), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		globs      []string
+		relativeTo string
+		want       []string
+	}{
+		{
+			name:  "directory-shaped globs prune at any depth",
+			globs: []string{"testdata/*", "build/**", "*.go"},
+			want:  []string{"main.go", "src/lib.go", "testdata.go", "testdata_old/notes.txt"},
+		},
+		{
+			name:  "file globs leave directories alone",
+			globs: []string{"*.txt"},
+			want: []string{
+				"build/out.bin", "main.go", "src/lib.go", "src/testdata/fixture.txt", "testdata.go",
+				"testdata/deep/input.txt", "testdata/golden.txt", "testdata_old/notes.txt",
+			},
+		},
+		{
+			name:  "negation forces descent only where it reaches",
+			globs: []string{"testdata/*", "build/**", "!testdata/golden.txt"},
+			want:  []string{"main.go", "src/lib.go", "testdata.go", "testdata/golden.txt", "testdata_old/notes.txt"},
+		},
+		{
+			name:  "negated name reaches every directory",
+			globs: []string{"testdata/*", "build/**", "!*.bin"},
+			want: []string{
+				"build/out.bin", "main.go", "src/lib.go", "src/testdata/fixture.txt", "testdata.go",
+				"testdata/deep/input.txt", "testdata/golden.txt", "testdata_old/notes.txt",
+			},
+		},
+		{
+			name:       "paths relative to an ancestor",
+			globs:      []string{"proj/src/*"},
+			relativeTo: tmpDir,
+			want: []string{
+				"proj/build/out.bin", "proj/main.go", "proj/testdata.go", "proj/testdata/deep/input.txt",
+				"proj/testdata/golden.txt", "proj/testdata_old/notes.txt",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := New().Scan(context.Background(), &Config{
+				Directory:   project,
+				Recursive:   true,
+				IgnoreGlobs: tt.globs,
+				RelativeTo:  tt.relativeTo,
+			})
+			if err != nil {
+				t.Fatalf("Scan() unexpected error: %v", err)
+			}
+
+			// Scan leaves file globs to its caller; only pruned directories are missing
+			var got []string
+			for _, file := range files {
+				got = append(got, filepath.ToSlash(file.RelPath))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Scan() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanSkipGitSubmodules(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	files := map[string]string{
+		"main.go":           "package main",
+		"vendored/.git":     "gitdir: ../.git/modules/vendored",
+		"vendored/lib.go":   "package lib",
+		"regular/.git/HEAD": "ref: refs/heads/main",
+		"regular/app.go":    "package app",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(tmpDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+			t.Fatalf("failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+
+	scan := func(skip bool, dir string) map[string]bool {
+		t.Helper()
+
+		got, err := New().Scan(context.Background(), &Config{
+			Directory:         dir,
+			Recursive:         true,
+			SkipGitSubmodules: skip,
+		})
+		if err != nil {
+			t.Fatalf("Scan() unexpected error: %v", err)
+		}
+
+		seen := make(map[string]bool, len(got))
+		for _, f := range got {
+			seen[filepath.ToSlash(f.RelPath)] = true
+		}
+
+		return seen
+	}
+
+	if seen := scan(false, tmpDir); !seen["vendored/lib.go"] {
+		t.Errorf("submodule file should be scanned by default, got %v", seen)
+	}
+
+	seen := scan(true, tmpDir)
+	if seen["vendored/lib.go"] {
+		t.Errorf("submodule file should be skipped, got %v", seen)
+	}
+	if !seen["regular/app.go"] || !seen["main.go"] {
+		t.Errorf("non-submodule files should be kept, got %v", seen)
+	}
+
+	// An explicitly requested submodule root is still scanned.
+	if seen := scan(true, filepath.Join(tmpDir, "vendored")); !seen["lib.go"] {
+		t.Errorf("explicit submodule root should be scanned, got %v", seen)
+	}
+}
+
+func TestScanFollowSymlinks(t *testing.T) {
+	testScanDirectoryLinks(t, func(target, link string) error {
+		return os.Symlink(target, link)
+	})
+}
+
+// testScanDirectoryLinks builds a tree whose directory links, made by link,
+// lead outside it, to a scanned directory from names sorting before and
+// after it, and back to the root, and checks that they are skipped by
+// default and that FollowSymlinks follows only the one leading outside.
+func testScanDirectoryLinks(t *testing.T, link func(target, link string) error) {
+	t.Helper()
+
+	tmpDir := t.TempDir()
+	root := filepath.Join(tmpDir, "root")
+	outside := filepath.Join(tmpDir, "outside")
+	for path, content := range map[string]string{
+		filepath.Join(root, "src", "a.go"): "package a",
+		filepath.Join(outside, "b.go"):     "package b",
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+	for name, target := range map[string]string{
+		"ext":      outside,
+		"lib":      filepath.Join(root, "src"),
+		"zlib":     filepath.Join(root, "src"),
+		"src/loop": root,
+	} {
+		if err := link(target, filepath.Join(root, filepath.FromSlash(name))); err != nil {
+			t.Skipf("directory links not supported: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		follow bool
+		want   []string
+	}{
+		{name: "default", want: []string{"src/a.go"}},
+		{name: "follow", follow: true, want: []string{"ext/b.go", "src/a.go"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := New().Scan(context.Background(), &Config{
+				Directory:      root,
+				Recursive:      true,
+				FollowSymlinks: tt.follow,
+				MaxDepth:       16, // Ends a missed cycle quickly
+			})
+			if err != nil {
+				t.Fatalf("Scan() unexpected error: %v", err)
+			}
+
+			got := make([]string, 0, len(files))
+			for _, f := range files {
+				got = append(got, filepath.ToSlash(f.RelPath))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Scan() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	_, verdicts, err := New().Explain(&Config{Directory: root, Recursive: true}, filepath.Join(root, "ext", "b.go"))
+	if err != nil {
+		t.Fatalf("Explain() unexpected error: %v", err)
+	}
+	if verdict, excluded := FirstExclusion(verdicts); !excluded || verdict.Rule != "symlink" {
+		t.Errorf("Explain() first exclusion = %v, want the symlink rule", verdict)
+	}
+}
+
+// countingDetector records how many files were sniffed.
+type countingDetector struct {
+	calls int
+}
+
+func (d *countingDetector) IsBinary(string) bool {
+	d.calls++
+
+	return false
+}
+
+func TestDetectionCache(t *testing.T) {
+	tmpDir := t.TempDir()
+	cachePath := filepath.Join(tmpDir, "cache", "detect.json")
+	srcDir := filepath.Join(tmpDir, "src")
+	if err := os.MkdirAll(srcDir, 0o755); err != nil {
+		t.Fatalf("failed to create src: %v", err)
+	}
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(srcDir, name), []byte("data"), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	scan := func() int {
+		t.Helper()
+
+		detector := &countingDetector{}
+		s := &Scanner{binaryDetector: detector}
+		cache := LoadDetectionCache(cachePath)
+		if _, err := s.Scan(context.Background(), &Config{Directory: srcDir, DetectCache: cache}); err != nil {
+			t.Fatalf("Scan() unexpected error: %v", err)
+		}
+		if err := cache.Save(); err != nil {
+			t.Fatalf("Save() unexpected error: %v", err)
+		}
+
+		return detector.calls
+	}
+
+	if got := scan(); got != 2 {
+		t.Errorf("cold scan sniffed %d files, want 2", got)
+	}
+	if got := scan(); got != 0 {
+		t.Errorf("warm scan sniffed %d files, want 0", got)
+	}
+
+	// Changing a file's size invalidates only that entry.
+	if err := os.WriteFile(filepath.Join(srcDir, "a.txt"), []byte("more data"), 0o644); err != nil {
+		t.Fatalf("failed to rewrite a.txt: %v", err)
+	}
+	if got := scan(); got != 1 {
+		t.Errorf("scan after change sniffed %d files, want 1", got)
+	}
+}
+
+func TestDetectionCacheCapped(t *testing.T) {
+	cache := NewMemoryDetectionCache()
+	modTime := time.Now()
+	for i := range MaxDetectionEntries + 10 {
+		cache.Store(fmt.Sprintf("/src/%d.txt", i), 1, modTime, func(entry *DetectionEntry) { entry.HasBinary = true })
+	}
+	if got := len(cache.entries); got != MaxDetectionEntries {
+		t.Errorf("cache holds %d entries, want the cap of %d", got, MaxDetectionEntries)
+	}
+
+	// The newest entry is never the one evicted
+	last := fmt.Sprintf("/src/%d.txt", MaxDetectionEntries+9)
+	if _, ok := cache.Lookup(last, 1, modTime); !ok {
+		t.Errorf("Lookup(%s) missed the entry stored last", last)
+	}
+}
+
+func BenchmarkScanWarmDetectionCache(b *testing.B) {
+	const fileCount = 20000
+	const perDir = 500
+
+	root := b.TempDir()
+	for i := range fileCount {
+		dir := filepath.Join(root, fmt.Sprintf("d%02d", i/perDir))
+		if i%perDir == 0 {
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				b.Fatalf("failed to create %s: %v", dir, err)
+			}
+		}
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("f%05d.txt", i)), []byte("x"), 0o644); err != nil {
+			b.Fatalf("failed to write file %d: %v", i, err)
+		}
+	}
+
+	cachePath := filepath.Join(b.TempDir(), "detect.json")
+	cfg := &Config{Directory: root, Recursive: true}
+
+	// Prime the cache with a cold scan.
+	cfg.DetectCache = LoadDetectionCache(cachePath)
+	if _, err := (&Scanner{binaryDetector: &countingDetector{}}).Scan(context.Background(), cfg); err != nil {
+		b.Fatalf("priming scan failed: %v", err)
+	}
+	if err := cfg.DetectCache.Save(); err != nil {
+		b.Fatalf("failed to save cache: %v", err)
+	}
+
+	b.ResetTimer()
+	var sniffed int
+	for b.Loop() {
+		detector := &countingDetector{}
+		cfg.DetectCache = LoadDetectionCache(cachePath)
+		if _, err := (&Scanner{binaryDetector: detector}).Scan(context.Background(), cfg); err != nil {
+			b.Fatalf("Scan() failed: %v", err)
+		}
+		sniffed += detector.calls
+	}
+
+	b.ReportMetric(float64(sniffed)/float64(b.N), "sniffs/op")
+}
+
+func TestScanPredicates(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, path := range []string{"keep.go", "drop.txt", ".hidden.go", "sub/inner.go", "skip/deep.go"} {
+		fullPath := filepath.Join(tmpDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+			t.Fatalf("failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte("x"), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+
+	cfg := &Config{Directory: tmpDir, RelativeTo: tmpDir, Recursive: true}
+	s := &Scanner{binaryDetector: &countingDetector{}}
+
+	relPaths := func(files []FileInfo) []string {
+		paths := make([]string, 0, len(files))
+		for _, f := range files {
+			paths = append(paths, filepath.ToSlash(f.RelPath))
+		}
+
+		return paths
+	}
+
+	tests := []struct {
+		name string
+		opts []Option
+		want []string
+	}{
+		{
+			name: "defaults skip hidden files",
+			want: []string{"drop.txt", "keep.go", "skip/deep.go", "sub/inner.go"},
+		},
+		{
+			name: "include predicate narrows files",
+			opts: []Option{WithInclude(func(f FileInfo) bool {
+				return DefaultShouldInclude(cfg)(f) && filepath.Ext(f.Path) == ".go"
+			})},
+			want: []string{"keep.go", "skip/deep.go", "sub/inner.go"},
+		},
+		{
+			name: "include predicate replaces hidden default",
+			opts: []Option{WithInclude(func(f FileInfo) bool {
+				return filepath.Base(f.Path) == ".hidden.go"
+			})},
+			want: []string{".hidden.go"},
+		},
+		{
+			name: "descend predicate prunes directories",
+			opts: []Option{WithDescend(func(dir string) bool {
+				return s.DefaultShouldDescend(cfg)(dir) && filepath.Base(dir) != "skip"
+			})},
+			want: []string{"drop.txt", "keep.go", "sub/inner.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := s.Scan(context.Background(), cfg, tt.opts...)
+			if err != nil {
+				t.Fatalf("Scan() unexpected error: %v", err)
+			}
+
+			got := relPaths(files)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Scan() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanIncludeDirs(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, dir := range []string{"empty", "src/lib", ".hidden", "vendor"} {
+		if err := os.MkdirAll(filepath.Join(tmpDir, dir), 0o755); err != nil {
+			t.Fatalf("failed to create %s: %v", dir, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "src/lib/util.go"), []byte("package lib"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	cfg := &Config{
+		Directory:   tmpDir,
+		RelativeTo:  tmpDir,
+		Recursive:   true,
+		IgnoreDir:   []string{"vendor"},
+		IncludeDirs: true,
+	}
+
+	// Directories are structural records and must not be run through file predicates
+	rejectAll := WithInclude(func(FileInfo) bool { return false })
+
+	files, err := New().Scan(context.Background(), cfg, rejectAll)
+	if err != nil {
+		t.Fatalf("Scan() unexpected error: %v", err)
+	}
+
+	var got []string
+	for _, f := range files {
+		if !f.IsDir {
+			t.Errorf("file %s passed a reject-all include predicate", f.RelPath)
+		}
+		got = append(got, filepath.ToSlash(f.RelPath))
+	}
+
+	want := []string{"empty", "src", "src/lib"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Scan() directories = %v, want %v", got, want)
+	}
+}
+
+// writeFlatDir creates count one-byte files directly in dir.
+func writeFlatDir(tb testing.TB, dir string, count int) {
+	tb.Helper()
+
+	for i := range count {
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("f%06d.txt", i)), []byte("x"), 0o644); err != nil {
+			tb.Fatalf("failed to write file %d: %v", i, err)
+		}
+	}
+}
+
+func TestScanLargeDirectory(t *testing.T) {
+	tmpDir := t.TempDir()
+	count := sortedDirLimit + dirBatchSize + 7
+	writeFlatDir(t, tmpDir, count)
+	if err := os.Mkdir(filepath.Join(tmpDir, "node_modules"), 0o755); err != nil {
+		t.Fatalf("failed to create node_modules: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "node_modules", "pkg.js"), []byte("x"), 0o644); err != nil {
+		t.Fatalf("failed to write pkg.js: %v", err)
+	}
+
+	s := &Scanner{binaryDetector: &countingDetector{}}
+	cfg := &Config{Directory: tmpDir, RelativeTo: tmpDir, Recursive: true, IgnoreDir: []string{"node_modules"}}
+
+	files, err := s.Scan(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Scan() unexpected error: %v", err)
+	}
+
+	if len(files) != count {
+		t.Fatalf("Scan() returned %d files, want %d", len(files), count)
+	}
+	for i, f := range files {
+		if want := fmt.Sprintf("f%06d.txt", i); f.RelPath != want {
+			t.Fatalf("file %d = %s, want %s (streamed results must still be sorted)", i, f.RelPath, want)
+		}
+	}
+
+	t.Run("max files", func(t *testing.T) {
+		limited := *cfg
+		limited.MaxFiles = 100
+
+		_, err := s.Scan(context.Background(), &limited)
+		if !errors.Is(err, ErrTooManyFiles) {
+			t.Errorf("Scan() error = %v, want ErrTooManyFiles", err)
+		}
+
+		limited.MaxFiles = count
+		if _, err := s.Scan(context.Background(), &limited); err != nil {
+			t.Errorf("Scan() at exactly MaxFiles unexpected error: %v", err)
+		}
+	})
+}
+
+func TestScanDeepTree(t *testing.T) {
+	const levels = 100
+
+	root := t.TempDir()
+	dir := root
+	var want []string
+	for level := 1; level <= levels; level++ {
+		dir = filepath.Join(dir, fmt.Sprintf("level-%03d", level))
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			t.Fatalf("failed to create level %d: %v", level, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "file.txt"), []byte("x"), 0o644); err != nil {
+			t.Fatalf("failed to write file at level %d: %v", level, err)
+		}
+		rel, _ := filepath.Rel(root, filepath.Join(dir, "file.txt"))
+		want = append(want, rel)
+	}
+	// A symlink at the bottom is the one entry that still needs a stat
+	if err := os.Symlink("file.txt", filepath.Join(dir, "link.txt")); err != nil {
+		t.Fatalf("failed to create link: %v", err)
+	}
+	want = append(want, filepath.Join(filepath.Dir(want[levels-1]), "link.txt"))
+	slices.Sort(want)
+
+	s := &Scanner{binaryDetector: &countingDetector{}}
+	cfg := &Config{Directory: root, Recursive: true}
+	files, err := s.Scan(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Scan() unexpected error: %v", err)
+	}
+	var got []string
+	for _, f := range files {
+		got = append(got, f.RelPath)
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("Scan() found %d files, want all %d with their full relative paths:\n%v", len(got), len(want), got)
+	}
+
+	t.Run("max depth", func(t *testing.T) {
+		capped := *cfg
+		capped.MaxDepth = 10
+
+		files, err := s.Scan(context.Background(), &capped)
+		if err != nil {
+			t.Fatalf("Scan() unexpected error: %v", err)
+		}
+		if len(files) != 10 || files[9].RelPath != want[9] {
+			t.Errorf("Scan() with MaxDepth 10 found %d files, want the 10 of levels 1 to 10", len(files))
+		}
+
+		_, verdicts, err := s.Explain(&capped, filepath.Join(root, want[10]))
+		if err != nil {
+			t.Fatalf("Explain() unexpected error: %v", err)
+		}
+		if v, ok := FirstExclusion(verdicts); !ok || v.Detail != "11 levels deep, below --max-depth 10" {
+			t.Errorf("Explain() decision = %v, want the depth limit", v)
+		}
+	})
+}
+
+// writeImageTree writes images PNG headers and sources Go files across
+// a few directories and returns how many Go files there are.
+func writeImageTree(tb testing.TB, root string, images, sources int) int {
+	tb.Helper()
+
+	for i := range images + sources {
+		dir := filepath.Join(root, fmt.Sprintf("d%d", i%8))
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			tb.Fatalf("failed to create %s: %v", dir, err)
+		}
+		name, content := fmt.Sprintf("img%05d.png", i), "\x89PNG\r\n\x1a\n\x00"
+		if i < sources {
+			name, content = fmt.Sprintf("src%05d.go", i), "package d\n"
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			tb.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	return sources
+}
+
+func TestScanGlobsSkipDetection(t *testing.T) {
+	root := t.TempDir()
+	sources := writeImageTree(t, root, 200, 5)
+
+	detector := &countingDetector{}
+	s := &Scanner{binaryDetector: detector}
+	typed := 0
+	files, err := s.Scan(context.Background(), &Config{Directory: root, Recursive: true, Globs: []string{"*.go"}},
+		WithTypeDetector(func(FileInfo) string {
+			typed++
+
+			return "go"
+		}))
+	if err != nil {
+		t.Fatalf("Scan() unexpected error: %v", err)
+	}
+	if len(files) != sources || detector.calls != sources || typed != sources {
+		t.Errorf("Scan() returned %d files after %d binary and %d type detections, want %d of each",
+			len(files), detector.calls, typed, sources)
+	}
+
+	t.Run("prefilter", func(t *testing.T) {
+		detector.calls = 0
+		seen := 0
+		_, err := s.Scan(context.Background(), &Config{Directory: root, Recursive: true}, WithPrefilter(func(file FileInfo) bool {
+			seen++
+			if file.IsBinary || file.HasType {
+				t.Errorf("prefilter saw %s after detection", file.RelPath)
+			}
+
+			return strings.HasSuffix(file.RelPath, ".go")
+		}))
+		if err != nil {
+			t.Fatalf("Scan() unexpected error: %v", err)
+		}
+		if seen != 200+sources || detector.calls != sources {
+			t.Errorf("prefilter saw %d files and %d were detected, want %d and %d", seen, detector.calls, 200+sources, sources)
+		}
+	})
+}
+
+func BenchmarkScanNarrowGlob(b *testing.B) {
+	root := b.TempDir()
+	sources := writeImageTree(b, root, 20000, 100)
+
+	detector := &countingDetector{}
+	s := &Scanner{binaryDetector: detector}
+	cfg := &Config{Directory: root, Recursive: true, Globs: []string{"*.go"}}
+
+	b.ResetTimer()
+	for b.Loop() {
+		detector.calls = 0
+		files, err := s.Scan(context.Background(), cfg)
+		if err != nil {
+			b.Fatalf("Scan() failed: %v", err)
+		}
+		if len(files) != sources || detector.calls != sources {
+			b.Fatalf("Scan() detected %d files for %d matches, want %d of each", detector.calls, len(files), sources)
+		}
+	}
+}
+
+func BenchmarkScanLargeDirectory(b *testing.B) {
+	const fileCount = 200000
+
+	root := b.TempDir()
+	writeFlatDir(b, root, fileCount)
+
+	s := &Scanner{binaryDetector: &countingDetector{}}
+	cfg := &Config{Directory: root}
+
+	b.ResetTimer()
+	for b.Loop() {
+		files, err := s.Scan(context.Background(), cfg)
+		if err != nil {
+			b.Fatalf("Scan() failed: %v", err)
+		}
+		if len(files) != fileCount {
+			b.Fatalf("Scan() returned %d files, want %d", len(files), fileCount)
+		}
+	}
+}
+
+func TestCaseCollisions(t *testing.T) {
+	files := []FileInfo{
+		{RelPath: "README.md"},
+		{RelPath: "Readme.md"},
+		{RelPath: "docs/Guide.md"},
+		{RelPath: "docs/guide.md"},
+		{RelPath: "DOCS/guide.md"},
+		{RelPath: "main.go"},
+		{RelPath: "Main.go", IsDir: true},
+		{RelPath: "Straße.txt"},
+	}
+
+	got := fmt.Sprint(CaseCollisions(files))
+	want := "[[DOCS/guide.md docs/Guide.md docs/guide.md] [README.md Readme.md]]"
+	if got != want {
+		t.Errorf("CaseCollisions() = %s, want %s", got, want)
+	}
+
+	if groups := CaseCollisions(files[5:]); groups != nil {
+		t.Errorf("CaseCollisions() = %v, want none", groups)
+	}
+}
+
+func TestSniffImage(t *testing.T) {
+	tests := []struct {
+		name string
+		head string
+		want string
+	}{
+		{name: "png", head: "\x89PNG\r\n\x1a\n\x00\x00", want: "image/png"},
+		{name: "jpeg", head: "\xFF\xD8\xFF\xE0", want: "image/jpeg"},
+		{name: "gif", head: "GIF89a\x01\x00", want: "image/gif"},
+		{name: "webp", head: "RIFF\x24\x00\x00\x00WEBPVP8 ", want: "image/webp"},
+		{name: "riff that is not webp", head: "RIFF\x24\x00\x00\x00WAVEfmt "},
+		{name: "svg", head: "<svg xmlns=\"http://www.w3.org/2000/svg\"/>", want: MIMETypeSVG},
+		{
+			name: "svg after prolog",
+			head: "\xEF\xBB\xBF<?xml version=\"1.0\"?>\n<!-- icon -->\n<!DOCTYPE svg>\n<svg>",
+			want: MIMETypeSVG,
+		},
+		{name: "html with inline svg", head: "<html><body><svg></svg></body></html>"},
+		{name: "text", head: "package main"},
+		{name: "empty", head: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SniffImage([]byte(tt.head)); got != tt.want {
+				t.Errorf("SniffImage(%q) = %q, want %q", tt.head, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSniffEncoding(t *testing.T) {
+	tests := []struct {
+		name     string
+		sample   string
+		want     Encoding
+		wantText string // Decoded sample; empty when it must not decode cleanly
+	}{
+		{name: "utf-8 with bom", sample: "\xEF\xBB\xBFhi", want: EncodingUTF8, wantText: "hi"},
+		{name: "utf-16le with bom", sample: "\xFF\xFEh\x00i\x00", want: EncodingUTF16LE, wantText: "hi"},
+		{name: "utf-16be with bom", sample: "\xFE\xFF\x00h\x00i", want: EncodingUTF16BE, wantText: "hi"},
+		{name: "utf-32le with bom", sample: "\xFF\xFE\x00\x00h\x00\x00\x00", want: EncodingUTF32LE, wantText: "h"},
+		{name: "utf-32be with bom", sample: "\x00\x00\xFE\xFF\x00\x00\x00h", want: EncodingUTF32BE, wantText: "h"},
+		{name: "utf-16le without bom", sample: "h\x00i\x00\r\x00\n\x00", want: EncodingUTF16LE, wantText: "hi\r\n"},
+		{name: "surrogate pair cut off", sample: "\xFF\xFEh\x00\x3D\xD8", want: EncodingUTF16LE, wantText: "h"},
+		{name: "plain text", sample: "package main"},
+		{name: "binary with zero pairs", sample: "\x7FELF\x02\x01\x01\x00\x00\x00"},
+		{name: "binary resembling utf-16", sample: "\x00\x01\x00\x02", want: EncodingUTF16BE},
+		{name: "empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoding, bomLen := SniffEncoding([]byte(tt.sample))
+			if encoding != tt.want {
+				t.Fatalf("SniffEncoding(%q) = %q, want %q", tt.sample, encoding, tt.want)
+			}
+			if encoding == EncodingNone {
+				return
+			}
+
+			text, ok := DecodeText([]byte(tt.sample), encoding, bomLen)
+			if ok != (tt.wantText != "") || text != tt.wantText {
+				t.Errorf("DecodeText(%q) = %q, %v, want %q", tt.sample, text, ok, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestBinaryDetectorEncodedText(t *testing.T) {
+	detector := &FileBinaryDetector{}
+	for _, name := range []string{"utf16le_bom.ps1", "utf16le.ps1"} {
+		path := filepath.Join("testdata", name)
+		if !detector.isBinaryByBytes(path) {
+			t.Fatalf("%s has no zero bytes; the fixture no longer tests anything", name)
+		}
+		if detector.IsBinary(path) {
+			t.Errorf("IsBinary(%s) = true, want false", name)
+		}
+	}
+
+	binary := filepath.Join(t.TempDir(), "data.bin")
+	if err := os.WriteFile(binary, []byte("\x00\x01\x00\x02\x00\x03"), 0o644); err != nil {
+		t.Fatalf("failed to write %s: %v", binary, err)
+	}
+	if !detector.IsBinary(binary) {
+		t.Error("IsBinary() = false for binary data that resembles UTF-16")
+	}
+}
+
+func TestExpandGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+		wantErr string
+	}{
+		{pattern: "*.go", want: []string{"*.go"}},
+		{pattern: "./src/*.go", want: []string{"src/*.go"}},
+		{pattern: "*.{go,md}", want: []string{"*.go", "*.md"}},
+		{pattern: "{cmd,internal}/*.{go,mod}", want: []string{"cmd/*.go", "cmd/*.mod", "internal/*.go", "internal/*.mod"}},
+		{pattern: "*.{go,{ts,tsx}}", want: []string{"*.go", "*.ts", "*.tsx"}},
+		{pattern: "file{,.bak}", want: []string{"file", "file.bak"}},
+		{pattern: "", wantErr: "empty pattern"},
+		{pattern: "*.{go,md", wantErr: "unclosed '{'"},
+		{pattern: "*.go}", wantErr: "unexpected '}'"},
+		{pattern: "{a,b}}", wantErr: "unexpected '}'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			got, err := ExpandGlob(tt.pattern)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ExpandGlob(%q) error = %v, want %q", tt.pattern, err, tt.wantErr)
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExpandGlob(%q) unexpected error: %v", tt.pattern, err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ExpandGlob(%q) = %q, want %q", tt.pattern, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExplain(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, path := range []string{"top.go", "a/.b/c/deep.go", "a/vendor/lib.go", "a/inner.go"} {
+		fullPath := filepath.Join(tmpDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+			t.Fatalf("failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte("x"), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+
+	tests := []struct {
+		path       string
+		recursive  bool
+		wantRule   string
+		wantDetail string
+	}{
+		{path: "top.go"},
+		{path: "a/inner.go", recursive: true},
+		{path: "a/inner.go", wantRule: "recursion"},
+		{path: "a/.b/c/deep.go", recursive: true, wantRule: "hidden directory", wantDetail: filepath.Join("a", ".b") + " starts with a dot"},
+		{path: "a/vendor/lib.go", recursive: true, wantRule: "ignore-dir", wantDetail: filepath.Join("a", "vendor") + ` matches "vendor"`},
+	}
+
+	s := New()
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			cfg := &Config{Directory: tmpDir, RelativeTo: tmpDir, Recursive: tt.recursive, IgnoreDir: []string{"vendor"}}
+			file, verdicts, err := s.Explain(cfg, filepath.Join(tmpDir, tt.path))
+			if err != nil {
+				t.Fatalf("Explain() unexpected error: %v", err)
+			}
+			if file.RelPath != filepath.FromSlash(tt.path) {
+				t.Errorf("RelPath = %q, want %q", file.RelPath, tt.path)
+			}
+
+			verdict, _ := FirstExclusion(verdicts)
+			if verdict.Rule != tt.wantRule || (tt.wantDetail != "" && verdict.Detail != tt.wantDetail) {
+				t.Errorf("deciding verdict = %+v, want rule %q with detail %q", verdict, tt.wantRule, tt.wantDetail)
+			}
+
+			// Scan agrees with the explanation
+			files, err := s.Scan(context.Background(), cfg)
+			if err != nil {
+				t.Fatalf("Scan() unexpected error: %v", err)
+			}
+			found := slices.ContainsFunc(files, func(f FileInfo) bool { return f.RelPath == file.RelPath })
+			if found != (tt.wantRule == "") {
+				t.Errorf("Scan() found %s = %v, want %v", tt.path, found, tt.wantRule == "")
+			}
+		})
+	}
+}
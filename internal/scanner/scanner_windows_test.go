@@ -0,0 +1,61 @@
+//go:build windows
+
+package scanner
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode/utf16"
+
+	"golang.org/x/sys/windows"
+)
+
+func TestScanFollowJunctions(t *testing.T) {
+	testScanDirectoryLinks(t, createJunction)
+}
+
+// createJunction makes link an NTFS junction (mount point) to the directory
+// target. Unlike symlinks, junctions need no privilege to create.
+func createJunction(target, link string) error {
+	target, err := filepath.Abs(target)
+	if err != nil {
+		return err
+	}
+	if err := os.Mkdir(link, 0o755); err != nil {
+		return err
+	}
+	name, err := windows.UTF16PtrFromString(link)
+	if err != nil {
+		return err
+	}
+	handle, err := windows.CreateFile(name, windows.GENERIC_WRITE, 0, nil, windows.OPEN_EXISTING,
+		windows.FILE_FLAG_BACKUP_SEMANTICS|windows.FILE_FLAG_OPEN_REPARSE_POINT, 0)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(handle)
+
+	// REPARSE_DATA_BUFFER with a MountPointReparseBuffer: the NT path the
+	// junction resolves to, then the path shown for it, each NUL-terminated
+	substitute := utf16.Encode([]rune(`\??\` + target))
+	display := utf16.Encode([]rune(target))
+	names := make([]uint16, 0, len(substitute)+len(display)+2)
+	names = append(append(append(append(names, substitute...), 0), display...), 0)
+
+	buf := binary.LittleEndian.AppendUint32(nil, windows.IO_REPARSE_TAG_MOUNT_POINT)
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(8+2*len(names))) // ReparseDataLength
+	buf = binary.LittleEndian.AppendUint16(buf, 0)                      // Reserved
+	buf = binary.LittleEndian.AppendUint16(buf, 0)                      // SubstituteNameOffset
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(2*len(substitute)))
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(2*len(substitute)+2)) // PrintNameOffset
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(2*len(display)))
+	for _, c := range names {
+		buf = binary.LittleEndian.AppendUint16(buf, c)
+	}
+
+	var returned uint32
+
+	return windows.DeviceIoControl(handle, windows.FSCTL_SET_REPARSE_POINT, &buf[0], uint32(len(buf)), nil, 0, &returned, nil)
+}
@@ -0,0 +1,72 @@
+// Minimal syntax highlighting for the file view: comments, strings, numbers,
+// and common keywords. Each line is highlighted on its own, so constructs that
+// span lines, such as block comments, are only colored on their first line.
+(function () {
+  "use strict";
+
+  const keywords = new Set([
+    "break", "case", "catch", "class", "const", "continue", "def", "default",
+    "defer", "do", "else", "elif", "enum", "export", "extends", "false", "fi",
+    "finally", "fn", "for", "func", "function", "go", "if", "impl", "import",
+    "in", "interface", "let", "match", "mut", "new", "nil", "None", "null",
+    "package", "pub", "return", "select", "self", "static", "struct", "switch",
+    "then", "this", "throw", "true", "True", "False", "try", "type", "use",
+    "var", "while", "with", "yield",
+  ]);
+
+  const hashComments = new Set([
+    "bash", "python", "ruby", "perl", "yaml", "toml", "nix", "makefile",
+    "dockerfile",
+  ]);
+
+  function tokenPattern(lang) {
+    const comment = hashComments.has(lang) ? "#.*" : "\\/\\/.*|\\/\\*.*?(?:\\*\\/|$)|--.*";
+    return new RegExp(
+      "(" + comment + ")" +
+        "|(\"(?:[^\"\\\\]|\\\\.)*\"?|'(?:[^'\\\\]|\\\\.)*'?|`[^`]*`?)" +
+        "|\\b(\\d[\\d_.xXa-fA-F]*)\\b" +
+        "|([A-Za-z_][A-Za-z0-9_]*)",
+      "g",
+    );
+  }
+
+  function span(cls, text) {
+    const el = document.createElement("span");
+    el.className = cls;
+    el.textContent = text;
+    return el;
+  }
+
+  function highlightLine(line, pattern) {
+    const text = line.textContent;
+    const out = document.createDocumentFragment();
+    let last = 0;
+    pattern.lastIndex = 0;
+
+    for (let m; (m = pattern.exec(text)) !== null; ) {
+      const [match, comment, str, num, word] = m;
+      let cls = "";
+      if (comment) cls = "tok-comment";
+      else if (str) cls = "tok-string";
+      else if (num) cls = "tok-number";
+      else if (word && keywords.has(word)) cls = "tok-keyword";
+      if (!cls) continue;
+
+      out.append(text.slice(last, m.index), span(cls, match));
+      last = m.index + match.length;
+    }
+
+    out.append(text.slice(last));
+    line.replaceChildren(out);
+  }
+
+  for (const code of document.querySelectorAll("code[data-lang]")) {
+    const lang = code.dataset.lang;
+    if (!lang || lang === "markdown") continue;
+
+    const pattern = tokenPattern(lang);
+    for (const line of code.querySelectorAll(".line")) {
+      highlightLine(line, pattern);
+    }
+  }
+})();
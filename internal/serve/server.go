@@ -0,0 +1,356 @@
+// Package serve renders catls scan results as a browsable HTML page over a
+// local HTTP server.
+package serve
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"maps"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/connerohnesorge/catls/internal/catls"
+	"github.com/connerohnesorge/catls/internal/contentcache"
+	"github.com/connerohnesorge/catls/internal/languages"
+	"github.com/connerohnesorge/catls/internal/scanner"
+)
+
+// shutdownTimeout bounds how long in-flight requests may run after the
+// context passed to Serve is cancelled.
+const shutdownTimeout = 5 * time.Second
+
+//go:embed assets
+var assets embed.FS
+
+// Server serves the files selected by a catls configuration. Every request
+// rescans with a fresh copy of the configuration, so the page always reflects
+// the tree on disk; only the detection and content caches, which are bounded
+// and checked against each file's size and modification time, are shared
+// between requests.
+type Server struct {
+	cfg   catls.Config
+	page  *template.Template
+	bound net.Addr // Address Serve listens on, which requests may name as Host
+}
+
+// New creates a server for cfg. Interactive selection and reordering need a
+// terminal, so they are rejected.
+func New(cfg *catls.Config) (*Server, error) {
+	if cfg.Interactive || cfg.Order {
+		return nil, errors.New("serve does not support --interactive or --order")
+	}
+
+	page, err := template.New("index.html.tmpl").Funcs(template.FuncMap{
+		"indent": func(depth int) template.CSS {
+			return template.CSS(fmt.Sprintf("padding-left: %.1fem", 0.5+float64(depth)))
+		},
+		"highlight": languages.HighlightName,
+		// Embedded images are data: URIs built from base64, which html/template
+		// would otherwise replace as unsafe
+		"urlSafe": func(uri string) template.URL { return template.URL(uri) }, //nolint:gosec // base64 data URI built by catls
+	}).ParseFS(assets, "assets/index.html.tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse page template: %w", err)
+	}
+
+	server := &Server{cfg: *cfg, page: page}
+	if server.cfg.DetectCache == nil && server.cfg.DetectCachePath != "" {
+		server.cfg.DetectCache = scanner.LoadDetectionCache(cfg.DetectCachePath)
+	} else if server.cfg.DetectCache == nil {
+		server.cfg.DetectCache = scanner.NewMemoryDetectionCache()
+	}
+	if server.cfg.ContentCache == nil {
+		server.cfg.ContentCache = contentcache.New(contentcache.DefaultMaxBytes)
+	}
+
+	return server, nil
+}
+
+// Handler returns the HTTP handler serving the page, downloads, and assets.
+// Requests naming a Host other than localhost or an address the server is
+// reachable at are refused, so a page on another site cannot read the tree
+// through a DNS name rebound to this machine.
+func (s *Server) Handler() http.Handler {
+	static, _ := fs.Sub(assets, "assets")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.handleIndex)
+	mux.HandleFunc("GET /download", s.handleDownload)
+	mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServerFS(static)))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.allowedHost(r.Host) {
+			http.Error(w, fmt.Sprintf("unexpected Host %q; open the address catls serve printed", r.Host), http.StatusMisdirectedRequest)
+
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// allowedHost reports whether host, a Host header with or without a port,
+// names localhost, a loopback address, or the address Serve listens on. A
+// server listening on every interface accepts any IP address, since only a
+// DNS name can be rebound.
+func (s *Server) allowedHost(host string) bool {
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		host = name
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	if ip.IsLoopback() {
+		return true
+	}
+	bound, ok := s.bound.(*net.TCPAddr)
+
+	return ok && (bound.IP.IsUnspecified() || bound.IP.Equal(ip))
+}
+
+// Serve handles requests on ln until ctx is cancelled, then shuts down,
+// giving in-flight requests a few seconds to finish.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	s.bound = ln.Addr()
+	srv := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	errs := make(chan error, 1)
+	go func() { errs <- srv.Serve(ln) }()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down server: %w", err)
+	}
+	if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// pageData is the model rendered by the index template.
+type pageData struct {
+	Root     string
+	Entries  []treeEntry
+	Selected *catls.ProcessedFile
+	Formats  []string
+	Links    *catls.CrossLinks  // Mentions of other files to link (nil without --cross-link)
+	Cache    contentcache.Stats // Content cache counters after the scan
+}
+
+// Line renders a content line of the selected file, linking the mentions of
+// other files found by Links to their pages.
+func (d pageData) Line(content string) template.HTML {
+	var b strings.Builder
+	last := 0
+	for _, match := range d.Links.Find(content, d.Selected.Info.RelPath) {
+		b.WriteString(template.HTMLEscapeString(content[last:match.Start]))
+		fmt.Fprintf(&b, `<a href="/?path=%s">%s</a>`,
+			url.QueryEscape(match.Path), template.HTMLEscapeString(content[match.Start:match.End]))
+		last = match.End
+	}
+	b.WriteString(template.HTMLEscapeString(content[last:]))
+
+	return template.HTML(b.String()) //nolint:gosec // content is escaped above
+}
+
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	cfg := s.config()
+	cfg.Output = io.Discard
+
+	app, err := catls.New(cfg)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	selectedPath := r.URL.Query().Get("path")
+	data := pageData{Root: cfg.Directory, Formats: catls.GetSupportedFormats()}
+
+	var files []catls.ProcessedFile
+	for file, err := range app.Files(r.Context()) {
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+		if file.Info.RelPath == selectedPath && !file.Info.IsDir {
+			data.Selected = &file
+		}
+		files = append(files, file)
+	}
+
+	data.Entries = buildTree(files)
+	data.Cache = cfg.ContentCache.Stats()
+	if cfg.CrossLink {
+		data.Links = catls.NewCrossLinks(files)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if selectedPath != "" && data.Selected == nil {
+		w.WriteHeader(http.StatusNotFound)
+	}
+	if err := s.page.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// handleDownload sends the formatter output for the format query parameter.
+// The output is built before anything is sent, so a failed run is an error
+// response rather than a truncated download.
+func (s *Server) handleDownload(w http.ResponseWriter, r *http.Request) {
+	format := catls.OutputFormat(r.URL.Query().Get("format"))
+	if !format.IsValid() {
+		http.Error(w, fmt.Sprintf("unsupported output format: %s", format), http.StatusBadRequest)
+
+		return
+	}
+
+	cfg := s.config()
+	if format != cfg.OutputFormat {
+		// Format-specific settings were chosen for the command line format
+		cfg.FormatOptions = nil
+		cfg.FenceStyle = ""
+		cfg.Sentinel = ""
+		cfg.EmbedImages = 0
+	}
+	var out bytes.Buffer
+	cfg.OutputFormat = format
+	cfg.Output = &out
+
+	app, err := catls.New(cfg)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+	if err := app.Run(r.Context()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "catls."+fileExtension(format)))
+	w.Header().Set("Content-Length", strconv.Itoa(out.Len()))
+	_, _ = out.WriteTo(w)
+}
+
+// config returns a copy of the configuration that a single request may
+// modify; App appends file arguments to Globs on every scan. Downloads are
+// files rather than terminal output, so they are never colored, and each
+// holds a single format written to the response.
+func (s *Server) config() *catls.Config {
+	cfg := s.cfg
+	cfg.Color = false
+	cfg.Theme = ""
+	cfg.OutputDir = ""
+	cfg.OutputFormats = nil
+	cfg.Globs = slices.Clone(s.cfg.Globs)
+	cfg.IgnoreDir = slices.Clone(s.cfg.IgnoreDir)
+	cfg.FormatOptions = maps.Clone(s.cfg.FormatOptions)
+
+	return &cfg
+}
+
+func contentType(format catls.OutputFormat) string {
+	switch format {
+	case catls.OutputFormatXML:
+		return "application/xml; charset=utf-8"
+	case catls.OutputFormatJSON:
+		return "application/json; charset=utf-8"
+	case catls.OutputFormatMarkdown:
+		return "text/markdown; charset=utf-8"
+	case catls.OutputFormatPrompt, catls.OutputFormatPretty:
+		return "text/plain; charset=utf-8"
+	}
+
+	return "text/plain; charset=utf-8"
+}
+
+func fileExtension(format catls.OutputFormat) string {
+	info, _ := catls.LookupFormat(format)
+
+	return info.FileExtension()
+}
+
+// treeEntry is one row of the sidebar: a directory heading or a file link.
+type treeEntry struct {
+	Name  string
+	Path  string
+	Depth int
+	IsDir bool
+}
+
+// buildTree turns the scan results into sidebar rows, emitting a heading for
+// each directory the first time a path below it appears. Files keep the order
+// the pipeline produced.
+func buildTree(files []catls.ProcessedFile) []treeEntry {
+	var entries []treeEntry
+	seen := make(map[string]bool)
+
+	addDirs := func(dir string) {
+		if dir == "." || dir == "" {
+			return
+		}
+
+		parts := strings.Split(dir, "/")
+		for i := range parts {
+			prefix := strings.Join(parts[:i+1], "/")
+			if seen[prefix] {
+				continue
+			}
+			seen[prefix] = true
+			entries = append(entries, treeEntry{Name: parts[i] + "/", Path: prefix, Depth: i, IsDir: true})
+		}
+	}
+
+	for _, file := range files {
+		rel := strings.ReplaceAll(file.Info.RelPath, "\\", "/")
+		if file.Info.IsDir {
+			addDirs(rel)
+
+			continue
+		}
+
+		dir := path.Dir(rel)
+		addDirs(dir)
+		depth := 0
+		if dir != "." {
+			depth = strings.Count(dir, "/") + 1
+		}
+		entries = append(entries, treeEntry{Name: path.Base(rel), Path: file.Info.RelPath, Depth: depth})
+	}
+
+	return entries
+}
@@ -0,0 +1,333 @@
+package serve
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/connerohnesorge/catls/internal/catls"
+	"github.com/connerohnesorge/catls/internal/contentcache"
+	"github.com/connerohnesorge/catls/internal/testutil"
+)
+
+func newTestServer(t *testing.T, cfg catls.Config) *httptest.Server {
+	t.Helper()
+
+	root := t.TempDir()
+	testutil.WriteFile(t, root, "main.go", "package main\n\n// See docs/guide.md.\nfunc main() { println(\"<hi>\") }\n")
+	testutil.WriteFile(t, root, "docs/guide.md", "# Guide\n")
+	testutil.WriteFile(t, root, "skip.log", "noise\n")
+
+	cfg.Directory = root
+	cfg.RelativeTo = root
+	cfg.Recursive = true
+	cfg.IgnoreGlobs = []string{"*.log"}
+	if cfg.OutputFormat == "" {
+		cfg.OutputFormat = catls.OutputFormatXML
+	}
+
+	server, err := New(&cfg)
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	ts := httptest.NewServer(server.Handler())
+	t.Cleanup(ts.Close)
+
+	return ts
+}
+
+func get(t *testing.T, url string) (*http.Response, string) {
+	t.Helper()
+
+	resp, err := http.Get(url) //nolint:noctx // test request against a local server
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read body of %s: %v", url, err)
+	}
+
+	return resp, string(body)
+}
+
+func TestIndex(t *testing.T) {
+	ts := newTestServer(t, catls.Config{})
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		want       []string
+		avoid      []string
+	}{
+		{
+			name:       "lists filtered files",
+			path:       "/",
+			wantStatus: http.StatusOK,
+			want:       []string{`<li class="dir"`, `>docs/</li>`, `href="/?path=docs%2fguide.md"`, `href="/?path=main.go"`, "Select a file"},
+			avoid:      []string{"skip.log"},
+		},
+		{
+			name:       "shows escaped content of the selected file",
+			path:       "/?path=main.go",
+			wantStatus: http.StatusOK,
+			want:       []string{`<code data-lang="go">`, "println(&#34;&lt;hi&gt;&#34;)", `class="file selected"`},
+		},
+		{
+			name:       "unknown file",
+			path:       "/?path=missing.go",
+			wantStatus: http.StatusNotFound,
+			want:       []string{"Select a file"},
+		},
+	}
+
+	// The page rescans on every request, so repeating one must not change it
+	for range 2 {
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				resp, body := get(t, ts.URL+tt.path)
+				if resp.StatusCode != tt.wantStatus {
+					t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+				}
+				for _, want := range tt.want {
+					if !strings.Contains(body, want) {
+						t.Errorf("page missing %q:\n%s", want, body)
+					}
+				}
+				for _, avoid := range tt.avoid {
+					if strings.Contains(body, avoid) {
+						t.Errorf("page should not contain %q", avoid)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestDownload(t *testing.T) {
+	// Markdown-only settings must not break downloads in other formats
+	ts := newTestServer(t, catls.Config{
+		OutputFormat:  catls.OutputFormatMarkdown,
+		FenceStyle:    catls.FenceStyleTilde,
+		FormatOptions: map[string]string{"heading-level": "3"},
+	})
+
+	resp, body := get(t, ts.URL+"/download?format=json")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", resp.StatusCode, body)
+	}
+	if got := resp.Header.Get("Content-Disposition"); got != `attachment; filename="catls.json"` {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+
+	var doc struct {
+		Files []catls.JSONFile `json:"files"`
+	}
+	if err := json.Unmarshal([]byte(body), &doc); err != nil {
+		t.Fatalf("download is not JSON: %v\n%s", err, body)
+	}
+	if len(doc.Files) != 2 {
+		t.Errorf("download has %d files, want 2", len(doc.Files))
+	}
+
+	_, body = get(t, ts.URL+"/download?format=markdown")
+	if !strings.Contains(body, "### main.go\n") || !strings.Contains(body, "~~~go") {
+		t.Errorf("markdown download ignored the configured options:\n%s", body)
+	}
+
+	resp, _ = get(t, ts.URL+"/download?format=yaml")
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unknown format status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestDownloadFailure(t *testing.T) {
+	ts := newTestServer(t, catls.Config{MaxFiles: 1})
+
+	resp, body := get(t, ts.URL+"/download?format=xml")
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", resp.StatusCode)
+	}
+	if resp.Header.Get("Content-Disposition") != "" || strings.Contains(body, "<files>") {
+		t.Errorf("failed download was sent as a file: %q", body)
+	}
+}
+
+func TestHostCheck(t *testing.T) {
+	ts := newTestServer(t, catls.Config{})
+
+	tests := []struct {
+		host       string
+		wantStatus int
+	}{
+		{host: "localhost", wantStatus: http.StatusOK},
+		{host: "LOCALHOST:8080", wantStatus: http.StatusOK},
+		{host: "127.0.0.1:8080", wantStatus: http.StatusOK},
+		{host: "[::1]:8080", wantStatus: http.StatusOK},
+		{host: "attacker.example", wantStatus: http.StatusMisdirectedRequest},
+		{host: "attacker.example:8080", wantStatus: http.StatusMisdirectedRequest},
+		{host: "192.0.2.1", wantStatus: http.StatusMisdirectedRequest},
+	}
+
+	for _, tt := range tests {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, ts.URL+"/download?format=xml", nil)
+		if err != nil {
+			t.Fatalf("NewRequest() error = %v", err)
+		}
+		req.Host = tt.host
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET with Host %s failed: %v", tt.host, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.wantStatus {
+			t.Errorf("Host %s: status = %d, want %d", tt.host, resp.StatusCode, tt.wantStatus)
+		}
+	}
+}
+
+func TestAllowedHostBound(t *testing.T) {
+	tests := []struct {
+		bound string
+		host  string
+		want  bool
+	}{
+		{bound: "192.0.2.1:8080", host: "192.0.2.1:8080", want: true},
+		{bound: "192.0.2.1:8080", host: "192.0.2.2:8080"},
+		{bound: "0.0.0.0:8080", host: "192.0.2.2:8080", want: true},
+		{bound: "0.0.0.0:8080", host: "intranet.example:8080"},
+	}
+
+	for _, tt := range tests {
+		bound, err := net.ResolveTCPAddr("tcp", tt.bound)
+		if err != nil {
+			t.Fatalf("ResolveTCPAddr(%s) error = %v", tt.bound, err)
+		}
+		s := &Server{bound: bound}
+		if got := s.allowedHost(tt.host); got != tt.want {
+			t.Errorf("bound to %s: allowedHost(%s) = %v, want %v", tt.bound, tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestCrossLink(t *testing.T) {
+	for _, crossLink := range []bool{false, true} {
+		ts := newTestServer(t, catls.Config{CrossLink: crossLink})
+
+		_, body := get(t, ts.URL+"/?path=main.go")
+		linked := strings.Contains(body, `// See <a href="/?path=docs%2Fguide.md">docs/guide.md</a>.`)
+		if linked != crossLink {
+			t.Errorf("CrossLink %v: mention linked = %v, want %v:\n%s", crossLink, linked, crossLink, body)
+		}
+		if !strings.Contains(body, "println(&#34;&lt;hi&gt;&#34;)") {
+			t.Errorf("CrossLink %v: content is not escaped:\n%s", crossLink, body)
+		}
+	}
+}
+
+// TestContentCache checks that unchanged files are read once across
+// requests, and that a file modified between requests is read again.
+func TestContentCache(t *testing.T) {
+	root := t.TempDir()
+	testutil.WriteFile(t, root, "main.go", "package main\n\nfunc main() { println(\"<hi>\") }\n")
+	testutil.WriteFile(t, root, "README.md", "# Readme\n")
+
+	cache := contentcache.New(contentcache.DefaultMaxBytes)
+	server, err := New(&catls.Config{Directory: root, OutputFormat: catls.OutputFormatXML, ContentCache: cache})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	ts := httptest.NewServer(server.Handler())
+	t.Cleanup(ts.Close)
+
+	_, body := get(t, ts.URL+"/?path=main.go")
+	if got := cache.Stats(); got.Hits != 0 || got.Misses != 2 || got.Files != 2 {
+		t.Errorf("after the first request Stats() = %+v, want 2 misses", got)
+	}
+	if !strings.Contains(body, "Content cache: 0 hits, 2 misses") {
+		t.Errorf("page does not show the cache counters:\n%s", body)
+	}
+
+	_, body = get(t, ts.URL+"/?path=main.go")
+	if got := cache.Stats(); got.Hits != 2 || got.Misses != 2 {
+		t.Errorf("after the second request Stats() = %+v, want 2 hits", got)
+	}
+	if !strings.Contains(body, "println(&#34;&lt;hi&gt;&#34;)") {
+		t.Errorf("cached content is not rendered:\n%s", body)
+	}
+
+	later := time.Now().Add(time.Hour)
+	testutil.WriteFile(t, root, "main.go", "package main\n\nfunc main() { println(\"edited\") }\n")
+	if err := os.Chtimes(filepath.Join(root, "main.go"), later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	_, body = get(t, ts.URL+"/?path=main.go")
+	if got := cache.Stats(); got.Hits != 3 || got.Misses != 3 {
+		t.Errorf("after the edit Stats() = %+v, want 3 hits and 3 misses", got)
+	}
+	if !strings.Contains(body, "edited") {
+		t.Errorf("edited content is not rendered:\n%s", body)
+	}
+}
+
+func TestStaticAssets(t *testing.T) {
+	ts := newTestServer(t, catls.Config{})
+
+	for _, asset := range []string{"/static/app.js", "/static/style.css"} {
+		resp, body := get(t, ts.URL+asset)
+		if resp.StatusCode != http.StatusOK || body == "" {
+			t.Errorf("GET %s = %d with %d bytes", asset, resp.StatusCode, len(body))
+		}
+	}
+}
+
+func TestNewRejectsTerminalModes(t *testing.T) {
+	for _, cfg := range []catls.Config{{Interactive: true}, {Order: true}} {
+		if _, err := New(&cfg); err == nil {
+			t.Errorf("New(%+v) expected error", cfg)
+		}
+	}
+}
+
+func TestServeStopsOnCancel(t *testing.T) {
+	server, err := New(&catls.Config{Directory: t.TempDir(), OutputFormat: catls.OutputFormatXML})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- server.Serve(ctx, ln) }()
+
+	if resp, _ := get(t, "http://"+ln.Addr().String()+"/static/app.js"); resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Serve() error = %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve() did not return after cancel")
+	}
+}
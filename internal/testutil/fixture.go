@@ -0,0 +1,88 @@
+// Package testutil provides helpers shared by catls tests, such as building
+// a representative fixture tree on disk.
+package testutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// HugeFileLines is the number of lines in the fixture's huge file, chosen to
+// exceed the processor's display limit so truncation is exercised.
+const HugeFileLines = 1500
+
+// FixtureFiles returns the text files of the fixture tree as path -> content.
+// Paths use forward slashes.
+func FixtureFiles() map[string]string {
+	return map[string]string{
+		"README.md":             "# Fixture\n\nA tree used by end-to-end tests.\n",
+		"main.go":               "package main\n\nfunc main() {\n\t// TODO: wire things up\n\tprintln(\"hi <&>\")\n}\n",
+		"notes.txt":             "first note\nsecond note\n",
+		"empty.txt":             "",
+		".dotfile":              "hidden=true\n",
+		".hidden/secret.txt":    "do not show\n",
+		"src/app.ts":            "export const app = () => `template ${1}`;\n",
+		"src/lib/util.py":       "def util():\n    return 42  # TODO: real value\n",
+		"src/lib/huge.log":      hugeContent(),
+		"docs/guide.md":         "# Guide\n\n```sh\ncatls -r .\n```\n",
+		"ünïcödé/日本語.txt":       "こんにちは\n",
+		"ünïcödé/emoji 🚀.md":    "rocket 🚀\n",
+		"node_modules/dep/x.js": "module.exports = 1;\n",
+	}
+}
+
+// BuildFixtureTree creates the fixture tree in a fresh temp directory and
+// returns its path. Besides FixtureFiles it contains a binary blob at
+// assets/blob.bin, an empty directory scratch/empty and, where the platform
+// allows, a symlink link-to-main.go pointing at main.go.
+func BuildFixtureTree(tb testing.TB) string {
+	tb.Helper()
+
+	root := tb.TempDir()
+
+	for path, content := range FixtureFiles() {
+		WriteFile(tb, root, path, content)
+	}
+
+	blob := make([]byte, 512)
+	for i := range blob {
+		blob[i] = byte(i % 256)
+	}
+	WriteFile(tb, root, "assets/blob.bin", string(blob))
+
+	// Git cannot track empty directories, so the tree needs one created here.
+	if err := os.MkdirAll(filepath.Join(root, "scratch", "empty"), 0o755); err != nil {
+		tb.Fatalf("failed to create empty directory: %v", err)
+	}
+
+	// Symlinks need privileges on some platforms; the tree is still usable without one.
+	_ = os.Symlink("main.go", filepath.Join(root, "link-to-main.go"))
+
+	return root
+}
+
+// WriteFile writes content to root/relPath, creating parent directories.
+func WriteFile(tb testing.TB, root, relPath, content string) {
+	tb.Helper()
+
+	fullPath := filepath.Join(root, filepath.FromSlash(relPath))
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+		tb.Fatalf("failed to create directory for %s: %v", relPath, err)
+	}
+	if err := os.WriteFile(fullPath, []byte(content), 0o644); err != nil {
+		tb.Fatalf("failed to write %s: %v", relPath, err)
+	}
+}
+
+// hugeContent returns HugeFileLines numbered lines.
+func hugeContent() string {
+	var b strings.Builder
+	for i := 1; i <= HugeFileLines; i++ {
+		fmt.Fprintf(&b, "log line %d\n", i)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,38 @@
+package testutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// UpdateGoldenEnv is the environment variable that, when set to a non-empty
+// value, rewrites golden files with the current output instead of comparing.
+const UpdateGoldenEnv = "UPDATE_GOLDEN"
+
+// AssertGolden compares got with the golden file at path, or rewrites the
+// golden file when UPDATE_GOLDEN is set.
+func AssertGolden(tb testing.TB, path, got string) {
+	tb.Helper()
+
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			tb.Fatalf("failed to create golden directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			tb.Fatalf("failed to update golden file %s: %v", path, err)
+		}
+
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		tb.Fatalf("failed to read golden file %s (run with %s=1 to create it): %v", path, UpdateGoldenEnv, err)
+	}
+
+	if string(want) != got {
+		tb.Errorf("output does not match golden file %s (run with %s=1 to update)\n--- got ---\n%s\n--- want ---\n%s",
+			path, UpdateGoldenEnv, got, want)
+	}
+}
@@ -0,0 +1,244 @@
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Schema is a parsed JSON Schema. ValidateJSON supports the subset the catls
+// schemas use: type, properties, required, additionalProperties, items,
+// enum, const, minimum, pattern, oneOf, and $ref to "#/$defs/NAME".
+type Schema struct {
+	root map[string]any
+}
+
+// ParseSchema parses a JSON Schema document.
+func ParseSchema(data []byte) (*Schema, error) {
+	root, err := decodeJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	object, ok := root.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid schema: not an object")
+	}
+
+	return &Schema{root: object}, nil
+}
+
+// ValidateJSON checks a JSON document against the schema and returns every
+// violation found, each prefixed with the JSON pointer of the value.
+func (s *Schema) ValidateJSON(data []byte) []string {
+	value, err := decodeJSON(data)
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	return s.validate(s.root, value, "")
+}
+
+// Resolve returns the subschema of node, following a $ref into $defs.
+func (s *Schema) Resolve(node map[string]any) map[string]any {
+	ref, ok := node["$ref"].(string)
+	if !ok {
+		return node
+	}
+	name, _ := strings.CutPrefix(ref, "#/$defs/")
+	defs, _ := s.root["$defs"].(map[string]any)
+	def, _ := defs[name].(map[string]any)
+
+	return def
+}
+
+// Root returns the top-level schema object.
+func (s *Schema) Root() map[string]any {
+	return s.root
+}
+
+// decodeJSON decodes data keeping numbers as json.Number, so integers can
+// be told from other numbers.
+func decodeJSON(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// validate checks value against node and returns the violations at path.
+func (s *Schema) validate(node map[string]any, value any, path string) []string {
+	node = s.Resolve(node)
+	if node == nil {
+		return []string{fmt.Sprintf("%s: unresolved $ref", pointer(path))}
+	}
+
+	var errs []string
+	fail := func(format string, args ...any) {
+		errs = append(errs, pointer(path)+": "+fmt.Sprintf(format, args...))
+	}
+
+	if types := schemaTypes(node["type"]); len(types) > 0 && !slices.ContainsFunc(types, func(t string) bool { return hasType(value, t) }) {
+		fail("got %s, want %s", typeName(value), strings.Join(types, " or "))
+
+		return errs
+	}
+	if want, ok := node["const"]; ok && !jsonEqual(value, want) {
+		fail("got %v, want %v", value, want)
+	}
+	if enum, ok := node["enum"].([]any); ok && !slices.ContainsFunc(enum, func(e any) bool { return jsonEqual(value, e) }) {
+		fail("%v is not one of %v", value, enum)
+	}
+	if minimum, ok := node["minimum"].(json.Number); ok {
+		if n, isNumber := value.(json.Number); isNumber {
+			got, _ := n.Float64()
+			floor, _ := minimum.Float64()
+			if got < floor {
+				fail("%v is less than %v", n, minimum)
+			}
+		}
+	}
+	if pattern, ok := node["pattern"].(string); ok {
+		if str, isString := value.(string); isString && !regexp.MustCompile(pattern).MatchString(str) {
+			fail("%q does not match %q", str, pattern)
+		}
+	}
+	if branches, ok := node["oneOf"].([]any); ok {
+		matched := 0
+		for _, branch := range branches {
+			if sub, isObject := branch.(map[string]any); isObject && len(s.validate(sub, value, path)) == 0 {
+				matched++
+			}
+		}
+		if matched != 1 {
+			fail("matches %d of the oneOf schemas, want exactly 1", matched)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		errs = append(errs, s.validateObject(node, v, path)...)
+	case []any:
+		if items, ok := node["items"].(map[string]any); ok {
+			for i, item := range v {
+				errs = append(errs, s.validate(items, item, fmt.Sprintf("%s/%d", path, i))...)
+			}
+		}
+	}
+
+	return errs
+}
+
+// validateObject checks the properties of object against node.
+func (s *Schema) validateObject(node, object map[string]any, path string) []string {
+	var errs []string
+	properties, _ := node["properties"].(map[string]any)
+	if required, ok := node["required"].([]any); ok {
+		for _, name := range required {
+			if _, present := object[name.(string)]; !present {
+				errs = append(errs, fmt.Sprintf("%s: missing required property %q", pointer(path), name))
+			}
+		}
+	}
+
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		child := path + "/" + name
+		if sub, ok := properties[name].(map[string]any); ok {
+			errs = append(errs, s.validate(sub, object[name], child)...)
+
+			continue
+		}
+		switch additional := node["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				errs = append(errs, fmt.Sprintf("%s: property is not in the schema", pointer(child)))
+			}
+		case map[string]any:
+			errs = append(errs, s.validate(additional, object[name], child)...)
+		}
+	}
+
+	return errs
+}
+
+// schemaTypes returns the types a "type" keyword allows.
+func schemaTypes(keyword any) []string {
+	switch t := keyword.(type) {
+	case string:
+		return []string{t}
+	case []any:
+		types := make([]string, 0, len(t))
+		for _, name := range t {
+			types = append(types, name.(string))
+		}
+
+		return types
+	}
+
+	return nil
+}
+
+// hasType reports whether value is of the JSON Schema type name.
+func hasType(value any, name string) bool {
+	switch v := value.(type) {
+	case nil:
+		return name == "null"
+	case bool:
+		return name == "boolean"
+	case string:
+		return name == "string"
+	case []any:
+		return name == "array"
+	case map[string]any:
+		return name == "object"
+	case json.Number:
+		if name == "number" {
+			return true
+		}
+		_, err := v.Int64()
+
+		return name == "integer" && err == nil
+	}
+
+	return false
+}
+
+// typeName names the JSON type of value for messages.
+func typeName(value any) string {
+	for _, name := range []string{"null", "boolean", "string", "array", "object", "integer", "number"} {
+		if hasType(value, name) {
+			return name
+		}
+	}
+
+	return fmt.Sprintf("%T", value)
+}
+
+// jsonEqual compares two decoded JSON values.
+func jsonEqual(a, b any) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
+
+// pointer returns path as a JSON pointer, "/" for the document itself.
+func pointer(path string) string {
+	if path == "" {
+		return "/"
+	}
+
+	return path
+}
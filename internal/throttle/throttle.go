@@ -0,0 +1,127 @@
+// Package throttle limits how fast bytes are written to an io.Writer, for
+// consumers that fail when megabytes arrive at once. Writes are split into
+// small chunks released by a token bucket, so output trickles out evenly,
+// and a wait ends as soon as the write's context is done.
+package throttle
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	// chunksPerSecond is how many chunks a second of output is split into,
+	// bounded by minChunk and maxChunk bytes.
+	chunksPerSecond = 20
+	minChunk        = 64
+	maxChunk        = 32 << 10
+)
+
+// Clock tells time and waits for the Writer; tests replace the real one.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// flusher is implemented by writers that buffer, such as *bufio.Writer.
+type flusher interface {
+	Flush() error
+}
+
+// Writer writes to an underlying writer at no more than a set number of
+// bytes per second. It is safe for concurrent use.
+type Writer struct {
+	mu     sync.Mutex
+	w      io.Writer
+	clock  Clock
+	rate   float64 // Bytes per second
+	chunk  int     // Largest write passed to w, which is also the bucket size
+	tokens float64 // Bytes that may be written now
+	last   time.Time
+}
+
+// NewWriter returns a Writer passing bytes to w at up to rate bytes per
+// second, which must be positive.
+func NewWriter(w io.Writer, rate int64) *Writer {
+	return newWriter(w, rate, realClock{})
+}
+
+func newWriter(w io.Writer, rate int64, clock Clock) *Writer {
+	chunk := int(min(max(rate/chunksPerSecond, minChunk), maxChunk))
+
+	return &Writer{
+		w:      w,
+		clock:  clock,
+		rate:   float64(rate),
+		chunk:  chunk,
+		tokens: float64(chunk),
+		last:   clock.Now(),
+	}
+}
+
+// Write is WriteContext without a context.
+func (w *Writer) Write(p []byte) (int, error) {
+	return w.WriteContext(context.Background(), p)
+}
+
+// WriteContext writes p a chunk at a time, waiting between chunks for the
+// rate to allow each, and flushes w after each chunk when it buffers. When
+// ctx is done first it returns the bytes written so far and ctx's error.
+func (w *Writer) WriteContext(ctx context.Context, p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	written := 0
+	for len(p) > 0 {
+		n := min(len(p), w.chunk)
+		if err := w.wait(ctx, n); err != nil {
+			return written, err
+		}
+
+		m, err := w.w.Write(p[:n])
+		written += m
+		if err != nil {
+			return written, err
+		}
+		if f, ok := w.w.(flusher); ok {
+			if err := f.Flush(); err != nil {
+				return written, err
+			}
+		}
+		p = p[n:]
+	}
+
+	return written, nil
+}
+
+// wait blocks until the bucket holds n bytes, then takes them.
+func (w *Writer) wait(ctx context.Context, n int) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		now := w.clock.Now()
+		w.tokens = min(w.tokens+now.Sub(w.last).Seconds()*w.rate, float64(w.chunk))
+		w.last = now
+		if w.tokens >= float64(n) {
+			w.tokens -= float64(n)
+
+			return nil
+		}
+
+		delay := time.Duration((float64(n) - w.tokens) / w.rate * float64(time.Second))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-w.clock.After(max(delay, time.Millisecond)):
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package throttle
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// fakeClock advances by the requested delay whenever it is waited on, so
+// tests observe the time a write takes without sleeping.
+type fakeClock struct {
+	now   time.Time
+	waits int
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.now = c.now.Add(d)
+	c.waits++
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+
+	return ch
+}
+
+// chunkRecorder records the size of every write it receives.
+type chunkRecorder struct {
+	bytes.Buffer
+	sizes []int
+}
+
+func (r *chunkRecorder) Write(p []byte) (int, error) {
+	r.sizes = append(r.sizes, len(p))
+
+	return r.Buffer.Write(p)
+}
+
+func TestWriterRate(t *testing.T) {
+	tests := []struct {
+		name  string
+		rate  int64
+		total int
+	}{
+		{name: "200k/s", rate: 200 << 10, total: 1 << 20},
+		{name: "1k/s", rate: 1 << 10, total: 10 << 10},
+		{name: "5m/s", rate: 5 << 20, total: 20 << 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &fakeClock{now: time.Unix(0, 0)}
+			var out chunkRecorder
+			w := newWriter(&out, tt.rate, clock)
+
+			data := bytes.Repeat([]byte("0123456789abcdef"), tt.total/16)
+			start := clock.Now()
+			// Several writes of odd sizes, as formatters make them
+			for rest := data; len(rest) > 0; {
+				n := min(len(rest), 3000)
+				if _, err := w.Write(rest[:n]); err != nil {
+					t.Fatalf("Write() unexpected error: %v", err)
+				}
+				rest = rest[n:]
+			}
+			elapsed := clock.Now().Sub(start).Seconds()
+
+			if !bytes.Equal(out.Bytes(), data) {
+				t.Fatal("output differs from the bytes written")
+			}
+			achieved := float64(tt.total) / elapsed
+			if math.Abs(achieved-float64(tt.rate))/float64(tt.rate) > 0.05 {
+				t.Errorf("achieved %.0f bytes/s, want %d within 5%%", achieved, tt.rate)
+			}
+			for _, size := range out.sizes {
+				if size > w.chunk {
+					t.Fatalf("wrote a chunk of %d bytes, want at most %d", size, w.chunk)
+				}
+			}
+		})
+	}
+}
+
+// blockedClock never lets a wait end.
+type blockedClock struct{ fakeClock }
+
+func (*blockedClock) After(time.Duration) <-chan time.Time { return nil }
+
+func TestWriterCancel(t *testing.T) {
+	clock := &blockedClock{fakeClock{now: time.Unix(0, 0)}}
+	var out bytes.Buffer
+	w := newWriter(&out, 1<<10, clock)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	var n int
+	var err error
+	go func() {
+		defer close(done)
+		n, err = w.WriteContext(ctx, make([]byte, 10<<10))
+	}()
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("WriteContext() kept waiting after its context was cancelled")
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("WriteContext() error = %v, want context.Canceled", err)
+	}
+	if n != out.Len() || n >= 10<<10 {
+		t.Errorf("WriteContext() = %d with %d bytes written, want a partial count matching the output", n, out.Len())
+	}
+}
+
+type flushRecorder struct {
+	bytes.Buffer
+	flushes int
+}
+
+func (f *flushRecorder) Flush() error {
+	f.flushes++
+
+	return nil
+}
+
+func TestWriterFlushes(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	var out flushRecorder
+	w := newWriter(&out, 1<<10, clock)
+
+	if _, err := w.Write(make([]byte, 4*w.chunk)); err != nil {
+		t.Fatal(err)
+	}
+	if out.flushes != 4 {
+		t.Errorf("flushed %d times, want once per chunk (4)", out.flushes)
+	}
+}
@@ -0,0 +1,202 @@
+// Package unidiff renders line diffs in the unified format of diff -u.
+package unidiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// edit is one line of an edit script: kept (' '), deleted ('-'), or
+// inserted ('+').
+type edit struct {
+	op   byte
+	line string
+}
+
+// Lines returns a unified diff that turns a into b, with the given number of
+// context lines around each change, under "---" and "+++" headers naming
+// the two sides. It returns an empty string when a and b are equal.
+func Lines(oldName, newName string, a, b []string, context int) string {
+	edits := editScript(a, b)
+
+	var out strings.Builder
+	for _, h := range hunks(edits, context) {
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+		}
+		writeHunk(&out, edits, h)
+	}
+
+	return out.String()
+}
+
+// editScript returns a shortest edit script from a to b, found with Myers'
+// algorithm. Lines shared at both ends are matched first, so the search only
+// covers the region that changed.
+func editScript(a, b []string) []edit {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	edits := make([]edit, 0, len(a)+len(b)-prefix-suffix)
+	for _, line := range a[:prefix] {
+		edits = append(edits, edit{' ', line})
+	}
+	edits = append(edits, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		edits = append(edits, edit{' ', line})
+	}
+
+	return edits
+}
+
+// myers returns a shortest edit script from a to b. Each round d keeps the
+// furthest point reached on every diagonal k in [-d, d]; the script is read
+// back from the rounds' snapshots.
+func myers(a, b []string) []edit {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+
+	var trace [][]int
+	for d := 0; d <= n+m; d++ {
+		// Diagonals of round d are read back as they stood before it
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				return backtrack(a, b, trace)
+			}
+		}
+	}
+
+	return nil
+}
+
+// backtrack walks the rounds of myers from the end of both inputs back to
+// their start and returns the edits in order.
+func backtrack(a, b []string, trace [][]int) []edit {
+	var edits []edit
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d >= 0; d-- {
+		prevX, prevY := 0, 0
+		if d > 0 {
+			round := func(k int) int { return trace[d][k+d] }
+			k := x - y
+			prevK := k - 1
+			if k == -d || (k != d && round(k-1) < round(k+1)) {
+				prevK = k + 1
+			}
+			prevX = round(prevK)
+			prevY = prevX - prevK
+		}
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			edits = append(edits, edit{' ', a[x]})
+		}
+		if d == 0 {
+			break
+		}
+		if x == prevX {
+			edits = append(edits, edit{'+', b[prevY]})
+		} else {
+			edits = append(edits, edit{'-', a[prevX]})
+		}
+		x, y = prevX, prevY
+	}
+
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+
+	return edits
+}
+
+// hunk is a range of an edit script, [start, end), written as one hunk.
+type hunk struct {
+	start, end int
+}
+
+// hunks groups the changes in edits with context lines around them. Changes
+// closer than twice the context share a hunk.
+func hunks(edits []edit, context int) []hunk {
+	var result []hunk
+	for i, e := range edits {
+		if e.op == ' ' {
+			continue
+		}
+
+		start, end := max(0, i-context), min(len(edits), i+1+context)
+		if n := len(result); n > 0 && start <= result[n-1].end {
+			result[n-1].end = end
+
+			continue
+		}
+		result = append(result, hunk{start, end})
+	}
+
+	return result
+}
+
+// writeHunk writes the "@@" header and lines of h.
+func writeHunk(b *strings.Builder, edits []edit, h hunk) {
+	oldLine, newLine := 1, 1
+	for _, e := range edits[:h.start] {
+		if e.op != '+' {
+			oldLine++
+		}
+		if e.op != '-' {
+			newLine++
+		}
+	}
+
+	oldLen, newLen := 0, 0
+	for _, e := range edits[h.start:h.end] {
+		if e.op != '+' {
+			oldLen++
+		}
+		if e.op != '-' {
+			newLen++
+		}
+	}
+
+	fmt.Fprintf(b, "@@ -%s +%s @@\n", hunkRange(oldLine, oldLen), hunkRange(newLine, newLen))
+	for _, e := range edits[h.start:h.end] {
+		b.WriteByte(e.op)
+		b.WriteString(e.line)
+		b.WriteByte('\n')
+	}
+}
+
+// hunkRange formats the start and length of one side of a hunk. An empty
+// side names the line before it, as diff -u does.
+func hunkRange(start, length int) string {
+	switch length {
+	case 0:
+		return fmt.Sprintf("%d,0", start-1)
+	case 1:
+		return fmt.Sprint(start)
+	default:
+		return fmt.Sprintf("%d,%d", start, length)
+	}
+}
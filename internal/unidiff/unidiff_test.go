@@ -0,0 +1,95 @@
+package unidiff
+
+import (
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{name: "equal", a: "a b c", b: "a b c", want: ""},
+		{
+			name: "one change",
+			a:    "1 2 3 4 5 6 7 8 9",
+			b:    "1 2 3 4 five 6 7 8 9",
+			want: "--- old\n+++ new\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			name: "distant changes make two hunks",
+			a:    "1 2 3 4 5 6 7 8 9 10 11 12",
+			b:    "one 2 3 4 5 6 7 8 9 10 11 twelve",
+			want: "--- old\n+++ new\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -9,4 +9,4 @@\n 9\n 10\n 11\n-12\n+twelve\n",
+		},
+		{name: "from empty", a: "", b: "x y", want: "--- old\n+++ new\n@@ -0,0 +1,2 @@\n+x\n+y\n"},
+		{name: "to empty", a: "x", b: "", want: "--- old\n+++ new\n@@ -1 +0,0 @@\n-x\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Lines("old", "new", strings.Fields(tt.a), strings.Fields(tt.b), 3); got != tt.want {
+				t.Errorf("Lines() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEditScriptIsShortest(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	words := func() []string {
+		s := make([]string, rng.IntN(12))
+		for i := range s {
+			s[i] = string(rune('a' + rng.IntN(4)))
+		}
+
+		return s
+	}
+
+	for range 500 {
+		a, b := words(), words()
+		edits := editScript(a, b)
+
+		var gotA, gotB []string
+		changes := 0
+		for _, e := range edits {
+			if e.op != '+' {
+				gotA = append(gotA, e.line)
+			}
+			if e.op != '-' {
+				gotB = append(gotB, e.line)
+			}
+			if e.op != ' ' {
+				changes++
+			}
+		}
+		if !slices.Equal(gotA, a) || !slices.Equal(gotB, b) {
+			t.Fatalf("editScript(%v, %v) = %v, which does not turn a into b", a, b, edits)
+		}
+		if want := len(a) + len(b) - 2*lcs(a, b); changes != want {
+			t.Fatalf("editScript(%v, %v) makes %d changes, want %d", a, b, changes, want)
+		}
+	}
+}
+
+// lcs returns the length of the longest common subsequence of a and b.
+func lcs(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	for i := range a {
+		cur := make([]int, len(b)+1)
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(prev[j+1], cur[j])
+			}
+		}
+		prev = cur
+	}
+
+	return prev[len(b)]
+}
@@ -0,0 +1,18 @@
+// Package xattr lists the names of a file's extended attributes, such as
+// com.apple.quarantine on macOS, on the platforms that have them. Elsewhere
+// Supported is false and List reports errors.ErrUnsupported.
+package xattr
+
+import "strings"
+
+// splitNames splits the NUL-terminated names the list system call returns.
+func splitNames(buf []byte) []string {
+	var names []string
+	for _, name := range strings.Split(string(buf), "\x00") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
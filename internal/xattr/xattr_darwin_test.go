@@ -0,0 +1,33 @@
+//go:build darwin
+
+package xattr
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.txt")
+	if err := os.WriteFile(path, []byte("data"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	for _, name := range []string{"com.example.catls.b", "com.apple.quarantine"} {
+		if err := unix.Setxattr(path, name, []byte("value"), 0); err != nil {
+			t.Fatalf("Setxattr(%s) unexpected error: %v", name, err)
+		}
+	}
+
+	names, err := List(path)
+	if err != nil {
+		t.Fatalf("List() unexpected error: %v", err)
+	}
+	if want := []string{"com.apple.quarantine", "com.example.catls.b"}; !slices.Equal(names, want) {
+		t.Errorf("List() = %v, want %v", names, want)
+	}
+}
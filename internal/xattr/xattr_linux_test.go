@@ -0,0 +1,45 @@
+//go:build linux
+
+package xattr
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.txt")
+	if err := os.WriteFile(path, []byte("data"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	if names, err := List(path); err != nil || len(names) != 0 {
+		t.Fatalf("List() = %v, %v, want no names", names, err)
+	}
+
+	for _, name := range []string{"user.catls.b", "user.catls.a"} {
+		if err := unix.Setxattr(path, name, []byte("value"), 0); err != nil {
+			if errors.Is(err, unix.ENOTSUP) || errors.Is(err, unix.EPERM) {
+				t.Skipf("the temporary directory does not support user attributes: %v", err)
+			}
+			t.Fatalf("Setxattr(%s) unexpected error: %v", name, err)
+		}
+	}
+
+	names, err := List(path)
+	if err != nil {
+		t.Fatalf("List() unexpected error: %v", err)
+	}
+	if want := []string{"user.catls.a", "user.catls.b"}; !slices.Equal(names, want) {
+		t.Errorf("List() = %v, want %v", names, want)
+	}
+
+	if _, err := List(filepath.Join(t.TempDir(), "missing")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("List() of a missing file error = %v, want it not to exist", err)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package xattr
+
+import "errors"
+
+// Supported reports whether List works on this platform.
+const Supported = false
+
+// List is unsupported on this platform.
+func List(string) ([]string, error) {
+	return nil, errors.ErrUnsupported
+}
@@ -0,0 +1,52 @@
+//go:build linux || darwin
+
+package xattr
+
+import (
+	"errors"
+	"slices"
+
+	"golang.org/x/sys/unix"
+)
+
+// Supported reports whether List works on this platform.
+const Supported = true
+
+// List returns the sorted names, not values, of the extended attributes of
+// the file at path, following symlinks. A filesystem without extended
+// attributes yields no names rather than an error.
+func List(path string) ([]string, error) {
+	for {
+		size, err := unix.Listxattr(path, nil)
+		if err != nil {
+			return nil, ignoreUnsupported(err)
+		}
+		if size == 0 {
+			return nil, nil
+		}
+
+		buf := make([]byte, size)
+		n, err := unix.Listxattr(path, buf)
+		if errors.Is(err, unix.ERANGE) {
+			continue // An attribute was added since the size was read
+		}
+		if err != nil {
+			return nil, ignoreUnsupported(err)
+		}
+
+		names := splitNames(buf[:n])
+		slices.Sort(names)
+
+		return names, nil
+	}
+}
+
+// ignoreUnsupported drops the error of a filesystem that has no extended
+// attributes.
+func ignoreUnsupported(err error) error {
+	if errors.Is(err, unix.ENOTSUP) || errors.Is(err, unix.EOPNOTSUPP) {
+		return nil
+	}
+
+	return err
+}